@@ -0,0 +1,122 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+
+	"github.com/pion/webrtc/v4"
+	"github.com/rescp17/lanFileSharer/pkg/crypto"
+	"github.com/rescp17/lanFileSharer/pkg/transfer"
+)
+
+// ErrUnknownSession is returned by a BondFunc for a token no running session has
+var ErrUnknownSession = errors.New("no running session has this token")
+
+// BondPayload carries the offer and the answer of POST /bond. Token is the resume token of the
+// running session the connection joins. Both descriptions are complete, as with POST /echo.
+type BondPayload struct {
+	Token     string                     `json:"token,omitempty"`
+	Offer     *webrtc.SessionDescription `json:"offer,omitempty"`
+	Signature []byte                     `json:"signature,omitempty"` // Signs token and offer with the key of the session's structure
+	Answer    *webrtc.SessionDescription `json:"answer,omitempty"`
+	MAC       string                     `json:"mac,omitempty"` // Signs the answer in air-gap mode
+}
+
+// BondFunc answers the offer of an extra connection to the running session with resume token
+// token, whose data channels carry chunks of the session next to its own. It returns an error
+// wrapping ErrUnknownSession if no running session has the token, and one wrapping
+// crypto.ErrBondOfferUnsigned if signature was not made over token and offer by the key that
+// signed the session's structure.
+type BondFunc func(ctx context.Context, token string, offer webrtc.SessionDescription, signature []byte) (*webrtc.SessionDescription, error)
+
+// SetBondHandler enables POST /bond, letting senders stripe a session over several connections.
+func (a *API) SetBondHandler(bond BondFunc) {
+	a.server.bond = bond
+}
+
+// BondHandler answers the offer of an extra connection to a running session. The token names
+// the session, and the offer must be signed with the key of its structure, which stands in for
+// asking the user again: the token travels in plaintext, but the key never leaves the sender.
+func (s *ReceiverService) BondHandler(w http.ResponseWriter, r *http.Request) {
+	if s.bond == nil {
+		http.Error(w, "Bonding not supported", http.StatusNotFound)
+		return
+	}
+	var req BondPayload
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Offer == nil || req.Token == "" {
+		http.Error(w, "Invalid bond payload", http.StatusBadRequest)
+		return
+	}
+	if len(req.Signature) == 0 {
+		http.Error(w, "Unsigned bond offer", http.StatusForbidden)
+		return
+	}
+	transfer.TraceSignal(transfer.TraceIn, "/bond", "bond", int(r.ContentLength))
+
+	// The connection lasts as long as the session, not this request
+	answer, err := s.bond(context.WithoutCancel(r.Context()), req.Token, *req.Offer, req.Signature)
+	if errors.Is(err, ErrUnknownSession) {
+		http.Error(w, "Unknown session", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, crypto.ErrBondOfferUnsigned) {
+		slog.Warn("Refused bond offer", "error", err)
+		http.Error(w, "Unsigned bond offer", http.StatusForbidden)
+		return
+	}
+	if err != nil {
+		slog.Error("Failed to answer bond offer", "error", err)
+		http.Error(w, "Failed to answer bond offer", http.StatusInternalServerError)
+		return
+	}
+
+	response := BondPayload{Answer: answer}
+	if s.psk != nil {
+		response.MAC = s.psk.answerMAC(answer.SDP)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		slog.Error("Failed to encode bond answer", "error", err)
+		return
+	}
+	transfer.TraceSignal(transfer.TraceOut, "/bond", "bond_answer", -1)
+}
+
+// Bond offers the receiver an extra connection to the running session with resume token token,
+// with signature made by crypto.FileStructureSigner.SignBondOffer, and returns its complete answer.
+func (c *Client) Bond(ctx context.Context, receiverURL, token string, offer webrtc.SessionDescription, signature []byte) (*webrtc.SessionDescription, error) {
+	endpoint, err := url.JoinPath(receiverURL, "bond")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create bond url: %w", err)
+	}
+	body, err := json.Marshal(BondPayload{Token: token, Offer: &offer, Signature: signature})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal bond payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create bond request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	var resp BondPayload
+	if err := c.doJSON(req, &resp); err != nil {
+		return nil, fmt.Errorf("bond failed: %w", err)
+	}
+	transfer.TraceSignal(transfer.TraceOut, "/bond", "bond", len(body))
+	transfer.TraceSignal(transfer.TraceIn, "/bond", "bond_answer", -1)
+	if resp.Answer == nil {
+		return nil, fmt.Errorf("bond failed: no answer in response")
+	}
+	if err := c.psk.checkAnswer(resp.Answer.SDP, resp.MAC); err != nil {
+		return nil, fmt.Errorf("bond failed: %w", err)
+	}
+	return resp.Answer, nil
+}
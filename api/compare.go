@@ -0,0 +1,105 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+
+	"github.com/rescp17/lanFileSharer/pkg/crypto"
+	"github.com/rescp17/lanFileSharer/pkg/fileInfo"
+)
+
+// ComparePayload is the body of POST /compare: the structure a sender would offer, without content.
+type ComparePayload struct {
+	SignedFiles *crypto.SignedFileStructure `json:"signed_files"`
+	// SenderName is the sender's hostname, which output templates may lay files out by
+	SenderName string `json:"sender_name,omitempty"`
+}
+
+// CompareReport tells how the receiver's output differs from an offered structure. Paths are
+// relative to the receiver's output directory, as the files would be saved there.
+type CompareReport struct {
+	Missing   []string `json:"missing"`   // Offered files the receiver does not have
+	Different []string `json:"different"` // Files the receiver has with other content
+	Extra     []string `json:"extra"`     // Files next to the offered ones that were not offered
+	Same      int      `json:"same"`      // Offered files the receiver has unchanged
+}
+
+// CompareFunc compares the output directory with the offered files.
+type CompareFunc func(files []fileInfo.FileNode, senderName string) (*CompareReport, error)
+
+// SetCompareHandler enables POST /compare, answered with compare for trusted senders.
+func (a *API) SetCompareHandler(compare CompareFunc) {
+	a.server.compare = compare
+}
+
+// CompareHandler compares a signed structure with the output directory. The report lists the
+// receiver's files, so only senders whose keys are trusted, or who proved they know the
+// pre-shared key, get one; the user is not asked.
+func (s *ReceiverService) CompareHandler(w http.ResponseWriter, r *http.Request) {
+	if s.compare == nil {
+		http.Error(w, "Compare not supported", http.StatusNotFound)
+		return
+	}
+	var req ComparePayload
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.SignedFiles == nil {
+		http.Error(w, "Invalid compare payload", http.StatusBadRequest)
+		return
+	}
+	if err := crypto.VerifyFileStructure(req.SignedFiles); err != nil {
+		slog.Error("failed to verify compared file structure", "error", err)
+		http.Error(w, "Invalid file structure", http.StatusBadRequest)
+		return
+	}
+
+	fingerprint := crypto.PublicKeyFingerprint(req.SignedFiles.PublicKey)
+	if !s.peerFilter.AllowsFingerprint(fingerprint) {
+		slog.Warn("Refused compare signed by a filtered key", "fingerprint", fingerprint, "addr", r.RemoteAddr)
+		writeForbidden(w)
+		return
+	}
+	if s.psk == nil && s.senderTrust(req.SignedFiles) != crypto.TrustValid {
+		slog.Info("Refused compare from an untrusted sender", "fingerprint", fingerprint, "addr", r.RemoteAddr)
+		writeForbidden(w)
+		return
+	}
+
+	report, err := s.compare(req.SignedFiles.Files, req.SenderName)
+	if err != nil {
+		slog.Error("Failed to compare output directory", "error", err)
+		http.Error(w, "Failed to compare", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		slog.Error("Failed to encode compare report", "error", err)
+	}
+}
+
+// Compare sends a signed structure to the receiver's compare endpoint and returns its report.
+func (c *Client) Compare(ctx context.Context, receiverURL string, signedFiles *crypto.SignedFileStructure) (*CompareReport, error) {
+	endpoint, err := url.JoinPath(receiverURL, "compare")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create compare url: %w", err)
+	}
+	body, err := json.Marshal(ComparePayload{SignedFiles: signedFiles, SenderName: senderName()})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal compare payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create compare request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	var report CompareReport
+	if err := c.doJSON(req, &report); err != nil {
+		return nil, fmt.Errorf("compare failed: %w", err)
+	}
+	return &report, nil
+}
@@ -0,0 +1,49 @@
+package api
+
+import (
+	"context"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/rescp17/lanFileSharer/internal/app"
+	"github.com/rescp17/lanFileSharer/pkg/crypto"
+	"github.com/rescp17/lanFileSharer/pkg/fileInfo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompareHandler_TrustedSendersOnly(t *testing.T) {
+	uiMessages := make(chan tea.Msg, 1)
+	handler := NewAPI(uiMessages, app.NewSingleRequestManager(), nil)
+	trustStore, err := crypto.LoadTrustStore(filepath.Join(t.TempDir(), crypto.TrustStoreFileName), 0)
+	require.NoError(t, err)
+	handler.SetTrustStore(trustStore)
+
+	var compared []fileInfo.FileNode
+	handler.SetCompareHandler(func(files []fileInfo.FileNode, senderName string) (*CompareReport, error) {
+		compared = files
+		return &CompareReport{Missing: []string{"test.txt"}}, nil
+	})
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	client := NewClient("test-service-id")
+	signedFiles := createTestSignedFiles(t)
+
+	_, err = client.Compare(ctx, server.URL, signedFiles)
+	assert.ErrorContains(t, err, "403", "unknown senders are refused")
+	assert.Nil(t, compared)
+
+	trustStore.Trust(crypto.PublicKeyFingerprint(signedFiles.PublicKey), "laptop", time.Time{})
+	report, err := client.Compare(ctx, server.URL, signedFiles)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"test.txt"}, report.Missing)
+	require.Len(t, compared, 1)
+	assert.Equal(t, "test.txt", compared[0].Name)
+	assert.Empty(t, uiMessages, "the user is not asked")
+}
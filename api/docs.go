@@ -0,0 +1,340 @@
+package api
+
+import (
+	"encoding"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"path"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pion/webrtc/v4"
+	"github.com/rescp17/lanFileSharer/internal/version"
+	"github.com/rescp17/lanFileSharer/pkg/jobs"
+	"github.com/rescp17/lanFileSharer/pkg/share"
+	"github.com/rescp17/lanFileSharer/pkg/transfer"
+)
+
+// DocsPath is where the receiver serves the OpenAPI document of its API
+const DocsPath = "/api/docs"
+
+// askEvents describes the server-sent events of POST /ask, which OpenAPI has no schema for
+const askEvents = "The response is a stream of server-sent events. `answer` carries the WebRTC answer " +
+	"and the receiver's capabilities once the user accepts, followed by a `candidate` event per ICE " +
+	"candidate of the receiver and `candidates_done`. `rejection` and `timeout` end requests the user " +
+	"declined or did not answer, `error` those that failed; their data is a JSON object with a " +
+	"`message` or `error`. Candidates of the sender go to POST /candidate while the stream is open."
+
+// uploadDescription describes POST /upload, whose multipart body has no JSON schema
+const uploadDescription = "The body is multipart/form-data; every part with a file name is saved as a file. " +
+	"The token is given in the `token` query parameter or as `Authorization: Bearer <token>`. Browsers " +
+	"sending `Accept: text/html` get the form back with a summary instead of JSON."
+
+// uploadTokenParam is the token enabling the upload endpoints, see receive --upload
+var uploadTokenParam = routeParam{name: "token", in: "query", description: "Upload token the receiver printed"}
+
+// route is an endpoint of the receiver API, with what its OpenAPI document says about it
+type route struct {
+	pattern     string // Method and path, as registered with http.ServeMux
+	summary     string
+	description string
+	handler     http.HandlerFunc
+	params      []routeParam
+	request     any    // Zero value of the JSON request body, nil for none
+	response    any    // Zero value of the JSON response body, nil for none
+	contentType string // Content type of responses that are not JSON
+	status      int    // Status of successful responses, http.StatusOK if zero
+}
+
+// routeParam is a query or header parameter of a route; path parameters come from the pattern
+type routeParam struct {
+	name        string
+	in          string // "query" or "header"
+	description string
+}
+
+// routes returns the endpoints of the receiver API. registerRoutes serves them and the
+// OpenAPI document describes them, so both are generated from this table.
+func (a *API) routes() []route {
+	s := a.server
+	askHandler := s.ConcurrencyControlMiddleware(http.HandlerFunc(s.AskHandler))
+	return []route{
+		{
+			pattern: "POST /ask", summary: "Offer files to the receiver", description: askEvents,
+			handler: askHandler.ServeHTTP, request: AskPayload{}, contentType: "text/event-stream",
+		},
+		{
+			pattern: "POST /candidate", summary: "Send an ICE candidate of the sender during POST /ask",
+			handler: s.CandidateHandler, request: webrtc.ICECandidateInit{}, response: map[string]string{},
+		},
+		{
+			pattern: "GET /resume/{token}", summary: "Get the chunks received of an interrupted transfer",
+			handler: s.ResumeHandler, response: transfer.ResumeState{},
+		},
+		{
+			pattern: "GET /ping", summary: "Check the receiver is there without asking the user",
+			handler: s.PingHandler, response: PingResponse{},
+		},
+		{
+			pattern: "POST /echo", summary: "Open a connection echoing data channel messages, to measure the path",
+			handler: s.EchoHandler, request: EchoPayload{}, response: EchoPayload{},
+		},
+		{
+			pattern: "POST /bond", summary: "Add a connection to the running session, which its chunks are striped over",
+			handler: s.BondHandler, request: BondPayload{}, response: BondPayload{},
+		},
+		{
+			pattern: "GET /jobs", summary: "List the scheduled jobs of the daemon; answered on loopback only",
+			handler: s.JobsHandler, response: []jobs.Status{},
+		},
+		{
+			pattern: "POST /compare", summary: "Compare an offered structure with the receiver's files; trusted senders only",
+			handler: s.CompareHandler, request: ComparePayload{}, response: CompareReport{},
+		},
+		{
+			pattern: "GET /share", summary: "List a directory of the share",
+			handler: s.ShareHandler, response: []share.Entry{},
+			params: []routeParam{{name: "path", in: "query", description: "Directory relative to the share, the share itself if empty"}},
+		},
+		{
+			pattern: "GET /share/preview", summary: "Get the first bytes of a file of the share",
+			handler: s.PreviewHandler, contentType: "application/octet-stream",
+			params: []routeParam{
+				{name: "path", in: "query", description: "File relative to the share"},
+				{name: "Range", in: "header", description: "bytes=0-<last>; at most MaxPreviewBytes are returned"},
+			},
+		},
+		{
+			pattern: "GET " + ZipPath, summary: "Download a directory of the share as a zip archive, such as with a browser",
+			handler: s.ZipHandler, contentType: "application/zip",
+			params: []routeParam{{name: "path", in: "query", description: "Directory or file relative to the share, the share itself if empty"}},
+		},
+		{
+			pattern: "POST /pull", summary: "Have files of the share sent to the puller's receiver",
+			handler: s.PullHandler, request: PullPayload{}, status: http.StatusAccepted,
+		},
+		{
+			pattern: "GET " + UploadPath, summary: "Get the form browsers upload files with",
+			handler: s.UploadFormHandler, contentType: "text/html", params: []routeParam{uploadTokenParam},
+		},
+		{
+			pattern: "POST " + UploadPath, summary: "Upload files from a browser or script", description: uploadDescription,
+			handler: s.UploadHandler, response: UploadResult{}, params: []routeParam{uploadTokenParam},
+		},
+		{
+			pattern: "GET " + DocsPath, summary: "Get this OpenAPI document",
+			handler: a.DocsHandler, contentType: "application/json",
+		},
+	}
+}
+
+// DocsHandler serves the OpenAPI document of the receiver API, so other clients can talk to it
+func (a *API) DocsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(openAPIDocument(a.routes())); err != nil {
+		slog.Error("Failed to encode API docs", "error", err)
+	}
+}
+
+// pathParamPattern matches the parameters of a route pattern, such as {token}
+var pathParamPattern = regexp.MustCompile(`\{(\w+)\}`)
+
+// openAPIDocument returns the OpenAPI 3.0 document of routes
+func openAPIDocument(routes []route) map[string]any {
+	schemas := newSchemaBuilder()
+	paths := make(map[string]map[string]any)
+	for _, r := range routes {
+		method, urlPath, _ := strings.Cut(r.pattern, " ")
+		operation := map[string]any{"summary": r.summary}
+		if r.description != "" {
+			operation["description"] = r.description
+		}
+
+		var params []map[string]any
+		for _, match := range pathParamPattern.FindAllStringSubmatch(urlPath, -1) {
+			params = append(params, map[string]any{
+				"name": match[1], "in": "path", "required": true, "schema": map[string]any{"type": "string"},
+			})
+		}
+		for _, p := range r.params {
+			params = append(params, map[string]any{
+				"name": p.name, "in": p.in, "description": p.description, "schema": map[string]any{"type": "string"},
+			})
+		}
+		if len(params) > 0 {
+			operation["parameters"] = params
+		}
+
+		if r.request != nil {
+			operation["requestBody"] = map[string]any{
+				"required": true,
+				"content":  map[string]any{"application/json": map[string]any{"schema": schemas.schema(reflect.TypeOf(r.request))}},
+			}
+		}
+
+		status := http.StatusOK
+		if r.status != 0 {
+			status = r.status
+		}
+		success := map[string]any{"description": http.StatusText(status)}
+		switch {
+		case r.response != nil:
+			success["content"] = map[string]any{"application/json": map[string]any{"schema": schemas.schema(reflect.TypeOf(r.response))}}
+		case r.contentType != "":
+			success["content"] = map[string]any{r.contentType: map[string]any{}}
+		}
+		operation["responses"] = map[string]any{
+			strconv.Itoa(status): success,
+			"403":                map[string]any{"description": "Refused, such as by the peer filter"},
+		}
+
+		if paths[urlPath] == nil {
+			paths[urlPath] = make(map[string]any)
+		}
+		paths[urlPath][strings.ToLower(method)] = operation
+	}
+
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":       "lanFileSharer receiver API",
+			"version":     version.String(),
+			"description": "Signaling and sharing API of a lanFileSharer receiver. Files themselves go over WebRTC data channels, see docs/PROTOCOL_TRACE.md for their messages.",
+		},
+		"paths":      paths,
+		"components": map[string]any{"schemas": schemas.components},
+	}
+}
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// schemaBuilder derives JSON schemas from Go types as encoding/json encodes them. Named
+// structs become components referred to by name.
+type schemaBuilder struct {
+	components map[string]any
+	names      map[reflect.Type]string
+	taken      map[string]bool
+}
+
+func newSchemaBuilder() *schemaBuilder {
+	return &schemaBuilder{components: make(map[string]any), names: make(map[reflect.Type]string), taken: make(map[string]bool)}
+}
+
+// schema returns the schema of values of t
+func (b *schemaBuilder) schema(t reflect.Type) map[string]any {
+	if t == timeType {
+		return map[string]any{"type": "string", "format": "date-time"}
+	}
+	if t.Kind() != reflect.Pointer && marshalsItself(t) {
+		// Such as webrtc.SDPType, an integer encoded as its name
+		if t.Kind() == reflect.Struct {
+			return map[string]any{}
+		}
+		return map[string]any{"type": "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return b.schema(t.Elem())
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16:
+		return map[string]any{"type": "integer", "format": "int32"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64:
+		// time.Duration is encoded as nanoseconds
+		return map[string]any{"type": "integer", "format": "int64"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]any{"type": "string", "format": "byte"}
+		}
+		return map[string]any{"type": "array", "items": b.schema(t.Elem())}
+	case reflect.Array:
+		return map[string]any{"type": "array", "items": b.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": b.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return b.object(t)
+		}
+		return map[string]any{"$ref": "#/components/schemas/" + b.component(t)}
+	}
+	// Interfaces hold any value
+	return map[string]any{}
+}
+
+// marshalsItself reports whether values of t are encoded by their own MarshalJSON or MarshalText
+func marshalsItself(t reflect.Type) bool {
+	return t.Implements(jsonMarshalerType) || t.Implements(textMarshalerType) ||
+		reflect.PointerTo(t).Implements(jsonMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType)
+}
+
+// component adds the schema of the named struct t to the components, returning its name
+func (b *schemaBuilder) component(t reflect.Type) string {
+	if name, ok := b.names[t]; ok {
+		return name
+	}
+	name := t.Name()
+	if b.taken[name] {
+		// Types of different packages may share a name
+		name = path.Base(t.PkgPath()) + "." + name
+	}
+	b.names[t] = name
+	b.taken[name] = true
+	// Named before its fields are, so recursive types refer to themselves
+	b.components[name] = b.object(t)
+	return name
+}
+
+// object returns the schema of the fields of struct t
+func (b *schemaBuilder) object(t reflect.Type) map[string]any {
+	properties := make(map[string]any)
+	b.addFields(t, properties)
+	return map[string]any{"type": "object", "properties": properties}
+}
+
+// addFields adds the encoded fields of struct t to properties, those of embedded structs included
+func (b *schemaBuilder) addFields(t reflect.Type, properties map[string]any) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" || (!field.IsExported() && !field.Anonymous) {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+		fieldType := field.Type
+		if field.Anonymous && name == "" {
+			if fieldType.Kind() == reflect.Pointer {
+				fieldType = fieldType.Elem()
+			}
+			if fieldType.Kind() == reflect.Struct {
+				b.addFields(fieldType, properties)
+				continue
+			}
+			if !field.IsExported() {
+				continue
+			}
+		}
+		if name == "" {
+			name = field.Name
+		}
+		if strings.Contains(options, "string") {
+			properties[name] = map[string]any{"type": "string"}
+			continue
+		}
+		properties[name] = b.schema(fieldType)
+	}
+}
@@ -0,0 +1,50 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/rescp17/lanFileSharer/internal/app"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDocsHandler(t *testing.T) {
+	handler := NewAPI(make(chan tea.Msg, 1), app.NewSingleRequestManager(), nil)
+
+	request := httptest.NewRequest("GET", DocsPath, nil)
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var doc struct {
+		OpenAPI string                               `json:"openapi"`
+		Paths   map[string]map[string]map[string]any `json:"paths"`
+		Schemas struct {
+			Schemas map[string]struct {
+				Properties map[string]map[string]any `json:"properties"`
+			} `json:"schemas"`
+		} `json:"components"`
+	}
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &doc))
+	assert.Equal(t, "3.0.3", doc.OpenAPI)
+
+	for _, r := range handler.routes() {
+		method, path, _ := strings.Cut(r.pattern, " ")
+		assert.Contains(t, doc.Paths[path], strings.ToLower(method), "every served route is documented")
+	}
+	assert.Contains(t, doc.Paths["/resume/{token}"]["get"], "parameters")
+
+	ask := doc.Schemas.Schemas["AskPayload"]
+	assert.Equal(t, map[string]any{"type": "string"}, ask.Properties["guest_token"])
+	assert.Equal(t, "#/components/schemas/SignedFileStructure", ask.Properties["signed_files"]["$ref"])
+	assert.Equal(t, "#/components/schemas/FileNode", doc.Schemas.Schemas["FileNode"].Properties["children"]["items"].(map[string]any)["$ref"],
+		"recursive types refer to themselves")
+	assert.NotContains(t, doc.Schemas.Schemas["FileNode"].Properties, "Path", "fields encoding/json skips are left out")
+	assert.Equal(t, map[string]any{"type": "string"}, doc.Schemas.Schemas["SessionDescription"].Properties["type"],
+		"types encoding themselves are strings")
+}
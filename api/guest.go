@@ -0,0 +1,108 @@
+package api
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rescp17/lanFileSharer/internal/util"
+)
+
+const (
+	// GuestPassLifetime is how long a guest pass is accepted once issued
+	GuestPassLifetime = 15 * time.Minute
+	// guestTokenAlphabet leaves out letters and digits that are easily mistaken for each other,
+	// as guest tokens are read off the receiver's screen and typed on the sender
+	guestTokenAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+	// guestTokenLength is the number of characters of a guest token, shown in two groups of four
+	guestTokenLength = 8
+)
+
+// ErrGuestPass is returned by takeGuestPass for tokens that are unknown, used or expired
+var ErrGuestPass = errors.New("the guest token is unknown, used or expired")
+
+// GuestPass accepts a single transfer from any sender giving its token, without asking and
+// without trusting the sender's key
+type GuestPass struct {
+	Token    string // Shown as XXXX-XXXX; compared without case and separators
+	MaxBytes int64  // Largest transfer accepted with the pass, zero for any size
+	Expires  time.Time
+}
+
+// IssueGuestPass creates a guest pass accepting one transfer of at most maxBytes, zero for
+// any size, until it expires after lifetime. Passes issued before stay valid.
+func (a *API) IssueGuestPass(maxBytes int64, lifetime time.Duration) (GuestPass, error) {
+	token, err := newGuestToken()
+	if err != nil {
+		return GuestPass{}, err
+	}
+	pass := GuestPass{Token: token, MaxBytes: maxBytes, Expires: time.Now().Add(lifetime)}
+
+	a.server.pullsMu.Lock()
+	defer a.server.pullsMu.Unlock()
+	if a.server.guests == nil {
+		a.server.guests = make(map[string]GuestPass)
+	}
+	now := time.Now()
+	for t, guest := range a.server.guests {
+		if now.After(guest.Expires) {
+			delete(a.server.guests, t)
+		}
+	}
+	a.server.guests[normalizeGuestToken(token)] = pass
+	return pass, nil
+}
+
+// newGuestToken returns a random token of guestTokenAlphabet, grouped as XXXX-XXXX
+func newGuestToken() (string, error) {
+	b := make([]byte, guestTokenLength)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate guest token: %w", err)
+	}
+	var token strings.Builder
+	for i, c := range b {
+		if i == guestTokenLength/2 {
+			token.WriteByte('-')
+		}
+		// 256 is a multiple of the alphabet's 32 characters, so every one is as likely
+		token.WriteByte(guestTokenAlphabet[int(c)%len(guestTokenAlphabet)])
+	}
+	return token.String(), nil
+}
+
+// normalizeGuestToken drops the separators and case a user may have typed a token with
+func normalizeGuestToken(token string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, strings.ToUpper(strings.TrimSpace(token)))
+}
+
+// takeGuestPass uses up the guest pass of token for a transfer of size bytes, or of
+// fileInfo.UnknownSize when the size of some of its files is unknown. A transfer over the
+// pass's size cap, or of unknown size under a cap, is refused with a reason for the sender,
+// keeping the pass for a smaller one; unknown, used and expired tokens return ErrGuestPass.
+func (s *ReceiverService) takeGuestPass(token string, size int64) error {
+	key := normalizeGuestToken(token)
+	s.pullsMu.Lock()
+	defer s.pullsMu.Unlock()
+	pass, ok := s.guests[key]
+	if !ok || time.Now().After(pass.Expires) {
+		delete(s.guests, key)
+		return ErrGuestPass
+	}
+	if pass.MaxBytes > 0 && size < 0 {
+		return fmt.Errorf("the guest token allows at most %s, and the size of the transfer is unknown",
+			util.FormatSize(pass.MaxBytes))
+	}
+	if pass.MaxBytes > 0 && size > pass.MaxBytes {
+		return fmt.Errorf("the transfer of %s is larger than the %s the guest token allows",
+			util.FormatSize(size), util.FormatSize(pass.MaxBytes))
+	}
+	delete(s.guests, key)
+	return nil
+}
@@ -0,0 +1,58 @@
+package api
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/rescp17/lanFileSharer/internal/app"
+	"github.com/rescp17/lanFileSharer/pkg/fileInfo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGuestPass(t *testing.T) {
+	handler := NewAPI(make(chan tea.Msg, 1), app.NewSingleRequestManager(), nil)
+	pass, err := handler.IssueGuestPass(100, time.Minute)
+	require.NoError(t, err)
+	assert.Regexp(t, `^[A-Z2-9]{4}-[A-Z2-9]{4}$`, pass.Token)
+
+	assert.ErrorIs(t, handler.server.takeGuestPass("ABCD-EFGH", 10), ErrGuestPass)
+	err = handler.server.takeGuestPass(pass.Token, 101)
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, ErrGuestPass, "transfers over the cap keep the pass")
+	err = handler.server.takeGuestPass(pass.Token, fileInfo.UnknownSize)
+	require.Error(t, err, "a capped pass refuses transfers of unknown size")
+	assert.NotErrorIs(t, err, ErrGuestPass)
+
+	typed := strings.ToLower(strings.ReplaceAll(pass.Token, "-", " "))
+	assert.NoError(t, handler.server.takeGuestPass(typed, 100), "tokens are typed without case and separators")
+	assert.ErrorIs(t, handler.server.takeGuestPass(pass.Token, 10), ErrGuestPass, "passes are used once")
+
+	expired, err := handler.IssueGuestPass(0, -time.Second)
+	require.NoError(t, err)
+	assert.ErrorIs(t, handler.server.takeGuestPass(expired.Token, 10), ErrGuestPass)
+}
+
+func TestGuestPass_AnySize(t *testing.T) {
+	handler := NewAPI(make(chan tea.Msg, 1), app.NewSingleRequestManager(), nil)
+	pass, err := handler.IssueGuestPass(0, time.Minute)
+	require.NoError(t, err)
+	assert.NoError(t, handler.server.takeGuestPass(pass.Token, fileInfo.UnknownSize), "a pass without a cap takes streams")
+}
+
+func TestSizeUnknown(t *testing.T) {
+	file := fileInfo.FileNode{Name: "a.txt", Size: 100}
+	stream := fileInfo.FileNode{Name: "stdin", Size: fileInfo.UnknownSize}
+	lying := fileInfo.FileNode{Name: "b.txt", Size: -5000}
+	dir := func(children ...fileInfo.FileNode) fileInfo.FileNode {
+		// Directories may claim any size, only their files count
+		return fileInfo.FileNode{Name: "dir", IsDir: true, Size: 1, Children: children}
+	}
+
+	assert.False(t, sizeUnknown([]fileInfo.FileNode{file, dir(file)}))
+	assert.True(t, sizeUnknown([]fileInfo.FileNode{file, stream}))
+	assert.True(t, sizeUnknown([]fileInfo.FileNode{dir(file, dir(lying))}), "negative sizes inside directories")
+	assert.Equal(t, int64(300), filesSize([]fileInfo.FileNode{file, dir(file, lying, dir(file))}))
+}
@@ -0,0 +1,52 @@
+package api
+
+import (
+	"time"
+)
+
+// idleResumeLifetime is how long a session that went idle may be resumed without asking,
+// long enough for a laptop to be closed over lunch
+const idleResumeLifetime = time.Hour
+
+// idleSession is a session that went idle before it completed
+type idleSession struct {
+	fingerprint string    // Key that signed the offer of the session
+	expires     time.Time // When it may no longer be resumed without asking
+}
+
+// ExpectResume makes this receiver accept the offer resuming the session with token without
+// asking the user, once, if the offer is signed by the key with fingerprint. The session went
+// idle before it completed, so the sender resumes it once it is back.
+func (a *API) ExpectResume(token, fingerprint string) {
+	if token == "" || fingerprint == "" {
+		return
+	}
+	a.server.pullsMu.Lock()
+	defer a.server.pullsMu.Unlock()
+	if a.server.idle == nil {
+		a.server.idle = make(map[string]idleSession)
+	}
+	now := time.Now()
+	for t, session := range a.server.idle {
+		if now.After(session.expires) {
+			delete(a.server.idle, t)
+		}
+	}
+	a.server.idle[token] = idleSession{fingerprint: fingerprint, expires: now.Add(idleResumeLifetime)}
+}
+
+// takeResume reports whether token resumes a session that went idle, signed by the same key as
+// its offer, which it then forgets
+func (s *ReceiverService) takeResume(token, fingerprint string) bool {
+	if token == "" {
+		return false
+	}
+	s.pullsMu.Lock()
+	defer s.pullsMu.Unlock()
+	session, ok := s.idle[token]
+	if !ok || session.fingerprint != fingerprint {
+		return false
+	}
+	delete(s.idle, token)
+	return time.Now().Before(session.expires)
+}
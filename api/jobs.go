@@ -0,0 +1,77 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+
+	"github.com/rescp17/lanFileSharer/pkg/jobs"
+)
+
+// JobStatusFunc reports the scheduled jobs the daemon runs
+type JobStatusFunc func() ([]jobs.Status, error)
+
+// SetJobStatus enables GET /jobs, the control API of the scheduled jobs. It only answers
+// requests from this machine, since jobs name local directories.
+func (a *API) SetJobStatus(status JobStatusFunc) {
+	a.server.jobStatus = status
+}
+
+// JobsHandler serves the status of the scheduled jobs to local clients.
+func (s *ReceiverService) JobsHandler(w http.ResponseWriter, r *http.Request) {
+	if s.jobStatus == nil {
+		http.Error(w, "Jobs not supported", http.StatusNotFound)
+		return
+	}
+	if !isLoopback(r.RemoteAddr) {
+		slog.Warn("Refused job status request from another machine", "addr", r.RemoteAddr)
+		writeForbidden(w)
+		return
+	}
+	statuses, err := s.jobStatus()
+	if err != nil {
+		slog.Error("Failed to read job status", "error", err)
+		http.Error(w, "Failed to read jobs", http.StatusInternalServerError)
+		return
+	}
+	if statuses == nil {
+		statuses = []jobs.Status{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(statuses); err != nil {
+		slog.Error("Failed to encode job status", "error", err)
+	}
+}
+
+// isLoopback reports whether addr, a host:port, is a loopback address
+func isLoopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// JobStatus asks the daemon at receiverURL, which must be on this machine, for the status of its jobs.
+func (c *Client) JobStatus(ctx context.Context, receiverURL string) ([]jobs.Status, error) {
+	endpoint, err := url.JoinPath(receiverURL, "jobs")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create jobs url: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create jobs request: %w", err)
+	}
+
+	var statuses []jobs.Status
+	if err := c.doJSON(req, &statuses); err != nil {
+		return nil, fmt.Errorf("job status failed: %w", err)
+	}
+	return statuses, nil
+}
@@ -0,0 +1,46 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/rescp17/lanFileSharer/internal/app"
+	"github.com/rescp17/lanFileSharer/pkg/jobs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJobsHandler(t *testing.T) {
+	handler := NewAPI(make(chan tea.Msg, 1), app.NewSingleRequestManager(), nil)
+
+	request := httptest.NewRequest("GET", "/jobs", nil)
+	request.RemoteAddr = "127.0.0.1:50000"
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	assert.Equal(t, http.StatusNotFound, recorder.Code, "no daemon runs jobs")
+
+	next := time.Date(2024, 5, 16, 2, 0, 0, 0, time.UTC)
+	handler.SetJobStatus(func() ([]jobs.Status, error) {
+		return []jobs.Status{{Job: jobs.Job{ID: "a1", Peer: "nas", Cron: "0 2 * * *"}, Next: next}}, nil
+	})
+
+	request = httptest.NewRequest("GET", "/jobs", nil)
+	request.RemoteAddr = "192.168.1.20:50000"
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	assert.Equal(t, http.StatusForbidden, recorder.Code, "other machines are refused")
+
+	server := httptest.NewServer(handler)
+	defer server.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	statuses, err := NewClient("test-service-id").JobStatus(ctx, server.URL)
+	require.NoError(t, err)
+	require.Len(t, statuses, 1)
+	assert.Equal(t, "a1", statuses[0].Job.ID)
+	assert.True(t, next.Equal(statuses[0].Next))
+}
@@ -0,0 +1,151 @@
+package api
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/rescp17/lanFileSharer/internal/util"
+	"github.com/rescp17/lanFileSharer/pkg/audit"
+)
+
+// PeerFilter decides which peers may reach the receiver, by the subnet of their address and
+// the fingerprint of their signing key. A deny rule always wins over an allow rule.
+// An allowlist that was configured stays in force even if none of its entries are valid,
+// so a typo locks peers out rather than letting everyone in.
+type PeerFilter struct {
+	allowSubnets      []*net.IPNet
+	denySubnets       []*net.IPNet
+	allowFingerprints []string
+	denyFingerprints  []string
+	restrictSubnets   bool // An allowlist of subnets was configured
+	restrictKeys      bool // An allowlist of fingerprints was configured
+	lanOnly           bool // Only private and link-local addresses are allowed
+}
+
+// PeerFilterRules lists the CIDR ranges and key fingerprints of a PeerFilter
+type PeerFilterRules struct {
+	AllowSubnets      []string
+	DenySubnets       []string
+	AllowFingerprints []string
+	DenyFingerprints  []string
+	LANOnly           bool // Refuse every address outside the private and link-local ranges
+}
+
+// NewPeerFilter builds a filter from rules, returning nil if there are none. Invalid
+// entries are skipped with a warning.
+func NewPeerFilter(rules PeerFilterRules) *PeerFilter {
+	if len(rules.AllowSubnets)+len(rules.DenySubnets)+len(rules.AllowFingerprints)+len(rules.DenyFingerprints) == 0 && !rules.LANOnly {
+		return nil
+	}
+	return &PeerFilter{
+		allowSubnets:      parseSubnets(rules.AllowSubnets),
+		denySubnets:       parseSubnets(rules.DenySubnets),
+		allowFingerprints: normalizeFingerprints(rules.AllowFingerprints),
+		denyFingerprints:  normalizeFingerprints(rules.DenyFingerprints),
+		restrictSubnets:   len(rules.AllowSubnets) > 0,
+		restrictKeys:      len(rules.AllowFingerprints) > 0,
+		lanOnly:           rules.LANOnly,
+	}
+}
+
+// parseSubnets parses CIDR ranges; a bare address is taken as a single host
+func parseSubnets(entries []string) []*net.IPNet {
+	subnets := make([]*net.IPNet, 0, len(entries))
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if ip := net.ParseIP(entry); ip != nil {
+			bits := 128
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
+			}
+			subnets = append(subnets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, subnet, err := net.ParseCIDR(entry)
+		if err != nil {
+			slog.Warn("Ignoring invalid subnet in peer filter", "subnet", entry, "error", err)
+			continue
+		}
+		subnets = append(subnets, subnet)
+	}
+	return subnets
+}
+
+// normalizeFingerprints lowercases fingerprints and drops the separators people paste them with
+func normalizeFingerprints(entries []string) []string {
+	fingerprints := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		fingerprints = append(fingerprints, normalizeFingerprint(entry))
+	}
+	return fingerprints
+}
+
+func normalizeFingerprint(fingerprint string) string {
+	return strings.ToLower(strings.NewReplacer(":", "", " ", "", "-", "").Replace(fingerprint))
+}
+
+// AllowsAddr reports whether a peer at ip may connect. A nil filter allows everyone.
+func (f *PeerFilter) AllowsAddr(ip net.IP) bool {
+	if f == nil {
+		return true
+	}
+	if ip == nil {
+		return !f.restrictSubnets && len(f.denySubnets) == 0 && !f.lanOnly
+	}
+	if f.lanOnly && !util.IsLANAddress(ip) {
+		return false
+	}
+	contains := func(subnet *net.IPNet) bool { return subnet.Contains(ip) }
+	if slices.ContainsFunc(f.denySubnets, contains) {
+		return false
+	}
+	return !f.restrictSubnets || slices.ContainsFunc(f.allowSubnets, contains)
+}
+
+// AllowsFingerprint reports whether a peer signing with the key of fingerprint may send. A nil filter allows everyone.
+func (f *PeerFilter) AllowsFingerprint(fingerprint string) bool {
+	if f == nil {
+		return true
+	}
+	fingerprint = normalizeFingerprint(fingerprint)
+	if slices.Contains(f.denyFingerprints, fingerprint) {
+		return false
+	}
+	return !f.restrictKeys || slices.Contains(f.allowFingerprints, fingerprint)
+}
+
+// remoteIP returns the address of the peer that sent r, or nil if it cannot be parsed
+func remoteIP(r *http.Request) net.IP {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return net.ParseIP(host)
+}
+
+// PeerFilterMiddleware refuses requests from addresses the peer filter does not allow,
+// before they reach any handler or the user
+func (s *ReceiverService) PeerFilterMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ip := remoteIP(r); !s.peerFilter.AllowsAddr(ip) {
+			slog.Warn("Refused request from filtered peer", "addr", r.RemoteAddr, "path", r.URL.Path)
+			s.audit(audit.Record{Event: audit.EventRefused, Peer: r.RemoteAddr, Detail: "address filtered: " + r.URL.Path})
+			writeForbidden(w)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// writeForbidden answers a filtered peer without telling it which rule matched
+func writeForbidden(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusForbidden)
+	if err := json.NewEncoder(w).Encode(map[string]string{"error": "forbidden"}); err != nil {
+		slog.Error("Failed to encode forbidden response", "error", err)
+	}
+}
@@ -0,0 +1,93 @@
+package api
+
+import (
+	"context"
+	"net"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rescp17/lanFileSharer/internal/app"
+	"github.com/rescp17/lanFileSharer/pkg/crypto"
+)
+
+func TestPeerFilter_Addr(t *testing.T) {
+	assert.Nil(t, NewPeerFilter(PeerFilterRules{}), "no rules, no filter")
+	var none *PeerFilter
+	assert.True(t, none.AllowsAddr(net.ParseIP("203.0.113.7")))
+
+	filter := NewPeerFilter(PeerFilterRules{
+		AllowSubnets: []string{"192.168.10.0/24", "fd00::/8"},
+		DenySubnets:  []string{"192.168.10.66"},
+	})
+	assert.True(t, filter.AllowsAddr(net.ParseIP("192.168.10.5")))
+	assert.True(t, filter.AllowsAddr(net.ParseIP("::ffff:192.168.10.5")), "IPv4-mapped addresses match IPv4 ranges")
+	assert.True(t, filter.AllowsAddr(net.ParseIP("fd12::1")))
+	assert.False(t, filter.AllowsAddr(net.ParseIP("192.168.10.66")), "deny wins over allow")
+	assert.False(t, filter.AllowsAddr(net.ParseIP("10.0.0.1")))
+	assert.False(t, filter.AllowsAddr(nil))
+
+	denyOnly := NewPeerFilter(PeerFilterRules{DenySubnets: []string{"10.0.0.0/8"}})
+	assert.True(t, denyOnly.AllowsAddr(net.ParseIP("192.168.1.1")))
+	assert.False(t, denyOnly.AllowsAddr(net.ParseIP("10.1.2.3")))
+}
+
+func TestPeerFilter_LANOnly(t *testing.T) {
+	filter := NewPeerFilter(PeerFilterRules{LANOnly: true})
+	require.NotNil(t, filter)
+	assert.True(t, filter.AllowsAddr(net.ParseIP("192.168.1.20")))
+	assert.True(t, filter.AllowsAddr(net.ParseIP("fe80::1")))
+	assert.False(t, filter.AllowsAddr(net.ParseIP("203.0.113.7")))
+	assert.False(t, filter.AllowsAddr(nil))
+
+	withDeny := NewPeerFilter(PeerFilterRules{LANOnly: true, DenySubnets: []string{"192.168.1.66"}})
+	assert.False(t, withDeny.AllowsAddr(net.ParseIP("192.168.1.66")))
+}
+
+func TestPeerFilter_InvalidAllowlistFailsClosed(t *testing.T) {
+	filter := NewPeerFilter(PeerFilterRules{AllowSubnets: []string{"192.168.10.0/33"}})
+	assert.False(t, filter.AllowsAddr(net.ParseIP("192.168.10.5")))
+}
+
+func TestPeerFilter_Fingerprint(t *testing.T) {
+	filter := NewPeerFilter(PeerFilterRules{
+		AllowFingerprints: []string{"AB:CD:EF:01", "1234"},
+		DenyFingerprints:  []string{"1234"},
+	})
+	assert.True(t, filter.AllowsFingerprint("abcdef01"))
+	assert.False(t, filter.AllowsFingerprint("1234"), "deny wins over allow")
+	assert.False(t, filter.AllowsFingerprint("ffff"))
+
+	subnetsOnly := NewPeerFilter(PeerFilterRules{AllowSubnets: []string{"127.0.0.0/8"}})
+	assert.True(t, subnetsOnly.AllowsFingerprint("ffff"))
+}
+
+func TestPeerFilter_RefusesRequests(t *testing.T) {
+	uiMessages := make(chan tea.Msg, 10)
+	handler := NewAPI(uiMessages, app.NewSingleRequestManager(), nil)
+	server := httptest.NewServer(handler)
+	defer server.Close()
+	client := NewClient("test-service-id")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	handler.SetPeerFilter(NewPeerFilter(PeerFilterRules{AllowSubnets: []string{"192.0.2.0/24"}}))
+	_, err := client.Ping(ctx, server.URL)
+	assert.ErrorContains(t, err, "403", "requests from outside the allowed subnets are refused")
+
+	signedFiles := createTestSignedFiles(t)
+	handler.SetPeerFilter(NewPeerFilter(PeerFilterRules{
+		DenyFingerprints: []string{crypto.PublicKeyFingerprint(signedFiles.PublicKey)},
+	}))
+	_, err = client.Ping(ctx, server.URL)
+	require.NoError(t, err)
+
+	signaler := NewAPISignaler(client, server.URL, mockAddICECandidate)
+	err = signaler.SendOffer(ctx, createTestOffer(), signedFiles)
+	assert.ErrorContains(t, err, "403")
+	assert.Empty(t, uiMessages, "the user is not asked about a denied key")
+}
@@ -0,0 +1,152 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/pion/webrtc/v4"
+	"github.com/rescp17/lanFileSharer/pkg/transfer"
+)
+
+// PingResponse is the body of GET /ping.
+type PingResponse struct {
+	Time         int64  `json:"time"`                   // Receiver clock in Unix milliseconds
+	Busy         bool   `json:"busy"`                   // A transfer request is pending or running
+	Echo         bool   `json:"echo"`                   // POST /echo is available
+	Availability string `json:"availability,omitempty"` // Whether requests are put to the user
+}
+
+// EchoPayload carries the offer and the answer of POST /echo.
+// Both are complete descriptions, so no candidates are exchanged separately.
+type EchoPayload struct {
+	Offer  *webrtc.SessionDescription `json:"offer,omitempty"`
+	Answer *webrtc.SessionDescription `json:"answer,omitempty"`
+}
+
+// EchoFunc answers an echo offer with a complete description and echoes the data
+// channel messages of the resulting connection until it closes, which closes the
+// returned channel.
+type EchoFunc func(ctx context.Context, offer webrtc.SessionDescription) (*webrtc.SessionDescription, <-chan struct{}, error)
+
+// PingHandler answers health checks without asking the user.
+func (s *ReceiverService) PingHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(PingResponse{
+		Time:         time.Now().UnixMilli(),
+		Busy:         s.stateManager.HasActiveRequest(),
+		Echo:         s.echo != nil,
+		Availability: s.Availability().String(),
+	}); err != nil {
+		slog.Error("Failed to encode ping response", "error", err)
+	}
+}
+
+// EchoHandler answers an echo offer, one session at a time.
+func (s *ReceiverService) EchoHandler(w http.ResponseWriter, r *http.Request) {
+	if s.echo == nil {
+		http.Error(w, "Echo not supported", http.StatusNotFound)
+		return
+	}
+	var req EchoPayload
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Offer == nil {
+		http.Error(w, "Invalid echo payload", http.StatusBadRequest)
+		return
+	}
+	if !s.echoBusy.CompareAndSwap(false, true) {
+		http.Error(w, "Echo session already running", http.StatusServiceUnavailable)
+		return
+	}
+
+	// The echo session outlives this request, so it is not bound to its context
+	answer, closed, err := s.echo(context.WithoutCancel(r.Context()), *req.Offer)
+	if err != nil {
+		s.echoBusy.Store(false)
+		slog.Error("Failed to answer echo offer", "error", err)
+		http.Error(w, "Failed to answer echo offer", http.StatusInternalServerError)
+		return
+	}
+
+	// The next session may start once this one's connection is closed
+	go func() {
+		<-closed
+		s.echoBusy.Store(false)
+	}()
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(EchoPayload{Answer: answer}); err != nil {
+		slog.Error("Failed to encode echo answer", "error", err)
+	}
+}
+
+// Ping performs a signaling round trip to the receiver.
+func (c *Client) Ping(ctx context.Context, receiverURL string) (*PingResponse, error) {
+	endpoint, err := url.JoinPath(receiverURL, "ping")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create ping url: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create ping request: %w", err)
+	}
+
+	var ping PingResponse
+	if err := c.doJSON(req, &ping); err != nil {
+		return nil, fmt.Errorf("ping failed: %w", err)
+	}
+	return &ping, nil
+}
+
+// Echo sends a complete offer to the receiver's echo endpoint and returns its answer.
+func (c *Client) Echo(ctx context.Context, receiverURL string, offer webrtc.SessionDescription) (*webrtc.SessionDescription, error) {
+	endpoint, err := url.JoinPath(receiverURL, "echo")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create echo url: %w", err)
+	}
+	body, err := json.Marshal(EchoPayload{Offer: &offer})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal echo payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create echo request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	var resp EchoPayload
+	if err := c.doJSON(req, &resp); err != nil {
+		return nil, fmt.Errorf("echo failed: %w", err)
+	}
+	if resp.Answer == nil {
+		return nil, fmt.Errorf("echo failed: no answer in response")
+	}
+	return resp.Answer, nil
+}
+
+// doJSON sends req and decodes a 200 OK JSON response into out.
+func (c *Client) doJSON(req *http.Request, out any) error {
+	resp, err := c.HttpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %w", transfer.ErrPeerUnreachable, err)
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			slog.Warn("failed to close response body", "error", err)
+		}
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("receiver responded with non-OK status: %s", resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+package api
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/pbkdf2"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rescp17/lanFileSharer/pkg/audit"
+)
+
+const (
+	// pskHeader carries the timestamp and MAC that prove a request comes from a holder of the PSK
+	pskHeader = "X-PSK-Auth"
+	// pskSalt separates PSK keys from other uses of the same passphrase. It is fixed, as both
+	// sides must derive the same key without exchanging anything first.
+	pskSalt = "lanFileSharer pre-shared key v1"
+	// pskIterations is the PBKDF2-SHA256 work factor, slowing guesses at a passphrase from captured MACs
+	pskIterations = 600_000
+	// pskMaxSkew is how far the clocks of the two machines may be apart
+	pskMaxSkew = 5 * time.Minute
+	// pskMaxBodySize bounds signed request bodies, which are read whole to check their MAC
+	pskMaxBodySize = 32 << 20
+)
+
+// ErrPSKMismatch is returned when a peer does not prove knowledge of the pre-shared key
+var ErrPSKMismatch = errors.New("the peer does not know the pre-shared passphrase")
+
+// PSK is a key derived from a passphrase both peers entered. The sender signs every request
+// with it and the receiver signs its answer, whose DTLS fingerprint then authenticates the
+// encrypted session, so a machine without the passphrase can neither send nor intercept.
+// A nil PSK signs and checks nothing.
+type PSK struct {
+	key []byte
+}
+
+// NewPSK derives the key for passphrase
+func NewPSK(passphrase string) (*PSK, error) {
+	if passphrase == "" {
+		return nil, errors.New("the pre-shared passphrase must not be empty")
+	}
+	key, err := pbkdf2.Key(sha256.New, passphrase, []byte(pskSalt), pskIterations, 32)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive pre-shared key: %w", err)
+	}
+	return &PSK{key: key}, nil
+}
+
+// mac returns the MAC of the parts, separated so that they cannot be shifted into each other
+func (p *PSK) mac(parts ...string) string {
+	h := hmac.New(sha256.New, p.key)
+	for _, part := range parts {
+		fmt.Fprintf(h, "%d:%s", len(part), part)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// requestMAC returns the MAC of a request for path and its raw query at timestamp with body
+func (p *PSK) requestMAC(method, path, query, timestamp string, body []byte) string {
+	sum := sha256.Sum256(body)
+	return p.mac("request", method, path, query, timestamp, hex.EncodeToString(sum[:]))
+}
+
+// signRequest adds the PSK header to req, reading its body to cover it too
+func (p *PSK) signRequest(req *http.Request) error {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		if body, err = io.ReadAll(req.Body); err != nil {
+			return fmt.Errorf("failed to read request body: %w", err)
+		}
+		if err := req.Body.Close(); err != nil {
+			return fmt.Errorf("failed to close request body: %w", err)
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set(pskHeader, timestamp+"."+p.requestMAC(req.Method, req.URL.Path, req.URL.RawQuery, timestamp, body))
+	return nil
+}
+
+// verifyRequest checks the PSK header of r, leaving its body readable
+func (p *PSK) verifyRequest(r *http.Request, now time.Time) error {
+	timestamp, mac, ok := strings.Cut(r.Header.Get(pskHeader), ".")
+	if !ok {
+		return fmt.Errorf("%w: the request is not signed", ErrPSKMismatch)
+	}
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: invalid timestamp", ErrPSKMismatch)
+	}
+	if skew := now.Sub(time.Unix(seconds, 0)); skew > pskMaxSkew || skew < -pskMaxSkew {
+		return fmt.Errorf("%w: the request was signed %s away from now", ErrPSKMismatch, skew.Round(time.Second))
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read request body: %w", err)
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	if !hmac.Equal([]byte(mac), []byte(p.requestMAC(r.Method, r.URL.Path, r.URL.RawQuery, timestamp, body))) {
+		return ErrPSKMismatch
+	}
+	return nil
+}
+
+// answerMAC returns the MAC the receiver sends with the SDP of its answer
+func (p *PSK) answerMAC(sdp string) string {
+	return p.mac("answer", sdp)
+}
+
+// checkAnswer verifies the MAC the receiver sent with its answer. A nil PSK accepts any answer.
+func (p *PSK) checkAnswer(sdp, mac string) error {
+	if p == nil {
+		return nil
+	}
+	if !hmac.Equal([]byte(mac), []byte(p.answerMAC(sdp))) {
+		return fmt.Errorf("%w: the answer is not signed with it", ErrPSKMismatch)
+	}
+	return nil
+}
+
+// pskSigner is an http.RoundTripper that signs each request with a PSK
+type pskSigner struct {
+	psk  *PSK
+	next http.RoundTripper
+}
+
+// RoundTrip signs a copy of req and passes it to the next transport
+func (t *pskSigner) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	if err := t.psk.signRequest(req); err != nil {
+		return nil, err
+	}
+	return t.next.RoundTrip(req)
+}
+
+// PSKMiddleware refuses requests that are not signed with the pre-shared key, if one is set
+func (s *ReceiverService) PSKMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.psk != nil {
+			r.Body = http.MaxBytesReader(w, r.Body, pskMaxBodySize)
+			if err := s.psk.verifyRequest(r, time.Now()); err != nil {
+				slog.Warn("Refused request without the pre-shared key", "addr", r.RemoteAddr, "path", r.URL.Path, "error", err)
+				s.audit(audit.Record{Event: audit.EventRefused, Peer: r.RemoteAddr, Detail: "pre-shared key mismatch: " + r.URL.Path})
+				writeForbidden(w)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,98 @@
+package api
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPSK_SignedRequests(t *testing.T) {
+	psk, err := NewPSK("two laptops")
+	require.NoError(t, err)
+	other, err := NewPSK("another passphrase")
+	require.NoError(t, err)
+
+	newRequest := func() *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "/ask", bytes.NewBufferString(`{"offer":1}`))
+		require.NoError(t, psk.signRequest(req))
+		return req
+	}
+
+	req := newRequest()
+	require.NoError(t, psk.verifyRequest(req, time.Now()))
+	body, err := io.ReadAll(req.Body)
+	require.NoError(t, err)
+	assert.Equal(t, `{"offer":1}`, string(body), "the body stays readable for the handler")
+
+	assert.ErrorIs(t, other.verifyRequest(newRequest(), time.Now()), ErrPSKMismatch)
+	assert.ErrorIs(t, psk.verifyRequest(newRequest(), time.Now().Add(time.Hour)), ErrPSKMismatch, "old signatures expire")
+
+	tampered := newRequest()
+	tampered.Body = io.NopCloser(bytes.NewBufferString(`{"offer":2}`))
+	assert.ErrorIs(t, psk.verifyRequest(tampered, time.Now()), ErrPSKMismatch)
+
+	unsigned := httptest.NewRequest(http.MethodPost, "/ask", nil)
+	assert.ErrorIs(t, psk.verifyRequest(unsigned, time.Now()), ErrPSKMismatch)
+
+	// A captured request cannot be replayed for another query
+	preview := httptest.NewRequest(http.MethodGet, "/share/preview?path=notes.txt", nil)
+	require.NoError(t, psk.signRequest(preview))
+	replayed := httptest.NewRequest(http.MethodGet, "/share/preview?path=secret.txt", nil)
+	replayed.Header.Set(pskHeader, preview.Header.Get(pskHeader))
+	require.NoError(t, psk.verifyRequest(preview, time.Now()))
+	assert.ErrorIs(t, psk.verifyRequest(replayed, time.Now()), ErrPSKMismatch)
+}
+
+func TestPSKMiddleware_BoundsBody(t *testing.T) {
+	psk, err := NewPSK("two laptops")
+	require.NoError(t, err)
+	s := &ReceiverService{psk: psk}
+	handler := s.PSKMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/ask", bytes.NewBufferString(`{"offer":1}`))
+	require.NoError(t, psk.signRequest(req))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+
+	// The body is not buffered past the limit, whatever its signature
+	large := httptest.NewRequest(http.MethodPost, "/upload", io.LimitReader(zeroReader{}, pskMaxBodySize+1))
+	large.Header.Set(pskHeader, req.Header.Get(pskHeader))
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, large)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+}
+
+// zeroReader reads endless zeros
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
+
+func TestPSK_Answer(t *testing.T) {
+	psk, err := NewPSK("two laptops")
+	require.NoError(t, err)
+	other, err := NewPSK("another passphrase")
+	require.NoError(t, err)
+
+	sdp := "v=0\r\na=fingerprint:sha-256 AB:CD\r\n"
+	assert.NoError(t, psk.checkAnswer(sdp, psk.answerMAC(sdp)))
+	assert.ErrorIs(t, psk.checkAnswer(sdp, other.answerMAC(sdp)), ErrPSKMismatch)
+	assert.ErrorIs(t, psk.checkAnswer(sdp+"a=x\r\n", psk.answerMAC(sdp)), ErrPSKMismatch)
+
+	var none *PSK
+	assert.NoError(t, none.checkAnswer(sdp, ""), "without a key any answer is accepted")
+
+	_, err = NewPSK("")
+	assert.Error(t, err)
+}
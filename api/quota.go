@@ -0,0 +1,218 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/rescp17/lanFileSharer/internal/util"
+)
+
+// QuotaFileName is the file the daily usage of every sender is kept in, in the config directory
+const QuotaFileName = "quota.json"
+
+// quotaSaveInterval is how often the usage charged by meters is saved while files are written
+const quotaSaveInterval = 5 * time.Second
+
+// ErrQuotaExceeded is returned by Quota.Check when a request is over a limit
+var ErrQuotaExceeded = errors.New("quota exceeded")
+
+// QuotaRules limits what senders may send; a zero limit is not enforced
+type QuotaRules struct {
+	MaxTransferBytes int64 // Size of a single transfer
+	DailyBytes       int64 // Bytes accepted from one sender per day
+}
+
+// Quota enforces QuotaRules when requests are accepted. Senders are identified by their
+// key fingerprint, and the bytes accepted from each are kept in a file until the day ends.
+// A nil Quota allows everything.
+type Quota struct {
+	rules QuotaRules
+	path  string // Empty keeps usage in memory only
+	now   func() time.Time
+
+	mu    sync.Mutex
+	usage quotaUsage
+	dirty bool      // Usage was charged since it was saved
+	saved time.Time // When the usage was last saved
+}
+
+// quotaUsage is the persisted state of a Quota
+type quotaUsage struct {
+	Day     string           `json:"day"`     // Local date the counts are for
+	Senders map[string]int64 `json:"senders"` // Bytes accepted per sender fingerprint
+}
+
+// NewQuota returns a quota enforcing rules, with usage stored at path, or nil if rules
+// sets no limit. Corrupt usage is started over.
+func NewQuota(rules QuotaRules, path string) (*Quota, error) {
+	if rules.MaxTransferBytes <= 0 && rules.DailyBytes <= 0 {
+		return nil, nil
+	}
+	q := &Quota{rules: rules, path: path, now: time.Now}
+	if path == "" || rules.DailyBytes <= 0 {
+		return q, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return q, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read quota usage: %w", err)
+	}
+	if err := json.Unmarshal(data, &q.usage); err != nil {
+		slog.Warn("Starting quota usage over, the file is corrupt", "path", path, "error", err)
+		q.usage = quotaUsage{}
+	}
+	return q, nil
+}
+
+// Check returns an error wrapping ErrQuotaExceeded, with a reason meant for the sender,
+// if a transfer of size bytes from sender is over a limit
+func (q *Quota) Check(sender string, size int64) error {
+	if q == nil {
+		return nil
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.checkLocked(sender, 0, size)
+}
+
+// checkLocked is Check for size more bytes of a transfer that has written transferred bytes
+// already; q.mu must be held
+func (q *Quota) checkLocked(sender string, transferred, size int64) error {
+	if q.rules.MaxTransferBytes > 0 && transferred+size > q.rules.MaxTransferBytes {
+		return fmt.Errorf("%w: the transfer of %s is larger than the %s this receiver accepts at once",
+			ErrQuotaExceeded, util.FormatSize(transferred+size), util.FormatSize(q.rules.MaxTransferBytes))
+	}
+	if q.rules.DailyBytes <= 0 {
+		return nil
+	}
+
+	q.rollOver()
+	if used := q.usage.Senders[sender]; used+size > q.rules.DailyBytes {
+		return fmt.Errorf("%w: %s of your daily %s are used, the transfer of %s does not fit until tomorrow",
+			ErrQuotaExceeded, util.FormatSize(used), util.FormatSize(q.rules.DailyBytes), util.FormatSize(size))
+	}
+	return nil
+}
+
+// Record counts an accepted transfer of size bytes against the daily quota of sender
+func (q *Quota) Record(sender string, size int64) error {
+	if q == nil || q.rules.DailyBytes <= 0 {
+		return nil
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.rollOver()
+	q.usage.Senders[sender] += size
+	return q.save()
+}
+
+// Flush writes the usage charged by meters since it was last saved
+func (q *Quota) Flush() error {
+	if q == nil {
+		return nil
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if !q.dirty {
+		return nil
+	}
+	return q.save()
+}
+
+// QuotaMeter charges the bytes of one transfer to a Quota as they are written, so senders
+// are counted on what they sent rather than on the sizes they claimed, and transfers of
+// unknown size are limited as well
+type QuotaMeter struct {
+	quota  *Quota
+	sender string
+
+	mu      sync.Mutex
+	written int64
+}
+
+// Meter returns a meter for a transfer from sender. A nil Quota returns a nil meter, which
+// allows everything.
+func (q *Quota) Meter(sender string) *QuotaMeter {
+	if q == nil {
+		return nil
+	}
+	return &QuotaMeter{quota: q, sender: sender}
+}
+
+// Check returns an error wrapping ErrQuotaExceeded if n more bytes would take the transfer
+// over a limit, without charging them
+func (m *QuotaMeter) Check(n int64) error {
+	if m == nil {
+		return nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.quota.mu.Lock()
+	defer m.quota.mu.Unlock()
+	return m.quota.checkLocked(m.sender, m.written, n)
+}
+
+// Charge counts n more bytes written by the transfer against the quota, or returns an error
+// wrapping ErrQuotaExceeded and counts nothing if they would take it over a limit. The usage
+// is saved at most every quotaSaveInterval; Flush saves the rest.
+func (m *QuotaMeter) Charge(n int64) error {
+	if m == nil {
+		return nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	q := m.quota
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if err := q.checkLocked(m.sender, m.written, n); err != nil {
+		return err
+	}
+	m.written += n
+	if q.rules.DailyBytes <= 0 {
+		return nil
+	}
+	q.usage.Senders[m.sender] += n
+	q.dirty = true
+	if now := q.now(); now.Sub(q.saved) >= quotaSaveInterval {
+		if err := q.save(); err != nil {
+			slog.Warn("Failed to save quota usage", "error", err)
+		}
+	}
+	return nil
+}
+
+// rollOver starts the counts over when the day has changed; q.mu must be held
+func (q *Quota) rollOver() {
+	today := q.now().Format(time.DateOnly)
+	if q.usage.Day != today || q.usage.Senders == nil {
+		q.usage = quotaUsage{Day: today, Senders: make(map[string]int64)}
+	}
+}
+
+// save writes the usage to q.path; q.mu must be held
+func (q *Quota) save() error {
+	if q.path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(q.usage, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal quota usage: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(q.path), 0700); err != nil {
+		return fmt.Errorf("failed to create quota directory: %w", err)
+	}
+	if err := os.WriteFile(q.path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write quota usage: %w", err)
+	}
+	q.dirty, q.saved = false, q.now()
+	return nil
+}
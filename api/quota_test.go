@@ -0,0 +1,82 @@
+package api
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewQuota_NoLimits(t *testing.T) {
+	quota, err := NewQuota(QuotaRules{}, "")
+	require.NoError(t, err)
+	assert.Nil(t, quota)
+	assert.NoError(t, quota.Check("sender", 1<<40), "a nil quota allows everything")
+	assert.NoError(t, quota.Record("sender", 1<<40))
+}
+
+func TestQuota_MaxTransfer(t *testing.T) {
+	quota, err := NewQuota(QuotaRules{MaxTransferBytes: 100}, "")
+	require.NoError(t, err)
+
+	assert.NoError(t, quota.Check("sender", 100))
+	err = quota.Check("sender", 101)
+	assert.ErrorIs(t, err, ErrQuotaExceeded)
+	assert.ErrorContains(t, err, "accepts at once")
+}
+
+func TestQuota_Daily(t *testing.T) {
+	path := filepath.Join(t.TempDir(), QuotaFileName)
+	day := time.Date(2024, 6, 1, 10, 0, 0, 0, time.Local)
+	quota, err := NewQuota(QuotaRules{DailyBytes: 100}, path)
+	require.NoError(t, err)
+	quota.now = func() time.Time { return day }
+
+	require.NoError(t, quota.Check("alice", 60))
+	require.NoError(t, quota.Record("alice", 60))
+	assert.ErrorIs(t, quota.Check("alice", 50), ErrQuotaExceeded)
+	assert.NoError(t, quota.Check("bob", 50), "senders have separate quotas")
+
+	// Usage survives a restart
+	reloaded, err := NewQuota(QuotaRules{DailyBytes: 100}, path)
+	require.NoError(t, err)
+	reloaded.now = func() time.Time { return day }
+	assert.ErrorIs(t, reloaded.Check("alice", 50), ErrQuotaExceeded)
+
+	// and starts over the next day
+	reloaded.now = func() time.Time { return day.Add(24 * time.Hour) }
+	assert.NoError(t, reloaded.Check("alice", 50))
+}
+
+func TestQuotaMeter_ChargesWrittenBytes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), QuotaFileName)
+	day := time.Date(2024, 6, 1, 10, 0, 0, 0, time.Local)
+	quota, err := NewQuota(QuotaRules{MaxTransferBytes: 80, DailyBytes: 100}, path)
+	require.NoError(t, err)
+	quota.now = func() time.Time { return day }
+
+	first := quota.Meter("alice")
+	require.NoError(t, first.Charge(50))
+	require.NoError(t, first.Check(30))
+	err = first.Charge(40)
+	assert.ErrorIs(t, err, ErrQuotaExceeded)
+	assert.ErrorContains(t, err, "accepts at once", "the transfer limit counts the bytes of the transfer")
+
+	// The daily limit counts every transfer of the sender
+	second := quota.Meter("alice")
+	require.NoError(t, second.Charge(50))
+	assert.ErrorIs(t, second.Charge(1), ErrQuotaExceeded)
+	assert.NoError(t, quota.Meter("bob").Charge(80), "senders have separate quotas")
+
+	require.NoError(t, quota.Flush())
+	reloaded, err := NewQuota(QuotaRules{DailyBytes: 100}, path)
+	require.NoError(t, err)
+	reloaded.now = func() time.Time { return day }
+	assert.ErrorIs(t, reloaded.Check("alice", 1), ErrQuotaExceeded, "charged bytes are saved")
+
+	var meter *QuotaMeter
+	assert.NoError(t, meter.Charge(1<<40), "a nil meter allows everything")
+	assert.Nil(t, (*Quota)(nil).Meter("alice"))
+}
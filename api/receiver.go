@@ -7,54 +7,203 @@ import (
 	"fmt"
 	"log/slog"
 	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/pion/webrtc/v4"
 	"github.com/rescp17/lanFileSharer/internal/app"
 	"github.com/rescp17/lanFileSharer/internal/app_events/receiver"
+	"github.com/rescp17/lanFileSharer/internal/util"
+	"github.com/rescp17/lanFileSharer/pkg/audit"
 	"github.com/rescp17/lanFileSharer/pkg/concurrency"
 	"github.com/rescp17/lanFileSharer/pkg/crypto"
+	"github.com/rescp17/lanFileSharer/pkg/fileInfo"
+	"github.com/rescp17/lanFileSharer/pkg/peers"
+	"github.com/rescp17/lanFileSharer/pkg/share"
+	"github.com/rescp17/lanFileSharer/pkg/transfer"
 )
 
+// errAcceptTimeout is returned by awaitDecision when the user did not answer in time
+var errAcceptTimeout = errors.New("accept timeout expired")
+
 // API is the main entry point for the entire receiver API.
 type API struct {
-	server *ReceiverService
-	mux    *http.ServeMux
+	server  *ReceiverService
+	mux     *http.ServeMux
+	handler http.Handler // mux behind the peer filter
 }
 
 // AskPayload is the structure of the request body for the /ask endpoint.
 type AskPayload struct {
 	SignedFiles *crypto.SignedFileStructure `json:"signed_files"`
 	Offer       webrtc.SessionDescription   `json:"offer"`
+	ResumeToken string                      `json:"resume_token,omitempty"`
+	// WriteAcks asks the receiver to report the bytes it has written to disk during the transfer
+	WriteAcks bool `json:"write_acks,omitempty"`
+	// Keepalive tells the receiver the sender sends keepalives and ends the session when the
+	// receiver stops sending anything
+	Keepalive bool `json:"keepalive,omitempty"`
+	// SenderName is the sender's hostname, used to lay out received files
+	SenderName string `json:"sender_name,omitempty"`
+	// SenderIcon is the emoji or glyph the sender shows itself with, see util.DeviceIcon
+	SenderIcon string `json:"sender_icon,omitempty"`
+	// RelayTo asks a relay to store the files and forward them to the named receiver
+	RelayTo string `json:"relay_to,omitempty"`
+	// PullToken answers a pull of the receiver, which accepts the offer without asking
+	PullToken string `json:"pull_token,omitempty"`
+	// GuestToken is a guest pass the receiver issued, which accepts one transfer without asking
+	GuestToken string `json:"guest_token,omitempty"`
 }
 
 // NewAPI creates and initializes a new API instance.
-func NewAPI(uiMessages chan<- tea.Msg, stateManager *app.SingleRequestManager) *API {
+func NewAPI(uiMessages chan<- tea.Msg, stateManager *app.SingleRequestManager, resumeStore *transfer.ResumeStore) *API {
+	server := NewReceiverService(uiMessages, stateManager)
+	server.resumeStore = resumeStore
 	api := &API{
-		server: NewReceiverService(uiMessages, stateManager),
+		server: server,
 		mux:    http.NewServeMux(),
 	}
 	api.registerRoutes()
+	api.handler = server.PeerFilterMiddleware(server.PSKMiddleware(api.mux))
 	return api
 }
 
+// SetAcceptTimeout sets how long a request waits for the user's decision; zero waits forever.
+func (a *API) SetAcceptTimeout(timeout time.Duration) {
+	a.server.acceptTimeout = timeout
+}
+
+// SetEchoHandler enables POST /echo, answering ping offers with echo.
+func (a *API) SetEchoHandler(echo EchoFunc) {
+	a.server.echo = echo
+}
+
+// SetAvailability sets whether requests are put to the user or declined without asking.
+func (a *API) SetAvailability(availability receiver.Availability) {
+	a.server.availability.Store(int32(availability))
+}
+
+// Availability returns whether requests are put to the user or declined without asking.
+func (a *API) Availability() receiver.Availability {
+	return a.server.Availability()
+}
+
+// SetRelay sets whether requests asking to forward the files to another receiver are accepted.
+func (a *API) SetRelay(relay bool) {
+	a.server.relay = relay
+}
+
+// SetContentStore makes the answer list the offered files whose content has reports stored,
+// so the sender links them instead of sending them again.
+func (a *API) SetContentStore(has func(checksum string) bool) {
+	a.server.stored = has
+}
+
+// SetRemoteStorage sets whether received files leave the output directory for remote storage,
+// where they cannot be hard linked, so senders are told to send the content of hard links.
+func (a *API) SetRemoteStorage(remote bool) {
+	a.server.remoteStorage = remote
+}
+
+// SetDoNotDisturbMessage sets the reason sent with requests declined while unavailable.
+func (a *API) SetDoNotDisturbMessage(message string) {
+	a.server.dndMessage = message
+}
+
+// SetPeerFilter refuses requests from peers filter does not allow; nil allows everyone.
+func (a *API) SetPeerFilter(filter *PeerFilter) {
+	a.server.peerFilter = filter
+}
+
+// SetAuditLog records every request and how it was answered in log; nil records nothing.
+func (a *API) SetAuditLog(log *audit.Log) {
+	a.server.auditLog = log
+}
+
+// EndSession records how the accepted session ended in the audit log.
+// complete reports whether every file arrived; later calls for the same session do nothing.
+func (a *API) EndSession(files, failed int, bytes int64, complete bool) {
+	a.server.endSession(files, failed, bytes, complete)
+}
+
+// SetQuota limits the size of transfers and the bytes accepted from each sender per day; nil allows everything.
+func (a *API) SetQuota(quota *Quota) {
+	a.server.quota = quota
+}
+
+// SetPSK requires every request to be signed with psk, and signs answers with it.
+func (a *API) SetPSK(psk *PSK) {
+	a.server.psk = psk
+}
+
+// SetPeerSettings applies the settings saved for a sender, such as auto-accept, to its requests; nil applies none.
+func (a *API) SetPeerSettings(store *peers.Store) {
+	a.server.peerSettings = store
+}
+
+// SetTrustedOnly sets whether requests signed by keys the trust store does not trust are
+// declined without asking, as on locked down receivers.
+func (a *API) SetTrustedOnly(trustedOnly bool) {
+	a.server.trustedOnly = trustedOnly
+}
+
+// SetTrustStore enables checking sender keys against store and trusting them once accepted.
+func (a *API) SetTrustStore(store *crypto.TrustStore) {
+	a.server.trustStore = store
+}
+
 // ServeHTTP allows the API struct to satisfy the http.Handler interface.
 func (a *API) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	a.mux.ServeHTTP(w, r)
+	a.handler.ServeHTTP(w, r)
 }
 
-// registerRoutes connects all handlers and middleware.
+// registerRoutes connects all handlers and middleware, as listed by routes.
 func (a *API) registerRoutes() {
-	askHandlerWithMiddleware := a.server.ConcurrencyControlMiddleware(http.HandlerFunc(a.server.AskHandler))
-	a.mux.HandleFunc("POST /ask", askHandlerWithMiddleware.ServeHTTP)
-	a.mux.HandleFunc("POST /candidate", a.server.CandidateHandler)
+	for _, r := range a.routes() {
+		a.mux.HandleFunc(r.pattern, r.handler)
+	}
 }
 
 // ReceiverService manages the server's state and core logic.
 type ReceiverService struct {
-	guard        *concurrency.ConcurrencyGuard
-	uiMessages   chan<- tea.Msg // Channel to send messages to the UI
-	stateManager *app.SingleRequestManager
+	guard         *concurrency.ConcurrencyGuard
+	uiMessages    chan<- tea.Msg // Channel to send messages to the UI
+	stateManager  *app.SingleRequestManager
+	resumeStore   *transfer.ResumeStore // Optional, enables GET /resume/{token}
+	trustStore    *crypto.TrustStore    // Optional, tracks verified sender keys
+	acceptTimeout time.Duration         // Zero waits for the user's decision forever
+	echo          EchoFunc              // Optional, answers data channel echo offers
+	echoBusy      atomic.Bool           // Set while an echo session is running
+	bond          BondFunc              // Optional, answers extra connections to the running session
+	availability  atomic.Int32          // receiver.Availability; requests are declined unless Available
+	dndMessage    string                // Sent with requests declined while unavailable
+	peerFilter    *PeerFilter           // Optional, refuses peers by subnet and key fingerprint
+	auditLog      *audit.Log            // Optional, records requests and their outcome
+	quota         *Quota                // Optional, limits what senders may send
+	psk           *PSK                  // Optional, refuses requests not signed with the pre-shared key
+	relay         bool                  // Requests to forward files to another receiver are accepted
+	jobStatus     JobStatusFunc         // Optional, serves GET /jobs to local clients
+	compare       CompareFunc           // Optional, answers POST /compare
+	stored        func(string) bool     // Optional, reports content the receiver already stores
+	remoteStorage bool                  // Received files leave the output directory, so none can be linked
+	peerSettings  *peers.Store          // Optional, settings saved for individual senders
+	trustedOnly   bool                  // Requests signed by keys the trust store does not trust are declined
+	share         *share.Share          // Optional, serves GET /share and POST /pull
+	pull          PullFunc              // Sends the files of pulls from share
+	zipBusy       atomic.Bool           // Set while the share is streamed as a zip archive
+	uploadToken   string                // Token of browser uploads, empty disables them
+	upload        UploadFunc            // Saves the files of browser uploads
+	uploadBusy    atomic.Bool           // Set while a browser upload is saved
+
+	pullsMu sync.Mutex
+	pulls   map[string]expectedPull // Tokens of the pulls this receiver requested
+	guests  map[string]GuestPass    // Guest passes by normalized token, until used or expired
+	idle    map[string]idleSession  // Resume tokens of the sessions that went idle
+
+	sessionMu sync.Mutex
+	session   *audit.Record // The accepted request, until EndSession records its outcome
 }
 
 // NewReceiverService creates a new ReceiverServer instance.
@@ -106,6 +255,7 @@ func (s *ReceiverService) AskHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invalid request", http.StatusBadRequest)
 		return
 	}
+	transfer.TraceSignal(transfer.TraceIn, "/ask", "ask", int(r.ContentLength))
 
 	slog.Info("Ask received", "offer_type", req.Offer.Type)
 	if err := crypto.VerifyFileStructure(req.SignedFiles); err != nil {
@@ -115,6 +265,89 @@ func (s *ReceiverService) AskHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	slog.Info("success to verify file structure")
 
+	fingerprint := crypto.PublicKeyFingerprint(req.SignedFiles.PublicKey)
+	record := requestRecord(r, fingerprint, req.SignedFiles)
+	if !s.peerFilter.AllowsFingerprint(fingerprint) {
+		slog.Warn("Refused request signed by a filtered key", "fingerprint", fingerprint, "addr", r.RemoteAddr)
+		s.audit(record.As(audit.EventRefused, "key filtered"))
+		writeForbidden(w)
+		return
+	}
+	s.audit(record)
+
+	if availability := s.Availability(); availability != receiver.Available {
+		slog.Info("Declining request without asking", "availability", availability)
+		s.uiMessages <- receiver.RequestDeclinedMsg{Availability: availability}
+		s.audit(record.As(audit.EventDeclined, availability.String()))
+		if flusher, ok := startEventStream(w); ok {
+			if err := s.sendRejection(w, flusher, s.dndMessage); err != nil {
+				slog.Error("Failed to send rejection", "error", err)
+			}
+		}
+		return
+	}
+
+	if req.RelayTo != "" && !s.relay {
+		reason := "this receiver does not relay transfers"
+		slog.Info("Declining request to relay", "relay_to", req.RelayTo)
+		s.uiMessages <- receiver.RequestDeclinedMsg{Availability: receiver.Available, Reason: reason}
+		s.audit(record.As(audit.EventDeclined, reason))
+		if flusher, ok := startEventStream(w); ok {
+			if err := s.sendRejection(w, flusher, reason); err != nil {
+				slog.Error("Failed to send rejection", "error", err)
+			}
+		}
+		return
+	}
+
+	// A session that went idle was accepted and counted already
+	resumed := s.takeResume(req.ResumeToken, fingerprint)
+	if s.trustedOnly && !resumed && s.senderTrust(req.SignedFiles) != crypto.TrustValid {
+		reason := "this receiver only accepts senders it trusts"
+		slog.Info("Declining request from an untrusted key", "fingerprint", fingerprint)
+		s.uiMessages <- receiver.RequestDeclinedMsg{Availability: receiver.Available, Reason: reason}
+		s.audit(record.As(audit.EventDeclined, reason))
+		if flusher, ok := startEventStream(w); ok {
+			if err := s.sendRejection(w, flusher, reason); err != nil {
+				slog.Error("Failed to send rejection", "error", err)
+			}
+		}
+		return
+	}
+	// The claimed size only declines requests early, the receiver charges the quota with the
+	// bytes it writes
+	size := offeredBytes(req.SignedFiles)
+	if err := s.quota.Check(fingerprint, size); !resumed && err != nil {
+		slog.Info("Declining request over quota", "fingerprint", fingerprint, "size", size, "error", err)
+		s.uiMessages <- receiver.RequestDeclinedMsg{Availability: receiver.Available, Reason: err.Error()}
+		s.audit(record.As(audit.EventDeclined, err.Error()))
+		if flusher, ok := startEventStream(w); ok {
+			if err := s.sendRejection(w, flusher, err.Error()); err != nil {
+				slog.Error("Failed to send rejection", "error", err)
+			}
+		}
+		return
+	}
+	guest := false
+	if req.GuestToken != "" && !resumed {
+		guestSize := size
+		if sizeUnknown(req.SignedFiles.Files) {
+			guestSize = fileInfo.UnknownSize
+		}
+		if err := s.takeGuestPass(req.GuestToken, guestSize); err != nil {
+			slog.Info("Declining request with a guest token", "fingerprint", fingerprint, "error", err)
+			s.uiMessages <- receiver.RequestDeclinedMsg{Availability: receiver.Available, Reason: err.Error()}
+			s.audit(record.As(audit.EventDeclined, err.Error()))
+			if flusher, ok := startEventStream(w); ok {
+				if err := s.sendRejection(w, flusher, err.Error()); err != nil {
+					slog.Error("Failed to send rejection", "error", err)
+				}
+			}
+			return
+		}
+		guest = true
+	}
+
 	decisionChan, err := s.stateManager.CreateRequest(req.Offer, req.SignedFiles)
 	if err != nil {
 		slog.Error("failed to create request", "error", err)
@@ -123,8 +356,185 @@ func (s *ReceiverService) AskHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	defer s.stateManager.CloseRequest()
 
-	s.uiMessages <- receiver.FileNodeUpdateMsg{Nodes: req.SignedFiles.Files}
+	if req.ResumeToken != "" {
+		if err := transfer.ValidateResumeToken(req.ResumeToken); err != nil {
+			slog.Error("invalid resume token", "error", err)
+			http.Error(w, "Invalid resume token", http.StatusBadRequest)
+			return
+		}
+		if err := s.stateManager.SetResumeToken(req.ResumeToken); err != nil {
+			slog.Error("failed to store resume token", "error", err)
+		}
+	}
+
+	if err := s.stateManager.SetWriteAcks(req.WriteAcks); err != nil {
+		slog.Error("failed to store write ACK preference", "error", err)
+	}
+	if err := s.stateManager.SetKeepalive(req.Keepalive); err != nil {
+		slog.Error("failed to store keepalive preference", "error", err)
+	}
+	if err := s.stateManager.SetSenderName(req.SenderName); err != nil {
+		slog.Error("failed to store sender name", "error", err)
+	}
+
+	settings, _ := s.peerSettings.Get(fingerprint)
+	pulled := s.takePull(req.PullToken, fingerprint)
+	s.uiMessages <- receiver.FileNodeUpdateMsg{
+		Nodes:             req.SignedFiles.Files,
+		ResumeToken:       req.ResumeToken,
+		SenderFingerprint: fingerprint,
+		SenderTrust:       s.senderTrust(req.SignedFiles).String(),
+		TrustReadOnly:     s.trustStore != nil && s.trustStore.ReadOnly(),
+		RelayTo:           req.RelayTo,
+		AutoAccept:        settings.AutoAccept || pulled || resumed || guest,
+		Pulled:            pulled,
+		Resumed:           resumed,
+		Guest:             guest,
+		PeerLabel:         settings.Label,
+		SenderName:        util.SanitizeText(req.SenderName),
+		SenderIcon:        util.DeviceIcon(req.SenderIcon),
+	}
+
+	// Flush the headers now so the sender knows the request arrived while the user decides
+	flusher, ok := startEventStream(w)
+	if !ok {
+		return
+	}
+
+	decision, err := s.awaitDecision(r.Context(), decisionChan)
+	switch {
+	case errors.Is(err, errAcceptTimeout):
+		slog.Info("Request timed out waiting for the user", "timeout", s.acceptTimeout)
+		s.uiMessages <- receiver.RequestTimedOutMsg{Timeout: s.acceptTimeout}
+		s.audit(record.As(audit.EventTimedOut, s.acceptTimeout.String()))
+		if err := s.sendTimeout(w, flusher); err != nil {
+			slog.Error("Failed to send timeout", "error", err)
+		}
+		return
+	case err != nil:
+		slog.Warn("Request context cancelled before processing")
+		return
+	case decision == app.Rejected:
+		slog.Info("Request rejected by user")
+		s.audit(record.As(audit.EventRejected, ""))
+		if err := s.sendRejection(w, flusher, ""); err != nil {
+			slog.Error("Failed to send rejection", "error", err)
+		}
+		return
+	}
+
+	slog.Info("Request accepted by user")
+	s.audit(record.As(audit.EventAccepted, ""))
+	s.startSession(record)
+	if !guest {
+		// Guests are accepted once, their keys are not trusted for later requests
+		s.trustSender(fingerprint, req.SignedFiles)
+	}
+
+	if err := s.sendAnswer(w, flusher, r.Context(), s.storedChecksums(req.SignedFiles.Files)); err != nil {
+		slog.Error("Failed to send answer", "error", err)
+		sendErrorEvent(w, flusher, err)
+		return
+	}
+
+	if err := s.streamCandidates(w, flusher, r.Context()); err != nil {
+		slog.Error("Failed to stream candidates", "error", err)
+		sendErrorEvent(w, flusher, err)
+	}
+}
+
+// Availability returns whether requests are put to the user or declined without asking.
+func (s *ReceiverService) Availability() receiver.Availability {
+	return receiver.Availability(s.availability.Load())
+}
+
+// audit records an entry in the audit log, if there is one
+func (s *ReceiverService) audit(record audit.Record) {
+	if err := s.auditLog.Record(record); err != nil {
+		slog.Error("Failed to write audit log", "event", record.Event, "error", err)
+	}
+}
+
+// startSession remembers the accepted request so its outcome can be recorded
+func (s *ReceiverService) startSession(record audit.Record) {
+	s.sessionMu.Lock()
+	defer s.sessionMu.Unlock()
+	s.session = &record
+}
+
+func (s *ReceiverService) endSession(files, failed int, bytes int64, complete bool) {
+	// The bytes the session wrote were charged to the quota as they were written
+	if err := s.quota.Flush(); err != nil {
+		slog.Warn("Failed to save quota usage", "error", err)
+	}
+	s.sessionMu.Lock()
+	session := s.session
+	s.session = nil
+	s.sessionMu.Unlock()
+	if session == nil {
+		return
+	}
+
+	record := session.As(audit.EventCompleted, "")
+	record.Files, record.Bytes = files, bytes
+	switch {
+	case !complete:
+		record = record.As(audit.EventFailed, "connection closed before all files arrived")
+	case failed > 0:
+		record = record.As(audit.EventFailed, fmt.Sprintf("%d files failed", failed))
+	}
+	s.audit(record)
+}
+
+// requestRecord describes the request r for the audit log
+func requestRecord(r *http.Request, fingerprint string, signedFiles *crypto.SignedFileStructure) audit.Record {
+	record := audit.Record{
+		Event:       audit.EventRequest,
+		Peer:        r.RemoteAddr,
+		Fingerprint: fingerprint,
+		Files:       len(signedFiles.Files),
+		Bytes:       offeredBytes(signedFiles),
+	}
+	if metadata := signedFiles.Metadata; metadata != nil {
+		record.Files = metadata.TotalFiles
+	}
+	return record
+}
+
+// offeredBytes returns the total size of the signed files offered in signedFiles. Files of
+// unknown size count as empty, see sizeUnknown.
+func offeredBytes(signedFiles *crypto.SignedFileStructure) int64 {
+	return filesSize(signedFiles.Files)
+}
+
+// filesSize sums the sizes of the regular files in nodes. The sizes of the directories are
+// not used, as a sender could claim any.
+func filesSize(nodes []fileInfo.FileNode) int64 {
+	var total int64
+	for _, node := range nodes {
+		switch {
+		case node.IsDir:
+			total += filesSize(node.Children)
+		case node.Size > 0:
+			total += node.Size
+		}
+	}
+	return total
+}
+
+// sizeUnknown reports whether the size of any regular file in nodes is unknown, e.g. for a
+// stream, or negative
+func sizeUnknown(nodes []fileInfo.FileNode) bool {
+	for _, node := range nodes {
+		if node.IsDir && sizeUnknown(node.Children) || !node.IsDir && node.Size < 0 {
+			return true
+		}
+	}
+	return false
+}
 
+// startEventStream writes and flushes the headers of an SSE response
+func startEventStream(w http.ResponseWriter) (http.Flusher, bool) {
 	w.Header().Set("Content-Type", "text/event-stream")
 	w.Header().Set("Cache-Control", "no-cache")
 	w.Header().Set("Connection", "keep-alive")
@@ -132,45 +542,95 @@ func (s *ReceiverService) AskHandler(w http.ResponseWriter, r *http.Request) {
 	if !ok {
 		slog.Error("failed to support streaming")
 		http.Error(w, "Streaming unsupported!", http.StatusInternalServerError)
+		return nil, false
+	}
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	return flusher, true
+}
+
+// senderTrust returns the trust status of the key that signed signedFiles, unknown without a
+// trust store. A key endorsed by a trusted key the sender rotated is trusted.
+func (s *ReceiverService) senderTrust(signedFiles *crypto.SignedFileStructure) crypto.TrustStatus {
+	if s.trustStore == nil {
+		return crypto.TrustUnknown
+	}
+	return s.trustStore.StatusEndorsed(signedFiles.PublicKey, signedFiles.Endorsements, time.Now())
+}
+
+// trustSender records that the user verified the sender key by accepting its offer.
+func (s *ReceiverService) trustSender(fingerprint string, signedFiles *crypto.SignedFileStructure) {
+	if s.trustStore == nil || s.trustStore.ReadOnly() {
 		return
 	}
+	var keyExpiresAt time.Time
+	if signedFiles.Metadata != nil && signedFiles.Metadata.KeyExpiresAt != 0 {
+		keyExpiresAt = time.Unix(signedFiles.Metadata.KeyExpiresAt, 0)
+	}
+	// A rotated key keeps the label of the key that endorsed it
+	label := ""
+	if endorser, ok := s.trustStore.Endorser(signedFiles.PublicKey, signedFiles.Endorsements, time.Now()); ok {
+		label = endorser.Label
+	}
+	s.trustStore.Trust(fingerprint, label, keyExpiresAt)
+	if err := s.trustStore.Save(); err != nil {
+		slog.Warn("Failed to save trust store", "error", err)
+	}
+}
+
+// awaitDecision waits for the user to accept or reject the request. A closed decision
+// channel counts as a rejection. It returns errAcceptTimeout if the accept timeout passes first.
+func (s *ReceiverService) awaitDecision(ctx context.Context, decisionChan <-chan app.Decision) (app.Decision, error) {
+	var timeout <-chan time.Time
+	if s.acceptTimeout > 0 {
+		timer := time.NewTimer(s.acceptTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
 	select {
-	case <-r.Context().Done():
-		slog.Warn("Request context cancelled before processing")
-		return
+	case <-ctx.Done():
+		return app.Rejected, ctx.Err()
 	case decision, ok := <-decisionChan:
 		if !ok {
 			slog.Warn("Decision channel closed unexpectedly")
-			if err := s.sendRejection(w, flusher); err != nil {
-				slog.Error("Failed to send rejection", "error", err)
-			}
-			return
+			return app.Rejected, nil
 		}
-		if decision == app.Rejected {
-			slog.Info("Request rejected by user")
-			if err := s.sendRejection(w, flusher); err != nil {
-				slog.Error("Failed to send rejection", "error", err)
+		return decision, nil
+	case <-timeout:
+		// Record a rejection so a late answer from the user is refused. If that fails
+		// the user decided just as the timer fired, and their decision stands.
+		if err := s.stateManager.SetDecision(app.Rejected); err != nil {
+			if decision, ok := <-decisionChan; ok {
+				return decision, nil
 			}
-			return
 		}
+		return app.Rejected, errAcceptTimeout
 	}
+}
 
-	slog.Info("Request accepted by user")
-	if err := s.sendAnswer(w, flusher, r.Context()); err != nil {
-		slog.Error("Failed to send answer", "error", err)
-		sendErrorEvent(w, flusher, err)
-		return
+// sendTimeout tells the sender the request expired before the user answered it.
+func (s *ReceiverService) sendTimeout(w http.ResponseWriter, flusher http.Flusher) error {
+	response := map[string]string{"status": "timeout"}
+	jsonResponse, err := json.Marshal(response)
+	if err != nil {
+		return fmt.Errorf("failed to marshal timeout response: %w", err)
 	}
-
-	if err := s.streamCandidates(w, flusher, r.Context()); err != nil {
-		slog.Error("Failed to stream candidates", "error", err)
-		sendErrorEvent(w, flusher, err)
+	if _, err := fmt.Fprintf(w, "event: timeout\ndata: %s\n\n", jsonResponse); err != nil {
+		return fmt.Errorf("failed to write timeout event: %w", err)
 	}
+	transfer.TraceSignal(transfer.TraceOut, "/ask", "timeout", len(jsonResponse))
+
+	flusher.Flush()
+	return nil
 }
 
-// sendRejection sends a rejection message to the sender.
-func (s *ReceiverService) sendRejection(w http.ResponseWriter, flusher http.Flusher) error {
+// sendRejection sends a rejection message to the sender, with message as the reason if it is set.
+func (s *ReceiverService) sendRejection(w http.ResponseWriter, flusher http.Flusher, message string) error {
 	response := map[string]string{"status": "rejected"}
+	if message != "" {
+		response["message"] = message
+	}
 	jsonResponse, err := json.Marshal(response)
 	if err != nil {
 		slog.Error("Failed to marshal rejection response", "error", err)
@@ -179,13 +639,42 @@ func (s *ReceiverService) sendRejection(w http.ResponseWriter, flusher http.Flus
 	if _, err := fmt.Fprintf(w, "event: rejection\ndata: %s\n\n", jsonResponse); err != nil {
 		return fmt.Errorf("failed to write rejection event: %w", err)
 	}
+	transfer.TraceSignal(transfer.TraceOut, "/ask", "rejection", len(jsonResponse))
 
 	flusher.Flush()
 	return nil
 }
 
-// sendAnswer waits for the WebRTC answer and sends it as an SSE event.
-func (s *ReceiverService) sendAnswer(w http.ResponseWriter, flusher http.Flusher, ctx context.Context) error {
+// storedChecksums returns the checksums of the offered files whose content is already stored
+func (s *ReceiverService) storedChecksums(files []fileInfo.FileNode) []string {
+	if s.stored == nil {
+		return nil
+	}
+	var stored []string
+	seen := make(map[string]bool)
+	var walk func(nodes []fileInfo.FileNode)
+	walk = func(nodes []fileInfo.FileNode) {
+		for _, node := range nodes {
+			if node.IsDir {
+				walk(node.Children)
+				continue
+			}
+			if node.Checksum == "" || seen[node.Checksum] {
+				continue
+			}
+			seen[node.Checksum] = true
+			if s.stored(node.Checksum) {
+				stored = append(stored, node.Checksum)
+			}
+		}
+	}
+	walk(files)
+	return stored
+}
+
+// sendAnswer waits for the WebRTC answer and sends it as an SSE event, listing the offered
+// content that is already stored.
+func (s *ReceiverService) sendAnswer(w http.ResponseWriter, flusher http.Flusher, ctx context.Context, stored []string) error {
 	answerChan := s.stateManager.GetAnswerChan()
 
 	var answer webrtc.SessionDescription
@@ -202,7 +691,25 @@ func (s *ReceiverService) sendAnswer(w http.ResponseWriter, flusher http.Flusher
 
 	slog.Info("Sending answer to sender", "answer_type", answer.Type)
 
-	response := map[string]webrtc.SessionDescription{"answer": answer}
+	// file_acks tells the sender to wait for a verified ACK before completing each file,
+	// speed_probe that a speed probe channel is confirmed rather than taken for files,
+	// hard_links that hard links may be sent as FileLink messages instead of their content,
+	// unless received files move to remote storage,
+	// keepalive that the receiver sends keepalives back to senders that send them,
+	// control_channel that control messages may go on a channel of their own
+	response := map[string]any{"answer": answer, "file_acks": true, "speed_probe": true, "hard_links": !s.remoteStorage, "keepalive": true, "control_channel": true}
+	if s.bond != nil {
+		// The session may be striped over extra connections offered to POST /bond
+		response["bond"] = true
+	}
+	if len(stored) > 0 {
+		// Checksums of content the receiver has, which may be sent as FileLink messages without LinkTo
+		response["stored"] = stored
+	}
+	if s.psk != nil {
+		// Proves the answer, and the DTLS fingerprint in it, come from a holder of the key
+		response["mac"] = s.psk.answerMAC(answer.SDP)
+	}
 	jsonResponse, err := json.Marshal(response)
 	if err != nil {
 		return fmt.Errorf("failed to marshal answer: %w", err)
@@ -211,6 +718,7 @@ func (s *ReceiverService) sendAnswer(w http.ResponseWriter, flusher http.Flusher
 	if _, err := fmt.Fprintf(w, "event: answer\ndata: %s\n\n", jsonResponse); err != nil {
 		return fmt.Errorf("failed to write answer to response: %w", err)
 	}
+	transfer.TraceSignal(transfer.TraceOut, "/ask", "answer", len(jsonResponse))
 	flusher.Flush()
 	return nil
 }
@@ -234,6 +742,7 @@ func (s *ReceiverService) streamCandidates(w http.ResponseWriter, flusher http.F
 				if err != nil {
 					return fmt.Errorf("failed to write candidates_done event: %w", err)
 				}
+				transfer.TraceSignal(transfer.TraceOut, "/ask", "candidates_done", len("{}"))
 				flusher.Flush()
 				return nil
 			}
@@ -249,6 +758,7 @@ func (s *ReceiverService) streamCandidates(w http.ResponseWriter, flusher http.F
 			if _, err := fmt.Fprintf(w, "event: candidate\ndata: %s\n\n", jsonResponse); err != nil {
 				return fmt.Errorf("failed to write candidate to response: %w", err)
 			}
+			transfer.TraceSignal(transfer.TraceOut, "/ask", "candidate", len(jsonResponse))
 			flusher.Flush()
 		}
 	}
@@ -260,6 +770,7 @@ func (s *ReceiverService) CandidateHandler(w http.ResponseWriter, r *http.Reques
 		http.Error(w, "Invalid candidate payload", http.StatusBadRequest)
 		return
 	}
+	transfer.TraceSignal(transfer.TraceIn, "/candidate", "candidate", int(r.ContentLength))
 	slog.Info("Candidate received", "request", req)
 
 	if err := s.stateManager.SetCandidate(req); err != nil {
@@ -278,6 +789,33 @@ func (s *ReceiverService) CandidateHandler(w http.ResponseWriter, r *http.Reques
 	}
 }
 
+// ResumeHandler returns the persisted chunk bitmap of an interrupted session.
+func (s *ReceiverService) ResumeHandler(w http.ResponseWriter, r *http.Request) {
+	if s.resumeStore == nil {
+		http.Error(w, "Resume not supported", http.StatusNotFound)
+		return
+	}
+
+	state, err := s.resumeStore.Load(r.PathValue("token"))
+	switch {
+	case errors.Is(err, transfer.ErrInvalidResumeToken):
+		http.Error(w, "Invalid resume token", http.StatusBadRequest)
+		return
+	case errors.Is(err, transfer.ErrResumeStateNotFound):
+		http.Error(w, "Resume state not found", http.StatusNotFound)
+		return
+	case err != nil:
+		slog.Error("failed to load resume state", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(state); err != nil {
+		slog.Error("Failed to encode resume state", "error", err)
+	}
+}
+
 func sendErrorEvent(w http.ResponseWriter, flusher http.Flusher, err error) {
 	response := map[string]string{"error": err.Error()}
 	jsonResponse, marshalErr := json.Marshal(response) // Marshalling a simple map shouldn't fail
@@ -287,6 +825,8 @@ func sendErrorEvent(w http.ResponseWriter, flusher http.Flusher, err error) {
 	}
 	if _, writeErr := fmt.Fprintf(w, "event: error\ndata: %s\n\n", jsonResponse); writeErr != nil {
 		slog.Warn("failed to write error event to client", "error", writeErr)
+	} else {
+		transfer.TraceSignal(transfer.TraceOut, "/ask", "error", len(jsonResponse))
 	}
 	flusher.Flush()
 }
@@ -0,0 +1,272 @@
+package api
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/pion/webrtc/v4"
+	"github.com/rescp17/lanFileSharer/internal/app"
+	"github.com/rescp17/lanFileSharer/internal/app_events/receiver"
+	"github.com/rescp17/lanFileSharer/pkg/audit"
+	"github.com/rescp17/lanFileSharer/pkg/crypto"
+	"github.com/rescp17/lanFileSharer/pkg/fileInfo"
+	"github.com/rescp17/lanFileSharer/pkg/transfer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAskHandler_AcceptTimeout(t *testing.T) {
+	uiMessages := make(chan tea.Msg, 10)
+	stateManager := app.NewSingleRequestManager()
+	handler := NewAPI(uiMessages, stateManager, nil)
+	handler.SetAcceptTimeout(50 * time.Millisecond)
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	signaler := NewAPISignaler(NewClient("test-service-id"), server.URL, mockAddICECandidate)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, signaler.SendOffer(ctx, createTestOffer(), createTestSignedFiles(t)))
+
+	_, err := signaler.WaitForAnswer(ctx)
+	assert.ErrorIs(t, err, ErrRequestTimedOut)
+
+	require.IsType(t, receiver.FileNodeUpdateMsg{}, <-uiMessages)
+	timedOut, ok := (<-uiMessages).(receiver.RequestTimedOutMsg)
+	require.True(t, ok)
+	assert.Equal(t, 50*time.Millisecond, timedOut.Timeout)
+
+	// A late answer from the user is refused
+	assert.Error(t, stateManager.SetDecision(app.Accepted))
+}
+
+func TestAskHandler_DoNotDisturb(t *testing.T) {
+	uiMessages := make(chan tea.Msg, 10)
+	stateManager := app.NewSingleRequestManager()
+	handler := NewAPI(uiMessages, stateManager, nil)
+	handler.SetAvailability(receiver.DoNotDisturb)
+	handler.SetDoNotDisturbMessage("Back at 3pm")
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	signaler := NewAPISignaler(NewClient("test-service-id"), server.URL, mockAddICECandidate)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, signaler.SendOffer(ctx, createTestOffer(), createTestSignedFiles(t)))
+
+	_, err := signaler.WaitForAnswer(ctx)
+	assert.ErrorIs(t, err, ErrTransferRejected)
+	assert.ErrorContains(t, err, "Back at 3pm")
+
+	declined, ok := (<-uiMessages).(receiver.RequestDeclinedMsg)
+	require.True(t, ok, "the user is not asked")
+	assert.Equal(t, receiver.DoNotDisturb, declined.Availability)
+	assert.False(t, stateManager.HasActiveRequest())
+
+	ping, err := NewClient("test-service-id").Ping(ctx, server.URL)
+	require.NoError(t, err)
+	assert.Equal(t, "do not disturb", ping.Availability)
+}
+
+func TestAskHandler_Relay(t *testing.T) {
+	ask := func(t *testing.T, relay bool) (chan tea.Msg, error) {
+		uiMessages := make(chan tea.Msg, 10)
+		handler := NewAPI(uiMessages, app.NewSingleRequestManager(), nil)
+		handler.SetRelay(relay)
+		handler.SetAcceptTimeout(50 * time.Millisecond)
+		server := httptest.NewServer(handler)
+		t.Cleanup(server.Close)
+
+		signaler := NewAPISignaler(NewClient("test-service-id"), server.URL, mockAddICECandidate)
+		signaler.SetRelayTo("desk")
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		require.NoError(t, signaler.SendOffer(ctx, createTestOffer(), createTestSignedFiles(t)))
+		_, err := signaler.WaitForAnswer(ctx)
+		return uiMessages, err
+	}
+
+	t.Run("refused by receivers that do not relay", func(t *testing.T) {
+		uiMessages, err := ask(t, false)
+		assert.ErrorIs(t, err, ErrTransferRejected)
+		assert.ErrorContains(t, err, "does not relay")
+		_, ok := (<-uiMessages).(receiver.RequestDeclinedMsg)
+		assert.True(t, ok, "the user is not asked")
+	})
+
+	t.Run("passed on by relays", func(t *testing.T) {
+		uiMessages, err := ask(t, true)
+		assert.ErrorIs(t, err, ErrRequestTimedOut)
+		update, ok := (<-uiMessages).(receiver.FileNodeUpdateMsg)
+		require.True(t, ok)
+		assert.Equal(t, "desk", update.RelayTo)
+	})
+}
+
+func TestAskHandler_AuditLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), audit.FileName)
+	log, err := audit.Open(path)
+	require.NoError(t, err)
+
+	uiMessages := make(chan tea.Msg, 10)
+	handler := NewAPI(uiMessages, app.NewSingleRequestManager(), nil)
+	handler.SetAcceptTimeout(50 * time.Millisecond)
+	handler.SetAuditLog(log)
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	signaler := NewAPISignaler(NewClient("test-service-id"), server.URL, mockAddICECandidate)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, signaler.SendOffer(ctx, createTestOffer(), createTestSignedFiles(t)))
+	_, err = signaler.WaitForAnswer(ctx)
+	require.ErrorIs(t, err, ErrRequestTimedOut)
+
+	// Without an accepted session there is nothing to end
+	handler.EndSession(1, 0, 10, true)
+
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+	entries, err := audit.Verify(file)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, audit.EventRequest, entries[0].Event)
+	assert.NotEmpty(t, entries[0].Fingerprint)
+	assert.Equal(t, audit.EventTimedOut, entries[1].Event)
+}
+
+func TestPingAndEcho(t *testing.T) {
+	handler := NewAPI(make(chan tea.Msg, 10), app.NewSingleRequestManager(), nil)
+	server := httptest.NewServer(handler)
+	defer server.Close()
+	client := NewClient("test-service-id")
+	ctx := context.Background()
+
+	ping, err := client.Ping(ctx, server.URL)
+	require.NoError(t, err)
+	assert.False(t, ping.Busy)
+	assert.False(t, ping.Echo, "echo is off without a handler")
+	assert.InDelta(t, time.Now().UnixMilli(), ping.Time, float64(time.Minute.Milliseconds()))
+
+	_, err = client.Echo(ctx, server.URL, createTestOffer())
+	assert.Error(t, err)
+
+	answer := webrtc.SessionDescription{Type: webrtc.SDPTypeAnswer, SDP: "test-answer-sdp"}
+	closed := make(chan struct{})
+	handler.SetEchoHandler(func(ctx context.Context, offer webrtc.SessionDescription) (*webrtc.SessionDescription, <-chan struct{}, error) {
+		assert.Equal(t, "test-offer-sdp", offer.SDP)
+		return &answer, closed, nil
+	})
+	ping, err = client.Ping(ctx, server.URL)
+	require.NoError(t, err)
+	assert.True(t, ping.Echo)
+
+	got, err := client.Echo(ctx, server.URL, createTestOffer())
+	require.NoError(t, err)
+	assert.Equal(t, answer, *got)
+
+	// The session outlives the request, so a second one waits until its connection closes
+	_, err = client.Echo(ctx, server.URL, createTestOffer())
+	assert.Error(t, err, "an echo session is still running")
+	close(closed)
+	require.Eventually(t, func() bool {
+		_, err := client.Echo(ctx, server.URL, createTestOffer())
+		return err == nil
+	}, time.Second, 10*time.Millisecond)
+
+	resp, err := http.Post(server.URL+"/echo", "application/json", strings.NewReader(`{}`))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode, "an offer is required")
+}
+
+func TestBond(t *testing.T) {
+	handler := NewAPI(make(chan tea.Msg, 10), app.NewSingleRequestManager(), nil)
+	server := httptest.NewServer(handler)
+	defer server.Close()
+	client := NewClient("test-service-id")
+	ctx := context.Background()
+
+	signer, err := crypto.NewEd25519FileStructureSigner()
+	require.NoError(t, err)
+	testFile := filepath.Join(t.TempDir(), "test.txt")
+	require.NoError(t, os.WriteFile(testFile, []byte("test content"), 0644))
+	fsm := transfer.NewFileStructureManager()
+	require.NoError(t, fsm.AddPath(testFile))
+	structure, err := signer.SignFileStructureManager(fsm)
+	require.NoError(t, err)
+	offer := createTestOffer()
+	signature, err := signer.SignBondOffer("token", offer.SDP)
+	require.NoError(t, err)
+
+	_, err = client.Bond(ctx, server.URL, "token", offer, signature)
+	assert.Error(t, err, "bonding is off without a handler")
+
+	answer := webrtc.SessionDescription{Type: webrtc.SDPTypeAnswer, SDP: "test-answer-sdp"}
+	handler.SetBondHandler(func(ctx context.Context, token string, offer webrtc.SessionDescription, signature []byte) (*webrtc.SessionDescription, error) {
+		if token != "token" {
+			return nil, ErrUnknownSession
+		}
+		if err := crypto.VerifyBondOffer(structure, token, offer.SDP, signature); err != nil {
+			return nil, err
+		}
+		assert.Equal(t, "test-offer-sdp", offer.SDP)
+		return &answer, nil
+	})
+
+	got, err := client.Bond(ctx, server.URL, "token", offer, signature)
+	require.NoError(t, err)
+	assert.Equal(t, answer, *got)
+
+	_, err = client.Bond(ctx, server.URL, "other", offer, signature)
+	assert.Error(t, err, "only the running session can be joined")
+
+	_, err = client.Bond(ctx, server.URL, "token", offer, nil)
+	assert.Error(t, err, "the token alone does not join the session")
+
+	other, err := crypto.NewEd25519FileStructureSigner()
+	require.NoError(t, err)
+	forged, err := other.SignBondOffer("token", offer.SDP)
+	require.NoError(t, err)
+	body := fmt.Sprintf(`{"token":"token","offer":{"type":"offer","sdp":%q},"signature":%q}`, offer.SDP, base64.StdEncoding.EncodeToString(forged))
+	resp, err := http.Post(server.URL+"/bond", "application/json", strings.NewReader(body))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode, "offers must be signed with the session's key")
+
+	resp, err = http.Post(server.URL+"/bond", "application/json", strings.NewReader(`{"token":"token","offer":{"type":"offer","sdp":"x"}}`))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode, "a signature is required")
+
+	resp, err = http.Post(server.URL+"/bond", "application/json", strings.NewReader(`{"offer":{"type":"offer","sdp":"x"}}`))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode, "a token is required")
+}
+
+func TestStoredChecksums(t *testing.T) {
+	handler := NewAPI(make(chan tea.Msg, 10), app.NewSingleRequestManager(), nil)
+	files := []fileInfo.FileNode{
+		{Name: "a.txt", Checksum: "aaaa"},
+		{Name: "dir", IsDir: true, Checksum: "dddd", Children: []fileInfo.FileNode{
+			{Name: "b.txt", Checksum: "bbbb"},
+			{Name: "copy.txt", Checksum: "aaaa"},
+		}},
+		{Name: "stdin", Checksum: ""},
+	}
+	assert.Nil(t, handler.server.storedChecksums(files), "receivers without a content store list nothing")
+
+	handler.SetContentStore(func(checksum string) bool { return checksum != "bbbb" })
+	assert.Equal(t, []string{"aaaa"}, handler.server.storedChecksums(files))
+}
@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/pion/webrtc/v4"
+	"github.com/rescp17/lanFileSharer/pkg/transfer"
 )
 
 const serviceIDHeader = "X-Service-ID"
@@ -31,6 +32,7 @@ func (t *serviceIDInjector) RoundTrip(req *http.Request) (*http.Response, error)
 // It does not store receiver-specific state, making it safe for concurrent use.
 type Client struct {
 	HttpClient *http.Client
+	psk        *PSK // Signs requests and checks answers, nil without a pre-shared key
 }
 
 // NewClient creates a new API client, configured to automatically inject the provided serviceID.
@@ -51,6 +53,12 @@ func NewClient(serviceID string) *Client {
 	}
 }
 
+// SetPSK signs every request with psk and requires answers to be signed with it
+func (c *Client) SetPSK(psk *PSK) {
+	c.psk = psk
+	c.HttpClient.Transport = &pskSigner{psk: psk, next: c.HttpClient.Transport}
+}
+
 // SendICECandidateRequest sends an ICE candidate to the receiver's API endpoint.
 // This method is kept as a placeholder for the necessary sender-to-receiver
 // ICE candidate signaling. Implementing the corresponding endpoint on the receiver
@@ -77,7 +85,7 @@ func (c *Client) SendICECandidateRequest(ctx context.Context, receiverURL string
 
 	resp, err := c.HttpClient.Do(req)
 	if err != nil {
-		return fmt.Errorf("failed to send candidate request: %w", err)
+		return fmt.Errorf("%w: failed to send candidate request: %w", transfer.ErrPeerUnreachable, err)
 	}
 	defer func() {
 		if err := resp.Body.Close(); err != nil {
@@ -88,6 +96,44 @@ func (c *Client) SendICECandidateRequest(ctx context.Context, receiverURL string
 	if resp.StatusCode != http.StatusOK {
 		return fmt.Errorf("candidate responded with non-OK status: %s", resp.Status)
 	}
+	transfer.TraceSignal(transfer.TraceOut, "/candidate", "candidate", len(jsonData))
 
 	return nil
 }
+
+// FetchResumeState retrieves the receiver's persisted chunk bitmap for a share token.
+// It returns transfer.ErrResumeStateNotFound if the receiver has nothing to resume.
+func (c *Client) FetchResumeState(ctx context.Context, receiverURL string, token string) (*transfer.ResumeState, error) {
+	endpoint, err := url.JoinPath(receiverURL, "resume", token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create resume url: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create resume request: %w", err)
+	}
+
+	resp, err := c.HttpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send resume request: %w", err)
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			slog.Warn("failed to close response body", "error", err)
+		}
+	}()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, transfer.ErrResumeStateNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("resume responded with non-OK status: %s", resp.Status)
+	}
+
+	var state transfer.ResumeState
+	if err := json.NewDecoder(resp.Body).Decode(&state); err != nil {
+		return nil, fmt.Errorf("failed to decode resume state: %w", err)
+	}
+	return &state, nil
+}
@@ -0,0 +1,387 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rescp17/lanFileSharer/pkg/share"
+	"github.com/rescp17/lanFileSharer/pkg/transfer"
+)
+
+// pullTokenLifetime is how long a requested pull is accepted without asking. The sharing
+// device hashes the pulled files before it sends them, which takes a while for large trees.
+const pullTokenLifetime = 10 * time.Minute
+
+// MaxPreviewBytes is the most GET /share/preview returns of a file, so previews cannot stand
+// in for pulling it
+const MaxPreviewBytes = 64 << 10
+
+// ErrShareBusy is returned by a PullFunc that cannot take another pull yet
+var ErrShareBusy = errors.New("the share is sending other files, try again shortly")
+
+// ErrUnknownOffer is returned by a PullFunc for an offer the share did not export or that expired
+var ErrUnknownOffer = errors.New("the share does not know this offer or it expired")
+
+// PullPayload is the body of POST /pull: files of the share the puller wants sent to it.
+type PullPayload struct {
+	Paths []string `json:"paths"` // Relative to the share, with forward slashes
+	// Port is where the puller's receiver listens; the files are sent to it at the address
+	// the pull came from
+	Port int `json:"port"`
+	// Token is sent back with the offer, so the puller accepts it without asking
+	Token string `json:"token"`
+	// ReceiverName is the service name of the puller, shown by the sharing device
+	ReceiverName string `json:"receiver_name,omitempty"`
+	// Offer is the token of the offer file the pull imports, empty for pulls of browsed files
+	Offer string `json:"offer,omitempty"`
+}
+
+// PullRequest asks the sharing device to send files of its share to the puller.
+type PullRequest struct {
+	Paths        []string // Local paths inside the share
+	Host         string   // Address of the puller
+	Port         int
+	ReceiverName string
+	Token        string
+	Offer        string // Token of the imported offer, if any
+}
+
+// PullFunc starts sending the files of a pull, returning once it is under way.
+type PullFunc func(req PullRequest) error
+
+// SetShare enables GET /share and POST /pull, letting peers browse shared and pull files out
+// of it, which pull sends them, and GET /share/zip for browsers. Listings and downloads are
+// answered to every peer the filter allows.
+func (a *API) SetShare(shared *share.Share, pull PullFunc) {
+	a.server.share = shared
+	a.server.pull = pull
+}
+
+// expectedPull is a pull this receiver requested, accepted without asking until it expires
+type expectedPull struct {
+	fingerprint string // Key that must sign the offer, empty for any key
+	expires     time.Time
+}
+
+// ExpectPull makes this receiver accept the offer carrying token without asking the user,
+// once, for pulls it requested.
+func (a *API) ExpectPull(token string) {
+	a.ExpectOfferPull(token, "")
+}
+
+// ExpectOfferPull is ExpectPull for the pull of an offer file, whose offer must be signed with
+// the key of fingerprint, the key that signed the offer file. Empty accepts any key.
+func (a *API) ExpectOfferPull(token, fingerprint string) {
+	a.server.pullsMu.Lock()
+	defer a.server.pullsMu.Unlock()
+	if a.server.pulls == nil {
+		a.server.pulls = make(map[string]expectedPull)
+	}
+	now := time.Now()
+	for t, pull := range a.server.pulls {
+		if now.After(pull.expires) {
+			delete(a.server.pulls, t)
+		}
+	}
+	a.server.pulls[token] = expectedPull{fingerprint: fingerprint, expires: now.Add(pullTokenLifetime)}
+}
+
+// takePull reports whether token is a pull this receiver requested, answered by an offer signed
+// with the key of fingerprint if the pull asks for one. The pull is then forgotten.
+func (s *ReceiverService) takePull(token, fingerprint string) bool {
+	if token == "" {
+		return false
+	}
+	s.pullsMu.Lock()
+	defer s.pullsMu.Unlock()
+	pull, ok := s.pulls[token]
+	delete(s.pulls, token)
+	if pull.fingerprint != "" && pull.fingerprint != fingerprint {
+		slog.Warn("Pulled offer is signed with another key than the offer file", "expected", pull.fingerprint, "got", fingerprint)
+		return false
+	}
+	return ok && time.Now().Before(pull.expires)
+}
+
+// ShareHandler lists a directory of the share, given by the path query parameter.
+func (s *ReceiverService) ShareHandler(w http.ResponseWriter, r *http.Request) {
+	if s.share == nil {
+		http.Error(w, "Nothing is shared", http.StatusNotFound)
+		return
+	}
+	entries, err := s.share.List(r.URL.Query().Get("path"))
+	switch {
+	case errors.Is(err, share.ErrOutsideShare):
+		http.Error(w, "Invalid path", http.StatusBadRequest)
+		return
+	case errors.Is(err, os.ErrNotExist):
+		http.Error(w, "No such directory", http.StatusNotFound)
+		return
+	case err != nil:
+		slog.Error("Failed to list share", "path", r.URL.Query().Get("path"), "error", err)
+		http.Error(w, "Failed to list directory", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(entries); err != nil {
+		slog.Error("Failed to encode share listing", "error", err)
+	}
+}
+
+// PullHandler starts sending the requested files of the share back to the puller.
+func (s *ReceiverService) PullHandler(w http.ResponseWriter, r *http.Request) {
+	if s.share == nil || s.pull == nil {
+		http.Error(w, "Nothing is shared", http.StatusNotFound)
+		return
+	}
+	var req PullPayload
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.Paths) == 0 || req.Token == "" {
+		http.Error(w, "Invalid pull payload", http.StatusBadRequest)
+		return
+	}
+	if req.Port <= 0 || req.Port > 65535 {
+		http.Error(w, "Invalid port", http.StatusBadRequest)
+		return
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		http.Error(w, "Invalid remote address", http.StatusBadRequest)
+		return
+	}
+
+	paths := make([]string, 0, len(req.Paths))
+	for _, path := range req.Paths {
+		local, err := s.share.Resolve(path)
+		if err != nil || local == s.share.Root() {
+			http.Error(w, fmt.Sprintf("Invalid path %q", path), http.StatusBadRequest)
+			return
+		}
+		paths = append(paths, local)
+	}
+
+	slog.Info("Pull requested", "addr", r.RemoteAddr, "receiver", req.ReceiverName, "paths", len(paths))
+	err = s.pull(PullRequest{Paths: paths, Host: host, Port: req.Port, ReceiverName: req.ReceiverName, Token: req.Token, Offer: req.Offer})
+	if errors.Is(err, ErrShareBusy) {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	if errors.Is(err, ErrUnknownOffer) {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	if err != nil {
+		slog.Error("Failed to start pull", "error", err)
+		http.Error(w, "Failed to start sending", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// PreviewHandler returns the start of a file of the share, given by the path query parameter.
+// The Range header asks for the first bytes as "bytes=0-<last>"; without it, or beyond
+// MaxPreviewBytes, MaxPreviewBytes are returned.
+func (s *ReceiverService) PreviewHandler(w http.ResponseWriter, r *http.Request) {
+	if s.share == nil {
+		http.Error(w, "Nothing is shared", http.StatusNotFound)
+		return
+	}
+	length, err := previewLength(r.Header.Get("Range"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusRequestedRangeNotSatisfiable)
+		return
+	}
+	path := r.URL.Query().Get("path")
+	data, size, err := s.share.Preview(path, length)
+	switch {
+	case errors.Is(err, share.ErrOutsideShare), errors.Is(err, share.ErrNotAFile):
+		http.Error(w, "Invalid path", http.StatusBadRequest)
+		return
+	case errors.Is(err, os.ErrNotExist):
+		http.Error(w, "No such file", http.StatusNotFound)
+		return
+	case err != nil:
+		slog.Error("Failed to read share preview", "path", path, "error", err)
+		http.Error(w, "Failed to read file", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	if len(data) == 0 {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	w.Header().Set("Content-Range", fmt.Sprintf("bytes 0-%d/%d", len(data)-1, size))
+	w.WriteHeader(http.StatusPartialContent)
+	if _, err := w.Write(data); err != nil {
+		slog.Warn("Failed to write share preview", "error", err)
+	}
+}
+
+// ZipPath is where browsers download a directory of the share, given by the path query
+// parameter, as a zip archive
+const ZipPath = "/share/zip"
+
+// ZipHandler streams a directory or file of the share, given by the path query parameter and the
+// whole share if empty, as a zip archive, so devices without lanFileSharer can download it with a
+// browser. Like pulls, one archive is streamed at a time.
+func (s *ReceiverService) ZipHandler(w http.ResponseWriter, r *http.Request) {
+	if s.share == nil {
+		http.Error(w, "Nothing is shared", http.StatusNotFound)
+		return
+	}
+	path := r.URL.Query().Get("path")
+	local, err := s.share.Resolve(path)
+	switch {
+	case errors.Is(err, share.ErrOutsideShare):
+		http.Error(w, "Invalid path", http.StatusBadRequest)
+		return
+	case errors.Is(err, os.ErrNotExist):
+		http.Error(w, "No such file or directory", http.StatusNotFound)
+		return
+	case err != nil:
+		slog.Error("Failed to resolve share path", "path", path, "error", err)
+		http.Error(w, "Failed to read share", http.StatusInternalServerError)
+		return
+	}
+	if !s.zipBusy.CompareAndSwap(false, true) {
+		http.Error(w, "Another download is running, try again shortly", http.StatusServiceUnavailable)
+		return
+	}
+	defer s.zipBusy.Store(false)
+
+	slog.Info("Streaming share as zip", "addr", r.RemoteAddr, "path", path)
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filepath.Base(local) + ".zip"}))
+	// The size is only known once the archive is written, so it is sent chunked and a
+	// failure can only cut it short, which the browser reports as a failed download
+	if err := s.share.WriteZip(w, path); err != nil {
+		slog.Error("Failed to stream share as zip", "path", path, "error", err)
+	}
+}
+
+// previewLength returns how many bytes a preview Range header asks for, at most MaxPreviewBytes.
+// Only ranges from the start of the file are served.
+func previewLength(header string) (int, error) {
+	if header == "" {
+		return MaxPreviewBytes, nil
+	}
+	spec, ok := strings.CutPrefix(header, "bytes=0-")
+	if !ok {
+		return 0, fmt.Errorf("only ranges from the start of the file are served, got %q", header)
+	}
+	last, err := strconv.Atoi(spec)
+	if err != nil || last < 0 {
+		return 0, fmt.Errorf("invalid range %q", header)
+	}
+	return min(last+1, MaxPreviewBytes), nil
+}
+
+// ListShare lists the directory path of the share at shareURL; empty lists the share itself.
+func (c *Client) ListShare(ctx context.Context, shareURL, path string) ([]share.Entry, error) {
+	endpoint, err := url.JoinPath(shareURL, "share")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create share url: %w", err)
+	}
+	endpoint += "?" + url.Values{"path": {path}}.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create share request: %w", err)
+	}
+
+	var entries []share.Entry
+	if err := c.doJSON(req, &entries); err != nil {
+		return nil, fmt.Errorf("listing %q failed: %w", path, err)
+	}
+	return entries, nil
+}
+
+// Pull asks the share at shareURL to send the files of payload to this device.
+func (c *Client) Pull(ctx context.Context, shareURL string, payload PullPayload) error {
+	endpoint, err := url.JoinPath(shareURL, "pull")
+	if err != nil {
+		return fmt.Errorf("failed to create pull url: %w", err)
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal pull payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create pull request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.HttpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: failed to send pull request: %w", transfer.ErrPeerUnreachable, err)
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			slog.Warn("failed to close response body", "error", err)
+		}
+	}()
+	if resp.StatusCode != http.StatusAccepted {
+		// The share explains refusals, such as being busy, in the body
+		reason, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("pull refused with status %s: %s", resp.Status, strings.TrimSpace(string(reason)))
+	}
+	return nil
+}
+
+// PreviewShare fetches up to n bytes from the start of the file path of the share at shareURL,
+// returning them with the size of the whole file.
+func (c *Client) PreviewShare(ctx context.Context, shareURL, path string, n int) ([]byte, int64, error) {
+	endpoint, err := url.JoinPath(shareURL, "share", "preview")
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to create preview url: %w", err)
+	}
+	endpoint += "?" + url.Values{"path": {path}}.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to create preview request: %w", err)
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=0-%d", n-1))
+
+	resp, err := c.HttpClient.Do(req)
+	if err != nil {
+		return nil, 0, fmt.Errorf("%w: failed to send preview request: %w", transfer.ErrPeerUnreachable, err)
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			slog.Warn("failed to close response body", "error", err)
+		}
+	}()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusPartialContent {
+		reason, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, 0, fmt.Errorf("preview of %q refused with status %s: %s", path, resp.Status, strings.TrimSpace(string(reason)))
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, int64(n)))
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read preview of %q: %w", path, err)
+	}
+	size := int64(len(data))
+	if _, total, ok := strings.Cut(resp.Header.Get("Content-Range"), "/"); ok {
+		if parsed, err := strconv.ParseInt(total, 10, 64); err == nil {
+			size = parsed
+		}
+	}
+	return data, size, nil
+}
@@ -0,0 +1,186 @@
+package api
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/rescp17/lanFileSharer/internal/app"
+	"github.com/rescp17/lanFileSharer/pkg/share"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newShareServer(t *testing.T, pull PullFunc) *httptest.Server {
+	t.Helper()
+	root := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(root, "docs"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "docs", "notes.txt"), []byte("hello world"), 0644))
+	shared, err := share.New(root)
+	require.NoError(t, err)
+
+	handler := NewAPI(make(chan tea.Msg, 1), app.NewSingleRequestManager(), nil)
+	handler.SetShare(shared, pull)
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestShareHandler(t *testing.T) {
+	server := newShareServer(t, nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	client := NewClient("test-service-id")
+
+	entries, err := client.ListShare(ctx, server.URL, "")
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "docs", entries[0].Path)
+
+	entries, err = client.ListShare(ctx, server.URL, "docs")
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "docs/notes.txt", entries[0].Path)
+
+	_, err = client.ListShare(ctx, server.URL, "../..")
+	assert.Error(t, err)
+}
+
+func TestPreviewHandler(t *testing.T) {
+	server := newShareServer(t, nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	client := NewClient("test-service-id")
+
+	data, size, err := client.PreviewShare(ctx, server.URL, "docs/notes.txt", 5)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(data))
+	assert.Equal(t, int64(11), size)
+
+	_, _, err = client.PreviewShare(ctx, server.URL, "docs", 5)
+	assert.Error(t, err, "directories have no preview")
+
+	request, err := http.NewRequestWithContext(ctx, "GET", server.URL+"/share/preview?path=docs/notes.txt", nil)
+	require.NoError(t, err)
+	request.Header.Set("Range", "bytes=6-10")
+	resp, err := http.DefaultClient.Do(request)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusRequestedRangeNotSatisfiable, resp.StatusCode, "only the start of files is served")
+}
+
+func TestPreviewLength(t *testing.T) {
+	length, err := previewLength("")
+	require.NoError(t, err)
+	assert.Equal(t, MaxPreviewBytes, length)
+
+	length, err = previewLength("bytes=0-99")
+	require.NoError(t, err)
+	assert.Equal(t, 100, length)
+
+	length, err = previewLength("bytes=0-99999999")
+	require.NoError(t, err)
+	assert.Equal(t, MaxPreviewBytes, length)
+
+	_, err = previewLength("bytes=0-")
+	assert.Error(t, err)
+}
+
+func TestZipHandler(t *testing.T) {
+	server := newShareServer(t, nil)
+
+	resp, err := http.Get(server.URL + ZipPath + "?path=docs")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "application/zip", resp.Header.Get("Content-Type"))
+	assert.Equal(t, `attachment; filename=docs.zip`, resp.Header.Get("Content-Disposition"))
+	archive, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	reader, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	require.NoError(t, err)
+	require.Len(t, reader.File, 2)
+	assert.Equal(t, "docs/", reader.File[0].Name)
+	assert.Equal(t, "docs/notes.txt", reader.File[1].Name)
+
+	for path, status := range map[string]int{"../..": http.StatusBadRequest, "missing": http.StatusNotFound} {
+		resp, err := http.Get(server.URL + ZipPath + "?path=" + path)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, status, resp.StatusCode, path)
+	}
+}
+
+func TestPullHandler(t *testing.T) {
+	var pulled []PullRequest
+	server := newShareServer(t, func(req PullRequest) error {
+		pulled = append(pulled, req)
+		return nil
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	client := NewClient("test-service-id")
+
+	err := client.Pull(ctx, server.URL, PullPayload{Paths: []string{"docs/notes.txt"}, Port: 8080, Token: "t1", ReceiverName: "laptop"})
+	require.NoError(t, err)
+	require.Len(t, pulled, 1)
+	assert.Equal(t, "127.0.0.1", pulled[0].Host)
+	assert.Equal(t, "t1", pulled[0].Token)
+	assert.Equal(t, "notes.txt", filepath.Base(pulled[0].Paths[0]))
+
+	err = client.Pull(ctx, server.URL, PullPayload{Paths: []string{""}, Port: 8080, Token: "t2"})
+	assert.Error(t, err, "the share itself cannot be pulled")
+	assert.Len(t, pulled, 1)
+}
+
+func TestPullHandler_UnknownOffer(t *testing.T) {
+	server := newShareServer(t, func(req PullRequest) error {
+		if req.Offer != "offer" {
+			return ErrUnknownOffer
+		}
+		return nil
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	client := NewClient("test-service-id")
+
+	payload := PullPayload{Paths: []string{"docs/notes.txt"}, Port: 8080, Token: "t1", Offer: "offer"}
+	require.NoError(t, client.Pull(ctx, server.URL, payload))
+
+	payload.Offer = "expired"
+	err := client.Pull(ctx, server.URL, payload)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "403")
+}
+
+func TestExpectPull(t *testing.T) {
+	handler := NewAPI(make(chan tea.Msg, 1), app.NewSingleRequestManager(), nil)
+	handler.ExpectPull("token")
+
+	assert.False(t, handler.server.takePull("other", "sharer"))
+	assert.True(t, handler.server.takePull("token", "sharer"))
+	assert.False(t, handler.server.takePull("token", "sharer"), "tokens are used once")
+
+	handler.ExpectOfferPull("offer", "sharer")
+	assert.False(t, handler.server.takePull("offer", "someone else"), "offer files name the key of the offer")
+	handler.ExpectOfferPull("offer", "sharer")
+	assert.True(t, handler.server.takePull("offer", "sharer"))
+}
+
+func TestExpectResume(t *testing.T) {
+	handler := NewAPI(make(chan tea.Msg, 1), app.NewSingleRequestManager(), nil)
+	handler.ExpectResume("token", "sender")
+
+	assert.False(t, handler.server.takeResume("token", "someone else"), "only the sender of the session resumes it")
+	assert.False(t, handler.server.takeResume("other", "sender"))
+	assert.True(t, handler.server.takeResume("token", "sender"))
+	assert.False(t, handler.server.takeResume("token", "sender"), "sessions are resumed once without asking")
+}
@@ -10,14 +10,19 @@ import (
 	"log/slog"
 	"net/http"
 	"net/url"
+	"os"
 	"strings"
 
 	"github.com/pion/webrtc/v4"
 	"github.com/rescp17/lanFileSharer/pkg/crypto"
+	"github.com/rescp17/lanFileSharer/pkg/transfer"
 )
 
 var ErrTransferRejected = errors.New("transfer rejected by the receiver")
 
+// ErrRequestTimedOut is returned when the receiver did not accept or reject the request in time
+var ErrRequestTimedOut = errors.New("request timed out waiting for the receiver")
+
 // APISignaler is the client-side implementation of the Signaler interface.
 // It communicates with the receiver's API endpoint to exchange WebRTC signaling messages.
 type APISignaler struct {
@@ -26,6 +31,18 @@ type APISignaler struct {
 	addIceCandidateFunc func(webrtc.ICECandidateInit) error // Callback to add candidates to the sender's connection
 	answerChan          chan *webrtc.SessionDescription
 	errChan             chan error
+	resumeToken         string   // Share token sent with the offer
+	relayTo             string   // Receiver a relay forwards the files to, sent with the offer
+	pullToken           string   // Token of the pull the offer answers, sent with it
+	guestToken          string   // Guest pass of the receiver, sent with the offer
+	icon                string   // Emoji or glyph the sender shows itself with, sent with the offer
+	fileAcks            bool     // Whether the receiver acknowledges every verified file
+	speedProbe          bool     // Whether the receiver confirms speed probes
+	hardLinks           bool     // Whether the receiver recreates hard links from FileLink messages
+	keepalive           bool     // Whether the receiver sends keepalives
+	controlChannel      bool     // Whether the receiver takes a control channel
+	bond                bool     // Whether the receiver takes extra connections to the session
+	stored              []string // Checksums of offered content the receiver already stores
 }
 
 // NewAPISignaler creates a new signaler for the sender side.
@@ -45,6 +62,81 @@ func NewAPISignaler(
 	}
 }
 
+// SetResumeToken sets the share token sent with the next offer.
+func (s *APISignaler) SetResumeToken(token string) {
+	s.resumeToken = token
+}
+
+// SetRelayTo makes the next offer ask the receiver, a relay, to forward the files to the named receiver.
+func (s *APISignaler) SetRelayTo(name string) {
+	s.relayTo = name
+}
+
+// SetPullToken makes the next offer answer the receiver's pull with token, so it is accepted without asking.
+func (s *APISignaler) SetPullToken(token string) {
+	s.pullToken = token
+}
+
+// SetGuestToken makes the next offer give the receiver a guest pass it issued, so it is accepted without asking.
+func (s *APISignaler) SetGuestToken(token string) {
+	s.guestToken = token
+}
+
+// SetIcon makes offers carry the emoji or glyph the receiver shows this device with.
+func (s *APISignaler) SetIcon(icon string) {
+	s.icon = icon
+}
+
+// FileAcksSupported reports whether the receiver's answer announced per-file ACKs.
+// Older receivers do not send ACKs, so the sender must not wait for them.
+func (s *APISignaler) FileAcksSupported() bool {
+	return s.fileAcks
+}
+
+// SpeedProbeSupported reports whether the receiver's answer announced speed probes.
+// It is only meaningful after WaitForAnswer returned the answer.
+func (s *APISignaler) SpeedProbeSupported() bool {
+	return s.speedProbe
+}
+
+// HardLinksSupported reports whether the receiver's answer announced FileLink messages.
+// Older receivers need the content of every file, hard link or not.
+func (s *APISignaler) HardLinksSupported() bool {
+	return s.hardLinks
+}
+
+// KeepaliveSupported reports whether the receiver's answer announced keepalives.
+// Older receivers send nothing while idle, so the sender must not end the session for it.
+func (s *APISignaler) KeepaliveSupported() bool {
+	return s.keepalive
+}
+
+// ControlChannelSupported reports whether the receiver's answer announced a control channel.
+// Older receivers take every channel for files, so the sender must not open one.
+func (s *APISignaler) ControlChannelSupported() bool {
+	return s.controlChannel
+}
+
+// BondSupported reports whether the receiver's answer announced POST /bond.
+func (s *APISignaler) BondSupported() bool {
+	return s.bond
+}
+
+// StoredContent returns the checksums of the offered files whose content the receiver's
+// answer said it stores, which can be sent as FileLink messages without a target.
+func (s *APISignaler) StoredContent() []string {
+	return s.stored
+}
+
+// senderName returns the name sent with offers: the hostname, or "" if it is unknown
+func senderName() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return ""
+	}
+	return hostname
+}
+
 // SendOffer sends the offer to the receiver and starts listening for the SSE event stream.
 // This is the main entry point that triggers the entire signaling process.
 func (s *APISignaler) SendOffer(ctx context.Context, offer webrtc.SessionDescription, signedFiles *crypto.SignedFileStructure) error {
@@ -60,6 +152,14 @@ func (s *APISignaler) SendOffer(ctx context.Context, offer webrtc.SessionDescrip
 	payload := AskPayload{
 		SignedFiles: signedFiles,
 		Offer:       offer,
+		ResumeToken: s.resumeToken,
+		WriteAcks:   true,
+		Keepalive:   true,
+		SenderName:  senderName(),
+		SenderIcon:  s.icon,
+		RelayTo:     s.relayTo,
+		PullToken:   s.pullToken,
+		GuestToken:  s.guestToken,
 	}
 	body, err := json.Marshal(payload)
 	if err != nil {
@@ -73,14 +173,19 @@ func (s *APISignaler) SendOffer(ctx context.Context, offer webrtc.SessionDescrip
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Accept", "text/event-stream")
 
-	resp, err := s.apiClient.HttpClient.Do(req) //nolint:bodyclose // Body is closed in goroutine
+	// The stream stays open while the receiver decides, which it bounds with its own
+	// accept timeout, so only ctx limits it rather than the client's request timeout
+	streamClient := *s.apiClient.HttpClient
+	streamClient.Timeout = 0
+	resp, err := streamClient.Do(req) //nolint:bodyclose // Body is closed in goroutine
 	if err != nil {
-		return fmt.Errorf("failed to connect to /ask endpoint: %w", err)
+		return fmt.Errorf("%w: failed to connect to /ask endpoint: %w", transfer.ErrPeerUnreachable, err)
 	}
 
 	if resp.StatusCode != http.StatusOK {
 		return fmt.Errorf("failed to connect to /ask endpoint: %s", resp.Status)
 	}
+	transfer.TraceSignal(transfer.TraceOut, "/ask", "ask", len(body))
 
 	// Start a goroutine to process the streaming response.
 	// The response body will be closed in the goroutine via defer.
@@ -99,7 +204,7 @@ func (s *APISignaler) listenToSSEResponse(resp *http.Response) {
 	scanner := bufio.NewScanner(resp.Body)
 	var currentEvent string
 	var dataBuffer = &bytes.Buffer{}
-	var answerReceived, rejectionReceived bool
+	var answerReceived, rejectionReceived, timeoutReceived bool
 
 	for scanner.Scan() {
 		line := scanner.Text()
@@ -124,26 +229,31 @@ func (s *APISignaler) listenToSSEResponse(resp *http.Response) {
 				answerReceived = true
 			case "rejection":
 				rejectionReceived = true
+			case "timeout":
+				timeoutReceived = true
 			}
 		}
 	}
 
 	if err := scanner.Err(); err != nil {
-		s.sendError(fmt.Errorf("error reading SSE stream: %w", err))
-	} else if !answerReceived && !rejectionReceived {
-		s.sendError(fmt.Errorf("no answer or rejection received"))
+		s.sendError(fmt.Errorf("%w: error reading SSE stream: %w", transfer.ErrConnectionLost, err))
+	} else if !answerReceived && !rejectionReceived && !timeoutReceived {
+		s.sendError(fmt.Errorf("%w: no answer or rejection received", transfer.ErrConnectionLost))
 	}
 }
 
 // routeEvent dispatches SSE events to the appropriate handler.
 func (s *APISignaler) routeEvent(event, data string) {
+	transfer.TraceSignal(transfer.TraceIn, "/ask", event, len(data))
 	switch event {
 	case "answer":
 		s.handleAnswerEvent(data)
 	case "candidate":
 		s.handleCandidateEvent(data)
 	case "rejection":
-		s.sendError(ErrTransferRejected)
+		s.sendError(rejectionError(data))
+	case "timeout":
+		s.sendError(ErrRequestTimedOut)
 	case "candidates_done":
 		slog.Info("Receiver has finished sending candidates.")
 	default:
@@ -151,15 +261,45 @@ func (s *APISignaler) routeEvent(event, data string) {
 	}
 }
 
+// rejectionError returns ErrTransferRejected with the receiver's reason, if it gave one
+func rejectionError(data string) error {
+	var rejection struct {
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal([]byte(data), &rejection); err != nil || rejection.Message == "" {
+		return ErrTransferRejected
+	}
+	return fmt.Errorf("%w: %s", ErrTransferRejected, rejection.Message)
+}
+
 func (s *APISignaler) handleAnswerEvent(data string) {
 	var respData struct {
-		Answer webrtc.SessionDescription `json:"answer"`
+		Answer     webrtc.SessionDescription `json:"answer"`
+		FileAcks   bool                      `json:"file_acks"`
+		SpeedProbe bool                      `json:"speed_probe"`
+		HardLinks  bool                      `json:"hard_links"`
+		Keepalive  bool                      `json:"keepalive"`
+		Control    bool                      `json:"control_channel"`
+		Bond       bool                      `json:"bond"`
+		Stored     []string                  `json:"stored"`
+		MAC        string                    `json:"mac"`
 	}
 	// Answer is an important part of WebRTC connection establishment
 	if err := json.Unmarshal([]byte(data), &respData); err != nil {
 		s.sendError(fmt.Errorf("failed to unmarshal answer event: %w", err))
 		return
 	}
+	if err := s.apiClient.psk.checkAnswer(respData.Answer.SDP, respData.MAC); err != nil {
+		s.sendError(err)
+		return
+	}
+	s.fileAcks = respData.FileAcks
+	s.speedProbe = respData.SpeedProbe
+	s.hardLinks = respData.HardLinks
+	s.keepalive = respData.Keepalive
+	s.controlChannel = respData.Control
+	s.bond = respData.Bond
+	s.stored = respData.Stored
 	s.answerChan <- &respData.Answer
 }
 
@@ -143,6 +143,29 @@ func TestAPISignaler_WaitForAnswer_Success(t *testing.T) {
 	assert.NotNil(t, answer, "Answer should not be nil")
 	assert.Equal(t, webrtc.SDPTypeAnswer, answer.Type, "Expected answer type")
 	assert.Equal(t, "test-answer-sdp", answer.SDP, "Expected test-answer-sdp")
+	assert.False(t, signaler.FileAcksSupported(), "Answers without file_acks come from older receivers")
+	assert.False(t, signaler.HardLinksSupported(), "Answers without hard_links come from older receivers")
+}
+
+func TestAPISignaler_WaitForAnswer_FileAcks(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(http.StatusOK)
+
+		fmt.Fprint(w, "event: answer\n")
+		fmt.Fprint(w, "data: {\"answer\":{\"type\":\"answer\",\"sdp\":\"test-answer-sdp\"},\"file_acks\":true,\"hard_links\":true}\n")
+		fmt.Fprint(w, "\n")
+	}))
+	defer server.Close()
+
+	signaler := NewAPISignaler(NewClient("test-service-id"), server.URL, mockAddICECandidate)
+	ctx := context.Background()
+	require.NoError(t, signaler.SendOffer(ctx, createTestOffer(), createTestSignedFiles(t)))
+
+	_, err := signaler.WaitForAnswer(ctx)
+	require.NoError(t, err)
+	assert.True(t, signaler.FileAcksSupported())
+	assert.True(t, signaler.HardLinksSupported())
 }
 
 func TestAPISignaler_WaitForAnswer_Rejection(t *testing.T) {
@@ -285,3 +308,23 @@ func stringPtr(s string) *string {
 func uint16Ptr(u uint16) *uint16 {
 	return &u
 }
+
+func TestAPISignaler_WaitForAnswer_RequestTimedOut(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(http.StatusOK)
+
+		fmt.Fprint(w, "event: timeout\n")
+		fmt.Fprint(w, "data: {\"status\":\"timeout\"}\n")
+		fmt.Fprint(w, "\n")
+	}))
+	defer server.Close()
+
+	signaler := NewAPISignaler(NewClient("test-service-id"), server.URL, mockAddICECandidate)
+	ctx := context.Background()
+	require.NoError(t, signaler.SendOffer(ctx, createTestOffer(), createTestSignedFiles(t)))
+
+	answer, err := signaler.WaitForAnswer(ctx)
+	assert.ErrorIs(t, err, ErrRequestTimedOut)
+	assert.Nil(t, answer)
+}
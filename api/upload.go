@@ -0,0 +1,213 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/rescp17/lanFileSharer/internal/app_events/receiver"
+	"github.com/rescp17/lanFileSharer/internal/util"
+	"github.com/rescp17/lanFileSharer/pkg/audit"
+	"github.com/rescp17/lanFileSharer/pkg/transfer"
+)
+
+// UploadPath is where devices without lanFileSharer, such as phones, upload files with a browser
+const UploadPath = "/upload"
+
+// uploadTokenBytes is the number of random bytes of a generated upload token
+const uploadTokenBytes = 16
+
+// UploadResult reports what an upload saved
+type UploadResult struct {
+	Files  int   `json:"files"`  // Files saved
+	Failed int   `json:"failed"` // Files that failed, such as those the scanner flagged
+	Bytes  int64 `json:"bytes"`  // Bytes of the saved files
+}
+
+// UploadFunc saves the file parts of files, uploaded by the device at address sender, as the
+// files of a session. The result counts what was saved even when an error ends the upload.
+type UploadFunc func(ctx context.Context, sender string, files *multipart.Reader) (UploadResult, error)
+
+// SetUpload enables GET and POST /upload for requests giving token, saving their files with
+// upload. An empty token disables them.
+func (a *API) SetUpload(token string, upload UploadFunc) {
+	a.server.uploadToken = token
+	a.server.upload = upload
+}
+
+// NewUploadToken returns a random token for SetUpload
+func NewUploadToken() (string, error) {
+	b := make([]byte, uploadTokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate upload token: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// uploadAllowed reports whether uploads are enabled and r gives their token, in the token
+// query parameter or as a bearer token
+func (s *ReceiverService) uploadAllowed(r *http.Request) bool {
+	if s.upload == nil || s.uploadToken == "" {
+		return false
+	}
+	token := r.URL.Query().Get("token")
+	if bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		token = bearer
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(s.uploadToken)) == 1
+}
+
+// uploadPage is the form of GET /upload and the summary shown after a browser posted it
+var uploadPage = template.Must(template.New("upload").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Send files</title>
+<style>body{font-family:sans-serif;max-width:32em;margin:2em auto;padding:0 1em}input,button{font-size:1.1em;margin:.5em 0}</style>
+</head>
+<body>
+<h1>Send files</h1>
+{{with .Result}}<p>Saved {{.Files}} files{{if .Failed}}, {{.Failed}} failed{{end}}.</p>{{end}}
+{{with .Error}}<p>Upload failed: {{.}}</p>{{end}}
+<form method="post" action="{{.Action}}" enctype="multipart/form-data">
+<input type="file" name="files" multiple required><br>
+<button type="submit">Send</button>
+</form>
+</body>
+</html>
+`))
+
+// uploadPageData fills uploadPage
+type uploadPageData struct {
+	Action string
+	Result *UploadResult
+	Error  string
+}
+
+// writeUploadPage answers a browser with the upload form, after the result or error of an upload if set
+func (s *ReceiverService) writeUploadPage(w http.ResponseWriter, status int, data uploadPageData) {
+	// Callers got here with the token, which browsers post the form with
+	data.Action = UploadPath + "?token=" + url.QueryEscape(s.uploadToken)
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	if err := uploadPage.Execute(w, data); err != nil {
+		slog.Error("Failed to render upload page", "error", err)
+	}
+}
+
+// UploadFormHandler serves the page browsers upload files with
+func (s *ReceiverService) UploadFormHandler(w http.ResponseWriter, r *http.Request) {
+	if !s.uploadAllowed(r) {
+		writeForbidden(w)
+		return
+	}
+	s.writeUploadPage(w, http.StatusOK, uploadPageData{})
+}
+
+// UploadHandler saves the files of a multipart upload. The token stands in for a signed file
+// structure and the user accepting it, so uploads are refused when only trusted senders are;
+// quotas, the audit log and everything files pass on the way to disk apply as to any session.
+func (s *ReceiverService) UploadHandler(w http.ResponseWriter, r *http.Request) {
+	if !s.uploadAllowed(r) {
+		writeForbidden(w)
+		return
+	}
+	transfer.TraceSignal(transfer.TraceIn, UploadPath, "upload", int(r.ContentLength))
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	record := audit.Record{Event: audit.EventRequest, Peer: r.RemoteAddr, Bytes: r.ContentLength, Detail: "browser upload"}
+	s.audit(record)
+
+	refuse := func(status int, reason string) {
+		slog.Info("Refusing upload", "addr", r.RemoteAddr, "reason", reason)
+		s.audit(record.As(audit.EventDeclined, reason))
+		s.uiMessages <- receiver.RequestDeclinedMsg{Availability: receiver.Available, Reason: reason}
+		s.writeUploadError(w, r, status, reason)
+	}
+	switch {
+	case s.trustedOnly:
+		refuse(http.StatusForbidden, "this receiver only accepts senders it trusts")
+		return
+	case s.Availability() != receiver.Available:
+		reason := s.dndMessage
+		if reason == "" {
+			reason = "the receiver is " + s.Availability().String()
+		}
+		refuse(http.StatusServiceUnavailable, reason)
+		return
+	case r.ContentLength < 0:
+		refuse(http.StatusLengthRequired, "the upload has no Content-Length")
+		return
+	}
+	// Uploads have no key, so the quota is counted per address
+	quotaKey := "upload:" + host
+	if err := s.quota.Check(quotaKey, r.ContentLength); err != nil {
+		refuse(http.StatusRequestEntityTooLarge, err.Error())
+		return
+	}
+	files, err := r.MultipartReader()
+	if err != nil {
+		http.Error(w, "Expected a multipart/form-data upload", http.StatusBadRequest)
+		return
+	}
+	if !s.uploadBusy.CompareAndSwap(false, true) {
+		refuse(http.StatusServiceUnavailable, "another upload is running")
+		return
+	}
+	defer s.uploadBusy.Store(false)
+
+	s.audit(record.As(audit.EventAccepted, ""))
+	result, err := s.upload(r.Context(), host, files)
+	if recordErr := s.quota.Record(quotaKey, result.Bytes); recordErr != nil {
+		slog.Warn("Failed to record quota usage", "error", recordErr)
+	}
+	record.Files = result.Files + result.Failed
+	record.Bytes = result.Bytes
+	if err != nil {
+		slog.Error("Upload failed", "addr", r.RemoteAddr, "error", err)
+		s.audit(record.As(audit.EventFailed, err.Error()))
+		s.writeUploadError(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if result.Failed > 0 {
+		s.audit(record.As(audit.EventFailed, fmt.Sprintf("%d files failed", result.Failed)))
+	} else {
+		s.audit(record.As(audit.EventCompleted, ""))
+	}
+
+	if wantsHTML(r) {
+		s.writeUploadPage(w, http.StatusOK, uploadPageData{Result: &result})
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		slog.Error("Failed to encode upload result", "error", err)
+	}
+}
+
+// writeUploadError answers an upload that was refused or failed, with a page for browsers
+func (s *ReceiverService) writeUploadError(w http.ResponseWriter, r *http.Request, status int, reason string) {
+	if wantsHTML(r) {
+		s.writeUploadPage(w, status, uploadPageData{Error: util.SanitizeText(reason)})
+		return
+	}
+	http.Error(w, reason, status)
+}
+
+// wantsHTML reports whether r came from a browser form rather than a script
+func wantsHTML(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "text/html")
+}
@@ -0,0 +1,101 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/rescp17/lanFileSharer/internal/app"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// uploadBody returns a multipart body with a form field and files, by name, and its content type
+func uploadBody(t *testing.T, files map[string]string) (*bytes.Buffer, string) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	require.NoError(t, writer.WriteField("note", "not a file"))
+	for name, content := range files {
+		part, err := writer.CreateFormFile("files", name)
+		require.NoError(t, err)
+		_, err = io.WriteString(part, content)
+		require.NoError(t, err)
+	}
+	require.NoError(t, writer.Close())
+	return &body, writer.FormDataContentType()
+}
+
+func TestUploadHandler(t *testing.T) {
+	handler := NewAPI(make(chan tea.Msg, 10), app.NewSingleRequestManager(), nil)
+	post := func(target string, header http.Header) *httptest.ResponseRecorder {
+		body, contentType := uploadBody(t, map[string]string{"a.txt": "hello", "b.txt": "world!"})
+		request := httptest.NewRequest("POST", target, body)
+		request.Header.Set("Content-Type", contentType)
+		for name, values := range header {
+			request.Header[name] = values
+		}
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/upload?token=secret", nil))
+	assert.Equal(t, http.StatusForbidden, recorder.Code, "uploads are disabled by default")
+
+	received := make(map[string]string)
+	handler.SetUpload("secret", func(ctx context.Context, sender string, files *multipart.Reader) (UploadResult, error) {
+		assert.Equal(t, "192.0.2.1", sender)
+		var result UploadResult
+		for {
+			part, err := files.NextPart()
+			if err == io.EOF {
+				return result, nil
+			}
+			require.NoError(t, err)
+			if part.FileName() == "" {
+				continue
+			}
+			content, err := io.ReadAll(part)
+			require.NoError(t, err)
+			received[part.FileName()] = string(content)
+			result.Files++
+			result.Bytes += int64(len(content))
+		}
+	})
+
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/upload?token=wrong", nil))
+	assert.Equal(t, http.StatusForbidden, recorder.Code)
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/upload?token=secret", nil))
+	require.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), `action="/upload?token=secret"`)
+
+	assert.Equal(t, http.StatusForbidden, post("/upload", nil).Code, "the token is required")
+	recorder = post("/upload", http.Header{"Authorization": {"Bearer secret"}})
+	require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+	var result UploadResult
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &result))
+	assert.Equal(t, UploadResult{Files: 2, Bytes: 11}, result)
+	assert.Equal(t, map[string]string{"a.txt": "hello", "b.txt": "world!"}, received)
+
+	recorder = post("/upload?token=secret", http.Header{"Accept": {"text/html"}})
+	require.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "Saved 2 files")
+
+	quota, err := NewQuota(QuotaRules{MaxTransferBytes: 100}, "")
+	require.NoError(t, err)
+	handler.SetQuota(quota)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, post("/upload?token=secret", nil).Code, "uploads count against the quota")
+	handler.SetQuota(nil)
+
+	handler.SetTrustedOnly(true)
+	assert.Equal(t, http.StatusForbidden, post("/upload?token=secret", nil).Code, "browsers are never trusted senders")
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/rescp17/lanFileSharer/pkg/audit"
+	receiverApp "github.com/rescp17/lanFileSharer/pkg/receiver"
+)
+
+// newAuditCmd creates the command for checking and exporting the audit log
+func newAuditCmd() *cobra.Command {
+	auditCmd := &cobra.Command{
+		Use:   "audit",
+		Short: "Check and export the receiver audit log",
+		Long: "The audit log records connection attempts, acceptances, rejections and completed sessions " +
+			"when \"audit_log\" is enabled in the config file. Each entry carries the hash of the one before it, " +
+			"so edited or removed entries break the chain.",
+	}
+	auditCmd.PersistentFlags().String("file", "", "Audit log to read (default is the one in the config directory)")
+
+	verifyCmd := &cobra.Command{
+		Use:   "verify",
+		Short: "Check that the audit log chain is intact",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			entries, err := readAuditLog(cmd)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Audit log intact: %d entries\n", len(entries))
+			return nil
+		},
+	}
+
+	exportCmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export the verified audit log entries",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			format, _ := cmd.Flags().GetString("format")
+			if format != "json" && format != "csv" {
+				return fmt.Errorf("unsupported format %q, use json or csv", format)
+			}
+			entries, err := readAuditLog(cmd)
+			if err != nil {
+				return err
+			}
+
+			out := cmd.OutOrStdout()
+			if path, _ := cmd.Flags().GetString("out"); path != "" {
+				file, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+				if err != nil {
+					return fmt.Errorf("failed to create export: %w", err)
+				}
+				defer file.Close()
+				out = file
+			}
+			if format == "csv" {
+				return exportAuditCSV(out, entries)
+			}
+			encoder := json.NewEncoder(out)
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(entries)
+		},
+	}
+	exportCmd.Flags().String("format", "json", "Export format: json or csv")
+	exportCmd.Flags().String("out", "", "Write the export to a file instead of stdout")
+
+	auditCmd.AddCommand(verifyCmd, exportCmd)
+	return auditCmd
+}
+
+// readAuditLog reads and verifies the audit log selected by the --file flag
+func readAuditLog(cmd *cobra.Command) ([]audit.Entry, error) {
+	path, _ := cmd.Flags().GetString("file")
+	if path == "" {
+		path = receiverApp.AuditLogPath()
+	}
+	if path == "" {
+		return nil, fmt.Errorf("could not resolve the audit log location")
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer file.Close()
+
+	entries, err := audit.Verify(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s (%d entries verified before it): %w", path, len(entries), err)
+	}
+	return entries, nil
+}
+
+func exportAuditCSV(out io.Writer, entries []audit.Entry) error {
+	w := csv.NewWriter(out)
+	if err := w.Write([]string{"seq", "time", "event", "peer", "fingerprint", "files", "bytes", "detail", "hash"}); err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if err := w.Write([]string{
+			strconv.FormatInt(e.Seq, 10), e.Time.Format(time.RFC3339), string(e.Event), e.Peer, e.Fingerprint,
+			strconv.Itoa(e.Files), strconv.FormatInt(e.Bytes, 10), e.Detail, e.Hash,
+		}); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+
+	"github.com/spf13/cobra"
+
+	"github.com/rescp17/lanFileSharer/pkg/discovery"
+	receiverApp "github.com/rescp17/lanFileSharer/pkg/receiver"
+	senderApp "github.com/rescp17/lanFileSharer/pkg/sender"
+	"github.com/rescp17/lanFileSharer/pkg/transfer"
+)
+
+// newDiffCmd creates the command that compares a directory with what a peer received
+func newDiffCmd() *cobra.Command {
+	diffCmd := &cobra.Command{
+		Use:   "diff <dir>",
+		Short: "Show which files of a directory a peer is missing, has with other content or has in addition",
+		Long: "Hash the files of <dir> and send their signed structure, without content, to the peer, which " +
+			"compares it with its output directory. Nothing is shown to the peer's user, so the peer only answers " +
+			"senders whose keys it trusts: accept a transfer from this device on the peer once first.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runDiff(cmd, args[0])
+		},
+	}
+	diffCmd.Flags().String("peer", "", "Receiver to compare with (hostname or service name)")
+	_ = diffCmd.MarkFlagRequired("peer")
+	return diffCmd
+}
+
+func runDiff(cmd *cobra.Command, dir string) error {
+	peer, _ := cmd.Flags().GetString("peer")
+	out := cmd.OutOrStdout()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	cfg, err := loadConfig(cmd)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	files, err := transfer.PrepareNodes(ctx, []string{dir}, cfg.HashWorkerCount(), printPreparation(jsonProgress{}))
+	if err != nil {
+		return fmt.Errorf("%w: %w", errLocalFiles, err)
+	}
+
+	app := senderApp.NewAppWithOptions(&discovery.MDNSAdapter{}, senderApp.Options{
+		DeviceKeyPath:      senderApp.DeviceKeyPath(),
+		KeyLifetime:        cfg.KeyLifetime(),
+		KeyGrace:           cfg.KeyGrace(),
+		SignatureAlgorithm: senderApp.SignatureAlgorithm(cfg),
+		Scope:              senderApp.ServiceScope(cfg),
+		PeerFilter:         receiverApp.PeerFilter(cfg),
+		LANOnly:            cfg.LANOnly,
+	})
+	findCtx, cancel := context.WithTimeout(ctx, discoveryTimeout)
+	receiver, err := app.FindReceiver(findCtx, peer)
+	cancel()
+	if err != nil {
+		return err
+	}
+
+	report, err := app.CompareWithReceiver(ctx, receiver, files)
+	if err != nil {
+		return fmt.Errorf("%w (the peer only compares with senders it trusts)", err)
+	}
+
+	fmt.Fprintf(out, "Compared with %s: %d the same, %d missing, %d different, %d extra\n",
+		receiver.Name, report.Same, len(report.Missing), len(report.Different), len(report.Extra))
+	printDiffSection(out, "Missing on "+receiver.Name, report.Missing)
+	printDiffSection(out, "Different on "+receiver.Name, report.Different)
+	printDiffSection(out, "Extra on "+receiver.Name, report.Extra)
+	return nil
+}
+
+func printDiffSection(out io.Writer, title string, paths []string) {
+	if len(paths) == 0 {
+		return
+	}
+	fmt.Fprintf(out, "\n%s:\n", title)
+	for _, path := range paths {
+		fmt.Fprintf(out, "  %s\n", path)
+	}
+}
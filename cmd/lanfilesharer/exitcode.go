@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+
+	"github.com/rescp17/lanFileSharer/api"
+	senderApp "github.com/rescp17/lanFileSharer/pkg/sender"
+	"github.com/rescp17/lanFileSharer/pkg/transfer"
+)
+
+// Exit codes of lanfilesharer. They are stable, so scripts can branch on the kind of failure.
+const (
+	exitOK          = 0
+	exitFailure     = 1   // Any other failure, including invalid arguments
+	exitRejected    = 2   // The receiver declined the transfer
+	exitNetwork     = 3   // The receiver could not be reached, or the connection to it broke
+	exitPartial     = 4   // Some files were sent and others failed
+	exitNotFound    = 5   // No receiver with the given name was found
+	exitNoAnswer    = 6   // The receiver did not answer the request in time
+	exitLocalFiles  = 7   // The files to send could not be read
+	exitInterrupted = 130 // Interrupted with Ctrl+C
+)
+
+// errLocalFiles marks failures to read the files of a headless send
+var errLocalFiles = errors.New("cannot read the files to send")
+
+// headlessError is the failure of a headless command, with its exit code and a reason for scripts
+type headlessError struct {
+	err         error
+	code        int
+	reason      string
+	filesSent   int
+	filesFailed int
+}
+
+// Error implements error
+func (e *headlessError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap returns the underlying error
+func (e *headlessError) Unwrap() error {
+	return e.err
+}
+
+// newHeadlessError classifies err, the failure of a send that delivered sent files and
+// failed on failed others
+func newHeadlessError(err error, sent, failed int) *headlessError {
+	e := &headlessError{err: err, filesSent: sent, filesFailed: failed}
+	var netErr net.Error
+	switch {
+	case errors.Is(err, context.Canceled):
+		e.code, e.reason = exitInterrupted, "interrupted"
+	case errors.Is(err, api.ErrTransferRejected):
+		e.code, e.reason = exitRejected, "rejected"
+	case errors.Is(err, api.ErrRequestTimedOut):
+		e.code, e.reason = exitNoAnswer, "no_answer"
+	case errors.Is(err, senderApp.ErrReceiverNotFound):
+		e.code, e.reason = exitNotFound, "receiver_not_found"
+	case errors.Is(err, errLocalFiles):
+		e.code, e.reason = exitLocalFiles, "local_files"
+	case sent > 0 && failed > 0:
+		e.code, e.reason = exitPartial, "partial"
+	case transfer.IsTransient(err), errors.As(err, &netErr):
+		e.code, e.reason = exitNetwork, "network"
+	default:
+		e.code, e.reason = exitFailure, "failed"
+	}
+	if code := transfer.ErrorCode(err); code != "" && e.code == exitFailure {
+		// The taxonomy names the failure more precisely, e.g. "disk_full"
+		e.reason = code
+	}
+	return e
+}
+
+// headlessResult is the result of a headless send that ended with err after sending sent
+// files and failing on failed others: nil if it failed on none, a *headlessError otherwise
+func headlessResult(err error, sent, failed int) error {
+	if err == nil && failed > 0 {
+		err = fmt.Errorf("%d of %d files failed", failed, sent+failed)
+	}
+	if err != nil {
+		return newHeadlessError(err, sent, failed)
+	}
+	return nil
+}
+
+// failureReport is the JSON object a failed headless command writes last on stderr
+type failureReport struct {
+	Error struct {
+		Reason      string `json:"reason"`
+		ExitCode    int    `json:"exit_code"`
+		Message     string `json:"message"`
+		FilesSent   int    `json:"files_sent"`
+		FilesFailed int    `json:"files_failed"`
+	} `json:"error"`
+}
+
+// writeJSON writes the failure as a single line of JSON
+func (e *headlessError) writeJSON(w io.Writer) error {
+	var report failureReport
+	report.Error.Reason = e.reason
+	report.Error.ExitCode = e.code
+	report.Error.Message = e.err.Error()
+	report.Error.FilesSent = e.filesSent
+	report.Error.FilesFailed = e.filesFailed
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		return fmt.Errorf("failed to write failure report: %w", err)
+	}
+	return nil
+}
+
+// exitCode returns the exit code for the error a command returned
+func exitCode(err error) int {
+	if err == nil {
+		return exitOK
+	}
+	var failure *headlessError
+	if errors.As(err, &failure) {
+		return failure.code
+	}
+	return exitFailure
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rescp17/lanFileSharer/api"
+	"github.com/rescp17/lanFileSharer/pkg/transfer"
+)
+
+func TestHeadlessResult(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		sent    int
+		failed  int
+		code    int
+		reason  string
+		message string
+	}{
+		{"All sent", nil, 3, 0, exitOK, "", ""},
+		{"All failed", nil, 0, 3, exitFailure, "failed", "3 of 3 files failed"},
+		{"Partial", nil, 2, 1, exitPartial, "partial", "1 of 3 files failed"},
+		{"Partial before the connection broke", fmt.Errorf("%w: no ACK", transfer.ErrConnectionLost), 2, 1, exitPartial, "partial", ""},
+		{"Cancelled", fmt.Errorf("transfer cancelled: %w", context.Canceled), 1, 0, exitInterrupted, "interrupted", "transfer cancelled: context canceled"},
+		{"Cancelled after failures", fmt.Errorf("transfer cancelled: %w", context.Canceled), 1, 1, exitInterrupted, "interrupted", ""},
+		{"Rejected", api.ErrTransferRejected, 0, 0, exitRejected, "rejected", ""},
+		{"Connection lost", fmt.Errorf("%w: no ACK", transfer.ErrConnectionLost), 0, 0, exitNetwork, "network", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := headlessResult(tt.err, tt.sent, tt.failed)
+			assert.Equal(t, tt.code, exitCode(err))
+			if tt.code == exitOK {
+				assert.NoError(t, err)
+				return
+			}
+
+			var failure *headlessError
+			require.True(t, errors.As(err, &failure))
+			assert.Equal(t, tt.reason, failure.reason)
+			if tt.message != "" {
+				assert.Equal(t, tt.message, failure.Error())
+			}
+
+			var buf bytes.Buffer
+			require.NoError(t, failure.writeJSON(&buf))
+			var report failureReport
+			require.NoError(t, json.Unmarshal(buf.Bytes(), &report))
+			assert.Equal(t, tt.code, report.Error.ExitCode)
+			assert.Equal(t, tt.reason, report.Error.Reason)
+			assert.Equal(t, tt.sent, report.Error.FilesSent)
+			assert.Equal(t, tt.failed, report.Error.FilesFailed)
+		})
+	}
+}
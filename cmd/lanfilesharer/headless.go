@@ -0,0 +1,510 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/spf13/cobra"
+
+	"github.com/rescp17/lanFileSharer/api"
+	appevents "github.com/rescp17/lanFileSharer/internal/app_events"
+	"github.com/rescp17/lanFileSharer/internal/app_events/receiver"
+	"github.com/rescp17/lanFileSharer/internal/app_events/sender"
+	"github.com/rescp17/lanFileSharer/internal/config"
+	"github.com/rescp17/lanFileSharer/internal/util"
+	"github.com/rescp17/lanFileSharer/pkg/crypto"
+	"github.com/rescp17/lanFileSharer/pkg/discovery"
+	"github.com/rescp17/lanFileSharer/pkg/events"
+	"github.com/rescp17/lanFileSharer/pkg/fileInfo"
+	"github.com/rescp17/lanFileSharer/pkg/jobs"
+	"github.com/rescp17/lanFileSharer/pkg/peers"
+	receiverApp "github.com/rescp17/lanFileSharer/pkg/receiver"
+	"github.com/rescp17/lanFileSharer/pkg/recent"
+	"github.com/rescp17/lanFileSharer/pkg/scan"
+	senderApp "github.com/rescp17/lanFileSharer/pkg/sender"
+	"github.com/rescp17/lanFileSharer/pkg/storage"
+	"github.com/rescp17/lanFileSharer/pkg/transfer"
+	"github.com/rescp17/lanFileSharer/pkg/webhook"
+)
+
+// discoveryTimeout bounds how long a headless send waits for the receiver to appear
+const discoveryTimeout = 15 * time.Second
+
+// preparationPrintInterval is how often a headless send prints the progress of preparing files
+const preparationPrintInterval = 2 * time.Second
+
+// jsonProgress writes the NDJSON progress stream of a headless send, if --progress-json is set
+type jsonProgress struct {
+	writer *events.NDJSONWriter
+}
+
+// newJSONProgress returns the progress stream selected by the flags of cmd
+func newJSONProgress(cmd *cobra.Command) jsonProgress {
+	if enabled, _ := cmd.Flags().GetBool("progress-json"); enabled {
+		return jsonProgress{writer: events.NewNDJSONWriter(os.Stdout)}
+	}
+	return jsonProgress{}
+}
+
+// event writes the record of a transfer event
+func (p jsonProgress) event(e events.Event) {
+	if p.writer == nil {
+		return
+	}
+	if err := p.writer.WriteEvent(e); err != nil {
+		slog.Debug("Dropping progress record", "error", err)
+	}
+}
+
+// record writes a record that does not come from a transfer event
+func (p jsonProgress) record(record events.ProgressRecord) {
+	if p.writer == nil {
+		return
+	}
+	if err := p.writer.Write(record); err != nil {
+		slog.Debug("Dropping progress record", "error", err)
+	}
+}
+
+// collectFiles builds the file list for a headless send from args or stdin
+func collectFiles(cmd *cobra.Command, args []string, cfg config.Config, progress jsonProgress) ([]fileInfo.FileNode, error) {
+	stdinName, _ := cmd.Flags().GetString("stdin-name")
+	if stdinName != "" {
+		if len(args) > 0 {
+			return nil, errors.New("file arguments cannot be combined with --stdin-name")
+		}
+		return []fileInfo.FileNode{fileInfo.NewStdinNode(stdinName)}, nil
+	}
+
+	if len(args) == 0 {
+		return nil, errors.New("no files given; pass file paths or --stdin-name")
+	}
+
+	files, err := transfer.PrepareNodes(cmd.Context(), args, cfg.HashWorkerCount(), printPreparation(progress))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errLocalFiles, err)
+	}
+	return files, nil
+}
+
+// printPreparation reports preparation progress on stderr every few seconds and when it is done
+func printPreparation(progress jsonProgress) transfer.PrepareFunc {
+	var last time.Time
+	return func(p events.PreparationProgress) {
+		progress.event(p)
+		if p.Phase != events.PreparationDone && p.Time.Sub(last) < preparationPrintInterval {
+			return
+		}
+		last = p.Time
+		switch p.Phase {
+		case events.PreparationScanning:
+			fmt.Fprintf(os.Stderr, "Preparing: %d files scanned (%s)\n", p.FilesScanned, util.FormatSize(p.BytesScanned))
+		case events.PreparationHashing:
+			fmt.Fprintf(os.Stderr, "Preparing: hashed %s / %s\n", util.FormatSize(p.BytesHashed), util.FormatSize(p.BytesScanned))
+		case events.PreparationDone:
+			for _, path := range p.Locked {
+				fmt.Fprintf(os.Stderr, "Warning: skipping %s, another program locks it\n", path)
+			}
+			fmt.Fprintf(os.Stderr, "Prepared %d files (%s)\n", p.FilesScanned, util.FormatSize(p.BytesScanned))
+		}
+	}
+}
+
+// runHeadlessSend sends files to the --to peer without starting the TUI. A failure is
+// returned as a *headlessError, which carries the exit code.
+func runHeadlessSend(cmd *cobra.Command, args []string, cfg config.Config) error {
+	var tally sendTally
+	err := headlessSend(cmd, args, cfg, &tally)
+	return headlessResult(err, tally.sent, tally.failed)
+}
+
+// sendTally counts the files a headless send finished
+type sendTally struct {
+	sent, failed int
+}
+
+// headlessSend runs a headless send, counting finished files in tally
+func headlessSend(cmd *cobra.Command, args []string, cfg config.Config, tally *sendTally) error {
+	to, _ := cmd.Flags().GetString("to")
+	via, _ := cmd.Flags().GetString("via")
+	timeout, _ := cmd.Flags().GetDuration("timeout")
+	resumeToken, _ := cmd.Flags().GetString("resume")
+	guestToken, _ := cmd.Flags().GetString("guest-token")
+	batch, _ := cmd.Flags().GetBool("batch")
+	if stdinName, _ := cmd.Flags().GetString("stdin-name"); batch && stdinName != "" {
+		return errors.New("--batch cannot be combined with --stdin-name")
+	}
+	if resumeToken != "" {
+		if err := transfer.ValidateResumeToken(resumeToken); err != nil {
+			return fmt.Errorf("invalid --resume token %q: %w", resumeToken, err)
+		}
+	}
+
+	progress := newJSONProgress(cmd)
+	files, err := collectFiles(cmd, args, cfg, progress)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	app := senderApp.NewAppWithOptions(&discovery.MDNSAdapter{}, senderApp.Options{
+		OnSendStart:        cfg.OnSendStart,
+		OnSendComplete:     cfg.OnSendComplete,
+		GenerateManifest:   cfg.GenerateManifest,
+		TransferTimeout:    timeout,
+		ResumeToken:        resumeToken,
+		SentCachePath:      senderApp.SentCachePath(cfg),
+		ForceResend:        cfg.ForceResend,
+		DeviceKeyPath:      senderApp.DeviceKeyPath(),
+		KeyLifetime:        cfg.KeyLifetime(),
+		KeyGrace:           cfg.KeyGrace(),
+		SignatureAlgorithm: senderApp.SignatureAlgorithm(cfg),
+		RetryPolicy:        senderApp.RetryPolicy(cfg),
+		SpeedProbeSize:     cfg.SpeedProbeSize(),
+		ChunkSize:          cfg.ChunkSize(),
+		IdleTimeout:        cfg.SessionIdleTimeout(),
+		Bonding:            cfg.ExperimentalBonding,
+		PeerSettings:       peers.LoadDefault(),
+		Webhooks:           webhook.FromConfig(cfg),
+		BandwidthSchedule:  senderApp.BandwidthSchedule(cfg),
+		Battery:            senderApp.Battery(cfg),
+		Scope:              senderApp.ServiceScope(cfg),
+		PeerFilter:         receiverApp.PeerFilter(cfg),
+		LANOnly:            cfg.LANOnly,
+		Offline:            cfg.AirgapPeer != "",
+		PSK:                receiverApp.PSK(cfg),
+		Chaos:              senderApp.Chaos(cfg),
+		RelayTo:            relayTarget(to, via),
+		GuestToken:         guestToken,
+		Icon:               cfg.Icon,
+	})
+
+	// The events may drop files of a busy transfer, so the counts are taken from the final
+	// status of the files once the send is over
+	defer func() {
+		tally.sent, tally.failed = app.FileCounts()
+	}()
+
+	// Report each finished file from the transfer events rather than the UI messages
+	fileEvents := app.Events().SubscribeFunc(func(e events.Event) {
+		file, ok := e.(events.FileStatusChanged)
+		if !ok {
+			return
+		}
+		switch file.State {
+		case transfer.TransferStateCompleted.String():
+			fmt.Fprintf(os.Stderr, "Sent %s\n", file.FilePath)
+		case transfer.TransferStateFailed.String():
+			fmt.Fprintf(os.Stderr, "Failed %s: %v\n", file.FilePath, file.Err)
+		}
+	}, events.TopicFile)
+	defer fileEvents.Close()
+	if progress.writer != nil {
+		progressEvents := app.Events().SubscribeFunc(progress.event, events.TopicFile, events.TopicSession)
+		defer progressEvents.Close()
+	}
+
+	var receiver discovery.ServiceInfo
+	if cfg.AirgapPeer != "" {
+		port, _ := cmd.Flags().GetInt("port")
+		receiver = airgapReceiver(cfg.AirgapPeer, port)
+	} else {
+		name := to
+		if via != "" {
+			name = via
+		}
+		fmt.Fprintf(os.Stderr, "Looking for receiver %q...\n", name)
+		findCtx, cancel := context.WithTimeout(ctx, discoveryTimeout)
+		receiver, err = app.FindReceiver(findCtx, name)
+		cancel()
+		if err != nil {
+			return err
+		}
+		if via != "" {
+			if receiver.Meta.Advertised && !receiver.Meta.Relay {
+				fmt.Fprintf(os.Stderr, "Warning: %s does not advertise itself as a relay\n", receiver.Name)
+			}
+			fmt.Fprintf(os.Stderr, "Relaying through %s to %q\n", receiver.Name, to)
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "Sending to %s (%s:%d)\n", receiver.Name, receiver.Addr, receiver.Port)
+	onMessage := func(msg tea.Msg) {
+		switch m := msg.(type) {
+		case sender.StatusUpdateMsg:
+			fmt.Fprintln(os.Stderr, m.Message)
+			progress.record(events.ProgressRecord{Type: events.RecordStatus, Message: m.Message})
+		case sender.SpeedProbeMsg:
+			fmt.Fprintf(os.Stderr, "Connection speed %s/s, about %s for %s\n",
+				util.FormatSize(int64(m.Rate)), m.ETA.Round(time.Second), util.FormatSize(m.TotalBytes))
+			progress.record(events.ProgressRecord{
+				Type: events.RecordStatus, State: "speed_probe", TotalBytes: m.TotalBytes, Rate: m.Rate,
+				ETASeconds: m.ETA.Seconds(), Message: "Measured the connection speed",
+			})
+		case sender.WirelessPathMsg:
+			message := fmt.Sprintf("%s goes over Wi-Fi (%s) although both machines are wired (%s)",
+				util.FormatSize(m.TotalBytes), m.Interface, strings.Join(m.Wired, ", "))
+			fmt.Fprintln(os.Stderr, "Warning: "+message)
+			progress.record(events.ProgressRecord{Type: events.RecordStatus, State: "wireless_path", Message: message})
+		case sender.TransferCompleteMsg:
+			fmt.Fprintln(os.Stderr, "Transfer complete")
+			progress.record(events.ProgressRecord{Type: events.RecordComplete, State: "completed"})
+		}
+	}
+	if batch {
+		items := batchItems(files)
+		go readBatchCommands(app, len(items))
+		return app.SendBatchHeadless(ctx, receiver, items, onMessage)
+	}
+	return app.SendHeadless(ctx, receiver, files, onMessage)
+}
+
+// batchItems makes every file argument an item of its own, numbered from 1
+func batchItems(files []fileInfo.FileNode) []sender.BatchItem {
+	items := make([]sender.BatchItem, len(files))
+	for i := range files {
+		items[i] = sender.BatchItem{Name: files[i].Name, Files: files[i : i+1]}
+		fmt.Fprintf(os.Stderr, "Item %d: %s\n", i+1, files[i].Name)
+	}
+	fmt.Fprintln(os.Stderr, "Enter `cancel <item>` to stop sending an item")
+	return items
+}
+
+// readBatchCommands cancels items of a batch send as `cancel <item>` lines arrive on stdin
+func readBatchCommands(app *senderApp.App, items int) {
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 || fields[0] != "cancel" {
+			fmt.Fprintln(os.Stderr, "Unknown command, use: cancel <item>")
+			continue
+		}
+		if n, err := strconv.Atoi(fields[1]); err != nil || n < 1 || n > items {
+			fmt.Fprintf(os.Stderr, "No item %s, items are numbered 1 to %d\n", fields[1], items)
+			continue
+		}
+		app.CancelBatchItem(fields[1])
+	}
+}
+
+// relayTarget returns the receiver a relay forwards the files to: the --to receiver when
+// sending --via a relay, otherwise none
+func relayTarget(to, via string) string {
+	if via == "" {
+		return ""
+	}
+	return to
+}
+
+// airgapReceiver returns the receiver at addr, an IP with an optional port, which is the
+// same as ours when it is missing; in air-gap mode it is given rather than discovered
+func airgapReceiver(addr string, defaultPort int) discovery.ServiceInfo {
+	host, portText, err := net.SplitHostPort(addr)
+	if err != nil {
+		host, portText = addr, ""
+	}
+	port := defaultPort
+	if p, err := strconv.Atoi(portText); err == nil {
+		port = p
+	}
+	return discovery.ServiceInfo{Name: host, Addr: net.ParseIP(host), Port: port}
+}
+
+// runHeadlessReceive receives without the TUI until interrupted, as a service does. Nobody is
+// there to confirm requests, so only senders whose keys are trusted, because they were accepted
+// in the TUI before, are accepted; everyone else is declined. In air-gap mode every request
+// proved it knows the passphrase, which is enough.
+func runHeadlessReceive(cmd *cobra.Command, cfg config.Config) error {
+	port, _ := cmd.Flags().GetInt("port")
+	outputDir, err := resolveOutputDir(cmd, cfg)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	serviceName, err := receiverApp.NewServiceName()
+	if err != nil {
+		slog.Warn("Could not create service name, using the default", "error", err)
+	}
+	// Jobs find their peers through discovery, which air-gap mode goes without
+	var scheduler *jobs.Scheduler
+	var jobStatus api.JobStatusFunc
+	if cfg.AirgapPeer == "" {
+		if scheduler = newJobScheduler(cfg); scheduler != nil {
+			jobStatus = func() ([]jobs.Status, error) { return scheduler.Status(time.Now()) }
+		}
+	}
+
+	uploadToken, err := headlessUploadToken(cmd, cfg)
+	if err != nil {
+		return err
+	}
+
+	app := receiverApp.NewAppWithOptions(port, outputDir, receiverApp.Options{
+		TrustStorePath:      receiverApp.TrustStorePath(),
+		TrustMaxAge:         cfg.TrustMaxAge(),
+		TrustReadOnly:       cfg.TrustReadOnly,
+		Lockdown:            cfg.Lockdown,
+		AcceptTimeout:       cfg.AcceptTimeout(),
+		IdleTimeout:         cfg.SessionIdleTimeout(),
+		Registrar:           &discovery.MDNSAdapter{},
+		ServiceName:         serviceName,
+		DoNotDisturbMessage: cfg.DoNotDisturbMessage,
+		Scopes:              receiverApp.ServiceScopes(cfg),
+		PeerFilter:          receiverApp.PeerFilter(cfg),
+		AuditLog:            receiverApp.AuditLog(cfg),
+		VerifyWrites:        cfg.VerifyWritesFraction(),
+		OutputTemplate:      receiverApp.LoadOutputTemplate(cfg),
+		ContentAddressed:    cfg.ContentAddressed,
+		Quota:               receiverApp.Quota(cfg),
+		LANOnly:             cfg.LANOnly,
+		Offline:             cfg.AirgapPeer != "",
+		PSK:                 receiverApp.PSK(cfg),
+		JobStatus:           jobStatus,
+		PeerSettings:        peers.LoadDefault(),
+		Webhooks:            webhook.FromConfig(cfg),
+		Fingerprint:         senderApp.DeviceFingerprint(cfg),
+		Icon:                cfg.Icon,
+		Recent:              recent.OpenDefault(),
+		Scanner:             scan.FromConfig(cfg),
+		Storage:             storage.FromConfig(cfg, outputDir),
+		GuestMaxBytes:       cfg.GuestMaxBytes(),
+		UploadToken:         uploadToken,
+	})
+
+	if guest, _ := cmd.Flags().GetBool("guest"); guest {
+		pass, err := app.IssueGuestPass()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Guest token %s accepts one transfer%s until %s: lanFileSharer send --to <this receiver> --guest-token %s\n",
+			pass.Token, guestLimit(pass.MaxBytes), pass.Expires.Format("15:04"), pass.Token)
+	}
+	if uploadToken != "" {
+		for _, ip := range util.LANAddresses() {
+			fmt.Fprintf(os.Stderr, "Browsers on the network upload files at http://%s%s?token=%s\n",
+				net.JoinHostPort(ip.String(), strconv.Itoa(port)), api.UploadPath, uploadToken)
+		}
+	}
+	if scheduler != nil {
+		go scheduler.Run(ctx)
+	}
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg := <-app.UIMessages():
+				handleHeadlessReceiveMsg(app, msg, cfg.PSKPassphrase != "")
+			}
+		}
+	}()
+
+	if cfg.AirgapPeer != "" {
+		fmt.Fprintf(os.Stderr, "Receiving on port %d into %s from %s, in air-gap mode\n", port, outputDir, cfg.AirgapPeer)
+	} else {
+		fmt.Fprintf(os.Stderr, "Receiving on port %d into %s, accepting trusted senders only\n", port, outputDir)
+	}
+	return app.Run(ctx)
+}
+
+// headlessUploadToken returns the token of browser uploads with --upload, the configured one or
+// a new one, and none without it. Browsers can neither sign requests with the air-gap passphrase
+// nor be trusted senders, so uploads cannot get past those receivers.
+func headlessUploadToken(cmd *cobra.Command, cfg config.Config) (string, error) {
+	if upload, _ := cmd.Flags().GetBool("upload"); !upload {
+		return "", nil
+	}
+	switch {
+	case cfg.AirgapPeer != "":
+		return "", errors.New("--upload cannot be combined with --airgap, browsers cannot sign requests with the passphrase")
+	case cfg.Lockdown:
+		return "", errors.New("--upload is not available on locked down receivers, which accept trusted senders only")
+	case cfg.UploadToken != "":
+		return cfg.UploadToken, nil
+	}
+	return api.NewUploadToken()
+}
+
+// guestLimit describes the size cap of a guest pass in the line announcing it
+func guestLimit(maxBytes int64) string {
+	if maxBytes <= 0 {
+		return ""
+	}
+	return " of up to " + util.FormatSize(maxBytes)
+}
+
+// handleHeadlessReceiveMsg answers requests and logs the progress of a headless receiver.
+// With pskVerified, requests were signed with the pre-shared key and are all accepted.
+func handleHeadlessReceiveMsg(app *receiverApp.App, msg tea.Msg, pskVerified bool) {
+	switch m := msg.(type) {
+	case receiver.FileNodeUpdateMsg:
+		if pskVerified {
+			fmt.Fprintf(os.Stderr, "Accepting %d files from the air-gap peer %s\n", len(m.Nodes), m.SenderFingerprint)
+			app.AppEvents() <- receiver.FileRequestAccepted{}
+			return
+		}
+		if m.Resumed {
+			fmt.Fprintf(os.Stderr, "Resuming the session of %s, which stopped responding\n", peerName(m))
+			app.AppEvents() <- receiver.FileRequestAccepted{}
+			return
+		}
+		if m.Guest {
+			fmt.Fprintf(os.Stderr, "Accepting %d files from %s with the guest token, without trusting it\n", len(m.Nodes), peerName(m))
+			app.AppEvents() <- receiver.FileRequestAccepted{}
+			return
+		}
+		if m.AutoAccept {
+			fmt.Fprintf(os.Stderr, "Accepting %d files from %s, auto-accept is saved for this peer\n", len(m.Nodes), peerName(m))
+			app.AppEvents() <- receiver.FileRequestAccepted{}
+			return
+		}
+		if m.SenderTrust == crypto.TrustValid.String() {
+			fmt.Fprintf(os.Stderr, "Accepting %d files from trusted sender %s\n", len(m.Nodes), m.SenderFingerprint)
+			app.AppEvents() <- receiver.FileRequestAccepted{}
+			return
+		}
+		fmt.Fprintf(os.Stderr, "Declining %d files from %s sender %s, accept it once in the TUI to trust it\n",
+			len(m.Nodes), m.SenderTrust, m.SenderFingerprint)
+		app.AppEvents() <- receiver.FileRequestRejected{}
+	case receiver.RequestDeclinedMsg:
+		reason := m.Reason
+		if reason == "" {
+			reason = m.Availability.String()
+		}
+		fmt.Fprintf(os.Stderr, "Declined a request: %s\n", reason)
+	case receiver.UploadReceivedMsg:
+		summary := fmt.Sprintf("%d files (%s)", m.Files, util.FormatSize(m.Bytes))
+		if m.Failed > 0 {
+			summary += fmt.Sprintf(", %d failed", m.Failed)
+		}
+		if m.Err != nil {
+			fmt.Fprintf(os.Stderr, "Browser upload from %s broke off after %s: %v\n", m.Sender, summary, m.Err)
+			return
+		}
+		fmt.Fprintf(os.Stderr, "Received %s uploaded by a browser at %s\n", summary, m.Sender)
+	case receiver.TransferFinishedMsg:
+		if m.Err != nil {
+			fmt.Fprintf(os.Stderr, "Transfer failed: %v\n", m.Err)
+			return
+		}
+		fmt.Fprintf(os.Stderr, "Received %s\n", m.OutputPath)
+	case appevents.Error:
+		fmt.Fprintf(os.Stderr, "Error: %v\n", m.Err)
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/x/term"
+	"github.com/spf13/cobra"
+
+	"github.com/rescp17/lanFileSharer/internal/config"
+	"github.com/rescp17/lanFileSharer/pkg/crypto"
+)
+
+// bundlePassphraseEnv lets scripts pass the bundle passphrase instead of typing it
+const bundlePassphraseEnv = "LANFILESHARER_BUNDLE_PASSPHRASE"
+
+// identityFiles are the files of the config directory that make up the device identity
+var identityFiles = []string{config.FileName, crypto.DeviceKeyFileName, crypto.TrustStoreFileName}
+
+// newIdentityCmd creates the command for moving the device identity to another machine
+func newIdentityCmd() *cobra.Command {
+	identityCmd := &cobra.Command{
+		Use:   "identity",
+		Short: "Export and import the device identity",
+		Long: "Move the device signing keys, the trusted sender keys and the config to another machine " +
+			"as a single file encrypted with a passphrase. Peers that trusted this device keep trusting it " +
+			"after the import, since they know it by its key. The passphrase is read from the terminal, " +
+			"or from " + bundlePassphraseEnv + ".",
+	}
+
+	exportCmd := &cobra.Command{
+		Use:   "export <file>",
+		Short: "Write the identity to an encrypted bundle",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dir, err := config.Dir()
+			if err != nil {
+				return err
+			}
+			bundle, err := crypto.CollectIdentity(dir, identityFiles)
+			if err != nil {
+				return err
+			}
+			passphrase, err := readPassphrase(bundlePassphraseEnv, true)
+			if err != nil {
+				return err
+			}
+
+			file, err := os.OpenFile(args[0], os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+			if err != nil {
+				return fmt.Errorf("failed to create bundle: %w", err)
+			}
+			defer file.Close()
+			if err := bundle.Seal(file, passphrase); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Exported %d files to %s, keep it as safe as the passphrase\n", len(bundle.Files), args[0])
+			return nil
+		},
+	}
+
+	importCmd := &cobra.Command{
+		Use:   "import <file>",
+		Short: "Install the identity from a bundle, keeping replaced files as .bak",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			data, err := os.ReadFile(args[0])
+			if err != nil {
+				return fmt.Errorf("failed to read bundle: %w", err)
+			}
+			passphrase, err := readPassphrase(bundlePassphraseEnv, false)
+			if err != nil {
+				return err
+			}
+			bundle, err := crypto.OpenBundle(bytes.NewReader(data), passphrase)
+			if err != nil {
+				return err
+			}
+			dir, err := config.Dir()
+			if err != nil {
+				return err
+			}
+			written, err := bundle.Install(dir)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Imported %s from %s (exported %s) into %s\n",
+				strings.Join(written, ", "), bundle.Hostname, bundle.Created.Format("2006-01-02"), dir)
+			return nil
+		},
+	}
+
+	identityCmd.AddCommand(exportCmd, importCmd)
+	return identityCmd
+}
+
+// readPassphrase returns a passphrase from the environment variable env or the terminal,
+// asking twice when confirm is set
+func readPassphrase(env string, confirm bool) ([]byte, error) {
+	if passphrase := os.Getenv(env); passphrase != "" {
+		return []byte(passphrase), nil
+	}
+	if !term.IsTerminal(os.Stdin.Fd()) {
+		return nil, fmt.Errorf("no terminal to read the passphrase from, set %s", env)
+	}
+
+	fmt.Fprint(os.Stderr, "Passphrase: ")
+	passphrase, err := term.ReadPassword(os.Stdin.Fd())
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read passphrase: %w", err)
+	}
+	if len(passphrase) == 0 {
+		return nil, errors.New("the passphrase must not be empty")
+	}
+	if !confirm {
+		return passphrase, nil
+	}
+
+	fmt.Fprint(os.Stderr, "Repeat passphrase: ")
+	repeated, err := term.ReadPassword(os.Stdin.Fd())
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read passphrase: %w", err)
+	}
+	if !bytes.Equal(passphrase, repeated) {
+		return nil, errors.New("the passphrases do not match")
+	}
+	return passphrase, nil
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/rescp17/lanFileSharer/api"
+	"github.com/rescp17/lanFileSharer/internal/config"
+	"github.com/rescp17/lanFileSharer/pkg/discovery"
+	"github.com/rescp17/lanFileSharer/pkg/jobs"
+	"github.com/rescp17/lanFileSharer/pkg/peers"
+	receiverApp "github.com/rescp17/lanFileSharer/pkg/receiver"
+	senderApp "github.com/rescp17/lanFileSharer/pkg/sender"
+	"github.com/rescp17/lanFileSharer/pkg/transfer"
+	"github.com/rescp17/lanFileSharer/pkg/webhook"
+)
+
+// jobTransferTimeout bounds a single run of a job; backups can be much larger than what
+// the default send timeout allows
+const jobTransferTimeout = 12 * time.Hour
+
+// newJobCmd creates the command that manages the recurring sync jobs run by the daemon
+func newJobCmd() *cobra.Command {
+	jobCmd := &cobra.Command{
+		Use:   "job",
+		Short: "Manage recurring sync jobs that send a directory to a peer on a schedule",
+		Long: "Jobs send a directory to a receiver whenever their cron schedule matches, e.g. every night " +
+			"for a LAN backup. They are run by `receive --headless`, which `service install` starts at login; " +
+			"unchanged files already sent to the peer are skipped. Each run is recorded in the job history.",
+	}
+
+	addCmd := &cobra.Command{
+		Use:     "add",
+		Short:   "Add a job",
+		Example: `  lanfilesharer job add --src ~/Documents --peer nas --cron "0 2 * * *"`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			src, _ := cmd.Flags().GetString("src")
+			peer, _ := cmd.Flags().GetString("peer")
+			cron, _ := cmd.Flags().GetString("cron")
+			store, err := loadJobs()
+			if err != nil {
+				return err
+			}
+			job, err := store.Add(jobs.Job{Source: src, Peer: peer, Cron: cron})
+			if err != nil {
+				return err
+			}
+			if err := store.Save(); err != nil {
+				return err
+			}
+			schedule, _ := jobs.ParseSchedule(job.Cron)
+			fmt.Fprintf(cmd.OutOrStdout(), "Added job %s, next run %s\n", job.ID, formatNextRun(schedule.Next(time.Now())))
+			return nil
+		},
+	}
+	addCmd.Flags().String("src", "", "Directory to send")
+	addCmd.Flags().String("peer", "", "Receiver to send to, as given to send --to")
+	addCmd.Flags().String("cron", "", "When to send, as a cron expression: minute hour day month weekday")
+	for _, name := range []string{"src", "peer", "cron"} {
+		_ = addCmd.MarkFlagRequired(name)
+	}
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the jobs with their next and last run",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store, err := loadJobs()
+			if err != nil {
+				return err
+			}
+			var statuses []jobs.Status
+			for _, job := range store.Jobs() {
+				status := jobs.Status{Job: job}
+				if schedule, err := jobs.ParseSchedule(job.Cron); err == nil {
+					status.Next = schedule.Next(time.Now())
+				}
+				if run, ok := store.LastRun(job.ID); ok {
+					status.LastRun = &run
+				}
+				statuses = append(statuses, status)
+			}
+			return printJobStatus(cmd.OutOrStdout(), statuses)
+		},
+	}
+
+	statusCmd := &cobra.Command{
+		Use:   "status",
+		Short: "Ask the running daemon for the state of its jobs",
+		Long:  "Query the control API of the `receive --headless` daemon listening on --port of this machine.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			port, _ := cmd.Flags().GetInt("port")
+			ctx, cancel := context.WithTimeout(cmd.Context(), 5*time.Second)
+			defer cancel()
+			url := "http://" + net.JoinHostPort("127.0.0.1", strconv.Itoa(port))
+			statuses, err := api.NewClient("").JobStatus(ctx, url)
+			if err != nil {
+				return fmt.Errorf("is `receive --headless` running on port %d? %w", port, err)
+			}
+			return printJobStatus(cmd.OutOrStdout(), statuses)
+		},
+	}
+
+	removeCmd := &cobra.Command{
+		Use:   "remove <id>",
+		Short: "Remove a job and its history",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store, err := loadJobs()
+			if err != nil {
+				return err
+			}
+			if err := store.Remove(args[0]); err != nil {
+				return err
+			}
+			if err := store.Save(); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Removed job %s\n", args[0])
+			return nil
+		},
+	}
+
+	historyCmd := &cobra.Command{
+		Use:   "history <id>",
+		Short: "Show the recent runs of a job",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store, err := loadJobs()
+			if err != nil {
+				return err
+			}
+			if _, err := store.Job(args[0]); err != nil {
+				return err
+			}
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+			fmt.Fprintln(w, "STARTED\tDURATION\tSENT\tFAILED\tRESULT")
+			for _, run := range store.History(args[0]) {
+				fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%s\n", run.Started.Format(time.RFC3339),
+					run.Finished.Sub(run.Started).Round(time.Second), run.FilesSent, run.FilesFailed, formatRunResult(run))
+			}
+			return w.Flush()
+		},
+	}
+
+	jobCmd.AddCommand(addCmd, listCmd, statusCmd, removeCmd, historyCmd)
+	return jobCmd
+}
+
+// loadJobs opens the jobs file in the config directory
+func loadJobs() (*jobs.Store, error) {
+	path, err := jobs.DefaultPath()
+	if err != nil {
+		return nil, fmt.Errorf("could not resolve the jobs file: %w", err)
+	}
+	return jobs.Load(path)
+}
+
+func printJobStatus(out io.Writer, statuses []jobs.Status) error {
+	if len(statuses) == 0 {
+		fmt.Fprintln(out, "No jobs, add one with `job add`")
+		return nil
+	}
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tSOURCE\tPEER\tSCHEDULE\tNEXT RUN\tLAST RUN")
+	for _, status := range statuses {
+		last := "never"
+		if status.Running {
+			last = "running"
+		} else if status.LastRun != nil {
+			last = status.LastRun.Started.Format(time.RFC3339) + " " + formatRunResult(*status.LastRun)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", status.Job.ID, status.Job.Source, status.Job.Peer,
+			status.Job.Cron, formatNextRun(status.Next), last)
+	}
+	return w.Flush()
+}
+
+func formatNextRun(next time.Time) string {
+	if next.IsZero() {
+		return "never"
+	}
+	return next.Format(time.RFC3339)
+}
+
+func formatRunResult(run jobs.Run) string {
+	switch {
+	case run.Error != "":
+		return "failed: " + run.Error
+	case run.FilesFailed > 0:
+		return fmt.Sprintf("%d files failed", run.FilesFailed)
+	default:
+		return "ok"
+	}
+}
+
+// newJobScheduler returns the scheduler the headless receiver runs jobs with, or nil if the
+// jobs file cannot be located
+func newJobScheduler(cfg config.Config) *jobs.Scheduler {
+	path, err := jobs.DefaultPath()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Not running jobs, the jobs file cannot be located: %v\n", err)
+		return nil
+	}
+	return jobs.NewScheduler(path, func(ctx context.Context, job jobs.Job) (jobs.Outcome, error) {
+		return runJob(ctx, cfg, job)
+	})
+}
+
+// runJob sends the source of job to its peer like a headless send, skipping the files the
+// peer already has
+func runJob(ctx context.Context, cfg config.Config, job jobs.Job) (jobs.Outcome, error) {
+	files, err := transfer.PrepareNodes(ctx, []string{job.Source}, cfg.HashWorkerCount(), nil)
+	if err != nil {
+		return jobs.Outcome{}, fmt.Errorf("%w: %w", errLocalFiles, err)
+	}
+
+	sentCache, err := senderApp.DefaultSentCachePath()
+	if err != nil {
+		return jobs.Outcome{}, err
+	}
+	app := senderApp.NewAppWithOptions(&discovery.MDNSAdapter{}, senderApp.Options{
+		TransferTimeout:    jobTransferTimeout,
+		SentCachePath:      sentCache,
+		DeviceKeyPath:      senderApp.DeviceKeyPath(),
+		KeyLifetime:        cfg.KeyLifetime(),
+		KeyGrace:           cfg.KeyGrace(),
+		SignatureAlgorithm: senderApp.SignatureAlgorithm(cfg),
+		RetryPolicy:        senderApp.RetryPolicy(cfg),
+		ChunkSize:          cfg.ChunkSize(),
+		IdleTimeout:        cfg.SessionIdleTimeout(),
+		Bonding:            cfg.ExperimentalBonding,
+		PeerSettings:       peers.LoadDefault(),
+		Webhooks:           webhook.FromConfig(cfg),
+		BandwidthSchedule:  senderApp.BandwidthSchedule(cfg),
+		Battery:            senderApp.Battery(cfg),
+		Scope:              senderApp.ServiceScope(cfg),
+		PeerFilter:         receiverApp.PeerFilter(cfg),
+		LANOnly:            cfg.LANOnly,
+		Icon:               cfg.Icon,
+	})
+
+	findCtx, cancel := context.WithTimeout(ctx, discoveryTimeout)
+	receiver, err := app.FindReceiver(findCtx, job.Peer)
+	cancel()
+	if err == nil {
+		err = app.SendHeadless(ctx, receiver, files, nil)
+	}
+	sent, failed := app.FileCounts()
+	return jobs.Outcome{FilesSent: sent, FilesFailed: failed}, err
+}
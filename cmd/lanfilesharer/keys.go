@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/rescp17/lanFileSharer/pkg/crypto"
+	senderApp "github.com/rescp17/lanFileSharer/pkg/sender"
+)
+
+// newKeysCmd creates the command for inspecting and rotating the device signing key
+func newKeysCmd() *cobra.Command {
+	keysCmd := &cobra.Command{
+		Use:   "keys",
+		Short: "Show the device signing key",
+		Long: "Show the fingerprint and expiry of the key used to sign outgoing transfers. " +
+			"Receivers display this fingerprint so it can be compared before accepting.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store, err := deviceKeyStore(cmd)
+			if err != nil {
+				return err
+			}
+			key, err := store.Current()
+			if err != nil {
+				return fmt.Errorf("failed to load device key: %w", err)
+			}
+			return printDeviceKey(cmd, key)
+		},
+	}
+
+	rotateCmd := &cobra.Command{
+		Use:   "rotate",
+		Short: "Replace the device signing key",
+		Long:  "Generate a new device signing key. The previous key stays valid for the configured grace period.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store, err := deviceKeyStore(cmd)
+			if err != nil {
+				return err
+			}
+			key, err := store.Rotate()
+			if err != nil {
+				return fmt.Errorf("failed to rotate device key: %w", err)
+			}
+			return printDeviceKey(cmd, key)
+		},
+	}
+
+	keysCmd.AddCommand(rotateCmd)
+	return keysCmd
+}
+
+// deviceKeyStore opens the device key store using the lifetimes from the config file
+func deviceKeyStore(cmd *cobra.Command) (*crypto.KeyStore, error) {
+	cfg, err := loadConfig(cmd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	path := senderApp.DeviceKeyPath()
+	if path == "" {
+		return nil, fmt.Errorf("could not resolve the device key location")
+	}
+	store := crypto.NewKeyStore(path, cfg.KeyLifetime(), cfg.KeyGrace())
+	store.SetAlgorithm(senderApp.SignatureAlgorithm(cfg))
+	return store, nil
+}
+
+func printDeviceKey(cmd *cobra.Command, key *crypto.DeviceKey) error {
+	fingerprint, err := key.Fingerprint()
+	if err != nil {
+		return err
+	}
+	out := cmd.OutOrStdout()
+	fmt.Fprintf(out, "Fingerprint: %s\n", fingerprint)
+	fmt.Fprintf(out, "Algorithm:   %s\n", key.Algorithm())
+	fmt.Fprintf(out, "Created:     %s\n", key.CreatedAt.Format(time.RFC3339))
+	fmt.Fprintf(out, "Expires:     %s\n", key.ExpiresAt.Format(time.RFC3339))
+	return nil
+}
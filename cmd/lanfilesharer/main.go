@@ -2,30 +2,222 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"log/slog"
+	"net"
 	"os"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/fang"
 	"github.com/spf13/cobra"
 
+	"github.com/rescp17/lanFileSharer/internal/config"
+	"github.com/rescp17/lanFileSharer/internal/version"
+	senderApp "github.com/rescp17/lanFileSharer/pkg/sender"
+	"github.com/rescp17/lanFileSharer/pkg/system"
+	"github.com/rescp17/lanFileSharer/pkg/transfer"
 	"github.com/rescp17/lanFileSharer/pkg/ui"
 )
 
 func runWithUIMode(mode ui.Mode, cmd *cobra.Command) {
 	port, _ := cmd.Flags().GetInt("port")
-	outputDir, _ := cmd.Flags().GetString("output")
 
-	model := ui.InitialModel(mode, port, outputDir)
-	p := tea.NewProgram(model)
-	if _, err := p.Run(); err != nil {
+	cfg, err := loadConfig(cmd)
+	if err != nil {
+		fmt.Printf("Failed to load config: %v\n", err)
+		os.Exit(1)
+	}
+	outputDir, err := resolveOutputDir(cmd, cfg)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if cmd.Flags().Changed("auto-open") {
+		cfg.AutoOpen, _ = cmd.Flags().GetBool("auto-open")
+	}
+	if cmd.Flags().Changed("manifest") {
+		cfg.GenerateManifest, _ = cmd.Flags().GetBool("manifest")
+	}
+	if cmd.Flags().Changed("force") {
+		cfg.ForceResend, _ = cmd.Flags().GetBool("force")
+	}
+	if cmd.Flags().Changed("verify-writes") {
+		cfg.VerifyWritesPercent, _ = cmd.Flags().GetInt("verify-writes")
+	}
+	if cmd.Flags().Changed("cas") {
+		cfg.ContentAddressed, _ = cmd.Flags().GetBool("cas")
+	}
+	if cmd.Flags().Changed("no-mouse") {
+		cfg.DisableMouse, _ = cmd.Flags().GetBool("no-mouse")
+	}
+	if err := applyRetryFlags(cmd, &cfg); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	var options []tea.ProgramOption
+	if !cfg.DisableMouse {
+		options = append(options, tea.WithMouseCellMotion())
+	}
+	newModel := ui.InitialModel
+	if demo, _ := cmd.Flags().GetBool("demo"); demo {
+		newModel = ui.InitialDemoModel
+	}
+	p := tea.NewProgram(newModel(mode, port, outputDir, cfg), options...)
+	final, err := p.Run()
+	ui.ResetTerminal(final)
+	if err != nil {
 		fmt.Printf("Alas, there's been an error: %v", err)
 		os.Exit(1)
 	}
 }
 
+// resolveOutputDir returns the output directory given by --output, as far as the lockdown of cfg allows
+func resolveOutputDir(cmd *cobra.Command, cfg config.Config) (string, error) {
+	requested, _ := cmd.Flags().GetString("output")
+	return cfg.OutputDir(requested, cmd.Flags().Changed("output"))
+}
+
+// applyRetryFlags overrides the configured retry policy with the per-send retry flags that were given
+func applyRetryFlags(cmd *cobra.Command, cfg *config.Config) error {
+	changed := false
+	for _, name := range []string{"max-retries", "retry-delay", "retry-backoff", "retry-max-delay", "retry-on"} {
+		changed = changed || cmd.Flags().Changed(name)
+	}
+	if !changed {
+		return nil
+	}
+
+	if cmd.Flags().Changed("max-retries") {
+		cfg.RetryMaxRetries, _ = cmd.Flags().GetInt("max-retries")
+	}
+	if cmd.Flags().Changed("retry-delay") {
+		delay, _ := cmd.Flags().GetDuration("retry-delay")
+		cfg.RetryInitialDelayMs = int(delay.Milliseconds())
+	}
+	if cmd.Flags().Changed("retry-backoff") {
+		cfg.RetryBackoffFactor, _ = cmd.Flags().GetFloat64("retry-backoff")
+	}
+	if cmd.Flags().Changed("retry-max-delay") {
+		delay, _ := cmd.Flags().GetDuration("retry-max-delay")
+		cfg.RetryMaxDelayMs = int(delay.Milliseconds())
+	}
+	if cmd.Flags().Changed("retry-on") {
+		cfg.RetryOn, _ = cmd.Flags().GetStringSlice("retry-on")
+	}
+	if _, err := senderApp.ParseRetryPolicy(*cfg); err != nil {
+		return fmt.Errorf("invalid retry flags: %w", err)
+	}
+	return nil
+}
+
+// configPath returns the config file given by --config, or the default one; it is empty
+// if the default location cannot be resolved
+func configPath(cmd *cobra.Command) string {
+	path, _ := cmd.Flags().GetString("config")
+	if path == "" {
+		defaultPath, err := config.DefaultPath()
+		if err != nil {
+			slog.Warn("Could not resolve default config path, using defaults", "error", err)
+		}
+		path = defaultPath
+	}
+	return path
+}
+
+// loadConfig loads the config file given by --config, or the default one
+func loadConfig(cmd *cobra.Command) (config.Config, error) {
+	cfg := config.DefaultConfig()
+	if path := configPath(cmd); path != "" {
+		var err error
+		if cfg, err = config.Load(path); err != nil {
+			return cfg, err
+		}
+	}
+	if explicit, _ := cmd.Flags().GetString("config"); explicit != "" {
+		// The administrator's lockdown is in the default config
+		if defaultPath, err := config.DefaultPath(); err == nil {
+			admin, err := config.Load(defaultPath)
+			if err != nil {
+				return cfg, err
+			}
+			cfg.ApplyLockdown(admin)
+		}
+	}
+	if cmd.Flags().Changed("lan-only") {
+		cfg.LANOnly, _ = cmd.Flags().GetBool("lan-only")
+	}
+	if cmd.Flags().Changed("bond") {
+		cfg.ExperimentalBonding, _ = cmd.Flags().GetBool("bond")
+	}
+	if err := applyAirgapFlag(cmd, &cfg); err != nil {
+		return cfg, err
+	}
+	return cfg, nil
+}
+
+// pskEnv lets scripts pass the air-gap passphrase instead of typing it
+const pskEnv = "LANFILESHARER_PSK"
+
+// applyAirgapFlag sets up air-gap mode in cfg if --airgap is given, asking for the passphrase
+func applyAirgapFlag(cmd *cobra.Command, cfg *config.Config) error {
+	peer, _ := cmd.Flags().GetString("airgap")
+	if peer == "" {
+		return nil
+	}
+	host, _, err := net.SplitHostPort(peer)
+	if err != nil {
+		host = peer
+	}
+	if net.ParseIP(host) == nil {
+		return fmt.Errorf("--airgap needs an IP address, not %q", peer)
+	}
+	passphrase, err := readPassphrase(pskEnv, false)
+	if err != nil {
+		return err
+	}
+	cfg.AirgapPeer = peer
+	cfg.PSKPassphrase = string(passphrase)
+	return nil
+}
+
+// startProfiling starts the pprof server and the memory watermark logger requested by the flags
+func startProfiling(cmd *cobra.Command) error {
+	port, _ := cmd.Flags().GetInt("pprof-port")
+	interval, _ := cmd.Flags().GetDuration("memory-log")
+	if port > 0 {
+		if _, err := system.StartPprofServer(cmd.Context(), port); err != nil {
+			return err
+		}
+		if !cmd.Flags().Changed("memory-log") {
+			interval = system.DefaultMemoryLogInterval
+		}
+	}
+	if interval > 0 {
+		go system.NewMemoryWatermark().Run(cmd.Context(), interval)
+	}
+	return nil
+}
+
+// startProtocolTrace writes every protocol message to the file given by --trace-protocol
+func startProtocolTrace(cmd *cobra.Command) error {
+	path, _ := cmd.Flags().GetString("trace-protocol")
+	if path == "" {
+		return nil
+	}
+	// Left open for the life of the process, like debug.log
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to create protocol trace: %w", err)
+	}
+	transfer.SetProtocolTracer(transfer.NewProtocolTracer(file))
+	return nil
+}
+
 func main() {
 	f, _ := os.OpenFile("debug.log", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 	defer func() {
@@ -38,32 +230,211 @@ func main() {
 	cmd := &cobra.Command{
 		Use:   "lanFileSharer",
 		Short: "A file sharing application for local networks",
+		Long:  "A file sharing application for local networks. Without a subcommand it sends and receives at the same time, like `both`.",
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if err := startProtocolTrace(cmd); err != nil {
+				return err
+			}
+			return startProfiling(cmd)
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			runWithUIMode(ui.Both, cmd)
+		},
 	}
 
 	cmd.PersistentFlags().IntP("port", "p", 8080, "Port to listen on")
 	
 	cmd.PersistentFlags().StringP("output", "o", ".", "Output directory for received files")
 
+	cmd.PersistentFlags().String("config", "", "Path to config file (default is the user config directory)")
+	cmd.PersistentFlags().Int("pprof-port", 0, "Serve net/http/pprof on localhost at this port for profiling (0 disables)")
+	cmd.PersistentFlags().Bool("lan-only", false, "Keep transfers on private and link-local addresses, without STUN or TURN (overrides lan_only)")
+	cmd.PersistentFlags().Bool("bond", false, "Experimental: stripe large files over every local interface that reaches the receiver (overrides experimental_bonding)")
+	cmd.PersistentFlags().Bool("no-mouse", false, "Leave the mouse to the terminal instead of clicking and scrolling in the TUI (overrides disable_mouse)")
+	cmd.PersistentFlags().String("trace-protocol", "", "Write every signaling and data channel message to this file as newline-delimited JSON, see docs/PROTOCOL_TRACE.md")
+	cmd.PersistentFlags().Duration("memory-log", 0, "Log memory use to debug.log at this interval, new peaks at info level (defaults to 30s with --pprof-port)")
+
 	receiveCmd := &cobra.Command{
 		Use:   "receive",
 		Short: "Start the receiver mode",
-		Run: func(cmd *cobra.Command, args []string) {
+		Long: "Start the receiver mode. With --headless, receive without the TUI until interrupted, " +
+			"accepting only senders that were accepted in the TUI before; `service install` runs it at login.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			headless, _ := cmd.Flags().GetBool("headless")
+			if demo, _ := cmd.Flags().GetBool("demo"); demo && headless {
+				return fmt.Errorf("--demo only drives the TUI and cannot be combined with --headless")
+			}
+			if guest, _ := cmd.Flags().GetBool("guest"); guest && !headless {
+				return fmt.Errorf("--guest requires --headless, press g in the TUI instead")
+			}
+			if upload, _ := cmd.Flags().GetBool("upload"); upload && !headless {
+				return fmt.Errorf("--upload requires --headless")
+			}
+			if headless {
+				cfg, err := loadConfig(cmd)
+				if err != nil {
+					return fmt.Errorf("failed to load config: %w", err)
+				}
+				if cmd.Flags().Changed("verify-writes") {
+					cfg.VerifyWritesPercent, _ = cmd.Flags().GetInt("verify-writes")
+				}
+				if cmd.Flags().Changed("cas") {
+					cfg.ContentAddressed, _ = cmd.Flags().GetBool("cas")
+				}
+				return runHeadlessReceive(cmd, cfg)
+			}
 			runWithUIMode(ui.Receiver, cmd)
+			return nil
 		},
 	}
+	receiveCmd.Flags().Bool("headless", false, "Receive without the TUI, accepting trusted senders only")
+	receiveCmd.Flags().String("airgap", "", "Receive only from the sender at this IP, without mDNS or STUN, after both enter the same passphrase")
+	receiveCmd.Flags().Bool("auto-open", false, "Open received files with the default application when the transfer completes")
+	receiveCmd.Flags().Int("verify-writes", 0, "Percentage of written chunks to read back from disk and compare (overrides verify_writes_percent)")
+	receiveCmd.Flags().Bool("guest", false, "Print a guest token at start: one sender giving it with --guest-token sends up to guest_max_mb without being asked or trusted (with --headless)")
+	receiveCmd.Flags().Bool("upload", false, "Let browsers on the network upload files at /upload with the printed token, e.g. from phones (with --headless; the token is upload_token or a new one)")
+	receiveCmd.Flags().Bool("cas", false, "Store each received content once and skip content already stored, see `materialize` (overrides content_addressed)")
 
 	sendCmd := &cobra.Command{
-		Use:   "send",
+		Use:   "send [files...]",
 		Short: "Start the sender mode",
+		Long: "Start the sender mode. With --to, files are sent to the named receiver without the TUI, " +
+			"e.g. `cat backup.sql | lanFileSharer send --to peer --stdin-name backup.sql`. " +
+			"With --airgap, the receiver is reached at its address instead, for machines without multicast or " +
+			"internet such as two laptops on one cable; start it with `receive --airgap <sender-ip>` " +
+			"and enter the same passphrase on both.\n\n" +
+			"A send with --to or --airgap exits with 0 on success, 1 on any other failure, 2 when the receiver rejects it, " +
+			"3 on network failures, 4 when only some files were sent, 5 when the receiver is not found, " +
+			"6 when the receiver does not answer, 7 when the files cannot be read and 130 when interrupted. " +
+			"On failure the last line on stderr is a JSON object such as " +
+			"`{\"error\":{\"reason\":\"rejected\",\"exit_code\":2,\"message\":\"...\",\"files_sent\":0,\"files_failed\":0}}`.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			to, _ := cmd.Flags().GetString("to")
+			airgap, _ := cmd.Flags().GetString("airgap")
+			if to != "" && airgap != "" {
+				return fmt.Errorf("--to and --airgap cannot be combined")
+			}
+			if demo, _ := cmd.Flags().GetBool("demo"); demo && (to != "" || airgap != "") {
+				return fmt.Errorf("--demo only drives the TUI and cannot be combined with --to or --airgap")
+			}
+			if via, _ := cmd.Flags().GetString("via"); via != "" && to == "" {
+				return fmt.Errorf("--via requires --to")
+			}
+			if to == "" && airgap == "" {
+				if len(args) > 0 {
+					return fmt.Errorf("file arguments require --to")
+				}
+				if cmd.Flags().Changed("resume") {
+					return fmt.Errorf("--resume requires --to and the files of the interrupted transfer")
+				}
+				if cmd.Flags().Changed("progress-json") {
+					return fmt.Errorf("--progress-json requires --to")
+				}
+				if cmd.Flags().Changed("chaos") {
+					return fmt.Errorf("--chaos requires --to")
+				}
+				if cmd.Flags().Changed("batch") {
+					return fmt.Errorf("--batch requires --to")
+				}
+				if cmd.Flags().Changed("guest-token") {
+					return fmt.Errorf("--guest-token requires --to")
+				}
+				runWithUIMode(ui.Sender, cmd)
+				return nil
+			}
+
+			cfg, err := loadConfig(cmd)
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+			if cmd.Flags().Changed("manifest") {
+				cfg.GenerateManifest, _ = cmd.Flags().GetBool("manifest")
+			}
+			cfg.ForceResend, _ = cmd.Flags().GetBool("force")
+			if err := applyRetryFlags(cmd, &cfg); err != nil {
+				return err
+			}
+			cfg.Chaos, _ = cmd.Flags().GetString("chaos")
+			if _, err := senderApp.ParseChaos(cfg); err != nil {
+				return fmt.Errorf("invalid --chaos: %w", err)
+			}
+			return runHeadlessSend(cmd, args, cfg)
+		},
+	}
+	sendCmd.Flags().Bool("manifest", false, "Prepend a checksums.sha256 manifest describing the sent files")
+	sendCmd.Flags().String("to", "", "Send to the named receiver without the TUI (hostname or service name)")
+	sendCmd.Flags().String("via", "", "Send to the --to receiver through this relay, which stores the files and forwards them (see `relay`)")
+	sendCmd.Flags().String("airgap", "", "Send without the TUI to the receiver at this IP[:port], without mDNS or STUN, after both enter the same passphrase")
+	sendCmd.Flags().String("stdin-name", "", "Stream standard input to the receiver as a file with this name (requires --to)")
+	sendCmd.Flags().Bool("progress-json", false, "Write progress as newline-delimited JSON to stdout (with --to)")
+	sendCmd.Flags().Duration("timeout", 2*time.Minute, "Maximum duration of a transfer")
+	sendCmd.Flags().String("resume", "", "Resume an interrupted transfer using the token printed by both sides")
+	sendCmd.Flags().Bool("force", false, "Resend files even if the receiver already got them unchanged")
+	sendCmd.Flags().String("guest-token", "", "Token of a guest pass the receiver shows, which accepts this transfer without asking (with --to)")
+	sendCmd.Flags().Bool("batch", false, "Send each file argument as an item of one batch, accepted once; `cancel <item>` on stdin stops one item (with --to)")
+	sendCmd.Flags().Int("max-retries", 3, "How often a failed file is retried (overrides retry_max_retries)")
+	sendCmd.Flags().Duration("retry-delay", time.Second, "Delay before the first retry (overrides retry_initial_delay_ms)")
+	sendCmd.Flags().Float64("retry-backoff", 2, "Factor the retry delay grows by after every retry (overrides retry_backoff_factor)")
+	sendCmd.Flags().Duration("retry-max-delay", 30*time.Second, "Upper bound of the retry delay (overrides retry_max_delay_ms)")
+	sendCmd.Flags().StringSlice("retry-on", nil, "Error classes to retry, e.g. timeout,connection_lost (overrides retry_on)")
+	// Fault injection for testing recovery; not for everyday use, so it is left out of the help
+	sendCmd.Flags().String("chaos", "", "Inject faults into sent chunks, e.g. drop=0.01,delay=0.05:20ms,reorder=0.02,corrupt=0.01,seed=42")
+	_ = sendCmd.Flags().MarkHidden("chaos")
+
+	bothCmd := &cobra.Command{
+		Use:   "both",
+		Short: "Send and receive from one instance, with Send, Receive and History tabs",
 		Run: func(cmd *cobra.Command, args []string) {
-			runWithUIMode(ui.Sender, cmd)
+			runWithUIMode(ui.Both, cmd)
 		},
 	}
+	for _, c := range []*cobra.Command{cmd, receiveCmd, sendCmd, bothCmd} {
+		c.Flags().Bool("demo", false, "Drive the TUI with simulated peers and transfers, without the network, to work on the UI and themes")
+	}
+	for _, c := range []*cobra.Command{cmd, bothCmd} {
+		c.Flags().Bool("auto-open", false, "Open received files with the default application when the transfer completes")
+		c.Flags().Bool("manifest", false, "Prepend a checksums.sha256 manifest describing the sent files")
+		c.Flags().Bool("force", false, "Resend files even if the receiver already got them unchanged")
+		c.Flags().Int("verify-writes", 0, "Percentage of written chunks to read back from disk and compare (overrides verify_writes_percent)")
+	}
 
 	cmd.AddCommand(receiveCmd)
 	cmd.AddCommand(sendCmd)
+	cmd.AddCommand(bothCmd)
+	cmd.AddCommand(newKeysCmd())
+	cmd.AddCommand(newPeerCmd())
+	cmd.AddCommand(newPingCmd())
+	cmd.AddCommand(newTuneCmd())
+	cmd.AddCommand(newAuditCmd())
+	cmd.AddCommand(newThemeCmd())
+	cmd.AddCommand(newServiceCmd())
+	cmd.AddCommand(newIdentityCmd())
+	cmd.AddCommand(newTrustCmd())
+	cmd.AddCommand(newRelayCmd())
+	cmd.AddCommand(newShareCmd())
+	cmd.AddCommand(newPullCmd())
+	cmd.AddCommand(newRecentCmd())
+	cmd.AddCommand(newJobCmd())
+	cmd.AddCommand(newDiffCmd())
+	cmd.AddCommand(newMaterializeCmd())
+	cmd.AddCommand(newReplayCmd())
 
-	if err := fang.Execute(context.Background(), cmd); err != nil {
-		os.Exit(1)
+	if err := fang.Execute(context.Background(), cmd,
+		fang.WithVersion(version.String()),
+		fang.WithErrorHandler(reportError),
+	); err != nil {
+		os.Exit(exitCode(err))
+	}
+}
+
+// reportError prints err for people, followed by a JSON failure report for scripts when
+// a headless command failed
+func reportError(w io.Writer, styles fang.Styles, err error) {
+	fang.DefaultErrorHandler(w, styles, err)
+	var failure *headlessError
+	if errors.As(err, &failure) {
+		if err := failure.writeJSON(w); err != nil {
+			slog.Debug("Dropping failure report", "error", err)
+		}
 	}
 }
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/rescp17/lanFileSharer/pkg/cas"
+)
+
+// newMaterializeCmd creates the command that exports the files of a content-addressed output directory
+func newMaterializeCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "materialize <dest>",
+		Short: "Export the files received with --cas to <dest> as ordinary copies",
+		Long: "With content_addressed or `receive --cas`, every received content is stored once in the " +
+			cas.Dir + " directory of the output directory and the received files are hard links to it, so " +
+			"changing one in place changes every file with the same content. Export the received tree of " +
+			"--output to <dest> as independent copies, also of files since removed from the output directory.",
+		Example: "  lanfilesharer materialize ~/export -o ~/Received",
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			outputDir, _ := cmd.Flags().GetString("output")
+			if _, err := os.Stat(filepath.Join(outputDir, cas.Dir)); err != nil {
+				return fmt.Errorf("%s has no content store, receive with --cas first: %w", outputDir, err)
+			}
+			store, err := cas.Open(outputDir)
+			if err != nil {
+				return err
+			}
+			written, err := store.Materialize(args[0])
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Exported %d files to %s\n", written, args[0])
+			return nil
+		},
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/rescp17/lanFileSharer/internal/app_events/receiver"
+	"github.com/rescp17/lanFileSharer/internal/util"
+	"github.com/rescp17/lanFileSharer/pkg/peers"
+)
+
+// newPeerCmd creates the command for managing the settings saved for individual peers
+func newPeerCmd() *cobra.Command {
+	peerCmd := &cobra.Command{
+		Use:   "peer",
+		Short: "Manage settings saved for individual peers",
+		Long: "Save settings for a peer, keyed by the fingerprint of its device key as shown by keys: a bandwidth " +
+			"limit and transport used when sending to it, and an output subdirectory and auto-accept used when it " +
+			"sends to this device. They are applied when the peer is selected or connects.",
+	}
+
+	setCmd := &cobra.Command{
+		Use:   "set <fingerprint>",
+		Short: "Save settings for a peer, keeping those not given",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runPeerSet(cmd, args[0])
+		},
+	}
+	setCmd.Flags().String("label", "", "Name shown for the peer")
+	setCmd.Flags().Int("bandwidth-limit", 0, "Send to the peer at most this many KiB per second, 0 for no limit")
+	setCmd.Flags().String("output-subdir", "", "Save files from the peer in this directory under the output directory")
+	setCmd.Flags().Bool("auto-accept", false, "Accept requests from the peer without asking")
+	setCmd.Flags().String("transport", peers.TransportAuto, fmt.Sprintf("Reach the peer over %q or only the local network with %q",
+		peers.TransportAuto, peers.TransportLAN))
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List peers with saved settings",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store, err := loadPeerStore()
+			if err != nil {
+				return err
+			}
+			list := store.Peers()
+			if len(list) == 0 {
+				fmt.Fprintln(cmd.OutOrStdout(), "No peer settings saved")
+				return nil
+			}
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "FINGERPRINT\tLABEL\tLIMIT\tOUTPUT\tAUTO-ACCEPT\tTRANSPORT")
+			for _, peer := range list {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%t\t%s\n", peer.Fingerprint, orDash(peer.Label),
+					formatBandwidthLimit(peer.Settings), orDash(peer.OutputSubdir), peer.AutoAccept, orDash(peer.Transport))
+			}
+			return w.Flush()
+		},
+	}
+
+	removeCmd := &cobra.Command{
+		Use:   "remove <fingerprint>",
+		Short: "Delete the settings saved for a peer",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store, err := loadPeerStore()
+			if err != nil {
+				return err
+			}
+			if err := store.Remove(args[0]); err != nil {
+				return err
+			}
+			if err := store.Save(); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Removed settings for %s\n", peers.NormalizeFingerprint(args[0]))
+			return nil
+		},
+	}
+
+	peerCmd.AddCommand(setCmd, listCmd, removeCmd)
+	return peerCmd
+}
+
+func runPeerSet(cmd *cobra.Command, fingerprint string) error {
+	store, err := loadPeerStore()
+	if err != nil {
+		return err
+	}
+	settings, _ := store.Get(fingerprint)
+	flags := cmd.Flags()
+	if flags.Changed("label") {
+		settings.Label, _ = flags.GetString("label")
+	}
+	if flags.Changed("bandwidth-limit") {
+		settings.BandwidthLimitKB, _ = flags.GetInt("bandwidth-limit")
+	}
+	if flags.Changed("output-subdir") {
+		settings.OutputSubdir, _ = flags.GetString("output-subdir")
+	}
+	if flags.Changed("auto-accept") {
+		settings.AutoAccept, _ = flags.GetBool("auto-accept")
+	}
+	if flags.Changed("transport") {
+		settings.Transport, _ = flags.GetString("transport")
+	}
+	if err := store.Set(fingerprint, settings); err != nil {
+		return err
+	}
+	if err := store.Save(); err != nil {
+		return err
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "Saved settings for %s\n", peers.NormalizeFingerprint(fingerprint))
+	return nil
+}
+
+// loadPeerStore opens the peer settings file in the config directory
+func loadPeerStore() (*peers.Store, error) {
+	path, err := peers.DefaultPath()
+	if err != nil {
+		return nil, fmt.Errorf("could not resolve the peer settings location: %w", err)
+	}
+	return peers.Load(path)
+}
+
+// formatBandwidthLimit renders the bandwidth limit of settings, or a dash if there is none
+func formatBandwidthLimit(settings peers.Settings) string {
+	if settings.BandwidthLimitKB == 0 {
+		return "-"
+	}
+	return util.FormatSize(settings.BandwidthLimit()) + "/s"
+}
+
+// peerName returns the label saved for the sender of m, or its fingerprint, after the
+// icon the sender gave
+func peerName(m receiver.FileNodeUpdateMsg) string {
+	if m.PeerLabel != "" {
+		return util.WithIcon(m.SenderIcon, fmt.Sprintf("%s (%s)", m.PeerLabel, m.SenderFingerprint))
+	}
+	return util.WithIcon(m.SenderIcon, m.SenderFingerprint)
+}
+
+// orDash returns s, or a dash if it is empty
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/rescp17/lanFileSharer/pkg/discovery"
+	senderApp "github.com/rescp17/lanFileSharer/pkg/sender"
+	webrtcPkg "github.com/rescp17/lanFileSharer/pkg/webrtc"
+)
+
+// echoTimeout bounds setting up the echo connection and echoing every message
+const echoTimeout = 30 * time.Second
+
+// newPingCmd creates the command that checks whether a peer is reachable
+func newPingCmd() *cobra.Command {
+	pingCmd := &cobra.Command{
+		Use:   "ping <peer>",
+		Short: "Check that a receiver can be found, signaled and reached over a data channel",
+		Long: "Resolve the receiver through discovery, perform a signaling round trip and, unless --echo=false, " +
+			"echo a few messages over a WebRTC data channel. Nothing is shown to the receiver's user. " +
+			"The step that fails tells where sending breaks.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runPing(cmd, args[0])
+		},
+	}
+	pingCmd.Flags().Bool("echo", true, "Echo messages over a data channel and report the path type")
+	pingCmd.Flags().Int("count", 5, "Messages to echo")
+	pingCmd.Flags().Int("size", 1024, "Bytes per echoed message")
+	return pingCmd
+}
+
+func runPing(cmd *cobra.Command, peer string) error {
+	echo, _ := cmd.Flags().GetBool("echo")
+	count, _ := cmd.Flags().GetInt("count")
+	size, _ := cmd.Flags().GetInt("size")
+	out := cmd.OutOrStdout()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	cfg, err := loadConfig(cmd)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	app := senderApp.NewAppWithOptions(&discovery.MDNSAdapter{}, senderApp.Options{Scope: senderApp.ServiceScope(cfg)})
+
+	fmt.Fprintf(out, "Resolving %q...\n", peer)
+	start := time.Now()
+	findCtx, cancel := context.WithTimeout(ctx, discoveryTimeout)
+	receiver, err := app.FindReceiver(findCtx, peer)
+	cancel()
+	if err != nil {
+		return fmt.Errorf("discovery: %w", err)
+	}
+	fmt.Fprintf(out, "Discovery: found %s at %s:%d in %s\n", receiver.Name, receiver.Addr, receiver.Port, formatLatency(time.Since(start)))
+
+	pingCtx, cancel := context.WithTimeout(ctx, discoveryTimeout)
+	ping, rtt, err := app.PingReceiver(pingCtx, receiver)
+	cancel()
+	if err != nil {
+		return fmt.Errorf("signaling: %w", err)
+	}
+	state := "idle"
+	if ping.Busy {
+		state = "busy with a transfer"
+	}
+	if ping.Availability != "" && ping.Availability != "available" {
+		state += ", " + ping.Availability
+	}
+	fmt.Fprintf(out, "Signaling: %s round trip, receiver %s\n", formatLatency(rtt), state)
+
+	if !echo {
+		return nil
+	}
+	if !ping.Echo {
+		fmt.Fprintln(out, "Echo: not supported by the receiver")
+		return nil
+	}
+	echoCtx, cancel := context.WithTimeout(ctx, echoTimeout)
+	defer cancel()
+	result, err := app.EchoReceiver(echoCtx, receiver, webrtcPkg.EchoOptions{Count: count, Size: size})
+	if err != nil {
+		return fmt.Errorf("data channel: %w", err)
+	}
+	path := result.Path
+	if path == "" {
+		path = "unknown path"
+	}
+	fmt.Fprintf(out, "Data channel: open in %s, %s (%s)\n", formatLatency(result.SetupTime), result.PathType, path)
+	fmt.Fprintf(out, "Echo: %d/%d replies%s\n", len(result.RTTs), len(result.RTTs)+result.Lost, formatRTTs(result.RTTs))
+	if len(result.RTTs) == 0 {
+		return fmt.Errorf("data channel: no echo replies")
+	}
+	return nil
+}
+
+// formatRTTs renders the minimum, average and maximum of rtts, or "" if there are none
+func formatRTTs(rtts []time.Duration) string {
+	if len(rtts) == 0 {
+		return ""
+	}
+	lowest, highest, total := rtts[0], rtts[0], time.Duration(0)
+	for _, rtt := range rtts {
+		lowest = min(lowest, rtt)
+		highest = max(highest, rtt)
+		total += rtt
+	}
+	return fmt.Sprintf(", min/avg/max %s/%s/%s", formatLatency(lowest), formatLatency(total/time.Duration(len(rtts))), formatLatency(highest))
+}
+
+// formatLatency rounds d for display
+func formatLatency(d time.Duration) string {
+	if d < time.Millisecond {
+		return d.Round(time.Microsecond).String()
+	}
+	return d.Round(100 * time.Microsecond).String()
+}
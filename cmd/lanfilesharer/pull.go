@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/spf13/cobra"
+
+	appevents "github.com/rescp17/lanFileSharer/internal/app_events"
+	"github.com/rescp17/lanFileSharer/internal/app_events/receiver"
+	"github.com/rescp17/lanFileSharer/internal/config"
+	"github.com/rescp17/lanFileSharer/internal/util"
+	receiverApp "github.com/rescp17/lanFileSharer/pkg/receiver"
+	"github.com/rescp17/lanFileSharer/pkg/recent"
+	"github.com/rescp17/lanFileSharer/pkg/scan"
+	senderApp "github.com/rescp17/lanFileSharer/pkg/sender"
+	"github.com/rescp17/lanFileSharer/pkg/share"
+	"github.com/rescp17/lanFileSharer/pkg/storage"
+)
+
+// newPullCmd creates the command that imports an offer file and receives its files
+func newPullCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "pull <file" + share.OfferExtension + ">",
+		Short: "Receive the files of an offer file exported by `share --export-offer`",
+		Long: "Import an offer file moved here from a sharing device, by USB stick or email, and ask that device " +
+			"for the offered files. The offer is checked against the signature of the sharing device and " +
+			"its expiry first. The transfer is accepted without asking only when it is signed with the same key " +
+			"as the offer file; the command exits once it finished.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadConfig(cmd)
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+			file, err := os.Open(args[0])
+			if err != nil {
+				return fmt.Errorf("failed to open offer file: %w", err)
+			}
+			defer file.Close()
+			offer, err := share.ReadOffer(file)
+			if err != nil {
+				return err
+			}
+			return runPull(cmd, cfg, offer)
+		},
+	}
+}
+
+// runPull receives the files of offer into the output directory, returning once the transfer
+// finished or the share could not be asked for them
+func runPull(cmd *cobra.Command, cfg config.Config, offer *share.Offer) error {
+	port, _ := cmd.Flags().GetInt("port")
+	outputDir, err := resolveOutputDir(cmd, cfg)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	serviceName, err := receiverApp.NewServiceName()
+	if err != nil {
+		return fmt.Errorf("could not create service name: %w", err)
+	}
+	// The share is told where to send the files, so nothing is announced
+	app := receiverApp.NewAppWithOptions(port, outputDir, receiverApp.Options{
+		TrustStorePath: receiverApp.TrustStorePath(),
+		TrustMaxAge:    cfg.TrustMaxAge(),
+		TrustReadOnly:  cfg.TrustReadOnly,
+		Lockdown:       cfg.Lockdown,
+		AcceptTimeout:  cfg.AcceptTimeout(),
+		IdleTimeout:    cfg.SessionIdleTimeout(),
+		ServiceName:    serviceName,
+		Scopes:         receiverApp.ServiceScopes(cfg),
+		AuditLog:       receiverApp.AuditLog(cfg),
+		VerifyWrites:   cfg.VerifyWritesFraction(),
+		OutputTemplate: receiverApp.LoadOutputTemplate(cfg),
+		Quota:          receiverApp.Quota(cfg),
+		LANOnly:        cfg.LANOnly,
+		Offline:        true,
+		PSK:            receiverApp.PSK(cfg),
+		Fingerprint:    senderApp.DeviceFingerprint(cfg),
+		Recent:         recent.OpenDefault(),
+		Scanner:        scan.FromConfig(cfg),
+		Storage:        storage.FromConfig(cfg, outputDir),
+	})
+
+	fmt.Fprintf(os.Stderr, "Pulling %d files (%s) offered by %s, signed by %s, into %s\n",
+		len(offer.Structure.Files), util.FormatSize(offer.TotalSize()), offer.Sender, offer.Fingerprint(), outputDir)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- app.Run(ctx)
+	}()
+	select {
+	case app.AppEvents() <- receiver.PullOffer{Offer: offer}:
+	case err := <-done:
+		return err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-done:
+			return err
+		case msg := <-app.UIMessages():
+			finished, err := handlePullMsg(app, msg, offer)
+			if finished {
+				return err
+			}
+		}
+	}
+}
+
+// handlePullMsg answers the request of the share and reports the progress of a pull of offer,
+// returning whether the pull finished and how
+func handlePullMsg(app *receiverApp.App, msg tea.Msg, offer *share.Offer) (bool, error) {
+	switch m := msg.(type) {
+	case receiver.PullRequestedMsg:
+		if m.Err != nil {
+			return true, fmt.Errorf("%s could not be asked for the offer: %w", offer.Sender, m.Err)
+		}
+		fmt.Fprintf(os.Stderr, "%s is preparing the files, waiting for them...\n", offer.Sender)
+	case receiver.FileNodeUpdateMsg:
+		if !m.Pulled {
+			fmt.Fprintf(os.Stderr, "Declining %d files from %s, which is not the offer\n", len(m.Nodes), peerName(m))
+			app.AppEvents() <- receiver.FileRequestRejected{}
+			return false, nil
+		}
+		fmt.Fprintf(os.Stderr, "Accepting the offered files from %s\n", peerName(m))
+		app.AppEvents() <- receiver.FileRequestAccepted{}
+	case receiver.RequestDeclinedMsg:
+		fmt.Fprintf(os.Stderr, "Declined a request: %s\n", m.Reason)
+	case receiver.TransferFinishedMsg:
+		if m.Err != nil {
+			return true, fmt.Errorf("transfer failed: %w", m.Err)
+		}
+		fmt.Fprintf(os.Stderr, "Received %s\n", m.OutputPath)
+		return true, nil
+	case appevents.Error:
+		fmt.Fprintf(os.Stderr, "Error: %v\n", m.Err)
+	}
+	return false, nil
+}
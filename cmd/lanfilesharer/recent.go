@@ -0,0 +1,124 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/rescp17/lanFileSharer/internal/util"
+	"github.com/rescp17/lanFileSharer/pkg/recent"
+)
+
+// newRecentCmd creates the command that lists the recently received files
+func newRecentCmd() *cobra.Command {
+	recentCmd := &cobra.Command{
+		Use:   "recent",
+		Short: "List the recently received files, the newest first",
+		Long: fmt.Sprintf("List the last %d files received by any receiver of this user, numbered from the newest. "+
+			"`recent copy [n]` puts the path of file n, the newest by default, on the clipboard; "+
+			"--paths prints bare paths for other commands, as in `ls -l \"$(lanfilesharer recent --paths | head -1)\"`. "+
+			"The receiver TUI lists them with `r`.", recent.MaxEntries),
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			entries, err := loadRecent()
+			if err != nil {
+				return err
+			}
+			if paths, _ := cmd.Flags().GetBool("paths"); paths {
+				for _, entry := range entries {
+					fmt.Fprintln(cmd.OutOrStdout(), entry.Path)
+				}
+				return nil
+			}
+			return printRecent(cmd.OutOrStdout(), entries)
+		},
+	}
+	recentCmd.Flags().Bool("paths", false, "Print only the paths, one per line")
+
+	copyCmd := &cobra.Command{
+		Use:   "copy [n]",
+		Short: "Copy the path of a recently received file to the clipboard",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			entries, err := loadRecent()
+			if err != nil {
+				return err
+			}
+			n := 1
+			if len(args) == 1 {
+				if n, err = strconv.Atoi(args[0]); err != nil {
+					return fmt.Errorf("invalid file number %q", args[0])
+				}
+			}
+			if n < 1 || n > len(entries) {
+				return fmt.Errorf("no recently received file %d, there are %d", n, len(entries))
+			}
+			path := entries[n-1].Path
+			if err := util.CopyToClipboard(path); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.ErrOrStderr(), "Copied %s\n", path)
+			return nil
+		},
+	}
+
+	clearCmd := &cobra.Command{
+		Use:   "clear",
+		Short: "Forget the recently received files; the files themselves are kept",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store, err := openRecent()
+			if err != nil {
+				return err
+			}
+			if err := store.Clear(); err != nil {
+				return err
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), "Cleared the recently received files")
+			return nil
+		},
+	}
+
+	recentCmd.AddCommand(copyCmd, clearCmd)
+	return recentCmd
+}
+
+// openRecent opens the recently received files list in the config directory
+func openRecent() (*recent.Store, error) {
+	path, err := recent.DefaultPath()
+	if err != nil {
+		return nil, fmt.Errorf("could not resolve the recent files list: %w", err)
+	}
+	return recent.Open(path), nil
+}
+
+// loadRecent returns the recently received files, the newest first
+func loadRecent() ([]recent.Entry, error) {
+	store, err := openRecent()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := store.List()
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, errors.New("no files received yet")
+	}
+	return entries, nil
+}
+
+func printRecent(out io.Writer, entries []recent.Entry) error {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "#\tRECEIVED\tSIZE\tSENDER\tPATH")
+	for i, entry := range entries {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", i+1, entry.ReceivedAt.Format(time.DateTime),
+			util.FormatSize(entry.Size), orDash(entry.Sender), entry.Path)
+	}
+	return w.Flush()
+}
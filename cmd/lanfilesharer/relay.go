@@ -0,0 +1,340 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/spf13/cobra"
+
+	appevents "github.com/rescp17/lanFileSharer/internal/app_events"
+	"github.com/rescp17/lanFileSharer/internal/app_events/receiver"
+	"github.com/rescp17/lanFileSharer/internal/app_events/sender"
+	"github.com/rescp17/lanFileSharer/internal/config"
+	"github.com/rescp17/lanFileSharer/pkg/crypto"
+	"github.com/rescp17/lanFileSharer/pkg/discovery"
+	"github.com/rescp17/lanFileSharer/pkg/peers"
+	receiverApp "github.com/rescp17/lanFileSharer/pkg/receiver"
+	"github.com/rescp17/lanFileSharer/pkg/scan"
+	senderApp "github.com/rescp17/lanFileSharer/pkg/sender"
+	"github.com/rescp17/lanFileSharer/pkg/transfer"
+	"github.com/rescp17/lanFileSharer/pkg/webhook"
+)
+
+// relayUndelivered is the spool directory transfers that could not be forwarded are moved to
+const relayUndelivered = ".undelivered"
+
+// relayBusyMessage is sent with requests declined while the relay forwards a transfer
+const relayBusyMessage = "The relay is forwarding another transfer, try again shortly"
+
+// newRelayCmd creates the command that forwards transfers between devices that cannot reach each other
+func newRelayCmd() *cobra.Command {
+	relayCmd := &cobra.Command{
+		Use:   "relay",
+		Short: "Forward transfers between devices on networks that cannot reach each other",
+		Long: "Run a headless receiver that advertises itself as a relay, for senders and receivers on " +
+			"isolated networks, such as separate VLANs, that can both reach this device. A transfer sent with " +
+			"`send --to <receiver> --via <relay>` is stored in the spool directory and then forwarded to the " +
+			"receiver, which sees it coming from the relay. Transfers for the relay itself are declined.\n\n" +
+			"Like `receive --headless`, the relay accepts only senders whose keys it trusts, so accept each sender " +
+			"once in the TUI of this device; the receiver in turn has to trust the relay. Forwarded files are " +
+			"removed from the spool, files that could not be forwarded are kept in its " + relayUndelivered + " directory.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadConfig(cmd)
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+			return runRelay(cmd, cfg)
+		},
+	}
+	relayCmd.Flags().String("spool", "", "Directory transfers are stored in until they are forwarded (default is relay in the user config directory)")
+	relayCmd.Flags().Duration("timeout", 2*time.Minute, "Maximum duration of a forwarded transfer")
+	return relayCmd
+}
+
+// relay receives transfers meant for other receivers and forwards them one at a time
+type relay struct {
+	app     *receiverApp.App
+	cfg     config.Config
+	name    string // Service name of the relay, hidden when looking for receivers
+	spool   string
+	timeout time.Duration
+	target  string      // Receiver the accepted request is for
+	forward chan string // Receivers of the received transfers waiting to be forwarded
+}
+
+func runRelay(cmd *cobra.Command, cfg config.Config) error {
+	port, _ := cmd.Flags().GetInt("port")
+	timeout, _ := cmd.Flags().GetDuration("timeout")
+	spool, err := relaySpool(cmd)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(spool, 0755); err != nil {
+		return fmt.Errorf("failed to create spool directory: %w", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	serviceName, err := receiverApp.NewServiceName()
+	if err != nil {
+		slog.Warn("Could not create service name, using the default", "error", err)
+	}
+	r := &relay{
+		cfg:     cfg,
+		name:    serviceName,
+		spool:   spool,
+		timeout: timeout,
+		forward: make(chan string, 1),
+	}
+	r.app = receiverApp.NewAppWithOptions(port, spool, receiverApp.Options{
+		TrustStorePath:      receiverApp.TrustStorePath(),
+		TrustMaxAge:         cfg.TrustMaxAge(),
+		TrustReadOnly:       cfg.TrustReadOnly,
+		Lockdown:            cfg.Lockdown,
+		AcceptTimeout:       cfg.AcceptTimeout(),
+		IdleTimeout:         cfg.SessionIdleTimeout(),
+		Registrar:           &discovery.MDNSAdapter{},
+		ServiceName:         serviceName,
+		DoNotDisturbMessage: relayBusyMessage,
+		Scopes:              receiverApp.ServiceScopes(cfg),
+		PeerFilter:          receiverApp.PeerFilter(cfg),
+		AuditLog:            receiverApp.AuditLog(cfg),
+		VerifyWrites:        cfg.VerifyWritesFraction(),
+		Quota:               receiverApp.Quota(cfg),
+		LANOnly:             cfg.LANOnly,
+		Relay:               true,
+		PeerSettings:        peers.LoadDefault(),
+		Webhooks:            webhook.FromConfig(cfg),
+		Fingerprint:         senderApp.DeviceFingerprint(cfg),
+		Icon:                cfg.Icon,
+		Scanner:             scan.FromConfig(cfg),
+	})
+
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg := <-r.app.UIMessages():
+				r.handle(msg)
+			}
+		}
+	}()
+	go r.forwardLoop(ctx)
+
+	fmt.Fprintf(os.Stderr, "Relaying on port %d through %s, accepting trusted senders only\n", port, spool)
+	return r.app.Run(ctx)
+}
+
+// relaySpool returns the spool directory given by --spool, or the default one
+func relaySpool(cmd *cobra.Command) (string, error) {
+	if spool, _ := cmd.Flags().GetString("spool"); spool != "" {
+		return spool, nil
+	}
+	dir, err := config.Dir()
+	if err != nil {
+		return "", fmt.Errorf("could not resolve the spool directory, pass --spool: %w", err)
+	}
+	return filepath.Join(dir, "relay"), nil
+}
+
+// handle answers requests and hands received transfers to the forwarder. The spool holds one
+// transfer at a time: the relay declines requests until it forwarded the last one.
+func (r *relay) handle(msg tea.Msg) {
+	switch m := msg.(type) {
+	case receiver.FileNodeUpdateMsg:
+		if m.RelayTo == "" {
+			fmt.Fprintf(os.Stderr, "Declining %d files from %s, the relay only forwards transfers sent --via it\n",
+				len(m.Nodes), m.SenderFingerprint)
+			r.app.AppEvents() <- receiver.FileRequestRejected{}
+			return
+		}
+		if m.Resumed {
+			fmt.Fprintf(os.Stderr, "Resuming the session of %s for %q, which stopped responding\n", peerName(m), m.RelayTo)
+			r.target = m.RelayTo
+			r.app.AppEvents() <- receiver.FileRequestAccepted{}
+			return
+		}
+		if m.AutoAccept {
+			fmt.Fprintf(os.Stderr, "Accepting %d files for %q from %s, auto-accept is saved for this peer\n",
+				len(m.Nodes), m.RelayTo, peerName(m))
+			r.target = m.RelayTo
+			r.app.AppEvents() <- receiver.FileRequestAccepted{}
+			return
+		}
+		if m.SenderTrust != crypto.TrustValid.String() {
+			fmt.Fprintf(os.Stderr, "Declining %d files for %q from %s sender %s, accept it once in the TUI to trust it\n",
+				len(m.Nodes), m.RelayTo, m.SenderTrust, m.SenderFingerprint)
+			r.app.AppEvents() <- receiver.FileRequestRejected{}
+			return
+		}
+		fmt.Fprintf(os.Stderr, "Accepting %d files for %q from trusted sender %s\n", len(m.Nodes), m.RelayTo, m.SenderFingerprint)
+		r.target = m.RelayTo
+		r.app.AppEvents() <- receiver.FileRequestAccepted{}
+	case receiver.RequestDeclinedMsg:
+		reason := m.Reason
+		if reason == "" {
+			reason = m.Availability.String()
+		}
+		fmt.Fprintf(os.Stderr, "Declined a request: %s\n", reason)
+	case receiver.TransferFinishedMsg:
+		target := r.target
+		r.target = ""
+		if m.Err != nil {
+			fmt.Fprintf(os.Stderr, "Transfer for %q failed: %v\n", target, m.Err)
+			r.discardSpool()
+			return
+		}
+		if target == "" {
+			return
+		}
+		r.app.AppEvents() <- receiver.SetAvailability{Availability: receiver.DoNotDisturb}
+		r.forward <- target
+	case appevents.Error:
+		fmt.Fprintf(os.Stderr, "Error: %v\n", m.Err)
+	}
+}
+
+// forwardLoop forwards the received transfers until ctx ends, taking requests again after each
+func (r *relay) forwardLoop(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case target := <-r.forward:
+			if err := r.forwardSpool(ctx, target); err != nil {
+				fmt.Fprintf(os.Stderr, "Could not forward to %q: %v\n", target, err)
+				if dir, err := r.setAside(); err != nil {
+					fmt.Fprintf(os.Stderr, "Could not keep the undelivered files: %v\n", err)
+				} else {
+					fmt.Fprintf(os.Stderr, "Kept the undelivered files in %s\n", dir)
+				}
+			}
+			select {
+			case r.app.AppEvents() <- receiver.SetAvailability{Availability: receiver.Available}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+// forwardSpool sends everything in the spool to target and empties the spool once it arrived
+func (r *relay) forwardSpool(ctx context.Context, target string) error {
+	paths, err := spooledPaths(r.spool)
+	if err != nil {
+		return err
+	}
+	if len(paths) == 0 {
+		return errors.New("nothing was received")
+	}
+
+	app := senderApp.NewAppWithOptions(&discovery.MDNSAdapter{}, senderApp.Options{
+		TransferTimeout:    r.timeout,
+		DeviceKeyPath:      senderApp.DeviceKeyPath(),
+		KeyLifetime:        r.cfg.KeyLifetime(),
+		KeyGrace:           r.cfg.KeyGrace(),
+		SignatureAlgorithm: senderApp.SignatureAlgorithm(r.cfg),
+		RetryPolicy:        senderApp.RetryPolicy(r.cfg),
+		ChunkSize:          r.cfg.ChunkSize(),
+		IdleTimeout:        r.cfg.SessionIdleTimeout(),
+		Bonding:            r.cfg.ExperimentalBonding,
+		PeerSettings:       peers.LoadDefault(),
+		Webhooks:           webhook.FromConfig(r.cfg),
+		BandwidthSchedule:  senderApp.BandwidthSchedule(r.cfg),
+		IgnoreService:      r.name,
+		Scope:              senderApp.ServiceScope(r.cfg),
+		PeerFilter:         receiverApp.PeerFilter(r.cfg),
+		LANOnly:            r.cfg.LANOnly,
+		Icon:               r.cfg.Icon,
+	})
+
+	fmt.Fprintf(os.Stderr, "Looking for receiver %q...\n", target)
+	findCtx, cancel := context.WithTimeout(ctx, discoveryTimeout)
+	peer, err := app.FindReceiver(findCtx, target)
+	cancel()
+	if err != nil {
+		return err
+	}
+
+	files, err := transfer.PrepareNodes(ctx, paths, r.cfg.HashWorkerCount(), nil)
+	if err != nil {
+		return fmt.Errorf("%w: %w", errLocalFiles, err)
+	}
+	fmt.Fprintf(os.Stderr, "Forwarding %d files to %s (%s:%d)\n", len(files), peer.Name, peer.Addr, peer.Port)
+	err = app.SendHeadless(ctx, peer, files, func(msg tea.Msg) {
+		if m, ok := msg.(sender.StatusUpdateMsg); ok {
+			fmt.Fprintln(os.Stderr, m.Message)
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "Forwarded to %s\n", peer.Name)
+	for _, path := range paths {
+		if err := os.RemoveAll(path); err != nil {
+			slog.Warn("Failed to remove forwarded file from the spool", "path", path, "error", err)
+		}
+	}
+	return nil
+}
+
+// discardSpool removes what a failed transfer left in the spool, so it is not forwarded with the next one
+func (r *relay) discardSpool() {
+	paths, err := spooledPaths(r.spool)
+	if err != nil {
+		slog.Warn("Failed to list the spool", "error", err)
+		return
+	}
+	for _, path := range paths {
+		if err := os.RemoveAll(path); err != nil {
+			slog.Warn("Failed to remove file from the spool", "path", path, "error", err)
+		}
+	}
+}
+
+// setAside moves everything in the spool to a new directory under relayUndelivered and returns it
+func (r *relay) setAside() (string, error) {
+	paths, err := spooledPaths(r.spool)
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(r.spool, relayUndelivered, time.Now().Format("20060102-150405"))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	for _, path := range paths {
+		if err := os.Rename(path, filepath.Join(dir, filepath.Base(path))); err != nil {
+			return "", err
+		}
+	}
+	return dir, nil
+}
+
+// spooledPaths lists the received files and directories in the spool, leaving out the
+// hidden ones that hold resume state and undelivered transfers
+func spooledPaths(spool string) ([]string, error) {
+	entries, err := os.ReadDir(spool)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read spool directory: %w", err)
+	}
+	var paths []string
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		paths = append(paths, filepath.Join(spool, entry.Name()))
+	}
+	return paths, nil
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/spf13/cobra"
+
+	"github.com/rescp17/lanFileSharer/pkg/events"
+	"github.com/rescp17/lanFileSharer/pkg/ui"
+)
+
+// newReplayCmd creates the command for playing back a recorded progress stream
+func newReplayCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "replay <file>",
+		Short: "Play back a recorded transfer in the TUI",
+		Long: "Play back the progress stream of a transfer, as written by `send --to ... --progress-json > file`, " +
+			"to see a reported problem as the user saw it without their network. " +
+			"space plays and pauses, left and right seek, home and end jump to either end and + and - change the speed.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			file, err := os.Open(args[0])
+			if err != nil {
+				return fmt.Errorf("failed to open recording: %w", err)
+			}
+			records, err := events.ReadProgressRecords(file)
+			file.Close()
+			if err != nil {
+				return err
+			}
+			if len(records) == 0 {
+				return fmt.Errorf("%s has no progress records", args[0])
+			}
+			_, err = tea.NewProgram(ui.NewReplayModel(filepath.Base(args[0]), records)).Run()
+			return err
+		},
+	}
+}
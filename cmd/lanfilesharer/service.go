@@ -0,0 +1,312 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// serviceLabel names the installed receive service on every platform
+const serviceLabel = "lanfilesharer"
+
+// serviceLogFile is the file the service writes its output to, in the log directory
+const serviceLogFile = "receive.log"
+
+// serviceSpec is what the installed service runs
+type serviceSpec struct {
+	Args   []string // Executable and arguments
+	LogDir string   // Working directory of the service, where its logs and debug.log go
+}
+
+// serviceUnit is the file that registers the service with the platform, and the commands
+// that start and stop it
+type serviceUnit struct {
+	Path    string
+	Content string
+	Enable  [][]string
+	Disable [][]string
+}
+
+// newServiceCmd creates the command that installs the headless receiver as a service
+func newServiceCmd() *cobra.Command {
+	serviceCmd := &cobra.Command{
+		Use:   "service",
+		Short: "Run the receiver in the background when you log in",
+		Long: "Install `receive --headless` as a systemd user unit on Linux, a launchd agent on macOS " +
+			"or a scheduled task with a wrapper script on Windows. The --port, --output and --config " +
+			"flags given to install are passed on to the service.",
+	}
+
+	installCmd := &cobra.Command{
+		Use:   "install",
+		Short: "Install and start the receive service",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			spec, err := newServiceSpec(cmd)
+			if err != nil {
+				return err
+			}
+			unit, err := newServiceUnit(runtime.GOOS, spec)
+			if err != nil {
+				return err
+			}
+			if dryRun, _ := cmd.Flags().GetBool("print"); dryRun {
+				fmt.Fprintf(cmd.OutOrStdout(), "# %s\n%s\n", unit.Path, unit.Content)
+				for _, command := range unit.Enable {
+					fmt.Fprintf(cmd.OutOrStdout(), "# %s\n", strings.Join(command, " "))
+				}
+				return nil
+			}
+
+			if err := os.MkdirAll(spec.LogDir, 0755); err != nil {
+				return fmt.Errorf("failed to create log directory: %w", err)
+			}
+			if err := os.MkdirAll(filepath.Dir(unit.Path), 0755); err != nil {
+				return fmt.Errorf("failed to create service directory: %w", err)
+			}
+			if err := os.WriteFile(unit.Path, []byte(unit.Content), 0644); err != nil {
+				return fmt.Errorf("failed to write service file: %w", err)
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Wrote %s\n", unit.Path)
+			if err := runServiceCommands(unit.Enable); err != nil {
+				return fmt.Errorf("service file written, but starting it failed: %w", err)
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Service %s started, logs are in %s\n",
+				serviceLabel, filepath.Join(spec.LogDir, serviceLogFile))
+			return nil
+		},
+	}
+	installCmd.Flags().Bool("print", false, "Print the service file and commands instead of installing them")
+
+	uninstallCmd := &cobra.Command{
+		Use:   "uninstall",
+		Short: "Stop and remove the receive service",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			unit, err := newServiceUnit(runtime.GOOS, serviceSpec{})
+			if err != nil {
+				return err
+			}
+			if err := runServiceCommands(unit.Disable); err != nil {
+				// The service may not be running, removing its file is what matters
+				fmt.Fprintf(cmd.ErrOrStderr(), "Stopping the service failed: %v\n", err)
+			}
+			if err := os.Remove(unit.Path); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf("failed to remove service file: %w", err)
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Removed %s\n", unit.Path)
+			return nil
+		},
+	}
+
+	serviceCmd.AddCommand(installCmd, uninstallCmd)
+	return serviceCmd
+}
+
+// newServiceSpec builds the command line of the service from this executable and the flags of cmd.
+// Paths are made absolute, since services do not start in the current directory.
+func newServiceSpec(cmd *cobra.Command) (serviceSpec, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return serviceSpec{}, fmt.Errorf("failed to find the lanfilesharer executable: %w", err)
+	}
+	if resolved, err := filepath.EvalSymlinks(executable); err == nil {
+		executable = resolved
+	}
+
+	port, _ := cmd.Flags().GetInt("port")
+	cfg, err := loadConfig(cmd)
+	if err != nil {
+		return serviceSpec{}, fmt.Errorf("failed to load config: %w", err)
+	}
+	outputDir, err := resolveOutputDir(cmd, cfg)
+	if err != nil {
+		return serviceSpec{}, err
+	}
+	outputDir, err = filepath.Abs(outputDir)
+	if err != nil {
+		return serviceSpec{}, fmt.Errorf("failed to resolve output directory: %w", err)
+	}
+	args := []string{executable, "receive", "--headless", "--port", strconv.Itoa(port), "--output", outputDir}
+	if configPath, _ := cmd.Flags().GetString("config"); configPath != "" {
+		configPath, err = filepath.Abs(configPath)
+		if err != nil {
+			return serviceSpec{}, fmt.Errorf("failed to resolve config path: %w", err)
+		}
+		args = append(args, "--config", configPath)
+	}
+
+	logDir, err := serviceLogDir(runtime.GOOS)
+	if err != nil {
+		return serviceSpec{}, err
+	}
+	return serviceSpec{Args: args, LogDir: logDir}, nil
+}
+
+// serviceLogDir returns where the service of goos keeps its logs, following the platform conventions
+func serviceLogDir(goos string) (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find home directory: %w", err)
+	}
+	switch goos {
+	case "darwin":
+		return filepath.Join(home, "Library", "Logs", serviceLabel), nil
+	case "windows":
+		if dir := os.Getenv("LOCALAPPDATA"); dir != "" {
+			return filepath.Join(dir, serviceLabel, "logs"), nil
+		}
+		return filepath.Join(home, "AppData", "Local", serviceLabel, "logs"), nil
+	default:
+		if dir := os.Getenv("XDG_STATE_HOME"); dir != "" {
+			return filepath.Join(dir, serviceLabel), nil
+		}
+		return filepath.Join(home, ".local", "state", serviceLabel), nil
+	}
+}
+
+// newServiceUnit returns the service file of goos for spec and where it is installed
+func newServiceUnit(goos string, spec serviceSpec) (serviceUnit, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return serviceUnit{}, fmt.Errorf("failed to find home directory: %w", err)
+	}
+	logPath := filepath.Join(spec.LogDir, serviceLogFile)
+
+	switch goos {
+	case "linux":
+		configDir := os.Getenv("XDG_CONFIG_HOME")
+		if configDir == "" {
+			configDir = filepath.Join(home, ".config")
+		}
+		unitName := serviceLabel + ".service"
+		return serviceUnit{
+			Path:    filepath.Join(configDir, "systemd", "user", unitName),
+			Content: systemdUnit(spec, logPath),
+			Enable: [][]string{
+				{"systemctl", "--user", "daemon-reload"},
+				{"systemctl", "--user", "enable", "--now", unitName},
+			},
+			Disable: [][]string{
+				{"systemctl", "--user", "disable", "--now", unitName},
+				{"systemctl", "--user", "daemon-reload"},
+			},
+		}, nil
+	case "darwin":
+		path := filepath.Join(home, "Library", "LaunchAgents", "com."+serviceLabel+".receiver.plist")
+		return serviceUnit{
+			Path:    path,
+			Content: launchdPlist(spec, logPath),
+			Enable:  [][]string{{"launchctl", "load", "-w", path}},
+			Disable: [][]string{{"launchctl", "unload", "-w", path}},
+		}, nil
+	case "windows":
+		dir := os.Getenv("LOCALAPPDATA")
+		if dir == "" {
+			dir = filepath.Join(home, "AppData", "Local")
+		}
+		path := filepath.Join(dir, serviceLabel, "receive-service.cmd")
+		return serviceUnit{
+			Path:    path,
+			Content: windowsWrapper(spec, logPath),
+			Enable: [][]string{
+				{"schtasks", "/Create", "/F", "/SC", "ONLOGON", "/RL", "LIMITED", "/TN", serviceLabel, "/TR", `"` + path + `"`},
+				{"schtasks", "/Run", "/TN", serviceLabel},
+			},
+			Disable: [][]string{
+				{"schtasks", "/End", "/TN", serviceLabel},
+				{"schtasks", "/Delete", "/F", "/TN", serviceLabel},
+			},
+		}, nil
+	}
+	return serviceUnit{}, fmt.Errorf("installing a service is not supported on %s", goos)
+}
+
+// systemdUnit returns a systemd user unit that restarts the receiver when it fails
+func systemdUnit(spec serviceSpec, logPath string) string {
+	// systemd expands specifiers starting with %
+	escape := strings.NewReplacer("%", "%%").Replace
+	quoted := make([]string, len(spec.Args))
+	for i, arg := range spec.Args {
+		quoted[i] = escape(strconv.Quote(arg))
+	}
+	return fmt.Sprintf(`[Unit]
+Description=lanFileSharer receiver
+Wants=network-online.target
+After=network-online.target
+
+[Service]
+ExecStart=%s
+WorkingDirectory=%s
+Restart=on-failure
+RestartSec=5
+StandardOutput=append:%s
+StandardError=append:%s
+
+[Install]
+WantedBy=default.target
+`, strings.Join(quoted, " "), escape(spec.LogDir), escape(logPath), escape(logPath))
+}
+
+// launchdPlist returns a launchd agent that starts the receiver at login and restarts it when it fails
+func launchdPlist(spec serviceSpec, logPath string) string {
+	var args strings.Builder
+	for _, arg := range spec.Args {
+		fmt.Fprintf(&args, "\t\t<string>%s</string>\n", html.EscapeString(arg))
+	}
+	return fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>Label</key>
+	<string>com.%s.receiver</string>
+	<key>ProgramArguments</key>
+	<array>
+%s	</array>
+	<key>WorkingDirectory</key>
+	<string>%s</string>
+	<key>StandardOutPath</key>
+	<string>%s</string>
+	<key>StandardErrorPath</key>
+	<string>%s</string>
+	<key>RunAtLoad</key>
+	<true/>
+	<key>KeepAlive</key>
+	<dict>
+		<key>SuccessfulExit</key>
+		<false/>
+	</dict>
+</dict>
+</plist>
+`, serviceLabel, args.String(), html.EscapeString(spec.LogDir), html.EscapeString(logPath), html.EscapeString(logPath))
+}
+
+// windowsWrapper returns the script the scheduled task runs, which sends the output of the
+// receiver to the log file
+func windowsWrapper(spec serviceSpec, logPath string) string {
+	// Batch files expand variables written as %name%
+	escape := strings.NewReplacer("%", "%%").Replace
+	quoted := make([]string, len(spec.Args))
+	for i, arg := range spec.Args {
+		quoted[i] = `"` + escape(arg) + `"`
+	}
+	return fmt.Sprintf("@echo off\r\ncd /d \"%s\"\r\n%s >> \"%s\" 2>&1\r\n",
+		escape(spec.LogDir), strings.Join(quoted, " "), escape(logPath))
+}
+
+// runServiceCommands runs commands in order, stopping at the first that fails
+func runServiceCommands(commands [][]string) error {
+	for _, command := range commands {
+		out, err := exec.Command(command[0], command[1:]...).CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("%s: %w: %s", strings.Join(command, " "), err, strings.TrimSpace(string(out)))
+		}
+	}
+	return nil
+}
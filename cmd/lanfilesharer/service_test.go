@@ -0,0 +1,108 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewServiceUnit(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv("LOCALAPPDATA", "")
+
+	unix := serviceSpec{
+		Args:   []string{"/opt/My Apps/lanfilesharer", "receive", "--output", `/srv/Shared "Files" 100%`},
+		LogDir: "/var/log/lan sharer 50%",
+	}
+	windows := serviceSpec{
+		Args:   []string{`C:\Program Files\lanfilesharer.exe`, "receive", "--output", `D:\Shared 100%`},
+		LogDir: `C:\Logs\lan sharer`,
+	}
+
+	tests := []struct {
+		name     string
+		goos     string
+		spec     serviceSpec
+		path     string
+		contains []string
+		enable   []string
+	}{
+		{
+			name: "systemd",
+			goos: "linux",
+			spec: unix,
+			path: filepath.Join(home, ".config", "systemd", "user", "lanfilesharer.service"),
+			contains: []string{
+				`ExecStart="/opt/My Apps/lanfilesharer" "receive" "--output" "/srv/Shared \"Files\" 100%%"` + "\n",
+				"WorkingDirectory=/var/log/lan sharer 50%%\n",
+				"StandardOutput=append:/var/log/lan sharer 50%%/receive.log\n",
+				"Restart=on-failure\n",
+				"WantedBy=default.target\n",
+			},
+			enable: []string{"systemctl", "--user", "enable", "--now", "lanfilesharer.service"},
+		},
+		{
+			name: "launchd",
+			goos: "darwin",
+			spec: unix,
+			path: filepath.Join(home, "Library", "LaunchAgents", "com.lanfilesharer.receiver.plist"),
+			contains: []string{
+				"<string>com.lanfilesharer.receiver</string>",
+				"\t\t<string>/opt/My Apps/lanfilesharer</string>\n\t\t<string>receive</string>\n",
+				"<string>/srv/Shared &#34;Files&#34; 100%</string>",
+				"<key>WorkingDirectory</key>\n\t<string>/var/log/lan sharer 50%</string>",
+				"<key>StandardErrorPath</key>\n\t<string>/var/log/lan sharer 50%/receive.log</string>",
+			},
+			enable: []string{"launchctl", "load", "-w", filepath.Join(home, "Library", "LaunchAgents", "com.lanfilesharer.receiver.plist")},
+		},
+		{
+			name: "Windows",
+			goos: "windows",
+			spec: windows,
+			path: filepath.Join(home, "AppData", "Local", "lanfilesharer", "receive-service.cmd"),
+			contains: []string{
+				"@echo off\r\n",
+				`cd /d "C:\Logs\lan sharer"` + "\r\n",
+				`"C:\Program Files\lanfilesharer.exe" "receive" "--output" "D:\Shared 100%%" >> "` +
+					filepath.Join(`C:\Logs\lan sharer`, "receive.log") + `" 2>&1` + "\r\n",
+			},
+			enable: []string{"schtasks", "/Create", "/F", "/SC", "ONLOGON", "/RL", "LIMITED", "/TN", "lanfilesharer",
+				"/TR", `"` + filepath.Join(home, "AppData", "Local", "lanfilesharer", "receive-service.cmd") + `"`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			unit, err := newServiceUnit(tt.goos, tt.spec)
+			require.NoError(t, err)
+			assert.Equal(t, tt.path, unit.Path)
+			for _, want := range tt.contains {
+				assert.Contains(t, unit.Content, want)
+			}
+			require.NotEmpty(t, unit.Enable)
+			assert.Contains(t, unit.Enable, tt.enable)
+			assert.NotEmpty(t, unit.Disable)
+		})
+	}
+
+	_, err := newServiceUnit("plan9", unix)
+	assert.Error(t, err, "other platforms have no service")
+}
+
+func TestNewServiceUnit_ConfigDirs(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", "/etc/xdg home")
+	t.Setenv("LOCALAPPDATA", `C:\Users\me\AppData\Local`)
+
+	unit, err := newServiceUnit("linux", serviceSpec{})
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join("/etc/xdg home", "systemd", "user", "lanfilesharer.service"), unit.Path)
+
+	unit, err = newServiceUnit("windows", serviceSpec{})
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(`C:\Users\me\AppData\Local`, "lanfilesharer", "receive-service.cmd"), unit.Path)
+}
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/spf13/cobra"
+
+	"github.com/rescp17/lanFileSharer/api"
+	appevents "github.com/rescp17/lanFileSharer/internal/app_events"
+	"github.com/rescp17/lanFileSharer/internal/app_events/receiver"
+	"github.com/rescp17/lanFileSharer/internal/app_events/sender"
+	"github.com/rescp17/lanFileSharer/internal/config"
+	"github.com/rescp17/lanFileSharer/internal/util"
+	"github.com/rescp17/lanFileSharer/pkg/discovery"
+	"github.com/rescp17/lanFileSharer/pkg/peers"
+	receiverApp "github.com/rescp17/lanFileSharer/pkg/receiver"
+	senderApp "github.com/rescp17/lanFileSharer/pkg/sender"
+	"github.com/rescp17/lanFileSharer/pkg/share"
+	"github.com/rescp17/lanFileSharer/pkg/transfer"
+	"github.com/rescp17/lanFileSharer/pkg/webhook"
+)
+
+// shareOnlyMessage is sent with requests to send files to a device that only shares
+const shareOnlyMessage = "This device only shares files, browse it to pull them instead"
+
+// newShareCmd creates the command that publishes a directory for other devices to pull from
+func newShareCmd() *cobra.Command {
+	shareCmd := &cobra.Command{
+		Use:   "share <directory>",
+		Short: "Share a directory read-only for other devices to browse and pull from",
+		Long: "Publish a directory in pull mode: receivers on the network list it with `b` in their TUI, " +
+			"preview the start of its files, pick files and directories out of it and have them sent to them. " +
+			"Only the picked files are sent, " +
+			"one pull at a time; pulls arriving meanwhile are refused until the current one is sent.\n\n" +
+			"Hidden files and symbolic links are not shared. Requests to send files to this device are declined.\n\n" +
+			"Devices without lanFileSharer download the share, or a directory of it with ?path=<dir>, as a zip " +
+			"archive with a browser at the printed /share/zip URLs.\n\n" +
+			"With --export-offer, an offer of some files of the share, all of them by default, is written to a small " +
+			share.OfferExtension + " file signed with the device key. Moved to a receiver by USB stick or email, " +
+			"`pull <file>` there asks this device for the offered files while it keeps sharing.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadConfig(cmd)
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+			shared, err := share.New(args[0])
+			if err != nil {
+				return err
+			}
+			return runShare(cmd, cfg, shared)
+		},
+	}
+	shareCmd.Flags().Duration("timeout", 10*time.Minute, "Maximum duration of sending a pull")
+	shareCmd.Flags().String("export-offer", "", "Write an offer file of the share to this path for receivers to pull with `pull`")
+	shareCmd.Flags().StringArray("offer-path", nil, "Path relative to the share to offer, repeatable (defaults to everything shared)")
+	shareCmd.Flags().Duration("offer-lifetime", 7*24*time.Hour, "How long the exported offer can be pulled")
+	return shareCmd
+}
+
+func runShare(cmd *cobra.Command, cfg config.Config, shared *share.Share) error {
+	port, _ := cmd.Flags().GetInt("port")
+	timeout, _ := cmd.Flags().GetDuration("timeout")
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	serviceName, err := receiverApp.NewServiceName()
+	if err != nil {
+		return fmt.Errorf("could not create service name: %w", err)
+	}
+	var offer *share.Offer
+	if file, _ := cmd.Flags().GetString("export-offer"); file != "" {
+		if offer, err = exportOffer(ctx, cmd, cfg, shared, serviceName, port, file); err != nil {
+			return err
+		}
+	}
+
+	pulls := make(chan api.PullRequest, 1)
+	app := receiverApp.NewAppWithOptions(port, os.TempDir(), receiverApp.Options{
+		Registrar:           &discovery.MDNSAdapter{},
+		ServiceName:         serviceName,
+		DoNotDisturbMessage: shareOnlyMessage,
+		Scopes:              receiverApp.ServiceScopes(cfg),
+		PeerFilter:          receiverApp.PeerFilter(cfg),
+		AuditLog:            receiverApp.AuditLog(cfg),
+		LANOnly:             cfg.LANOnly,
+		PSK:                 receiverApp.PSK(cfg),
+		Fingerprint:         senderApp.DeviceFingerprint(cfg),
+		Icon:                cfg.Icon,
+		Share:               shared,
+		Pull: func(req api.PullRequest) error {
+			if req.Offer != "" && (offer == nil || req.Offer != offer.Token || offer.Expired(time.Now())) {
+				return api.ErrUnknownOffer
+			}
+			select {
+			case pulls <- req:
+				return nil
+			default:
+				return api.ErrShareBusy
+			}
+		},
+	})
+
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg := <-app.UIMessages():
+				switch m := msg.(type) {
+				case receiver.RequestDeclinedMsg:
+					fmt.Fprintln(os.Stderr, "Declined a request to send files here, this device only shares")
+				case appevents.Error:
+					fmt.Fprintf(os.Stderr, "Error: %v\n", m.Err)
+				}
+			}
+		}
+	}()
+	go func() {
+		// Requests are declined, the share is only browsed and pulled from
+		select {
+		case app.AppEvents() <- receiver.SetAvailability{Availability: receiver.DoNotDisturb}:
+		case <-ctx.Done():
+		}
+	}()
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case req := <-pulls:
+				if req.Offer != "" {
+					fmt.Fprintf(os.Stderr, "%s imported the offer file\n", req.ReceiverName)
+				}
+				if err := sendPull(ctx, cfg, serviceName, timeout, req); err != nil {
+					fmt.Fprintf(os.Stderr, "Could not send pull of %s: %v\n", req.ReceiverName, err)
+				}
+			}
+		}
+	}()
+
+	fmt.Fprintf(os.Stderr, "Sharing %s on port %d\n", shared.Root(), port)
+	if cfg.PSKPassphrase == "" {
+		// Browsers cannot sign their requests with the air-gap passphrase
+		for _, ip := range util.LANAddresses() {
+			fmt.Fprintf(os.Stderr, "Browsers download it as a zip at http://%s%s\n",
+				net.JoinHostPort(ip.String(), strconv.Itoa(port)), api.ZipPath)
+		}
+	}
+	return app.Run(ctx)
+}
+
+// exportOffer writes an offer file of the paths of shared given by --offer-path, every entry of
+// the share by default, to file. The files are signed with the device key, which sendPull signs with too.
+func exportOffer(ctx context.Context, cmd *cobra.Command, cfg config.Config, shared *share.Share, serviceName string, port int, file string) (*share.Offer, error) {
+	paths, _ := cmd.Flags().GetStringArray("offer-path")
+	lifetime, _ := cmd.Flags().GetDuration("offer-lifetime")
+	if len(paths) == 0 {
+		entries, err := shared.List("")
+		if err != nil {
+			return nil, fmt.Errorf("failed to list share: %w", err)
+		}
+		for _, entry := range entries {
+			paths = append(paths, entry.Path)
+		}
+	}
+	if len(paths) == 0 {
+		return nil, errors.New("nothing to offer, the share is empty")
+	}
+
+	locals := make([]string, 0, len(paths))
+	for i, path := range paths {
+		paths[i] = strings.Trim(filepath.ToSlash(path), "/")
+		local, err := shared.Resolve(paths[i])
+		if err != nil || local == shared.Root() {
+			return nil, fmt.Errorf("cannot offer %q, it is not in the share", path)
+		}
+		locals = append(locals, local)
+	}
+	nodes, err := transfer.PrepareNodes(ctx, locals, cfg.HashWorkerCount(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errLocalFiles, err)
+	}
+	structure := transfer.NewFileStructureManager()
+	for i := range nodes {
+		if err := structure.AddFileNode(&nodes[i]); err != nil {
+			return nil, fmt.Errorf("failed to add file %s: %w", nodes[i].Path, err)
+		}
+	}
+	signer, err := senderApp.DeviceSigner(cfg)
+	if err != nil {
+		return nil, err
+	}
+	signed, err := signer.SignFileStructureManagerContext(ctx, structure)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign offered files: %w", err)
+	}
+
+	var addrs []string
+	for _, ip := range util.LANAddresses() {
+		addrs = append(addrs, ip.String())
+	}
+	if len(addrs) == 0 {
+		return nil, errors.New("this device has no LAN address receivers could pull the offer from")
+	}
+	offer := share.NewOffer(serviceName, addrs, port, paths, signed, lifetime)
+
+	if filepath.Ext(file) == "" {
+		file += share.OfferExtension
+	}
+	out, err := os.OpenFile(file, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create offer file: %w", err)
+	}
+	defer out.Close()
+	if err := offer.Write(out); err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(os.Stderr, "Exported an offer of %d files (%s) to %s, valid until %s while this share runs\n",
+		len(signed.Files), util.FormatSize(offer.TotalSize()), file, offer.Expires.Format(time.DateTime))
+	return offer, nil
+}
+
+// sendPull sends the files of a pull to the receiver that asked for them
+func sendPull(ctx context.Context, cfg config.Config, serviceName string, timeout time.Duration, req api.PullRequest) error {
+	files, err := transfer.PrepareNodes(ctx, req.Paths, cfg.HashWorkerCount(), nil)
+	if err != nil {
+		return fmt.Errorf("%w: %w", errLocalFiles, err)
+	}
+
+	app := senderApp.NewAppWithOptions(&discovery.MDNSAdapter{}, senderApp.Options{
+		TransferTimeout:    timeout,
+		DeviceKeyPath:      senderApp.DeviceKeyPath(),
+		KeyLifetime:        cfg.KeyLifetime(),
+		KeyGrace:           cfg.KeyGrace(),
+		SignatureAlgorithm: senderApp.SignatureAlgorithm(cfg),
+		RetryPolicy:        senderApp.RetryPolicy(cfg),
+		ChunkSize:          cfg.ChunkSize(),
+		IdleTimeout:        cfg.SessionIdleTimeout(),
+		Bonding:            cfg.ExperimentalBonding,
+		PeerSettings:       peers.LoadDefault(),
+		Webhooks:           webhook.FromConfig(cfg),
+		BandwidthSchedule:  senderApp.BandwidthSchedule(cfg),
+		Battery:            senderApp.Battery(cfg),
+		IgnoreService:      serviceName,
+		Scope:              senderApp.ServiceScope(cfg),
+		LANOnly:            cfg.LANOnly,
+		PSK:                receiverApp.PSK(cfg),
+		PullToken:          req.Token,
+		Icon:               cfg.Icon,
+	})
+
+	peer := discovery.ServiceInfo{Name: req.ReceiverName, Addr: net.ParseIP(req.Host), Port: req.Port}
+	fmt.Fprintf(os.Stderr, "Sending %d files pulled by %s (%s:%d)\n", len(files), peer.Name, peer.Addr, peer.Port)
+	err = app.SendHeadless(ctx, peer, files, func(msg tea.Msg) {
+		if m, ok := msg.(sender.StatusUpdateMsg); ok {
+			fmt.Fprintln(os.Stderr, m.Message)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Sent to %s\n", peer.Name)
+	return nil
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/rescp17/lanFileSharer/internal/config"
+	"github.com/rescp17/lanFileSharer/pkg/ui/components"
+)
+
+// newThemeCmd creates the command for sharing and installing TUI themes
+func newThemeCmd() *cobra.Command {
+	themeCmd := &cobra.Command{
+		Use:   "theme",
+		Short: "List, export and import TUI themes",
+		Long: "Custom themes are JSON files in the themes directory of the config directory. " +
+			"Export a theme to start a new one, and import it when it is done; " +
+			"a running TUI picks up changes to the theme files without a restart.",
+	}
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the available themes",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			tm, err := newCLIThemeManager()
+			if err != nil {
+				return err
+			}
+			for _, name := range tm.ThemeNames() {
+				kind := "custom"
+				if tm.IsBuiltinTheme(name) {
+					kind = "built-in"
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "%-20s %-9s %s\n", name, kind, tm.GetAvailableThemes()[name].Description)
+			}
+			return nil
+		},
+	}
+
+	exportCmd := &cobra.Command{
+		Use:   "export <name> [file]",
+		Short: "Write a theme as JSON to a file, or to stdout",
+		Args:  cobra.RangeArgs(1, 2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			tm, err := newCLIThemeManager()
+			if err != nil {
+				return err
+			}
+			out := cmd.OutOrStdout()
+			if len(args) == 2 {
+				file, err := os.Create(args[1])
+				if err != nil {
+					return fmt.Errorf("failed to create export: %w", err)
+				}
+				defer file.Close()
+				out = file
+			}
+			return tm.ExportTheme(args[0], out)
+		},
+	}
+
+	importCmd := &cobra.Command{
+		Use:   "import <file>",
+		Short: "Check a theme file and install it in the themes directory",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			tm, err := newCLIThemeManager()
+			if err != nil {
+				return err
+			}
+			theme, err := tm.ImportTheme(args[0])
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Imported theme %q into %s, choose it with T in the TUI\n", theme.Name, tm.ThemesDir())
+			return nil
+		},
+	}
+
+	themeCmd.AddCommand(listCmd, exportCmd, importCmd)
+	return themeCmd
+}
+
+// newCLIThemeManager returns a theme manager with the custom themes of the config directory
+func newCLIThemeManager() (*components.ThemeManager, error) {
+	dir, err := config.Dir()
+	if err != nil {
+		return nil, err
+	}
+	return components.NewThemeManager(dir), nil
+}
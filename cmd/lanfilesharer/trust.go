@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/rescp17/lanFileSharer/pkg/crypto"
+	receiverApp "github.com/rescp17/lanFileSharer/pkg/receiver"
+)
+
+// newTrustCmd creates the command for provisioning receivers with trusted sender keys
+func newTrustCmd() *cobra.Command {
+	trustCmd := &cobra.Command{
+		Use:   "trust",
+		Short: "Export and import the trusted sender keys",
+		Long: "Provision receivers with the sender keys they trust. export writes the trust store signed with " +
+			"this device's key, and import installs such an export on another receiver after checking the " +
+			"signature. Set trust_read_only in the config of provisioned receivers so accepting a request " +
+			"does not trust keys beyond those imported.",
+	}
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the trusted sender keys",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store, err := loadTrustStore(cmd)
+			if err != nil {
+				return err
+			}
+			entries := store.Entries()
+			if len(entries) == 0 {
+				fmt.Fprintln(cmd.OutOrStdout(), "No trusted sender keys")
+				return nil
+			}
+			now := time.Now()
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "FINGERPRINT\tLABEL\tVERIFIED\tSTATUS")
+			for _, entry := range entries {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", entry.Fingerprint, orDash(entry.Label),
+					entry.VerifiedAt.Format("2006-01-02"), store.Status(entry.Fingerprint, now))
+			}
+			return w.Flush()
+		},
+	}
+
+	exportCmd := &cobra.Command{
+		Use:   "export <file>",
+		Short: "Write the trusted sender keys to a file signed with the device key",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store, err := loadTrustStore(cmd)
+			if err != nil {
+				return err
+			}
+			keys, err := deviceKeyStore(cmd)
+			if err != nil {
+				return err
+			}
+			key, err := keys.Current()
+			if err != nil {
+				return fmt.Errorf("failed to load device key: %w", err)
+			}
+			fingerprint, err := key.Fingerprint()
+			if err != nil {
+				return err
+			}
+
+			file, err := os.OpenFile(args[0], os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+			if err != nil {
+				return fmt.Errorf("failed to create export: %w", err)
+			}
+			defer file.Close()
+			if err := store.Export(file, key); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Exported %d keys to %s, signed by %s\n", len(store.Entries()), args[0], fingerprint)
+			return nil
+		},
+	}
+
+	importCmd := &cobra.Command{
+		Use:   "import <file>",
+		Short: "Trust the sender keys of an export signed by a given device",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runTrustImport(cmd, args[0])
+		},
+	}
+	importCmd.Flags().StringSlice("signer", nil, "Fingerprint of a device key allowed to sign the export, as shown by keys; required")
+	importCmd.Flags().Bool("replace", false, "Replace every trusted key with those of the export instead of adding them")
+
+	trustCmd.AddCommand(listCmd, exportCmd, importCmd)
+	return trustCmd
+}
+
+func runTrustImport(cmd *cobra.Command, path string) error {
+	signers, _ := cmd.Flags().GetStringSlice("signer")
+	replace, _ := cmd.Flags().GetBool("replace")
+	if len(signers) == 0 {
+		// Anyone can sign an export, only a known signer makes it worth trusting
+		return fmt.Errorf("--signer is required: pass the fingerprint of the device that exported the keys")
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to read export: %w", err)
+	}
+	defer file.Close()
+	export, signer, err := crypto.ReadTrustExport(file)
+	if err != nil {
+		return err
+	}
+	if !slices.ContainsFunc(signers, func(s string) bool { return strings.EqualFold(strings.TrimSpace(s), signer) }) {
+		return fmt.Errorf("the export is signed by %s, which is not one of the given signers", signer)
+	}
+
+	store, err := loadTrustStore(cmd)
+	if err != nil {
+		return err
+	}
+	changed := store.Import(export.Entries, replace)
+	if err := store.Save(); err != nil {
+		return err
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "Imported %d of %d keys from %s (exported %s by %s)\n",
+		changed, len(export.Entries), orDash(export.Hostname), export.Created.Format("2006-01-02"), signer)
+	return nil
+}
+
+// loadTrustStore opens the trust store in the config directory
+func loadTrustStore(cmd *cobra.Command) (*crypto.TrustStore, error) {
+	cfg, err := loadConfig(cmd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	path := receiverApp.TrustStorePath()
+	if path == "" {
+		return nil, fmt.Errorf("could not resolve the trust store location")
+	}
+	return crypto.LoadTrustStore(path, cfg.TrustMaxAge())
+}
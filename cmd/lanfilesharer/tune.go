@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/rescp17/lanFileSharer/internal/config"
+	"github.com/rescp17/lanFileSharer/internal/util"
+	"github.com/rescp17/lanFileSharer/pkg/discovery"
+	senderApp "github.com/rescp17/lanFileSharer/pkg/sender"
+	"github.com/rescp17/lanFileSharer/pkg/transfer"
+	webrtcPkg "github.com/rescp17/lanFileSharer/pkg/webrtc"
+)
+
+// tuneTimeout bounds setting up the echo connection and measuring every chunk size
+const tuneTimeout = 5 * time.Minute
+
+// newTuneCmd creates the command that measures which chunk size suits the link to a peer
+func newTuneCmd() *cobra.Command {
+	tuneCmd := &cobra.Command{
+		Use:   "tune <peer>",
+		Short: "Measure chunk sizes against a receiver and recommend the fastest",
+		Long: "Open a data channel to the receiver as ping does, probe the largest message it carries and " +
+			"echo data in chunk messages of several sizes to compare their throughput. Nothing is shown to the " +
+			"receiver's user. With --apply the recommended size is saved as chunk_size_kb in the config file.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runTune(cmd, args[0])
+		},
+	}
+	tuneCmd.Flags().IntSlice("sizes", nil, "Chunk sizes to measure in KiB (default 16,32,64,128,256)")
+	tuneCmd.Flags().Int("mb", 4, "MiB of data echoed per chunk size")
+	tuneCmd.Flags().Bool("apply", false, "Save the recommended chunk size to the config file")
+	return tuneCmd
+}
+
+func runTune(cmd *cobra.Command, peer string) error {
+	sizes, _ := cmd.Flags().GetIntSlice("sizes")
+	mb, _ := cmd.Flags().GetInt("mb")
+	apply, _ := cmd.Flags().GetBool("apply")
+	out := cmd.OutOrStdout()
+
+	options := webrtcPkg.TuneOptions{Bytes: int64(mb) << 20}
+	for _, kb := range sizes {
+		options.ChunkSizes = append(options.ChunkSizes, int32(kb)<<10)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	cfg, err := loadConfig(cmd)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	app := senderApp.NewAppWithOptions(&discovery.MDNSAdapter{}, senderApp.Options{Scope: senderApp.ServiceScope(cfg)})
+
+	fmt.Fprintf(out, "Resolving %q...\n", peer)
+	findCtx, cancel := context.WithTimeout(ctx, discoveryTimeout)
+	receiver, err := app.FindReceiver(findCtx, peer)
+	cancel()
+	if err != nil {
+		return fmt.Errorf("discovery: %w", err)
+	}
+	pingCtx, cancel := context.WithTimeout(ctx, discoveryTimeout)
+	ping, _, err := app.PingReceiver(pingCtx, receiver)
+	cancel()
+	if err != nil {
+		return fmt.Errorf("signaling: %w", err)
+	}
+	if !ping.Echo {
+		return errors.New("the receiver does not support data channel echoes, which tuning needs")
+	}
+
+	fmt.Fprintf(out, "Measuring %s at %s:%d...\n", receiver.Name, receiver.Addr, receiver.Port)
+	tuneCtx, cancel := context.WithTimeout(ctx, tuneTimeout)
+	defer cancel()
+	result, err := app.TuneReceiver(tuneCtx, receiver, options)
+	if err != nil {
+		return fmt.Errorf("data channel: %w", err)
+	}
+	path := result.Path
+	if path == "" {
+		path = "unknown path"
+	}
+	fmt.Fprintf(out, "Data channel: open in %s, %s (%s)\n", formatLatency(result.SetupTime), result.PathType, path)
+	fmt.Fprintf(out, "Largest message: %s\n", util.FormatSize(int64(result.MaxMessageSize)))
+	for _, sample := range result.Samples {
+		fmt.Fprintf(out, "  %8s chunks (%s messages): %s\n", util.FormatSize(int64(sample.ChunkSize)),
+			util.FormatSize(int64(sample.MessageSize)), formatTuneSample(sample))
+	}
+
+	if !result.Measured {
+		return errors.New("no chunk size could be measured without losses")
+	}
+	fmt.Fprintf(out, "Recommended chunk size: %s\n", util.FormatSize(int64(result.Recommended)))
+	if !apply {
+		fmt.Fprintln(out, "Run with --apply to save it to the config file")
+		return nil
+	}
+	return applyChunkSize(cmd, result.Recommended)
+}
+
+// formatTuneSample renders the throughput of sample, or why there is none
+func formatTuneSample(sample transfer.ChunkSizeSample) string {
+	switch {
+	case sample.Skipped:
+		return "skipped, larger than the largest message"
+	case sample.Lost > 0:
+		return fmt.Sprintf("%d messages lost", sample.Lost)
+	default:
+		return util.FormatSize(int64(sample.Rate())) + "/s"
+	}
+}
+
+// applyChunkSize saves chunkSize to the config file. The file is loaded again, so flags
+// overriding it for this run are not saved.
+func applyChunkSize(cmd *cobra.Command, chunkSize int32) error {
+	path := configPath(cmd)
+	if path == "" {
+		return errors.New("no config file to save the chunk size to, pass --config")
+	}
+	cfg, err := config.Load(path)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	cfg.ChunkSizeKB = int(chunkSize >> 10)
+	if err := config.Save(path, cfg); err != nil {
+		return err
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "Saved chunk_size_kb %d to %s\n", cfg.ChunkSizeKB, path)
+	return nil
+}
@@ -9,6 +9,13 @@ import (
 	"github.com/rescp17/lanFileSharer/pkg/crypto"
 )
 
+var (
+	// ErrNoActiveRequest is returned when there is no pending request, e.g. because it timed out
+	ErrNoActiveRequest = errors.New("no active request")
+	// ErrDecisionMade is returned when the request was already accepted, rejected or timed out
+	ErrDecisionMade = errors.New("a decision has already been made")
+)
+
 // Decision is the type for user's decision.
 type Decision bool
 
@@ -21,6 +28,10 @@ const (
 type RequestState struct {
 	Offer              webrtc.SessionDescription
 	SignedFiles        *crypto.SignedFileStructure // Store signed files information
+	ResumeToken        string                      // Share token used to resume an interrupted session
+	WriteAcks          bool                        // Sender wants write progress ACKs
+	Keepalive          bool                        // Sender sends and expects keepalives
+	SenderName         string                      // Name the sender gave, usually its hostname
 	DecisionChan       chan Decision
 	AnswerChan         chan webrtc.SessionDescription
 	CandidateChan      chan webrtc.ICECandidateInit
@@ -64,6 +75,13 @@ func (m *SingleRequestManager) CreateRequest(offer webrtc.SessionDescription, si
 	return m.state.DecisionChan, nil
 }
 
+// HasActiveRequest reports whether a request is waiting for a decision or being transferred.
+func (m *SingleRequestManager) HasActiveRequest() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.state != nil
+}
+
 // GetOffer retrieves the currently stored offer.
 func (m *SingleRequestManager) GetOffer() (webrtc.SessionDescription, error) {
 	m.mu.Lock()
@@ -87,6 +105,98 @@ func (m *SingleRequestManager) GetSignedFiles() (*crypto.SignedFileStructure, er
 	return m.state.SignedFiles, nil
 }
 
+// SetResumeToken records the share token sent with the current request.
+func (m *SingleRequestManager) SetResumeToken(token string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.state == nil {
+		return errors.New("no active request")
+	}
+	m.state.ResumeToken = token
+	return nil
+}
+
+// GetResumeToken retrieves the share token of the current request.
+func (m *SingleRequestManager) GetResumeToken() (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.state == nil {
+		return "", errors.New("no active request")
+	}
+	return m.state.ResumeToken, nil
+}
+
+// SetWriteAcks records whether the sender of the current request wants write progress ACKs.
+func (m *SingleRequestManager) SetWriteAcks(enabled bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.state == nil {
+		return errors.New("no active request")
+	}
+	m.state.WriteAcks = enabled
+	return nil
+}
+
+// GetWriteAcks reports whether the sender of the current request wants write progress ACKs.
+func (m *SingleRequestManager) GetWriteAcks() (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.state == nil {
+		return false, errors.New("no active request")
+	}
+	return m.state.WriteAcks, nil
+}
+
+// SetKeepalive records whether the sender of the current request sends and expects keepalives.
+func (m *SingleRequestManager) SetKeepalive(enabled bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.state == nil {
+		return errors.New("no active request")
+	}
+	m.state.Keepalive = enabled
+	return nil
+}
+
+// GetKeepalive reports whether the sender of the current request sends and expects keepalives.
+func (m *SingleRequestManager) GetKeepalive() (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.state == nil {
+		return false, errors.New("no active request")
+	}
+	return m.state.Keepalive, nil
+}
+
+// SetSenderName records the name the sender of the current request gave.
+func (m *SingleRequestManager) SetSenderName(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.state == nil {
+		return errors.New("no active request")
+	}
+	m.state.SenderName = name
+	return nil
+}
+
+// GetSenderName retrieves the name the sender of the current request gave.
+func (m *SingleRequestManager) GetSenderName() (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.state == nil {
+		return "", errors.New("no active request")
+	}
+	return m.state.SenderName, nil
+}
+
 // SetDecision records the user's decision and sends it to the waiting handler.
 func (m *SingleRequestManager) SetDecision(decision Decision) error {
 	m.mu.Lock()
@@ -94,11 +204,11 @@ func (m *SingleRequestManager) SetDecision(decision Decision) error {
 
 	if m.state == nil || m.state.DecisionChan == nil {
 		slog.Error("no active request")
-		return errors.New("no active request")
+		return ErrNoActiveRequest
 	}
 	if m.state.decisionSent {
 		slog.Error("a decision has already been made")
-		return errors.New("a decision has already been made")
+		return ErrDecisionMade
 	}
 	m.state.DecisionChan <- decision
 	m.state.decisionSent = true
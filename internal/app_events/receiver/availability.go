@@ -0,0 +1,31 @@
+package receiver
+
+// Availability is whether the receiver takes requests
+type Availability int32
+
+const (
+	// Available advertises the receiver and asks the user about every request
+	Available Availability = iota
+	// DoNotDisturb keeps advertising but declines requests without asking the user
+	DoNotDisturb
+	// Hidden stops advertising and declines requests from senders that still know the address
+	Hidden
+)
+
+func (a Availability) String() string {
+	switch a {
+	case Available:
+		return "available"
+	case DoNotDisturb:
+		return "do not disturb"
+	case Hidden:
+		return "hidden"
+	default:
+		return "unknown"
+	}
+}
+
+// Next returns the availability that follows a when cycling through them
+func (a Availability) Next() Availability {
+	return (a + 1) % (Hidden + 1)
+}
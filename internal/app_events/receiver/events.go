@@ -1,8 +1,12 @@
 package receiver
 
 import (
+	"time"
+
 	appevents "github.com/rescp17/lanFileSharer/internal/app_events"
+	"github.com/rescp17/lanFileSharer/pkg/discovery"
 	"github.com/rescp17/lanFileSharer/pkg/fileInfo"
+	"github.com/rescp17/lanFileSharer/pkg/share"
 )
 
 // --- UI to App Events ---
@@ -17,18 +21,165 @@ type FileRequestRejected struct {
 	appevents.Event
 }
 
+// SetAvailability is sent when the user changes whether the receiver takes requests.
+type SetAvailability struct {
+	appevents.Event
+	Availability Availability
+}
+
+// BrowseShares is sent when the user looks for devices sharing files to pull. The app
+// reports them with SharesFoundMsg until StopBrowsing.
+type BrowseShares struct {
+	appevents.Event
+}
+
+// StopBrowsing is sent when the user leaves the shares.
+type StopBrowsing struct {
+	appevents.Event
+}
+
+// ListShare is sent to list a directory of a share, answered with ShareListingMsg.
+type ListShare struct {
+	appevents.Event
+	Share discovery.ServiceInfo
+	Path  string // Relative to the share, empty for the share itself
+}
+
+// PullFiles is sent to ask a share to send paths to this receiver, answered with PullRequestedMsg.
+type PullFiles struct {
+	appevents.Event
+	Share discovery.ServiceInfo
+	Paths []string
+}
+
+// PullOffer is sent to pull the files of an offer file from the share that exported it,
+// answered with PullRequestedMsg. The offer must have been verified.
+type PullOffer struct {
+	appevents.Event
+	Offer *share.Offer
+}
+
+// PreviewShareFile is sent to fetch the start of a file of a share without pulling it,
+// answered with SharePreviewMsg.
+type PreviewShareFile struct {
+	appevents.Event
+	Share discovery.ServiceInfo
+	Path  string
+}
+
+// IssueGuestPass is sent to let one sender transfer without asking, answered with GuestPassMsg.
+type IssueGuestPass struct {
+	appevents.Event
+}
+
 // --- App to UI Messages ---
 
 // FileNodeUpdateMsg is a message sent to the UI to update it with file info.
 type FileNodeUpdateMsg struct {
 	appevents.AppUIMessage
+	Nodes       []fileInfo.FileNode
+	ResumeToken string // Share token the sender can use to resume this session
+	// SenderFingerprint identifies the key that signed the offer
+	SenderFingerprint string
+	// SenderTrust is the trust store status of that key: unknown, trusted or stale
+	SenderTrust string
+	// TrustReadOnly is set when accepting does not trust a new key, the trust store being read-only
+	TrustReadOnly bool
+	// RelayTo is the receiver a relay is asked to forward the files to, empty for other requests
+	RelayTo string
+	// AutoAccept is set when the settings saved for the sender accept its requests without
+	// asking; the UI accepts the request itself
+	AutoAccept bool
+	// Pulled is set when the offer answers a pull of this receiver, which accepts it as well
+	Pulled bool
+	// Resumed is set when the offer resumes a session that went idle when the sender stopped
+	// responding, which is accepted as well
+	Resumed bool
+	// Guest is set when the sender gave a guest pass of this receiver, which accepts one
+	// transfer as well without trusting the sender's key
+	Guest bool
+	// PeerLabel is the sender's label in the peer settings, empty if it has none
+	PeerLabel string
+	// SenderName and SenderIcon are the hostname and icon the sender gave, safe to display;
+	// either may be empty
+	SenderName string
+	SenderIcon string
+}
+
+// SharesFoundMsg lists the devices sharing files, replacing the previous list
+type SharesFoundMsg struct {
+	appevents.AppUIMessage
+	Shares []discovery.ServiceInfo
+	Err    error // set if discovery failed
+}
+
+// ShareListingMsg answers ListShare. The nodes are not walked: directories have no children
+// and their paths are relative to the share.
+type ShareListingMsg struct {
+	appevents.AppUIMessage
+	Share string
+	Path  string
 	Nodes []fileInfo.FileNode
+	Err   error
+}
+
+// SharePreviewMsg answers PreviewShareFile with the first bytes of the file
+type SharePreviewMsg struct {
+	appevents.AppUIMessage
+	Share string
+	Path  string
+	Data  []byte
+	Size  int64 // Size of the whole file
+	Err   error
+}
+
+// PullRequestedMsg answers PullFiles. The share then sends the files as a request this
+// receiver accepts without asking.
+type PullRequestedMsg struct {
+	appevents.AppUIMessage
+	Share string
+	Files int
+	Err   error
+}
+
+// GuestPassMsg answers IssueGuestPass with the token to give the sender
+type GuestPassMsg struct {
+	appevents.AppUIMessage
+	Token    string
+	MaxBytes int64 // Largest transfer the pass accepts, zero for any size
+	Expires  time.Time
+	Err      error
+}
+
+// RequestTimedOutMsg tells the UI the pending request expired before the user answered it
+type RequestTimedOutMsg struct {
+	appevents.AppUIMessage
+	Timeout time.Duration
+}
+
+// RequestDeclinedMsg tells the UI a request was declined without asking, because the receiver
+// is unavailable or the request is over a quota
+type RequestDeclinedMsg struct {
+	appevents.AppUIMessage
+	Availability Availability
+	Reason       string // Set when the request was declined for another reason than Availability
 }
 
 // TransferFinishedMsg signals the end of a file transfer, with status.
 type TransferFinishedMsg struct {
 	appevents.AppUIMessage
-	Err error // nil if transfer was successful
+	Err        error  // nil if transfer was successful
+	OutputPath string // Received file for single-file transfers, otherwise the output directory
+}
+
+// UploadReceivedMsg tells the UI a browser upload ended, see api.UploadPath
+type UploadReceivedMsg struct {
+	appevents.AppUIMessage
+	Sender string // Address of the uploading device
+	Files  int
+	Failed int
+	Bytes  int64
+	Err    error // Set if the upload broke off
 }
 
 // StatusUpdateMsg provides status updates during file transfer
@@ -36,3 +187,29 @@ type StatusUpdateMsg struct {
 	appevents.AppUIMessage
 	Message string
 }
+
+// ProgressUpdateMsg reports the overall progress of the files being received
+type ProgressUpdateMsg struct {
+	appevents.AppUIMessage
+	TotalFiles     int
+	CompletedFiles int
+	FailedFiles    int
+	TotalBytes     int64
+	ReceivedBytes  int64
+	CurrentFile    string
+	TransferRate   float64 // bytes per second
+	FilesPerMinute float64
+	DiskWriteRate  float64       // bytes per second while writing
+	DiskBusy       float64       // fraction of time spent writing to disk (0-1)
+	ETA            time.Duration // zero if unknown
+}
+
+// FileProgressMsg reports the progress of a single file being received
+type FileProgressMsg struct {
+	appevents.AppUIMessage
+	FileName      string
+	ReceivedBytes int64
+	TotalBytes    int64
+	Completed     bool
+	Err           error // set if the file failed
+}
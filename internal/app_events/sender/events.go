@@ -1,6 +1,8 @@
 package sender
 
 import (
+	"time"
+
 	appevents "github.com/rescp17/lanFileSharer/internal/app_events"
 	"github.com/rescp17/lanFileSharer/pkg/discovery"
 	"github.com/rescp17/lanFileSharer/pkg/fileInfo"
@@ -15,8 +17,37 @@ type SendFilesMsg struct {
 	Files    []fileInfo.FileNode
 }
 
+// BatchItem is one of the sends of a SendBatchMsg
+type BatchItem struct {
+	Name  string
+	Files []fileInfo.FileNode
+}
+
+// SendBatchMsg queues several independent sends to the same receiver as one batch, which
+// the receiver accepts once and which reports progress as a whole
+type SendBatchMsg struct {
+	appevents.Event
+	Receiver discovery.ServiceInfo
+	Items    []BatchItem
+}
+
+// CancelBatchItemMsg cancels the item of the running batch with ItemID, its position in
+// SendBatchMsg.Items counting from 1, while the other items carry on
+type CancelBatchItemMsg struct {
+	appevents.Event
+	ItemID string
+}
+
+// UseWiredPathMsg moves the running transfer to the wired interfaces offered by a WirelessPathMsg
+type UseWiredPathMsg struct {
+	appevents.Event
+}
+
 var (
 	_ appevents.AppEvent = (*SendFilesMsg)(nil)
+	_ appevents.AppEvent = (*SendBatchMsg)(nil)
+	_ appevents.AppEvent = (*CancelBatchItemMsg)(nil)
+	_ appevents.AppEvent = (*UseWiredPathMsg)(nil)
 )
 
 // --- UI Messages (from App to TUI) ---
@@ -33,6 +64,39 @@ type TransferStartedMsg struct{}
 
 type ReceiverAcceptedMsg struct{}
 
+// RequestTimedOutMsg is sent when the receiver did not answer the transfer request in time
+type RequestTimedOutMsg struct {
+	Receiver discovery.ServiceInfo
+}
+
+// SpeedProbeMsg reports the connection speed measured before the files are sent
+type SpeedProbeMsg struct {
+	Rate       float64 // bytes per second
+	TotalBytes int64
+	ETA        time.Duration // estimated duration of the whole transfer at Rate
+}
+
+// WirelessPathMsg warns that a large transfer goes over Wi-Fi although both machines are also
+// wired; a UseWiredPathMsg moves it to the wired interfaces
+type WirelessPathMsg struct {
+	Interface  string   // Wi-Fi interface the transfer goes over
+	Wired      []string // Wired interfaces of this machine
+	TotalBytes int64
+}
+
+// PathStat is what one path of a bonded transfer carried
+type PathStat struct {
+	Interface string  // Local interface of the path, its local address if that is unknown
+	Bytes     int64   // Bytes of striped files sent over the path
+	Rate      float64 // bytes per second since the previous PathStatsMsg
+}
+
+// PathStatsMsg reports the paths a transfer is striped over in bonding mode, the connection's
+// own path first
+type PathStatsMsg struct {
+	Paths []PathStat
+}
+
 type ProgressUpdateMsg struct {
 	TotalFiles       int
 	CompletedFiles   int
@@ -40,12 +104,33 @@ type ProgressUpdateMsg struct {
 	TransferredBytes int64
 	CurrentFile      string
 	TransferRate     float64 // bytes per second
+	FilesPerMinute   float64
 	ETA              string  // estimated time remaining
 	OverallProgress  float64 // percentage 0-100
+	// PersistedBytes is what the receiver has written to disk; zero if it does not report it
+	PersistedBytes   int64
+	ReceiverDiskRate float64 // bytes per second
+	// Phases is the time the finished files spent in each transfer phase, such as "hashing"
+	Phases map[string]time.Duration
 }
 
 type TransferCompleteMsg struct{}
 
+// FailedFile is a file a transfer could not deliver and why
+type FailedFile struct {
+	Path   string
+	Reason string
+}
+
+// FilesFailedMsg lists the files a transfer to Receiver could not deliver. It is sent before
+// the TransferCompleteMsg or error ending the transfer. Retry holds only those files, within
+// their selected directories, for sending them again.
+type FilesFailedMsg struct {
+	Receiver discovery.ServiceInfo
+	Files    []FailedFile
+	Retry    []fileInfo.FileNode
+}
+
 // Transfer control events
 type PauseTransferMsg struct {
 	appevents.Event
@@ -59,7 +144,12 @@ type CancelTransferMsg struct {
 	appevents.Event
 }
 
-// Transfer control response events
-type TransferPausedMsg struct{}
-type TransferResumedMsg struct{}
+// Transfer control response events. Files is the number of files in flight the session
+// change applied to, more than one when files are sent in parallel.
+type TransferPausedMsg struct {
+	Files int
+}
+type TransferResumedMsg struct {
+	Files int
+}
 type TransferCancelledMsg struct{}
@@ -0,0 +1,382 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+)
+
+const (
+	// AppDirName is the directory name used under the user config directory
+	AppDirName = "lanFileSharer"
+	// FileName is the default config file name
+	FileName = "config.json"
+)
+
+// Config holds user preferences loaded from the config file
+type Config struct {
+	// AutoOpen opens the received file or folder once a transfer completes
+	AutoOpen bool `json:"auto_open"`
+	// OnSendStart is a shell command run before each send; a non-zero exit aborts it
+	OnSendStart string `json:"on_send_start,omitempty"`
+	// OnSendComplete is a shell command run after each send finishes
+	OnSendComplete string `json:"on_send_complete,omitempty"`
+	// Webhooks are URLs that get an HTTP POST of a JSON event when a send or receive session
+	// starts, completes or fails; Slack and Discord incoming webhooks work as they are
+	Webhooks []string `json:"webhooks,omitempty"`
+	// GenerateManifest prepends a checksums.sha256 file describing the sent tree
+	GenerateManifest bool `json:"generate_manifest"`
+	// SkipSentFiles skips files already sent to the same peer with unchanged content
+	SkipSentFiles bool `json:"skip_sent_files"`
+	// ForceResend disables SkipSentFiles for one run; it is only set by the --force flag
+	ForceResend bool `json:"-"`
+	// KeyLifetimeDays is how long the device signing key is used before it is rotated
+	KeyLifetimeDays int `json:"key_lifetime_days"`
+	// KeyGraceDays is how long a rotated device key is still accepted
+	KeyGraceDays int `json:"key_grace_days"`
+	// TrustMaxAgeDays is how long an accepted sender key stays trusted before it must
+	// be verified again; zero keeps it trusted until the key expires
+	TrustMaxAgeDays int `json:"trust_max_age_days"`
+	// TrustReadOnly keeps the trust store as provisioned with trust import: accepting a
+	// request no longer trusts the sender key
+	TrustReadOnly bool `json:"trust_read_only"`
+	// SignatureAlgorithm is "ed25519" or "rsa"; rsa is slower but verifiable by older receivers
+	SignatureAlgorithm string `json:"signature_algorithm"`
+	// RetryMaxRetries is how often a failed file is retried before it is marked failed
+	RetryMaxRetries int `json:"retry_max_retries"`
+	// RetryInitialDelayMs is the delay before the first retry, in milliseconds
+	RetryInitialDelayMs int `json:"retry_initial_delay_ms"`
+	// RetryBackoffFactor multiplies the delay after every retry
+	RetryBackoffFactor float64 `json:"retry_backoff_factor"`
+	// RetryMaxDelayMs caps the delay between retries, in milliseconds
+	RetryMaxDelayMs int `json:"retry_max_delay_ms"`
+	// RetryOn lists the error classes that are retried, e.g. "timeout" or "connection_lost"
+	RetryOn []string `json:"retry_on"`
+	// HashWorkers is how many files are hashed at once after selecting files; 0 picks a default
+	HashWorkers int `json:"hash_workers,omitempty"`
+	// AcceptTimeoutSeconds is how long the receiver waits for the user to accept or reject
+	// an incoming request before answering the sender that it timed out; zero waits forever
+	AcceptTimeoutSeconds int `json:"accept_timeout_seconds"`
+	// SessionIdleTimeoutSeconds ends a transfer session once the peer sent nothing, not even
+	// a keepalive, for this long, e.g. because its laptop went to sleep; the sender reconnects
+	// and resumes it once the peer is back. Zero keeps sessions open
+	SessionIdleTimeoutSeconds int `json:"session_idle_timeout_seconds"`
+	// SpeedProbeMB is how much random data is sent to measure the connection before large
+	// transfers, so the first estimate is realistic; zero disables the probe
+	SpeedProbeMB int `json:"speed_probe_mb"`
+	// ChunkSizeKB is the size of the chunks files are sent in, as recommended by
+	// "lanfilesharer tune"; zero uses the transfer default. Resumed transfers must be sent
+	// with the chunk size they were started with
+	ChunkSizeKB int `json:"chunk_size_kb,omitempty"`
+	// BandwidthProfiles limit how fast files are sent at times of day, e.g. 10 MB/s during
+	// work hours; the first profile covering the time applies, and sending is unlimited
+	// outside every profile
+	BandwidthProfiles []BandwidthProfile `json:"bandwidth_profiles,omitempty"`
+	// BatteryWarnMB warns before sends of at least this size start while this machine runs on
+	// battery; zero disables the battery checks
+	BatteryWarnMB int `json:"battery_warn_mb"`
+	// BatterySaver sends those transfers on a single stream, and no faster than
+	// BatteryBandwidthKB per second if set, to draw less power
+	BatterySaver       bool `json:"battery_saver,omitempty"`
+	BatteryBandwidthKB int  `json:"battery_bandwidth_kb,omitempty"`
+	// DoNotDisturbMessage is the reason senders are given when the receiver declines
+	// requests in do not disturb mode
+	DoNotDisturbMessage string `json:"do_not_disturb_message"`
+	// Icon is an emoji or short glyph shown next to this device's name on other devices, in
+	// the list of receivers and the prompt to accept files, e.g. "💻"; at most two emoji wide
+	Icon string `json:"icon,omitempty"`
+	// ServiceType and ServiceDomain scope discovery, e.g. "_acme-share._tcp" to keep an
+	// organization's deployment apart; empty uses "_file-sharing._tcp" in "local"
+	ServiceType   string `json:"service_type,omitempty"`
+	ServiceDomain string `json:"service_domain,omitempty"`
+	// ExtraServiceTypes are announced by receivers as well, e.g. the default type while
+	// senders move to a new one; senders only look up ServiceType
+	ExtraServiceTypes []string `json:"extra_service_types,omitempty"`
+	// AllowSubnets and DenySubnets are CIDR ranges peers must, or must not, be in to show
+	// up in discovery or reach the receiver; an empty allowlist allows every subnet
+	AllowSubnets []string `json:"allow_subnets,omitempty"`
+	DenySubnets  []string `json:"deny_subnets,omitempty"`
+	// AllowFingerprints and DenyFingerprints filter requests by the sender's key fingerprint,
+	// as shown by "lanfilesharer keys"; an empty allowlist allows every key
+	AllowFingerprints []string `json:"allow_fingerprints,omitempty"`
+	DenyFingerprints  []string `json:"deny_fingerprints,omitempty"`
+	// VerifyWritesPercent is the share of written chunks read back from disk and compared,
+	// to catch storage that silently corrupts data; 100 checks every chunk, zero none
+	VerifyWritesPercent int `json:"verify_writes_percent,omitempty"`
+	// OutputTemplate lays out received files under the output directory, e.g.
+	// "{date}/{sender}/{original}"; placeholders are {date}, {time}, {sender}, {original},
+	// {name} and {ext}. Empty saves files directly in the output directory
+	OutputTemplate string `json:"output_template,omitempty"`
+	// ContentAddressed stores every received content once under .cas in the output directory,
+	// with received files as hard links to it, and skips transfers of content already stored;
+	// "lanfilesharer materialize" exports the received files as ordinary copies
+	ContentAddressed bool `json:"content_addressed,omitempty"`
+	// VirusScan scans every received file before it is completed: "clamd:<socket or host:port>"
+	// streams it to clamd, "command:<command>" runs the command with the file's path appended,
+	// which exits 1 for infected files. Infected files are moved to .quarantine in the output
+	// directory and fail; empty scans nothing
+	VirusScan string `json:"virus_scan,omitempty"`
+	// Storage is where received files land once verified: empty or "local" keeps them in the
+	// output directory, "s3" uploads them to the bucket in S3 and removes the local copy,
+	// "sftp" forwards them to the SFTP server in SFTP
+	Storage string `json:"storage,omitempty"`
+	// RequireMount refuses requests while the output directory is not a mount point, so an
+	// SMB or NFS share that came unmounted does not fill the local disk instead
+	RequireMount bool `json:"require_mount,omitempty"`
+	// S3 is the bucket received files are uploaded to with storage "s3"
+	S3 S3Storage `json:"s3,omitempty"`
+	// SFTP is the server received files are forwarded to with storage "sftp"
+	SFTP SFTPStorage `json:"sftp,omitempty"`
+	// MaxTransferMB refuses larger transfers, and DailyQuotaMB limits what each sender may send
+	// per day, e.g. for shared drop-box receivers; zero disables a limit
+	MaxTransferMB int `json:"max_transfer_mb,omitempty"`
+	DailyQuotaMB  int `json:"daily_quota_mb,omitempty"`
+	// GuestMaxMB caps the single transfer a guest pass accepts, see "receive --guest"; zero
+	// accepts any size
+	GuestMaxMB int `json:"guest_max_mb,omitempty"`
+	// UploadToken is the token of browser uploads, see "receive --upload"; empty generates
+	// one every time the receiver starts
+	UploadToken string `json:"upload_token,omitempty"`
+	// AuditLog keeps a hash-chained log of requests and sessions, see "lanfilesharer audit"
+	AuditLog bool `json:"audit_log,omitempty"`
+	// Lockdown is set by administrators of shared receivers, such as kiosks, so users cannot
+	// misconfigure them: received files go to LockdownOutputDir whatever --output says, the
+	// output subdirectories of peer settings are ignored, and only senders whose keys are in
+	// the trust store are accepted. A lockdown in the default config file also applies to
+	// configs given with --config. Transfers are always encrypted, there is no setting to lock.
+	Lockdown bool `json:"lockdown,omitempty"`
+	// LockdownOutputDir is the only output directory of a locked down receiver; empty leaves
+	// the output directory to --output
+	LockdownOutputDir string `json:"lockdown_output_dir,omitempty"`
+	// Theme is the TUI theme; empty picks "default" or "light" to suit the terminal background
+	Theme string `json:"theme,omitempty"`
+	// DisableMouse leaves the mouse to the terminal, e.g. for selecting text, instead of the TUI
+	DisableMouse bool `json:"disable_mouse,omitempty"`
+	// LANOnly refuses every peer, candidate and connection outside the private and link-local
+	// ranges and never contacts STUN or TURN servers, so no data leaves the local network
+	LANOnly bool `json:"lan_only,omitempty"`
+	// ExperimentalBonding stripes large files over extra connections opened over every other
+	// local interface that reaches the receiver, such as Ethernet and Wi-Fi together
+	ExperimentalBonding bool `json:"experimental_bonding,omitempty"`
+	// AirgapPeer is the address of the other machine in air-gap mode, set with --airgap.
+	// Nothing is announced or discovered, no STUN, TURN or multicast DNS is used, and only
+	// that peer is allowed.
+	AirgapPeer string `json:"-"`
+	// PSKPassphrase is the passphrase both machines entered for air-gap mode
+	PSKPassphrase string `json:"-"`
+	// Chaos injects faults into sent chunks to test recovery, set with the hidden --chaos
+	// flag; see transfer.ParseChaosConfig for the format
+	Chaos string `json:"-"`
+}
+
+// BandwidthProfile limits sending between two local times of day
+type BandwidthProfile struct {
+	// Name is shown in the TUI status bar while the profile applies
+	Name string `json:"name"`
+	// Start and End are times of day such as "9:00" and "18:00"; an End before the Start
+	// spans midnight
+	Start string `json:"start"`
+	End   string `json:"end"`
+	// LimitKB is the limit in KiB per second, zero for unlimited
+	LimitKB int `json:"limit_kb"`
+}
+
+// S3Storage is an S3-compatible bucket, such as AWS S3, MinIO or Backblaze B2
+type S3Storage struct {
+	// Endpoint is the URL of the service, e.g. "https://s3.eu-west-1.amazonaws.com" or
+	// "http://nas.local:9000"; empty uses AWS in Region
+	Endpoint string `json:"endpoint,omitempty"`
+	Region   string `json:"region,omitempty"`
+	Bucket   string `json:"bucket"`
+	// Prefix is prepended to the keys of uploaded files, e.g. "inbox/"
+	Prefix string `json:"prefix,omitempty"`
+	// AccessKeyID and SecretAccessKey default to the AWS_ACCESS_KEY_ID and
+	// AWS_SECRET_ACCESS_KEY environment variables, so they need not be stored here
+	AccessKeyID     string `json:"access_key_id,omitempty"`
+	SecretAccessKey string `json:"secret_access_key,omitempty"`
+	// PathStyle addresses the bucket in the path rather than the host name, as MinIO and
+	// most self-hosted services need
+	PathStyle bool `json:"path_style,omitempty"`
+}
+
+// SFTPStorage is a directory on an SFTP server, making the receiver a gateway from the LAN
+// to that server
+type SFTPStorage struct {
+	// Address is the server's "host" or "host:port", port 22 by default
+	Address string `json:"address"`
+	// User defaults to the current user
+	User string `json:"user,omitempty"`
+	// Path is the directory files are forwarded to, relative to the login directory unless
+	// absolute; empty is the login directory
+	Path string `json:"path,omitempty"`
+	// IdentityFile is the private key to log in with; empty tries the SSH agent and the
+	// default keys in ~/.ssh
+	IdentityFile string `json:"identity_file,omitempty"`
+	// KnownHostsFile holds the server's host key, ~/.ssh/known_hosts by default; servers
+	// not in it are refused
+	KnownHostsFile string `json:"known_hosts_file,omitempty"`
+	// KeepLocal keeps forwarded files in the output directory as well
+	KeepLocal bool `json:"keep_local,omitempty"`
+}
+
+// DefaultConfig returns the configuration used when no config file exists
+func DefaultConfig() Config {
+	return Config{
+		AutoOpen:                  false,
+		SkipSentFiles:             true,
+		KeyLifetimeDays:           90,
+		KeyGraceDays:              7,
+		TrustMaxAgeDays:           30,
+		SignatureAlgorithm:        "ed25519",
+		RetryMaxRetries:           3,
+		RetryInitialDelayMs:       1000,
+		RetryBackoffFactor:        2,
+		RetryMaxDelayMs:           30000,
+		RetryOn:                   []string{"peer_unreachable", "connection_lost", "timeout"},
+		AcceptTimeoutSeconds:      120,
+		SessionIdleTimeoutSeconds: 60,
+		SpeedProbeMB:              4,
+		BatteryWarnMB:             2048,
+		GuestMaxMB:                1024,
+		DoNotDisturbMessage:       "Do not disturb, try again later",
+	}
+}
+
+// KeyLifetime returns KeyLifetimeDays as a duration
+func (c Config) KeyLifetime() time.Duration {
+	return days(c.KeyLifetimeDays)
+}
+
+// KeyGrace returns KeyGraceDays as a duration
+func (c Config) KeyGrace() time.Duration {
+	return days(c.KeyGraceDays)
+}
+
+// TrustMaxAge returns TrustMaxAgeDays as a duration
+func (c Config) TrustMaxAge() time.Duration {
+	return days(c.TrustMaxAgeDays)
+}
+
+// HashWorkerCount returns HashWorkers, defaulting to one worker per CPU up to four
+func (c Config) HashWorkerCount() int {
+	if c.HashWorkers > 0 {
+		return c.HashWorkers
+	}
+	return min(runtime.NumCPU(), 4)
+}
+
+// AcceptTimeout returns AcceptTimeoutSeconds as a duration
+func (c Config) AcceptTimeout() time.Duration {
+	return time.Duration(c.AcceptTimeoutSeconds) * time.Second
+}
+
+// SessionIdleTimeout returns SessionIdleTimeoutSeconds as a duration
+func (c Config) SessionIdleTimeout() time.Duration {
+	return time.Duration(c.SessionIdleTimeoutSeconds) * time.Second
+}
+
+// SpeedProbeSize returns SpeedProbeMB in bytes
+func (c Config) SpeedProbeSize() int64 {
+	return int64(c.SpeedProbeMB) << 20
+}
+
+// BatteryWarnBytes returns BatteryWarnMB in bytes
+func (c Config) BatteryWarnBytes() int64 {
+	return int64(c.BatteryWarnMB) << 20
+}
+
+// ChunkSize returns ChunkSizeKB in bytes
+func (c Config) ChunkSize() int32 {
+	return int32(c.ChunkSizeKB) << 10
+}
+
+// VerifyWritesFraction returns VerifyWritesPercent as a fraction between 0 and 1
+func (c Config) VerifyWritesFraction() float64 {
+	return min(max(float64(c.VerifyWritesPercent)/100, 0), 1)
+}
+
+// MaxTransferBytes returns MaxTransferMB in bytes
+func (c Config) MaxTransferBytes() int64 {
+	return int64(c.MaxTransferMB) << 20
+}
+
+// DailyQuotaBytes returns DailyQuotaMB in bytes
+func (c Config) DailyQuotaBytes() int64 {
+	return int64(c.DailyQuotaMB) << 20
+}
+
+// GuestMaxBytes returns GuestMaxMB in bytes
+func (c Config) GuestMaxBytes() int64 {
+	return int64(c.GuestMaxMB) << 20
+}
+
+// RetryInitialDelay returns RetryInitialDelayMs as a duration
+func (c Config) RetryInitialDelay() time.Duration {
+	return time.Duration(c.RetryInitialDelayMs) * time.Millisecond
+}
+
+// RetryMaxDelay returns RetryMaxDelayMs as a duration
+func (c Config) RetryMaxDelay() time.Duration {
+	return time.Duration(c.RetryMaxDelayMs) * time.Millisecond
+}
+
+func days(n int) time.Duration {
+	return time.Duration(n) * 24 * time.Hour
+}
+
+// Dir returns the application directory under the user config directory
+func Dir() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get user config directory: %w", err)
+	}
+	return filepath.Join(dir, AppDirName), nil
+}
+
+// DefaultPath returns the default location of the config file
+func DefaultPath() (string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, FileName), nil
+}
+
+// Load reads the config file at path, falling back to defaults if it does not exist
+func Load(path string) (Config, error) {
+	cfg := DefaultConfig()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return cfg, nil
+		}
+		return cfg, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return DefaultConfig(), fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// Save writes the config to path, creating parent directories as needed
+func Save(path string, cfg Config) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal config: %w", err)
+	}
+
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad_MissingFileReturnsDefaults(t *testing.T) {
+	cfg, err := Load(filepath.Join(t.TempDir(), "missing.json"))
+	require.NoError(t, err)
+	assert.Equal(t, DefaultConfig(), cfg)
+}
+
+func TestLoad_ParsesFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	require.NoError(t, os.WriteFile(path, []byte(`{"auto_open": true}`), 0644))
+
+	cfg, err := Load(path)
+	require.NoError(t, err)
+	assert.True(t, cfg.AutoOpen)
+}
+
+func TestLoad_InvalidJSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	require.NoError(t, os.WriteFile(path, []byte(`{not json`), 0644))
+
+	cfg, err := Load(path)
+	assert.Error(t, err)
+	assert.Equal(t, DefaultConfig(), cfg)
+}
+
+func TestSaveAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", FileName)
+	want := DefaultConfig()
+	want.AutoOpen = true
+
+	require.NoError(t, Save(path, want))
+
+	got, err := Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, want, got)
+}
+
+func TestLoad_RetrySettings(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	require.NoError(t, os.WriteFile(path, []byte(`{"retry_max_retries": 5, "retry_initial_delay_ms": 250, "retry_on": ["timeout"]}`), 0644))
+
+	cfg, err := Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, 5, cfg.RetryMaxRetries)
+	assert.Equal(t, 250*time.Millisecond, cfg.RetryInitialDelay())
+	assert.Equal(t, []string{"timeout"}, cfg.RetryOn)
+	// Settings missing from the file keep their defaults
+	assert.Equal(t, DefaultConfig().RetryMaxDelay(), cfg.RetryMaxDelay())
+}
+
+func TestHashWorkerCount(t *testing.T) {
+	cfg := DefaultConfig()
+	assert.GreaterOrEqual(t, cfg.HashWorkerCount(), 1)
+	assert.LessOrEqual(t, cfg.HashWorkerCount(), 4)
+
+	cfg.HashWorkers = 7
+	assert.Equal(t, 7, cfg.HashWorkerCount())
+}
+
+func TestAcceptTimeout(t *testing.T) {
+	assert.Equal(t, 2*time.Minute, DefaultConfig().AcceptTimeout())
+
+	path := filepath.Join(t.TempDir(), FileName)
+	require.NoError(t, os.WriteFile(path, []byte(`{"accept_timeout_seconds": 0}`), 0644))
+	cfg, err := Load(path)
+	require.NoError(t, err)
+	assert.Zero(t, cfg.AcceptTimeout(), "zero disables the timeout")
+}
+
+func TestSessionIdleTimeout(t *testing.T) {
+	assert.Equal(t, time.Minute, DefaultConfig().SessionIdleTimeout())
+
+	path := filepath.Join(t.TempDir(), FileName)
+	require.NoError(t, os.WriteFile(path, []byte(`{"session_idle_timeout_seconds": 0}`), 0644))
+	cfg, err := Load(path)
+	require.NoError(t, err)
+	assert.Zero(t, cfg.SessionIdleTimeout(), "zero keeps sessions open")
+}
+
+func TestSpeedProbeSize(t *testing.T) {
+	assert.Equal(t, int64(4<<20), DefaultConfig().SpeedProbeSize())
+	assert.Zero(t, Config{}.SpeedProbeSize(), "zero disables the probe")
+}
+
+func TestChunkSize(t *testing.T) {
+	assert.Zero(t, DefaultConfig().ChunkSize(), "zero uses the transfer default")
+	assert.Equal(t, int32(128<<10), Config{ChunkSizeKB: 128}.ChunkSize())
+}
+
+func TestVerifyWritesFraction(t *testing.T) {
+	assert.Zero(t, DefaultConfig().VerifyWritesFraction())
+	assert.Equal(t, 0.05, Config{VerifyWritesPercent: 5}.VerifyWritesFraction())
+	assert.Equal(t, 1.0, Config{VerifyWritesPercent: 250}.VerifyWritesFraction())
+	assert.Zero(t, Config{VerifyWritesPercent: -1}.VerifyWritesFraction())
+}
+
+func TestOutputDir_Lockdown(t *testing.T) {
+	dir, err := Config{}.OutputDir("/tmp/anywhere", true)
+	require.NoError(t, err)
+	assert.Equal(t, "/tmp/anywhere", dir)
+
+	locked := Config{Lockdown: true, LockdownOutputDir: "/srv/kiosk"}
+	dir, err = locked.OutputDir(".", false)
+	require.NoError(t, err)
+	assert.Equal(t, "/srv/kiosk", dir, "the default output directory gives way to the locked one")
+	dir, err = locked.OutputDir("/srv/kiosk/", true)
+	require.NoError(t, err)
+	assert.Equal(t, "/srv/kiosk", dir)
+	_, err = locked.OutputDir("/tmp/elsewhere", true)
+	assert.ErrorIs(t, err, ErrLockedDown)
+
+	// A user config cannot lift the lockdown of the default config
+	user := Config{}
+	user.ApplyLockdown(locked)
+	assert.True(t, user.Lockdown)
+	assert.Equal(t, "/srv/kiosk", user.LockdownOutputDir)
+}
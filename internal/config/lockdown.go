@@ -0,0 +1,43 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+)
+
+// ErrLockedDown is returned for settings a locked down config does not let users change
+var ErrLockedDown = errors.New("locked down by the administrator")
+
+// ApplyLockdown locks c down as admin is, so a config given by the user cannot lift the
+// lockdown of the default one
+func (c *Config) ApplyLockdown(admin Config) {
+	if !admin.Lockdown {
+		return
+	}
+	c.Lockdown = true
+	c.LockdownOutputDir = admin.LockdownOutputDir
+}
+
+// OutputDir returns the directory received files are saved in. That is requested, from
+// --output, unless the config is locked down to LockdownOutputDir; then a requested
+// directory given explicitly must be that one.
+func (c Config) OutputDir(requested string, explicit bool) (string, error) {
+	if !c.Lockdown || c.LockdownOutputDir == "" {
+		return requested, nil
+	}
+	if explicit && !samePath(requested, c.LockdownOutputDir) {
+		return "", fmt.Errorf("output directory %s: %w, received files go to %s", requested, ErrLockedDown, c.LockdownOutputDir)
+	}
+	return c.LockdownOutputDir, nil
+}
+
+// samePath reports whether a and b name the same path once made absolute
+func samePath(a, b string) bool {
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+	if errA != nil || errB != nil {
+		return filepath.Clean(a) == filepath.Clean(b)
+	}
+	return absA == absB
+}
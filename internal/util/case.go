@@ -0,0 +1,36 @@
+package util
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// IsCaseInsensitiveDir reports whether the filesystem holding dir treats names that
+// differ only in case as the same file, as is the default on Windows and macOS.
+// It finds out by creating a probe file in dir and looking it up in lower case.
+func IsCaseInsensitiveDir(dir string) (bool, error) {
+	probe, err := os.CreateTemp(dir, ".CaseProbe-*")
+	if err != nil {
+		return false, fmt.Errorf("failed to create case probe: %w", err)
+	}
+	probePath := probe.Name()
+	defer func() {
+		_ = os.Remove(probePath)
+	}()
+	if err := probe.Close(); err != nil {
+		return false, fmt.Errorf("failed to close case probe: %w", err)
+	}
+
+	lower := filepath.Join(filepath.Dir(probePath), strings.ToLower(filepath.Base(probePath)))
+	_, err = os.Stat(lower)
+	switch {
+	case err == nil:
+		return true, nil
+	case os.IsNotExist(err):
+		return false, nil
+	default:
+		return false, fmt.Errorf("failed to stat case probe: %w", err)
+	}
+}
@@ -0,0 +1,36 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestIsCaseInsensitiveDir(t *testing.T) {
+	dir := t.TempDir()
+
+	insensitive, err := IsCaseInsensitiveDir(dir)
+	if err != nil {
+		t.Fatalf("IsCaseInsensitiveDir() error = %v", err)
+	}
+	// Linux filesystems are case-sensitive unless explicitly configured otherwise
+	if runtime.GOOS == "linux" && insensitive {
+		t.Errorf("IsCaseInsensitiveDir() = true on linux, expected false")
+	}
+
+	// The probe file is cleaned up
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("ReadDir() error = %v", err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("expected no files left behind, found %d", len(entries))
+	}
+}
+
+func TestIsCaseInsensitiveDir_MissingDir(t *testing.T) {
+	if _, err := IsCaseInsensitiveDir(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("expected an error for a missing directory")
+	}
+}
@@ -0,0 +1,32 @@
+package util
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/atotto/clipboard"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/charmbracelet/x/term"
+)
+
+// CopyToClipboard puts text on the system clipboard. Without a clipboard tool, such as over
+// SSH, it asks the terminal to do it with an OSC 52 sequence, which most terminals honour.
+func CopyToClipboard(text string) error {
+	err := clipboard.WriteAll(text)
+	if err == nil {
+		return nil
+	}
+	if !term.IsTerminal(os.Stdout.Fd()) {
+		return fmt.Errorf("failed to copy to the clipboard: %w", err)
+	}
+	return writeOSC52(os.Stdout, text)
+}
+
+// writeOSC52 writes the sequence asking the terminal on w to put text on the clipboard
+func writeOSC52(w io.Writer, text string) error {
+	if _, err := io.WriteString(w, ansi.SetSystemClipboard(text)); err != nil {
+		return fmt.Errorf("failed to copy to the clipboard: %w", err)
+	}
+	return nil
+}
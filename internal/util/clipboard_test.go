@@ -0,0 +1,16 @@
+package util
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteOSC52(t *testing.T) {
+	var b strings.Builder
+	require.NoError(t, writeOSC52(&b, "/home/me/report.pdf"))
+	assert.Equal(t, "\x1b]52;c;"+base64.StdEncoding.EncodeToString([]byte("/home/me/report.pdf"))+"\x07", b.String())
+}
@@ -0,0 +1,19 @@
+//go:build linux || darwin
+
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFreeSpace(t *testing.T) {
+	free, err := FreeSpace(t.TempDir())
+	require.NoError(t, err)
+	assert.Positive(t, free)
+
+	_, err = FreeSpace("/does/not/exist")
+	assert.Error(t, err)
+}
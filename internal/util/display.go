@@ -0,0 +1,87 @@
+package util
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/charmbracelet/x/ansi"
+)
+
+// SanitizeText makes a string received from another device safe to print on one line.
+// Tabs and line breaks become spaces; invalid UTF-8, other control characters and
+// bidirectional overrides, which would move the cursor, garble the terminal or reorder
+// the text around them, are replaced with U+FFFD.
+func SanitizeText(s string) string {
+	if isClean(s) {
+		return s
+	}
+	var b strings.Builder
+	b.Grow(len(s))
+	for i, w := 0, 0; i < len(s); i += w {
+		var r rune
+		r, w = utf8.DecodeRuneInString(s[i:])
+		switch r {
+		case '\t', '\n', '\r':
+			b.WriteByte(' ')
+			continue
+		}
+		if r == utf8.RuneError || unsafeRune(r) {
+			b.WriteRune(utf8.RuneError)
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func isClean(s string) bool {
+	for _, r := range s {
+		if r == utf8.RuneError || unsafeRune(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// unsafeRune reports whether r is a control character or changes the direction of the text after it
+func unsafeRune(r rune) bool {
+	switch {
+	case unicode.IsControl(r):
+		return true
+	case r >= '\u202a' && r <= '\u202e', r >= '\u2066' && r <= '\u2069':
+		return true
+	}
+	return false
+}
+
+// Truncate shortens s to at most width terminal cells, ending it with "..." if it was cut.
+// Wide characters and emoji count as two cells and styling escape sequences as none, so
+// the result never overflows a column and is never cut in the middle of a character.
+func Truncate(s string, width int) string {
+	if ansi.StringWidth(s) <= width {
+		return s
+	}
+	return ansi.Truncate(s, width, "...")
+}
+
+// MaxIconWidth is the most terminal cells a device icon may take, enough for two emoji
+const MaxIconWidth = 4
+
+// DeviceIcon returns the icon another device advertised, safe to print before its name, or
+// "" if it is empty or wider than MaxIconWidth cells, so it cannot pose as a name
+func DeviceIcon(s string) string {
+	s = strings.TrimSpace(SanitizeText(s))
+	if s == "" || strings.ContainsRune(s, utf8.RuneError) || ansi.StringWidth(s) > MaxIconWidth {
+		return ""
+	}
+	return s
+}
+
+// WithIcon prefixes name with icon, as returned by DeviceIcon, if there is one
+func WithIcon(icon, name string) string {
+	if icon == "" {
+		return name
+	}
+	return icon + " " + name
+}
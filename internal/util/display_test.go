@@ -0,0 +1,56 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/charmbracelet/x/ansi"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSanitizeText(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"Plain", "Living Room PC", "Living Room PC"},
+		{"CJK and emoji", "李的电脑 🎉", "李的电脑 🎉"},
+		{"Escape sequence", "evil\x1b[2Jname", "evil�[2Jname"},
+		{"Line breaks and tab", "a\r\nb\tc", "a  b c"},
+		{"C1 control", "a\u009bb", "a�b"},
+		{"Bidi override", "photo\u202egpj.exe", "photo�gpj.exe"},
+		{"Invalid UTF-8", "a\xffb", "a�b"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, SanitizeText(tt.input))
+		})
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	assert.Equal(t, "short", Truncate("short", 10))
+	assert.Equal(t, "hello w...", Truncate("hello world!", 10))
+
+	// Wide characters are two cells each and are never split
+	truncated := Truncate("你好世界你好世界", 9)
+	assert.LessOrEqual(t, ansi.StringWidth(truncated), 9)
+	assert.Equal(t, "你好世...", truncated)
+
+	// Styling does not count towards the width
+	styled := "\x1b[1m" + "hello world!" + "\x1b[0m"
+	assert.Equal(t, "hello world!", ansi.Strip(Truncate(styled, 12)))
+	assert.Equal(t, "hello w...", ansi.Strip(Truncate(styled, 10)))
+}
+
+func TestDeviceIcon(t *testing.T) {
+	assert.Equal(t, "💻", DeviceIcon(" 💻 "))
+	assert.Equal(t, "👨‍👩‍👧", DeviceIcon("👨‍👩‍👧"), "a joined emoji is one glyph")
+	assert.Equal(t, "VM", DeviceIcon("VM"))
+	assert.Empty(t, DeviceIcon("laptop"), "too wide for an icon")
+	assert.Empty(t, DeviceIcon("\x1b[2J"), "escape sequences are not icons")
+	assert.Empty(t, DeviceIcon(""))
+
+	assert.Equal(t, "💻 laptop", WithIcon("💻", "laptop"))
+	assert.Equal(t, "laptop", WithIcon("", "laptop"))
+}
@@ -0,0 +1,9 @@
+package util
+
+import "net"
+
+// IsLANAddress reports whether ip can only be reached on the local network: a private
+// (RFC 1918 or IPv6 unique local), link-local or loopback address
+func IsLANAddress(ip net.IP) bool {
+	return ip != nil && (ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLoopback())
+}
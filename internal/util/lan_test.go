@@ -0,0 +1,34 @@
+package util
+
+import (
+	"net"
+	"testing"
+)
+
+func TestIsLANAddress(t *testing.T) {
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{"192.168.1.10", true},
+		{"10.20.30.40", true},
+		{"172.16.0.1", true},
+		{"172.32.0.1", false},
+		{"169.254.10.1", true},
+		{"127.0.0.1", true},
+		{"fd12::1", true},
+		{"fe80::1", true},
+		{"::ffff:192.168.1.10", true},
+		{"8.8.8.8", false},
+		{"2001:db8::1", false},
+		{"100.64.0.1", false}, // Carrier-grade NAT is shared beyond the LAN
+	}
+	for _, tt := range tests {
+		if got := IsLANAddress(net.ParseIP(tt.addr)); got != tt.want {
+			t.Errorf("IsLANAddress(%s) = %v, want %v", tt.addr, got, tt.want)
+		}
+	}
+	if IsLANAddress(nil) {
+		t.Error("IsLANAddress(nil) = true, want false")
+	}
+}
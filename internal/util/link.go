@@ -0,0 +1,141 @@
+package util
+
+import (
+	"net"
+	"strings"
+)
+
+// LinkType is the kind of physical link behind a network interface
+type LinkType int
+
+const (
+	LinkUnknown LinkType = iota
+	LinkWired
+	LinkWireless
+)
+
+func (t LinkType) String() string {
+	switch t {
+	case LinkWired:
+		return "wired"
+	case LinkWireless:
+		return "Wi-Fi"
+	}
+	return "unknown"
+}
+
+// InterfaceByIP returns the interface holding ip, if one of this machine's does
+func InterfaceByIP(ip net.IP) (net.Interface, bool) {
+	if ip == nil {
+		return net.Interface{}, false
+	}
+	interfaces, err := net.Interfaces()
+	if err != nil {
+		return net.Interface{}, false
+	}
+	for _, iface := range interfaces {
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.Equal(ip) {
+				return iface, true
+			}
+		}
+	}
+	return net.Interface{}, false
+}
+
+// RouteInterface returns the interface this machine sends packets to remote from. No packet is
+// sent to find it.
+func RouteInterface(remote net.IP) (net.Interface, bool) {
+	if remote == nil {
+		return net.Interface{}, false
+	}
+	conn, err := net.DialUDP("udp", nil, &net.UDPAddr{IP: remote, Port: 9})
+	if err != nil {
+		return net.Interface{}, false
+	}
+	defer conn.Close()
+	return InterfaceByIP(conn.LocalAddr().(*net.UDPAddr).IP)
+}
+
+// LANInterfaces returns the interfaces that are up and hold a private LAN address, which is
+// not link-local
+func LANInterfaces() []net.Interface {
+	interfaces, err := net.Interfaces()
+	if err != nil {
+		return nil
+	}
+	var lan []net.Interface
+	for _, iface := range interfaces {
+		if len(lanAddresses(iface)) > 0 {
+			lan = append(lan, iface)
+		}
+	}
+	return lan
+}
+
+// LANAddresses returns the private LAN addresses of the LANInterfaces, IPv4 addresses first,
+// where peers on the network can reach this machine
+func LANAddresses() []net.IP {
+	var v4, v6 []net.IP
+	for _, iface := range LANInterfaces() {
+		for _, ip := range lanAddresses(iface) {
+			if ip.To4() != nil {
+				v4 = append(v4, ip)
+			} else {
+				v6 = append(v6, ip)
+			}
+		}
+	}
+	return append(v4, v6...)
+}
+
+// lanAddresses returns the private addresses of iface that are not link-local, none if it is
+// down or the loopback interface
+func lanAddresses(iface net.Interface) []net.IP {
+	if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 {
+		return nil
+	}
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return nil
+	}
+	var lan []net.IP
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok && IsLANAddress(ipNet.IP) && !ipNet.IP.IsLinkLocalUnicast() {
+			lan = append(lan, ipNet.IP)
+		}
+	}
+	return lan
+}
+
+// WiredInterfaces returns the LANInterfaces on a wired link
+func WiredInterfaces() []net.Interface {
+	var wired []net.Interface
+	for _, iface := range LANInterfaces() {
+		if InterfaceLink(iface.Name) == LinkWired {
+			wired = append(wired, iface)
+		}
+	}
+	return wired
+}
+
+// linkByName guesses the link of an interface from its name, such as "Wi-Fi" or "Ethernet 2"
+// on Windows and "wlan0" or "eth0" elsewhere
+func linkByName(name string) LinkType {
+	lower := strings.ToLower(name)
+	for _, prefix := range []string{"wi-fi", "wifi", "wireless", "wl"} {
+		if strings.HasPrefix(lower, prefix) {
+			return LinkWireless
+		}
+	}
+	for _, prefix := range []string{"ethernet", "eth", "enp", "eno", "ens", "enx"} {
+		if strings.HasPrefix(lower, prefix) {
+			return LinkWired
+		}
+	}
+	return LinkUnknown
+}
@@ -0,0 +1,36 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// sysClassNet is where Linux describes network interfaces
+const sysClassNet = "/sys/class/net"
+
+// InterfaceLink reports the link of the interface named name. Wireless interfaces have a
+// wireless directory in sysfs, wired ones are Ethernet devices without it; virtual
+// interfaces such as bridges and VPN tunnels are unknown.
+func InterfaceLink(name string) LinkType {
+	dir := filepath.Join(sysClassNet, name)
+	if !exists(dir) {
+		// sysfs of another network namespace, e.g. in a container
+		return linkByName(name)
+	}
+	if exists(filepath.Join(dir, "wireless")) || exists(filepath.Join(dir, "phy80211")) {
+		return LinkWireless
+	}
+	if !exists(filepath.Join(dir, "device")) {
+		return LinkUnknown
+	}
+	if kind, err := os.ReadFile(filepath.Join(dir, "type")); err == nil && strings.TrimSpace(string(kind)) == "1" {
+		return LinkWired
+	}
+	return LinkUnknown
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
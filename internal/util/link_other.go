@@ -0,0 +1,9 @@
+//go:build !linux
+
+package util
+
+// InterfaceLink reports the link of the interface named name, judged by its name as there
+// is no portable way to ask; names that tell nothing, like "en0" on macOS, are unknown
+func InterfaceLink(name string) LinkType {
+	return linkByName(name)
+}
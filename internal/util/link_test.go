@@ -0,0 +1,57 @@
+package util
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLinkByName(t *testing.T) {
+	for name, expected := range map[string]LinkType{
+		"Wi-Fi":      LinkWireless,
+		"wlan0":      LinkWireless,
+		"wlp3s0":     LinkWireless,
+		"Ethernet 2": LinkWired,
+		"eth0":       LinkWired,
+		"enp0s31f6":  LinkWired,
+		"en0":        LinkUnknown,
+		"tailscale0": LinkUnknown,
+	} {
+		assert.Equal(t, expected, linkByName(name), name)
+	}
+}
+
+func TestInterfaceByIP(t *testing.T) {
+	iface, ok := InterfaceByIP(net.ParseIP("127.0.0.1"))
+	if assert.True(t, ok, "loopback should be found") {
+		assert.NotZero(t, iface.Flags&net.FlagLoopback)
+	}
+	_, ok = InterfaceByIP(net.ParseIP("192.0.2.1"))
+	assert.False(t, ok, "a documentation address is on no interface")
+	_, ok = InterfaceByIP(nil)
+	assert.False(t, ok)
+}
+
+func TestRouteInterface(t *testing.T) {
+	iface, ok := RouteInterface(net.ParseIP("127.0.0.1"))
+	if assert.True(t, ok, "loopback routes over the loopback interface") {
+		assert.NotZero(t, iface.Flags&net.FlagLoopback)
+	}
+	_, ok = RouteInterface(nil)
+	assert.False(t, ok)
+}
+
+func TestLANAddresses(t *testing.T) {
+	addrs := LANAddresses()
+	sawV6 := false
+	for _, ip := range addrs {
+		assert.True(t, IsLANAddress(ip), ip.String())
+		assert.False(t, ip.IsLoopback() || ip.IsLinkLocalUnicast(), ip.String())
+		if ip.To4() == nil {
+			sawV6 = true
+		} else {
+			assert.False(t, sawV6, "IPv4 addresses come first")
+		}
+	}
+}
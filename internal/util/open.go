@@ -0,0 +1,28 @@
+package util
+
+import (
+	"fmt"
+	"os/exec"
+	"runtime"
+)
+
+// openCommand returns the OS default handler command for opening path
+func openCommand(goos, path string) (string, []string) {
+	switch goos {
+	case "darwin":
+		return "open", []string{path}
+	case "windows":
+		return "cmd", []string{"/c", "start", "", path}
+	default:
+		return "xdg-open", []string{path}
+	}
+}
+
+// OpenPath opens a file or directory with the OS default handler
+func OpenPath(path string) error {
+	name, args := openCommand(runtime.GOOS, path)
+	if err := exec.Command(name, args...).Start(); err != nil {
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	return nil
+}
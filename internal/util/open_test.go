@@ -0,0 +1,31 @@
+package util
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestOpenCommand(t *testing.T) {
+	tests := []struct {
+		goos         string
+		expectedName string
+		expectedArgs []string
+	}{
+		{"linux", "xdg-open", []string{"/tmp/out"}},
+		{"freebsd", "xdg-open", []string{"/tmp/out"}},
+		{"darwin", "open", []string{"/tmp/out"}},
+		{"windows", "cmd", []string{"/c", "start", "", "/tmp/out"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.goos, func(t *testing.T) {
+			name, args := openCommand(tt.goos, "/tmp/out")
+			if name != tt.expectedName {
+				t.Errorf("openCommand(%q) name = %q, want %q", tt.goos, name, tt.expectedName)
+			}
+			if !reflect.DeepEqual(args, tt.expectedArgs) {
+				t.Errorf("openCommand(%q) args = %v, want %v", tt.goos, args, tt.expectedArgs)
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// PowerSource is what this machine currently runs on
+type PowerSource int
+
+const (
+	PowerUnknown PowerSource = iota
+	PowerAC
+	PowerBattery
+)
+
+func (p PowerSource) String() string {
+	switch p {
+	case PowerAC:
+		return "AC power"
+	case PowerBattery:
+		return "battery"
+	}
+	return "unknown"
+}
+
+// sysClassPowerSupply is where Linux describes batteries and power adapters
+const sysClassPowerSupply = "/sys/class/power_supply"
+
+// powerSourceFromSysfs reads the power supplies in dir: any online adapter means AC power,
+// a discharging battery without one means battery, and machines without a battery are on AC
+func powerSourceFromSysfs(dir string) PowerSource {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return PowerUnknown
+	}
+	read := func(supply, name string) string {
+		value, _ := os.ReadFile(filepath.Join(dir, supply, name))
+		return strings.TrimSpace(string(value))
+	}
+	source := PowerAC
+	for _, entry := range entries {
+		supply := entry.Name()
+		switch read(supply, "type") {
+		case "Mains", "USB", "USB_C", "USB_PD":
+			if read(supply, "online") == "1" {
+				return PowerAC
+			}
+		case "Battery":
+			// Device batteries, e.g. of a wireless mouse, do not power this machine
+			if read(supply, "scope") != "Device" && read(supply, "status") == "Discharging" {
+				source = PowerBattery
+			}
+		}
+	}
+	return source
+}
+
+// parsePmset reads the output of macOS's "pmset -g batt", whose first line names the source
+// drawn from, e.g. "Now drawing from 'Battery Power'"
+func parsePmset(output string) PowerSource {
+	first, _, _ := strings.Cut(output, "\n")
+	switch {
+	case strings.Contains(first, "'Battery Power'"):
+		return PowerBattery
+	case strings.Contains(first, "'AC Power'"), strings.Contains(first, "'UPS Power'"):
+		return PowerAC
+	}
+	return PowerUnknown
+}
@@ -0,0 +1,12 @@
+package util
+
+import "os/exec"
+
+// CurrentPowerSource reports whether this machine runs on battery, as pmset tells
+func CurrentPowerSource() PowerSource {
+	output, err := exec.Command("pmset", "-g", "batt").Output()
+	if err != nil {
+		return PowerUnknown
+	}
+	return parsePmset(string(output))
+}
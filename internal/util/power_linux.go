@@ -0,0 +1,7 @@
+package util
+
+// CurrentPowerSource reports whether this machine runs on battery, from the power supplies
+// in sysfs
+func CurrentPowerSource() PowerSource {
+	return powerSourceFromSysfs(sysClassPowerSupply)
+}
@@ -0,0 +1,8 @@
+//go:build !linux && !darwin && !windows
+
+package util
+
+// CurrentPowerSource cannot tell the power source on this platform
+func CurrentPowerSource() PowerSource {
+	return PowerUnknown
+}
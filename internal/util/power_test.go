@@ -0,0 +1,41 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeSupply(t *testing.T, dir, name string, values map[string]string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, name), 0755))
+	for key, value := range values {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name, key), []byte(value+"\n"), 0644))
+	}
+}
+
+func TestPowerSourceFromSysfs(t *testing.T) {
+	desktop := t.TempDir()
+	assert.Equal(t, PowerAC, powerSourceFromSysfs(desktop), "no battery")
+	writeSupply(t, desktop, "hidpp_battery_0", map[string]string{"type": "Battery", "scope": "Device", "status": "Discharging"})
+	assert.Equal(t, PowerAC, powerSourceFromSysfs(desktop), "a mouse battery does not power the machine")
+
+	laptop := t.TempDir()
+	writeSupply(t, laptop, "BAT0", map[string]string{"type": "Battery", "status": "Discharging"})
+	writeSupply(t, laptop, "AC", map[string]string{"type": "Mains", "online": "0"})
+	assert.Equal(t, PowerBattery, powerSourceFromSysfs(laptop))
+
+	writeSupply(t, laptop, "AC", map[string]string{"online": "1"})
+	assert.Equal(t, PowerAC, powerSourceFromSysfs(laptop))
+
+	assert.Equal(t, PowerUnknown, powerSourceFromSysfs(filepath.Join(laptop, "missing")))
+}
+
+func TestParsePmset(t *testing.T) {
+	assert.Equal(t, PowerBattery, parsePmset("Now drawing from 'Battery Power'\n -InternalBattery-0 (id=1234)\t85%; discharging; 4:12 remaining present: true\n"))
+	assert.Equal(t, PowerAC, parsePmset("Now drawing from 'AC Power'\n -InternalBattery-0 (id=1234)\t100%; charged; 0:00 remaining present: true\n"))
+	assert.Equal(t, PowerUnknown, parsePmset(""))
+}
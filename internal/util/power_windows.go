@@ -0,0 +1,34 @@
+package util
+
+import (
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var procGetSystemPowerStatus = windows.NewLazySystemDLL("kernel32.dll").NewProc("GetSystemPowerStatus")
+
+// systemPowerStatus is SYSTEM_POWER_STATUS
+type systemPowerStatus struct {
+	ACLineStatus        byte
+	BatteryFlag         byte
+	BatteryLifePercent  byte
+	SystemStatusFlag    byte
+	BatteryLifeTime     uint32
+	BatteryFullLifeTime uint32
+}
+
+// CurrentPowerSource reports whether this machine runs on battery, as GetSystemPowerStatus tells
+func CurrentPowerSource() PowerSource {
+	var status systemPowerStatus
+	if ok, _, _ := procGetSystemPowerStatus.Call(uintptr(unsafe.Pointer(&status))); ok == 0 {
+		return PowerUnknown
+	}
+	switch status.ACLineStatus {
+	case 0:
+		return PowerBattery
+	case 1:
+		return PowerAC
+	}
+	return PowerUnknown
+}
@@ -0,0 +1,18 @@
+// Package version reports the version of the running binary
+package version
+
+import "runtime/debug"
+
+// Version is set at build time with -ldflags "-X github.com/rescp17/lanFileSharer/internal/version.Version=v1.2.3"
+var Version = ""
+
+// String returns Version, the module version recorded by go install, or "dev"
+func String() string {
+	if Version != "" {
+		return Version
+	}
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+	return "dev"
+}
@@ -0,0 +1,185 @@
+// Package audit keeps an append-only log of transfer sessions in which every entry carries
+// the hash of the one before it, so edits and deletions inside the log can be detected.
+package audit
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// FileName is the audit log file in the application config directory
+const FileName = "audit.log"
+
+// genesisHash is the previous hash of the first entry
+var genesisHash = hex.EncodeToString(make([]byte, sha256.Size))
+
+// ErrTampered is returned by Verify when an entry does not match the chain
+var ErrTampered = errors.New("audit log chain is broken")
+
+// Event is what happened in an audit entry
+type Event string
+
+const (
+	EventRequest   Event = "request"   // A sender asked to send files
+	EventRefused   Event = "refused"   // The peer filter refused the sender
+	EventDeclined  Event = "declined"  // The request was declined without asking, e.g. in do not disturb mode
+	EventAccepted  Event = "accepted"  // The user accepted the request
+	EventRejected  Event = "rejected"  // The user rejected the request
+	EventTimedOut  Event = "timed_out" // The user did not answer in time
+	EventCompleted Event = "completed" // Every file was received
+	EventFailed    Event = "failed"    // The transfer ended with an error
+)
+
+// Record describes an event to log
+type Record struct {
+	Event       Event  `json:"event"`
+	Peer        string `json:"peer,omitempty"`        // Address of the sender
+	Fingerprint string `json:"fingerprint,omitempty"` // Sender key fingerprint
+	Files       int    `json:"files,omitempty"`
+	Bytes       int64  `json:"bytes,omitempty"`
+	Detail      string `json:"detail,omitempty"`
+}
+
+// As returns a copy of r for event, with detail if it is set
+func (r Record) As(event Event, detail string) Record {
+	r.Event = event
+	if detail != "" {
+		r.Detail = detail
+	}
+	return r
+}
+
+// Entry is a logged record with its position in the chain
+type Entry struct {
+	Seq  int64     `json:"seq"`
+	Time time.Time `json:"time"`
+	Record
+	PrevHash string `json:"prev_hash"`
+	Hash     string `json:"hash"`
+}
+
+// computeHash hashes e without its own hash
+func (e Entry) computeHash() (string, error) {
+	e.Hash = ""
+	data, err := json.Marshal(e)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal audit entry: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Log appends hash-chained entries to a file. A nil Log records nothing.
+type Log struct {
+	path string
+	now  func() time.Time
+
+	mu       sync.Mutex
+	seq      int64
+	lastHash string
+}
+
+// Open opens the audit log at path, continuing the chain of its existing entries.
+// It fails if the existing entries are not a valid chain, so a tampered log is noticed.
+func Open(path string) (*Log, error) {
+	log := &Log{path: path, now: time.Now, lastHash: genesisHash}
+
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return log, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer file.Close()
+
+	entries, err := Verify(file)
+	if err != nil {
+		return nil, fmt.Errorf("audit log %s: %w", path, err)
+	}
+	if len(entries) > 0 {
+		last := entries[len(entries)-1]
+		log.seq, log.lastHash = last.Seq, last.Hash
+	}
+	return log, nil
+}
+
+// Record appends record to the log
+func (l *Log) Record(record Record) error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entry := Entry{Seq: l.seq + 1, Time: l.now().UTC(), Record: record, PrevHash: l.lastHash}
+	hash, err := entry.computeHash()
+	if err != nil {
+		return err
+	}
+	entry.Hash = hash
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit entry: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(l.path), 0700); err != nil {
+		return fmt.Errorf("failed to create audit log directory: %w", err)
+	}
+	file, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to close audit log: %w", err)
+	}
+	l.seq, l.lastHash = entry.Seq, entry.Hash
+	return nil
+}
+
+// Verify reads the entries of an audit log and checks that each one follows the one
+// before it. It returns the entries read before the first broken link with ErrTampered.
+// Entries removed from the end of the log cannot be detected from the log alone.
+func Verify(r io.Reader) ([]Entry, error) {
+	var entries []Entry
+	prevHash, seq := genesisHash, int64(0)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return entries, fmt.Errorf("%w: line %d is not an entry: %w", ErrTampered, line, err)
+		}
+		hash, err := entry.computeHash()
+		if err != nil {
+			return entries, err
+		}
+		switch {
+		case entry.Seq != seq+1:
+			return entries, fmt.Errorf("%w: line %d has sequence %d, expected %d", ErrTampered, line, entry.Seq, seq+1)
+		case entry.PrevHash != prevHash:
+			return entries, fmt.Errorf("%w: line %d does not follow the entry before it", ErrTampered, line)
+		case entry.Hash != hash:
+			return entries, fmt.Errorf("%w: line %d was modified", ErrTampered, line)
+		}
+		entries = append(entries, entry)
+		prevHash, seq = entry.Hash, entry.Seq
+	}
+	if err := scanner.Err(); err != nil {
+		return entries, fmt.Errorf("failed to read audit log: %w", err)
+	}
+	return entries, nil
+}
@@ -0,0 +1,73 @@
+package audit
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLog_RecordAndVerify(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit", FileName)
+	log, err := Open(path)
+	require.NoError(t, err)
+	require.NoError(t, log.Record(Record{Event: EventRequest, Peer: "192.168.1.5", Files: 2, Bytes: 100}))
+	require.NoError(t, log.Record(Record{Event: EventAccepted, Peer: "192.168.1.5"}))
+
+	// Reopening continues the chain
+	log, err = Open(path)
+	require.NoError(t, err)
+	require.NoError(t, log.Record(Record{Event: EventCompleted, Peer: "192.168.1.5"}))
+
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+	entries, err := Verify(file)
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	assert.Equal(t, int64(3), entries[2].Seq)
+	assert.Equal(t, EventCompleted, entries[2].Event)
+	assert.Equal(t, entries[1].Hash, entries[2].PrevHash)
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+}
+
+func TestVerify_DetectsTampering(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	log, err := Open(path)
+	require.NoError(t, err)
+	for _, event := range []Event{EventRequest, EventRejected, EventRequest} {
+		require.NoError(t, log.Record(Record{Event: event, Peer: "10.0.0.2"}))
+	}
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	lines := strings.SplitAfter(strings.TrimSuffix(string(data), "\n"), "\n")
+
+	modified := strings.Replace(string(data), `"event":"rejected"`, `"event":"accepted"`, 1)
+	entries, err := Verify(strings.NewReader(modified))
+	assert.ErrorIs(t, err, ErrTampered)
+	assert.Len(t, entries, 1, "entries before the modified one are returned")
+
+	deleted := lines[0] + lines[2]
+	_, err = Verify(strings.NewReader(deleted))
+	assert.ErrorIs(t, err, ErrTampered)
+
+	require.NoError(t, os.WriteFile(path, []byte(modified), 0600))
+	_, err = Open(path)
+	assert.ErrorIs(t, err, ErrTampered, "a tampered log is not continued")
+}
+
+func TestLog_NilRecordsNothing(t *testing.T) {
+	var log *Log
+	assert.NoError(t, log.Record(Record{Event: EventRequest}))
+
+	entries, err := Verify(&bytes.Buffer{})
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+}
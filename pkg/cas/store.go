@@ -0,0 +1,253 @@
+// Package cas keeps received files in a content-addressable layout. Every distinct content
+// is stored once, as an object named by its SHA-256, and the files of the received tree are
+// hard links to the objects. A file received again, under any name or from any sender, then
+// takes neither disk space nor a transfer.
+package cas
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Dir is the hidden directory of the store inside the output directory
+const Dir = ".cas"
+
+const (
+	objectsDir = "objects"
+	// indexFile maps the files of the tree to their objects, so the tree can be exported
+	// even after its hard links were changed or removed
+	indexFile = "tree.json"
+)
+
+// ErrInvalidChecksum is returned for object names that are not SHA-256 hex digests
+var ErrInvalidChecksum = errors.New("invalid checksum")
+
+// Entry is a file of the received tree
+type Entry struct {
+	Path     string    `json:"path"` // Relative to the output directory, with forward slashes
+	Checksum string    `json:"checksum"`
+	Size     int64     `json:"size"`
+	Received time.Time `json:"received"`
+}
+
+// Store is the content-addressable store of an output directory
+type Store struct {
+	root string // Output directory the tree is materialized in
+	mu   sync.Mutex
+	tree map[string]Entry
+}
+
+// Open opens the store of the output directory root; a directory without one yields an empty store
+func Open(root string) (*Store, error) {
+	store := &Store{root: root, tree: make(map[string]Entry)}
+	data, err := os.ReadFile(store.indexPath())
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return store, nil
+		}
+		return nil, fmt.Errorf("failed to read content store index: %w", err)
+	}
+	var entries []Entry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse content store index %s: %w", store.indexPath(), err)
+	}
+	for _, entry := range entries {
+		store.tree[entry.Path] = entry
+	}
+	return store, nil
+}
+
+func (s *Store) indexPath() string {
+	return filepath.Join(s.root, Dir, indexFile)
+}
+
+// ObjectPath returns where the object with checksum is stored
+func (s *Store) ObjectPath(checksum string) (string, error) {
+	if b, err := hex.DecodeString(checksum); err != nil || len(b) != 32 || strings.ToLower(checksum) != checksum {
+		return "", fmt.Errorf("%w: %q", ErrInvalidChecksum, checksum)
+	}
+	return filepath.Join(s.root, Dir, objectsDir, checksum[:2], checksum), nil
+}
+
+// Has reports whether the store holds the object with checksum
+func (s *Store) Has(checksum string) bool {
+	object, err := s.ObjectPath(checksum)
+	if err != nil {
+		return false
+	}
+	info, err := os.Stat(object)
+	return err == nil && info.Mode().IsRegular()
+}
+
+// Add stores the verified file at path, in the output directory, under checksum. A file whose
+// content is already stored is replaced with a hard link to the object, otherwise the file
+// becomes the object. The file is then recorded in the tree.
+func (s *Store) Add(path, checksum string) error {
+	rel, err := filepath.Rel(s.root, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("%s is outside the output directory", path)
+	}
+	object, err := s.ObjectPath(checksum)
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	objectInfo, err := os.Stat(object)
+	switch {
+	case err == nil && os.SameFile(info, objectInfo):
+	case err == nil:
+		if err := replaceWithLink(object, path); err != nil {
+			return fmt.Errorf("failed to link %s to its stored content: %w", rel, err)
+		}
+	case errors.Is(err, os.ErrNotExist):
+		if err := storeObject(path, object); err != nil {
+			return fmt.Errorf("failed to store %s: %w", rel, err)
+		}
+	default:
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rel = filepath.ToSlash(rel)
+	s.tree[rel] = Entry{Path: rel, Checksum: checksum, Size: info.Size(), Received: time.Now()}
+	return s.saveLocked()
+}
+
+// storeObject makes the file at path the object, by hard link or where the filesystem cannot
+// link, by copy
+func storeObject(path, object string) error {
+	if err := os.MkdirAll(filepath.Dir(object), 0755); err != nil {
+		return err
+	}
+	if err := os.Link(path, object); err == nil {
+		return nil
+	} else {
+		slog.Debug("Hard link not possible, copying into the content store", "path", path, "error", err)
+	}
+	tmp := object + ".tmp"
+	if err := copyFile(path, tmp); err != nil {
+		return err
+	}
+	return os.Rename(tmp, object)
+}
+
+// replaceWithLink replaces the file at path with a hard link to object, in one step so the
+// file never goes missing
+func replaceWithLink(object, path string) error {
+	tmp := path + ".cas-link"
+	if err := os.Link(object, tmp); err != nil {
+		// Without hard links the file stays a copy of the object
+		slog.Debug("Hard link not possible, keeping the copy", "path", path, "error", err)
+		return nil
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// Entries returns the files of the tree, sorted by path
+func (s *Store) Entries() []Entry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entries := make([]Entry, 0, len(s.tree))
+	for _, entry := range s.tree {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Path < entries[j].Path })
+	return entries
+}
+
+// Materialize exports the tree to dest as ordinary files, copies that can be changed without
+// touching the store. It returns the number of files written.
+func (s *Store) Materialize(dest string) (int, error) {
+	written := 0
+	for _, entry := range s.Entries() {
+		object, err := s.ObjectPath(entry.Checksum)
+		if err != nil {
+			return written, fmt.Errorf("%s: %w", entry.Path, err)
+		}
+		target := filepath.Join(dest, filepath.FromSlash(entry.Path))
+		if !strings.HasPrefix(target, filepath.Clean(dest)+string(filepath.Separator)) {
+			return written, fmt.Errorf("invalid path in content store index: %s", entry.Path)
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return written, err
+		}
+		if err := copyFile(object, target); err != nil {
+			return written, fmt.Errorf("failed to export %s: %w", entry.Path, err)
+		}
+		written++
+	}
+	return written, nil
+}
+
+// saveLocked writes the index, replacing the file in one step; s.mu must be held
+func (s *Store) saveLocked() error {
+	entries := make([]Entry, 0, len(s.tree))
+	for _, entry := range s.tree {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Path < entries[j].Path })
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal content store index: %w", err)
+	}
+
+	path := s.indexPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create content store directory: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write content store index: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write content store index: %w", err)
+	}
+	return nil
+}
+
+// copyFile copies src to dst, replacing dst. An existing dst is removed rather than
+// truncated, since it may be a hard link to an object.
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	if err := os.Remove(dst); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return err
+	}
+	return out.Close()
+}
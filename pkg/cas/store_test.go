@@ -0,0 +1,100 @@
+package cas
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func checksumOf(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+func TestStore_AddDeduplicates(t *testing.T) {
+	root := t.TempDir()
+	store, err := Open(root)
+	require.NoError(t, err)
+
+	content := []byte("received twice")
+	checksum := checksumOf(content)
+	assert.False(t, store.Has(checksum))
+
+	first := filepath.Join(root, "first.txt")
+	second := filepath.Join(root, "2024", "second.txt")
+	require.NoError(t, os.WriteFile(first, content, 0644))
+	require.NoError(t, os.MkdirAll(filepath.Dir(second), 0755))
+	require.NoError(t, os.WriteFile(second, content, 0644))
+
+	require.NoError(t, store.Add(first, checksum))
+	require.NoError(t, store.Add(second, checksum))
+	assert.True(t, store.Has(checksum))
+
+	object, err := store.ObjectPath(checksum)
+	require.NoError(t, err)
+	objectInfo, err := os.Stat(object)
+	require.NoError(t, err)
+	for _, path := range []string{first, second} {
+		info, err := os.Stat(path)
+		require.NoError(t, err)
+		assert.True(t, os.SameFile(objectInfo, info), "%s is a link to the object", path)
+	}
+
+	// The index survives reopening
+	reopened, err := Open(root)
+	require.NoError(t, err)
+	entries := reopened.Entries()
+	require.Len(t, entries, 2)
+	assert.Equal(t, "2024/second.txt", entries[0].Path)
+	assert.Equal(t, "first.txt", entries[1].Path)
+	assert.Equal(t, checksum, entries[1].Checksum)
+	assert.Equal(t, int64(len(content)), entries[1].Size)
+}
+
+func TestStore_Materialize(t *testing.T) {
+	root := t.TempDir()
+	store, err := Open(root)
+	require.NoError(t, err)
+
+	content := []byte("exported")
+	path := filepath.Join(root, "docs", "a.txt")
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, os.WriteFile(path, content, 0644))
+	require.NoError(t, store.Add(path, checksumOf(content)))
+	// The tree file is gone, the object is not
+	require.NoError(t, os.Remove(path))
+
+	dest := t.TempDir()
+	written, err := store.Materialize(dest)
+	require.NoError(t, err)
+	assert.Equal(t, 1, written)
+
+	exported := filepath.Join(dest, "docs", "a.txt")
+	saved, err := os.ReadFile(exported)
+	require.NoError(t, err)
+	assert.Equal(t, content, saved)
+
+	// Exported files are copies, changing one leaves the store intact
+	require.NoError(t, os.WriteFile(exported, []byte("changed"), 0644))
+	object, err := store.ObjectPath(checksumOf(content))
+	require.NoError(t, err)
+	stored, err := os.ReadFile(object)
+	require.NoError(t, err)
+	assert.Equal(t, content, stored)
+}
+
+func TestStore_ObjectPath(t *testing.T) {
+	store, err := Open(t.TempDir())
+	require.NoError(t, err)
+
+	for _, checksum := range []string{"", "../../etc/passwd", "abc", checksumOf(nil)[:63] + "G"} {
+		_, err := store.ObjectPath(checksum)
+		assert.ErrorIs(t, err, ErrInvalidChecksum, checksum)
+		assert.False(t, store.Has(checksum))
+	}
+}
@@ -0,0 +1,142 @@
+package crypto
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// SignatureAlgorithm identifies how a structure was signed
+type SignatureAlgorithm string
+
+const (
+	// AlgorithmRSA is RSA PKCS#1 v1.5 over SHA-256, understood by every receiver
+	AlgorithmRSA SignatureAlgorithm = "rsa-pkcs1v15-sha256"
+	// AlgorithmEd25519 is Ed25519 over the SHA-256 digest of the signed data
+	AlgorithmEd25519 SignatureAlgorithm = "ed25519"
+
+	// DefaultSignatureAlgorithm is used for new keys unless configured otherwise
+	DefaultSignatureAlgorithm = AlgorithmEd25519
+)
+
+// ErrUnsupportedAlgorithm is returned for signature algorithms this build cannot verify
+var ErrUnsupportedAlgorithm = errors.New("unsupported signature algorithm")
+
+// ParseSignatureAlgorithm parses a configured algorithm name; "rsa" and "ed25519" are accepted
+func ParseSignatureAlgorithm(name string) (SignatureAlgorithm, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "":
+		return DefaultSignatureAlgorithm, nil
+	case "ed25519":
+		return AlgorithmEd25519, nil
+	case "rsa", string(AlgorithmRSA):
+		return AlgorithmRSA, nil
+	default:
+		return "", fmt.Errorf("%w: %q", ErrUnsupportedAlgorithm, name)
+	}
+}
+
+// normalize maps the empty algorithm of structures from older senders to RSA
+func (a SignatureAlgorithm) normalize() SignatureAlgorithm {
+	if a == "" {
+		return AlgorithmRSA
+	}
+	return a
+}
+
+// signedName is the algorithm recorded in signed data. RSA is left out so RSA
+// signatures stay identical to those made before algorithms were recorded.
+func (a SignatureAlgorithm) signedName() SignatureAlgorithm {
+	if a.normalize() == AlgorithmRSA {
+		return ""
+	}
+	return a
+}
+
+// parsePublicKey parses a DER encoded public key and checks that it fits algorithm
+func parsePublicKey(publicKey []byte, algorithm SignatureAlgorithm) (crypto.PublicKey, error) {
+	publicKeyInterface, err := x509.ParsePKIXPublicKey(publicKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse public key: %w", err)
+	}
+
+	switch algorithm.normalize() {
+	case AlgorithmRSA:
+		if _, ok := publicKeyInterface.(*rsa.PublicKey); !ok {
+			return nil, fmt.Errorf("public key is not RSA")
+		}
+	case AlgorithmEd25519:
+		if _, ok := publicKeyInterface.(ed25519.PublicKey); !ok {
+			return nil, fmt.Errorf("public key is not Ed25519")
+		}
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedAlgorithm, algorithm)
+	}
+	return publicKeyInterface, nil
+}
+
+// verifyDigest verifies signature over a SHA-256 digest with a key returned by parsePublicKey
+func verifyDigest(publicKey crypto.PublicKey, digest []byte, signature []byte) error {
+	switch key := publicKey.(type) {
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(key, crypto.SHA256, digest, signature)
+	case ed25519.PublicKey:
+		if !ed25519.Verify(key, digest, signature) {
+			return errors.New("ed25519: invalid signature")
+		}
+		return nil
+	default:
+		return fmt.Errorf("%w: %T", ErrUnsupportedAlgorithm, publicKey)
+	}
+}
+
+// signDigest signs a SHA-256 digest with an RSA or Ed25519 private key
+func signDigest(privateKey crypto.Signer, digest []byte) ([]byte, error) {
+	switch key := privateKey.(type) {
+	case *rsa.PrivateKey:
+		return rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest)
+	case ed25519.PrivateKey:
+		return ed25519.Sign(key, digest), nil
+	default:
+		return nil, fmt.Errorf("%w: %T", ErrUnsupportedAlgorithm, privateKey)
+	}
+}
+
+// Ed25519PrivateKeyToPEM converts an Ed25519 private key to PKCS#8 PEM format
+func Ed25519PrivateKeyToPEM(privateKey ed25519.PrivateKey) ([]byte, error) {
+	privateKeyBytes, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal private key: %w", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{
+		Type:  "PRIVATE KEY",
+		Bytes: privateKeyBytes,
+	}), nil
+}
+
+// Ed25519PrivateKeyFromPEM parses an Ed25519 private key from PKCS#8 PEM format
+func Ed25519PrivateKeyFromPEM(pemData []byte) (ed25519.PrivateKey, error) {
+	block, _ := pem.Decode(pemData)
+	if block == nil {
+		return nil, fmt.Errorf("failed to decode PEM block")
+	}
+	if block.Type != "PRIVATE KEY" {
+		return nil, fmt.Errorf("invalid PEM block type: %s", block.Type)
+	}
+
+	privateKey, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key: %w", err)
+	}
+	edKey, ok := privateKey.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("private key is not Ed25519")
+	}
+	return edKey, nil
+}
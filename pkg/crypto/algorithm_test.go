@@ -0,0 +1,92 @@
+package crypto
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rescp17/lanFileSharer/pkg/transfer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestManager(t *testing.T) *transfer.FileStructureManager {
+	t.Helper()
+	tempDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "a.txt"), []byte("a"), 0644))
+	fsm := transfer.NewFileStructureManager()
+	require.NoError(t, fsm.AddPath(tempDir))
+	return fsm
+}
+
+func TestParseSignatureAlgorithm(t *testing.T) {
+	for name, expected := range map[string]SignatureAlgorithm{
+		"":        DefaultSignatureAlgorithm,
+		"ed25519": AlgorithmEd25519,
+		"RSA":     AlgorithmRSA,
+	} {
+		algorithm, err := ParseSignatureAlgorithm(name)
+		require.NoError(t, err)
+		assert.Equal(t, expected, algorithm, "name %q", name)
+	}
+
+	_, err := ParseSignatureAlgorithm("dsa")
+	assert.ErrorIs(t, err, ErrUnsupportedAlgorithm)
+}
+
+func TestSignAndVerifyEd25519(t *testing.T) {
+	signer, err := NewEd25519FileStructureSigner()
+	require.NoError(t, err)
+	assert.Nil(t, signer.GetPublicKey())
+
+	signed, err := signer.SignFileStructureManager(newTestManager(t))
+	require.NoError(t, err)
+	assert.Equal(t, AlgorithmEd25519, signed.Algorithm)
+	require.NoError(t, VerifyFileStructure(signed))
+
+	// The algorithm survives the JSON round trip of the /ask request
+	data, err := json.Marshal(signed)
+	require.NoError(t, err)
+	var decoded SignedFileStructure
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	require.NoError(t, VerifyFileStructure(&decoded))
+
+	// Relabelling the signature as RSA must not verify
+	decoded.Algorithm = AlgorithmRSA
+	assert.Error(t, VerifyFileStructure(&decoded))
+}
+
+func TestVerifyLegacyRSAStructure(t *testing.T) {
+	signer, err := NewFileStructureSigner()
+	require.NoError(t, err)
+	signed, err := signer.SignFileStructureManager(newTestManager(t))
+	require.NoError(t, err)
+	assert.Equal(t, AlgorithmRSA, signed.Algorithm)
+
+	// Older senders do not send an algorithm and older receivers ignore it
+	signed.Algorithm = ""
+	assert.NoError(t, VerifyFileStructure(signed))
+
+	signed.Algorithm = "dsa"
+	assert.ErrorIs(t, VerifyFileStructure(signed), ErrUnsupportedAlgorithm)
+}
+
+func TestStructureChain_Ed25519(t *testing.T) {
+	fsm := newTestManager(t)
+	signer, err := NewEd25519FileStructureSigner()
+	require.NoError(t, err)
+	base, err := signer.SignFileStructureManager(fsm)
+	require.NoError(t, err)
+
+	chain, err := NewStructureChain(base)
+	require.NoError(t, err)
+	collector := &changeCollector{}
+	fsm.AddStructureListener(collector)
+
+	require.NoError(t, fsm.RemoveFileNode(fsm.GetAllFiles()[0]))
+	delta, err := NewStructureDeltaSigner(signer, base).Sign(DeltaChangesFromStructure(collector.drain()))
+	require.NoError(t, err)
+	require.NoError(t, chain.Apply(delta))
+	assert.Empty(t, chain.Structure().Files)
+}
@@ -0,0 +1,61 @@
+package crypto
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ErrBondOfferUnsigned is returned when a bond offer does not carry a valid signature of the session's key
+var ErrBondOfferUnsigned = errors.New("bond offer not signed with the session key")
+
+// bondSignatureData is the signed content of a bond offer. The offer's DTLS fingerprint is part
+// of the SDP, so a replayed offer cannot be completed by anyone but its sender.
+type bondSignatureData struct {
+	Purpose string `json:"purpose"`
+	Token   string `json:"token"`
+	SDP     string `json:"sdp"`
+}
+
+func bondDigest(token, sdp string) ([32]byte, error) {
+	dataJSON, err := json.Marshal(bondSignatureData{Purpose: "bond", Token: token, SDP: sdp})
+	if err != nil {
+		return [32]byte{}, fmt.Errorf("failed to marshal bond signature data: %w", err)
+	}
+	return sha256.Sum256(dataJSON), nil
+}
+
+// SignBondOffer signs the offer sdp of an extra connection to the session with resume token
+// token, whose structure this signer signed
+func (s *FileStructureSigner) SignBondOffer(token, sdp string) ([]byte, error) {
+	hash, err := bondDigest(token, sdp)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := signDigest(s.privateKey(), hash[:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign bond offer: %w", err)
+	}
+	return signature, nil
+}
+
+// VerifyBondOffer checks that signature was made over token and sdp by the key that signed
+// structure, the verified structure of the running session
+func VerifyBondOffer(structure *SignedFileStructure, token, sdp string, signature []byte) error {
+	if structure == nil || len(signature) == 0 {
+		return ErrBondOfferUnsigned
+	}
+	publicKey, err := parsePublicKey(structure.PublicKey, structure.Algorithm)
+	if err != nil {
+		return err
+	}
+	hash, err := bondDigest(token, sdp)
+	if err != nil {
+		return err
+	}
+	if err := verifyDigest(publicKey, hash[:], signature); err != nil {
+		return fmt.Errorf("%w: %w", ErrBondOfferUnsigned, err)
+	}
+	return nil
+}
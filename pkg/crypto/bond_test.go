@@ -0,0 +1,34 @@
+package crypto
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBondOfferSignature(t *testing.T) {
+	for _, algorithm := range []SignatureAlgorithm{AlgorithmEd25519, AlgorithmRSA} {
+		t.Run(string(algorithm), func(t *testing.T) {
+			signer, err := NewFileStructureSignerWithAlgorithm(algorithm)
+			require.NoError(t, err)
+			structure, err := signer.SignFileStructureManager(newTestManager(t))
+			require.NoError(t, err)
+
+			signature, err := signer.SignBondOffer("token", "offer-sdp")
+			require.NoError(t, err)
+			require.NoError(t, VerifyBondOffer(structure, "token", "offer-sdp", signature))
+
+			assert.ErrorIs(t, VerifyBondOffer(structure, "token", "offer-sdp", nil), ErrBondOfferUnsigned, "unsigned offer")
+			assert.ErrorIs(t, VerifyBondOffer(nil, "token", "offer-sdp", signature), ErrBondOfferUnsigned, "no running session")
+			assert.ErrorIs(t, VerifyBondOffer(structure, "other", "offer-sdp", signature), ErrBondOfferUnsigned, "signed for another session")
+			assert.ErrorIs(t, VerifyBondOffer(structure, "token", "forged-sdp", signature), ErrBondOfferUnsigned, "offer swapped by a peer that saw the token")
+
+			other, err := NewFileStructureSignerWithAlgorithm(algorithm)
+			require.NoError(t, err)
+			forged, err := other.SignBondOffer("token", "offer-sdp")
+			require.NoError(t, err)
+			assert.ErrorIs(t, VerifyBondOffer(structure, "token", "offer-sdp", forged), ErrBondOfferUnsigned, "signed with another key")
+		})
+	}
+}
@@ -0,0 +1,173 @@
+package crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	// bundleVersion is the format of sealed identity bundles
+	bundleVersion = 1
+	// bundleIterations is the PBKDF2-SHA256 work factor that turns a passphrase into the bundle key
+	bundleIterations = 600_000
+	// bundleSaltSize is the size of the random salt of a bundle key
+	bundleSaltSize = 16
+)
+
+// ErrBundlePassphrase is returned by OpenBundle when the passphrase is wrong or the bundle was altered
+var ErrBundlePassphrase = errors.New("wrong passphrase or damaged bundle")
+
+// IdentityBundle carries the files that make up a device identity, such as the device keys,
+// the trust store and the config, so the identity can move to another machine
+type IdentityBundle struct {
+	Created  time.Time         `json:"created"`
+	Hostname string            `json:"hostname,omitempty"` // Machine the bundle was exported on
+	Files    map[string][]byte `json:"files"`              // Contents by file name in the config directory
+}
+
+// sealedBundle is the encrypted form of an IdentityBundle written to disk
+type sealedBundle struct {
+	Version    int    `json:"version"`
+	KDF        string `json:"kdf"`
+	Iterations int    `json:"iterations"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// CollectIdentity reads the files called names from dir into a bundle; missing files are skipped
+func CollectIdentity(dir string, names []string) (*IdentityBundle, error) {
+	hostname, _ := os.Hostname()
+	bundle := &IdentityBundle{Created: time.Now(), Hostname: hostname, Files: make(map[string][]byte)}
+	for _, name := range names {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		bundle.Files[name] = data
+	}
+	if len(bundle.Files) == 0 {
+		return nil, fmt.Errorf("no identity files found in %s", dir)
+	}
+	return bundle, nil
+}
+
+// Seal encrypts the bundle with a key derived from passphrase and writes it to w
+func (b *IdentityBundle) Seal(w io.Writer, passphrase []byte) error {
+	plaintext, err := json.Marshal(b)
+	if err != nil {
+		return fmt.Errorf("failed to marshal identity bundle: %w", err)
+	}
+	sealed := sealedBundle{Version: bundleVersion, KDF: "pbkdf2-sha256", Iterations: bundleIterations}
+	sealed.Salt = make([]byte, bundleSaltSize)
+	if _, err := rand.Read(sealed.Salt); err != nil {
+		return fmt.Errorf("failed to generate salt: %w", err)
+	}
+	aead, err := bundleCipher(passphrase, sealed.Salt, sealed.Iterations)
+	if err != nil {
+		return err
+	}
+	sealed.Nonce = make([]byte, aead.NonceSize())
+	if _, err := rand.Read(sealed.Nonce); err != nil {
+		return fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	sealed.Ciphertext = aead.Seal(nil, sealed.Nonce, plaintext, nil)
+
+	if err := json.NewEncoder(w).Encode(sealed); err != nil {
+		return fmt.Errorf("failed to write identity bundle: %w", err)
+	}
+	return nil
+}
+
+// OpenBundle reads a bundle written by Seal and decrypts it with passphrase
+func OpenBundle(r io.Reader, passphrase []byte) (*IdentityBundle, error) {
+	var sealed sealedBundle
+	if err := json.NewDecoder(r).Decode(&sealed); err != nil {
+		return nil, fmt.Errorf("failed to parse identity bundle: %w", err)
+	}
+	if sealed.Version != bundleVersion || sealed.KDF != "pbkdf2-sha256" {
+		return nil, fmt.Errorf("unsupported identity bundle version %d (%s)", sealed.Version, sealed.KDF)
+	}
+	aead, err := bundleCipher(passphrase, sealed.Salt, sealed.Iterations)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed.Nonce) != aead.NonceSize() {
+		return nil, ErrBundlePassphrase
+	}
+	plaintext, err := aead.Open(nil, sealed.Nonce, sealed.Ciphertext, nil)
+	if err != nil {
+		return nil, ErrBundlePassphrase
+	}
+
+	var bundle IdentityBundle
+	if err := json.Unmarshal(plaintext, &bundle); err != nil {
+		return nil, fmt.Errorf("failed to parse identity bundle contents: %w", err)
+	}
+	return &bundle, nil
+}
+
+// Install writes the files of the bundle to dir. A file that already exists is kept with a
+// .bak suffix, so an identity replaced by mistake can be restored. It returns the names written.
+func (b *IdentityBundle) Install(dir string) ([]string, error) {
+	for name := range b.Files {
+		// The bundle may come from anywhere, it must not write outside dir
+		if name != filepath.Base(name) || name == "." || name == ".." {
+			return nil, fmt.Errorf("identity bundle contains an invalid file name %q", name)
+		}
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create config directory: %w", err)
+	}
+
+	var written []string
+	for name, data := range b.Files {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err == nil {
+			if err := os.Rename(path, path+".bak"); err != nil {
+				return written, fmt.Errorf("failed to back up %s: %w", name, err)
+			}
+		}
+		if err := os.WriteFile(path, data, 0600); err != nil {
+			return written, fmt.Errorf("failed to write %s: %w", name, err)
+		}
+		written = append(written, name)
+	}
+	return written, nil
+}
+
+// bundleCipher derives the bundle key from passphrase and returns its AES-256-GCM cipher
+func bundleCipher(passphrase, salt []byte, iterations int) (cipher.AEAD, error) {
+	if len(passphrase) == 0 {
+		return nil, errors.New("the passphrase must not be empty")
+	}
+	if iterations <= 0 || len(salt) == 0 {
+		return nil, fmt.Errorf("invalid identity bundle key parameters")
+	}
+	key, err := pbkdf2.Key(sha256.New, string(passphrase), salt, iterations, 32)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive bundle key: %w", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create bundle cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create bundle cipher: %w", err)
+	}
+	return aead, nil
+}
@@ -0,0 +1,57 @@
+package crypto
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdentityBundle_RoundTrip(t *testing.T) {
+	src := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(src, DeviceKeyFileName), []byte(`{"keys":1}`), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(src, TrustStoreFileName), []byte(`{"abc":{}}`), 0600))
+
+	bundle, err := CollectIdentity(src, []string{DeviceKeyFileName, TrustStoreFileName, "config.json"})
+	require.NoError(t, err)
+	assert.Len(t, bundle.Files, 2, "missing files are skipped")
+
+	var sealed bytes.Buffer
+	require.NoError(t, bundle.Seal(&sealed, []byte("correct horse")))
+	assert.NotContains(t, sealed.String(), "keys", "bundle contents must be encrypted")
+
+	_, err = OpenBundle(bytes.NewReader(sealed.Bytes()), []byte("wrong"))
+	assert.ErrorIs(t, err, ErrBundlePassphrase)
+
+	opened, err := OpenBundle(bytes.NewReader(sealed.Bytes()), []byte("correct horse"))
+	require.NoError(t, err)
+
+	dst := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dst, DeviceKeyFileName), []byte("old"), 0600))
+	written, err := opened.Install(dst)
+	require.NoError(t, err)
+	sort.Strings(written)
+	assert.Equal(t, []string{DeviceKeyFileName, TrustStoreFileName}, written)
+
+	data, err := os.ReadFile(filepath.Join(dst, DeviceKeyFileName))
+	require.NoError(t, err)
+	assert.Equal(t, `{"keys":1}`, string(data))
+	backup, err := os.ReadFile(filepath.Join(dst, DeviceKeyFileName+".bak"))
+	require.NoError(t, err)
+	assert.Equal(t, "old", string(backup), "replaced files are backed up")
+}
+
+func TestIdentityBundle_InstallRejectsPaths(t *testing.T) {
+	bundle := &IdentityBundle{Files: map[string][]byte{"../evil": []byte("x")}}
+	_, err := bundle.Install(t.TempDir())
+	assert.Error(t, err)
+}
+
+func TestIdentityBundle_EmptyPassphrase(t *testing.T) {
+	bundle := &IdentityBundle{Files: map[string][]byte{"a": []byte("x")}}
+	assert.Error(t, bundle.Seal(&bytes.Buffer{}, nil))
+}
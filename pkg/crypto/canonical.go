@@ -0,0 +1,175 @@
+package crypto
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/rescp17/lanFileSharer/pkg/fileInfo"
+)
+
+// signaturePayload is the content covered by a structure signature.
+// It is serialized as the same JSON json.Marshal produced for earlier versions,
+// but written node by node so memory use does not grow with the structure.
+type signaturePayload struct {
+	Files        []fileInfo.FileNode
+	Dirs         []fileInfo.FileNode
+	RootNodes    []fileInfo.FileNode
+	FileCount    int
+	DirCount     int
+	TotalSize    int64
+	Timestamp    int64
+	KeyExpiresAt int64
+	Algorithm    SignatureAlgorithm
+}
+
+// digest returns the SHA-256 hash of the canonical serialization of the payload
+func (p *signaturePayload) digest() ([32]byte, error) {
+	return p.digestContext(context.Background())
+}
+
+// digestContext is digest failing with ctx's error once ctx is done
+func (p *signaturePayload) digestContext(ctx context.Context) ([32]byte, error) {
+	hash := sha256.New()
+	if err := p.writeTo(contextWriter{ctx: ctx, w: hash}); err != nil {
+		return [32]byte{}, err
+	}
+	var sum [32]byte
+	copy(sum[:], hash.Sum(nil))
+	return sum, nil
+}
+
+// writeTo writes the canonical serialization of the payload to w
+func (p *signaturePayload) writeTo(w io.Writer) error {
+	cw := newCanonicalWriter(w)
+
+	cw.raw(`{"files":`)
+	cw.nodes(p.Files)
+	cw.raw(`,"directories":`)
+	cw.nodes(p.Dirs)
+	cw.raw(`,"root_nodes":`)
+	cw.nodes(p.RootNodes)
+	cw.raw(`,"stats":{"file_count":`)
+	cw.raw(strconv.Itoa(p.FileCount))
+	cw.raw(`,"dir_count":`)
+	cw.raw(strconv.Itoa(p.DirCount))
+	cw.raw(`,"total_size":`)
+	cw.raw(strconv.FormatInt(p.TotalSize, 10))
+	cw.raw(`},"timestamp":`)
+	cw.raw(strconv.FormatInt(p.Timestamp, 10))
+	if p.KeyExpiresAt != 0 {
+		cw.raw(`,"key_expires_at":`)
+		cw.raw(strconv.FormatInt(p.KeyExpiresAt, 10))
+	}
+	if p.Algorithm != "" {
+		cw.raw(`,"algorithm":`)
+		cw.value(p.Algorithm)
+	}
+	cw.raw(`}`)
+
+	return cw.flush()
+}
+
+// contextWriter fails writes once ctx is done, which stops long serializations early
+type contextWriter struct {
+	ctx context.Context
+	w   io.Writer
+}
+
+func (cw contextWriter) Write(p []byte) (int, error) {
+	if err := cw.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return cw.w.Write(p)
+}
+
+// canonicalWriter writes JSON through a buffer, remembering the first error
+type canonicalWriter struct {
+	w       *bufio.Writer
+	scratch bytes.Buffer
+	encoder *json.Encoder
+	err     error
+}
+
+func newCanonicalWriter(w io.Writer) *canonicalWriter {
+	cw := &canonicalWriter{w: bufio.NewWriterSize(w, 64*1024)}
+	cw.encoder = json.NewEncoder(&cw.scratch)
+	return cw
+}
+
+func (cw *canonicalWriter) raw(s string) {
+	if cw.err != nil {
+		return
+	}
+	_, cw.err = cw.w.WriteString(s)
+}
+
+// value writes v as json.Marshal would, reusing one scratch buffer
+func (cw *canonicalWriter) value(v any) {
+	if cw.err != nil {
+		return
+	}
+	cw.scratch.Reset()
+	if err := cw.encoder.Encode(v); err != nil {
+		cw.err = fmt.Errorf("failed to marshal signature data: %w", err)
+		return
+	}
+	// Encode terminates each value with a newline that json.Marshal does not write
+	_, cw.err = cw.w.Write(bytes.TrimSuffix(cw.scratch.Bytes(), []byte("\n")))
+}
+
+// nodes writes a node slice as json.Marshal would, including null for a nil slice
+func (cw *canonicalWriter) nodes(nodes []fileInfo.FileNode) {
+	if nodes == nil {
+		cw.raw("null")
+		return
+	}
+	cw.raw("[")
+	for i := range nodes {
+		if i > 0 {
+			cw.raw(",")
+		}
+		cw.node(&nodes[i])
+	}
+	cw.raw("]")
+}
+
+// node writes a node and its children one node at a time. Children is the last
+// serialized field of FileNode, so it can be appended after the other fields.
+func (cw *canonicalWriter) node(node *fileInfo.FileNode) {
+	if cw.err != nil {
+		return
+	}
+	if len(node.Children) == 0 {
+		cw.value(node)
+		return
+	}
+
+	fields := *node
+	fields.Children = nil
+	cw.scratch.Reset()
+	if err := cw.encoder.Encode(&fields); err != nil {
+		cw.err = fmt.Errorf("failed to marshal signature data: %w", err)
+		return
+	}
+	// Drop the closing brace and newline, then continue the object with the children
+	encoded := bytes.TrimSuffix(cw.scratch.Bytes(), []byte("}\n"))
+	if _, cw.err = cw.w.Write(encoded); cw.err != nil {
+		return
+	}
+	cw.raw(`,"children":`)
+	cw.nodes(node.Children)
+	cw.raw("}")
+}
+
+func (cw *canonicalWriter) flush() error {
+	if cw.err != nil {
+		return cw.err
+	}
+	return cw.w.Flush()
+}
@@ -0,0 +1,178 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/rescp17/lanFileSharer/pkg/fileInfo"
+	"github.com/rescp17/lanFileSharer/pkg/transfer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// legacySignatureJSON is how signature data was serialized before it was streamed.
+// Streamed output must match it byte for byte so existing signatures stay valid.
+func legacySignatureJSON(t testing.TB, p *signaturePayload) []byte {
+	t.Helper()
+	type stats struct {
+		FileCount int   `json:"file_count"`
+		DirCount  int   `json:"dir_count"`
+		TotalSize int64 `json:"total_size"`
+	}
+	data, err := json.Marshal(struct {
+		Files        []fileInfo.FileNode `json:"files"`
+		Dirs         []fileInfo.FileNode `json:"directories"`
+		RootNodes    []fileInfo.FileNode `json:"root_nodes"`
+		Stats        stats               `json:"stats"`
+		Timestamp    int64               `json:"timestamp"`
+		KeyExpiresAt int64               `json:"key_expires_at,omitempty"`
+		Algorithm    SignatureAlgorithm  `json:"algorithm,omitempty"`
+	}{
+		Files:        p.Files,
+		Dirs:         p.Dirs,
+		RootNodes:    p.RootNodes,
+		Stats:        stats{FileCount: p.FileCount, DirCount: p.DirCount, TotalSize: p.TotalSize},
+		Timestamp:    p.Timestamp,
+		KeyExpiresAt: p.KeyExpiresAt,
+		Algorithm:    p.Algorithm,
+	})
+	require.NoError(t, err)
+	return data
+}
+
+// syntheticTree builds a directory tree of fileCount files spread over dirs of filesPerDir
+func syntheticTree(fileCount, filesPerDir int) fileInfo.FileNode {
+	root := fileInfo.FileNode{Name: "root", IsDir: true, Path: "/root"}
+	for d := 0; d*filesPerDir < fileCount; d++ {
+		dirName := fmt.Sprintf("dir-%05d", d)
+		dir := fileInfo.FileNode{Name: dirName, IsDir: true, Path: filepath.Join(root.Path, dirName)}
+		for f := d * filesPerDir; f < fileCount && f < (d+1)*filesPerDir; f++ {
+			name := fmt.Sprintf("file-%07d.txt", f)
+			dir.Children = append(dir.Children, fileInfo.FileNode{
+				Name:     name,
+				Size:     int64(f),
+				MimeType: "text/plain; charset=utf-8",
+				Checksum: fmt.Sprintf("%064x", f),
+				Path:     filepath.Join(dir.Path, name),
+			})
+		}
+		dir.SummarizeDir()
+		root.Children = append(root.Children, dir)
+	}
+	root.SummarizeDir()
+	return root
+}
+
+func TestSignaturePayload_MatchesJSONMarshal(t *testing.T) {
+	tree := syntheticTree(25, 4)
+	tree.Children[0].Children[0].Name = `<odd> & "quoted" name ✓`
+	stream := fileInfo.NewStdinNode("backup.sql")
+
+	payloads := map[string]*signaturePayload{
+		"empty": {},
+		"nested": {
+			Files:     append(tree.Children[1].Children, stream),
+			Dirs:      tree.Children,
+			RootNodes: []fileInfo.FileNode{tree, stream},
+			FileCount: 5,
+			DirCount:  7,
+			TotalSize: 12345,
+			Timestamp: 1700000000,
+		},
+		"expiry and algorithm": {
+			Files:        []fileInfo.FileNode{},
+			RootNodes:    []fileInfo.FileNode{{Name: "empty", IsDir: true, Children: []fileInfo.FileNode{}}},
+			Timestamp:    1700000000,
+			KeyExpiresAt: 1800000000,
+			Algorithm:    AlgorithmEd25519,
+		},
+	}
+
+	for name, payload := range payloads {
+		t.Run(name, func(t *testing.T) {
+			var streamed bytes.Buffer
+			require.NoError(t, payload.writeTo(&streamed))
+			expected := legacySignatureJSON(t, payload)
+			assert.Equal(t, string(expected), streamed.String())
+
+			digest, err := payload.digest()
+			require.NoError(t, err)
+			assert.Equal(t, sha256.Sum256(expected), digest)
+		})
+	}
+}
+
+// signatureHashMemoryCeiling is the most hashing a structure may allocate, whatever its size
+const signatureHashMemoryCeiling = 1 << 20
+
+// TestSignaturePayload_MemoryCeiling checks that hashing stays under a fixed allocation ceiling,
+// so signing hundreds of thousands of entries does not need a second copy of the structure in memory
+func TestSignaturePayload_MemoryCeiling(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping memory measurement in short mode")
+	}
+
+	tree := syntheticTree(50000, 500)
+	payload := &signaturePayload{RootNodes: []fileInfo.FileNode{tree}, Dirs: tree.Children}
+	serializedSize := len(legacySignatureJSON(t, payload))
+
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	_, err := payload.digest()
+	runtime.ReadMemStats(&after)
+	require.NoError(t, err)
+
+	allocated := after.TotalAlloc - before.TotalAlloc
+	t.Logf("serialized %d bytes, allocated %d bytes while hashing", serializedSize, allocated)
+	assert.Less(t, allocated, uint64(signatureHashMemoryCeiling), "hashing must not buffer the serialized structure")
+}
+
+func benchmarkManager(b *testing.B, fileCount int) *transfer.FileStructureManager {
+	b.Helper()
+	tree := syntheticTree(fileCount, 1000)
+	fsm := transfer.NewFileStructureManager()
+	require.NoError(b, fsm.AddFileNode(&tree))
+	return fsm
+}
+
+// BenchmarkSignFileStructureManager signs a structure with 200k files
+func BenchmarkSignFileStructureManager(b *testing.B) {
+	fsm := benchmarkManager(b, 200000)
+	signer, err := NewEd25519FileStructureSigner()
+	require.NoError(b, err)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := signer.SignFileStructureManager(fsm); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkSignaturePayloadDigest compares streamed hashing with marshalling the whole payload
+func BenchmarkSignaturePayloadDigest(b *testing.B) {
+	tree := syntheticTree(200000, 1000)
+	payload := &signaturePayload{RootNodes: []fileInfo.FileNode{tree}, Dirs: tree.Children}
+
+	b.Run("streamed", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := payload.digest(); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("marshal", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			sha256.Sum256(legacySignatureJSON(b, payload))
+		}
+	})
+}
@@ -0,0 +1,362 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rescp17/lanFileSharer/pkg/fileInfo"
+	"github.com/rescp17/lanFileSharer/pkg/transfer"
+)
+
+var (
+	// ErrDeltaOutOfOrder is returned when a delta does not follow the last applied link of the chain
+	ErrDeltaOutOfOrder = errors.New("structure delta out of order")
+	// ErrDeltaNotApplicable is returned when a delta references nodes missing from the structure
+	ErrDeltaNotApplicable = errors.New("structure delta not applicable")
+)
+
+// DeltaOperation is the kind of change carried by a structure delta
+type DeltaOperation string
+
+const (
+	DeltaAdd    DeltaOperation = "add"
+	DeltaRemove DeltaOperation = "remove"
+	DeltaUpdate DeltaOperation = "update"
+)
+
+// StructureDeltaChange is a single change to a shared structure
+type StructureDeltaChange struct {
+	Op DeltaOperation `json:"op"`
+	// Path is the slash separated path starting at a root node's name
+	Path string `json:"path"`
+	// Node is the new node for add and update operations
+	Node *fileInfo.FileNode `json:"node,omitempty"`
+}
+
+// SignedStructureDelta is one link of a signature chain rooted at a SignedFileStructure.
+// Each link signs the hash of the previous link's signature, so deltas can only be applied in order.
+type SignedStructureDelta struct {
+	Sequence     uint64                 `json:"sequence"`
+	PreviousHash []byte                 `json:"previous_hash"`
+	Changes      []StructureDeltaChange `json:"changes"`
+	SignedAt     int64                  `json:"signed_at"`
+	Signature    []byte                 `json:"signature"`
+}
+
+// deltaSignatureData is the signed content of a delta
+type deltaSignatureData struct {
+	Sequence     uint64                 `json:"sequence"`
+	PreviousHash []byte                 `json:"previous_hash"`
+	Changes      []StructureDeltaChange `json:"changes"`
+	SignedAt     int64                  `json:"signed_at"`
+}
+
+func (d *SignedStructureDelta) digest() ([32]byte, error) {
+	dataJSON, err := json.Marshal(deltaSignatureData{
+		Sequence:     d.Sequence,
+		PreviousHash: d.PreviousHash,
+		Changes:      d.Changes,
+		SignedAt:     d.SignedAt,
+	})
+	if err != nil {
+		return [32]byte{}, fmt.Errorf("failed to marshal delta signature data: %w", err)
+	}
+	return sha256.Sum256(dataJSON), nil
+}
+
+// chainHash returns the link hash the next delta must reference
+func chainHash(signature []byte) []byte {
+	hash := sha256.Sum256(signature)
+	return hash[:]
+}
+
+// DeltaChangesFromStructure converts file structure change notifications into delta changes
+func DeltaChangesFromStructure(changes []transfer.StructureChange) []StructureDeltaChange {
+	deltaChanges := make([]StructureDeltaChange, 0, len(changes))
+	for _, change := range changes {
+		deltaChange := StructureDeltaChange{Path: change.RelativePath}
+		switch change.Type {
+		case transfer.StructureNodeAdded:
+			deltaChange.Op = DeltaAdd
+		case transfer.StructureNodeUpdated:
+			deltaChange.Op = DeltaUpdate
+		case transfer.StructureNodeRemoved:
+			deltaChange.Op = DeltaRemove
+		default:
+			continue
+		}
+		if deltaChange.Op != DeltaRemove {
+			node := change.Node
+			node.Children = cloneNodes(node.Children)
+			deltaChange.Node = &node
+		}
+		deltaChanges = append(deltaChanges, deltaChange)
+	}
+	return deltaChanges
+}
+
+// StructureDeltaSigner signs successive deltas of a structure previously signed by the same signer
+type StructureDeltaSigner struct {
+	signer        *FileStructureSigner
+	mu            sync.Mutex
+	sequence      uint64
+	lastSignature []byte
+}
+
+// NewStructureDeltaSigner starts a delta chain at base, which must have been signed by signer
+func NewStructureDeltaSigner(signer *FileStructureSigner, base *SignedFileStructure) *StructureDeltaSigner {
+	return &StructureDeltaSigner{
+		signer:        signer,
+		lastSignature: base.Signature,
+	}
+}
+
+// Sign creates the next link of the chain for changes
+func (s *StructureDeltaSigner) Sign(changes []StructureDeltaChange) (*SignedStructureDelta, error) {
+	if len(changes) == 0 {
+		return nil, fmt.Errorf("structure delta cannot be empty")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delta := &SignedStructureDelta{
+		Sequence:     s.sequence + 1,
+		PreviousHash: chainHash(s.lastSignature),
+		Changes:      changes,
+		SignedAt:     time.Now().Unix(),
+	}
+
+	hash, err := delta.digest()
+	if err != nil {
+		return nil, err
+	}
+	signature, err := signDigest(s.signer.privateKey(), hash[:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign structure delta: %w", err)
+	}
+	delta.Signature = signature
+
+	s.sequence = delta.Sequence
+	s.lastSignature = signature
+	return delta, nil
+}
+
+// StructureChain tracks a verified structure on the receiver and applies signed deltas to it
+type StructureChain struct {
+	mu            sync.RWMutex
+	publicKey     crypto.PublicKey
+	structure     *SignedFileStructure
+	sequence      uint64
+	lastSignature []byte
+}
+
+// NewStructureChain verifies base and starts a chain at it
+func NewStructureChain(base *SignedFileStructure) (*StructureChain, error) {
+	if err := VerifyFileStructure(base); err != nil {
+		return nil, err
+	}
+
+	// Deltas are signed with the same key and algorithm as the base
+	publicKey, err := parsePublicKey(base.PublicKey, base.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	return &StructureChain{
+		publicKey:     publicKey,
+		structure:     base,
+		lastSignature: base.Signature,
+	}, nil
+}
+
+// Structure returns the structure with every applied delta
+func (c *StructureChain) Structure() *SignedFileStructure {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.structure
+}
+
+// Sequence returns the sequence number of the last applied delta
+func (c *StructureChain) Sequence() uint64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.sequence
+}
+
+// Apply verifies delta against the chain and applies its changes.
+// The structure is left untouched if verification or any change fails.
+func (c *StructureChain) Apply(delta *SignedStructureDelta) error {
+	if delta == nil {
+		return fmt.Errorf("structure delta cannot be nil")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if delta.Sequence != c.sequence+1 {
+		return fmt.Errorf("%w: expected sequence %d, got %d", ErrDeltaOutOfOrder, c.sequence+1, delta.Sequence)
+	}
+	if !bytes.Equal(delta.PreviousHash, chainHash(c.lastSignature)) {
+		return fmt.Errorf("%w: previous hash does not match", ErrDeltaOutOfOrder)
+	}
+
+	hash, err := delta.digest()
+	if err != nil {
+		return err
+	}
+	if err := verifyDigest(c.publicKey, hash[:], delta.Signature); err != nil {
+		return fmt.Errorf("structure delta signature verification failed: %w", err)
+	}
+
+	roots := cloneNodes(c.structure.RootNodes)
+	for _, change := range delta.Changes {
+		if roots, err = applyDeltaChange(roots, change); err != nil {
+			return err
+		}
+	}
+
+	updated := *c.structure
+	updated.RootNodes = roots
+	// The Merkle root only covers the files of the base structure
+	updated.MerkleRoot = nil
+	updated.Files, updated.Directories = flattenNodes(roots)
+	updated.Metadata = &StructureMetadata{
+		TotalFiles: len(updated.Files),
+		TotalDirs:  len(updated.Directories),
+		TotalSize:  totalFileSize(updated.Files),
+		SignedAt:   delta.SignedAt,
+		Version:    "1.0",
+	}
+	if c.structure.Metadata != nil {
+		updated.Metadata.CreatedAt = c.structure.Metadata.CreatedAt
+		updated.Metadata.Version = c.structure.Metadata.Version
+	}
+
+	c.structure = &updated
+	c.sequence = delta.Sequence
+	c.lastSignature = delta.Signature
+	return nil
+}
+
+// applyDeltaChange applies a single change to roots, refreshing the summaries of parent directories
+func applyDeltaChange(roots []fileInfo.FileNode, change StructureDeltaChange) ([]fileInfo.FileNode, error) {
+	segments, err := splitDeltaPath(change.Path)
+	if err != nil {
+		return nil, err
+	}
+	if change.Op != DeltaRemove && change.Node == nil {
+		return nil, fmt.Errorf("%w: %s of %s has no node", ErrDeltaNotApplicable, change.Op, change.Path)
+	}
+
+	// Walk down to the slice holding the target, remembering the directories on the way
+	siblings := &roots
+	var parents []*fileInfo.FileNode
+	for _, name := range segments[:len(segments)-1] {
+		i := indexOfNode(*siblings, name)
+		if i < 0 || !(*siblings)[i].IsDir {
+			return nil, fmt.Errorf("%w: directory %s not found", ErrDeltaNotApplicable, name)
+		}
+		parent := &(*siblings)[i]
+		parents = append(parents, parent)
+		siblings = &parent.Children
+	}
+
+	name := segments[len(segments)-1]
+	i := indexOfNode(*siblings, name)
+	switch change.Op {
+	case DeltaAdd:
+		if i >= 0 {
+			return nil, fmt.Errorf("%w: %s already exists", ErrDeltaNotApplicable, change.Path)
+		}
+		node := *change.Node
+		node.Name = name
+		*siblings = append(*siblings, node)
+	case DeltaUpdate:
+		if i < 0 {
+			return nil, fmt.Errorf("%w: %s not found", ErrDeltaNotApplicable, change.Path)
+		}
+		node := *change.Node
+		node.Name = name
+		(*siblings)[i] = node
+	case DeltaRemove:
+		if i < 0 {
+			return nil, fmt.Errorf("%w: %s not found", ErrDeltaNotApplicable, change.Path)
+		}
+		*siblings = append((*siblings)[:i], (*siblings)[i+1:]...)
+	default:
+		return nil, fmt.Errorf("%w: unknown operation %q", ErrDeltaNotApplicable, change.Op)
+	}
+
+	for j := len(parents) - 1; j >= 0; j-- {
+		parents[j].SummarizeDir()
+	}
+	return roots, nil
+}
+
+// splitDeltaPath validates a delta path and splits it into node names
+func splitDeltaPath(path string) ([]string, error) {
+	segments := strings.Split(path, "/")
+	for _, segment := range segments {
+		if segment == "" || segment == "." || segment == ".." || strings.ContainsRune(segment, '\\') {
+			return nil, fmt.Errorf("%w: invalid path %q", ErrDeltaNotApplicable, path)
+		}
+	}
+	return segments, nil
+}
+
+func indexOfNode(nodes []fileInfo.FileNode, name string) int {
+	for i := range nodes {
+		if nodes[i].Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func cloneNodes(nodes []fileInfo.FileNode) []fileInfo.FileNode {
+	if nodes == nil {
+		return nil
+	}
+	cloned := make([]fileInfo.FileNode, len(nodes))
+	for i, node := range nodes {
+		cloned[i] = node
+		cloned[i].Children = cloneNodes(node.Children)
+	}
+	return cloned
+}
+
+// flattenNodes lists every file and directory below roots
+func flattenNodes(roots []fileInfo.FileNode) (files, dirs []fileInfo.FileNode) {
+	var walk func(nodes []fileInfo.FileNode)
+	walk = func(nodes []fileInfo.FileNode) {
+		for _, node := range nodes {
+			if node.IsDir {
+				dirs = append(dirs, node)
+				walk(node.Children)
+			} else {
+				files = append(files, node)
+			}
+		}
+	}
+	walk(roots)
+	return files, dirs
+}
+
+func totalFileSize(files []fileInfo.FileNode) int64 {
+	var total int64
+	for _, file := range files {
+		if file.IsStream() {
+			continue
+		}
+		total += file.Size
+	}
+	return total
+}
@@ -0,0 +1,158 @@
+package crypto
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/rescp17/lanFileSharer/pkg/fileInfo"
+	"github.com/rescp17/lanFileSharer/pkg/transfer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// changeCollector records structure changes so they can be signed as a delta
+type changeCollector struct {
+	mu      sync.Mutex
+	changes []transfer.StructureChange
+}
+
+func (c *changeCollector) ID() string { return "collector" }
+
+func (c *changeCollector) OnStructureChanged(change transfer.StructureChange) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.changes = append(c.changes, change)
+}
+
+func (c *changeCollector) drain() []transfer.StructureChange {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	changes := c.changes
+	c.changes = nil
+	return changes
+}
+
+// setupDeltaTest signs a small shared folder and returns everything needed to send deltas for it
+func setupDeltaTest(t *testing.T) (string, *transfer.FileStructureManager, *StructureDeltaSigner, *StructureChain, *changeCollector) {
+	t.Helper()
+
+	tempDir := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(tempDir, "sub"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "a.txt"), []byte("a"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "sub", "b.txt"), []byte("bb"), 0644))
+
+	node, err := fileInfo.CreateNode(tempDir)
+	require.NoError(t, err)
+	fsm := transfer.NewFileStructureManager()
+	require.NoError(t, fsm.AddFileNode(&node))
+
+	signer, err := NewFileStructureSigner()
+	require.NoError(t, err)
+	base, err := signer.SignFileStructureManager(fsm)
+	require.NoError(t, err)
+
+	chain, err := NewStructureChain(base)
+	require.NoError(t, err)
+
+	collector := &changeCollector{}
+	fsm.AddStructureListener(collector)
+	return tempDir, fsm, NewStructureDeltaSigner(signer, base), chain, collector
+}
+
+func TestStructureChain_AppliesSignedDeltas(t *testing.T) {
+	tempDir, fsm, deltaSigner, chain, collector := setupDeltaTest(t)
+
+	// Add a new file, change one and remove another
+	newPath := filepath.Join(tempDir, "sub", "c.txt")
+	require.NoError(t, os.WriteFile(newPath, []byte("ccc"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "a.txt"), []byte("changed"), 0644))
+	changed, err := fileInfo.CreateNode(filepath.Join(tempDir, "a.txt"))
+	require.NoError(t, err)
+	require.NoError(t, fsm.UpdateFileNode(&changed))
+	require.NoError(t, os.Remove(filepath.Join(tempDir, "sub", "b.txt")))
+	require.NoError(t, fsm.RemovePath(filepath.Join(tempDir, "sub", "b.txt")))
+
+	delta, err := deltaSigner.Sign(DeltaChangesFromStructure(collector.drain()))
+	require.NoError(t, err)
+	require.NoError(t, chain.Apply(delta))
+
+	// The nested add is delivered as an update of its parent directory
+	subDir, ok := fsm.GetDir(filepath.Join(tempDir, "sub"))
+	require.True(t, ok)
+	subNode, err := fileInfo.CreateNode(newPath)
+	require.NoError(t, err)
+	updatedSub := *subDir
+	updatedSub.Children = append(append([]fileInfo.FileNode{}, subDir.Children...), subNode)
+	require.NoError(t, fsm.UpdateFileNode(&updatedSub))
+
+	delta, err = deltaSigner.Sign(DeltaChangesFromStructure(collector.drain()))
+	require.NoError(t, err)
+	require.NoError(t, chain.Apply(delta))
+	assert.Equal(t, uint64(2), chain.Sequence())
+
+	expected, err := fileInfo.CreateNode(tempDir)
+	require.NoError(t, err)
+	structure := chain.Structure()
+	require.Len(t, structure.RootNodes, 1)
+	assert.Equal(t, expected.Checksum, structure.RootNodes[0].Checksum)
+	assert.Equal(t, fsm.GetTotalSize(), structure.Metadata.TotalSize)
+	assert.Len(t, structure.Files, 2)
+	assert.Len(t, structure.Directories, 2)
+}
+
+func TestStructureChain_RejectsOutOfOrderDeltas(t *testing.T) {
+	tempDir, fsm, deltaSigner, chain, collector := setupDeltaTest(t)
+
+	require.NoError(t, fsm.RemovePath(filepath.Join(tempDir, "a.txt")))
+	first, err := deltaSigner.Sign(DeltaChangesFromStructure(collector.drain()))
+	require.NoError(t, err)
+	require.NoError(t, fsm.RemovePath(filepath.Join(tempDir, "sub")))
+	second, err := deltaSigner.Sign(DeltaChangesFromStructure(collector.drain()))
+	require.NoError(t, err)
+
+	assert.ErrorIs(t, chain.Apply(second), ErrDeltaOutOfOrder)
+	require.NoError(t, chain.Apply(first))
+	assert.ErrorIs(t, chain.Apply(first), ErrDeltaOutOfOrder, "replayed delta must be rejected")
+	require.NoError(t, chain.Apply(second))
+	assert.Empty(t, chain.Structure().Files)
+}
+
+func TestStructureChain_RejectsTamperedDelta(t *testing.T) {
+	tempDir, fsm, deltaSigner, chain, collector := setupDeltaTest(t)
+	before := chain.Structure()
+
+	require.NoError(t, fsm.RemovePath(filepath.Join(tempDir, "a.txt")))
+	delta, err := deltaSigner.Sign(DeltaChangesFromStructure(collector.drain()))
+	require.NoError(t, err)
+
+	delta.Changes[0].Path = filepath.Base(tempDir) + "/sub"
+	assert.Error(t, chain.Apply(delta))
+	assert.Same(t, before, chain.Structure(), "structure must be unchanged after a rejected delta")
+}
+
+func TestStructureChain_RejectsDeltaFromOtherKey(t *testing.T) {
+	tempDir, fsm, _, chain, collector := setupDeltaTest(t)
+
+	otherSigner, err := NewFileStructureSigner()
+	require.NoError(t, err)
+	forger := NewStructureDeltaSigner(otherSigner, chain.Structure())
+
+	require.NoError(t, fsm.RemovePath(filepath.Join(tempDir, "a.txt")))
+	delta, err := forger.Sign(DeltaChangesFromStructure(collector.drain()))
+	require.NoError(t, err)
+
+	assert.Error(t, chain.Apply(delta))
+	assert.Equal(t, uint64(0), chain.Sequence())
+}
+
+func TestApplyDeltaChange_InvalidPaths(t *testing.T) {
+	roots := []fileInfo.FileNode{{Name: "root", IsDir: true}}
+	node := &fileInfo.FileNode{Name: "x"}
+
+	for _, path := range []string{"", "root/../etc", "root//x", `root\x`, "missing/x"} {
+		_, err := applyDeltaChange(roots, StructureDeltaChange{Op: DeltaAdd, Path: path, Node: node})
+		assert.ErrorIs(t, err, ErrDeltaNotApplicable, "path %q", path)
+	}
+}
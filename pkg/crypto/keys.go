@@ -0,0 +1,380 @@
+package crypto
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	// DeviceKeyFileName is the key store file in the application config directory
+	DeviceKeyFileName = "device_keys.json"
+	// DefaultKeyLifetime is how long a device key is used before it is rotated
+	DefaultKeyLifetime = 90 * 24 * time.Hour
+	// DefaultRotationGrace is how long a rotated key is still accepted after rotation
+	DefaultRotationGrace = 7 * 24 * time.Hour
+)
+
+// DeviceKey is a long-lived signing key with certificate-style validity.
+// Exactly one of KeyPair and Ed25519Key is set.
+type DeviceKey struct {
+	KeyPair    *KeyPair
+	Ed25519Key ed25519.PrivateKey
+	CreatedAt  time.Time
+	ExpiresAt  time.Time
+}
+
+// Algorithm returns the signature algorithm of the key
+func (k *DeviceKey) Algorithm() SignatureAlgorithm {
+	if k.Ed25519Key != nil {
+		return AlgorithmEd25519
+	}
+	return AlgorithmRSA
+}
+
+// Expired reports whether the key is past its expiry at now
+func (k *DeviceKey) Expired(now time.Time) bool {
+	return !k.ExpiresAt.IsZero() && now.After(k.ExpiresAt)
+}
+
+// Fingerprint returns the fingerprint of the key's public half
+func (k *DeviceKey) Fingerprint() (string, error) {
+	publicKeyBytes, err := NewFileStructureSignerFromDeviceKey(k).GetPublicKeyBytes()
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal public key: %w", err)
+	}
+	return PublicKeyFingerprint(publicKeyBytes), nil
+}
+
+// PublicKeyFingerprint returns a short hex fingerprint of a DER encoded public key
+func PublicKeyFingerprint(publicKey []byte) string {
+	hash := sha256.Sum256(publicKey)
+	return hex.EncodeToString(hash[:16])
+}
+
+// retiredKey is a rotated key kept for its grace period
+type retiredKey struct {
+	*DeviceKey
+	GraceUntil time.Time
+}
+
+// storedKey is the on-disk form of a device key
+type storedKey struct {
+	// Algorithm is empty for RSA keys written before other algorithms were supported
+	Algorithm     SignatureAlgorithm `json:"algorithm,omitempty"`
+	PrivateKeyPEM string             `json:"private_key_pem"`
+	CreatedAt     time.Time          `json:"created_at"`
+	ExpiresAt     time.Time          `json:"expires_at"`
+	GraceUntil    time.Time          `json:"grace_until,omitempty"`
+}
+
+type storedKeys struct {
+	Current  *storedKey  `json:"current,omitempty"`
+	Previous []storedKey `json:"previous,omitempty"`
+}
+
+// KeyStore persists the device key and rotates it when it expires.
+// Rotated keys remain valid for a grace period so peers can catch up.
+type KeyStore struct {
+	path      string
+	lifetime  time.Duration
+	grace     time.Duration
+	algorithm SignatureAlgorithm
+
+	mu       sync.Mutex
+	loaded   bool
+	current  *DeviceKey
+	previous []retiredKey
+}
+
+// NewKeyStore creates a key store backed by the file at path.
+// Non-positive durations fall back to DefaultKeyLifetime and DefaultRotationGrace.
+func NewKeyStore(path string, lifetime, grace time.Duration) *KeyStore {
+	if lifetime <= 0 {
+		lifetime = DefaultKeyLifetime
+	}
+	if grace <= 0 {
+		grace = DefaultRotationGrace
+	}
+	return &KeyStore{
+		path:      path,
+		lifetime:  lifetime,
+		grace:     grace,
+		algorithm: DefaultSignatureAlgorithm,
+	}
+}
+
+// SetAlgorithm sets the algorithm of newly generated keys. A current key of
+// another algorithm is rotated the next time Current is called.
+func (s *KeyStore) SetAlgorithm(algorithm SignatureAlgorithm) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.algorithm = algorithm.normalize()
+}
+
+// Current returns the active device key, creating it on first use and rotating it
+// once expired or when the configured algorithm changed
+func (s *KeyStore) Current() (*DeviceKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.loadLocked(); err != nil {
+		return nil, err
+	}
+	if s.current != nil && !s.current.Expired(time.Now()) && s.current.Algorithm() == s.algorithm {
+		return s.current, nil
+	}
+	return s.rotateLocked()
+}
+
+// Rotate replaces the device key with a new one, keeping the old key for the grace period
+func (s *KeyStore) Rotate() (*DeviceKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.loadLocked(); err != nil {
+		return nil, err
+	}
+	return s.rotateLocked()
+}
+
+// ValidKeys returns the current key followed by rotated keys still within their grace period
+func (s *KeyStore) ValidKeys(now time.Time) ([]*DeviceKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.loadLocked(); err != nil {
+		return nil, err
+	}
+
+	var keys []*DeviceKey
+	if s.current != nil && !s.current.Expired(now) {
+		keys = append(keys, s.current)
+	}
+	for _, key := range s.previous {
+		if now.Before(key.GraceUntil) {
+			keys = append(keys, key.DeviceKey)
+		}
+	}
+	return keys, nil
+}
+
+// endorsementContext separates endorsement signatures from those the same keys make over structures
+const endorsementContext = "lanFileSharer key endorsement\x00"
+
+// KeyEndorsement is the signature of a rotated key over the key that replaced it, made while
+// the rotated key is in its grace period, so peers that trusted the old key trust the new one
+type KeyEndorsement struct {
+	PublicKey []byte             `json:"public_key"` // The rotated key
+	Algorithm SignatureAlgorithm `json:"algorithm,omitempty"`
+	Signature []byte             `json:"signature"`
+}
+
+// endorsementDigest is what an endorsement of publicKey signs
+func endorsementDigest(publicKey []byte) [32]byte {
+	return sha256.Sum256(append([]byte(endorsementContext), publicKey...))
+}
+
+// Endorsements returns the endorsements of the current key by every rotated key still within
+// its grace period, as returned by ValidKeys
+func (s *KeyStore) Endorsements(now time.Time) ([]KeyEndorsement, error) {
+	keys, err := s.ValidKeys(now)
+	if err != nil || len(keys) < 2 {
+		return nil, err
+	}
+	publicKey, err := NewFileStructureSignerFromDeviceKey(keys[0]).GetPublicKeyBytes()
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal public key: %w", err)
+	}
+	digest := endorsementDigest(publicKey)
+
+	endorsements := make([]KeyEndorsement, 0, len(keys)-1)
+	for _, key := range keys[1:] {
+		signer := NewFileStructureSignerFromDeviceKey(key)
+		rotatedKey, err := signer.GetPublicKeyBytes()
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal public key: %w", err)
+		}
+		signature, err := signDigest(signer.privateKey(), digest[:])
+		if err != nil {
+			return nil, fmt.Errorf("failed to endorse device key: %w", err)
+		}
+		endorsements = append(endorsements, KeyEndorsement{PublicKey: rotatedKey, Algorithm: signer.Algorithm(), Signature: signature})
+	}
+	return endorsements, nil
+}
+
+// Verify checks that the endorsement was signed by its key over publicKey
+func (e *KeyEndorsement) Verify(publicKey []byte) error {
+	endorsingKey, err := parsePublicKey(e.PublicKey, e.Algorithm)
+	if err != nil {
+		return err
+	}
+	digest := endorsementDigest(publicKey)
+	if err := verifyDigest(endorsingKey, digest[:], e.Signature); err != nil {
+		return fmt.Errorf("key endorsement does not verify: %w", err)
+	}
+	return nil
+}
+
+func (s *KeyStore) rotateLocked() (*DeviceKey, error) {
+	now := time.Now()
+	key := &DeviceKey{
+		CreatedAt: now,
+		ExpiresAt: now.Add(s.lifetime),
+	}
+	switch s.algorithm {
+	case AlgorithmEd25519:
+		_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate device key: %w", err)
+		}
+		key.Ed25519Key = privateKey
+	case AlgorithmRSA:
+		keyPair, err := GenerateKeyPair(KEY_PAIR_BIT_SIZE)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate device key: %w", err)
+		}
+		key.KeyPair = keyPair
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedAlgorithm, s.algorithm)
+	}
+
+	if s.current != nil {
+		s.previous = append(s.previous, retiredKey{DeviceKey: s.current, GraceUntil: now.Add(s.grace)})
+	}
+	s.current = key
+
+	// Drop rotated keys whose grace period is over
+	kept := s.previous[:0]
+	for _, key := range s.previous {
+		if now.Before(key.GraceUntil) {
+			kept = append(kept, key)
+		}
+	}
+	s.previous = kept
+
+	if err := s.saveLocked(); err != nil {
+		return nil, err
+	}
+	return s.current, nil
+}
+
+func (s *KeyStore) loadLocked() error {
+	if s.loaded {
+		return nil
+	}
+
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			s.loaded = true
+			return nil
+		}
+		return fmt.Errorf("failed to read key store: %w", err)
+	}
+
+	var stored storedKeys
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return fmt.Errorf("failed to parse key store %s: %w", s.path, err)
+	}
+
+	if stored.Current != nil {
+		key, err := stored.Current.deviceKey()
+		if err != nil {
+			return err
+		}
+		s.current = key
+	}
+	for _, storedPrevious := range stored.Previous {
+		key, err := storedPrevious.deviceKey()
+		if err != nil {
+			return err
+		}
+		s.previous = append(s.previous, retiredKey{DeviceKey: key, GraceUntil: storedPrevious.GraceUntil})
+	}
+	s.loaded = true
+	return nil
+}
+
+func (s *KeyStore) saveLocked() error {
+	var stored storedKeys
+	if s.current != nil {
+		current, err := newStoredKey(s.current, time.Time{})
+		if err != nil {
+			return err
+		}
+		stored.Current = &current
+	}
+	for _, key := range s.previous {
+		previous, err := newStoredKey(key.DeviceKey, key.GraceUntil)
+		if err != nil {
+			return err
+		}
+		stored.Previous = append(stored.Previous, previous)
+	}
+
+	data, err := json.MarshalIndent(stored, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal key store: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return fmt.Errorf("failed to create key store directory: %w", err)
+	}
+	if err := os.WriteFile(s.path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write key store: %w", err)
+	}
+	return nil
+}
+
+func newStoredKey(key *DeviceKey, graceUntil time.Time) (storedKey, error) {
+	var privateKeyPEM []byte
+	var err error
+	if key.Ed25519Key != nil {
+		privateKeyPEM, err = Ed25519PrivateKeyToPEM(key.Ed25519Key)
+	} else {
+		privateKeyPEM, err = PrivateKeyToPEM(key.KeyPair.PrivateKey)
+	}
+	if err != nil {
+		return storedKey{}, err
+	}
+	return storedKey{
+		Algorithm:     key.Algorithm(),
+		PrivateKeyPEM: string(privateKeyPEM),
+		CreatedAt:     key.CreatedAt,
+		ExpiresAt:     key.ExpiresAt,
+		GraceUntil:    graceUntil,
+	}, nil
+}
+
+func (k storedKey) deviceKey() (*DeviceKey, error) {
+	key := &DeviceKey{
+		CreatedAt: k.CreatedAt,
+		ExpiresAt: k.ExpiresAt,
+	}
+	switch k.Algorithm.normalize() {
+	case AlgorithmEd25519:
+		privateKey, err := Ed25519PrivateKeyFromPEM([]byte(k.PrivateKeyPEM))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse stored device key: %w", err)
+		}
+		key.Ed25519Key = privateKey
+	case AlgorithmRSA:
+		privateKey, err := PrivateKeyFromPEM([]byte(k.PrivateKeyPEM))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse stored device key: %w", err)
+		}
+		key.KeyPair = &KeyPair{PrivateKey: privateKey, PublicKey: &privateKey.PublicKey}
+	default:
+		return nil, fmt.Errorf("%w: stored device key uses %q", ErrUnsupportedAlgorithm, k.Algorithm)
+	}
+	return key, nil
+}
@@ -0,0 +1,135 @@
+package crypto
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rescp17/lanFileSharer/pkg/transfer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyStore_PersistsCurrentKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "device_keys.json")
+
+	key, err := NewKeyStore(path, time.Hour, time.Minute).Current()
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), key.ExpiresAt, time.Minute)
+
+	reloaded, err := NewKeyStore(path, time.Hour, time.Minute).Current()
+	require.NoError(t, err)
+	assert.Equal(t, AlgorithmEd25519, key.Algorithm())
+	assert.True(t, key.Ed25519Key.Equal(reloaded.Ed25519Key), "reloading must not create a new key")
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+}
+
+func TestKeyStore_RotateKeepsPreviousKeyForGrace(t *testing.T) {
+	store := NewKeyStore(filepath.Join(t.TempDir(), "device_keys.json"), time.Hour, time.Minute)
+
+	old, err := store.Current()
+	require.NoError(t, err)
+	rotated, err := store.Rotate()
+	require.NoError(t, err)
+	assert.False(t, old.Ed25519Key.Equal(rotated.Ed25519Key))
+
+	keys, err := store.ValidKeys(time.Now())
+	require.NoError(t, err)
+	require.Len(t, keys, 2)
+	assert.Same(t, rotated, keys[0])
+	assert.Same(t, old, keys[1])
+
+	keys, err = store.ValidKeys(time.Now().Add(2 * time.Minute))
+	require.NoError(t, err)
+	require.Len(t, keys, 1, "old key must be dropped once the grace period is over")
+	assert.Same(t, rotated, keys[0])
+}
+
+func TestKeyStore_EndorsementsByRotatedKeys(t *testing.T) {
+	store := NewKeyStore(filepath.Join(t.TempDir(), "device_keys.json"), time.Hour, time.Minute)
+
+	old, err := store.Current()
+	require.NoError(t, err)
+	endorsements, err := store.Endorsements(time.Now())
+	require.NoError(t, err)
+	assert.Empty(t, endorsements, "a key that replaced none is endorsed by none")
+
+	rotated, err := store.Rotate()
+	require.NoError(t, err)
+	endorsements, err = store.Endorsements(time.Now())
+	require.NoError(t, err)
+	require.Len(t, endorsements, 1)
+
+	oldKey, err := NewFileStructureSignerFromDeviceKey(old).GetPublicKeyBytes()
+	require.NoError(t, err)
+	newKey, err := NewFileStructureSignerFromDeviceKey(rotated).GetPublicKeyBytes()
+	require.NoError(t, err)
+	assert.Equal(t, oldKey, endorsements[0].PublicKey)
+	assert.NoError(t, endorsements[0].Verify(newKey))
+	assert.Error(t, endorsements[0].Verify(oldKey), "the endorsement is of the new key only")
+
+	endorsements, err = store.Endorsements(time.Now().Add(2 * time.Minute))
+	require.NoError(t, err)
+	assert.Empty(t, endorsements, "keys past their grace period endorse nothing")
+}
+
+func TestKeyStore_RotatesExpiredKey(t *testing.T) {
+	store := NewKeyStore(filepath.Join(t.TempDir(), "device_keys.json"), time.Hour, time.Minute)
+
+	old, err := store.Current()
+	require.NoError(t, err)
+	old.ExpiresAt = time.Now().Add(-time.Second)
+
+	current, err := store.Current()
+	require.NoError(t, err)
+	assert.NotSame(t, old, current)
+	assert.False(t, current.Expired(time.Now()))
+}
+
+func TestKeyStore_RotatesOnAlgorithmChange(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "device_keys.json")
+	store := NewKeyStore(path, time.Hour, time.Minute)
+	store.SetAlgorithm(AlgorithmRSA)
+
+	rsaKey, err := store.Current()
+	require.NoError(t, err)
+	require.NotNil(t, rsaKey.KeyPair)
+
+	reloaded := NewKeyStore(path, time.Hour, time.Minute)
+	edKey, err := reloaded.Current()
+	require.NoError(t, err)
+	assert.Equal(t, AlgorithmEd25519, edKey.Algorithm())
+
+	keys, err := reloaded.ValidKeys(time.Now())
+	require.NoError(t, err)
+	require.Len(t, keys, 2, "the RSA key stays valid for the grace period")
+	assert.Equal(t, AlgorithmRSA, keys[1].Algorithm())
+}
+
+func TestSignWithDeviceKeyExpiry(t *testing.T) {
+	tempDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "a.txt"), []byte("a"), 0644))
+	fsm := transfer.NewFileStructureManager()
+	require.NoError(t, fsm.AddPath(tempDir))
+
+	keyPair, err := GenerateKeyPair(KEY_PAIR_BIT_SIZE)
+	require.NoError(t, err)
+	key := &DeviceKey{KeyPair: keyPair, CreatedAt: time.Now(), ExpiresAt: time.Now().Add(time.Hour)}
+
+	signed, err := NewFileStructureSignerFromDeviceKey(key).SignFileStructureManager(fsm)
+	require.NoError(t, err)
+	assert.Equal(t, key.ExpiresAt.Unix(), signed.Metadata.KeyExpiresAt)
+	require.NoError(t, VerifyFileStructure(signed))
+
+	// The expiry is covered by the signature
+	signed.Metadata.KeyExpiresAt += 3600
+	assert.Error(t, VerifyFileStructure(signed))
+
+	key.ExpiresAt = time.Now().Add(-time.Second)
+	_, err = NewFileStructureSignerFromDeviceKey(key).SignFileStructureManager(fsm)
+	assert.ErrorIs(t, err, ErrKeyExpired)
+}
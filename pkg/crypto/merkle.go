@@ -0,0 +1,290 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rescp17/lanFileSharer/pkg/fileInfo"
+)
+
+// ErrMerkleProofInvalid is returned when a file does not prove into a signed Merkle root
+var ErrMerkleProofInvalid = errors.New("merkle proof invalid")
+
+// Domain separation prefixes, as in RFC 6962, so a leaf can never be passed off as an inner node
+const (
+	merkleLeafPrefix = 0x00
+	merkleNodePrefix = 0x01
+)
+
+// MerkleTree is a hash tree over the files of a structure, in the order they were signed.
+// It follows the RFC 6962 layout, so a proof for one file needs only log2(n) hashes.
+type MerkleTree struct {
+	// levels[0] holds the leaf hashes, every following level the hashes above them
+	levels [][][32]byte
+}
+
+// MerkleProof is the audit path proving that one file is part of a Merkle root
+type MerkleProof struct {
+	// Index is the position of the file among the signed files
+	Index int `json:"index"`
+	// Siblings are the hashes needed to rebuild the root, from the leaf upwards
+	Siblings [][]byte `json:"siblings"`
+}
+
+// SignedMerkleRoot is a signature over the Merkle root of a structure's files.
+// It is signed on its own, so a subset of files can be verified without the rest of the structure.
+type SignedMerkleRoot struct {
+	Root         []byte             `json:"root"`
+	LeafCount    int                `json:"leaf_count"`
+	SignedAt     int64              `json:"signed_at"`
+	KeyExpiresAt int64              `json:"key_expires_at,omitempty"`
+	Algorithm    SignatureAlgorithm `json:"algorithm,omitempty"`
+	Signature    []byte             `json:"signature"`
+}
+
+// ProvenFile is a file together with the proof that it belongs to a signed root
+type ProvenFile struct {
+	Node  fileInfo.FileNode `json:"node"`
+	Proof MerkleProof       `json:"proof"`
+}
+
+// FileSubset is a verifiable selection of files from a signed structure
+type FileSubset struct {
+	PublicKey  []byte            `json:"public_key"`
+	MerkleRoot *SignedMerkleRoot `json:"merkle_root"`
+	Files      []ProvenFile      `json:"files"`
+}
+
+// merkleLeafHash hashes the fields of a file that a receiver relies on.
+// Path is local to the sender and never sent, so the position in the tree stands in for it.
+func merkleLeafHash(node *fileInfo.FileNode) [32]byte {
+	h := sha256.New()
+	h.Write([]byte{merkleLeafPrefix})
+	for _, field := range []string{node.Name, node.Checksum} {
+		binary.Write(h, binary.BigEndian, uint32(len(field)))
+		h.Write([]byte(field))
+	}
+	binary.Write(h, binary.BigEndian, node.Size)
+	var sum [32]byte
+	copy(sum[:], h.Sum(nil))
+	return sum
+}
+
+func merkleNodeHash(left, right []byte) [32]byte {
+	h := sha256.New()
+	h.Write([]byte{merkleNodePrefix})
+	h.Write(left)
+	h.Write(right)
+	var sum [32]byte
+	copy(sum[:], h.Sum(nil))
+	return sum
+}
+
+// NewMerkleTree builds the Merkle tree over files
+func NewMerkleTree(files []fileInfo.FileNode) *MerkleTree {
+	leaves := make([][32]byte, len(files))
+	for i := range files {
+		leaves[i] = merkleLeafHash(&files[i])
+	}
+
+	tree := &MerkleTree{levels: [][][32]byte{leaves}}
+	for level := leaves; len(level) > 1; {
+		// A node without a right sibling moves up unchanged, which matches the RFC 6962 split
+		next := make([][32]byte, 0, (len(level)+1)/2)
+		for i := 0; i < len(level); i += 2 {
+			if i+1 == len(level) {
+				next = append(next, level[i])
+				continue
+			}
+			next = append(next, merkleNodeHash(level[i][:], level[i+1][:]))
+		}
+		tree.levels = append(tree.levels, next)
+		level = next
+	}
+	return tree
+}
+
+// LeafCount returns the number of files in the tree
+func (t *MerkleTree) LeafCount() int {
+	return len(t.levels[0])
+}
+
+// Root returns the Merkle root; the root of an empty tree is the hash of no data
+func (t *MerkleTree) Root() []byte {
+	if t.LeafCount() == 0 {
+		sum := sha256.Sum256(nil)
+		return sum[:]
+	}
+	root := t.levels[len(t.levels)-1][0]
+	return root[:]
+}
+
+// Proof returns the audit path for the file at index
+func (t *MerkleTree) Proof(index int) (MerkleProof, error) {
+	if index < 0 || index >= t.LeafCount() {
+		return MerkleProof{}, fmt.Errorf("leaf index %d out of range [0, %d)", index, t.LeafCount())
+	}
+
+	proof := MerkleProof{Index: index}
+	for _, level := range t.levels[:len(t.levels)-1] {
+		sibling := index ^ 1
+		if sibling < len(level) {
+			proof.Siblings = append(proof.Siblings, append([]byte(nil), level[sibling][:]...))
+		}
+		index /= 2
+	}
+	return proof, nil
+}
+
+// merkleRootFromProof rebuilds the root for node from its proof in a tree of leafCount files
+func merkleRootFromProof(node *fileInfo.FileNode, proof MerkleProof, leafCount int) ([]byte, error) {
+	if proof.Index < 0 || proof.Index >= leafCount {
+		return nil, fmt.Errorf("%w: index %d out of range [0, %d)", ErrMerkleProofInvalid, proof.Index, leafCount)
+	}
+
+	hash := merkleLeafHash(node)
+	index, width := proof.Index, leafCount
+	siblings := proof.Siblings
+	for width > 1 {
+		sibling := index ^ 1
+		if sibling < width {
+			if len(siblings) == 0 {
+				return nil, fmt.Errorf("%w: proof too short", ErrMerkleProofInvalid)
+			}
+			if index%2 == 0 {
+				hash = merkleNodeHash(hash[:], siblings[0])
+			} else {
+				hash = merkleNodeHash(siblings[0], hash[:])
+			}
+			siblings = siblings[1:]
+		}
+		index /= 2
+		width = (width + 1) / 2
+	}
+	if len(siblings) != 0 {
+		return nil, fmt.Errorf("%w: proof too long", ErrMerkleProofInvalid)
+	}
+	return hash[:], nil
+}
+
+// merkleRootSignatureData is the signed content of a Merkle root
+type merkleRootSignatureData struct {
+	Root         []byte             `json:"root"`
+	LeafCount    int                `json:"leaf_count"`
+	SignedAt     int64              `json:"signed_at"`
+	KeyExpiresAt int64              `json:"key_expires_at,omitempty"`
+	Algorithm    SignatureAlgorithm `json:"algorithm"`
+}
+
+func (r *SignedMerkleRoot) digest() ([32]byte, error) {
+	dataJSON, err := json.Marshal(merkleRootSignatureData{
+		Root:         r.Root,
+		LeafCount:    r.LeafCount,
+		SignedAt:     r.SignedAt,
+		KeyExpiresAt: r.KeyExpiresAt,
+		Algorithm:    r.Algorithm.normalize(),
+	})
+	if err != nil {
+		return [32]byte{}, fmt.Errorf("failed to marshal merkle root signature data: %w", err)
+	}
+	return sha256.Sum256(dataJSON), nil
+}
+
+// signMerkleRoot signs the Merkle root of files
+func (s *FileStructureSigner) signMerkleRoot(files []fileInfo.FileNode, signedAt int64) (*SignedMerkleRoot, error) {
+	tree := NewMerkleTree(files)
+	root := &SignedMerkleRoot{
+		Root:         tree.Root(),
+		LeafCount:    tree.LeafCount(),
+		SignedAt:     signedAt,
+		KeyExpiresAt: s.keyExpiresAt,
+		Algorithm:    s.Algorithm(),
+	}
+
+	hash, err := root.digest()
+	if err != nil {
+		return nil, err
+	}
+	root.Signature, err = signDigest(s.privateKey(), hash[:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign merkle root: %w", err)
+	}
+	return root, nil
+}
+
+// Verify checks the root's signature against a DER encoded public key and the key's expiry
+func (r *SignedMerkleRoot) Verify(publicKey []byte) error {
+	if r == nil {
+		return fmt.Errorf("merkle root cannot be nil")
+	}
+
+	key, err := parsePublicKey(publicKey, r.Algorithm)
+	if err != nil {
+		return err
+	}
+	hash, err := r.digest()
+	if err != nil {
+		return err
+	}
+	if err := verifyDigest(key, hash[:], r.Signature); err != nil {
+		return fmt.Errorf("merkle root signature verification failed: %w", err)
+	}
+
+	if r.KeyExpiresAt != 0 && (r.SignedAt > r.KeyExpiresAt || time.Now().Unix() > r.KeyExpiresAt) {
+		return fmt.Errorf("%w at %s", ErrKeyExpired, time.Unix(r.KeyExpiresAt, 0).Format(time.RFC3339))
+	}
+	return nil
+}
+
+// VerifyFile checks that node is the file proven by proof under this root.
+// The root's signature must have been checked with Verify first.
+func (r *SignedMerkleRoot) VerifyFile(node fileInfo.FileNode, proof MerkleProof) error {
+	root, err := merkleRootFromProof(&node, proof, r.LeafCount)
+	if err != nil {
+		return fmt.Errorf("%s: %w", node.Name, err)
+	}
+	if !bytes.Equal(root, r.Root) {
+		return fmt.Errorf("%w: %s does not match the signed root", ErrMerkleProofInvalid, node.Name)
+	}
+	return nil
+}
+
+// Subset returns the files at indices of Files with the proofs a receiver needs to verify
+// them without the rest of the structure. The structure must carry a Merkle root.
+func (s *SignedFileStructure) Subset(indices []int) (*FileSubset, error) {
+	if s.MerkleRoot == nil {
+		return nil, fmt.Errorf("structure has no merkle root")
+	}
+
+	tree := NewMerkleTree(s.Files)
+	subset := &FileSubset{PublicKey: s.PublicKey, MerkleRoot: s.MerkleRoot}
+	for _, i := range indices {
+		proof, err := tree.Proof(i)
+		if err != nil {
+			return nil, err
+		}
+		subset.Files = append(subset.Files, ProvenFile{Node: s.Files[i], Proof: proof})
+	}
+	return subset, nil
+}
+
+// VerifyFileSubset verifies the signed root of subset and every file in it
+func VerifyFileSubset(subset *FileSubset) error {
+	if subset == nil {
+		return fmt.Errorf("file subset cannot be nil")
+	}
+	if err := subset.MerkleRoot.Verify(subset.PublicKey); err != nil {
+		return err
+	}
+	for _, file := range subset.Files {
+		if err := subset.MerkleRoot.VerifyFile(file.Node, file.Proof); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package crypto
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rescp17/lanFileSharer/pkg/fileInfo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func merkleTestFiles(count int) []fileInfo.FileNode {
+	files := make([]fileInfo.FileNode, count)
+	for i := range files {
+		name := fmt.Sprintf("file-%d.txt", i)
+		files[i] = fileInfo.FileNode{Name: name, Size: int64(i), Checksum: fmt.Sprintf("%064x", i)}
+	}
+	return files
+}
+
+func TestMerkleTree_ProofsForEveryShape(t *testing.T) {
+	for count := 1; count <= 17; count++ {
+		files := merkleTestFiles(count)
+		tree := NewMerkleTree(files)
+		root := &SignedMerkleRoot{Root: tree.Root(), LeafCount: tree.LeafCount()}
+
+		for i := range files {
+			proof, err := tree.Proof(i)
+			require.NoError(t, err)
+			assert.NoError(t, root.VerifyFile(files[i], proof), "count %d index %d", count, i)
+
+			// The proof must not carry over to another file
+			other := files[(i+1)%count]
+			if count > 1 {
+				assert.ErrorIs(t, root.VerifyFile(other, proof), ErrMerkleProofInvalid)
+			}
+		}
+	}
+}
+
+func TestMerkleTree_RejectsTamperedProofs(t *testing.T) {
+	files := merkleTestFiles(6)
+	tree := NewMerkleTree(files)
+	root := &SignedMerkleRoot{Root: tree.Root(), LeafCount: tree.LeafCount()}
+	proof, err := tree.Proof(4)
+	require.NoError(t, err)
+
+	changed := files[4]
+	changed.Checksum = fmt.Sprintf("%064x", 99)
+	assert.ErrorIs(t, root.VerifyFile(changed, proof), ErrMerkleProofInvalid)
+
+	short := MerkleProof{Index: proof.Index, Siblings: proof.Siblings[1:]}
+	assert.ErrorIs(t, root.VerifyFile(files[4], short), ErrMerkleProofInvalid)
+
+	long := MerkleProof{Index: proof.Index, Siblings: append(proof.Siblings, proof.Siblings[0])}
+	assert.ErrorIs(t, root.VerifyFile(files[4], long), ErrMerkleProofInvalid)
+
+	outOfRange := MerkleProof{Index: 6, Siblings: proof.Siblings}
+	assert.ErrorIs(t, root.VerifyFile(files[4], outOfRange), ErrMerkleProofInvalid)
+}
+
+func TestSignedStructure_VerifiesSubset(t *testing.T) {
+	tempDir := t.TempDir()
+	var paths []string
+	for i := 0; i < 5; i++ {
+		path := filepath.Join(tempDir, fmt.Sprintf("file-%d.txt", i))
+		require.NoError(t, os.WriteFile(path, []byte(fmt.Sprintf("content %d", i)), 0644))
+		paths = append(paths, path)
+	}
+	signed, err := CreateSignedFileStructure(paths)
+	require.NoError(t, err)
+	require.NotNil(t, signed.MerkleRoot)
+	require.NoError(t, VerifyFileStructure(signed))
+
+	subset, err := signed.Subset([]int{1, 3})
+	require.NoError(t, err)
+	assert.Len(t, subset.Files, 2)
+
+	// The subset travels on its own and verifies without the other files
+	data, err := json.Marshal(subset)
+	require.NoError(t, err)
+	var decoded FileSubset
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	require.NoError(t, VerifyFileSubset(&decoded))
+
+	decoded.Files[0].Node.Size++
+	assert.ErrorIs(t, VerifyFileSubset(&decoded), ErrMerkleProofInvalid)
+
+	_, err = signed.Subset([]int{5})
+	assert.Error(t, err)
+}
+
+func TestVerifyFileStructure_RejectsMismatchedMerkleRoot(t *testing.T) {
+	signer, err := NewEd25519FileStructureSigner()
+	require.NoError(t, err)
+	signed, err := signer.SignFileStructureManager(newTestManager(t))
+	require.NoError(t, err)
+
+	// A root signed by the same key over other files must not be accepted
+	other, err := signer.signMerkleRoot(merkleTestFiles(3), signed.Metadata.SignedAt)
+	require.NoError(t, err)
+	signed.MerkleRoot = other
+	assert.ErrorIs(t, VerifyFileStructure(signed), ErrMerkleProofInvalid)
+
+	// A root whose signature was tampered with fails as well
+	signed.MerkleRoot = &SignedMerkleRoot{Root: other.Root, LeafCount: other.LeafCount, Algorithm: other.Algorithm, Signature: other.Signature, SignedAt: other.SignedAt + 1}
+	assert.Error(t, VerifyFileStructure(signed))
+
+	// Structures from older senders have no root
+	signed.MerkleRoot = nil
+	assert.NoError(t, VerifyFileStructure(signed))
+}
@@ -1,12 +1,14 @@
 package crypto
 
 import (
+	"bytes"
+	"context"
 	"crypto"
+	"crypto/ed25519"
 	"crypto/rand"
 	"crypto/rsa"
-	"crypto/sha256"
 	"crypto/x509"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
@@ -14,9 +16,18 @@ import (
 	"github.com/rescp17/lanFileSharer/pkg/transfer"
 )
 
-// FileStructureSigner handles signing for FileNode structures using composition
+// ErrKeyExpired is returned when a structure was signed with a key past its expiry
+var ErrKeyExpired = errors.New("signing key expired")
+
+// FileStructureSigner handles signing for FileNode structures using composition.
+// It holds either an RSA key pair or an Ed25519 key.
 type FileStructureSigner struct {
-	keyPair *KeyPair
+	keyPair    *KeyPair
+	ed25519Key ed25519.PrivateKey
+	// keyExpiresAt is the unix expiry embedded in signatures, zero for ephemeral keys
+	keyExpiresAt int64
+	// endorsements of the key by the keys it replaced, set with SetEndorsements
+	endorsements []KeyEndorsement
 }
 
 // SignedFileStructure contains the file structure with digital signature
@@ -24,11 +35,18 @@ type SignedFileStructure struct {
 	Files     []fileInfo.FileNode `json:"files"`
 	PublicKey []byte              `json:"public_key"`
 	Signature []byte              `json:"signature"`
+	// Algorithm is how Signature was made; empty means RSA, as sent by older versions
+	Algorithm SignatureAlgorithm `json:"algorithm,omitempty"`
 
 	// Enhanced structure information
 	Directories []fileInfo.FileNode `json:"directories,omitempty"`
 	RootNodes   []fileInfo.FileNode `json:"root_nodes,omitempty"`
 	Metadata    *StructureMetadata  `json:"metadata,omitempty"`
+	// MerkleRoot signs a hash tree over Files, so files can be verified one by one
+	MerkleRoot *SignedMerkleRoot `json:"merkle_root,omitempty"`
+	// Endorsements of PublicKey by the sender's rotated keys. They are not covered by
+	// Signature, each is signed on its own.
+	Endorsements []KeyEndorsement `json:"endorsements,omitempty"`
 }
 
 // StructureMetadata contains additional information about the file structure
@@ -39,13 +57,16 @@ type StructureMetadata struct {
 	CreatedAt  int64  `json:"created_at"`
 	SignedAt   int64  `json:"signed_at"`
 	Version    string `json:"version"`
+	// KeyExpiresAt is the unix expiry of the signing key, zero when the key does not expire
+	KeyExpiresAt int64 `json:"key_expires_at,omitempty"`
 }
 
 const (
 	KEY_PAIR_BIT_SIZE = 2048
 )
 
-// NewFileStructureSigner creates a new signer with generated RSA key pair
+// NewFileStructureSigner creates a new signer with generated RSA key pair.
+// RSA signatures can be verified by every receiver, including older versions.
 func NewFileStructureSigner() (*FileStructureSigner, error) {
 	keyPair, err := GenerateKeyPair(KEY_PAIR_BIT_SIZE)
 	if err != nil {
@@ -57,31 +78,83 @@ func NewFileStructureSigner() (*FileStructureSigner, error) {
 	}, nil
 }
 
-// GetKeyPair returns the underlying key pair for advanced usage
+// NewEd25519FileStructureSigner creates a new signer with a generated Ed25519 key
+func NewEd25519FileStructureSigner() (*FileStructureSigner, error) {
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate Ed25519 key: %w", err)
+	}
+
+	return &FileStructureSigner{
+		ed25519Key: privateKey,
+	}, nil
+}
+
+// NewFileStructureSignerWithAlgorithm creates a new signer with a generated key for algorithm
+func NewFileStructureSignerWithAlgorithm(algorithm SignatureAlgorithm) (*FileStructureSigner, error) {
+	switch algorithm.normalize() {
+	case AlgorithmRSA:
+		return NewFileStructureSigner()
+	case AlgorithmEd25519:
+		return NewEd25519FileStructureSigner()
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedAlgorithm, algorithm)
+	}
+}
+
+// Algorithm returns the signature algorithm of the signer's key
+func (s *FileStructureSigner) Algorithm() SignatureAlgorithm {
+	if s.ed25519Key != nil {
+		return AlgorithmEd25519
+	}
+	return AlgorithmRSA
+}
+
+// GetKeyPair returns the underlying RSA key pair for advanced usage, nil for Ed25519 signers
 func (s *FileStructureSigner) GetKeyPair() *KeyPair {
 	return s.keyPair
 }
 
-// GetPublicKey returns the public key for direct access
+// GetPublicKey returns the RSA public key for direct access, nil for Ed25519 signers
 func (s *FileStructureSigner) GetPublicKey() *rsa.PublicKey {
+	if s.keyPair == nil {
+		return nil
+	}
 	return s.keyPair.PublicKey
 }
 
-// GetPrivateKey returns the private key for direct access (use with caution)
+// GetPrivateKey returns the RSA private key for direct access (use with caution), nil for Ed25519 signers
 func (s *FileStructureSigner) GetPrivateKey() *rsa.PrivateKey {
+	if s.keyPair == nil {
+		return nil
+	}
 	return s.keyPair.PrivateKey
 }
 
 // GetPublicKeyBytes returns the public key as bytes for external use
 func (s *FileStructureSigner) GetPublicKeyBytes() ([]byte, error) {
+	if s.ed25519Key != nil {
+		return x509.MarshalPKIXPublicKey(s.ed25519Key.Public())
+	}
 	return x509.MarshalPKIXPublicKey(s.keyPair.PublicKey)
 }
 
 // GetPrivateKeyBytes returns the private key as bytes (for testing/debugging only)
 func (s *FileStructureSigner) GetPrivateKeyBytes() ([]byte, error) {
+	if s.ed25519Key != nil {
+		return x509.MarshalPKCS8PrivateKey(s.ed25519Key)
+	}
 	return x509.MarshalPKCS1PrivateKey(s.keyPair.PrivateKey), nil
 }
 
+// privateKey returns the key used to sign
+func (s *FileStructureSigner) privateKey() crypto.Signer {
+	if s.ed25519Key != nil {
+		return s.ed25519Key
+	}
+	return s.keyPair.PrivateKey
+}
+
 // NewFileStructureSignerFromKeyPair creates a signer from an existing key pair
 func NewFileStructureSignerFromKeyPair(keyPair *KeyPair) *FileStructureSigner {
 	return &FileStructureSigner{
@@ -89,8 +162,40 @@ func NewFileStructureSignerFromKeyPair(keyPair *KeyPair) *FileStructureSigner {
 	}
 }
 
+// NewFileStructureSignerFromDeviceKey creates a signer that embeds the device key's expiry in signatures
+func NewFileStructureSignerFromDeviceKey(key *DeviceKey) *FileStructureSigner {
+	signer := &FileStructureSigner{
+		keyPair:    key.KeyPair,
+		ed25519Key: key.Ed25519Key,
+	}
+	if !key.ExpiresAt.IsZero() {
+		signer.keyExpiresAt = key.ExpiresAt.Unix()
+	}
+	return signer
+}
+
+// SetEndorsements sends endorsements, from KeyStore.Endorsements, along with the structures
+// the signer signs
+func (s *FileStructureSigner) SetEndorsements(endorsements []KeyEndorsement) {
+	s.endorsements = endorsements
+}
+
+// KeyExpiresAt returns the expiry of the signing key, zero when it does not expire
+func (s *FileStructureSigner) KeyExpiresAt() time.Time {
+	if s.keyExpiresAt == 0 {
+		return time.Time{}
+	}
+	return time.Unix(s.keyExpiresAt, 0)
+}
+
 // SignFileStructureManager creates a signed file structure from FileStructureManager
 func (s *FileStructureSigner) SignFileStructureManager(fsm *transfer.FileStructureManager) (*SignedFileStructure, error) {
+	return s.SignFileStructureManagerContext(context.Background(), fsm)
+}
+
+// SignFileStructureManagerContext signs like SignFileStructureManager, aborting the
+// serialization of large structures as soon as ctx is done
+func (s *FileStructureSigner) SignFileStructureManagerContext(ctx context.Context, fsm *transfer.FileStructureManager) (*SignedFileStructure, error) {
 	if fsm == nil {
 		return nil, fmt.Errorf("FileStructureManager cannot be nil")
 	}
@@ -117,48 +222,46 @@ func (s *FileStructureSigner) SignFileStructureManager(fsm *transfer.FileStructu
 		}
 	}
 
+	// The manager may be changed after signing, so the signed copies must not share its children
+	dirs = cloneNodes(dirs)
+	rootNodes = cloneNodes(rootNodes)
+
 	now := time.Now().Unix()
+	if s.keyExpiresAt != 0 && now > s.keyExpiresAt {
+		return nil, fmt.Errorf("%w: rotate the device key before signing", ErrKeyExpired)
+	}
 
-	// Create comprehensive signature data
-	signatureData := struct {
-		Files     []fileInfo.FileNode `json:"files"`
-		Dirs      []fileInfo.FileNode `json:"directories"`
-		RootNodes []fileInfo.FileNode `json:"root_nodes"`
-		Stats     struct {
-			FileCount int   `json:"file_count"`
-			DirCount  int   `json:"dir_count"`
-			TotalSize int64 `json:"total_size"`
-		} `json:"stats"`
-		Timestamp int64 `json:"timestamp"`
-	}{
-		Files:     files,
-		Dirs:      dirs,
-		RootNodes: rootNodes,
-		Stats: struct {
-			FileCount int   `json:"file_count"`
-			DirCount  int   `json:"dir_count"`
-			TotalSize int64 `json:"total_size"`
-		}{
-			FileCount: fsm.GetFileCount(),
-			DirCount:  fsm.GetDirCount(),
-			TotalSize: fsm.GetTotalSize(),
-		},
-		Timestamp: now,
-	}
-
-	// Serialize and sign
-	dataJSON, err := json.Marshal(signatureData)
+	// Hash the signature data as it is serialized, without holding it in memory
+	payload := signaturePayload{
+		Files:        files,
+		Dirs:         dirs,
+		RootNodes:    rootNodes,
+		FileCount:    fsm.GetFileCount(),
+		DirCount:     fsm.GetDirCount(),
+		TotalSize:    fsm.GetTotalSize(),
+		Timestamp:    now,
+		KeyExpiresAt: s.keyExpiresAt,
+		Algorithm:    s.Algorithm().signedName(),
+	}
+	hash, err := payload.digestContext(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal signature data: %w", err)
+		return nil, err
 	}
 
-	hash := sha256.Sum256(dataJSON)
-	signature, err := rsa.SignPKCS1v15(rand.Reader, s.keyPair.PrivateKey, crypto.SHA256, hash[:])
+	signature, err := signDigest(s.privateKey(), hash[:])
 	if err != nil {
 		return nil, fmt.Errorf("failed to sign data: %w", err)
 	}
 
-	publicKeyBytes, err := x509.MarshalPKIXPublicKey(s.keyPair.PublicKey)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	merkleRoot, err := s.signMerkleRoot(files, now)
+	if err != nil {
+		return nil, err
+	}
+
+	publicKeyBytes, err := s.GetPublicKeyBytes()
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal public key: %w", err)
 	}
@@ -171,21 +274,28 @@ func (s *FileStructureSigner) SignFileStructureManager(fsm *transfer.FileStructu
 		CreatedAt:  now,
 		SignedAt:   now,
 		Version:    "1.0",
+
+		KeyExpiresAt: s.keyExpiresAt,
 	}
 
 	return &SignedFileStructure{
 		Files:       files,
 		PublicKey:   publicKeyBytes,
 		Signature:   signature,
+		Algorithm:   s.Algorithm(),
 		Directories: dirs,
 		RootNodes:   rootNodes,
 		Metadata:    metadata,
+		MerkleRoot:  merkleRoot,
+
+		Endorsements: s.endorsements,
 	}, nil
 }
 
 // CreateSignedFileStructureFromManager creates a signed structure from FileStructureManager
+// using a one-time key of the default algorithm
 func CreateSignedFileStructureFromManager(fsm *transfer.FileStructureManager) (*SignedFileStructure, error) {
-	signer, err := NewFileStructureSigner()
+	signer, err := NewFileStructureSignerWithAlgorithm(DefaultSignatureAlgorithm)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create signer: %w", err)
 	}
@@ -199,72 +309,66 @@ func VerifyFileStructure(signedStructure *SignedFileStructure) error {
 		return fmt.Errorf("signed structure cannot be nil")
 	}
 
-	// Parse the public key
-	publicKeyInterface, err := x509.ParsePKIXPublicKey(signedStructure.PublicKey)
+	// Parse the public key, which must match the declared algorithm
+	publicKey, err := parsePublicKey(signedStructure.PublicKey, signedStructure.Algorithm)
 	if err != nil {
-		return fmt.Errorf("failed to parse public key: %w", err)
-	}
-
-	publicKey, ok := publicKeyInterface.(*rsa.PublicKey)
-	if !ok {
-		return fmt.Errorf("public key is not RSA")
-	}
-
-	// Recreate the signature data structure
-	signatureData := struct {
-		Files     []fileInfo.FileNode `json:"files"`
-		Dirs      []fileInfo.FileNode `json:"directories"`
-		RootNodes []fileInfo.FileNode `json:"root_nodes"`
-		Stats     struct {
-			FileCount int   `json:"file_count"`
-			DirCount  int   `json:"dir_count"`
-			TotalSize int64 `json:"total_size"`
-		} `json:"stats"`
-		Timestamp int64 `json:"timestamp"`
-	}{
+		return err
+	}
+
+	// Recreate the signature data
+	payload := signaturePayload{
 		Files:     signedStructure.Files,
 		Dirs:      signedStructure.Directories,
 		RootNodes: signedStructure.RootNodes,
-		Stats: struct {
-			FileCount int   `json:"file_count"`
-			DirCount  int   `json:"dir_count"`
-			TotalSize int64 `json:"total_size"`
-		}{
-			FileCount: len(signedStructure.Files),
-			DirCount:  len(signedStructure.Directories),
-			TotalSize: func() int64 {
-				var total int64
-				for _, file := range signedStructure.Files {
-					total += file.Size
-				}
-				return total
-			}(),
-		},
-		Timestamp: func() int64 {
-			if signedStructure.Metadata != nil {
-				return signedStructure.Metadata.SignedAt
-			}
-			return 0
-		}(),
-	}
-
-	// Serialize and verify
-	dataJSON, err := json.Marshal(signatureData)
+		FileCount: len(signedStructure.Files),
+		DirCount:  len(signedStructure.Directories),
+		TotalSize: totalFileSize(signedStructure.Files),
+		Algorithm: signedStructure.Algorithm.signedName(),
+	}
+	if signedStructure.Metadata != nil {
+		payload.Timestamp = signedStructure.Metadata.SignedAt
+		payload.KeyExpiresAt = signedStructure.Metadata.KeyExpiresAt
+	}
+
+	hash, err := payload.digest()
 	if err != nil {
-		return fmt.Errorf("failed to marshal signature data: %w", err)
+		return err
 	}
 
-	hash := sha256.Sum256(dataJSON)
-	err = rsa.VerifyPKCS1v15(publicKey, crypto.SHA256, hash[:], signedStructure.Signature)
+	err = verifyDigest(publicKey, hash[:], signedStructure.Signature)
 	if err != nil {
 		return fmt.Errorf("signature verification failed: %w", err)
 	}
 
+	// The expiry is covered by the signature, so it can be trusted now
+	if expiresAt := payload.KeyExpiresAt; expiresAt != 0 {
+		if payload.Timestamp > expiresAt || time.Now().Unix() > expiresAt {
+			return fmt.Errorf("%w at %s", ErrKeyExpired, time.Unix(expiresAt, 0).Format(time.RFC3339))
+		}
+	}
+
+	// Older senders do not send a Merkle root; when present it must describe the signed files
+	if root := signedStructure.MerkleRoot; root != nil {
+		if err := root.Verify(signedStructure.PublicKey); err != nil {
+			return err
+		}
+		tree := NewMerkleTree(signedStructure.Files)
+		if root.LeafCount != tree.LeafCount() || !bytes.Equal(root.Root, tree.Root()) {
+			return fmt.Errorf("%w: root does not match the signed files", ErrMerkleProofInvalid)
+		}
+	}
+
 	return nil
 }
 
 // CreateSignedFileStructure creates a signed file structure from file paths
 func CreateSignedFileStructure(filePaths []string) (*SignedFileStructure, error) {
+	return CreateSignedFileStructureContext(context.Background(), filePaths)
+}
+
+// CreateSignedFileStructureContext is CreateSignedFileStructure stopping the directory
+// walks, hashing and signing as soon as ctx is done
+func CreateSignedFileStructureContext(ctx context.Context, filePaths []string) (*SignedFileStructure, error) {
 	if len(filePaths) == 0 {
 		return nil, fmt.Errorf("file paths cannot be empty")
 	}
@@ -273,12 +377,16 @@ func CreateSignedFileStructure(filePaths []string) (*SignedFileStructure, error)
 	fsm := transfer.NewFileStructureManager()
 
 	for _, path := range filePaths {
-		err := fsm.AddPath(path)
+		err := fsm.AddPathContext(ctx, path)
 		if err != nil {
 			return nil, fmt.Errorf("failed to add path %s: %w", path, err)
 		}
 	}
 
 	// Create signed structure
-	return CreateSignedFileStructureFromManager(fsm)
+	signer, err := NewFileStructureSignerWithAlgorithm(DefaultSignatureAlgorithm)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create signer: %w", err)
+	}
+	return signer.SignFileStructureManagerContext(ctx, fsm)
 }
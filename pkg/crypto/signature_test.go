@@ -1,6 +1,7 @@
 package crypto
 
 import (
+	"context"
 	"crypto/x509"
 	"fmt"
 	"os"
@@ -566,3 +567,27 @@ func TestVerifyFileStructureWithNonRSAKey(t *testing.T) {
 		t.Error("Should fail with incomplete ASN.1 data")
 	}
 }
+
+func TestSignAndVerifyStreamNode(t *testing.T) {
+	fsm := transfer.NewFileStructureManager()
+	node := fileInfo.NewStdinNode("backup.sql")
+	require.NoError(t, fsm.AddFileNode(&node))
+
+	signed, err := CreateSignedFileStructureFromManager(fsm)
+	require.NoError(t, err)
+	assert.NoError(t, VerifyFileStructure(signed))
+}
+
+func TestSignFileStructureManagerContextCancelled(t *testing.T) {
+	fsm := newTestManager(t)
+	signer, err := NewFileStructureSigner()
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = signer.SignFileStructureManagerContext(ctx, fsm)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	_, err = CreateSignedFileStructureContext(ctx, []string{t.TempDir()})
+	assert.ErrorIs(t, err, context.Canceled)
+}
@@ -0,0 +1,199 @@
+package crypto
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// TrustStoreFileName is the trust store file in the application config directory
+const TrustStoreFileName = "trusted_keys.json"
+
+// TrustStatus describes what the trust store knows about a key
+type TrustStatus int
+
+const (
+	// TrustUnknown means the key has never been verified
+	TrustUnknown TrustStatus = iota
+	// TrustValid means the key was verified recently and has not expired
+	TrustValid
+	// TrustStale means the key expired or was verified too long ago and should be re-verified
+	TrustStale
+)
+
+func (s TrustStatus) String() string {
+	switch s {
+	case TrustValid:
+		return "trusted"
+	case TrustStale:
+		return "stale"
+	default:
+		return "unknown"
+	}
+}
+
+// TrustEntry records a verified peer key
+type TrustEntry struct {
+	Fingerprint  string    `json:"fingerprint"`
+	Label        string    `json:"label,omitempty"`
+	FirstSeen    time.Time `json:"first_seen"`
+	VerifiedAt   time.Time `json:"verified_at"`
+	KeyExpiresAt time.Time `json:"key_expires_at,omitempty"`
+}
+
+// TrustStore persists the peer keys a user has verified
+type TrustStore struct {
+	path     string
+	maxAge   time.Duration
+	readOnly bool
+
+	mu      sync.Mutex
+	entries map[string]*TrustEntry
+}
+
+// LoadTrustStore reads the trust store at path; a missing file yields an empty store.
+// Entries verified longer than maxAge ago are stale; a non-positive maxAge disables that check.
+func LoadTrustStore(path string, maxAge time.Duration) (*TrustStore, error) {
+	store := &TrustStore{
+		path:    path,
+		maxAge:  maxAge,
+		entries: make(map[string]*TrustEntry),
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return store, nil
+		}
+		return nil, fmt.Errorf("failed to read trust store: %w", err)
+	}
+	if err := json.Unmarshal(data, &store.entries); err != nil {
+		return nil, fmt.Errorf("failed to parse trust store %s: %w", path, err)
+	}
+	return store, nil
+}
+
+// Status returns the trust status of the key with fingerprint at now
+func (t *TrustStore) Status(fingerprint string, now time.Time) TrustStatus {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	entry, ok := t.entries[fingerprint]
+	if !ok {
+		return TrustUnknown
+	}
+	if !entry.KeyExpiresAt.IsZero() && now.After(entry.KeyExpiresAt) {
+		return TrustStale
+	}
+	if t.maxAge > 0 && now.After(entry.VerifiedAt.Add(t.maxAge)) {
+		return TrustStale
+	}
+	return TrustValid
+}
+
+// Endorser returns the entry of a key the store trusts that endorsed publicKey, one of
+// endorsements, so a sender's new key is trusted without being verified again. The endorsing
+// key is honored until DefaultRotationGrace after it expired, as its sender keeps it for a
+// grace period after rotating it.
+func (t *TrustStore) Endorser(publicKey []byte, endorsements []KeyEndorsement, now time.Time) (TrustEntry, bool) {
+	for _, endorsement := range endorsements {
+		if endorsement.Verify(publicKey) != nil {
+			continue
+		}
+		entry, ok := t.Entry(PublicKeyFingerprint(endorsement.PublicKey))
+		if !ok {
+			continue
+		}
+		if !entry.KeyExpiresAt.IsZero() && now.After(entry.KeyExpiresAt.Add(DefaultRotationGrace)) {
+			continue
+		}
+		if t.maxAge > 0 && now.After(entry.VerifiedAt.Add(t.maxAge)) {
+			continue
+		}
+		return entry, true
+	}
+	return TrustEntry{}, false
+}
+
+// StatusEndorsed returns the trust status of publicKey like Status, or TrustValid when a key
+// the store trusts endorsed it
+func (t *TrustStore) StatusEndorsed(publicKey []byte, endorsements []KeyEndorsement, now time.Time) TrustStatus {
+	status := t.Status(PublicKeyFingerprint(publicKey), now)
+	if status == TrustValid {
+		return status
+	}
+	if _, ok := t.Endorser(publicKey, endorsements, now); ok {
+		return TrustValid
+	}
+	return status
+}
+
+// Entry returns the stored entry for fingerprint
+func (t *TrustStore) Entry(fingerprint string) (TrustEntry, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	entry, ok := t.entries[fingerprint]
+	if !ok {
+		return TrustEntry{}, false
+	}
+	return *entry, true
+}
+
+// SetReadOnly makes Trust leave the store unchanged, for stores provisioned with Import
+// where only the keys provisioned may be trusted
+func (t *TrustStore) SetReadOnly(readOnly bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.readOnly = readOnly
+}
+
+// ReadOnly reports whether Trust leaves the store unchanged
+func (t *TrustStore) ReadOnly() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.readOnly
+}
+
+// Trust marks the key with fingerprint as verified now, unless the store is read-only
+func (t *TrustStore) Trust(fingerprint, label string, keyExpiresAt time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.readOnly {
+		return
+	}
+
+	now := time.Now()
+	entry, ok := t.entries[fingerprint]
+	if !ok {
+		entry = &TrustEntry{Fingerprint: fingerprint, FirstSeen: now}
+		t.entries[fingerprint] = entry
+	}
+	if label != "" {
+		entry.Label = label
+	}
+	entry.VerifiedAt = now
+	entry.KeyExpiresAt = keyExpiresAt
+}
+
+// Save writes the trust store to disk, creating parent directories as needed
+func (t *TrustStore) Save() error {
+	t.mu.Lock()
+	data, err := json.MarshalIndent(t.entries, "", "  ")
+	t.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to marshal trust store: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(t.path), 0700); err != nil {
+		return fmt.Errorf("failed to create trust store directory: %w", err)
+	}
+	if err := os.WriteFile(t.path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write trust store: %w", err)
+	}
+	return nil
+}
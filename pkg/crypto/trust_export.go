@@ -0,0 +1,137 @@
+package crypto
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+)
+
+// trustExportVersion is the format of signed trust store exports
+const trustExportVersion = 1
+
+// ErrTrustExportSignature is returned by ReadTrustExport when the export was altered or its
+// signature does not verify
+var ErrTrustExportSignature = errors.New("trust export signature does not verify")
+
+// TrustExport is the contents of a trust store exported to provision other receivers with
+// the same trusted sender keys
+type TrustExport struct {
+	Created  time.Time    `json:"created"`
+	Hostname string       `json:"hostname,omitempty"` // Machine the export was made on
+	Entries  []TrustEntry `json:"entries"`
+}
+
+// signedTrustExport is the on-disk form of a TrustExport, signed with the device key of the
+// exporting machine. Payload is signed as written, so it verifies without re-encoding.
+type signedTrustExport struct {
+	Version   int                `json:"version"`
+	Algorithm SignatureAlgorithm `json:"algorithm"`
+	PublicKey []byte             `json:"public_key"`
+	Signature []byte             `json:"signature"`
+	Payload   json.RawMessage    `json:"payload"`
+}
+
+// Entries returns the entries of the store sorted by fingerprint
+func (t *TrustStore) Entries() []TrustEntry {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	entries := make([]TrustEntry, 0, len(t.entries))
+	for _, entry := range t.entries {
+		entries = append(entries, *entry)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Fingerprint < entries[j].Fingerprint })
+	return entries
+}
+
+// Export writes every entry of the store to w, signed with key
+func (t *TrustStore) Export(w io.Writer, key *DeviceKey) error {
+	hostname, _ := os.Hostname()
+	payload, err := json.Marshal(TrustExport{Created: time.Now(), Hostname: hostname, Entries: t.Entries()})
+	if err != nil {
+		return fmt.Errorf("failed to marshal trust export: %w", err)
+	}
+
+	signer := NewFileStructureSignerFromDeviceKey(key)
+	publicKey, err := signer.GetPublicKeyBytes()
+	if err != nil {
+		return fmt.Errorf("failed to marshal public key: %w", err)
+	}
+	digest := sha256.Sum256(payload)
+	signature, err := signDigest(signer.privateKey(), digest[:])
+	if err != nil {
+		return fmt.Errorf("failed to sign trust export: %w", err)
+	}
+
+	signed := signedTrustExport{
+		Version:   trustExportVersion,
+		Algorithm: signer.Algorithm(),
+		PublicKey: publicKey,
+		Signature: signature,
+		Payload:   payload,
+	}
+	if err := json.NewEncoder(w).Encode(signed); err != nil {
+		return fmt.Errorf("failed to write trust export: %w", err)
+	}
+	return nil
+}
+
+// ReadTrustExport reads an export written by Export and verifies its signature. It returns
+// the fingerprint of the key that signed it, which the caller must check is one it trusts
+// to provision keys.
+func ReadTrustExport(r io.Reader) (*TrustExport, string, error) {
+	var signed signedTrustExport
+	if err := json.NewDecoder(r).Decode(&signed); err != nil {
+		return nil, "", fmt.Errorf("failed to parse trust export: %w", err)
+	}
+	if signed.Version != trustExportVersion {
+		return nil, "", fmt.Errorf("unsupported trust export version %d", signed.Version)
+	}
+	publicKey, err := parsePublicKey(signed.PublicKey, signed.Algorithm)
+	if err != nil {
+		return nil, "", err
+	}
+	digest := sha256.Sum256(signed.Payload)
+	if err := verifyDigest(publicKey, digest[:], signed.Signature); err != nil {
+		return nil, "", ErrTrustExportSignature
+	}
+
+	var export TrustExport
+	if err := json.Unmarshal(signed.Payload, &export); err != nil {
+		return nil, "", fmt.Errorf("failed to parse trust export contents: %w", err)
+	}
+	for _, entry := range export.Entries {
+		if entry.Fingerprint == "" {
+			return nil, "", errors.New("trust export contains an entry without a fingerprint")
+		}
+	}
+	return &export, PublicKeyFingerprint(signed.PublicKey), nil
+}
+
+// Import adds entries to the store, or replaces every entry with them if replace is set.
+// An entry already in the store is updated when the imported one was verified later.
+// It returns how many entries were added or updated.
+func (t *TrustStore) Import(entries []TrustEntry, replace bool) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if replace {
+		t.entries = make(map[string]*TrustEntry, len(entries))
+	}
+	changed := 0
+	for _, entry := range entries {
+		current, ok := t.entries[entry.Fingerprint]
+		if ok && !entry.VerifiedAt.After(current.VerifiedAt) {
+			continue
+		}
+		imported := entry
+		t.entries[entry.Fingerprint] = &imported
+		changed++
+	}
+	return changed
+}
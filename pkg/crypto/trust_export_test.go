@@ -0,0 +1,73 @@
+package crypto
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrustStore_ExportImport(t *testing.T) {
+	key, err := NewKeyStore(filepath.Join(t.TempDir(), "device_keys.json"), time.Hour, time.Minute).Current()
+	require.NoError(t, err)
+	source, err := LoadTrustStore(filepath.Join(t.TempDir(), "trust.json"), 0)
+	require.NoError(t, err)
+	source.Trust("aaa", "laptop", time.Time{})
+	source.Trust("bbb", "", time.Time{})
+
+	var exported bytes.Buffer
+	require.NoError(t, source.Export(&exported, key))
+
+	export, signer, err := ReadTrustExport(bytes.NewReader(exported.Bytes()))
+	require.NoError(t, err)
+	fingerprint, err := key.Fingerprint()
+	require.NoError(t, err)
+	assert.Equal(t, fingerprint, signer)
+	require.Len(t, export.Entries, 2)
+	assert.Equal(t, "laptop", export.Entries[0].Label)
+
+	target, err := LoadTrustStore(filepath.Join(t.TempDir(), "trust.json"), 0)
+	require.NoError(t, err)
+	target.Trust("ccc", "", time.Time{})
+	assert.Equal(t, 2, target.Import(export.Entries, false))
+	assert.Len(t, target.Entries(), 3)
+	assert.Equal(t, 0, target.Import(export.Entries, false), "entries verified no later are kept")
+
+	assert.Equal(t, 2, target.Import(export.Entries, true))
+	assert.Equal(t, TrustUnknown, target.Status("ccc", time.Now()), "replacing drops the other entries")
+	assert.Equal(t, TrustValid, target.Status("aaa", time.Now()))
+}
+
+func TestReadTrustExport_RejectsAlteredExport(t *testing.T) {
+	key, err := NewKeyStore(filepath.Join(t.TempDir(), "device_keys.json"), time.Hour, time.Minute).Current()
+	require.NoError(t, err)
+	store, err := LoadTrustStore(filepath.Join(t.TempDir(), "trust.json"), 0)
+	require.NoError(t, err)
+	store.Trust("aaa", "", time.Time{})
+
+	var exported bytes.Buffer
+	require.NoError(t, store.Export(&exported, key))
+	var signed signedTrustExport
+	require.NoError(t, json.Unmarshal(exported.Bytes(), &signed))
+	signed.Payload = bytes.Replace(signed.Payload, []byte(`"aaa"`), []byte(`"eee"`), 1)
+	altered, err := json.Marshal(signed)
+	require.NoError(t, err)
+
+	_, _, err = ReadTrustExport(bytes.NewReader(altered))
+	assert.ErrorIs(t, err, ErrTrustExportSignature)
+}
+
+func TestTrustStore_ReadOnly(t *testing.T) {
+	store, err := LoadTrustStore(filepath.Join(t.TempDir(), "trust.json"), 0)
+	require.NoError(t, err)
+	store.Import([]TrustEntry{{Fingerprint: "aaa", VerifiedAt: time.Now()}}, false)
+	store.SetReadOnly(true)
+
+	store.Trust("bbb", "", time.Time{})
+	assert.Equal(t, TrustUnknown, store.Status("bbb", time.Now()), "read-only stores take no new keys")
+	assert.Equal(t, TrustValid, store.Status("aaa", time.Now()))
+}
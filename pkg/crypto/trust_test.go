@@ -0,0 +1,68 @@
+package crypto
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrustStore_Status(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "trust.json")
+	store, err := LoadTrustStore(path, time.Hour)
+	require.NoError(t, err)
+
+	now := time.Now()
+	assert.Equal(t, TrustUnknown, store.Status("abc", now))
+
+	store.Trust("abc", "laptop", now.Add(24*time.Hour))
+	assert.Equal(t, TrustValid, store.Status("abc", now))
+	assert.Equal(t, TrustStale, store.Status("abc", now.Add(2*time.Hour)), "entries must be re-verified after max age")
+
+	store.Trust("short", "", now.Add(time.Minute))
+	assert.Equal(t, TrustStale, store.Status("short", now.Add(2*time.Minute)), "entries go stale when the key expires")
+
+	require.NoError(t, store.Save())
+	reloaded, err := LoadTrustStore(path, time.Hour)
+	require.NoError(t, err)
+	entry, ok := reloaded.Entry("abc")
+	require.True(t, ok)
+	assert.Equal(t, "laptop", entry.Label)
+	assert.Equal(t, TrustValid, reloaded.Status("abc", now))
+}
+
+func TestTrustStore_HonorsEndorsementsOfRotatedKeys(t *testing.T) {
+	keys := NewKeyStore(filepath.Join(t.TempDir(), "device_keys.json"), time.Hour, time.Minute)
+	old, err := keys.Current()
+	require.NoError(t, err)
+	oldFingerprint, err := old.Fingerprint()
+	require.NoError(t, err)
+	rotated, err := keys.Rotate()
+	require.NoError(t, err)
+	newKey, err := NewFileStructureSignerFromDeviceKey(rotated).GetPublicKeyBytes()
+	require.NoError(t, err)
+	endorsements, err := keys.Endorsements(time.Now())
+	require.NoError(t, err)
+
+	store, err := LoadTrustStore(filepath.Join(t.TempDir(), "trust.json"), 0)
+	require.NoError(t, err)
+	now := time.Now()
+	assert.Equal(t, TrustUnknown, store.StatusEndorsed(newKey, endorsements, now), "the endorsing key is not trusted yet")
+
+	store.Trust(oldFingerprint, "laptop", old.ExpiresAt)
+	assert.Equal(t, TrustUnknown, store.StatusEndorsed(newKey, nil, now))
+	assert.Equal(t, TrustValid, store.StatusEndorsed(newKey, endorsements, now))
+	entry, ok := store.Endorser(newKey, endorsements, now)
+	require.True(t, ok)
+	assert.Equal(t, "laptop", entry.Label)
+
+	afterExpiry := old.ExpiresAt.Add(time.Minute)
+	assert.Equal(t, TrustValid, store.StatusEndorsed(newKey, endorsements, afterExpiry), "the old key is honored in its grace period")
+	assert.Equal(t, TrustUnknown, store.StatusEndorsed(newKey, endorsements, old.ExpiresAt.Add(DefaultRotationGrace+time.Minute)))
+
+	forged := endorsements[0]
+	forged.PublicKey = newKey
+	assert.Equal(t, TrustUnknown, store.StatusEndorsed(newKey, []KeyEndorsement{forged}, now))
+}
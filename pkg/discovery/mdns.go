@@ -8,11 +8,34 @@ import (
 	"github.com/brutella/dnssd"
 )
 
-type MDNSAdapter struct{}
+type MDNSAdapter struct {
+	mu        sync.Mutex
+	announced map[announcedKey]announcedService // Services being announced
+}
 
-func (m *MDNSAdapter) Announce(ctx context.Context, serviceInfo ServiceInfo) error {
+// announcedKey identifies an announcement; one name can be announced on several types
+type announcedKey struct {
+	name, serviceType string
+}
+
+// announcedService is what UpdateMeta needs to change an announced TXT record
+type announcedService struct {
+	responder dnssd.Responder
+	handle    dnssd.ServiceHandle
+}
+
+// serviceText returns the TXT record entries of serviceInfo
+func serviceText(serviceInfo ServiceInfo) map[string]string {
 	text := make(map[string]string)
+	if serviceInfo.Meta.Advertised {
+		text = serviceInfo.Meta.Text()
+	}
 	text["desc"] = "Local file sender"
+	return text
+}
+
+func (m *MDNSAdapter) Announce(ctx context.Context, serviceInfo ServiceInfo) error {
+	text := serviceText(serviceInfo)
 
 	cfg := dnssd.Config{
 		Name:   serviceInfo.Name,
@@ -34,9 +57,22 @@ func (m *MDNSAdapter) Announce(ctx context.Context, serviceInfo ServiceInfo) err
 		return fmt.Errorf("failed to create mDNS responder: %w", err)
 	}
 
-	if _, err = rp.Add(service); err != nil {
+	handle, err := rp.Add(service)
+	if err != nil {
 		return fmt.Errorf("failed to add mDNS service: %w", err)
 	}
+	m.mu.Lock()
+	if m.announced == nil {
+		m.announced = make(map[announcedKey]announcedService)
+	}
+	key := announcedKey{name: serviceInfo.Name, serviceType: serviceInfo.Type}
+	m.announced[key] = announcedService{responder: rp, handle: handle}
+	m.mu.Unlock()
+	defer func() {
+		m.mu.Lock()
+		delete(m.announced, key)
+		m.mu.Unlock()
+	}()
 
 	if err = rp.Respond(ctx); err != nil {
 		// Context cancellation is not an error in normal operation
@@ -50,6 +86,26 @@ func (m *MDNSAdapter) Announce(ctx context.Context, serviceInfo ServiceInfo) err
 	return nil
 }
 
+// UpdateMeta replaces the metadata in the TXT records of the service name on every type it is announced on
+func (m *MDNSAdapter) UpdateMeta(name string, meta ServiceMeta) error {
+	m.mu.Lock()
+	var services []announcedService
+	for key, service := range m.announced {
+		if key.name == name {
+			services = append(services, service)
+		}
+	}
+	m.mu.Unlock()
+	if len(services) == 0 {
+		return fmt.Errorf("service %q is not announced", name)
+	}
+	text := serviceText(ServiceInfo{Meta: meta})
+	for _, service := range services {
+		service.handle.UpdateText(text, service.responder)
+	}
+	return nil
+}
+
 // DiscoveryResult contains either service info or an error
 // DiscoverWithErrors returns a channel that can contain both services and errors
 func (m *MDNSAdapter) Discover(ctx context.Context, service string) <-chan DiscoveryResult {
@@ -87,6 +143,7 @@ func (m *MDNSAdapter) Discover(ctx context.Context, service string) <-chan Disco
 			Domain: e.Domain,
 			Addr:   e.IPs[0],
 			Port:   e.Port,
+			Meta:   ParseServiceMeta(e.Text),
 		}
 		mu.Unlock()
 		sendSnapshot()
@@ -0,0 +1,111 @@
+package discovery
+
+import "strconv"
+
+// TXT record keys of a receiver's advertisement
+const (
+	TextKeyVersion     = "version"
+	TextKeyFree        = "free"
+	TextKeyAutoAccept  = "auto_accept"
+	TextKeyLoad        = "load"
+	TextKeyRelay       = "relay"
+	TextKeyShare       = "share"
+	TextKeyFingerprint = "fingerprint"
+	TextKeyWired       = "wired"
+	TextKeyIcon        = "icon"
+)
+
+// Load values advertised under TextKeyLoad
+const (
+	LoadIdle         = "idle"
+	LoadBusy         = "busy"
+	LoadDoNotDisturb = "dnd"
+)
+
+// ServiceMeta is the state a receiver advertises in its TXT record, so senders can tell
+// before selecting it whether it can take a transfer
+type ServiceMeta struct {
+	Advertised bool   // Whether the record carried metadata; older receivers send none
+	Version    string // App version of the receiver
+	FreeBytes  int64  // Free space in the output directory, negative if unknown
+	AutoAccept bool   // Whether requests are accepted without asking the user
+	Busy       bool   // Whether the receiver is handling a request
+	// DoNotDisturb is set while the receiver declines requests without asking
+	DoNotDisturb bool
+	// Relay is set by receivers that store files and forward them to another receiver
+	Relay bool
+	// Share is set by devices publishing a directory that receivers can browse and pull from
+	Share bool
+	// Fingerprint is the receiver's device key fingerprint, which senders look up their
+	// settings for the peer by. It is not verified, so it must not decide what is trusted.
+	Fingerprint string
+	// Wired is set by receivers with a wired interface on the LAN, so a sender about to send
+	// over Wi-Fi can suggest the wired path
+	Wired bool
+	// Icon is the emoji or glyph the receiver shows itself with; like the name, it must be
+	// sanitized before it is displayed
+	Icon string
+}
+
+// Text encodes m as TXT record entries
+func (m ServiceMeta) Text() map[string]string {
+	text := map[string]string{
+		TextKeyVersion:    m.Version,
+		TextKeyAutoAccept: strconv.FormatBool(m.AutoAccept),
+		TextKeyLoad:       LoadIdle,
+	}
+	switch {
+	case m.DoNotDisturb:
+		text[TextKeyLoad] = LoadDoNotDisturb
+	case m.Busy:
+		text[TextKeyLoad] = LoadBusy
+	}
+	if m.Relay {
+		text[TextKeyRelay] = "true"
+	}
+	if m.Share {
+		text[TextKeyShare] = "true"
+	}
+	if m.Fingerprint != "" {
+		text[TextKeyFingerprint] = m.Fingerprint
+	}
+	if m.Wired {
+		text[TextKeyWired] = "true"
+	}
+	if m.Icon != "" {
+		text[TextKeyIcon] = m.Icon
+	}
+	if m.FreeBytes >= 0 {
+		text[TextKeyFree] = strconv.FormatInt(m.FreeBytes, 10)
+	}
+	return text
+}
+
+// ParseServiceMeta decodes the metadata of a TXT record, tolerating missing and malformed entries
+func ParseServiceMeta(text map[string]string) ServiceMeta {
+	meta := ServiceMeta{FreeBytes: -1}
+	version, ok := text[TextKeyVersion]
+	if !ok {
+		return meta
+	}
+	meta.Advertised = true
+	meta.Version = version
+	if free, err := strconv.ParseInt(text[TextKeyFree], 10, 64); err == nil && free >= 0 {
+		meta.FreeBytes = free
+	}
+	meta.AutoAccept, _ = strconv.ParseBool(text[TextKeyAutoAccept])
+	meta.Busy = text[TextKeyLoad] == LoadBusy
+	meta.DoNotDisturb = text[TextKeyLoad] == LoadDoNotDisturb
+	meta.Relay, _ = strconv.ParseBool(text[TextKeyRelay])
+	meta.Share, _ = strconv.ParseBool(text[TextKeyShare])
+	meta.Fingerprint = text[TextKeyFingerprint]
+	meta.Wired, _ = strconv.ParseBool(text[TextKeyWired])
+	meta.Icon = text[TextKeyIcon]
+	return meta
+}
+
+// MetaUpdater is implemented by adapters that can change the metadata of a service they
+// announce. Browsers that already found the service may only see the change on their next lookup.
+type MetaUpdater interface {
+	UpdateMeta(name string, meta ServiceMeta) error
+}
@@ -0,0 +1,71 @@
+package discovery
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServiceMeta_RoundTrip(t *testing.T) {
+	meta := ServiceMeta{Advertised: true, Version: "v1.2.0", FreeBytes: 2 << 30, AutoAccept: true, Busy: true}
+	assert.Equal(t, meta, ParseServiceMeta(meta.Text()))
+	assert.NotContains(t, meta.Text(), TextKeyFingerprint)
+
+	withKey := ServiceMeta{Advertised: true, Version: "v1", FreeBytes: -1, Fingerprint: "0123abcd"}
+	assert.Equal(t, withKey, ParseServiceMeta(withKey.Text()))
+
+	dnd := ServiceMeta{Advertised: true, Version: "v1", FreeBytes: 0, DoNotDisturb: true}
+	assert.Equal(t, LoadDoNotDisturb, dnd.Text()[TextKeyLoad])
+	assert.Equal(t, dnd, ParseServiceMeta(dnd.Text()))
+
+	relay := ServiceMeta{Advertised: true, Version: "v1", FreeBytes: 1 << 30, Relay: true}
+	assert.Equal(t, "true", relay.Text()[TextKeyRelay])
+	assert.Equal(t, relay, ParseServiceMeta(relay.Text()))
+	assert.NotContains(t, meta.Text(), TextKeyRelay, "only relays advertise the key")
+
+	shared := ServiceMeta{Advertised: true, Version: "v1", FreeBytes: -1, Share: true}
+	assert.Equal(t, "true", shared.Text()[TextKeyShare])
+	assert.Equal(t, shared, ParseServiceMeta(shared.Text()))
+	assert.NotContains(t, meta.Text(), TextKeyShare)
+
+	wired := ServiceMeta{Advertised: true, Version: "v1", FreeBytes: -1, Wired: true}
+	assert.Equal(t, "true", wired.Text()[TextKeyWired])
+	assert.Equal(t, wired, ParseServiceMeta(wired.Text()))
+	assert.NotContains(t, meta.Text(), TextKeyWired)
+
+	icon := ServiceMeta{Advertised: true, Version: "v1", FreeBytes: -1, Icon: "💻"}
+	assert.Equal(t, "💻", icon.Text()[TextKeyIcon])
+	assert.Equal(t, icon, ParseServiceMeta(icon.Text()))
+	assert.NotContains(t, meta.Text(), TextKeyIcon)
+
+	unknownFree := ServiceMeta{Advertised: true, Version: "dev", FreeBytes: -1}
+	text := unknownFree.Text()
+	assert.NotContains(t, text, TextKeyFree)
+	assert.Equal(t, LoadIdle, text[TextKeyLoad])
+	assert.Equal(t, unknownFree, ParseServiceMeta(text))
+}
+
+func TestParseServiceMeta_Tolerant(t *testing.T) {
+	legacy := ParseServiceMeta(map[string]string{"desc": "Local file sender"})
+	assert.False(t, legacy.Advertised)
+	assert.Equal(t, int64(-1), legacy.FreeBytes)
+
+	malformed := ParseServiceMeta(map[string]string{TextKeyVersion: "v1", TextKeyFree: "lots", TextKeyAutoAccept: "maybe"})
+	assert.True(t, malformed.Advertised)
+	assert.Equal(t, int64(-1), malformed.FreeBytes)
+	assert.False(t, malformed.AutoAccept)
+	assert.False(t, malformed.Busy)
+}
+
+func TestMDNSAdapter_UpdateMetaRequiresAnnouncement(t *testing.T) {
+	adapter := &MDNSAdapter{}
+	assert.Error(t, adapter.UpdateMeta("not-announced", ServiceMeta{Advertised: true}))
+}
+
+func TestServiceText(t *testing.T) {
+	assert.Equal(t, map[string]string{"desc": "Local file sender"}, serviceText(ServiceInfo{}))
+
+	text := serviceText(ServiceInfo{Meta: ServiceMeta{Advertised: true, Version: "v1", FreeBytes: -1}})
+	assert.Equal(t, "v1", text[TextKeyVersion])
+	assert.Equal(t, "Local file sender", text["desc"])
+}
@@ -0,0 +1,20 @@
+package discovery
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOfflineAdapter(t *testing.T) {
+	var adapter Adapter = OfflineAdapter{}
+
+	_, ok := <-adapter.Discover(context.Background(), DefaultServerType)
+	assert.False(t, ok, "nothing is discovered")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.NoError(t, adapter.Announce(ctx, ServiceInfo{Name: "peer"}), "announcing lasts until ctx is done")
+}
@@ -0,0 +1,68 @@
+package discovery
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// serviceTypePattern matches "_name._tcp" or "_name._udp" with an RFC 6335 service name
+var serviceTypePattern = regexp.MustCompile(`^_([a-z0-9]|[a-z0-9][a-z0-9-]{0,13}[a-z0-9])\._(tcp|udp)$`)
+
+// Scope is the mDNS service type and domain receivers are announced and looked up in.
+// Organizations can use their own type, e.g. "_acme-share._tcp", to keep deployments apart.
+type Scope struct {
+	Type   string
+	Domain string
+}
+
+// DefaultScope returns the scope used when none is configured
+func DefaultScope() Scope {
+	return Scope{Type: DefaultServerType, Domain: DefaultDomain}
+}
+
+// ParseScope validates serviceType and domain, defaulting empty values
+func ParseScope(serviceType, domain string) (Scope, error) {
+	scope := DefaultScope()
+	if serviceType = strings.ToLower(strings.Trim(serviceType, ".")); serviceType != "" {
+		if !serviceTypePattern.MatchString(serviceType) || strings.Contains(serviceType, "--") {
+			return scope, fmt.Errorf("invalid service type %q, expected e.g. \"_acme-share._tcp\"", serviceType)
+		}
+		scope.Type = serviceType
+	}
+	if domain = strings.ToLower(strings.Trim(domain, ".")); domain != "" {
+		for _, label := range strings.Split(domain, ".") {
+			if label == "" || strings.ContainsAny(label, " \t") {
+				return scope, fmt.Errorf("invalid service domain %q", domain)
+			}
+		}
+		scope.Domain = domain
+	}
+	return scope, nil
+}
+
+// Query returns the name browsed to find services in s, e.g. "_file-sharing._tcp.local."
+func (s Scope) Query() string {
+	return fmt.Sprintf("%s.%s.", s.Type, s.Domain)
+}
+
+// Matches reports whether service was announced in s. An empty type or domain, left
+// unset by adapters that do not report it, matches any.
+func (s Scope) Matches(service ServiceInfo) bool {
+	matches := func(got, want string) bool {
+		got = strings.Trim(got, ".")
+		return got == "" || strings.EqualFold(got, want)
+	}
+	return matches(service.Type, s.Type) && matches(service.Domain, s.Domain)
+}
+
+// Filter returns the services of services announced in s
+func (s Scope) Filter(services []ServiceInfo) []ServiceInfo {
+	filtered := make([]ServiceInfo, 0, len(services))
+	for _, service := range services {
+		if s.Matches(service) {
+			filtered = append(filtered, service)
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,40 @@
+package discovery
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseScope(t *testing.T) {
+	scope, err := ParseScope("", "")
+	require.NoError(t, err)
+	assert.Equal(t, DefaultScope(), scope)
+
+	scope, err = ParseScope("_Acme-Share._tcp.", "corp.example.")
+	require.NoError(t, err)
+	assert.Equal(t, Scope{Type: "_acme-share._tcp", Domain: "corp.example"}, scope)
+	assert.Equal(t, "_acme-share._tcp.corp.example.", scope.Query())
+
+	for _, invalid := range []string{"acme._tcp", "_acme._sctp", "_-acme._tcp", "_a--b._tcp", "_this-name-is-too-long._tcp", "_acme"} {
+		_, err := ParseScope(invalid, "")
+		assert.Error(t, err, invalid)
+	}
+	_, err = ParseScope("", "corp..example")
+	assert.Error(t, err)
+}
+
+func TestScope_Filter(t *testing.T) {
+	scope := Scope{Type: "_acme-share._tcp", Domain: "local"}
+	services := []ServiceInfo{
+		{Name: "ours", Type: "_acme-share._tcp", Domain: "local."},
+		{Name: "other-team", Type: "_file-sharing._tcp", Domain: "local"},
+		{Name: "other-domain", Type: "_acme-share._tcp", Domain: "corp"},
+		{Name: "unreported"},
+	}
+	filtered := scope.Filter(services)
+	require.Len(t, filtered, 2)
+	assert.Equal(t, "ours", filtered[0].Name)
+	assert.Equal(t, "unreported", filtered[1].Name)
+}
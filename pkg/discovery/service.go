@@ -21,6 +21,7 @@ type ServiceInfo struct {
 	Domain string // domain, e.g., "local"
 	Addr   net.IP
 	Port   int
+	Meta   ServiceMeta // Advertised receiver state, carried in the TXT record
 }
 
 type Adapter interface {
@@ -0,0 +1,133 @@
+// Package events provides a typed publish/subscribe bus for transfer events.
+// The transfer stack publishes to it without knowing who listens, so the TUI,
+// logs, metrics and hooks can consume the same events independently.
+package events
+
+import (
+	"log/slog"
+	"slices"
+	"sync"
+	"sync/atomic"
+)
+
+// Topic names a category of events
+type Topic string
+
+// Event is a message published on a Bus
+type Event interface {
+	Topic() Topic
+}
+
+// DefaultBufferSize is the buffer of a subscription created with a size of zero or less
+const DefaultBufferSize = 64
+
+// Bus delivers published events to every subscription interested in their topic.
+// Publishing never blocks: a subscriber that falls behind loses events, which are
+// counted in Subscription.Dropped.
+type Bus struct {
+	mu     sync.RWMutex
+	subs   map[*Subscription]struct{}
+	closed bool
+}
+
+// NewBus creates an empty event bus
+func NewBus() *Bus {
+	return &Bus{subs: make(map[*Subscription]struct{})}
+}
+
+// Subscription receives the events of its topics on C until it is closed
+type Subscription struct {
+	bus     *Bus
+	topics  []Topic
+	ch      chan Event
+	dropped atomic.Uint64
+	once    sync.Once
+}
+
+// Subscribe returns a subscription to topics, or to every topic if none are given.
+// buffer is the number of events held for a slow subscriber.
+func (b *Bus) Subscribe(buffer int, topics ...Topic) *Subscription {
+	if buffer <= 0 {
+		buffer = DefaultBufferSize
+	}
+	sub := &Subscription{bus: b, topics: topics, ch: make(chan Event, buffer)}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		close(sub.ch)
+		return sub
+	}
+	b.subs[sub] = struct{}{}
+	return sub
+}
+
+// SubscribeFunc calls fn for every event of topics on its own goroutine until the
+// returned subscription is closed. It suits consumers such as loggers and hooks.
+func (b *Bus) SubscribeFunc(fn func(Event), topics ...Topic) *Subscription {
+	sub := b.Subscribe(DefaultBufferSize, topics...)
+	go func() {
+		for event := range sub.C() {
+			fn(event)
+		}
+	}()
+	return sub
+}
+
+// Publish delivers event to the subscribers of its topic without blocking
+func (b *Bus) Publish(event Event) {
+	if b == nil || event == nil {
+		return
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for sub := range b.subs {
+		if !sub.wants(event.Topic()) {
+			continue
+		}
+		select {
+		case sub.ch <- event:
+		default:
+			if sub.dropped.Add(1) == 1 {
+				slog.Debug("Event subscriber is falling behind, dropping events", "topic", event.Topic())
+			}
+		}
+	}
+}
+
+// Close closes every subscription; later subscriptions are closed immediately
+func (b *Bus) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return
+	}
+	b.closed = true
+	for sub := range b.subs {
+		sub.once.Do(func() { close(sub.ch) })
+	}
+	b.subs = make(map[*Subscription]struct{})
+}
+
+// C returns the channel events are delivered on; it is closed with the subscription
+func (s *Subscription) C() <-chan Event {
+	return s.ch
+}
+
+// Dropped returns how many events were lost because the subscription's buffer was full
+func (s *Subscription) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+// Close stops delivery and closes C
+func (s *Subscription) Close() {
+	s.bus.mu.Lock()
+	defer s.bus.mu.Unlock()
+	delete(s.bus.subs, s)
+	s.once.Do(func() { close(s.ch) })
+}
+
+func (s *Subscription) wants(topic Topic) bool {
+	return len(s.topics) == 0 || slices.Contains(s.topics, topic)
+}
@@ -0,0 +1,87 @@
+package events
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func receive(t *testing.T, sub *Subscription) Event {
+	t.Helper()
+	select {
+	case event := <-sub.C():
+		return event
+	case <-time.After(time.Second):
+		t.Fatal("no event received")
+		return nil
+	}
+}
+
+func TestBus_DeliversByTopic(t *testing.T) {
+	bus := NewBus()
+	files := bus.Subscribe(4, TopicFile)
+	all := bus.Subscribe(4)
+
+	bus.Publish(FileStatusChanged{FilePath: "/data/a.txt"})
+	bus.Publish(SessionProgress{SessionID: "s1"})
+
+	assert.Equal(t, FileStatusChanged{FilePath: "/data/a.txt"}, receive(t, files))
+	assert.Empty(t, files.C(), "session events are not delivered to file subscribers")
+
+	assert.Equal(t, TopicFile, receive(t, all).Topic())
+	assert.Equal(t, TopicSession, receive(t, all).Topic())
+}
+
+func TestBus_SlowSubscriberDoesNotBlock(t *testing.T) {
+	bus := NewBus()
+	sub := bus.Subscribe(1)
+
+	for i := 0; i < 3; i++ {
+		bus.Publish(SessionProgress{CompletedFiles: i})
+	}
+	assert.Equal(t, uint64(2), sub.Dropped())
+	assert.Equal(t, 0, receive(t, sub).(SessionProgress).CompletedFiles)
+}
+
+func TestBus_Close(t *testing.T) {
+	bus := NewBus()
+	sub := bus.Subscribe(1)
+	sub.Close()
+	sub.Close()
+	_, open := <-sub.C()
+	assert.False(t, open)
+	bus.Publish(SessionProgress{}) // must not panic on the closed subscription
+
+	other := bus.Subscribe(1)
+	bus.Close()
+	_, open = <-other.C()
+	assert.False(t, open)
+	other.Close()
+
+	late := bus.Subscribe(1)
+	_, open = <-late.C()
+	assert.False(t, open, "subscriptions after Close are closed at once")
+}
+
+func TestBus_SubscribeFunc(t *testing.T) {
+	bus := NewBus()
+	got := make(chan Event, 1)
+	sub := bus.SubscribeFunc(func(e Event) { got <- e }, TopicSession)
+	defer sub.Close()
+
+	bus.Publish(SessionProgress{SessionID: "s1"})
+	select {
+	case event := <-got:
+		require.IsType(t, SessionProgress{}, event)
+	case <-time.After(time.Second):
+		t.Fatal("callback not called")
+	}
+}
+
+func TestSessionProgress_ETA(t *testing.T) {
+	assert.Zero(t, SessionProgress{TotalBytes: 100}.ETA())
+	assert.Equal(t, 5*time.Second, SessionProgress{TotalBytes: 100, BytesCompleted: 50, TransferRate: 10}.ETA())
+	assert.Equal(t, 8*time.Second, SessionProgress{TotalBytes: 100, BytesCompleted: 50, TransferRate: 10, RemainingTime: 8 * time.Second}.ETA())
+}
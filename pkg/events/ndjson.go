@@ -0,0 +1,142 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// Types of ProgressRecord
+const (
+	RecordPrepare  = "prepare"  // PreparationProgress
+	RecordFile     = "file"     // FileStatusChanged
+	RecordSession  = "session"  // SessionProgress
+	RecordWritten  = "written"  // WriteProgress
+	RecordStatus   = "status"   // A message for the user
+	RecordComplete = "complete" // The transfer finished
+)
+
+// recordInterval is the shortest time between two progress records of the same file or
+// session in the same state; changes of state are always written
+const recordInterval = 100 * time.Millisecond
+
+// ProgressRecord is one line of a newline-delimited JSON progress stream. Its fields are
+// stable, so wrappers such as GUIs and scripts can follow a transfer without scraping
+// terminal output. Byte counts and rates are in bytes and bytes per second.
+type ProgressRecord struct {
+	Type           string    `json:"type"`
+	Time           time.Time `json:"time"`
+	Session        string    `json:"session,omitempty"`
+	File           string    `json:"file,omitempty"`
+	State          string    `json:"state,omitempty"`
+	Bytes          int64     `json:"bytes"`
+	TotalBytes     int64     `json:"total_bytes"`
+	Rate           float64   `json:"rate"`
+	Files          int64     `json:"files,omitempty"`
+	CompletedFiles int       `json:"completed_files,omitempty"`
+	FailedFiles    int       `json:"failed_files,omitempty"`
+	ETASeconds     float64   `json:"eta_seconds,omitempty"`
+	Retries        int       `json:"retries,omitempty"`
+	// PhaseSeconds is the time spent in each phase of the transfer, such as "hashing"
+	// or "verifying", of the file or of the finished files of the session
+	PhaseSeconds map[string]float64 `json:"phase_seconds,omitempty"`
+	Message      string             `json:"message,omitempty"`
+	Error        string             `json:"error,omitempty"`
+}
+
+// NewProgressRecord converts event to its record, or reports false if it has none
+func NewProgressRecord(event Event) (ProgressRecord, bool) {
+	switch e := event.(type) {
+	case PreparationProgress:
+		return ProgressRecord{
+			Type: RecordPrepare, Time: e.Time, State: e.Phase,
+			Bytes: e.BytesHashed, TotalBytes: e.BytesScanned, Files: e.FilesScanned,
+		}, true
+	case FileStatusChanged:
+		record := ProgressRecord{
+			Type: RecordFile, Time: e.Time, Session: e.SessionID, File: e.FilePath, State: e.State,
+			Bytes: e.BytesSent, TotalBytes: e.TotalBytes, Rate: e.TransferRate, Retries: e.RetryCount,
+			PhaseSeconds: phaseSeconds(e.Phases),
+		}
+		if e.Err != nil {
+			record.Error = e.Err.Error()
+		}
+		return record, true
+	case SessionProgress:
+		return ProgressRecord{
+			Type: RecordSession, Time: e.Time, Session: e.SessionID, File: e.CurrentFile, State: e.State,
+			Bytes: e.BytesCompleted, TotalBytes: e.TotalBytes, Rate: e.TransferRate,
+			Files: int64(e.TotalFiles), CompletedFiles: e.CompletedFiles, FailedFiles: e.FailedFiles,
+			ETASeconds: e.ETA().Seconds(), PhaseSeconds: phaseSeconds(e.Phases),
+		}, true
+	case WriteProgress:
+		return ProgressRecord{
+			Type: RecordWritten, Time: e.Time, Bytes: e.BytesPersisted, TotalBytes: e.TotalBytes, Rate: e.DiskWriteRate,
+		}, true
+	}
+	return ProgressRecord{}, false
+}
+
+// phaseSeconds converts phase timings to seconds, nil if there are none
+func phaseSeconds(phases map[string]time.Duration) map[string]float64 {
+	if len(phases) == 0 {
+		return nil
+	}
+	seconds := make(map[string]float64, len(phases))
+	for phase, d := range phases {
+		seconds[phase] = d.Seconds()
+	}
+	return seconds
+}
+
+// NDJSONWriter writes progress records as newline-delimited JSON. Records of a file or
+// session that only report progress are thinned to one per recordInterval.
+// It is safe for concurrent use.
+type NDJSONWriter struct {
+	mu      sync.Mutex
+	encoder *json.Encoder
+	last    map[string]ProgressRecord // Last record written per type and file
+}
+
+// NewNDJSONWriter returns a writer of progress records to w
+func NewNDJSONWriter(w io.Writer) *NDJSONWriter {
+	return &NDJSONWriter{encoder: json.NewEncoder(w), last: make(map[string]ProgressRecord)}
+}
+
+// WriteEvent writes the record of event, if it has one
+func (w *NDJSONWriter) WriteEvent(event Event) error {
+	record, ok := NewProgressRecord(event)
+	if !ok {
+		return nil
+	}
+	return w.Write(record)
+}
+
+// Write writes record unless it repeats the state of the previous record of its file
+// or session too soon. A zero Time is set to now.
+func (w *NDJSONWriter) Write(record ProgressRecord) error {
+	if record.Time.IsZero() {
+		record.Time = time.Now()
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	key := record.Type + "\x00" + record.File
+	if record.Type == RecordSession {
+		key = record.Type + "\x00" + record.Session
+	}
+	finished := record.TotalBytes > 0 && record.Bytes >= record.TotalBytes
+	progressOnly := record.Type != RecordStatus && record.Type != RecordComplete && record.Error == "" && !finished
+	if last, ok := w.last[key]; ok && progressOnly && last.State == record.State &&
+		record.Time.Sub(last.Time) < recordInterval {
+		return nil
+	}
+	w.last[key] = record
+
+	if err := w.encoder.Encode(record); err != nil {
+		return fmt.Errorf("failed to write progress record: %w", err)
+	}
+	return nil
+}
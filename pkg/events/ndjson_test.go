@@ -0,0 +1,81 @@
+package events
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readRecords(t *testing.T, data []byte) []ProgressRecord {
+	t.Helper()
+	var records []ProgressRecord
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		var record ProgressRecord
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &record), "every line is one JSON object")
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestNDJSONWriter_WritesOneRecordPerLine(t *testing.T) {
+	var out bytes.Buffer
+	w := NewNDJSONWriter(&out)
+	now := time.Now()
+
+	require.NoError(t, w.WriteEvent(FileStatusChanged{
+		SessionID: "s1", FilePath: "a.txt", State: "transferring", BytesSent: 10, TotalBytes: 100, TransferRate: 5, Time: now,
+	}))
+	require.NoError(t, w.WriteEvent(SessionProgress{
+		SessionID: "s1", State: "active", TotalFiles: 2, CompletedFiles: 1, TotalBytes: 200, BytesCompleted: 110,
+		Phases: map[string]time.Duration{"transferring": 1500 * time.Millisecond}, Time: now,
+	}))
+	require.NoError(t, w.Write(ProgressRecord{Type: RecordStatus, Message: "Waiting for the receiver"}))
+
+	records := readRecords(t, out.Bytes())
+	require.Len(t, records, 3)
+	assert.Equal(t, RecordFile, records[0].Type)
+	assert.Equal(t, "a.txt", records[0].File)
+	assert.Equal(t, int64(10), records[0].Bytes)
+	assert.Equal(t, float64(5), records[0].Rate)
+	assert.Equal(t, RecordSession, records[1].Type)
+	assert.Equal(t, int64(2), records[1].Files)
+	assert.Equal(t, 1, records[1].CompletedFiles)
+	assert.Nil(t, records[0].PhaseSeconds, "no phase took time yet")
+	assert.Equal(t, map[string]float64{"transferring": 1.5}, records[1].PhaseSeconds)
+	assert.Equal(t, "Waiting for the receiver", records[2].Message)
+	assert.False(t, records[2].Time.IsZero(), "records without a time get the current one")
+}
+
+func TestNDJSONWriter_ThinsProgressButKeepsChanges(t *testing.T) {
+	var out bytes.Buffer
+	w := NewNDJSONWriter(&out)
+	now := time.Now()
+	progress := func(sent int64, state string, at time.Duration, err error) {
+		require.NoError(t, w.WriteEvent(FileStatusChanged{
+			FilePath: "a.txt", State: state, BytesSent: sent, TotalBytes: 100, Err: err, Time: now.Add(at),
+		}))
+	}
+
+	progress(10, "transferring", 0, nil)
+	progress(20, "transferring", 10*time.Millisecond, nil) // Too soon
+	progress(30, "transferring", 150*time.Millisecond, nil)
+	progress(30, "retrying", 160*time.Millisecond, errors.New("timeout"))
+	progress(100, "transferring", 170*time.Millisecond, nil) // Finished
+
+	records := readRecords(t, out.Bytes())
+	require.Len(t, records, 4)
+	assert.Equal(t, []int64{10, 30, 30, 100}, []int64{records[0].Bytes, records[1].Bytes, records[2].Bytes, records[3].Bytes})
+	assert.Equal(t, "timeout", records[2].Error)
+}
+
+func TestNewProgressRecord_IgnoresOtherEvents(t *testing.T) {
+	_, ok := NewProgressRecord(nil)
+	assert.False(t, ok)
+}
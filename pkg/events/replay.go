@@ -0,0 +1,113 @@
+package events
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ReadProgressRecords reads a progress stream written by NDJSONWriter, skipping blank lines
+func ReadProgressRecords(r io.Reader) ([]ProgressRecord, error) {
+	var records []ProgressRecord
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var record ProgressRecord
+		if err := json.Unmarshal([]byte(text), &record); err != nil {
+			return nil, fmt.Errorf("line %d is not a progress record: %w", line, err)
+		}
+		records = append(records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read progress stream: %w", err)
+	}
+	return records, nil
+}
+
+// ReplayState is what a progress stream has reported up to a point of its recording
+type ReplayState struct {
+	Prepare  *ProgressRecord  // Last prepare record, nil before the first
+	Session  *ProgressRecord  // Last session record
+	Written  *ProgressRecord  // Last written record
+	Complete *ProgressRecord  // The complete record, once reached
+	Files    []ProgressRecord // Last record of each file, in the order the files first appeared
+	Statuses []ProgressRecord // Status records so far, the oldest first
+	Applied  int              // Number of records folded into the state
+}
+
+// Replay steps through a recorded progress stream by the time since its first record
+type Replay struct {
+	records []ProgressRecord
+	start   time.Time
+}
+
+// NewReplay returns a replay of records, ordered by their time
+func NewReplay(records []ProgressRecord) *Replay {
+	records = slices.Clone(records)
+	slices.SortStableFunc(records, func(a, b ProgressRecord) int { return a.Time.Compare(b.Time) })
+	r := &Replay{records: records}
+	if len(records) > 0 {
+		r.start = records[0].Time
+	}
+	return r
+}
+
+// Len returns the number of records of the replay
+func (r *Replay) Len() int {
+	return len(r.records)
+}
+
+// Start returns the time of the first record
+func (r *Replay) Start() time.Time {
+	return r.start
+}
+
+// Duration returns the time from the first record to the last
+func (r *Replay) Duration() time.Duration {
+	if len(r.records) == 0 {
+		return 0
+	}
+	return r.records[len(r.records)-1].Time.Sub(r.start)
+}
+
+// StateAt folds the records up to offset after the first record into their state. Seeking
+// backwards is folding again from the start, which is cheap for streams thinned by NDJSONWriter.
+func (r *Replay) StateAt(offset time.Duration) ReplayState {
+	var state ReplayState
+	files := make(map[string]int)
+	for i := range r.records {
+		record := r.records[i]
+		if record.Time.Sub(r.start) > offset {
+			break
+		}
+		state.Applied++
+		switch record.Type {
+		case RecordPrepare:
+			state.Prepare = &record
+		case RecordSession:
+			state.Session = &record
+		case RecordWritten:
+			state.Written = &record
+		case RecordComplete:
+			state.Complete = &record
+		case RecordStatus:
+			state.Statuses = append(state.Statuses, record)
+		case RecordFile:
+			if at, ok := files[record.File]; ok {
+				state.Files[at] = record
+				continue
+			}
+			files[record.File] = len(state.Files)
+			state.Files = append(state.Files, record)
+		}
+	}
+	return state
+}
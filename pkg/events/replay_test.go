@@ -0,0 +1,59 @@
+package events
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadProgressRecords_ReadsWriterOutput(t *testing.T) {
+	var out bytes.Buffer
+	w := NewNDJSONWriter(&out)
+	require.NoError(t, w.Write(ProgressRecord{Type: RecordStatus, Message: "Waiting for the receiver"}))
+	require.NoError(t, w.Write(ProgressRecord{Type: RecordComplete}))
+	out.WriteString("\n")
+
+	records, err := ReadProgressRecords(&out)
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.Equal(t, "Waiting for the receiver", records[0].Message)
+
+	_, err = ReadProgressRecords(strings.NewReader(`{"type":"status"}` + "\nnot json\n"))
+	assert.ErrorContains(t, err, "line 2")
+}
+
+func TestReplay_StateAt(t *testing.T) {
+	start := time.Now()
+	at := func(d time.Duration) time.Time { return start.Add(d) }
+	replay := NewReplay([]ProgressRecord{
+		{Type: RecordFile, Time: at(2 * time.Second), File: "b.txt", State: "active", Bytes: 5},
+		{Type: RecordStatus, Time: at(0), Message: "Waiting for the receiver"},
+		{Type: RecordFile, Time: at(time.Second), File: "a.txt", State: "active", Bytes: 10},
+		{Type: RecordFile, Time: at(3 * time.Second), File: "a.txt", State: "completed", Bytes: 100},
+		{Type: RecordSession, Time: at(3 * time.Second), CompletedFiles: 1},
+		{Type: RecordComplete, Time: at(4 * time.Second)},
+	})
+	assert.Equal(t, 6, replay.Len())
+	assert.Equal(t, 4*time.Second, replay.Duration())
+
+	state := replay.StateAt(2500 * time.Millisecond)
+	assert.Equal(t, 3, state.Applied)
+	require.Len(t, state.Files, 2)
+	assert.Equal(t, "a.txt", state.Files[0].File, "records are ordered by time")
+	assert.Equal(t, int64(10), state.Files[0].Bytes)
+	assert.Nil(t, state.Session)
+	assert.Nil(t, state.Complete)
+	require.Len(t, state.Statuses, 1)
+
+	state = replay.StateAt(replay.Duration())
+	assert.Equal(t, "completed", state.Files[0].State, "later records of a file replace earlier ones")
+	require.NotNil(t, state.Session)
+	assert.Equal(t, 1, state.Session.CompletedFiles)
+	assert.NotNil(t, state.Complete)
+
+	assert.Equal(t, 1, replay.StateAt(0).Applied, "seeking back folds again from the start")
+}
@@ -0,0 +1,105 @@
+package events
+
+import "time"
+
+// Topics published by the transfer stack
+const (
+	// TopicFile carries FileStatusChanged events
+	TopicFile Topic = "transfer.file"
+	// TopicSession carries SessionProgress and WriteProgress events
+	TopicSession Topic = "transfer.session"
+	// TopicPrepare carries PreparationProgress events
+	TopicPrepare Topic = "transfer.prepare"
+)
+
+// Phases of PreparationProgress
+const (
+	PreparationScanning = "scanning"
+	PreparationHashing  = "hashing"
+	PreparationDone     = "done"
+)
+
+// FileStatusChanged is published when a file of a transfer changes state or makes progress
+type FileStatusChanged struct {
+	SessionID    string
+	FilePath     string
+	State        string
+	BytesSent    int64
+	TotalBytes   int64
+	TransferRate float64 // bytes per second
+	RetryCount   int
+	// Phases is the time spent so far in each phase of the transfer by name, such as
+	// "transferring"; see transfer.PhaseTimings
+	Phases map[string]time.Duration
+	Err    error
+	Time   time.Time
+}
+
+// Topic implements Event
+func (FileStatusChanged) Topic() Topic { return TopicFile }
+
+// SessionProgress is published when the totals of a transfer session change
+type SessionProgress struct {
+	SessionID       string
+	State           string
+	TotalFiles      int
+	CompletedFiles  int
+	FailedFiles     int
+	TotalBytes      int64
+	BytesCompleted  int64
+	OverallProgress float64 // 0-100 percentage
+	CurrentFile     string
+	TransferRate    float64 // bytes per second of the current file
+	FilesPerMinute  float64
+	// DiskWriteRate and DiskBusy are reported by receivers: the rate the disk accepted
+	// data at while writing, and the fraction of time spent writing (0-1)
+	DiskWriteRate float64
+	DiskBusy      float64
+	// RemainingTime estimates the time left including per-file overhead; zero if unknown
+	RemainingTime time.Duration
+	// Phases sums the time the finished files spent in each phase, like FileStatusChanged.Phases
+	Phases map[string]time.Duration
+	Time   time.Time
+}
+
+// Topic implements Event
+func (SessionProgress) Topic() Topic { return TopicSession }
+
+// ETA estimates the remaining time, or zero if it is unknown. Without a RemainingTime
+// from the publisher it is derived from the remaining bytes at the current rate.
+func (p SessionProgress) ETA() time.Duration {
+	if p.RemainingTime > 0 {
+		return p.RemainingTime
+	}
+	remaining := p.TotalBytes - p.BytesCompleted
+	if p.TransferRate <= 0 || remaining <= 0 {
+		return 0
+	}
+	return time.Duration(float64(remaining) / p.TransferRate * float64(time.Second))
+}
+
+// WriteProgress is published by senders when the receiver reports the bytes it has written to disk
+type WriteProgress struct {
+	BytesPersisted int64
+	TotalBytes     int64
+	DiskWriteRate  float64 // bytes per second the receiver's disk accepted data at
+	Time           time.Time
+}
+
+// Topic implements Event
+func (WriteProgress) Topic() Topic { return TopicSession }
+
+// PreparationProgress is published while the files of a transfer are scanned and hashed
+type PreparationProgress struct {
+	Phase        string
+	FilesScanned int64
+	// BytesScanned is the size of the files scanned so far, and the total to hash once scanning is done
+	BytesScanned int64
+	BytesHashed  int64
+	// Locked lists the files left out because another process locks them, once preparation is done
+	Locked []string
+	Time   time.Time
+}
+
+// Topic implements Event
+func (PreparationProgress) Topic() Topic { return TopicPrepare }
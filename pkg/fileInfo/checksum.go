@@ -55,6 +55,33 @@ func (n *FileNode) CalcChecksum() (string, error) {
 	return n.Checksum, nil
 }
 
+// SummarizeDir recomputes a directory's size and checksum from the recorded values of its
+// children, without reading any file. The result matches CreateNode for unchanged files.
+func (n *FileNode) SummarizeDir() {
+	if !n.IsDir {
+		return
+	}
+
+	// Sort a copy so pointers into Children stay valid
+	sorted := make([]*FileNode, len(n.Children))
+	for i := range n.Children {
+		sorted[i] = &n.Children[i]
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Name < sorted[j].Name
+	})
+
+	childSums := make([]string, 0, len(sorted))
+	var size int64
+	for _, child := range sorted {
+		size += child.Size
+		childSums = append(childSums, child.Name+":"+child.Checksum)
+	}
+	hash := sha256.Sum256([]byte(strings.Join(childSums, "|")))
+	n.Size = size
+	n.Checksum = hex.EncodeToString(hash[:])
+}
+
 func (n *FileNode) VerifySHA256(expectedChecksum string) (bool, error) {
 	actual, err := n.CalcChecksum()
 	if err != nil {
@@ -1,11 +1,8 @@
 package fileInfo
 
 import (
-	"log"
-	"os"
-	"path/filepath"
-
-	"github.com/gabriel-vasile/mimetype"
+	"context"
+	"time"
 )
 
 type FileNode struct {
@@ -16,49 +13,29 @@ type FileNode struct {
 	Checksum string     `json:"checksum,omitempty"`
 	Children []FileNode `json:"children,omitempty"`
 	Path     string     `json:"-"`
+	// LinkTo is the path of an earlier file in the selection this one is a hard link to,
+	// set by MarkHardLinks so its content is sent once
+	LinkTo string `json:"-"`
+
+	key     fileKey   // Device and inode, set when linked
+	linked  bool      // Whether the file has other hard links
+	modTime time.Time // Modification time when the file was scanned
 }
 
+// ModTime returns the modification time of the file when it was scanned, zero for nodes
+// that were not scanned, such as those received in an offer
+func (n *FileNode) ModTime() time.Time {
+	return n.modTime
+}
+
+// CreateNode builds the tree under path and computes all checksums before returning
 func CreateNode(path string) (FileNode, error) {
-	info, err := os.Stat(path)
+	node, err := ScanNode(path)
 	if err != nil {
 		return FileNode{}, err
 	}
-	node := FileNode{
-		Name:  info.Name(),
-		IsDir: info.IsDir(),
-		Size:  info.Size(),
-		Path:  path,
-	}
-	if node.IsDir {
-		entries, err := os.ReadDir(path)
-		if err != nil {
-			return FileNode{}, err
-		}
-
-		node.Children = make([]FileNode, 0)
-
-		for _, entry := range entries {
-			childPath := filepath.Join(path, entry.Name())
-			childNode, err := CreateNode(childPath)
-			if err != nil {
-				log.Printf("Skipping %s: %v", childPath, err)
-				continue
-			}
-			node.Children = append(node.Children, childNode)
-			node.Size += childNode.Size
-		}
-	} else {
-		mime, err := mimetype.DetectFile(path)
-		if err != nil {
-			node.MimeType = "application/octet-stream"
-		} else {
-			node.MimeType = mime.String()
-		}
-	}
-	checksum, err := node.CalcChecksum()
-	if err != nil {
+	if err := node.HashTree(context.Background(), 1, nil); err != nil {
 		return FileNode{}, err
 	}
-	node.Checksum = checksum
 	return node, nil
 }
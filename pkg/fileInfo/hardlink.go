@@ -0,0 +1,36 @@
+package fileInfo
+
+import "os"
+
+// fileKey identifies a file on disk by device and inode, the same for every hard link to it
+type fileKey struct {
+	dev uint64
+	ino uint64
+}
+
+// MarkHardLinks sets LinkTo on every file under nodes that is a hard link to a file found
+// earlier, pointing it at the path of the first one, and returns how many it marked.
+// Links are only recognized between files scanned on platforms that report inodes.
+func MarkHardLinks(nodes []FileNode) int {
+	first := make(map[fileKey]string)
+	marked := 0
+	for i := range nodes {
+		nodes[i].walkFiles(func(f *FileNode) {
+			if !f.linked {
+				return
+			}
+			if path, ok := first[f.key]; ok {
+				f.LinkTo = path
+				marked++
+				return
+			}
+			first[f.key] = f.Path
+		})
+	}
+	return marked
+}
+
+// setLinkKey records the identity of the file info was read from, if it has other hard links
+func (n *FileNode) setLinkKey(info os.FileInfo) {
+	n.key, n.linked = linkKey(info)
+}
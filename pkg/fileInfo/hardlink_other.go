@@ -0,0 +1,10 @@
+//go:build !unix
+
+package fileInfo
+
+import "os"
+
+// linkKey is not supported on this platform, so hard links are sent as separate files
+func linkKey(info os.FileInfo) (fileKey, bool) {
+	return fileKey{}, false
+}
@@ -0,0 +1,17 @@
+//go:build unix
+
+package fileInfo
+
+import (
+	"os"
+	"syscall"
+)
+
+// linkKey returns the identity of the file info describes, and false unless it has several links
+func linkKey(info os.FileInfo) (fileKey, bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok || stat.Nlink < 2 {
+		return fileKey{}, false
+	}
+	return fileKey{dev: uint64(stat.Dev), ino: uint64(stat.Ino)}, true
+}
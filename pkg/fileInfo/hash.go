@@ -0,0 +1,203 @@
+package fileInfo
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/gabriel-vasile/mimetype"
+	"golang.org/x/sync/errgroup"
+)
+
+// hashBufferSize is how much of a file is hashed between cancellation checks and progress reports
+const hashBufferSize = 1 << 20
+
+// HashProgressFunc receives the bytes hashed so far and the bytes to hash in total
+type HashProgressFunc func(hashed, total int64)
+
+// ScanProgressFunc receives the number of files scanned so far and their total size
+type ScanProgressFunc func(files, bytes int64)
+
+// ScanNode builds the tree under path like CreateNode but leaves the checksums empty,
+// so it only costs a stat per entry. Call HashTree to fill them in.
+func ScanNode(path string) (FileNode, error) {
+	return ScanNodeWithProgress(path, nil)
+}
+
+// ScanNodeWithProgress is ScanNode calling progress, if set, after every file it scans
+func ScanNodeWithProgress(path string, progress ScanProgressFunc) (FileNode, error) {
+	var files, bytes int64
+	return scanNode(path, func(size int64) {
+		files++
+		bytes += size
+		if progress != nil {
+			progress(files, bytes)
+		}
+	})
+}
+
+func scanNode(path string, onFile func(size int64)) (FileNode, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return FileNode{}, err
+	}
+	node := FileNode{
+		Name:  info.Name(),
+		IsDir: info.IsDir(),
+		Size:  info.Size(),
+		Path:  path,
+	}
+	if !node.IsDir {
+		mime, err := mimetype.DetectFile(path)
+		if err != nil {
+			node.MimeType = "application/octet-stream"
+		} else {
+			node.MimeType = mime.String()
+		}
+		node.setLinkKey(info)
+		node.modTime = info.ModTime()
+		onFile(node.Size)
+		return node, nil
+	}
+
+	// A directory's size is that of the files under it, as SummarizeDir computes it
+	node.Size = 0
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return FileNode{}, err
+	}
+	node.Children = make([]FileNode, 0)
+	for _, entry := range entries {
+		childPath := filepath.Join(path, entry.Name())
+		childNode, err := scanNode(childPath, onFile)
+		if err != nil {
+			log.Printf("Skipping %s: %v", childPath, err)
+			continue
+		}
+		node.Children = append(node.Children, childNode)
+		node.Size += childNode.Size
+	}
+	return node, nil
+}
+
+// HashTree computes the checksums of every file under n with up to workers files hashed
+// at once, then derives the directory checksums from them. Each file is read exactly once.
+// progress, if set, is called from one goroutine at a time as data is hashed.
+func (n *FileNode) HashTree(ctx context.Context, workers int, progress HashProgressFunc) error {
+	if workers < 1 {
+		workers = 1
+	}
+
+	// Hard links to the same file are read once and share its checksum
+	var files []*FileNode
+	var total int64
+	first := make(map[fileKey]*FileNode)
+	links := make(map[*FileNode]*FileNode)
+	n.walkFiles(func(f *FileNode) {
+		if f.linked {
+			if target, ok := first[f.key]; ok {
+				links[f] = target
+				return
+			}
+			first[f.key] = f
+		}
+		files = append(files, f)
+		total += f.Size
+	})
+
+	var mu sync.Mutex
+	var hashed int64
+	report := func(read int64) {
+		if progress == nil {
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		hashed += read
+		progress(hashed, total)
+	}
+
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(workers)
+	for _, f := range files {
+		g.Go(func() error {
+			sum, err := hashFile(gctx, f.Path, report)
+			if err != nil {
+				return fmt.Errorf("failed to hash %s: %w", f.Path, err)
+			}
+			f.Checksum = sum
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return err
+	}
+	for link, target := range links {
+		link.Checksum = target.Checksum
+	}
+
+	n.summarizeDirs()
+	return nil
+}
+
+// walkFiles calls fn for every regular file under n, including n itself
+func (n *FileNode) walkFiles(fn func(*FileNode)) {
+	if !n.IsDir {
+		fn(n)
+		return
+	}
+	for i := range n.Children {
+		n.Children[i].walkFiles(fn)
+	}
+}
+
+// summarizeDirs sets the checksum of every directory under n from its children, deepest first
+func (n *FileNode) summarizeDirs() {
+	if !n.IsDir {
+		return
+	}
+	for i := range n.Children {
+		n.Children[i].summarizeDirs()
+	}
+	n.SummarizeDir()
+}
+
+// hashFile returns the hex SHA-256 of the file at path, stopping early when ctx is done
+func hashFile(ctx context.Context, path string, report func(int64)) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		if err := file.Close(); err != nil {
+			slog.Error("fail to close file", "error", err.Error())
+		}
+	}()
+
+	hasher := sha256.New()
+	buf := make([]byte, hashBufferSize)
+	for {
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+		n, err := file.Read(buf)
+		if n > 0 {
+			hasher.Write(buf[:n])
+			report(int64(n))
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
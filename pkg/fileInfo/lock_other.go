@@ -0,0 +1,8 @@
+//go:build !linux && !darwin && !windows
+
+package fileInfo
+
+// IsLocked cannot detect locks on this platform; locked files fail when they are read
+func IsLocked(path string) bool {
+	return false
+}
@@ -0,0 +1,36 @@
+//go:build linux || darwin
+
+package fileInfo
+
+import (
+	"errors"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// IsLocked reports whether another process holds an exclusive lock on the file at path,
+// taken with flock or as a POSIX write lock, as virtual machines and databases do with
+// their images. Files that cannot be opened are left to fail when they are read.
+func IsLocked(path string) bool {
+	file, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+	fd := int(file.Fd())
+
+	if err := unix.Flock(fd, unix.LOCK_SH|unix.LOCK_NB); err != nil {
+		return errors.Is(err, unix.EWOULDBLOCK)
+	}
+	if err := unix.Flock(fd, unix.LOCK_UN); err != nil {
+		return false
+	}
+
+	// F_GETLK only reports locks of other processes, which would block a read lock
+	lock := unix.Flock_t{Type: unix.F_RDLCK, Whence: 0, Start: 0, Len: 0}
+	if err := unix.FcntlFlock(uintptr(fd), unix.F_GETLK, &lock); err != nil {
+		return false
+	}
+	return lock.Type != unix.F_UNLCK
+}
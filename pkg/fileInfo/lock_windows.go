@@ -0,0 +1,23 @@
+package fileInfo
+
+import (
+	"errors"
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// IsLocked reports whether another process keeps the file at path from being read, by
+// opening it without sharing, as Outlook does with its data files, or by locking a range
+// of it, as running virtual machines do with their disks
+func IsLocked(path string) bool {
+	file, err := os.Open(path)
+	if err != nil {
+		return errors.Is(err, windows.ERROR_SHARING_VIOLATION) || errors.Is(err, windows.ERROR_LOCK_VIOLATION)
+	}
+	defer file.Close()
+
+	var buf [1]byte
+	_, err = file.Read(buf[:])
+	return errors.Is(err, windows.ERROR_LOCK_VIOLATION)
+}
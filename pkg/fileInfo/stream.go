@@ -0,0 +1,23 @@
+package fileInfo
+
+const (
+	// StdinPath is the conventional path used for a node streamed from standard input
+	StdinPath = "-"
+	// UnknownSize marks a node whose size is only known once it has been fully read
+	UnknownSize int64 = -1
+)
+
+// NewStdinNode creates a node that streams standard input under the given name
+func NewStdinNode(name string) FileNode {
+	return FileNode{
+		Name:     name,
+		Size:     UnknownSize,
+		MimeType: "application/octet-stream",
+		Path:     StdinPath,
+	}
+}
+
+// IsStream reports whether the node's size is unknown until it has been read
+func (n *FileNode) IsStream() bool {
+	return !n.IsDir && n.Size == UnknownSize
+}
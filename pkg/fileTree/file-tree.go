@@ -2,6 +2,7 @@ package fileTree
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/charmbracelet/bubbles/key"
@@ -17,6 +18,7 @@ type KeyMap struct {
 	Down       key.Binding
 	GoToParent key.Binding
 	GoToChild  key.Binding
+	Select     key.Binding
 	Quit       key.Binding
 }
 
@@ -26,9 +28,13 @@ var DefaultKeyMap = KeyMap{
 	Down:       key.NewBinding(key.WithKeys("down", "j"), key.WithHelp("↓/j", "down")),
 	GoToParent: key.NewBinding(key.WithKeys("backspace", "h", "b"), key.WithHelp("←/h/b", "back")),
 	GoToChild:  key.NewBinding(key.WithKeys("enter", "l"), key.WithHelp("→/l/enter", "open")),
+	Select:     key.NewBinding(key.WithKeys(" "), key.WithHelp("space", "select")),
 	Quit:       key.NewBinding(key.WithKeys("q", "ctrl+c"), key.WithHelp("q", "quit")),
 }
 
+// rowHeight is the number of lines a node takes in the view, including the blank line after it.
+const rowHeight = 2
+
 // Model represents the state of the file tree TUI.
 type Model struct {
 	title string
@@ -39,6 +45,20 @@ type Model struct {
 	cursor  int
 	width   int
 	height  int
+
+	// lazy trees list directories when they are opened instead of holding every node
+	lazy    bool
+	loading bool
+	// pending is the directory being loaded, entered once SetChildren delivers its nodes
+	pending string
+	// selected holds the nodes picked by path, nil if the tree does not select
+	selected map[string]fileInfo.FileNode
+}
+
+// LoadChildrenMsg asks the owner of a lazy tree to list the directory at Path and hand the
+// nodes to SetChildren. An empty Path is the root.
+type LoadChildrenMsg struct {
+	Path string
 }
 
 // NewFileTree creates a new file tree model.
@@ -52,17 +72,121 @@ func NewFileTree(title string, nodes []fileInfo.FileNode) Model {
 	}
 }
 
+// NewLazyFileTree creates a file tree whose nodes are listed one directory at a time, such as
+// the share of another device. Nodes are selected with the Select key and identified by
+// their Path. Init asks for the root.
+func NewLazyFileTree(title string) Model {
+	m := NewFileTree(title, nil)
+	m.lazy = true
+	m.loading = true
+	m.selected = make(map[string]fileInfo.FileNode)
+	return m
+}
+
 func (m Model) Init() tea.Cmd {
+	if m.lazy && m.loading && m.pending == "" {
+		return loadChildren("")
+	}
 	return nil
 }
 
+// loadChildren returns a command asking for the nodes of the directory at path
+func loadChildren(path string) tea.Cmd {
+	return func() tea.Msg {
+		return LoadChildrenMsg{Path: path}
+	}
+}
+
+// SetChildren enters the directory at path with nodes, if it is the one being loaded,
+// and reports whether it was
+func (m *Model) SetChildren(path string, nodes []fileInfo.FileNode) bool {
+	if !m.loading || path != m.pending {
+		return false
+	}
+	if path != "" {
+		m.history = append(m.history, m.nodes)
+	}
+	m.nodes = nodes
+	m.cursor = 0
+	m.loading = false
+	m.pending = ""
+	return true
+}
+
+// CancelLoad stays in the current directory when the one being loaded cannot be listed
+func (m *Model) CancelLoad() {
+	m.loading = false
+	m.pending = ""
+}
+
+// Loading reports whether a directory is being loaded
+func (m Model) Loading() bool {
+	return m.loading
+}
+
+// AtRoot reports whether the tree shows its top level
+func (m Model) AtRoot() bool {
+	return len(m.history) == 0
+}
+
+// Selected returns the selected nodes, sorted by path
+func (m Model) Selected() []fileInfo.FileNode {
+	nodes := make([]fileInfo.FileNode, 0, len(m.selected))
+	for _, node := range m.selected {
+		nodes = append(nodes, node)
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].Path < nodes[j].Path })
+	return nodes
+}
+
+// toggleSelected selects the node under the cursor, or unselects it
+func (m *Model) toggleSelected() {
+	if m.selected == nil || len(m.nodes) == 0 {
+		return
+	}
+	node := m.nodes[m.cursor]
+	if _, ok := m.selected[node.Path]; ok {
+		delete(m.selected, node.Path)
+	} else {
+		m.selected[node.Path] = node
+	}
+}
+
 // Update handles messages and updates the model's state.
+// The Y of mouse messages must be relative to the first line of the view.
 func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
 	switch msg := msg.(type) {
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
 		m.height = msg.Height
 
+	case tea.MouseMsg:
+		if msg.Action != tea.MouseActionPress {
+			break
+		}
+		switch msg.Button {
+		case tea.MouseButtonWheelUp:
+			if m.cursor > 0 {
+				m.cursor--
+			}
+		case tea.MouseButtonWheelDown:
+			if m.cursor < len(m.nodes)-1 {
+				m.cursor++
+			}
+		case tea.MouseButtonLeft:
+			// The first click selects a node, a click on the selected one opens it
+			if i, ok := m.rowAt(msg.Y); ok {
+				if i == m.cursor {
+					cmd = m.openSelected()
+				} else {
+					m.cursor = i
+				}
+			}
+		case tea.MouseButtonRight:
+			m.goToParent()
+		}
+
 	case tea.KeyMsg:
 		switch {
 		case key.Matches(msg, m.keys.Quit):
@@ -79,30 +203,69 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 
 		case key.Matches(msg, m.keys.GoToParent):
-			if len(m.history) > 0 {
-				// Pop from the history stack to go back to the parent.
-				lastIndex := len(m.history) - 1
-				m.nodes = m.history[lastIndex]
-				m.history = m.history[:lastIndex] // Slice off the last element
-				m.cursor = 0
-			}
+			m.goToParent()
 
 		case key.Matches(msg, m.keys.GoToChild):
-			if len(m.nodes) == 0 {
-				return m, nil
-			}
-			selectedNode := m.nodes[m.cursor]
-			if selectedNode.IsDir && len(selectedNode.Children) > 0 {
-				// Push the current view onto the history stack.
-				m.history = append(m.history, m.nodes)
-				// Move into the child directory.
-				m.nodes = selectedNode.Children
-				m.cursor = 0
-			}
+			cmd = m.openSelected()
+
+		case key.Matches(msg, m.keys.Select):
+			m.toggleSelected()
+		}
+	}
+
+	return m, cmd
+}
+
+// goToParent returns to the parent directory, if there is one
+func (m *Model) goToParent() {
+	if m.loading && m.pending != "" {
+		// Going back while a directory loads stays where the tree is
+		m.CancelLoad()
+		return
+	}
+	if len(m.history) > 0 {
+		// Pop from the history stack to go back to the parent.
+		lastIndex := len(m.history) - 1
+		m.nodes = m.history[lastIndex]
+		m.history = m.history[:lastIndex] // Slice off the last element
+		m.cursor = 0
+	}
+}
+
+// openSelected moves into the selected node if it is a directory with children. Lazy trees
+// ask for its children instead and move into it once they are set.
+func (m *Model) openSelected() tea.Cmd {
+	if len(m.nodes) == 0 || m.loading {
+		return nil
+	}
+	selectedNode := m.nodes[m.cursor]
+	if m.lazy {
+		if !selectedNode.IsDir {
+			return nil
 		}
+		m.loading = true
+		m.pending = selectedNode.Path
+		return loadChildren(selectedNode.Path)
+	}
+	if selectedNode.IsDir && len(selectedNode.Children) > 0 {
+		// Push the current view onto the history stack.
+		m.history = append(m.history, m.nodes)
+		// Move into the child directory.
+		m.nodes = selectedNode.Children
+		m.cursor = 0
 	}
+	return nil
+}
 
-	return m, nil
+// rowAt returns the index of the node drawn on line y of the view
+func (m Model) rowAt(y int) (int, bool) {
+	// The margin is followed by the title, the header and a blank line after each
+	row := y - style.DocStyle.GetMarginTop() - 4
+	if row < 0 || row%rowHeight != 0 {
+		return 0, false
+	}
+	i := row / rowHeight
+	return i, i < len(m.nodes)
 }
 
 // View renders the UI.
@@ -132,7 +295,14 @@ func (m Model) View() string {
 			cursor = style.CursorStyle.String()
 		}
 
-		name := node.Name
+		name := util.SanitizeText(node.Name)
+		if m.selected != nil {
+			mark := "[ ] "
+			if _, ok := m.selected[node.Path]; ok {
+				mark = "[x] "
+			}
+			name = mark + name
+		}
 		sizeStr := ""
 		typeStr := "<DIR>"
 
@@ -143,7 +313,10 @@ func (m Model) View() string {
 			typeCell = style.DirStyle.Render(util.PadRight(typeStr, typeWidth))
 		} else {
 			sizeStr = util.FormatSize(node.Size)
-			typeStr = node.MimeType
+			if node.IsStream() {
+				sizeStr = "stream"
+			}
+			typeStr = util.SanitizeText(node.MimeType)
 			nameCell = style.FileStyle.Render(util.PadRight(name, nameWidth))
 			typeCell = style.FileStyle.Render(util.PadRight(typeStr, typeWidth))
 		}
@@ -159,14 +332,22 @@ func (m Model) View() string {
 		}
 	}
 
+	if m.loading {
+		s.WriteString(style.HelpStyle.Render("\nLoading..."))
+		s.WriteString("\n")
+	}
+
 	// Help view
-	help := fmt.Sprintf("\n%s  %s  %s  %s  %s",
+	help := fmt.Sprintf("\n%s  %s  %s  %s",
 		m.keys.Up.Help().Key+"/"+m.keys.Up.Help().Desc,
 		m.keys.Down.Help().Key+"/"+m.keys.Down.Help().Desc,
 		m.keys.GoToChild.Help().Key+"/"+m.keys.GoToChild.Help().Desc,
 		m.keys.GoToParent.Help().Key+"/"+m.keys.GoToParent.Help().Desc,
-		m.keys.Quit.Help().Key+"/"+m.keys.Quit.Help().Desc,
 	)
+	if m.selected != nil {
+		help += "  " + m.keys.Select.Help().Key + "/" + m.keys.Select.Help().Desc
+	}
+	help += "  " + m.keys.Quit.Help().Key + "/" + m.keys.Quit.Help().Desc
 	s.WriteString(style.HelpStyle.Render(help))
 
 	return style.DocStyle.Render(s.String())
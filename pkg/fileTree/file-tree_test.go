@@ -0,0 +1,98 @@
+package fileTree
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rescp17/lanFileSharer/pkg/fileInfo"
+)
+
+func press(t *testing.T, m Model, keys ...tea.KeyMsg) (Model, tea.Cmd) {
+	t.Helper()
+	var cmd tea.Cmd
+	for _, k := range keys {
+		var updated tea.Model
+		updated, cmd = m.Update(k)
+		m = updated.(Model)
+	}
+	return m, cmd
+}
+
+func TestLazyFileTree_LoadsDirectoriesWhenOpened(t *testing.T) {
+	m := NewLazyFileTree("Share")
+	cmd := m.Init()
+	require.NotNil(t, cmd)
+	assert.Equal(t, LoadChildrenMsg{Path: ""}, cmd())
+
+	require.True(t, m.SetChildren("", []fileInfo.FileNode{
+		{Name: "photos", Path: "photos", IsDir: true},
+		{Name: "notes.txt", Path: "notes.txt"},
+	}))
+	assert.True(t, m.AtRoot())
+
+	m, cmd = press(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+	require.NotNil(t, cmd)
+	assert.Equal(t, LoadChildrenMsg{Path: "photos"}, cmd())
+	assert.True(t, m.Loading())
+	assert.False(t, m.SetChildren("other", nil), "only the directory being loaded is entered")
+
+	require.True(t, m.SetChildren("photos", []fileInfo.FileNode{{Name: "cat.jpg", Path: "photos/cat.jpg"}}))
+	assert.False(t, m.AtRoot())
+	assert.Equal(t, "photos/cat.jpg", m.GetSelectedNode().Path)
+
+	m, _ = press(t, m, tea.KeyMsg{Type: tea.KeyBackspace})
+	assert.True(t, m.AtRoot())
+	assert.Equal(t, "photos", m.GetSelectedNode().Path)
+}
+
+func TestLazyFileTree_BackCancelsLoading(t *testing.T) {
+	m := NewLazyFileTree("Share")
+	m.SetChildren("", []fileInfo.FileNode{{Name: "photos", Path: "photos", IsDir: true}})
+
+	m, _ = press(t, m, tea.KeyMsg{Type: tea.KeyEnter}, tea.KeyMsg{Type: tea.KeyBackspace})
+	assert.False(t, m.Loading())
+	assert.False(t, m.SetChildren("photos", nil), "a cancelled directory is not entered")
+	assert.True(t, m.AtRoot())
+}
+
+func TestLazyFileTree_SelectsAcrossDirectories(t *testing.T) {
+	m := NewLazyFileTree("Share")
+	m.SetChildren("", []fileInfo.FileNode{
+		{Name: "photos", Path: "photos", IsDir: true},
+		{Name: "notes.txt", Path: "notes.txt"},
+	})
+	space := tea.KeyMsg{Type: tea.KeySpace, Runes: []rune{' '}}
+
+	m, _ = press(t, m, tea.KeyMsg{Type: tea.KeyDown}, space, tea.KeyMsg{Type: tea.KeyUp}, tea.KeyMsg{Type: tea.KeyEnter})
+	m.SetChildren("photos", []fileInfo.FileNode{{Name: "cat.jpg", Path: "photos/cat.jpg"}})
+	m, _ = press(t, m, space)
+
+	selected := m.Selected()
+	require.Len(t, selected, 2)
+	assert.Equal(t, "notes.txt", selected[0].Path)
+	assert.Equal(t, "photos/cat.jpg", selected[1].Path)
+	assert.Contains(t, m.View(), "[x] cat.jpg")
+
+	m, _ = press(t, m, space)
+	assert.Len(t, m.Selected(), 1, "selecting again unselects")
+}
+
+func TestFileTree_DoesNotSelect(t *testing.T) {
+	m := NewFileTree("Files", []fileInfo.FileNode{{Name: "a.txt", Path: "a.txt"}})
+	m, _ = press(t, m, tea.KeyMsg{Type: tea.KeySpace, Runes: []rune{' '}})
+	assert.Empty(t, m.Selected())
+	assert.NotContains(t, m.View(), "[ ]")
+}
+
+func TestFileTree_SanitizesRemoteNames(t *testing.T) {
+	m := NewFileTree("Files", []fileInfo.FileNode{
+		{Name: "clear\x1b[2J.txt", Path: "clear\x1b[2J.txt", MimeType: "text/plain"},
+	})
+
+	view := m.View()
+	assert.NotContains(t, view, "\x1b[2J", "escape sequences in names must not reach the terminal")
+	assert.Contains(t, view, "clear�[2J.txt")
+}
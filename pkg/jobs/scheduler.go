@@ -0,0 +1,159 @@
+package jobs
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// checkInterval is how often the scheduler looks for due jobs, the resolution of cron
+const checkInterval = time.Minute
+
+// Outcome is what a run of a job sent
+type Outcome struct {
+	FilesSent   int
+	FilesFailed int
+}
+
+// RunFunc sends the source of job to its peer
+type RunFunc func(ctx context.Context, job Job) (Outcome, error)
+
+// Status is the state of a job as the daemon sees it, served by the control API
+type Status struct {
+	Job     Job       `json:"job"`
+	Next    time.Time `json:"next"` // Zero if the schedule never matches again
+	Running bool      `json:"running"`
+	LastRun *Run      `json:"last_run,omitempty"`
+}
+
+// Scheduler runs the jobs of a store when they are due, one at a time. Runs missed while
+// the scheduler was not running, or busy with another job, are run once when it gets to them.
+type Scheduler struct {
+	path string
+	run  RunFunc
+
+	mu      sync.Mutex
+	checked time.Time // Jobs due up to this minute have been run
+	running string    // ID of the running job
+}
+
+// NewScheduler creates a scheduler for the jobs file at path, which runs jobs with run
+func NewScheduler(path string, run RunFunc) *Scheduler {
+	return &Scheduler{path: path, run: run}
+}
+
+// Run checks for due jobs every minute until ctx ends
+func (s *Scheduler) Run(ctx context.Context) {
+	s.mu.Lock()
+	if s.checked.IsZero() {
+		s.checked = time.Now()
+	}
+	s.mu.Unlock()
+
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+	for {
+		s.RunDue(ctx, time.Now())
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunDue runs the jobs with a scheduled minute after the previous check and up to now, and
+// records their runs. The first check only sets where the next one starts.
+func (s *Scheduler) RunDue(ctx context.Context, now time.Time) {
+	store, err := Load(s.path)
+	if err != nil {
+		slog.Error("Failed to load jobs", "error", err)
+		return
+	}
+
+	s.mu.Lock()
+	since := s.checked
+	if since.IsZero() {
+		since = now
+	}
+	s.checked = now
+	s.mu.Unlock()
+
+	for _, job := range store.Jobs() {
+		schedule, err := ParseSchedule(job.Cron)
+		if err != nil {
+			slog.Warn("Skipping job with an invalid schedule", "job", job.ID, "error", err)
+			continue
+		}
+		if next := schedule.Next(since); next.IsZero() || next.After(now) {
+			continue
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		s.runJob(ctx, job)
+	}
+}
+
+// runJob runs job and records the run in the jobs file
+func (s *Scheduler) runJob(ctx context.Context, job Job) {
+	s.mu.Lock()
+	s.running = job.ID
+	s.mu.Unlock()
+
+	slog.Info("Running job", "job", job.ID, "source", job.Source, "peer", job.Peer)
+	run := Run{JobID: job.ID, Started: time.Now()}
+	outcome, err := s.run(ctx, job)
+	run.Finished = time.Now()
+	run.FilesSent, run.FilesFailed = outcome.FilesSent, outcome.FilesFailed
+	if err != nil {
+		run.Error = err.Error()
+		slog.Warn("Job failed", "job", job.ID, "error", err)
+	} else {
+		slog.Info("Job finished", "job", job.ID, "sent", run.FilesSent, "failed", run.FilesFailed)
+	}
+
+	s.mu.Lock()
+	s.running = ""
+	s.mu.Unlock()
+
+	// Reload, the job commands may have changed the file while the job ran
+	store, err := Load(s.path)
+	if err != nil {
+		slog.Error("Failed to record job run", "job", job.ID, "error", err)
+		return
+	}
+	if _, err := store.Job(job.ID); err != nil {
+		return
+	}
+	store.RecordRun(run)
+	if err := store.Save(); err != nil {
+		slog.Error("Failed to record job run", "job", job.ID, "error", err)
+	}
+}
+
+// Status returns the state of every job in the jobs file at now
+func (s *Scheduler) Status(now time.Time) ([]Status, error) {
+	store, err := Load(s.path)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	running := s.running
+	s.mu.Unlock()
+
+	var statuses []Status
+	for _, job := range store.Jobs() {
+		status := Status{Job: job, Running: job.ID == running}
+		if schedule, err := ParseSchedule(job.Cron); err == nil {
+			status.Next = schedule.Next(now)
+		}
+		if run, ok := store.LastRun(job.ID); ok {
+			status.LastRun = &run
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
@@ -0,0 +1,67 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScheduler_RunsDueJobsAndRecordsRuns(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	store, err := Load(path)
+	require.NoError(t, err)
+	nightly, err := store.Add(Job{Source: t.TempDir(), Peer: "nas", Cron: "0 2 * * *"})
+	require.NoError(t, err)
+	hourly, err := store.Add(Job{Source: t.TempDir(), Peer: "offline-box", Cron: "0 * * * *"})
+	require.NoError(t, err)
+	require.NoError(t, store.Save())
+
+	var ran []string
+	scheduler := NewScheduler(path, func(ctx context.Context, job Job) (Outcome, error) {
+		ran = append(ran, job.ID)
+		if job.Peer == "offline-box" {
+			return Outcome{}, errors.New("receiver not found")
+		}
+		return Outcome{FilesSent: 3}, nil
+	})
+
+	ctx := context.Background()
+	day := time.Date(2024, 5, 15, 0, 0, 0, 0, time.Local)
+	scheduler.RunDue(ctx, day.Add(time.Hour+30*time.Minute))
+	assert.Empty(t, ran, "the first check only starts the schedule")
+
+	scheduler.RunDue(ctx, day.Add(time.Hour+59*time.Minute))
+	assert.Empty(t, ran)
+
+	scheduler.RunDue(ctx, day.Add(2*time.Hour+time.Second))
+	assert.Equal(t, []string{nightly.ID, hourly.ID}, ran)
+
+	// Several missed hours run once
+	ran = nil
+	scheduler.RunDue(ctx, day.Add(5*time.Hour+time.Second))
+	assert.Equal(t, []string{hourly.ID}, ran)
+
+	store, err = Load(path)
+	require.NoError(t, err)
+	nightlyRuns := store.History(nightly.ID)
+	require.Len(t, nightlyRuns, 1)
+	assert.True(t, nightlyRuns[0].Succeeded())
+	assert.Equal(t, 3, nightlyRuns[0].FilesSent)
+	hourlyRuns := store.History(hourly.ID)
+	require.Len(t, hourlyRuns, 2)
+	assert.Equal(t, "receiver not found", hourlyRuns[0].Error)
+
+	statuses, err := scheduler.Status(day.Add(5 * time.Hour))
+	require.NoError(t, err)
+	require.Len(t, statuses, 2)
+	assert.Equal(t, day.Add(26*time.Hour), statuses[0].Next)
+	assert.Equal(t, day.Add(6*time.Hour), statuses[1].Next)
+	require.NotNil(t, statuses[1].LastRun)
+	assert.False(t, statuses[1].LastRun.Succeeded())
+	assert.False(t, statuses[0].Running)
+}
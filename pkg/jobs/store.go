@@ -0,0 +1,246 @@
+// Package jobs keeps recurring sync jobs, which send a directory to a peer on a cron
+// schedule, and the history of their runs. The headless receiver runs them as a daemon.
+package jobs
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rescp17/lanFileSharer/internal/config"
+)
+
+// FileName is the jobs file in the application config directory
+const FileName = "jobs.json"
+
+// maxRunsPerJob is how many runs of each job the history keeps
+const maxRunsPerJob = 20
+
+// ErrJobNotFound is returned for job IDs that are not in the store
+var ErrJobNotFound = errors.New("job not found")
+
+// Job sends the Source directory to Peer whenever Cron matches
+type Job struct {
+	ID        string    `json:"id"`
+	Source    string    `json:"source"` // Absolute path of the directory to send
+	Peer      string    `json:"peer"`   // Receiver name, as given to send --to
+	Cron      string    `json:"cron"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Run is the history entry of one run of a job
+type Run struct {
+	JobID       string    `json:"job_id"`
+	Started     time.Time `json:"started"`
+	Finished    time.Time `json:"finished"`
+	FilesSent   int       `json:"files_sent"`
+	FilesFailed int       `json:"files_failed"`
+	Error       string    `json:"error,omitempty"` // Empty if the run succeeded
+}
+
+// Succeeded reports whether every file of the run was sent
+func (r Run) Succeeded() bool {
+	return r.Error == "" && r.FilesFailed == 0
+}
+
+// storeFile is the content of the jobs file
+type storeFile struct {
+	Jobs    []Job `json:"jobs"`
+	History []Run `json:"history,omitempty"`
+}
+
+// Store is the jobs file. The daemon and the job commands each load it when they need it,
+// so changes made by one are seen by the other on its next load.
+type Store struct {
+	path string
+	mu   sync.Mutex
+	file storeFile
+}
+
+// DefaultPath returns the default location of the jobs file
+func DefaultPath() (string, error) {
+	dir, err := config.Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, FileName), nil
+}
+
+// Load reads the store at path; a missing file yields an empty store
+func Load(path string) (*Store, error) {
+	store := &Store{path: path}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return store, nil
+		}
+		return nil, fmt.Errorf("failed to read jobs: %w", err)
+	}
+	if err := json.Unmarshal(data, &store.file); err != nil {
+		return nil, fmt.Errorf("failed to parse jobs file %s: %w", path, err)
+	}
+	return store, nil
+}
+
+// Jobs returns the jobs, oldest first
+func (s *Store) Jobs() []Job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Job(nil), s.file.Jobs...)
+}
+
+// Job returns the job with id
+func (s *Store) Job(id string) (Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, job := range s.file.Jobs {
+		if job.ID == id {
+			return job, nil
+		}
+	}
+	return Job{}, fmt.Errorf("%w: %s", ErrJobNotFound, id)
+}
+
+// Add checks job, gives it an ID and adds it
+func (s *Store) Add(job Job) (Job, error) {
+	if _, err := ParseSchedule(job.Cron); err != nil {
+		return Job{}, err
+	}
+	if job.Peer == "" {
+		return Job{}, errors.New("job has no peer")
+	}
+	source, err := filepath.Abs(job.Source)
+	if err != nil {
+		return Job{}, fmt.Errorf("invalid source %q: %w", job.Source, err)
+	}
+	info, err := os.Stat(source)
+	if err != nil {
+		return Job{}, fmt.Errorf("invalid source: %w", err)
+	}
+	if !info.IsDir() {
+		return Job{}, fmt.Errorf("source %s is not a directory", source)
+	}
+	job.Source = source
+	if job.CreatedAt.IsZero() {
+		job.CreatedAt = time.Now()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job.ID, err = s.newID()
+	if err != nil {
+		return Job{}, err
+	}
+	s.file.Jobs = append(s.file.Jobs, job)
+	return job, nil
+}
+
+// newID returns an ID no job has
+func (s *Store) newID() (string, error) {
+	for {
+		b := make([]byte, 4)
+		if _, err := rand.Read(b); err != nil {
+			return "", fmt.Errorf("failed to create job id: %w", err)
+		}
+		id := hex.EncodeToString(b)
+		taken := false
+		for _, job := range s.file.Jobs {
+			taken = taken || job.ID == id
+		}
+		if !taken {
+			return id, nil
+		}
+	}
+}
+
+// Remove deletes the job with id and its history
+func (s *Store) Remove(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, job := range s.file.Jobs {
+		if job.ID != id {
+			continue
+		}
+		s.file.Jobs = append(s.file.Jobs[:i], s.file.Jobs[i+1:]...)
+		history := s.file.History[:0]
+		for _, run := range s.file.History {
+			if run.JobID != id {
+				history = append(history, run)
+			}
+		}
+		s.file.History = history
+		return nil
+	}
+	return fmt.Errorf("%w: %s", ErrJobNotFound, id)
+}
+
+// RecordRun adds run to the history, dropping the oldest runs of its job beyond the limit
+func (s *Store) RecordRun(run Run) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.file.History = append(s.file.History, run)
+
+	kept := 0
+	for i := len(s.file.History) - 1; i >= 0; i-- {
+		if s.file.History[i].JobID != run.JobID {
+			continue
+		}
+		kept++
+		if kept > maxRunsPerJob {
+			s.file.History = append(s.file.History[:i], s.file.History[i+1:]...)
+		}
+	}
+}
+
+// History returns the recorded runs of the job with id, most recent first
+func (s *Store) History(id string) []Run {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var runs []Run
+	for _, run := range s.file.History {
+		if run.JobID == id {
+			runs = append(runs, run)
+		}
+	}
+	sort.SliceStable(runs, func(i, j int) bool { return runs[i].Started.After(runs[j].Started) })
+	return runs
+}
+
+// LastRun returns the most recent run of the job with id
+func (s *Store) LastRun(id string) (Run, bool) {
+	runs := s.History(id)
+	if len(runs) == 0 {
+		return Run{}, false
+	}
+	return runs[0], true
+}
+
+// Save writes the store to disk, replacing the file in one step so a concurrent Load never
+// sees half of it
+func (s *Store) Save() error {
+	s.mu.Lock()
+	data, err := json.MarshalIndent(s.file, "", "  ")
+	s.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to marshal jobs: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("failed to create jobs directory: %w", err)
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write jobs: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("failed to write jobs: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package jobs
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore_AddRemoveAndPersist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	store, err := Load(path)
+	require.NoError(t, err)
+	assert.Empty(t, store.Jobs())
+
+	source := t.TempDir()
+	job, err := store.Add(Job{Source: source, Peer: "nas", Cron: "0 2 * * *"})
+	require.NoError(t, err)
+	assert.NotEmpty(t, job.ID)
+	assert.Equal(t, source, job.Source)
+	require.NoError(t, store.Save())
+
+	reloaded, err := Load(path)
+	require.NoError(t, err)
+	require.Len(t, reloaded.Jobs(), 1)
+	assert.Equal(t, job.ID, reloaded.Jobs()[0].ID)
+
+	require.NoError(t, reloaded.Remove(job.ID))
+	assert.ErrorIs(t, reloaded.Remove(job.ID), ErrJobNotFound)
+	assert.Empty(t, reloaded.Jobs())
+}
+
+func TestStore_AddValidates(t *testing.T) {
+	store, err := Load(filepath.Join(t.TempDir(), FileName))
+	require.NoError(t, err)
+	dir := t.TempDir()
+
+	_, err = store.Add(Job{Source: dir, Peer: "nas", Cron: "every night"})
+	assert.Error(t, err, "invalid schedule")
+	_, err = store.Add(Job{Source: dir, Cron: "0 2 * * *"})
+	assert.Error(t, err, "no peer")
+	_, err = store.Add(Job{Source: filepath.Join(dir, "missing"), Peer: "nas", Cron: "0 2 * * *"})
+	assert.Error(t, err, "missing source")
+	assert.Empty(t, store.Jobs())
+}
+
+func TestStore_HistoryIsBoundedPerJob(t *testing.T) {
+	store, err := Load(filepath.Join(t.TempDir(), FileName))
+	require.NoError(t, err)
+
+	start := time.Date(2024, 5, 15, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < maxRunsPerJob+5; i++ {
+		store.RecordRun(Run{JobID: "a", Started: start.Add(time.Duration(i) * time.Hour)})
+	}
+	store.RecordRun(Run{JobID: "b", Started: start, Error: "peer not found"})
+
+	runs := store.History("a")
+	require.Len(t, runs, maxRunsPerJob)
+	assert.Equal(t, start.Add(time.Duration(maxRunsPerJob+4)*time.Hour), runs[0].Started, "most recent first")
+	assert.Equal(t, start.Add(5*time.Hour), runs[len(runs)-1].Started, "the oldest runs were dropped")
+
+	last, ok := store.LastRun("b")
+	require.True(t, ok)
+	assert.False(t, last.Succeeded())
+}
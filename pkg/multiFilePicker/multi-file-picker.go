@@ -1,6 +1,7 @@
 package multiFilePicker
 
 import (
+	"context"
 	"fmt"
 	"io/fs"
 	"log"
@@ -9,6 +10,8 @@ import (
 	"path/filepath"
 	"sort"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/textinput"
@@ -17,12 +20,16 @@ import (
 	"github.com/gabriel-vasile/mimetype"
 	"github.com/rescp17/lanFileSharer/internal/style"
 	"github.com/rescp17/lanFileSharer/internal/util"
+	"github.com/rescp17/lanFileSharer/pkg/events"
 	"github.com/rescp17/lanFileSharer/pkg/fileInfo"
+	"github.com/rescp17/lanFileSharer/pkg/transfer"
 )
 
 type mode int
 type SelectedFileNodeMsg struct {
 	Files []fileInfo.FileNode
+	// Locked are the paths of selected files left out because other processes lock them
+	Locked []string
 }
 
 const (
@@ -30,6 +37,30 @@ const (
 	modeInput
 )
 
+// hashProgressInterval is how often the view is refreshed while the selection is prepared
+const hashProgressInterval = 100 * time.Millisecond
+
+// hashJob prepares a confirmed selection in the background
+type hashJob struct {
+	cancel context.CancelFunc
+	result chan hashDoneMsg
+
+	mu       sync.Mutex
+	progress events.PreparationProgress
+}
+
+// hashProgressMsg asks the model to redraw the progress of job and keep waiting for it
+type hashProgressMsg struct {
+	job *hashJob
+}
+
+// hashDoneMsg carries the hashed selection of job, or the error that stopped it
+type hashDoneMsg struct {
+	job   *hashJob
+	files []fileInfo.FileNode
+	err   error
+}
+
 // --- Key Map ---
 type KeyMap struct {
 	Up           key.Binding
@@ -79,6 +110,10 @@ type Model struct {
 	height   int // For viewport height
 	offset   int // For scrolling
 	files    []*fileInfo.FileNode
+	// hashWorkers is how many files are hashed at once, zero for one at a time
+	hashWorkers int
+	hashing     *hashJob
+	eventBus    *events.Bus // Optional bus preparation progress is published to
 	// OnSelect func([]*fileInfo.FileNode) tea.Cmd // Callback for when files are selected
 }
 
@@ -203,7 +238,35 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.height = msg.Height
 		return m, nil
 
+	case hashProgressMsg:
+		if msg.job != m.hashing {
+			return m, nil
+		}
+		return m, m.hashing.wait()
+
+	case hashDoneMsg:
+		if msg.job != m.hashing {
+			return m, nil
+		}
+		m.hashing = nil
+		if msg.err != nil {
+			m.inputErr = fmt.Errorf("failed to prepare selection: %w", msg.err)
+			return m, nil
+		}
+		files, locked := msg.files, msg.job.latest().Locked
+		return m, func() tea.Msg {
+			return SelectedFileNodeMsg{Files: files, Locked: locked}
+		}
+
 	case tea.KeyMsg:
+		// While the selection is hashed, the only way out is to cancel it
+		if m.hashing != nil {
+			if key.Matches(msg, m.keys.Quit) {
+				m.CancelHashing()
+			}
+			return m, nil
+		}
+
 		// Global quit
 		if key.Matches(msg, m.keys.Quit) {
 			if m.mode == modeInput {
@@ -316,12 +379,11 @@ func (m Model) updateBrowse(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		}
 
 	case key.Matches(msg, m.keys.Confirm):
-		// If we have selected files, return them
+		// If we have selected files, hash them in the background and return them when done
 		if len(m.selected) > 0 {
-			files := getSelectedFileNodes(m.selected)
-			return m, func() tea.Msg {
-				return SelectedFileNodeMsg{Files: files}
-			}
+			m.inputErr = nil
+			m.hashing = startHashing(m.selected, m.hashWorkerCount(), m.eventBus)
+			return m, m.hashing.wait()
 		}
 		
 		// If no files selected but cursor is on a directory, navigate into it
@@ -394,6 +456,10 @@ func (m Model) View() string {
 	}
 	s.WriteString("\n\n")
 
+	if m.hashing != nil {
+		s.WriteString(m.hashingView() + "\n\n")
+	}
+
 	if m.path == "" {
 		return s.String()
 	}
@@ -481,6 +547,37 @@ func (m Model) View() string {
 	return s.String()
 }
 
+// hashingView renders the progress of preparing the confirmed selection
+func (m Model) hashingView() string {
+	progress := m.hashing.latest()
+	cancel := fmt.Sprintf("'%s' to cancel", m.keys.Quit.Help().Key)
+	if progress.Phase != events.PreparationHashing && progress.Phase != events.PreparationDone {
+		return fmt.Sprintf("Preparing: %s files scanned (%s), %s",
+			formatCount(progress.FilesScanned), util.FormatSize(progress.BytesScanned), cancel)
+	}
+
+	percent := 0.0
+	if progress.BytesScanned > 0 {
+		percent = float64(progress.BytesHashed) / float64(progress.BytesScanned) * 100
+	}
+	return fmt.Sprintf("Preparing: %s files, hashed %s / %s (%.0f%%), %s",
+		formatCount(progress.FilesScanned), util.FormatSize(progress.BytesHashed),
+		util.FormatSize(progress.BytesScanned), percent, cancel)
+}
+
+// formatCount formats n with thousands separators
+func formatCount(n int64) string {
+	digits := fmt.Sprintf("%d", n)
+	var b strings.Builder
+	for i, d := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(d)
+	}
+	return b.String()
+}
+
 func (m Model) helpView() string {
 	return lipgloss.NewStyle().Faint(true).Render(
 		fmt.Sprintf("Use '%s'/'%s' to page, '%s' to browse, '%s' to confirm, '%s' to quit",
@@ -488,17 +585,89 @@ func (m Model) helpView() string {
 	)
 }
 
-func getSelectedFileNodes(selection map[string]struct{}) []fileInfo.FileNode {
-	var files []fileInfo.FileNode
+// SetHashWorkers sets how many files are hashed at once after confirming; zero hashes one at a time
+func (m *Model) SetHashWorkers(workers int) {
+	m.hashWorkers = workers
+}
+
+// HashWorkers returns the value set by SetHashWorkers
+func (m Model) HashWorkers() int {
+	return m.hashWorkers
+}
+
+// SetEventBus publishes PreparationProgress events to bus while a confirmed selection is prepared
+func (m *Model) SetEventBus(bus *events.Bus) {
+	m.eventBus = bus
+}
+
+// EventBus returns the bus set by SetEventBus
+func (m Model) EventBus() *events.Bus {
+	return m.eventBus
+}
+
+// Hashing reports whether a confirmed selection is still being hashed
+func (m Model) Hashing() bool {
+	return m.hashing != nil
+}
+
+// CancelHashing stops hashing the confirmed selection, if any, keeping the selection
+func (m *Model) CancelHashing() {
+	if m.hashing == nil {
+		return
+	}
+	m.hashing.cancel()
+	m.hashing = nil
+}
+
+func (m Model) hashWorkerCount() int {
+	if m.hashWorkers > 0 {
+		return m.hashWorkers
+	}
+	return 1
+}
+
+// startHashing scans and hashes the selected paths on a background goroutine
+func startHashing(selection map[string]struct{}, workers int, bus *events.Bus) *hashJob {
+	paths := make([]string, 0, len(selection))
 	for path := range selection {
-		info, err := fileInfo.CreateNode(path)
-		if err != nil {
-			log.Printf("Failed to create fileNode, %v", err)
-			continue
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	job := &hashJob{cancel: cancel, result: make(chan hashDoneMsg, 1)}
+	go func() {
+		defer cancel()
+		files, err := transfer.PrepareNodes(ctx, paths, workers, func(p events.PreparationProgress) {
+			job.mu.Lock()
+			job.progress = p
+			job.mu.Unlock()
+			bus.Publish(p)
+		})
+		job.result <- hashDoneMsg{job: job, files: files, err: err}
+	}()
+	return job
+}
+
+// latest returns the most recent progress of the job
+func (j *hashJob) latest() events.PreparationProgress {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.progress
+}
+
+// wait returns a command that delivers the result of the job, or a progress tick if it takes longer
+func (j *hashJob) wait() tea.Cmd {
+	return func() tea.Msg {
+		timer := time.NewTimer(hashProgressInterval)
+		defer timer.Stop()
+		select {
+		case msg := <-j.result:
+			return msg
+		case <-timer.C:
+			return hashProgressMsg{job: j}
 		}
-		files = append(files, info)
 	}
-	return files
 }
 
 func (m *Model) SetPath(path string) error {
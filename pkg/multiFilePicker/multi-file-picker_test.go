@@ -160,13 +160,14 @@ func TestConfirmSelection(t *testing.T) {
 	// Verify we have 2 items selected
 	assert.Len(t, m.selected, 2, "should have 2 items selected")
 
-	// 4. Confirm selection - this should return a command that sends SelectedFileNodeMsg
+	// 4. Confirm selection - this hashes in the background and eventually sends SelectedFileNodeMsg
 	finalModel, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
 	m = finalModel.(Model)
+	assert.True(t, m.Hashing(), "confirming should start hashing")
 
-	// Execute the command to get the message
+	// Execute the commands until the hashed selection arrives
 	if cmd != nil {
-		msg := cmd()
+		msg := runUntilSelected(t, m, cmd)
 		if selectedMsg, ok := msg.(SelectedFileNodeMsg); ok {
 			assert.Len(t, selectedMsg.Files, 2, "should have 2 files in the message")
 			
@@ -192,6 +193,49 @@ func TestConfirmSelection(t *testing.T) {
 	}
 }
 
+// runUntilSelected feeds the messages of cmd back into m until SelectedFileNodeMsg is sent
+func runUntilSelected(t *testing.T, m Model, cmd tea.Cmd) tea.Msg {
+	t.Helper()
+	for i := 0; i < 100 && cmd != nil; i++ {
+		msg := cmd()
+		if _, ok := msg.(SelectedFileNodeMsg); ok {
+			assert.False(t, m.Hashing(), "hashing should be finished")
+			return msg
+		}
+		newModel, next := m.Update(msg)
+		m = newModel.(Model)
+		cmd = next
+	}
+	return nil
+}
+
+func TestConfirmSelection_CancelHashing(t *testing.T) {
+	tempDir, cleanup := setupTestDir(t)
+	defer cleanup()
+
+	m := InitialModel()
+	require.NoError(t, m.SetPath(tempDir))
+
+	newModel, _ := m.Update(tea.KeyMsg{Type: tea.KeySpace})
+	m = newModel.(Model)
+	newModel, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = newModel.(Model)
+	require.True(t, m.Hashing())
+	require.NotNil(t, cmd)
+
+	// Escape cancels hashing instead of quitting, and the late result is dropped
+	newModel, _ = m.Update(tea.KeyMsg{Type: tea.KeyEscape})
+	m = newModel.(Model)
+	assert.False(t, m.Hashing())
+	assert.False(t, m.quitting, "cancelling hashing should not quit")
+	assert.Len(t, m.selected, 1, "cancelling should keep the selection")
+
+	newModel, next := m.Update(cmd())
+	m = newModel.(Model)
+	assert.Nil(t, next, "a cancelled job should not send its selection")
+	assert.NoError(t, m.inputErr)
+}
+
 func TestQuit(t *testing.T) {
 	m := Model{keys: DefaultKeyMap}
 	finalModel, _ := m.Update(tea.KeyMsg{Type: tea.KeyEscape})
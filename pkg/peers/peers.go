@@ -0,0 +1,211 @@
+// Package peers keeps settings for individual peers, keyed by the fingerprint of their
+// device key: senders apply them when the peer is selected, receivers when it connects.
+package peers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/rescp17/lanFileSharer/internal/config"
+)
+
+// FileName is the peer settings file in the application config directory
+const FileName = "peers.json"
+
+// Transports a peer can be reached over
+const (
+	// TransportAuto uses the transport of the config, which may use STUN servers
+	TransportAuto = "auto"
+	// TransportLAN keeps the connection on the local network, as lan_only does
+	TransportLAN = "lan"
+)
+
+// ErrPeerNotFound is returned for fingerprints without settings
+var ErrPeerNotFound = errors.New("no settings for peer")
+
+// Settings override the config for one peer. Zero values leave the config in effect.
+type Settings struct {
+	// Label names the peer in listings
+	Label string `json:"label,omitempty"`
+	// BandwidthLimitKB caps how fast files are sent to the peer, in KiB per second
+	BandwidthLimitKB int `json:"bandwidth_limit_kb,omitempty"`
+	// OutputSubdir is the directory under the output directory files from the peer are saved in
+	OutputSubdir string `json:"output_subdir,omitempty"`
+	// AutoAccept accepts the peer's requests without asking
+	AutoAccept bool `json:"auto_accept,omitempty"`
+	// Transport is TransportAuto or TransportLAN; empty is TransportAuto
+	Transport string `json:"transport,omitempty"`
+}
+
+// BandwidthLimit returns BandwidthLimitKB in bytes per second, zero if unlimited
+func (s Settings) BandwidthLimit() int64 {
+	return int64(s.BandwidthLimitKB) << 10
+}
+
+// LANOnly reports whether connections to the peer must stay on the local network
+func (s Settings) LANOnly() bool {
+	return s.Transport == TransportLAN
+}
+
+// Validate checks that the settings can be applied
+func (s Settings) Validate() error {
+	if s.BandwidthLimitKB < 0 {
+		return fmt.Errorf("bandwidth limit %d must not be negative", s.BandwidthLimitKB)
+	}
+	switch s.Transport {
+	case "", TransportAuto, TransportLAN:
+	default:
+		return fmt.Errorf("unknown transport %q, use %q or %q", s.Transport, TransportAuto, TransportLAN)
+	}
+	if s.OutputSubdir != "" {
+		if filepath.IsAbs(s.OutputSubdir) || !filepath.IsLocal(s.OutputSubdir) {
+			return fmt.Errorf("output subdirectory %q must be relative and stay inside the output directory", s.OutputSubdir)
+		}
+	}
+	return nil
+}
+
+// Peer is a fingerprint with its settings
+type Peer struct {
+	Fingerprint string
+	Settings
+}
+
+// Store is the peer settings file. The running apps load it at start, so changes take
+// effect the next time they start.
+type Store struct {
+	path  string
+	mu    sync.RWMutex
+	peers map[string]Settings
+}
+
+// DefaultPath returns the default location of the peer settings file
+func DefaultPath() (string, error) {
+	dir, err := config.Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, FileName), nil
+}
+
+// LoadDefault loads the peer settings file in the config directory. If it cannot be read,
+// which is logged, it returns nil and peers are used without settings.
+func LoadDefault() *Store {
+	path, err := DefaultPath()
+	if err != nil {
+		slog.Warn("Could not resolve the peer settings file, peers are used without settings", "error", err)
+		return nil
+	}
+	store, err := Load(path)
+	if err != nil {
+		slog.Warn("Failed to load peer settings, peers are used without settings", "error", err)
+		return nil
+	}
+	return store
+}
+
+// Load reads the store at path; a missing file yields an empty store
+func Load(path string) (*Store, error) {
+	store := &Store{path: path, peers: make(map[string]Settings)}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return store, nil
+		}
+		return nil, fmt.Errorf("failed to read peer settings: %w", err)
+	}
+	if err := json.Unmarshal(data, &store.peers); err != nil {
+		return nil, fmt.Errorf("failed to parse peer settings file %s: %w", path, err)
+	}
+	return store, nil
+}
+
+// NormalizeFingerprint lowercases fingerprint and drops the separators people paste it with
+func NormalizeFingerprint(fingerprint string) string {
+	return strings.ToLower(strings.NewReplacer(":", "", " ", "", "-", "").Replace(fingerprint))
+}
+
+// Get returns the settings of the peer with fingerprint. A nil store has none.
+func (s *Store) Get(fingerprint string) (Settings, bool) {
+	if s == nil || fingerprint == "" {
+		return Settings{}, false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	settings, ok := s.peers[NormalizeFingerprint(fingerprint)]
+	return settings, ok
+}
+
+// Set checks settings and stores them for the peer with fingerprint
+func (s *Store) Set(fingerprint string, settings Settings) error {
+	fingerprint = NormalizeFingerprint(fingerprint)
+	if fingerprint == "" {
+		return errors.New("peer fingerprint is empty")
+	}
+	if err := settings.Validate(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.peers[fingerprint] = settings
+	return nil
+}
+
+// Remove deletes the settings of the peer with fingerprint
+func (s *Store) Remove(fingerprint string) error {
+	fingerprint = NormalizeFingerprint(fingerprint)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.peers[fingerprint]; !ok {
+		return fmt.Errorf("%w: %s", ErrPeerNotFound, fingerprint)
+	}
+	delete(s.peers, fingerprint)
+	return nil
+}
+
+// Peers returns every peer with settings, sorted by label and fingerprint
+func (s *Store) Peers() []Peer {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	peers := make([]Peer, 0, len(s.peers))
+	for fingerprint, settings := range s.peers {
+		peers = append(peers, Peer{Fingerprint: fingerprint, Settings: settings})
+	}
+	sort.Slice(peers, func(i, j int) bool {
+		if peers[i].Label != peers[j].Label {
+			return peers[i].Label < peers[j].Label
+		}
+		return peers[i].Fingerprint < peers[j].Fingerprint
+	})
+	return peers
+}
+
+// Save writes the store to disk, replacing the file in one step so a concurrent Load never
+// sees half of it
+func (s *Store) Save() error {
+	s.mu.RLock()
+	data, err := json.MarshalIndent(s.peers, "", "  ")
+	s.mu.RUnlock()
+	if err != nil {
+		return fmt.Errorf("failed to marshal peer settings: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("failed to create peer settings directory: %w", err)
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write peer settings: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("failed to write peer settings: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package peers
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore_SetGetAndPersist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	store, err := Load(path)
+	require.NoError(t, err)
+	assert.Empty(t, store.Peers())
+
+	settings := Settings{Label: "nas", BandwidthLimitKB: 512, OutputSubdir: "nas", AutoAccept: true, Transport: TransportLAN}
+	require.NoError(t, store.Set("AB:CD:EF", settings))
+	require.NoError(t, store.Save())
+
+	reloaded, err := Load(path)
+	require.NoError(t, err)
+	got, ok := reloaded.Get("abcdef")
+	require.True(t, ok)
+	assert.Equal(t, settings, got)
+	assert.Equal(t, int64(512<<10), got.BandwidthLimit())
+	assert.True(t, got.LANOnly())
+	require.Len(t, reloaded.Peers(), 1)
+	assert.Equal(t, "abcdef", reloaded.Peers()[0].Fingerprint)
+
+	require.NoError(t, reloaded.Remove("ab-cd-ef"))
+	assert.ErrorIs(t, reloaded.Remove("abcdef"), ErrPeerNotFound)
+	_, ok = reloaded.Get("abcdef")
+	assert.False(t, ok)
+}
+
+func TestStore_NilHasNoSettings(t *testing.T) {
+	var store *Store
+	_, ok := store.Get("abcdef")
+	assert.False(t, ok)
+}
+
+func TestSettings_Validate(t *testing.T) {
+	assert.NoError(t, Settings{}.Validate())
+	assert.NoError(t, Settings{OutputSubdir: "from/laptop", Transport: TransportAuto}.Validate())
+	assert.Error(t, Settings{BandwidthLimitKB: -1}.Validate())
+	assert.Error(t, Settings{Transport: "carrier-pigeon"}.Validate())
+	assert.Error(t, Settings{OutputSubdir: "../outside"}.Validate())
+	assert.Error(t, Settings{OutputSubdir: "/abs"}.Validate())
+
+	store, err := Load(filepath.Join(t.TempDir(), FileName))
+	require.NoError(t, err)
+	assert.Error(t, store.Set("abcdef", Settings{Transport: "carrier-pigeon"}))
+	assert.Error(t, store.Set("", Settings{}))
+	assert.Empty(t, store.Peers())
+}
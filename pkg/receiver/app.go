@@ -8,6 +8,8 @@ import (
 	"log/slog"
 	"net/http"
 	"os"
+	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
@@ -19,9 +21,22 @@ import (
 	"github.com/rescp17/lanFileSharer/internal/app"
 	appevents "github.com/rescp17/lanFileSharer/internal/app_events"
 	"github.com/rescp17/lanFileSharer/internal/app_events/receiver"
+	"github.com/rescp17/lanFileSharer/internal/config"
 	"github.com/rescp17/lanFileSharer/internal/util"
+	"github.com/rescp17/lanFileSharer/pkg/audit"
+	"github.com/rescp17/lanFileSharer/pkg/cas"
 	"github.com/rescp17/lanFileSharer/pkg/concurrency"
+	"github.com/rescp17/lanFileSharer/pkg/crypto"
 	"github.com/rescp17/lanFileSharer/pkg/discovery"
+	"github.com/rescp17/lanFileSharer/pkg/events"
+	"github.com/rescp17/lanFileSharer/pkg/fileInfo"
+	"github.com/rescp17/lanFileSharer/pkg/peers"
+	"github.com/rescp17/lanFileSharer/pkg/recent"
+	"github.com/rescp17/lanFileSharer/pkg/scan"
+	"github.com/rescp17/lanFileSharer/pkg/share"
+	"github.com/rescp17/lanFileSharer/pkg/storage"
+	"github.com/rescp17/lanFileSharer/pkg/transfer"
+	"github.com/rescp17/lanFileSharer/pkg/webhook"
 	webrtcPkg "github.com/rescp17/lanFileSharer/pkg/webrtc"
 )
 
@@ -39,17 +54,155 @@ type App struct {
 	connMu               sync.Mutex
 	errChan              chan error
 	outputPath           string
+	resumeStore          *transfer.ResumeStore
+	bus                  *events.Bus // Reception progress, consumed by the UI and other subscribers
+	serviceName          string
+	announceCancel       context.CancelFunc // Stops the running announcement, nil while hidden
+	scopes               []discovery.Scope  // Service types and domains the receiver is announced in
 
 	// File reception management
 	fileReceiver *FileReceiver
 	receiverMu   sync.Mutex
+	verifyWrites float64              // Share of written chunks read back from disk
+	template     *OutputTemplate      // Layout of received files, nil saves them in outputPath
+	lanOnly      bool                 // Connections stay on private and link-local addresses
+	offline      bool                 // No STUN, TURN or multicast DNS
+	relay        bool                 // Advertised as a relay
+	contentStore *cas.Store           // Content-addressable store of the output directory, nil keeps plain files
+	scanner      scan.Scanner         // Virus scanner received files must pass, nil scans none
+	storage      storage.WriteBackend // Where completed files land, nil keeps them in outputPath
+	peerSettings *peers.Store         // Settings saved for individual senders, nil if there are none
+	lockdown     bool                 // Peer settings may not move received files out of outputPath
+	fingerprint  string               // Device key fingerprint advertised to senders
+	icon         string               // Emoji or glyph advertised with the service name
+	webhooks     *webhook.Notifier
+	webhookStart *webhook.Event     // Start of the session reported to webhooks, nil if none was
+	sharing      bool               // A directory is shared for pulling
+	shareClient  *api.Client        // Lists and pulls from the shares of other devices
+	browseCancel context.CancelFunc // Stops looking for shares, nil while not browsing
+	idleTimeout  time.Duration      // Silence of a sender sending keepalives that ends its session
+	recent       *recent.Store      // Recently received files, nil records none
+	guestMax     int64              // Largest transfer a guest pass accepts in bytes, zero for any size
+	quota        *api.Quota         // Charged with the bytes received from each sender, nil allows everything
+
+	// The sender cancelled the session; chunks still arriving are dropped. Guarded by receiverMu.
+	senderCancelled bool
+
+	// Extra connections the sender stripes the running session over, see serveBond
+	bondMu        sync.Mutex
+	bondToken     string                      // Resume token of the running session, empty if it cannot be joined
+	bondStructure *crypto.SignedFileStructure // Structure of the running session, whose key signs bond offers
+	bondConns     []*webrtc.PeerConnection
+}
+
+// Options configures optional receiver behaviour
+type Options struct {
+	// TrustStorePath is where accepted sender keys are recorded; empty disables key tracking
+	TrustStorePath string
+	// TrustMaxAge is how long an accepted key stays trusted; zero keeps it until the key expires
+	TrustMaxAge time.Duration
+	// TrustReadOnly stops accepted keys from being added to the trust store, see crypto.TrustStore.SetReadOnly
+	TrustReadOnly bool
+	// Lockdown declines senders whose keys are not trusted and ignores the output
+	// subdirectories of peer settings, see config.Config.Lockdown
+	Lockdown bool
+	// Registrar announces the receiver; nil uses mDNS. Combined mode shares it with the sender.
+	Registrar discovery.Adapter
+	// ServiceName is the announced instance name; empty derives one from the hostname
+	ServiceName string
+	// AcceptTimeout is how long a request waits for the user to accept it; zero waits forever
+	AcceptTimeout time.Duration
+	// DoNotDisturbMessage is the reason sent with requests declined while unavailable
+	DoNotDisturbMessage string
+	// Scopes are the service types and domains the receiver is announced in; empty uses the default
+	Scopes []discovery.Scope
+	// PeerFilter refuses senders by subnet and key fingerprint; nil allows everyone
+	PeerFilter *api.PeerFilter
+	// AuditLog records requests and how sessions ended; nil records nothing
+	AuditLog *audit.Log
+	// VerifyWrites is the share (0-1) of written chunks read back from disk and compared
+	VerifyWrites float64
+	// OutputTemplate lays out received files under the output directory; nil saves them there directly
+	OutputTemplate *OutputTemplate
+	// Quota limits transfer sizes and what each sender may send per day; nil allows everything
+	Quota *api.Quota
+	// LANOnly keeps connections to private and link-local addresses, without STUN or TURN
+	LANOnly bool
+	// Offline neither announces the receiver nor uses STUN, TURN or multicast DNS,
+	// for senders that are given its address
+	Offline bool
+	// PSK refuses requests not signed with a pre-shared key and signs answers; nil uses none
+	PSK *api.PSK
+	// Relay advertises the receiver as a relay and accepts requests to forward files to
+	// another receiver; the caller forwards them once received
+	Relay bool
+	// JobStatus serves the status of the scheduled jobs on GET /jobs to local clients; nil disables it
+	JobStatus api.JobStatusFunc
+	// ContentAddressed stores received files once per content in a store in the output directory,
+	// with the received tree made of hard links to it, and tells senders which files it already has
+	ContentAddressed bool
+	// PeerSettings are the settings saved for individual senders, applied when they connect;
+	// nil applies none
+	PeerSettings *peers.Store
+	// Fingerprint is the device key fingerprint advertised to senders, so they can apply their
+	// settings for this receiver; empty advertises none
+	Fingerprint string
+	// Icon is the emoji or glyph advertised with the service name; empty advertises none
+	Icon string
+	// Webhooks are notified when an accepted session starts and when it completes or fails;
+	// nil notifies none
+	Webhooks *webhook.Notifier
+	// Share is a directory peers may browse and pull files from, which Pull sends them;
+	// nil shares nothing
+	Share *share.Share
+	// Pull starts sending the files of a pull of Share
+	Pull api.PullFunc
+	// IdleTimeout ends sessions whose sender sent nothing, not even a keepalive, for this long,
+	// keeping what was received for the sender to resume without asking; zero never ends them
+	IdleTimeout time.Duration
+	// Recent records the received files for `recent` and the TUI picker; nil records none
+	Recent *recent.Store
+	// Scanner checks every received file for malware before it completes, quarantining the
+	// files it flags; nil scans none
+	Scanner scan.Scanner
+	// Storage is where completed files land, validated before each request is accepted;
+	// nil keeps them in the output directory without checks
+	Storage storage.WriteBackend
+	// GuestMaxBytes is the largest transfer a guest pass accepts, see IssueGuestPass; zero
+	// accepts any size
+	GuestMaxBytes int64
+	// UploadToken enables browser uploads giving it, see api.UploadPath; empty disables them
+	UploadToken string
+}
+
+// NewServiceName returns a unique instance name for this host
+func NewServiceName() (string, error) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s-%s", hostname, uuid.New().String()[:8]), nil
+}
+
+// TrustStorePath returns the default location of the trust store, or "" if it cannot be resolved
+func TrustStorePath() string {
+	dir, err := config.Dir()
+	if err != nil {
+		slog.Warn("Could not resolve trust store path, sender keys will not be tracked", "error", err)
+		return ""
+	}
+	return filepath.Join(dir, crypto.TrustStoreFileName)
 }
 
 // NewApp creates a new receiver application instance.
 func NewApp(port int, outputPath string) *App {
+	return NewAppWithOptions(port, outputPath, Options{})
+}
+
+// NewAppWithOptions creates a new receiver application instance with a trust store.
+func NewAppWithOptions(port int, outputPath string, options Options) *App {
 	uiMessages := make(chan tea.Msg, 10)
 	stateManager := app.NewSingleRequestManager()
-	apiHandler := api.NewAPI(uiMessages, stateManager)
 
 	dnssdlog.Info.SetOutput(io.Discard)
 	dnssdlog.Debug.SetOutput(io.Discard)
@@ -78,9 +231,74 @@ func NewApp(port int, outputPath string) *App {
 		slog.Info("Using specified output directory", "path", path)
 	}
 
-	return &App{
+	resumeStore := transfer.NewResumeStore(path)
+	apiHandler := api.NewAPI(uiMessages, stateManager, resumeStore)
+	apiHandler.SetAcceptTimeout(options.AcceptTimeout)
+	apiHandler.SetDoNotDisturbMessage(options.DoNotDisturbMessage)
+	apiHandler.SetPeerFilter(options.PeerFilter)
+	apiHandler.SetAuditLog(options.AuditLog)
+	apiHandler.SetQuota(options.Quota)
+	apiHandler.SetPSK(options.PSK)
+	apiHandler.SetRelay(options.Relay)
+	apiHandler.SetPeerSettings(options.PeerSettings)
+	apiHandler.SetTrustedOnly(options.Lockdown)
+	apiHandler.SetCompareHandler(func(files []fileInfo.FileNode, senderName string) (*api.CompareReport, error) {
+		return CompareOutput(path, options.OutputTemplate, TemplateValues{Time: time.Now(), Sender: senderName}, files)
+	})
+	if options.Share != nil {
+		apiHandler.SetShare(options.Share, options.Pull)
+	}
+	if options.JobStatus != nil {
+		apiHandler.SetJobStatus(options.JobStatus)
+	}
+	if _, local := options.Storage.(*storage.Local); options.Storage != nil && !local {
+		// Files leave the output directory, so there is nothing to link or deduplicate
+		apiHandler.SetRemoteStorage(true)
+		if options.ContentAddressed {
+			slog.Warn("Content-addressed storage needs local storage, saving plain files", "storage", options.Storage)
+			options.ContentAddressed = false
+		}
+	}
+	var contentStore *cas.Store
+	if options.ContentAddressed {
+		if contentStore, err = cas.Open(path); err != nil {
+			slog.Warn("Failed to open the content store, saving plain files", "error", err)
+		} else {
+			apiHandler.SetContentStore(contentStore.Has)
+		}
+	}
+	apiHandler.SetEchoHandler(webrtcPkg.NewWebrtcAPIWithOptions(webrtcPkg.APIOptions{LANOnly: options.LANOnly, Offline: options.Offline}).ServeEchoSession)
+	if options.TrustStorePath != "" {
+		trustStore, err := crypto.LoadTrustStore(options.TrustStorePath, options.TrustMaxAge)
+		if err != nil {
+			slog.Warn("Failed to load trust store, sender keys will not be tracked", "error", err)
+		} else {
+			trustStore.SetReadOnly(options.TrustReadOnly)
+			apiHandler.SetTrustStore(trustStore)
+		}
+	}
+
+	var registrar discovery.Adapter = &discovery.MDNSAdapter{}
+	if options.Registrar != nil {
+		registrar = options.Registrar
+	}
+	if options.Offline {
+		registrar = discovery.OfflineAdapter{}
+	}
+	scopes := options.Scopes
+	if len(scopes) == 0 {
+		scopes = []discovery.Scope{discovery.DefaultScope()}
+	}
+	shareClient := api.NewClient(options.ServiceName)
+	if options.PSK != nil {
+		shareClient.SetPSK(options.PSK)
+	}
+
+	a := &App{
 		guard:                concurrency.NewConcurrencyGuard(),
-		registrar:            &discovery.MDNSAdapter{},
+		registrar:            registrar,
+		serviceName:          options.ServiceName,
+		scopes:               scopes,
 		api:                  apiHandler,
 		port:                 port,
 		uiMessages:           uiMessages,
@@ -89,7 +307,31 @@ func NewApp(port int, outputPath string) *App {
 		inboundCandidateChan: make(chan webrtc.ICECandidateInit, 10),
 		errChan:              make(chan error, 1),
 		outputPath:           path,
+		resumeStore:          resumeStore,
+		verifyWrites:         options.VerifyWrites,
+		template:             options.OutputTemplate,
+		lanOnly:              options.LANOnly,
+		offline:              options.Offline,
+		relay:                options.Relay,
+		contentStore:         contentStore,
+		peerSettings:         options.PeerSettings,
+		lockdown:             options.Lockdown,
+		fingerprint:          options.Fingerprint,
+		icon:                 util.DeviceIcon(options.Icon),
+		webhooks:             options.Webhooks,
+		sharing:              options.Share != nil,
+		shareClient:          shareClient,
+		idleTimeout:          options.IdleTimeout,
+		recent:               options.Recent,
+		guestMax:             options.GuestMaxBytes,
+		quota:                options.Quota,
+		scanner:              options.Scanner,
+		storage:              options.Storage,
+		bus:                  events.NewBus(),
 	}
+	apiHandler.SetBondHandler(a.serveBond)
+	apiHandler.SetUpload(options.UploadToken, a.receiveUpload)
+	return a
 }
 
 // InboundCandidateChan provides a channel for the API layer to send candidates to the app logic.
@@ -120,9 +362,13 @@ func (a *App) handleInboundCandidate(candidate webrtc.ICECandidateInit) error {
 func (a *App) Run(ctx context.Context) error {
 	tctx, cancel := context.WithCancel(ctx)
 	defer cancel()
-	a.startRegistration(tctx, a.port, cancel)
+	a.startRegistration(tctx, cancel)
 	a.startServer(tctx, a.port)
 
+	progress := a.bus.Subscribe(0, events.TopicFile, events.TopicSession)
+	defer progress.Close()
+	go a.forwardProgress(tctx, progress)
+
 	for {
 		select {
 		case <-ctx.Done():
@@ -134,7 +380,7 @@ func (a *App) Run(ctx context.Context) error {
 				slog.Warn("Failed to handle inbound ICE candidate", "error", err)
 			}
 		case event := <-a.appEvents:
-			switch event.(type) {
+			switch e := event.(type) {
 			case receiver.FileRequestAccepted:
 				go func() {
 					if err := a.guard.Execute(func() error {
@@ -155,6 +401,22 @@ func (a *App) Run(ctx context.Context) error {
 					slog.Error("Failed to set decision", "error", err)
 				}
 				continue
+			case receiver.SetAvailability:
+				a.setAvailability(tctx, e.Availability)
+			case receiver.BrowseShares:
+				a.browseShares(tctx)
+			case receiver.StopBrowsing:
+				a.stopBrowsing()
+			case receiver.ListShare:
+				go a.listShare(tctx, e)
+			case receiver.PullFiles:
+				go a.pullFiles(tctx, e)
+			case receiver.PullOffer:
+				go a.pullOffer(tctx, e)
+			case receiver.PreviewShareFile:
+				go a.previewShareFile(tctx, e)
+			case receiver.IssueGuestPass:
+				a.issueGuestPass()
 			default:
 				slog.Warn("Received unhandled app event", "event", event)
 			}
@@ -162,6 +424,20 @@ func (a *App) Run(ctx context.Context) error {
 	}
 }
 
+// setAvailability changes whether requests are put to the user, and stops or restarts
+// the announcement when the receiver is hidden or shown again
+func (a *App) setAvailability(ctx context.Context, availability receiver.Availability) {
+	slog.Info("Receiver availability changed", "availability", availability)
+	a.api.SetAvailability(availability)
+	switch {
+	case availability == receiver.Hidden && a.announceCancel != nil:
+		a.announceCancel()
+		a.announceCancel = nil
+	case availability != receiver.Hidden && a.announceCancel == nil:
+		a.announce(ctx)
+	}
+}
+
 // sendAndLogError is a helper function to both log an error and send it to the UI.
 func (a *App) sendAndLogError(baseMessage string, err error) {
 	slog.Error(baseMessage, "error", err)
@@ -174,23 +450,61 @@ func (a *App) handleAcceptFileRequest(ctx context.Context) error {
 	hctx, cancel := context.WithTimeout(ctx, 30*time.Second)
 	defer cancel()
 
-	if err := a.stateManager.SetDecision(app.Accepted); err != nil {
-		a.sendAndLogError("Failed to set decision", err)
-		return err
+	if a.storage != nil {
+		// Files the storage cannot take would fail one by one, or fill the wrong disk
+		if err := a.storage.Validate(hctx); err != nil {
+			if decisionErr := a.stateManager.SetDecision(app.Rejected); decisionErr != nil {
+				slog.Warn("Failed to decline the request", "error", decisionErr)
+			}
+			a.sendAndLogError(fmt.Sprintf("Storage %s unavailable, declined the request", a.storage), err)
+			return err
+		}
 	}
 
 	// Get signed files information to determine expected file count
 	signedFiles, err := a.stateManager.GetSignedFiles()
-	expectedFileCount := 0
 	if err != nil {
 		slog.Warn("Could not get signed files information", "error", err)
-		// Continue without setting expected files - fallback behavior
-	} else if signedFiles != nil {
-		expectedFileCount = len(signedFiles.Files)
-		slog.Info("Expected file count determined", "count", expectedFileCount)
+	}
+	resumeToken, err := a.stateManager.GetResumeToken()
+	if err != nil {
+		slog.Warn("Could not get resume token", "error", err)
+	}
+
+	// Completed files are proven into the signed Merkle root of the accepted files
+	accepted, err := a.acceptedFiles(signedFiles, resumeToken)
+	if err != nil {
+		if decisionErr := a.stateManager.SetDecision(app.Rejected); decisionErr != nil {
+			slog.Warn("Failed to decline the request", "error", decisionErr)
+		}
+		a.sendAndLogError("Signed file list does not verify, declined the request", err)
+		return err
+	}
+
+	if err := a.stateManager.SetDecision(app.Accepted); err != nil {
+		if errors.Is(err, app.ErrNoActiveRequest) || errors.Is(err, app.ErrDecisionMade) {
+			// The request timed out just before the user accepted it, the API reports that
+			slog.Warn("Request is no longer pending", "error", err)
+			return err
+		}
+		a.sendAndLogError("Failed to set decision", err)
+		return err
+	}
+
+	expectedFileCount := len(signedFiles.Files)
+	slog.Info("Expected file count determined", "count", expectedFileCount)
+	a.prepareFileReceiver(signedFiles, accepted, expectedFileCount, resumeToken)
+	a.notifySessionStart(signedFiles)
+	if writeAcks, err := a.stateManager.GetWriteAcks(); err == nil && writeAcks {
+		a.enableWriteAcks()
+	}
+	session := idleSession{resumeToken: resumeToken}
+	session.keepalive, _ = a.stateManager.GetKeepalive()
+	if signedFiles != nil {
+		session.fingerprint = crypto.PublicKeyFingerprint(signedFiles.PublicKey)
 	}
 
-	webrtcAPI := webrtcPkg.NewWebrtcAPI()
+	webrtcAPI := webrtcPkg.NewWebrtcAPIWithOptions(webrtcPkg.APIOptions{LANOnly: a.lanOnly, Offline: a.offline})
 
 	offer, err := a.stateManager.GetOffer()
 	if err != nil {
@@ -227,16 +541,53 @@ func (a *App) handleAcceptFileRequest(ctx context.Context) error {
 	})
 
 	// Set up data channel handler for file reception
+	control := &controlChannel{}
 	receiverConn.Peer().OnDataChannel(func(dc *webrtc.DataChannel) {
+		if dc.Label() == webrtcPkg.SpeedProbeLabel {
+			// The sender measures the path before sending files; nothing is stored
+			webrtcPkg.ServeSpeedProbe(dc)
+			return
+		}
+		if dc.Label() == webrtcPkg.ControlLabel {
+			// Replies go out here instead of behind the chunks of the file channels
+			slog.Info("Control channel opened")
+			dc.OnOpen(func() { control.attach(dc) })
+			dc.OnMessage(func(msg webrtc.DataChannelMessage) {
+				control.touch()
+				transfer.TraceWire(transfer.TraceIn, dc.Label(), msg.Data)
+				if err := a.handleControlMessage(msg.Data, func(data []byte) error { return sendTraced(dc, data) }); err != nil {
+					slog.Error("Failed to handle control message", "error", err)
+				}
+			})
+			dc.OnClose(control.detach)
+			return
+		}
 		slog.Info("Data channel opened for file reception", "label", dc.Label())
+		// Large files may arrive over several channels; only the first one reports its state
+		primary := !strings.HasPrefix(dc.Label(), "file-transfer-")
+		// Keepalives go both ways on the control channel, or the first channel without one,
+		// but the sender may talk on any of them
+		var monitor *transfer.KeepaliveMonitor
+		stopKeepalive := func() {}
+		reply := control.replyOn(dc)
+		if primary {
+			monitor, stopKeepalive = a.watchIdle(receiverConn, reply, session)
+			control.watch(monitor)
+		}
 
 		dc.OnOpen(func() {
-			slog.Info("File transfer data channel opened")
-			a.uiMessages <- receiver.StatusUpdateMsg{Message: "Starting file reception..."}
+			slog.Info("File transfer data channel opened", "label", dc.Label())
+			if primary {
+				a.uiMessages <- receiver.StatusUpdateMsg{Message: "Starting file reception..."}
+			}
 		})
 
 		dc.OnMessage(func(msg webrtc.DataChannelMessage) {
-			if err := a.handleFileChunk(msg.Data); err != nil {
+			if monitor != nil {
+				monitor.Touch()
+			}
+			transfer.TraceWire(transfer.TraceIn, dc.Label(), msg.Data)
+			if err := a.handleFileChunk(msg.Data, reply); err != nil {
 				slog.Error("Failed to handle file chunk", "error", err)
 				a.uiMessages <- receiver.StatusUpdateMsg{Message: fmt.Sprintf("Error receiving file: %v", err)}
 			}
@@ -248,8 +599,16 @@ func (a *App) handleAcceptFileRequest(ctx context.Context) error {
 		})
 
 		dc.OnClose(func() {
-			slog.Info("File transfer data channel closed")
-			a.uiMessages <- receiver.StatusUpdateMsg{Message: "File transfer completed"}
+			slog.Info("File transfer data channel closed", "label", dc.Label())
+			if primary {
+				stopKeepalive()
+				a.endBond()
+				a.persistResumeState()
+				a.recordRecent()
+				a.expectResume(session)
+				a.endSession()
+				a.uiMessages <- receiver.StatusUpdateMsg{Message: "File transfer completed"}
+			}
 		})
 	})
 
@@ -279,6 +638,7 @@ func (a *App) handleAcceptFileRequest(ctx context.Context) error {
 		return err
 	}
 	slog.Info("Answer created and sent to state manager.")
+	a.startBond(resumeToken, signedFiles)
 	success = true
 	return nil
 }
@@ -291,29 +651,47 @@ func (a *App) AppEvents() chan<- appevents.AppEvent {
 	return a.appEvents
 }
 
-func (a *App) startRegistration(ctx context.Context, port int, cancel context.CancelFunc) {
-	hostname, err := os.Hostname()
-	if err != nil {
-		a.sendAndLogError("Could not get hostname", err)
-		cancel()
+func (a *App) startRegistration(ctx context.Context, cancel context.CancelFunc) {
+	if a.serviceName == "" {
+		name, err := NewServiceName()
+		if err != nil {
+			a.sendAndLogError("Could not get hostname", err)
+			cancel()
+		}
+		a.serviceName = name
 	}
-	serviceUUID := uuid.New().String()
 
-	serviceInfo := discovery.ServiceInfo{
-		Name:   fmt.Sprintf("%s-%s", hostname, serviceUUID[:8]),
-		Type:   discovery.DefaultServerType,
-		Domain: discovery.DefaultDomain,
-		Addr:   nil,
-		Port:   port,
-	}
+	a.announce(ctx)
+}
 
-	go func() {
-		err := a.registrar.Announce(ctx, serviceInfo)
-		if err != nil {
-			a.sendAndLogError("Failed to start mDNS announcement", err)
-			a.errChan <- err
+// announce advertises the receiver in every scope until ctx ends or the receiver is hidden
+func (a *App) announce(ctx context.Context) {
+	ctx, cancel := context.WithCancel(ctx)
+	a.announceCancel = cancel
+
+	meta := a.serviceMeta()
+	go a.advertiseMeta(ctx, meta)
+
+	for _, scope := range a.scopes {
+		serviceInfo := discovery.ServiceInfo{
+			Name:   a.serviceName,
+			Type:   scope.Type,
+			Domain: scope.Domain,
+			Addr:   nil,
+			Port:   a.port,
+			Meta:   meta,
 		}
-	}()
+		go func() {
+			err := a.registrar.Announce(ctx, serviceInfo)
+			if err != nil {
+				a.sendAndLogError("Failed to start mDNS announcement", err)
+				select {
+				case a.errChan <- err:
+				default: // Another announcement already failed
+				}
+			}
+		}()
+	}
 }
 
 func (a *App) startServer(ctx context.Context, port int) {
@@ -364,20 +742,222 @@ func (a *App) setActiveConn(conn webrtcPkg.ReceiverConnection) {
 	}
 }
 
-// handleFileChunk processes incoming file chunk messages
-func (a *App) handleFileChunk(data []byte) error {
+// endSession records the outcome of the current session in the audit log
+func (a *App) endSession() {
+	a.receiverMu.Lock()
+	defer a.receiverMu.Unlock()
+
+	var completed, failed int
+	var received int64
+	var complete bool
+	if a.fileReceiver != nil {
+		completed, failed, received, complete = a.fileReceiver.Outcome()
+	}
+	a.api.EndSession(completed, failed, received, complete)
+	a.notifySessionEnd(failed, received, complete)
+}
+
+// handleFileChunk processes incoming file chunk messages; reply sends file ACKs back to the sender
+func (a *App) handleFileChunk(data []byte, reply func([]byte) error) error {
 	a.receiverMu.Lock()
 	defer a.receiverMu.Unlock()
 
+	if a.senderCancelled {
+		// Chunks the sender queued before cancelling; the session ends once its channels close
+		return nil
+	}
+
 	// Initialize file receiver if not exists
 	if a.fileReceiver == nil {
-		a.fileReceiver = NewFileReceiver(a.outputPath, a.uiMessages)
+		a.fileReceiver = NewFileReceiver(a.sessionOutputPath(), a.uiMessages)
+		a.fileReceiver.SetEventBus(a.bus)
+		a.fileReceiver.SetWriteVerification(a.verifyWrites)
+		a.fileReceiver.SetContentStore(a.contentStore)
+		a.fileReceiver.SetScanner(a.scanner)
+		a.fileReceiver.SetStorage(a.storage)
+		a.applyOutputTemplate()
+		a.applyRecentFiles()
 
 		// Set expected file count if available
 		if signedFiles, err := a.stateManager.GetSignedFiles(); err == nil && signedFiles != nil {
 			a.fileReceiver.SetExpectedFiles(len(signedFiles.Files))
+			a.fileReceiver.SetExpectedBytes(expectedBytes(signedFiles.Files))
+			a.applyQuota(signedFiles)
 		}
 	}
+	// Accepted sessions prepare the receiver before the data channel exists
+	a.fileReceiver.SetAcknowledger(reply)
 
 	return a.fileReceiver.ProcessChunk(data)
 }
+
+// sessionOutputPath returns the directory the files of the current request are saved in:
+// the subdirectory saved in the sender's peer settings, or the output directory
+func (a *App) sessionOutputPath() string {
+	signedFiles, err := a.stateManager.GetSignedFiles()
+	if err != nil || signedFiles == nil {
+		return a.outputPath
+	}
+	settings, ok := a.peerSettings.Get(crypto.PublicKeyFingerprint(signedFiles.PublicKey))
+	if !ok || settings.OutputSubdir == "" {
+		return a.outputPath
+	}
+	if a.lockdown {
+		slog.Warn("Ignoring the sender's output subdirectory, the receiver is locked down", "subdir", settings.OutputSubdir)
+		return a.outputPath
+	}
+	path := filepath.Join(a.outputPath, settings.OutputSubdir)
+	if err := os.MkdirAll(path, 0755); err != nil {
+		slog.Warn("Failed to create the sender's output subdirectory, saving in the output directory", "path", path, "error", err)
+		return a.outputPath
+	}
+	return path
+}
+
+// applyOutputTemplate lays out the files of the new session with the output template; a.receiverMu must be held
+func (a *App) applyOutputTemplate() {
+	if a.template == nil {
+		return
+	}
+	senderName, err := a.stateManager.GetSenderName()
+	if err != nil {
+		slog.Warn("Could not get sender name", "error", err)
+	}
+	a.fileReceiver.SetOutputTemplate(a.template, TemplateValues{Time: time.Now(), Sender: senderName})
+}
+
+// applyQuota charges what the session receives to the quota of the sender of signedFiles;
+// a.receiverMu must be held
+func (a *App) applyQuota(signedFiles *crypto.SignedFileStructure) {
+	if a.quota == nil {
+		return
+	}
+	a.fileReceiver.SetQuota(a.quota.Meter(crypto.PublicKeyFingerprint(signedFiles.PublicKey)))
+}
+
+// enableWriteAcks makes the prepared FileReceiver report write progress to the sender
+func (a *App) enableWriteAcks() {
+	a.receiverMu.Lock()
+	defer a.receiverMu.Unlock()
+	a.fileReceiver.EnableWriteAcks()
+}
+
+// prepareFileReceiver creates a fresh FileReceiver for an accepted session, proving completed
+// files into the signed root of accepted if set.
+// With a resume token, a persisted state is reused if it still matches the signed structure.
+func (a *App) prepareFileReceiver(signedFiles *crypto.SignedFileStructure, accepted *crypto.FileSubset, expectedFileCount int, resumeToken string) {
+	a.receiverMu.Lock()
+	defer a.receiverMu.Unlock()
+
+	a.senderCancelled = false
+	a.fileReceiver = NewFileReceiver(a.sessionOutputPath(), a.uiMessages)
+	a.fileReceiver.SetEventBus(a.bus)
+	a.fileReceiver.SetWriteVerification(a.verifyWrites)
+	a.fileReceiver.SetContentStore(a.contentStore)
+	a.fileReceiver.SetScanner(a.scanner)
+	a.fileReceiver.SetStorage(a.storage)
+	a.applyOutputTemplate()
+	a.applyRecentFiles()
+	a.checkNameCollisions(signedFiles)
+	a.fileReceiver.SetSignedFiles(accepted)
+	if expectedFileCount > 0 {
+		a.fileReceiver.SetExpectedFiles(expectedFileCount)
+	}
+	if signedFiles != nil {
+		a.fileReceiver.SetExpectedBytes(expectedBytes(signedFiles.Files))
+		a.applyQuota(signedFiles)
+		chain, err := crypto.NewStructureChain(signedFiles)
+		if err != nil {
+			slog.Warn("Structure updates disabled for this session", "error", err)
+		} else {
+			a.fileReceiver.SetStructureChain(chain)
+		}
+	}
+	if resumeToken == "" {
+		return
+	}
+
+	state, err := a.resumeStore.Load(resumeToken)
+	switch {
+	case errors.Is(err, transfer.ErrResumeStateNotFound):
+		state = transfer.NewResumeState(resumeToken)
+	case err != nil:
+		slog.Warn("Failed to load resume state, starting over", "token", resumeToken, "error", err)
+		state = transfer.NewResumeState(resumeToken)
+	case !resumeStateMatches(state, signedFiles):
+		slog.Warn("Resume state does not match the signed file structure, starting over", "token", resumeToken)
+		if err := a.resumeStore.Delete(resumeToken); err != nil {
+			slog.Warn("Failed to delete stale resume state", "error", err)
+		}
+		state = transfer.NewResumeState(resumeToken)
+	default:
+		slog.Info("Resuming interrupted session", "token", resumeToken)
+		a.uiMessages <- receiver.StatusUpdateMsg{Message: fmt.Sprintf("Resuming session %s", resumeToken)}
+	}
+
+	a.fileReceiver.EnableResume(a.resumeStore, state)
+}
+
+// checkNameCollisions finds offered files that would be written to the same output file,
+// including names differing only in case on case-insensitive filesystems, and warns that the
+// receiver will save them under numbered names instead of overwriting; a.receiverMu must be held
+func (a *App) checkNameCollisions(signedFiles *crypto.SignedFileStructure) {
+	caseInsensitive, err := util.IsCaseInsensitiveDir(a.outputPath)
+	if err != nil {
+		slog.Warn("Could not tell whether the output directory is case-insensitive", "path", a.outputPath, "error", err)
+	}
+	a.fileReceiver.SetCaseInsensitive(caseInsensitive)
+	if signedFiles == nil {
+		return
+	}
+
+	// Files are written flat into the output directory under their base names
+	names := make([]string, 0, len(signedFiles.Files))
+	for _, file := range signedFiles.Files {
+		names = append(names, filepath.Base(file.Name))
+	}
+	collisions := transfer.FindNameCollisions(names, caseInsensitive)
+	if len(collisions) == 0 {
+		return
+	}
+
+	renamed := 0
+	for _, collision := range collisions {
+		renamed += len(collision.Names) - 1
+		slog.Warn("Offered files collide in the output directory", "names", collision.Names, "caseInsensitive", caseInsensitive)
+	}
+	a.uiMessages <- receiver.StatusUpdateMsg{Message: fmt.Sprintf(
+		"%d offered files collide with other files' names in %s and will be saved under numbered names", renamed, a.outputPath)}
+}
+
+// resumeStateMatches re-validates persisted progress against the newly signed structure:
+// every file recorded in the state must still be offered with the same name, size and checksum.
+func resumeStateMatches(state *transfer.ResumeState, signedFiles *crypto.SignedFileStructure) bool {
+	if signedFiles == nil {
+		return false
+	}
+
+	offered := make(map[string]bool, len(signedFiles.Files))
+	for _, file := range signedFiles.Files {
+		offered[fmt.Sprintf("%s|%d|%s", file.Name, file.Size, file.Checksum)] = true
+	}
+	for _, file := range state.Files {
+		if !offered[fmt.Sprintf("%s|%d|%s", file.FileName, file.TotalSize, file.ExpectedHash)] {
+			return false
+		}
+	}
+	return true
+}
+
+// persistResumeState flushes the active session's chunk bitmap to disk
+func (a *App) persistResumeState() {
+	a.receiverMu.Lock()
+	defer a.receiverMu.Unlock()
+
+	if a.fileReceiver == nil {
+		return
+	}
+	if err := a.fileReceiver.PersistResumeState(); err != nil {
+		slog.Warn("Failed to persist resume state", "error", err)
+	}
+}
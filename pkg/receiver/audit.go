@@ -0,0 +1,37 @@
+package receiver
+
+import (
+	"log/slog"
+	"path/filepath"
+
+	"github.com/rescp17/lanFileSharer/internal/config"
+	"github.com/rescp17/lanFileSharer/pkg/audit"
+)
+
+// AuditLogPath returns the location of the audit log, or "" if it cannot be resolved
+func AuditLogPath() string {
+	dir, err := config.Dir()
+	if err != nil {
+		slog.Warn("Could not resolve audit log path", "error", err)
+		return ""
+	}
+	return filepath.Join(dir, audit.FileName)
+}
+
+// AuditLog opens the audit log if cfg enables it. It returns nil, recording nothing,
+// when the log is disabled or cannot be opened, e.g. because its chain is broken.
+func AuditLog(cfg config.Config) *audit.Log {
+	if !cfg.AuditLog {
+		return nil
+	}
+	path := AuditLogPath()
+	if path == "" {
+		return nil
+	}
+	log, err := audit.Open(path)
+	if err != nil {
+		slog.Warn("Audit log disabled", "error", err)
+		return nil
+	}
+	return log
+}
@@ -0,0 +1,92 @@
+package receiver
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/pion/webrtc/v4"
+	"github.com/rescp17/lanFileSharer/api"
+	"github.com/rescp17/lanFileSharer/internal/app_events/receiver"
+	"github.com/rescp17/lanFileSharer/pkg/crypto"
+	"github.com/rescp17/lanFileSharer/pkg/transfer"
+	webrtcPkg "github.com/rescp17/lanFileSharer/pkg/webrtc"
+)
+
+// maxBondPaths bounds the extra connections a sender may stripe a session over
+const maxBondPaths = 4
+
+// startBond lets the sender of the session accepted with resumeToken stripe it over extra
+// connections offered with the key of signedFiles, until endBond
+func (a *App) startBond(resumeToken string, signedFiles *crypto.SignedFileStructure) {
+	a.bondMu.Lock()
+	defer a.bondMu.Unlock()
+	a.bondToken = resumeToken
+	a.bondStructure = signedFiles
+}
+
+// endBond closes the extra connections of the session and refuses new ones
+func (a *App) endBond() {
+	a.bondMu.Lock()
+	defer a.bondMu.Unlock()
+	for _, pc := range a.bondConns {
+		if err := pc.Close(); err != nil {
+			slog.Warn("Failed to close bond connection", "error", err)
+		}
+	}
+	a.bondConns = nil
+	a.bondToken = ""
+	a.bondStructure = nil
+}
+
+// bondAccepted reports whether token joins the running session, offer was signed with the key
+// of its structure and it has room for another path
+func (a *App) bondAccepted(token string, offer webrtc.SessionDescription, signature []byte) error {
+	a.bondMu.Lock()
+	defer a.bondMu.Unlock()
+	if token == "" || token != a.bondToken {
+		return api.ErrUnknownSession
+	}
+	if err := crypto.VerifyBondOffer(a.bondStructure, token, offer.SDP, signature); err != nil {
+		return err
+	}
+	if len(a.bondConns) >= maxBondPaths {
+		return fmt.Errorf("session already has %d bond paths", maxBondPaths)
+	}
+	return nil
+}
+
+// serveBond answers the offer of an extra connection to the running session, whose channels
+// carry chunks of the session's files like its own secondary channels
+func (a *App) serveBond(ctx context.Context, token string, offer webrtc.SessionDescription, signature []byte) (*webrtc.SessionDescription, error) {
+	if err := a.bondAccepted(token, offer, signature); err != nil {
+		return nil, err
+	}
+	webrtcAPI := webrtcPkg.NewWebrtcAPIWithOptions(webrtcPkg.APIOptions{LANOnly: a.lanOnly, Offline: a.offline})
+	pc, answer, err := webrtcAPI.AnswerBond(ctx, offer, func(dc *webrtc.DataChannel) {
+		slog.Info("Bond channel opened for file reception", "label", dc.Label())
+		dc.OnMessage(func(msg webrtc.DataChannelMessage) {
+			transfer.TraceWire(transfer.TraceIn, dc.Label(), msg.Data)
+			if err := a.handleFileChunk(msg.Data, func(data []byte) error { return sendTraced(dc, data) }); err != nil {
+				slog.Error("Failed to handle file chunk", "error", err)
+				a.uiMessages <- receiver.StatusUpdateMsg{Message: fmt.Sprintf("Error receiving file: %v", err)}
+			}
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// The session may have ended while the answer was gathered
+	a.bondMu.Lock()
+	defer a.bondMu.Unlock()
+	if token != a.bondToken {
+		if err := pc.Close(); err != nil {
+			slog.Warn("Failed to close bond connection", "error", err)
+		}
+		return nil, api.ErrUnknownSession
+	}
+	a.bondConns = append(a.bondConns, pc)
+	slog.Info("Sender added a bond path to the session", "paths", len(a.bondConns)+1)
+	return answer, nil
+}
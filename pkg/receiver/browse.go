@@ -0,0 +1,173 @@
+package receiver
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"mime"
+	"net"
+	"path"
+	"time"
+
+	"github.com/rescp17/lanFileSharer/api"
+	"github.com/rescp17/lanFileSharer/internal/app_events/receiver"
+	"github.com/rescp17/lanFileSharer/pkg/discovery"
+	"github.com/rescp17/lanFileSharer/pkg/fileInfo"
+	"github.com/rescp17/lanFileSharer/pkg/share"
+	"github.com/rescp17/lanFileSharer/pkg/transfer"
+)
+
+// shareRequestTimeout bounds listing a directory of a share and asking it for files
+const shareRequestTimeout = 10 * time.Second
+
+// previewBytes is how much of a file of a share is fetched to preview it, enough for a
+// screen of text or the header of an image
+const previewBytes = 4 << 10
+
+// shareURL returns the base URL of the API of the device sharing files
+func shareURL(shared discovery.ServiceInfo) string {
+	return fmt.Sprintf("http://%s", net.JoinHostPort(shared.Addr.String(), fmt.Sprintf("%d", shared.Port)))
+}
+
+// browseShares starts reporting the devices sharing files in the receiver's first scope,
+// until stopBrowsing or the end of ctx
+func (a *App) browseShares(ctx context.Context) {
+	a.stopBrowsing()
+	ctx, cancel := context.WithCancel(ctx)
+	a.browseCancel = cancel
+
+	serviceChan := a.registrar.Discover(ctx, a.scopes[0].Query())
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case result, ok := <-serviceChan:
+				if !ok {
+					return
+				}
+				msg := receiver.SharesFoundMsg{Err: result.Error}
+				if result.Error == nil {
+					msg.Shares = a.filterShares(result.Services)
+				}
+				select {
+				case a.uiMessages <- msg:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+}
+
+// stopBrowsing stops looking for shares, if the receiver is
+func (a *App) stopBrowsing() {
+	if a.browseCancel != nil {
+		a.browseCancel()
+		a.browseCancel = nil
+	}
+}
+
+// filterShares returns the services sharing files, other than this receiver
+func (a *App) filterShares(services []discovery.ServiceInfo) []discovery.ServiceInfo {
+	shares := make([]discovery.ServiceInfo, 0, len(services))
+	for _, service := range services {
+		if service.Meta.Share && service.Name != a.serviceName {
+			shares = append(shares, service)
+		}
+	}
+	return shares
+}
+
+// listShare lists a directory of a share for the UI
+func (a *App) listShare(ctx context.Context, e receiver.ListShare) {
+	ctx, cancel := context.WithTimeout(ctx, shareRequestTimeout)
+	defer cancel()
+
+	entries, err := a.shareClient.ListShare(ctx, shareURL(e.Share), e.Path)
+	if err != nil {
+		slog.Warn("Failed to list share", "share", e.Share.Name, "path", e.Path, "error", err)
+	}
+	a.uiMessages <- receiver.ShareListingMsg{Share: e.Share.Name, Path: e.Path, Nodes: shareNodes(entries), Err: err}
+}
+
+// shareNodes converts a listing of a share to file nodes for the file tree. Directories have
+// no children until they are listed themselves.
+func shareNodes(entries []share.Entry) []fileInfo.FileNode {
+	nodes := make([]fileInfo.FileNode, 0, len(entries))
+	for _, entry := range entries {
+		node := fileInfo.FileNode{
+			Name:  entry.Name,
+			IsDir: entry.IsDir,
+			Size:  entry.Size,
+			Path:  entry.Path,
+		}
+		if !entry.IsDir {
+			node.MimeType = mime.TypeByExtension(path.Ext(entry.Name))
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes
+}
+
+// pullFiles asks a share to send paths to this receiver, which accepts the offer without
+// asking when it arrives
+func (a *App) pullFiles(ctx context.Context, e receiver.PullFiles) {
+	ctx, cancel := context.WithTimeout(ctx, shareRequestTimeout)
+	defer cancel()
+
+	token := transfer.NewResumeToken()
+	a.api.ExpectPull(token)
+	err := a.shareClient.Pull(ctx, shareURL(e.Share), api.PullPayload{
+		Paths:        e.Paths,
+		Port:         a.port,
+		Token:        token,
+		ReceiverName: a.serviceName,
+	})
+	if err != nil {
+		slog.Warn("Failed to pull from share", "share", e.Share.Name, "error", err)
+	} else {
+		slog.Info("Pull requested", "share", e.Share.Name, "paths", len(e.Paths))
+	}
+	a.uiMessages <- receiver.PullRequestedMsg{Share: e.Share.Name, Files: len(e.Paths), Err: err}
+}
+
+// previewShareFile fetches the start of a file of a share for the UI, without pulling it
+func (a *App) previewShareFile(ctx context.Context, e receiver.PreviewShareFile) {
+	ctx, cancel := context.WithTimeout(ctx, shareRequestTimeout)
+	defer cancel()
+
+	data, size, err := a.shareClient.PreviewShare(ctx, shareURL(e.Share), e.Path, previewBytes)
+	if err != nil {
+		slog.Warn("Failed to preview share file", "share", e.Share.Name, "path", e.Path, "error", err)
+	}
+	a.uiMessages <- receiver.SharePreviewMsg{Share: e.Share.Name, Path: e.Path, Data: data, Size: size, Err: err}
+}
+
+// pullOffer asks the share that exported an offer file for its files, trying each address the
+// offer lists. The offer that answers is accepted without asking if the key of the offer file signs it.
+func (a *App) pullOffer(ctx context.Context, e receiver.PullOffer) {
+	token := transfer.NewResumeToken()
+	a.api.ExpectOfferPull(token, e.Offer.Fingerprint())
+	payload := api.PullPayload{
+		Paths:        e.Offer.Paths,
+		Port:         a.port,
+		Token:        token,
+		ReceiverName: a.serviceName,
+		Offer:        e.Offer.Token,
+	}
+
+	var err error
+	for _, addr := range e.Offer.Addrs {
+		shared := discovery.ServiceInfo{Name: e.Offer.Sender, Addr: net.ParseIP(addr), Port: e.Offer.Port}
+		reqCtx, cancel := context.WithTimeout(ctx, shareRequestTimeout)
+		err = a.shareClient.Pull(reqCtx, shareURL(shared), payload)
+		cancel()
+		if err == nil {
+			slog.Info("Offer pulled", "share", e.Offer.Sender, "addr", addr, "paths", len(e.Offer.Paths))
+			break
+		}
+		slog.Warn("Failed to pull offer", "share", e.Offer.Sender, "addr", addr, "error", err)
+	}
+	a.uiMessages <- receiver.PullRequestedMsg{Share: e.Offer.Sender, Files: len(e.Offer.Paths), Err: err}
+}
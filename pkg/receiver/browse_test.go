@@ -0,0 +1,37 @@
+package receiver
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rescp17/lanFileSharer/pkg/discovery"
+	"github.com/rescp17/lanFileSharer/pkg/share"
+)
+
+func TestShareNodes(t *testing.T) {
+	nodes := shareNodes([]share.Entry{
+		{Name: "photos", Path: "photos", IsDir: true},
+		{Name: "notes.txt", Path: "docs/notes.txt", Size: 5},
+	})
+	require.Len(t, nodes, 2)
+	assert.True(t, nodes[0].IsDir)
+	assert.Empty(t, nodes[0].Children, "directories are listed when opened")
+	assert.Equal(t, "docs/notes.txt", nodes[1].Path, "paths stay relative to the share")
+	assert.Equal(t, int64(5), nodes[1].Size)
+	assert.Contains(t, nodes[1].MimeType, "text/plain")
+}
+
+func TestFilterShares(t *testing.T) {
+	app := NewAppWithOptions(0, t.TempDir(), Options{ServiceName: "self"})
+	require.NotNil(t, app)
+
+	shares := app.filterShares([]discovery.ServiceInfo{
+		{Name: "self", Meta: discovery.ServiceMeta{Share: true}},
+		{Name: "nas", Meta: discovery.ServiceMeta{Share: true}},
+		{Name: "laptop"},
+	})
+	require.Len(t, shares, 1)
+	assert.Equal(t, "nas", shares[0].Name)
+}
@@ -0,0 +1,162 @@
+package receiver
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rescp17/lanFileSharer/pkg/fileInfo"
+	"github.com/rescp17/lanFileSharer/pkg/transfer"
+)
+
+// chaosSender sends a file to a FileReceiver through a fault injector the way SenderConn
+// does, and reports the outcome from the receiver's file ACKs
+type chaosSender struct {
+	t        *testing.T
+	receiver *FileReceiver
+	acks     []*transfer.ChunkMessage
+}
+
+func newChaosSender(t *testing.T, outputDir string) *chaosSender {
+	s := &chaosSender{t: t, receiver: NewFileReceiver(outputDir, nil)}
+	serializer := transfer.NewJSONSerializer()
+	s.receiver.SetAcknowledger(func(data []byte) error {
+		ack, err := serializer.Unmarshal(data)
+		require.NoError(t, err)
+		s.acks = append(s.acks, ack)
+		return nil
+	})
+	return s
+}
+
+// send sends every chunk of node once and returns the error the sender would retry on
+func (s *chaosSender) send(node *fileInfo.FileNode, faults *transfer.FaultInjector) error {
+	chunker, err := transfer.NewChunkerFromFileNode(node, transfer.MinChunkSize)
+	require.NoError(s.t, err)
+	defer chunker.Close()
+
+	serializer := transfer.NewJSONSerializer()
+	deliver := func(msg *transfer.ChunkMessage) error {
+		data, err := serializer.Marshal(msg)
+		require.NoError(s.t, err)
+		// Failures reach the sender as ACKs, like over a data channel
+		_ = s.receiver.ProcessChunk(data)
+		return nil
+	}
+
+	acksBefore := len(s.acks)
+	for {
+		chunk, err := chunker.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		require.NoError(s.t, err)
+		require.NoError(s.t, faults.Send(&transfer.ChunkMessage{
+			Type:         transfer.ChunkData,
+			FileID:       node.Path,
+			FileName:     node.Name,
+			SequenceNo:   chunk.SequenceNo,
+			Offset:       chunk.Offset,
+			Data:         chunk.Data,
+			ChunkHash:    chunk.Hash,
+			TotalSize:    node.Size,
+			ExpectedHash: node.Checksum,
+			IsLast:       chunk.IsLast,
+		}, deliver))
+	}
+	require.NoError(s.t, faults.Flush())
+
+	if len(s.acks) == acksBefore {
+		return fmt.Errorf("%w: no ACK for %s", transfer.ErrTimeout, node.Name)
+	}
+	ack := s.acks[len(s.acks)-1]
+	if ack.ErrorMessage != "" {
+		return transfer.ErrorFromCode(ack.ErrorCode, ack.ErrorMessage)
+	}
+	if ack.ExpectedHash != node.Checksum {
+		return fmt.Errorf("%w: receiver verified %q", transfer.ErrChecksumMismatch, ack.ExpectedHash)
+	}
+	return nil
+}
+
+// sendWithRetries sends node until the receiver verifies it or the error handler gives up
+func (s *chaosSender) sendWithRetries(node *fileInfo.FileNode, policy *transfer.RetryPolicy, faults func(attempt int) *transfer.FaultInjector) (int, error) {
+	handler := transfer.NewDefaultErrorHandler(policy)
+	for attempt := 0; ; attempt++ {
+		err := s.send(node, faults(attempt))
+		if err == nil {
+			return attempt + 1, nil
+		}
+		if handler.HandleError(node.Path, err, attempt) != transfer.ErrorActionRetry {
+			return attempt + 1, err
+		}
+	}
+}
+
+func writeChaosSource(t *testing.T, size int) *fileInfo.FileNode {
+	content := make([]byte, size)
+	rand.New(rand.NewSource(1)).Read(content)
+	path := filepath.Join(t.TempDir(), "chaos.bin")
+	require.NoError(t, os.WriteFile(path, content, 0644))
+
+	node := &fileInfo.FileNode{Name: "chaos.bin", Path: path, Size: int64(size)}
+	_, err := node.CalcChecksum()
+	require.NoError(t, err)
+	return node
+}
+
+func assertReceivedIntact(t *testing.T, outputDir string, node *fileInfo.FileNode) {
+	want, err := os.ReadFile(node.Path)
+	require.NoError(t, err)
+	got, err := os.ReadFile(filepath.Join(outputDir, node.Name))
+	require.NoError(t, err)
+	assert.Equal(t, want, got)
+}
+
+func TestChaos_RetriesRecoverLostAndReorderedChunks(t *testing.T) {
+	node := writeChaosSource(t, 32*transfer.MinChunkSize)
+	outputDir := t.TempDir()
+	sender := newChaosSender(t, outputDir)
+
+	policy := transfer.DefaultRetryPolicy()
+	policy.MaxRetries = 20
+	faults := transfer.NewFaultInjector(transfer.ChaosConfig{DropRate: 0.2, ReorderRate: 0.3, Seed: 7})
+
+	attempts, err := sender.sendWithRetries(node, policy, func(int) *transfer.FaultInjector { return faults })
+	require.NoError(t, err)
+	assert.Greater(t, attempts, 1, "lost chunks leave the file unacknowledged until it is sent again")
+	assert.Positive(t, faults.Stats().Dropped)
+	assertReceivedIntact(t, outputDir, node)
+}
+
+func TestChaos_VerificationCatchesCorruptedChunks(t *testing.T) {
+	node := writeChaosSource(t, 8*transfer.MinChunkSize)
+	corruptFirst := func(attempt int) *transfer.FaultInjector {
+		if attempt == 0 {
+			return transfer.NewFaultInjector(transfer.ChaosConfig{CorruptRate: 1, Seed: 7})
+		}
+		return transfer.NewFaultInjector(transfer.ChaosConfig{})
+	}
+
+	// Checksum mismatches are not retried by default, so the corrupted file fails...
+	outputDir := t.TempDir()
+	_, err := newChaosSender(t, outputDir).sendWithRetries(node, transfer.DefaultRetryPolicy(), corruptFirst)
+	require.ErrorIs(t, err, transfer.ErrChecksumMismatch)
+	assert.NoFileExists(t, filepath.Join(outputDir, node.Name), "a file failing verification is removed")
+
+	// ...and is sent again, intact, when the policy retries them
+	policy := transfer.DefaultRetryPolicy()
+	policy.RetryableClasses = append(transfer.DefaultRetryableClasses(), "checksum_mismatch")
+	outputDir = t.TempDir()
+	attempts, err := newChaosSender(t, outputDir).sendWithRetries(node, policy, corruptFirst)
+	require.NoError(t, err)
+	assert.Equal(t, 2, attempts)
+	assertReceivedIntact(t, outputDir, node)
+}
@@ -0,0 +1,95 @@
+package receiver
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/rescp17/lanFileSharer/api"
+	"github.com/rescp17/lanFileSharer/pkg/fileInfo"
+)
+
+// CompareOutput compares the files a sender offers with outputDir, where they would be saved:
+// under their names, laid out by template if it is set. Content is compared by size and
+// checksum. Extra files are those in the same directories that were not offered; hidden
+// files, such as resume state, are left out. Templates using {time} never match earlier files.
+func CompareOutput(outputDir string, template *OutputTemplate, values TemplateValues, files []fileInfo.FileNode) (*api.CompareReport, error) {
+	report := &api.CompareReport{Missing: []string{}, Different: []string{}, Extra: []string{}}
+	offered := make(map[string]bool)
+	dirs := make(map[string]bool)
+
+	for _, file := range files {
+		if file.IsDir || file.IsStream() {
+			continue
+		}
+		rel := file.Name
+		if template != nil {
+			v := values
+			v.Original = file.Name
+			var err error
+			if rel, err = template.Expand(v); err != nil {
+				return nil, err
+			}
+		}
+		rel = filepath.Clean(rel)
+		offered[rel] = true
+		dirs[filepath.Dir(rel)] = true
+
+		same, err := sameContent(filepath.Join(outputDir, rel), file)
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+			report.Missing = append(report.Missing, rel)
+		case err != nil:
+			return nil, fmt.Errorf("failed to compare %s: %w", rel, err)
+		case same:
+			report.Same++
+		default:
+			report.Different = append(report.Different, rel)
+		}
+	}
+
+	for dir := range dirs {
+		entries, err := os.ReadDir(filepath.Join(outputDir, dir))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to list %s: %w", dir, err)
+		}
+		for _, entry := range entries {
+			rel := filepath.Join(dir, entry.Name())
+			if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") || offered[rel] {
+				continue
+			}
+			report.Extra = append(report.Extra, rel)
+		}
+	}
+
+	sort.Strings(report.Missing)
+	sort.Strings(report.Different)
+	sort.Strings(report.Extra)
+	return report, nil
+}
+
+// sameContent reports whether the file at path has the size and checksum of file
+func sameContent(path string, file fileInfo.FileNode) (bool, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return false, err
+	}
+	if info.IsDir() || info.Size() != file.Size {
+		return false, nil
+	}
+	if file.Checksum == "" {
+		return true, nil
+	}
+	local := fileInfo.FileNode{Path: path}
+	checksum, err := local.CalcChecksum()
+	if err != nil {
+		return false, err
+	}
+	return checksum == file.Checksum, nil
+}
@@ -0,0 +1,64 @@
+package receiver
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rescp17/lanFileSharer/pkg/fileInfo"
+)
+
+// offeredFile returns the node a sender would offer for a file with content
+func offeredFile(name, content string) fileInfo.FileNode {
+	sum := sha256.Sum256([]byte(content))
+	return fileInfo.FileNode{Name: name, Size: int64(len(content)), Checksum: hex.EncodeToString(sum[:])}
+}
+
+func TestCompareOutput(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+	write("same.txt", "unchanged")
+	write("edited.txt", "old content")
+	write("resized.txt", "short")
+	write("extra.txt", "only on the receiver")
+	write(".lanfilesharer-resume/state.json", "{}")
+
+	files := []fileInfo.FileNode{
+		{Name: "photos", IsDir: true},
+		offeredFile("same.txt", "unchanged"),
+		offeredFile("edited.txt", "new content"),
+		offeredFile("resized.txt", "much longer now"),
+		offeredFile("new.txt", "not sent yet"),
+	}
+	report, err := CompareOutput(dir, nil, TemplateValues{}, files)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"new.txt"}, report.Missing)
+	assert.Equal(t, []string{"edited.txt", "resized.txt"}, report.Different)
+	assert.Equal(t, []string{"extra.txt"}, report.Extra)
+	assert.Equal(t, 1, report.Same)
+}
+
+func TestCompareOutput_Template(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "laptop"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "laptop", "report.pdf"), []byte("v1"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "other.pdf"), []byte("elsewhere"), 0644))
+
+	template, err := ParseOutputTemplate("{sender}/{original}")
+	require.NoError(t, err)
+	values := TemplateValues{Time: time.Now(), Sender: "laptop"}
+	report, err := CompareOutput(dir, template, values, []fileInfo.FileNode{offeredFile("report.pdf", "v1")})
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.Same)
+	assert.Empty(t, report.Missing)
+	assert.Empty(t, report.Extra, "files outside the template's directories are not extra")
+}
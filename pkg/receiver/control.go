@@ -0,0 +1,97 @@
+package receiver
+
+import (
+	"log/slog"
+	"sync"
+
+	"github.com/pion/webrtc/v4"
+	"github.com/rescp17/lanFileSharer/internal/app_events/receiver"
+	"github.com/rescp17/lanFileSharer/pkg/transfer"
+)
+
+// controlChannel is the control channel the sender of a session opened, see
+// webrtcPkg.ControlLabel. ACKs and keepalives go out on it instead of the file channels,
+// so they are not queued behind the chunks those are busy with.
+type controlChannel struct {
+	mu      sync.Mutex
+	dc      *webrtc.DataChannel
+	monitor *transfer.KeepaliveMonitor // Keepalives of the session, touched by control messages
+}
+
+func (c *controlChannel) attach(dc *webrtc.DataChannel) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.dc = dc
+}
+
+func (c *controlChannel) detach() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.dc = nil
+}
+
+// watch makes control messages count as the sender being there for monitor, which may be nil
+func (c *controlChannel) watch(monitor *transfer.KeepaliveMonitor) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.monitor = monitor
+}
+
+// touch tells the keepalive monitor of the session the sender sent something
+func (c *controlChannel) touch() {
+	c.mu.Lock()
+	monitor := c.monitor
+	c.mu.Unlock()
+	if monitor != nil {
+		monitor.Touch()
+	}
+}
+
+// replyOn returns the function replies to the messages of dc are sent with: the control
+// channel while it is open, dc itself for senders that did not open one
+func (c *controlChannel) replyOn(dc *webrtc.DataChannel) func([]byte) error {
+	return func(data []byte) error {
+		c.mu.Lock()
+		control := c.dc
+		c.mu.Unlock()
+		if control != nil && control.ReadyState() == webrtc.DataChannelStateOpen {
+			return sendTraced(control, data)
+		}
+		return sendTraced(dc, data)
+	}
+}
+
+// sendTraced sends data on dc, adding it to the protocol trace once sent
+func sendTraced(dc *webrtc.DataChannel, data []byte) error {
+	if err := dc.Send(data); err != nil {
+		return err
+	}
+	transfer.TraceWire(transfer.TraceOut, dc.Label(), data)
+	return nil
+}
+
+// handleControlMessage handles a message of the control channel: pause and cancel notices are
+// shown to the user, anything else is handled like the messages of the file channels
+func (a *App) handleControlMessage(data []byte, reply func([]byte) error) error {
+	msg, err := transfer.NewJSONSerializer().Unmarshal(data)
+	if err != nil {
+		return err
+	}
+	switch msg.Type {
+	case transfer.TransferPause:
+		slog.Info("Sender paused the transfer")
+		a.uiMessages <- receiver.StatusUpdateMsg{Message: "Sender paused the transfer"}
+	case transfer.TransferResume:
+		slog.Info("Sender resumed the transfer")
+		a.uiMessages <- receiver.StatusUpdateMsg{Message: "Sender resumed the transfer"}
+	case transfer.TransferCancel:
+		slog.Info("Sender cancelled the transfer, dropping chunks still in flight")
+		a.receiverMu.Lock()
+		a.senderCancelled = true
+		a.receiverMu.Unlock()
+		a.uiMessages <- receiver.StatusUpdateMsg{Message: "Sender cancelled the transfer"}
+	default:
+		return a.handleFileChunk(data, reply)
+	}
+	return nil
+}
@@ -0,0 +1,15 @@
+package receiver
+
+import (
+	"log/slog"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// dropCachedPages evicts the synced range of file from the page cache
+func dropCachedPages(file *os.File, offset, length int64) {
+	if err := unix.Fadvise(int(file.Fd()), offset, length, unix.FADV_DONTNEED); err != nil {
+		slog.Debug("Failed to drop cached pages, verifying against the cache", "error", err)
+	}
+}
@@ -0,0 +1,8 @@
+//go:build !linux
+
+package receiver
+
+import "os"
+
+// dropCachedPages is not supported on this platform; written chunks are read back through the cache
+func dropCachedPages(file *os.File, offset, length int64) {}
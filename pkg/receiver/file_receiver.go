@@ -1,20 +1,31 @@
 package receiver
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"log/slog"
 	"os"
 	"path/filepath"
 	"strings"
 	"sync"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/rescp17/lanFileSharer/internal/app_events/receiver"
+	"github.com/rescp17/lanFileSharer/pkg/cas"
+	"github.com/rescp17/lanFileSharer/pkg/crypto"
+	"github.com/rescp17/lanFileSharer/pkg/events"
 	"github.com/rescp17/lanFileSharer/pkg/fileInfo"
+	"github.com/rescp17/lanFileSharer/pkg/recent"
+	"github.com/rescp17/lanFileSharer/pkg/scan"
+	"github.com/rescp17/lanFileSharer/pkg/storage"
 	"github.com/rescp17/lanFileSharer/pkg/transfer"
 )
 
+// errChunkOutOfBounds is returned for chunks outside the size of their file
+var errChunkOutOfBounds = errors.New("chunk outside its file")
+
 // FileReceiver manages the reception and reconstruction of files
 type FileReceiver struct {
 	serializer   transfer.MessageSerializer
@@ -24,11 +35,80 @@ type FileReceiver struct {
 	uiMessages   chan<- tea.Msg // Channel to send status updates to UI
 
 	// Session tracking
-	expectedFiles   int  // Total number of files expected in this session
-	completedFiles  int  // Number of files completed
-	sessionComplete bool // Whether the entire session is complete
+	expectedFiles   int    // Total number of files expected in this session
+	completedFiles  int    // Number of files completed
+	sessionComplete bool   // Whether the entire session is complete
+	lastOutputPath  string // Output path of the most recently completed file
+	// Verified files of this session by file ID, the targets of FileLink messages
+	completedOutputs map[string]completedOutput
+	// Checksums of the verified files of this session by file ID, whose late chunks are dropped
+	finishedFiles map[string]string
+
+	// Output names taken in this session, keyed by transfer.NameKey, to the file ID owning them
+	claimedNames    map[string]string
+	caseInsensitive bool // Whether the output directory ignores case, set with SetCaseInsensitive
+
+	// Resume support, enabled with EnableResume
+	resumeStore        *transfer.ResumeStore
+	resumeState        *transfer.ResumeState
+	chunksSincePersist int
+
+	// Verified structure that signed updates are applied to, set with SetStructureChain
+	structureChain *crypto.StructureChain
+	// Signed files by name with their proofs into merkleRoot, set with SetSignedFiles
+	signedFiles map[string][]crypto.ProvenFile
+	merkleRoot  *crypto.SignedMerkleRoot
+	// Files added or changed by structure updates by name, signed by their delta
+	deltaFiles map[string][]fileInfo.FileNode
+
+	// acknowledge sends per-file completion ACKs to the sender, set with SetAcknowledger
+	acknowledge func(data []byte) error
+	// Layout of received files under outputDir, set with SetOutputTemplate; nil saves them in outputDir
+	outputTemplate *OutputTemplate
+	templateValues TemplateValues
+	// Share of written chunks read back from disk, set with SetWriteVerification
+	verifyFraction float64
+	// Store verified files are added to, set with SetContentStore; nil keeps them as plain files
+	contentStore *cas.Store
+	// Virus scanner verified files must pass before they complete, set with SetScanner; nil scans none
+	scanner scan.Scanner
+	// Where completed files land, set with SetStorage; nil keeps them where they were received
+	storage storage.WriteBackend
+	// Completed files not yet added to the recent files list, set with SetRecentFiles
+	recent       *recent.Store
+	recentSender string
+	recentFiles  []recent.Entry
+	// Charged with every chunk written, set with SetQuota; nil allows everything
+	quota ByteMeter
+	// Write progress ACKs, enabled with EnableWriteAcks
+	writeAcks    bool
+	lastWriteAck time.Time
+
+	// Progress events, published when a bus is set with SetEventBus
+	bus                *events.Bus
+	expectedBytes      int64 // Total size of the files expected in this session
+	receivedBytes      int64 // Bytes written in this session, across all files
+	failedFiles        int
+	startedAt          time.Time // First progress publication of the session
+	lastPublish        time.Time
+	lastPublishedBytes int64
+	rate               float64       // bytes per second between the last two publications
+	diskBytes          int64         // Bytes written to disk in this session
+	diskTime           time.Duration // Time spent writing them, including syncs
+	lastDiskBytes      int64
+	lastDiskTime       time.Duration
+	phases             transfer.PhaseTimings // Summed over the finished files
 }
 
+const (
+	// resumePersistInterval is the number of chunks written between resume state saves
+	resumePersistInterval = 64
+	// progressPublishInterval throttles progress events while a file is being received
+	progressPublishInterval = 250 * time.Millisecond
+	// writeAckInterval throttles write progress ACKs while a file is being received
+	writeAckInterval = 250 * time.Millisecond
+)
+
 // ReceptionStatus represents the current status of file reception
 type ReceptionStatus int
 
@@ -49,25 +129,158 @@ type FileReception struct {
 	ReceivedSize int64
 	ExpectedHash string
 	File         *os.File
-	// Remove Chunks cache, support out-of-order direct writing
-	ReceivedChunks  map[uint32]bool // Track received chunk sequence numbers
-	mu              sync.RWMutex    // Protect concurrent writes
+	// Chunks are written at their offsets as they arrive, in any order
+	ReceivedChunks  transfer.ChunkSet // Chunks written so far, by sequence number
+	writing         map[uint32]bool   // Chunks being written, see claimChunk
+	mu              sync.RWMutex      // Protect concurrent writes
 	IsComplete      bool
 	Status          ReceptionStatus
 	VerificationErr error
 	OutputPath      string // Full path to the output file
+	Location        string // Where the completed file landed, if it left OutputPath
+	WriteMismatches int    // Chunks that read back differently from disk
+	// Phases is the time spent writing and verifying the file
+	Phases transfer.PhaseTimings
 }
 
 // NewFileReceiver creates a new file receiver
 func NewFileReceiver(outputDir string, uiMessages chan<- tea.Msg) *FileReceiver {
 	return &FileReceiver{
-		serializer:   transfer.NewJSONSerializer(),
-		currentFiles: make(map[string]*FileReception),
-		outputDir:    outputDir,
-		uiMessages:   uiMessages,
+		serializer:       transfer.NewJSONSerializer(),
+		currentFiles:     make(map[string]*FileReception),
+		claimedNames:     make(map[string]string),
+		completedOutputs: make(map[string]completedOutput),
+		finishedFiles:    make(map[string]string),
+		outputDir:        outputDir,
+		uiMessages:       uiMessages,
 	}
 }
 
+// SetCaseInsensitive makes names that differ only in case collide, for output
+// directories on case-insensitive filesystems
+func (fr *FileReceiver) SetCaseInsensitive(caseInsensitive bool) {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	fr.caseInsensitive = caseInsensitive
+}
+
+// claimOutputName reserves name for fileID, renaming it if another file of the session
+// already writes to that name, or exists reports a file there, so files never silently
+// overwrite each other. exists may be nil; fr.mu must be held
+func (fr *FileReceiver) claimOutputName(fileID, name string, exists func(name string) bool) (string, error) {
+	unique, err := transfer.UniqueName(name, func(candidate string) bool {
+		if owner, ok := fr.claimedNames[transfer.NameKey(candidate, fr.caseInsensitive)]; ok {
+			return owner != fileID
+		}
+		return exists != nil && exists(candidate)
+	})
+	if err != nil {
+		return "", err
+	}
+	if unique != name {
+		slog.Warn("File name collides with another file of the session, renaming", "fileName", name, "savedAs", unique)
+		if fr.uiMessages != nil {
+			fr.uiMessages <- receiver.StatusUpdateMsg{Message: fmt.Sprintf("%s collides with another received file, saving it as %s", name, unique)}
+		}
+	}
+	fr.claimedNames[transfer.NameKey(unique, fr.caseInsensitive)] = fileID
+	return unique, nil
+}
+
+// releaseOutputName frees the name of a reception whose output file was removed; fr.mu must be held
+func (fr *FileReceiver) releaseOutputName(fileReception *FileReception) {
+	key := transfer.NameKey(filepath.Base(fileReception.OutputPath), fr.caseInsensitive)
+	if fr.claimedNames[key] == fileReception.FilePath {
+		delete(fr.claimedNames, key)
+	}
+}
+
+// SetOutputTemplate lays out the received files under the output directory with template,
+// expanded with values for every file; nil saves the files directly in the output directory
+func (fr *FileReceiver) SetOutputTemplate(template *OutputTemplate, values TemplateValues) {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	fr.outputTemplate = template
+	fr.templateValues = values
+}
+
+// SetContentStore adds every verified file to store, which replaces files it already holds
+// with hard links and lets FileLink messages name its objects
+func (fr *FileReceiver) SetContentStore(store *cas.Store) {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	fr.contentStore = store
+}
+
+// SetScanner makes every verified file pass scanner before it completes; files it flags, or
+// cannot scan, are quarantined and fail with transfer.ErrFileInfected or scan.ErrScanFailed
+func (fr *FileReceiver) SetScanner(scanner scan.Scanner) {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	fr.scanner = scanner
+}
+
+// ByteMeter limits the bytes a session writes, as *api.QuotaMeter does
+type ByteMeter interface {
+	// Check returns an error if n more bytes are over a limit, without counting them
+	Check(n int64) error
+	// Charge counts n more bytes written, or returns an error if they are over a limit
+	Charge(n int64) error
+}
+
+// SetQuota charges the bytes of the session's chunks to meter as they are written, failing
+// the files that would take it over a limit; nil allows everything
+func (fr *FileReceiver) SetQuota(meter ByteMeter) {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	fr.quota = meter
+}
+
+// SetStorage makes completed files land in backend once verified and scanned; files it
+// fails to take fail as well
+func (fr *FileReceiver) SetStorage(backend storage.WriteBackend) {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	fr.storage = backend
+}
+
+// SetRecentFiles adds the completed files of the session to store, as sent by sender, once the
+// session completes or RecordRecent is called
+func (fr *FileReceiver) SetRecentFiles(store *recent.Store, sender string) {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	fr.recent = store
+	fr.recentSender = sender
+}
+
+// RecordRecent adds the files completed since the last call to the recent files list
+func (fr *FileReceiver) RecordRecent() {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	fr.recordRecentLocked()
+}
+
+// recordRecentLocked is RecordRecent; fr.mu must be held
+func (fr *FileReceiver) recordRecentLocked() {
+	if len(fr.recentFiles) == 0 {
+		return
+	}
+	if err := fr.recent.Record(fr.recentFiles...); err != nil {
+		slog.Warn("Failed to record recently received files", "error", err)
+	}
+	fr.recentFiles = nil
+}
+
+// outputName returns the path of the file named name relative to the output directory
+func (fr *FileReceiver) outputName(name string) (string, error) {
+	if fr.outputTemplate == nil {
+		return name, nil
+	}
+	values := fr.templateValues
+	values.Original = name
+	return fr.outputTemplate.Expand(values)
+}
+
 // SetExpectedFiles sets the total number of files expected in this session
 func (fr *FileReceiver) SetExpectedFiles(count int) {
 	fr.mu.Lock()
@@ -76,6 +289,282 @@ func (fr *FileReceiver) SetExpectedFiles(count int) {
 	slog.Info("Set expected files for session", "count", count)
 }
 
+// SetExpectedBytes sets the total size of the files expected in this session
+func (fr *FileReceiver) SetExpectedBytes(total int64) {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	fr.expectedBytes = total
+}
+
+// SetEventBus publishes the progress of received files to bus
+func (fr *FileReceiver) SetEventBus(bus *events.Bus) {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	fr.bus = bus
+}
+
+// publishProgress publishes the state of fileReception and the session totals; fr.mu must be held.
+// Progress of an active file is throttled, state changes are always published.
+func (fr *FileReceiver) publishProgress(fileReception *FileReception, state transfer.TransferState, fileErr error) {
+	if fr.bus == nil {
+		return
+	}
+	now := time.Now()
+	if fr.startedAt.IsZero() {
+		fr.startedAt = now
+	}
+	if state == transfer.TransferStateActive && now.Sub(fr.lastPublish) < progressPublishInterval {
+		return
+	}
+	var diskRate, diskBusy float64
+	if elapsed := now.Sub(fr.lastPublish).Seconds(); !fr.lastPublish.IsZero() && elapsed > 0 {
+		fr.rate = float64(fr.receivedBytes-fr.lastPublishedBytes) / elapsed
+		if writing := (fr.diskTime - fr.lastDiskTime).Seconds(); writing > 0 {
+			diskRate = float64(fr.diskBytes-fr.lastDiskBytes) / writing
+			diskBusy = min(writing/elapsed, 1)
+		}
+	}
+	fr.lastPublish = now
+	fr.lastPublishedBytes = fr.receivedBytes
+	fr.lastDiskBytes = fr.diskBytes
+	fr.lastDiskTime = fr.diskTime
+
+	fr.bus.Publish(events.FileStatusChanged{
+		FilePath:     fileReception.FileName,
+		State:        state.String(),
+		BytesSent:    fileReception.ReceivedSize,
+		TotalBytes:   fileReception.TotalSize,
+		TransferRate: fr.rate,
+		Phases:       fileReception.Phases.Map(),
+		Err:          fileErr,
+		Time:         now,
+	})
+
+	progress := events.SessionProgress{
+		State:          transfer.StatusSessionStateActive.String(),
+		TotalFiles:     fr.expectedFiles,
+		CompletedFiles: fr.completedFiles,
+		FailedFiles:    fr.failedFiles,
+		TotalBytes:     fr.expectedBytes,
+		BytesCompleted: fr.receivedBytes,
+		CurrentFile:    fileReception.FileName,
+		TransferRate:   fr.rate,
+		DiskWriteRate:  diskRate,
+		DiskBusy:       diskBusy,
+		Phases:         fr.phases.Map(),
+		Time:           now,
+	}
+	elapsed := now.Sub(fr.startedAt)
+	if elapsed > 0 {
+		progress.FilesPerMinute = float64(fr.completedFiles) / elapsed.Minutes()
+	}
+	filesDone := fr.completedFiles + fr.failedFiles
+	progress.RemainingTime = transfer.EstimateRemaining(elapsed, fr.receivedBytes, fr.expectedBytes-fr.receivedBytes,
+		filesDone, fr.expectedFiles-filesDone, fr.rate)
+	if fr.expectedBytes > 0 {
+		progress.OverallProgress = min(100, float64(fr.receivedBytes)/float64(fr.expectedBytes)*100)
+	}
+	if fr.sessionComplete {
+		progress.State = transfer.StatusSessionStateCompleted.String()
+	}
+	fr.bus.Publish(progress)
+}
+
+// Outcome reports the files completed and failed, the bytes received and whether the session is complete
+func (fr *FileReceiver) Outcome() (completed, failed int, received int64, complete bool) {
+	fr.mu.RLock()
+	defer fr.mu.RUnlock()
+	return fr.completedFiles, fr.failedFiles, fr.receivedBytes, fr.sessionComplete
+}
+
+// SetStructureChain enables signed structure updates during the session
+func (fr *FileReceiver) SetStructureChain(chain *crypto.StructureChain) {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	fr.structureChain = chain
+}
+
+// SetAcknowledger makes the receiver report every finished file to the sender with send,
+// so the sender only marks a file completed once it is written and verified
+func (fr *FileReceiver) SetAcknowledger(send func(data []byte) error) {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	fr.acknowledge = send
+}
+
+// sendFileAck reports the outcome of fileID to the sender; fr.mu must be held
+func (fr *FileReceiver) sendFileAck(fileID, checksum string, fileErr error) {
+	ack := &transfer.ChunkMessage{
+		Type:         transfer.FileAck,
+		FileID:       fileID,
+		ExpectedHash: checksum,
+	}
+	if fileErr != nil {
+		ack.ErrorMessage = fileErr.Error()
+		ack.ErrorCode = transfer.ErrorCode(fileErr)
+	}
+	fr.sendAck(ack)
+}
+
+// sendAck sends ack to the sender, if it asked for ACKs; fr.mu must be held
+func (fr *FileReceiver) sendAck(ack *transfer.ChunkMessage) {
+	if fr.acknowledge == nil {
+		return
+	}
+	data, err := fr.serializer.Marshal(ack)
+	if err != nil {
+		slog.Error("Failed to marshal file ACK", "fileID", ack.FileID, "error", err)
+		return
+	}
+	if err := fr.acknowledge(data); err != nil {
+		slog.Warn("Failed to send file ACK", "fileID", ack.FileID, "error", err)
+	}
+}
+
+// EnableWriteAcks makes the receiver report the bytes written to disk to the sender as it writes
+func (fr *FileReceiver) EnableWriteAcks() {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	fr.writeAcks = true
+}
+
+// sendWriteAck reports the session bytes persisted so far, at most every writeAckInterval
+// unless force is set; fr.mu must be held
+func (fr *FileReceiver) sendWriteAck(force bool) {
+	if !fr.writeAcks || fr.acknowledge == nil {
+		return
+	}
+	now := time.Now()
+	if !force && now.Sub(fr.lastWriteAck) < writeAckInterval {
+		return
+	}
+	fr.lastWriteAck = now
+
+	ack := &transfer.ChunkMessage{
+		Type:      transfer.WriteAck,
+		Offset:    fr.receivedBytes,
+		TotalSize: fr.expectedBytes,
+	}
+	if fr.diskTime > 0 {
+		ack.DiskRate = float64(fr.diskBytes) / fr.diskTime.Seconds()
+	}
+	data, err := fr.serializer.Marshal(ack)
+	if err != nil {
+		slog.Error("Failed to marshal write ACK", "error", err)
+		return
+	}
+	if err := fr.acknowledge(data); err != nil {
+		slog.Debug("Failed to send write ACK", "error", err)
+	}
+}
+
+// EnableResume persists chunk progress in store under state.Token so an interrupted
+// session can be resumed. Files already recorded in state are reopened rather than truncated.
+func (fr *FileReceiver) EnableResume(store *transfer.ResumeStore, state *transfer.ResumeState) {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	fr.resumeStore = store
+	fr.resumeState = state
+
+	for fileID, file := range state.Files {
+		if file.Completed {
+			fr.completedFiles++
+		}
+		// Files received before the interruption keep their names, renamed or not
+		fr.claimedNames[transfer.NameKey(filepath.Base(file.OutputPath), fr.caseInsensitive)] = fileID
+	}
+	slog.Info("Resume enabled for session", "token", state.Token, "files", len(state.Files), "completed", fr.completedFiles)
+}
+
+// PersistResumeState saves the current chunk bitmap, if resume is enabled
+func (fr *FileReceiver) PersistResumeState() error {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	return fr.persistResumeStateLocked()
+}
+
+// persistResumeStateLocked saves the chunk bitmap; fr.mu must be held
+func (fr *FileReceiver) persistResumeStateLocked() error {
+	if fr.resumeStore == nil || fr.resumeState == nil || fr.sessionComplete {
+		return nil
+	}
+
+	for fileID, reception := range fr.currentFiles {
+		entry, ok := fr.resumeState.Files[fileID]
+		if !ok {
+			continue
+		}
+		reception.mu.RLock()
+		entry.ReceivedChunks = reception.ReceivedChunks.Sequences()
+		entry.ReceivedSize = reception.ReceivedSize
+		reception.mu.RUnlock()
+	}
+
+	fr.chunksSincePersist = 0
+	return fr.resumeStore.Save(fr.resumeState)
+}
+
+// openReception creates the output file, or reopens it if the resume state has partial data for it
+func (fr *FileReceiver) openReception(fileReception *FileReception) error {
+	// Streams cannot be replayed by the sender, so they are never resumed
+	if fr.resumeState != nil && fileReception.TotalSize != fileInfo.UnknownSize {
+		entry, ok := fr.resumeState.Files[fileReception.FilePath]
+		if ok && !entry.Completed && entry.OutputPath == fileReception.OutputPath &&
+			entry.TotalSize == fileReception.TotalSize && entry.ExpectedHash == fileReception.ExpectedHash {
+			file, err := os.OpenFile(fileReception.OutputPath, os.O_RDWR, 0644)
+			if err == nil {
+				fileReception.File = file
+				fileReception.ReceivedSize = entry.ReceivedSize
+				fileReception.ReceivedChunks = transfer.NewChunkSet(entry.ReceivedChunks)
+				slog.Info("Resuming partially received file", "fileName", fileReception.FileName,
+					"receivedSize", entry.ReceivedSize, "chunks", len(entry.ReceivedChunks))
+				return nil
+			}
+			slog.Warn("Could not reopen partial file, starting over", "path", fileReception.OutputPath, "error", err)
+		}
+
+		fr.resumeState.Files[fileReception.FilePath] = &transfer.ResumeFileState{
+			FileName:     fileReception.FileName,
+			OutputPath:   fileReception.OutputPath,
+			TotalSize:    fileReception.TotalSize,
+			ExpectedHash: fileReception.ExpectedHash,
+		}
+	}
+
+	// A file that cannot fit in the quota would fail partway, after taking the disk space
+	if fr.quota != nil && fileReception.TotalSize > 0 {
+		if err := fr.quota.Check(fileReception.TotalSize); err != nil {
+			return err
+		}
+	}
+	if fr.outputTemplate != nil {
+		if err := os.MkdirAll(filepath.Dir(fileReception.OutputPath), 0755); err != nil {
+			return err
+		}
+	}
+	if fr.contentStore != nil {
+		// A file received before is a hard link to a stored object, truncating it would change the object
+		if err := os.Remove(fileReception.OutputPath); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	file, err := os.Create(fileReception.OutputPath)
+	if err != nil {
+		return err
+	}
+	// Reserving the final size up front reduces fragmentation and reports a full disk now
+	// rather than partway through the file
+	if fileReception.TotalSize > 0 {
+		if err := preallocate(file, fileReception.TotalSize); err != nil {
+			file.Close()
+			os.Remove(fileReception.OutputPath)
+			return fmt.Errorf("failed to reserve %d bytes: %w", fileReception.TotalSize, err)
+		}
+	}
+	fileReception.File = file
+	return nil
+}
+
 // ProcessChunk processes a single chunk message
 func (fr *FileReceiver) ProcessChunk(data []byte) error {
 	// Deserialize the chunk message
@@ -83,133 +572,451 @@ func (fr *FileReceiver) ProcessChunk(data []byte) error {
 	if err != nil {
 		return fmt.Errorf("failed to unmarshal chunk message: %w", err)
 	}
+	return fr.ProcessMessage(chunkMsg)
+}
+
+// ProcessMessage processes a decoded chunk message, such as one of a browser upload that
+// never went over a data channel
+func (fr *FileReceiver) ProcessMessage(chunkMsg *transfer.ChunkMessage) error {
+	if chunkMsg.Type == transfer.Keepalive {
+		// Only shows the sender is there, which the app's keepalive monitor was told
+		return nil
+	}
+	if chunkMsg.Type == transfer.StructureUpdate {
+		return fr.applyStructureUpdate(chunkMsg)
+	}
+
+	fileReception, err := fr.receptionFor(chunkMsg)
+	if fileReception == nil {
+		return err
+	}
 
+	// Chunks are written without fr.mu, so a slow disk does not hold up the other channels
+	writeStart := time.Now()
+	written, err := fr.writeChunkAtOffset(fileReception, chunkMsg)
+	return fr.chunkWritten(fileReception, chunkMsg, written, time.Since(writeStart), err)
+}
+
+// receptionFor returns the reception chunkMsg is written to, opening it for the first chunk
+// of a file. It returns nil if the chunk was handled here, as links and late chunks are, or
+// the reception could not be opened.
+func (fr *FileReceiver) receptionFor(chunkMsg *transfer.ChunkMessage) (*FileReception, error) {
 	fr.mu.Lock()
 	defer fr.mu.Unlock()
 
+	if chunkMsg.Type == transfer.FileLink {
+		return nil, fr.processLink(chunkMsg)
+	}
+
+	if fr.lateChunk(chunkMsg) {
+		return nil, nil
+	}
+
 	// Get or create file reception
-	fileReception, exists := fr.currentFiles[chunkMsg.FileID]
-	if !exists {
-		// Create output file path
-		// Sanitize the filename to prevent path traversal
-		cleanFileName := filepath.Base(chunkMsg.FileName)
-		outputPath := filepath.Join(fr.outputDir, cleanFileName)
-
-		if !strings.HasPrefix(outputPath, filepath.Clean(fr.outputDir)) {
-			return fmt.Errorf("invalid output path: %s", outputPath)
-		}
-
-		// Create new file reception
-		fileReception = &FileReception{
-			FilePath:       chunkMsg.FileID,
-			FileName:       chunkMsg.FileName,
-			TotalSize:      chunkMsg.TotalSize,
-			ExpectedHash:   chunkMsg.ExpectedHash,
-			ReceivedChunks: make(map[uint32]bool),
-			Status:         StatusReceiving,
-			OutputPath:     outputPath,
-		}
-
-		// Create output file
-		file, err := os.Create(outputPath)
-		if err != nil {
-			fileReception.Status = StatusFailed
-			return fmt.Errorf("failed to create output file %s: %w", outputPath, err)
-		}
-		fileReception.File = file
-		fr.currentFiles[chunkMsg.FileID] = fileReception
+	if fileReception, exists := fr.currentFiles[chunkMsg.FileID]; exists {
+		return fileReception, nil
+	}
+	if err := fr.checkAcceptedFile(chunkMsg.FileName, chunkMsg.TotalSize); err != nil {
+		fr.sendFileAck(chunkMsg.FileID, "", err)
+		fr.failedFiles++
+		return nil, err
+	}
+	outputPath, err := fr.claimOutputPath(chunkMsg.FileID, chunkMsg.FileName, nil)
+	if err != nil {
+		return nil, err
+	}
 
-		slog.Info("Started receiving file", "fileName", chunkMsg.FileName, "totalSize", chunkMsg.TotalSize)
-		if fr.uiMessages != nil {
-			fr.uiMessages <- receiver.StatusUpdateMsg{Message: fmt.Sprintf("Receiving file: %s", chunkMsg.FileName)}
-		}
+	// Create new file reception
+	fileReception := &FileReception{
+		FilePath:     chunkMsg.FileID,
+		FileName:     chunkMsg.FileName,
+		TotalSize:    chunkMsg.TotalSize,
+		ExpectedHash: chunkMsg.ExpectedHash,
+		Status:       StatusReceiving,
+		OutputPath:   outputPath,
+	}
+
+	// Create output file
+	if err := fr.openReception(fileReception); err != nil {
+		fr.releaseOutputName(fileReception)
+		fileReception.Status = StatusFailed
+		err = transfer.ClassifyIOError(err)
+		fr.sendFileAck(chunkMsg.FileID, "", err)
+		fr.failedFiles++
+		fr.publishProgress(fileReception, transfer.TransferStateFailed, err)
+		return nil, fmt.Errorf("failed to create output file %s: %w", outputPath, err)
 	}
+	fr.currentFiles[chunkMsg.FileID] = fileReception
+	// A resumed file starts with the bytes received before the interruption
+	fr.receivedBytes += fileReception.ReceivedSize
+
+	slog.Info("Started receiving file", "fileName", chunkMsg.FileName, "totalSize", chunkMsg.TotalSize)
+	if fr.uiMessages != nil {
+		fr.uiMessages <- receiver.StatusUpdateMsg{Message: fmt.Sprintf("Receiving file: %s", chunkMsg.FileName)}
+	}
+	return fileReception, nil
+}
 
-	// Use offset to write chunk directly, supporting out-of-order writes
-	if err := fr.writeChunkAtOffset(fileReception, chunkMsg); err != nil {
+// chunkWritten updates the session with the chunk of fileReception that writeChunkAtOffset
+// wrote, or failed to write with err, completing the file once all of it is written
+func (fr *FileReceiver) chunkWritten(fileReception *FileReception, chunkMsg *transfer.ChunkMessage, written int64, writeTime time.Duration, err error) error {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+
+	fr.diskTime += writeTime
+	fileReception.Phases.Add(transfer.PhaseWriting, writeTime)
+	if err != nil {
+		fr.sendFileAck(chunkMsg.FileID, "", err)
+		fr.failedFiles++
+		fr.publishProgress(fileReception, transfer.TransferStateFailed, err)
 		return fmt.Errorf("failed to write chunk at offset: %w", err)
 	}
+	fr.diskBytes += written
+
+	// Another channel may have finished the file while this chunk was written
+	if fr.currentFiles[chunkMsg.FileID] != fileReception {
+		if fileReception.Status != StatusFailed {
+			fr.receivedBytes += written
+		}
+		return nil
+	}
+	fr.receivedBytes += written
+
+	fileReception.mu.RLock()
+	complete := fileReception.TotalSize != fileInfo.UnknownSize && fileReception.ReceivedSize >= fileReception.TotalSize
+	fileReception.mu.RUnlock()
+	fr.sendWriteAck(complete)
+
+	if fr.resumeStore != nil {
+		fr.chunksSincePersist++
+		if fr.chunksSincePersist >= resumePersistInterval {
+			if err := fr.persistResumeStateLocked(); err != nil {
+				slog.Warn("Failed to persist resume state", "error", err)
+			}
+		}
+	}
 
-	// Check if file is complete
-	if fileReception.ReceivedSize >= fileReception.TotalSize {
-		if err := fr.completeFile(fileReception); err != nil {
-			return fmt.Errorf("failed to complete file: %w", err)
+	if !complete {
+		fr.publishProgress(fileReception, transfer.TransferStateActive, nil)
+		return nil
+	}
+	if err := fr.completeFile(fileReception); err != nil {
+		if fr.resumeState != nil {
+			// The corrupted file has been removed, so it cannot be resumed
+			delete(fr.resumeState.Files, chunkMsg.FileID)
 		}
 		delete(fr.currentFiles, chunkMsg.FileID)
+		fr.releaseOutputName(fileReception)
+		fr.sendFileAck(chunkMsg.FileID, "", err)
+		fr.failedFiles++
+		// The removed file's bytes no longer count as received
+		fr.receivedBytes -= fileReception.ReceivedSize
+		fr.publishProgress(fileReception, transfer.TransferStateFailed, err)
+		return fmt.Errorf("failed to complete file: %w", err)
+	}
+	delete(fr.currentFiles, chunkMsg.FileID)
+	fr.recordCompletedFile(fileReception)
+	return nil
+}
+
+// claimOutputPath chooses and reserves the output path of the file fileID named name, see
+// claimOutputName; fr.mu must be held
+func (fr *FileReceiver) claimOutputPath(fileID, name string, exists func(name string) bool) (string, error) {
+	// Sanitize the filename to prevent path traversal
+	cleanFileName, err := fr.outputName(filepath.Base(name))
+	if err != nil {
+		return "", fmt.Errorf("failed to choose output name: %w", err)
+	}
+	cleanFileName, err = fr.claimOutputName(fileID, cleanFileName, exists)
+	if err != nil {
+		return "", fmt.Errorf("failed to choose output name: %w", err)
+	}
+	outputPath := filepath.Join(fr.outputDir, cleanFileName)
 
-		// Increment completed files counter
-		fr.completedFiles++
-		slog.Info("File reception completed", "fileName", fileReception.FileName,
-			"completed", fr.completedFiles, "expected", fr.expectedFiles)
+	if !strings.HasPrefix(outputPath, filepath.Clean(fr.outputDir)) {
+		return "", fmt.Errorf("invalid output path: %s", outputPath)
+	}
+	return outputPath, nil
+}
 
-		// Check if all files are completed
-		if fr.expectedFiles > 0 && fr.completedFiles >= fr.expectedFiles && !fr.sessionComplete {
-			fr.sessionComplete = true
-			slog.Info("All files received successfully", "totalFiles", fr.completedFiles)
-			if fr.uiMessages != nil {
-				fr.uiMessages <- receiver.TransferFinishedMsg{}
+// lateChunk reports whether chunkMsg belongs to a file already verified in this session, as
+// duplicated, reordered or resent chunks do. Opening a new reception for it would truncate the
+// verified file, so it is dropped; the last chunk is acknowledged again, as the sender may be
+// resending the file because its ACK was lost. fr.mu must be held.
+func (fr *FileReceiver) lateChunk(chunkMsg *transfer.ChunkMessage) bool {
+	checksum, ok := fr.finishedFiles[chunkMsg.FileID]
+	// A file sent again with other content is received again
+	if !ok || (chunkMsg.ExpectedHash != checksum && chunkMsg.TotalSize != fileInfo.UnknownSize) {
+		return false
+	}
+	slog.Debug("Dropping late chunk of a received file", "fileName", chunkMsg.FileName, "sequence", chunkMsg.SequenceNo)
+	if chunkMsg.IsLast {
+		fr.sendFileAck(chunkMsg.FileID, checksum, nil)
+	}
+	return true
+}
+
+// recordCompletedFile acknowledges a written and verified file and updates the session,
+// finishing it when this was the last expected file; fr.mu must be held
+func (fr *FileReceiver) recordCompletedFile(fileReception *FileReception) {
+	if fr.contentStore != nil && fileReception.ExpectedHash != "" && fileReception.Location == "" {
+		// The file is verified and saved either way, the store only deduplicates it
+		if err := fr.contentStore.Add(fileReception.OutputPath, fileReception.ExpectedHash); err != nil {
+			slog.Warn("Failed to add file to the content store", "fileName", fileReception.FileName, "error", err)
+		}
+	}
+	// The sender learns where the file's time went on the receiver
+	fr.sendAck(&transfer.ChunkMessage{
+		Type:         transfer.FileAck,
+		FileID:       fileReception.FilePath,
+		ExpectedHash: fileReception.ExpectedHash,
+		WriteTime:    fileReception.Phases.Writing,
+	})
+
+	// Increment completed files counter
+	fr.completedFiles++
+	fr.phases.Merge(fileReception.Phases)
+	fr.finishedFiles[fileReception.FilePath] = fileReception.ExpectedHash
+	if fileReception.Location != "" {
+		// The file left the output directory, so later files cannot link to it
+		fr.lastOutputPath = fileReception.Location
+	} else {
+		fr.lastOutputPath = fileReception.OutputPath
+		fr.completedOutputs[fileReception.FilePath] = completedOutput{path: fileReception.OutputPath, checksum: fileReception.ExpectedHash}
+	}
+	if fr.recent != nil {
+		path, err := filepath.Abs(fileReception.OutputPath)
+		if err != nil {
+			path = fileReception.OutputPath
+		}
+		if fileReception.Location != "" {
+			path = fileReception.Location
+		}
+		fr.recentFiles = append(fr.recentFiles, recent.Entry{Path: path, Size: fileReception.TotalSize, Sender: fr.recentSender, ReceivedAt: time.Now()})
+	}
+
+	if fr.resumeState != nil {
+		if entry, ok := fr.resumeState.Files[fileReception.FilePath]; ok {
+			entry.Completed = true
+			entry.ReceivedChunks = nil
+			entry.ReceivedSize = fileReception.TotalSize
+		}
+		if err := fr.persistResumeStateLocked(); err != nil {
+			slog.Warn("Failed to persist resume state", "error", err)
+		}
+	}
+	slog.Info("File reception completed", "fileName", fileReception.FileName,
+		"completed", fr.completedFiles, "expected", fr.expectedFiles)
+
+	// Check if all files are completed
+	if fr.expectedFiles > 0 && fr.completedFiles >= fr.expectedFiles && !fr.sessionComplete {
+		fr.sessionComplete = true
+		fr.publishProgress(fileReception, transfer.TransferStateCompleted, nil)
+		slog.Info("All files received successfully", "totalFiles", fr.completedFiles)
+		fr.recordRecentLocked()
+		if fr.resumeStore != nil && fr.resumeState != nil {
+			if err := fr.resumeStore.Delete(fr.resumeState.Token); err != nil {
+				slog.Warn("Failed to delete resume state", "error", err)
 			}
 		}
+		if fr.uiMessages != nil {
+			fr.uiMessages <- receiver.TransferFinishedMsg{OutputPath: fr.sessionOutputPath()}
+		}
+	} else {
+		fr.publishProgress(fileReception, transfer.TransferStateCompleted, nil)
+	}
+}
+
+// applyStructureUpdate verifies a signed structure delta against the chain and
+// updates the files expected in this session
+func (fr *FileReceiver) applyStructureUpdate(msg *transfer.ChunkMessage) error {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+
+	if fr.structureChain == nil {
+		return fmt.Errorf("structure update received without a verified structure")
 	}
 
+	var delta crypto.SignedStructureDelta
+	if err := json.Unmarshal(msg.Data, &delta); err != nil {
+		return fmt.Errorf("failed to unmarshal structure update: %w", err)
+	}
+	if err := fr.structureChain.Apply(&delta); err != nil {
+		return fmt.Errorf("rejected structure update: %w", err)
+	}
+	fr.addDeltaFiles(&delta)
+
+	structure := fr.structureChain.Structure()
+	fr.expectedFiles = len(structure.Files)
+	fr.sessionComplete = fr.completedFiles >= fr.expectedFiles
+	slog.Info("Applied structure update", "sequence", delta.Sequence, "changes", len(delta.Changes), "expectedFiles", fr.expectedFiles)
+
+	if fr.uiMessages != nil {
+		fr.uiMessages <- receiver.FileNodeUpdateMsg{Nodes: structure.Files}
+		fr.uiMessages <- receiver.StatusUpdateMsg{Message: fmt.Sprintf("Sender updated the shared files (%d changes)", len(delta.Changes))}
+	}
 	return nil
 }
 
-// writeChunkAtOffset writes chunk directly to file at specified offset (supports out-of-order writes)
-func (fr *FileReceiver) writeChunkAtOffset(fileReception *FileReception, chunkMsg *transfer.ChunkMessage) error {
-	// Lock to protect concurrent writes
-	fileReception.mu.Lock()
-	defer fileReception.mu.Unlock()
+// sessionOutputPath returns the received file for single-file sessions, otherwise the output directory
+func (fr *FileReceiver) sessionOutputPath() string {
+	if fr.completedFiles == 1 && fr.lastOutputPath != "" {
+		return fr.lastOutputPath
+	}
+	return fr.outputDir
+}
 
-	// Check if this chunk has already been received
-	if fileReception.ReceivedChunks[chunkMsg.SequenceNo] {
-		slog.Debug("Chunk already received, skipping", "fileID", chunkMsg.FileID, "sequence", chunkMsg.SequenceNo)
-		return nil // Duplicate chunk, skip directly
+// writeChunkAtOffset writes chunk directly to file at specified offset (supports out-of-order
+// writes) and returns the bytes it added to the file. fr.mu must not be held; the reception is
+// only locked to claim the chunk and to mark it written, so chunks of parallel channels are
+// written side by side.
+func (fr *FileReceiver) writeChunkAtOffset(fileReception *FileReception, chunkMsg *transfer.ChunkMessage) (int64, error) {
+	fr.mu.RLock()
+	quota, verify := fr.quota, fr.shouldVerifyWrite()
+	fr.mu.RUnlock()
+
+	claimed, err := fileReception.claimChunk(chunkMsg)
+	if !claimed {
+		return 0, err
 	}
+	defer fileReception.releaseChunk(chunkMsg.SequenceNo)
 
-	// Use offset to seek to the correct position in the file
-	if _, err := fileReception.File.Seek(chunkMsg.Offset, io.SeekStart); err != nil {
-		return fmt.Errorf("failed to seek to offset %d: %w", chunkMsg.Offset, err)
+	if quota != nil {
+		if err := quota.Charge(int64(len(chunkMsg.Data))); err != nil {
+			return 0, err
+		}
 	}
 
-	// Write chunk data
-	bytesWritten, err := fileReception.File.Write(chunkMsg.Data)
+	// Write the chunk at its offset with a positional write, so chunks of parallel channels
+	// and retransmits can arrive in any order. The file is synced once, when it is complete.
+	bytesWritten, err := fileReception.File.WriteAt(chunkMsg.Data, chunkMsg.Offset)
 	if err != nil {
-		return fmt.Errorf("failed to write chunk %d at offset %d: %w", chunkMsg.SequenceNo, chunkMsg.Offset, err)
+		return 0, fmt.Errorf("failed to write chunk %d at offset %d: %w", chunkMsg.SequenceNo, chunkMsg.Offset, transfer.ClassifyIOError(err))
 	}
 
 	// Verify the number of bytes written
 	if bytesWritten != len(chunkMsg.Data) {
-		return fmt.Errorf("incomplete write: expected %d bytes, wrote %d bytes", len(chunkMsg.Data), bytesWritten)
+		return 0, fmt.Errorf("incomplete write: expected %d bytes, wrote %d bytes", len(chunkMsg.Data), bytesWritten)
 	}
 
-	// Force flush to disk (optional, improves data safety)
-	if err := fileReception.File.Sync(); err != nil {
-		slog.Warn("Failed to sync file to disk", "error", err)
+	var mismatch error
+	if verify {
+		if mismatch = verifyWrite(fileReception.File, chunkMsg.Offset, chunkMsg.Data); mismatch != nil {
+			slog.Error("Write verification failed", "fileName", fileReception.FileName, "error", mismatch)
+			if fr.uiMessages != nil {
+				fr.uiMessages <- receiver.StatusUpdateMsg{Message: fmt.Sprintf("Disk write verification failed: %s - %v", fileReception.FileName, mismatch)}
+			}
+		}
 	}
 
 	// Mark chunk as received
-	fileReception.ReceivedChunks[chunkMsg.SequenceNo] = true
+	fileReception.mu.Lock()
+	defer fileReception.mu.Unlock()
+	if mismatch != nil {
+		// The file is failed once complete, so the session is not reported as complete
+		fileReception.WriteMismatches++
+	}
+	fileReception.ReceivedChunks.Add(chunkMsg.SequenceNo)
 	fileReception.ReceivedSize += int64(len(chunkMsg.Data))
+	// Streams announce their final size and hash on the last chunk
+	if fileReception.TotalSize == fileInfo.UnknownSize && chunkMsg.IsLast {
+		fileReception.TotalSize = chunkMsg.TotalSize
+		fileReception.ExpectedHash = chunkMsg.ExpectedHash
+	}
 
 	slog.Debug("Chunk written successfully",
 		"fileID", chunkMsg.FileID,
 		"sequence", chunkMsg.SequenceNo,
 		"offset", chunkMsg.Offset,
 		"size", len(chunkMsg.Data),
-		"progress", fmt.Sprintf("%.1f%%", float64(fileReception.ReceivedSize)/float64(fileReception.TotalSize)*100))
+		"received", fileReception.ReceivedSize,
+		"total", fileReception.TotalSize)
+
+	return int64(len(chunkMsg.Data)), nil
+}
 
+// claimChunk reserves chunkMsg for writing. It reports false for a chunk written before or
+// being written from another channel, and for one that does not fit in the file, with the error.
+func (r *FileReception) claimChunk(chunkMsg *transfer.ChunkMessage) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Check if this chunk has already been received
+	if r.ReceivedChunks.Has(chunkMsg.SequenceNo) || r.writing[chunkMsg.SequenceNo] {
+		slog.Debug("Chunk already received, skipping", "fileID", chunkMsg.FileID, "sequence", chunkMsg.SequenceNo)
+		return false, nil // Duplicate chunk, skip directly
+	}
+	if err := checkChunkBounds(r, chunkMsg); err != nil {
+		return false, err
+	}
+	if r.writing == nil {
+		r.writing = make(map[uint32]bool)
+	}
+	r.writing[chunkMsg.SequenceNo] = true
+	return true, nil
+}
+
+// releaseChunk ends the write of chunk sequence, written or not
+func (r *FileReception) releaseChunk(sequence uint32) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.writing, sequence)
+}
+
+// checkChunkBounds returns an error if chunkMsg does not fit in the file of fileReception,
+// so a sender cannot write more than the size of the file the user accepted. Streams have
+// no size until their last chunk; the quota limits them instead.
+func checkChunkBounds(fileReception *FileReception, chunkMsg *transfer.ChunkMessage) error {
+	end := chunkMsg.Offset + int64(len(chunkMsg.Data))
+	switch {
+	case chunkMsg.Offset < 0:
+		return fmt.Errorf("%w: chunk %d of %s starts at offset %d",
+			errChunkOutOfBounds, chunkMsg.SequenceNo, fileReception.FileName, chunkMsg.Offset)
+	case fileReception.TotalSize == fileInfo.UnknownSize:
+		return nil
+	case chunkMsg.TotalSize != fileReception.TotalSize:
+		return fmt.Errorf("%w: chunk %d of %s claims a size of %d bytes instead of %d",
+			errChunkOutOfBounds, chunkMsg.SequenceNo, fileReception.FileName, chunkMsg.TotalSize, fileReception.TotalSize)
+	case end > fileReception.TotalSize:
+		return fmt.Errorf("%w: chunk %d of %s ends at %d, past its %d bytes",
+			errChunkOutOfBounds, chunkMsg.SequenceNo, fileReception.FileName, end, fileReception.TotalSize)
+	}
 	return nil
 }
 
 // completeFile finalizes the file reception with integrity verification
 func (fr *FileReceiver) completeFile(fileReception *FileReception) error {
+	// Chunks are not synced as they are written; the whole file is, before it is verified
+	syncStart := time.Now()
+	err := fileReception.File.Sync()
+	syncTime := time.Since(syncStart)
+	fr.diskTime += syncTime
+	fileReception.Phases.Add(transfer.PhaseWriting, syncTime)
+	if err != nil {
+		fileReception.File.Close()
+		fileReception.Status = StatusFailed
+		if cleanupErr := fr.cleanupCorruptedFile(fileReception); cleanupErr != nil {
+			slog.Error("Failed to cleanup unsynced file", "fileName", fileReception.FileName, "error", cleanupErr)
+		}
+		return fmt.Errorf("failed to sync file: %w", transfer.ClassifyIOError(err))
+	}
+
 	// Close the file first
 	if err := fileReception.File.Close(); err != nil {
 		fileReception.Status = StatusFailed
 		return fmt.Errorf("failed to close file: %w", err)
 	}
 
+	if fileReception.WriteMismatches > 0 {
+		fileReception.Status = StatusFailed
+		err := fmt.Errorf("%w: %d chunks read back differently from disk", transfer.ErrChecksumMismatch, fileReception.WriteMismatches)
+		fileReception.VerificationErr = err
+		if cleanupErr := fr.cleanupCorruptedFile(fileReception); cleanupErr != nil {
+			slog.Error("Failed to cleanup corrupted file", "fileName", fileReception.FileName, "error", cleanupErr)
+		}
+		return fmt.Errorf("write verification failed for %s: %w", fileReception.FileName, err)
+	}
+
 	// Perform integrity verification if expected hash is provided
 	if fileReception.ExpectedHash != "" {
 		fileReception.Status = StatusVerifying
@@ -219,7 +1026,10 @@ func (fr *FileReceiver) completeFile(fileReception *FileReception) error {
 			fr.uiMessages <- receiver.StatusUpdateMsg{Message: fmt.Sprintf("Verifying integrity of file: %s", fileReception.FileName)}
 		}
 
-		if err := fr.verifyFileIntegrity(fileReception); err != nil {
+		verifyStart := time.Now()
+		err := fr.verifyFileIntegrity(fileReception)
+		fileReception.Phases.Add(transfer.PhaseVerifying, time.Since(verifyStart))
+		if err != nil {
 			fileReception.Status = StatusFailed
 			fileReception.VerificationErr = err
 
@@ -242,6 +1052,37 @@ func (fr *FileReceiver) completeFile(fileReception *FileReception) error {
 		}
 	}
 
+	if err := fr.verifySignedFile(fileReception); err != nil {
+		fileReception.Status = StatusFailed
+		fileReception.VerificationErr = err
+		if cleanupErr := fr.cleanupCorruptedFile(fileReception); cleanupErr != nil {
+			slog.Error("Failed to cleanup unsigned file", "fileName", fileReception.FileName, "error", cleanupErr)
+		}
+		slog.Error("File is not one of the signed files", "fileName", fileReception.FileName, "error", err)
+		return fmt.Errorf("signed file verification failed for %s: %w", fileReception.FileName, err)
+	}
+
+	if fr.scanner != nil {
+		scanStart := time.Now()
+		err := fr.scanFile(fileReception)
+		fileReception.Phases.Add(transfer.PhaseVerifying, time.Since(scanStart))
+		if err != nil {
+			fileReception.Status = StatusFailed
+			fileReception.VerificationErr = err
+			return err
+		}
+	}
+
+	if fr.storage != nil {
+		storeStart := time.Now()
+		err := fr.storeFile(fileReception)
+		fileReception.Phases.Add(transfer.PhaseWriting, time.Since(storeStart))
+		if err != nil {
+			fileReception.Status = StatusFailed
+			return err
+		}
+	}
+
 	// Mark as completed
 	fileReception.Status = StatusCompleted
 	fileReception.IsComplete = true
@@ -263,11 +1104,11 @@ func (fr *FileReceiver) verifyFileIntegrity(fileReception *FileReception) error
 	// Verify the file hash using the existing VerifySHA256 method
 	isValid, err := fileNode.VerifySHA256(fileReception.ExpectedHash)
 	if err != nil {
-		return fmt.Errorf("failed to calculate file hash: %w", err)
+		return fmt.Errorf("failed to calculate file hash: %w", transfer.ClassifyIOError(err))
 	}
 
 	if !isValid {
-		return fmt.Errorf("file hash mismatch - file may be corrupted during transmission")
+		return fmt.Errorf("%w: file hash mismatch - file may be corrupted during transmission", transfer.ErrChecksumMismatch)
 	}
 
 	return nil
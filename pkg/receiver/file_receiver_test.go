@@ -1,15 +1,26 @@
 package receiver
 
 import (
+	"bytes"
+	"context"
 	"crypto/sha256"
 	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/rescp17/lanFileSharer/internal/app_events/receiver"
+	"github.com/rescp17/lanFileSharer/pkg/crypto"
+	"github.com/rescp17/lanFileSharer/pkg/events"
+	"github.com/rescp17/lanFileSharer/pkg/fileInfo"
+	"github.com/rescp17/lanFileSharer/pkg/recent"
+	"github.com/rescp17/lanFileSharer/pkg/scan"
 	"github.com/rescp17/lanFileSharer/pkg/transfer"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -199,6 +210,7 @@ func TestFileReceiver_VerifyFileIntegrity(t *testing.T) {
 		err = fileReceiver.verifyFileIntegrity(fileReception)
 		require.Error(t, err, "Verification should fail with incorrect hash")
 		assert.Contains(t, err.Error(), "file hash mismatch", "Error should indicate hash mismatch")
+		assert.ErrorIs(t, err, transfer.ErrChecksumMismatch)
 	})
 
 	t.Run("nonexistent_file_verification", func(t *testing.T) {
@@ -274,8 +286,654 @@ func TestFileReceiver_CleanupCorruptedFile(t *testing.T) {
 	})
 }
 
+// TestFileReceiver_StreamOfUnknownLength tests reception of a stream whose size arrives with the last chunk
+func TestFileReceiver_StreamOfUnknownLength(t *testing.T) {
+	tempDir := t.TempDir()
+	fileReceiver := NewFileReceiver(tempDir, nil)
+	serializer := transfer.NewJSONSerializer()
+
+	parts := [][]byte{[]byte("first part, "), []byte("second part")}
+	full := append(append([]byte{}, parts[0]...), parts[1]...)
+
+	var offset int64
+	for i, part := range parts {
+		isLast := i == len(parts)-1
+		chunkMsg := &transfer.ChunkMessage{
+			Type:       transfer.ChunkData,
+			FileID:     fileInfo.StdinPath,
+			FileName:   "backup.sql",
+			SequenceNo: uint32(i + 1),
+			Offset:     offset,
+			Data:       part,
+			TotalSize:  fileInfo.UnknownSize,
+			IsLast:     isLast,
+		}
+		if isLast {
+			chunkMsg.TotalSize = int64(len(full))
+			chunkMsg.ExpectedHash = calculateTestHash(full)
+		}
+		offset += int64(len(part))
+
+		data, err := serializer.Marshal(chunkMsg)
+		require.NoError(t, err)
+		require.NoError(t, fileReceiver.ProcessChunk(data))
+
+		if !isLast {
+			_, stillReceiving := fileReceiver.currentFiles[fileInfo.StdinPath]
+			assert.True(t, stillReceiving, "stream must not complete before its last chunk")
+		}
+	}
+
+	_, stillReceiving := fileReceiver.currentFiles[fileInfo.StdinPath]
+	assert.False(t, stillReceiving)
+
+	content, err := os.ReadFile(filepath.Join(tempDir, "backup.sql"))
+	require.NoError(t, err)
+	assert.Equal(t, full, content)
+}
+
+func TestFileReceiver_SendsFileAcks(t *testing.T) {
+	tempDir := t.TempDir()
+	fileReceiver := NewFileReceiver(tempDir, nil)
+	serializer := transfer.NewJSONSerializer()
+
+	var acks []*transfer.ChunkMessage
+	fileReceiver.SetAcknowledger(func(data []byte) error {
+		ack, err := serializer.Unmarshal(data)
+		require.NoError(t, err)
+		acks = append(acks, ack)
+		return nil
+	})
+
+	send := func(fileID, name string, content []byte, expectedHash string) error {
+		data, err := serializer.Marshal(&transfer.ChunkMessage{
+			Type:         transfer.ChunkData,
+			FileID:       fileID,
+			FileName:     name,
+			SequenceNo:   1,
+			Data:         content,
+			TotalSize:    int64(len(content)),
+			ExpectedHash: expectedHash,
+			IsLast:       true,
+		})
+		require.NoError(t, err)
+		return fileReceiver.ProcessChunk(data)
+	}
+
+	content := []byte("acknowledged content")
+	require.NoError(t, send("/src/good.txt", "good.txt", content, calculateTestHash(content)))
+	require.Len(t, acks, 1)
+	assert.Equal(t, transfer.FileAck, acks[0].Type)
+	assert.Equal(t, "/src/good.txt", acks[0].FileID)
+	assert.Equal(t, calculateTestHash(content), acks[0].ExpectedHash)
+	assert.Empty(t, acks[0].ErrorMessage)
+	assert.Positive(t, acks[0].WriteTime, "the sender learns how long writing took")
+
+	// A file that fails verification is reported instead of silently dropped
+	assert.Error(t, send("/src/bad.txt", "bad.txt", content, calculateTestHash([]byte("other"))))
+	require.Len(t, acks, 2)
+	assert.Equal(t, "/src/bad.txt", acks[1].FileID)
+	assert.NotEmpty(t, acks[1].ErrorMessage)
+	assert.Equal(t, "checksum_mismatch", acks[1].ErrorCode)
+
+	// The sender may retry a rejected file
+	require.NoError(t, send("/src/bad.txt", "bad.txt", content, calculateTestHash(content)))
+	require.Len(t, acks, 3)
+	assert.Empty(t, acks[2].ErrorMessage)
+}
+
+func TestFileReceiver_DropsLateChunksOfReceivedFiles(t *testing.T) {
+	tempDir := t.TempDir()
+	fileReceiver := NewFileReceiver(tempDir, nil)
+	serializer := transfer.NewJSONSerializer()
+
+	acks := 0
+	fileReceiver.SetAcknowledger(func(data []byte) error {
+		acks++
+		return nil
+	})
+
+	content := []byte("first halfsecond half")
+	send := func(sequence uint32, offset int64, data []byte, isLast bool) {
+		msg, err := serializer.Marshal(&transfer.ChunkMessage{
+			Type:         transfer.ChunkData,
+			FileID:       "/src/late.txt",
+			FileName:     "late.txt",
+			SequenceNo:   sequence,
+			Offset:       offset,
+			Data:         data,
+			TotalSize:    int64(len(content)),
+			ExpectedHash: calculateTestHash(content),
+			IsLast:       isLast,
+		})
+		require.NoError(t, err)
+		require.NoError(t, fileReceiver.ProcessChunk(msg))
+	}
+
+	send(0, 0, content[:10], false)
+	send(1, 10, content[10:], true)
+	require.Equal(t, 1, acks)
+
+	// A duplicated first chunk arriving late must not start the file over
+	send(0, 0, content[:10], false)
+	saved, err := os.ReadFile(filepath.Join(tempDir, "late.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, content, saved)
+	assert.Empty(t, fileReceiver.currentFiles)
+
+	// A file resent because its ACK was lost is acknowledged again
+	send(1, 10, content[10:], true)
+	assert.Equal(t, 2, acks)
+	completed, _, _, _ := fileReceiver.Outcome()
+	assert.Equal(t, 1, completed)
+}
+
+// scannerFunc is a scan.Scanner calling itself
+type scannerFunc func(ctx context.Context, path string) (scan.Result, error)
+
+func (f scannerFunc) Scan(ctx context.Context, path string) (scan.Result, error) {
+	return f(ctx, path)
+}
+
+func TestFileReceiver_QuarantinesInfectedFiles(t *testing.T) {
+	tempDir := t.TempDir()
+	fileReceiver := NewFileReceiver(tempDir, nil)
+	serializer := transfer.NewJSONSerializer()
+	fileReceiver.SetScanner(scannerFunc(func(ctx context.Context, path string) (scan.Result, error) {
+		content, err := os.ReadFile(path)
+		require.NoError(t, err)
+		if bytes.Contains(content, []byte("EICAR")) {
+			return scan.Result{Infected: true, Signature: "Eicar-Test-Signature"}, nil
+		}
+		return scan.Result{}, nil
+	}))
+
+	var acks []*transfer.ChunkMessage
+	fileReceiver.SetAcknowledger(func(data []byte) error {
+		ack, err := serializer.Unmarshal(data)
+		require.NoError(t, err)
+		acks = append(acks, ack)
+		return nil
+	})
+	send := func(fileID, name string, content []byte) error {
+		data, err := serializer.Marshal(&transfer.ChunkMessage{
+			Type:         transfer.ChunkData,
+			FileID:       fileID,
+			FileName:     name,
+			SequenceNo:   1,
+			Data:         content,
+			TotalSize:    int64(len(content)),
+			ExpectedHash: calculateTestHash(content),
+			IsLast:       true,
+		})
+		require.NoError(t, err)
+		return fileReceiver.ProcessChunk(data)
+	}
+
+	require.NoError(t, send("/src/clean.txt", "clean.txt", []byte("clean content")))
+	assert.FileExists(t, filepath.Join(tempDir, "clean.txt"))
+
+	err := send("/src/infected.exe", "infected.exe", []byte("X5O!P%@AP EICAR"))
+	assert.ErrorIs(t, err, transfer.ErrFileInfected)
+	assert.NoFileExists(t, filepath.Join(tempDir, "infected.exe"))
+	assert.FileExists(t, filepath.Join(tempDir, scan.QuarantineDir, "infected.exe"))
+	require.Len(t, acks, 2)
+	assert.Equal(t, "infected", acks[1].ErrorCode, "the sender learns why the file failed")
+	assert.Contains(t, acks[1].ErrorMessage, "Eicar-Test-Signature")
+
+	completed, failed, _, _ := fileReceiver.Outcome()
+	assert.Equal(t, 1, completed)
+	assert.Equal(t, 1, failed)
+}
+
+// fakeStorage is a storage.WriteBackend taking files out of the output directory
+type fakeStorage struct {
+	keys    []string
+	content map[string][]byte
+	err     error
+}
+
+func (f *fakeStorage) Validate(ctx context.Context) error { return f.err }
+
+func (f *fakeStorage) Store(ctx context.Context, localPath, key, checksum string) (string, error) {
+	if f.err != nil {
+		return "", f.err
+	}
+	content, err := os.ReadFile(localPath)
+	if err != nil {
+		return "", err
+	}
+	f.keys = append(f.keys, key)
+	f.content[key] = content
+	return "fake://" + key, os.Remove(localPath)
+}
+
+func (f *fakeStorage) String() string { return "fake://" }
+
+func TestFileReceiver_StoresCompletedFiles(t *testing.T) {
+	tempDir := t.TempDir()
+	fileReceiver := NewFileReceiver(tempDir, nil)
+	backend := &fakeStorage{content: map[string][]byte{}}
+	fileReceiver.SetStorage(backend)
+	serializer := transfer.NewJSONSerializer()
+
+	content := []byte("stored content")
+	data, err := serializer.Marshal(&transfer.ChunkMessage{
+		Type:         transfer.ChunkData,
+		FileID:       "/src/report.txt",
+		FileName:     "report.txt",
+		SequenceNo:   1,
+		Data:         content,
+		TotalSize:    int64(len(content)),
+		ExpectedHash: calculateTestHash(content),
+		IsLast:       true,
+	})
+	require.NoError(t, err)
+	require.NoError(t, fileReceiver.ProcessChunk(data))
+
+	assert.Equal(t, []string{"report.txt"}, backend.keys, "keys are paths under the output directory")
+	assert.Equal(t, content, backend.content["report.txt"])
+	assert.NoFileExists(t, filepath.Join(tempDir, "report.txt"))
+	assert.Equal(t, "fake://report.txt", fileReceiver.sessionOutputPath())
+
+	backend.err = errors.New("bucket gone")
+	data, err = serializer.Marshal(&transfer.ChunkMessage{
+		Type:         transfer.ChunkData,
+		FileID:       "/src/b.txt",
+		FileName:     "b.txt",
+		SequenceNo:   1,
+		Data:         content,
+		TotalSize:    int64(len(content)),
+		ExpectedHash: calculateTestHash(content),
+		IsLast:       true,
+	})
+	require.NoError(t, err)
+	assert.ErrorContains(t, fileReceiver.ProcessChunk(data), "bucket gone")
+	assert.FileExists(t, filepath.Join(tempDir, "b.txt"), "files the storage did not take stay put")
+
+	completed, failed, _, _ := fileReceiver.Outcome()
+	assert.Equal(t, 1, completed)
+	assert.Equal(t, 1, failed)
+}
+
+// fakeMeter allows limit bytes
+type fakeMeter struct {
+	limit, charged int64
+}
+
+var errFakeQuota = errors.New("over quota")
+
+func (m *fakeMeter) Check(n int64) error {
+	if m.charged+n > m.limit {
+		return errFakeQuota
+	}
+	return nil
+}
+
+func (m *fakeMeter) Charge(n int64) error {
+	if err := m.Check(n); err != nil {
+		return err
+	}
+	m.charged += n
+	return nil
+}
+
+func TestFileReceiver_ChargesWrittenBytes(t *testing.T) {
+	tempDir := t.TempDir()
+	fileReceiver := NewFileReceiver(tempDir, nil)
+	meter := &fakeMeter{limit: 10}
+	fileReceiver.SetQuota(meter)
+
+	// A file that cannot fit is refused before any space is taken for it
+	err := fileReceiver.ProcessMessage(&transfer.ChunkMessage{
+		Type: transfer.ChunkData, FileID: "/src/big.bin", FileName: "big.bin", Data: []byte("0123"), TotalSize: 100,
+	})
+	assert.ErrorIs(t, err, errFakeQuota)
+	assert.NoFileExists(t, filepath.Join(tempDir, "big.bin"))
+
+	// Streams claim no size, so they are charged chunk by chunk
+	chunk := func(sequence uint32, offset int64) *transfer.ChunkMessage {
+		return &transfer.ChunkMessage{
+			Type: transfer.ChunkData, FileID: fileInfo.StdinPath, FileName: "stream.bin",
+			SequenceNo: sequence, Offset: offset, Data: []byte("0123456"), TotalSize: fileInfo.UnknownSize,
+		}
+	}
+	require.NoError(t, fileReceiver.ProcessMessage(chunk(0, 0)))
+	require.NoError(t, fileReceiver.ProcessMessage(chunk(0, 0)), "a duplicate chunk is not charged again")
+	assert.ErrorIs(t, fileReceiver.ProcessMessage(chunk(1, 7)), errFakeQuota)
+	assert.Equal(t, int64(7), meter.charged)
+}
+
+func TestFileReceiver_WritesChunksOfParallelChannels(t *testing.T) {
+	tempDir := t.TempDir()
+	fileReceiver := NewFileReceiver(tempDir, nil)
+	fileReceiver.SetExpectedFiles(1)
+
+	content := bytes.Repeat([]byte("0123456789abcdef"), 64)
+	const chunkSize = 16
+	chunks := len(content) / chunkSize
+	chunk := func(sequence int) *transfer.ChunkMessage {
+		offset := sequence * chunkSize
+		return &transfer.ChunkMessage{
+			Type: transfer.ChunkData, FileID: "/src/file.bin", FileName: "file.bin",
+			SequenceNo: uint32(sequence), Offset: int64(offset), Data: content[offset : offset+chunkSize],
+			TotalSize: int64(len(content)), ExpectedHash: calculateTestHash(content),
+		}
+	}
+	require.NoError(t, fileReceiver.ProcessMessage(chunk(0)))
+
+	// Every chunk arrives on two channels at once, as retransmits across paths do
+	var wg sync.WaitGroup
+	for channel := 0; channel < 4; channel++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for sequence := 1 + channel%2; sequence < chunks; sequence += 2 {
+				assert.NoError(t, fileReceiver.ProcessMessage(chunk(sequence)))
+			}
+		}()
+	}
+	wg.Wait()
+
+	completed, failed, received, complete := fileReceiver.Outcome()
+	assert.Equal(t, 1, completed, "the file completes once")
+	assert.Zero(t, failed)
+	assert.Equal(t, int64(len(content)), received, "duplicates are not counted")
+	assert.True(t, complete)
+	written, err := os.ReadFile(filepath.Join(tempDir, "file.bin"))
+	require.NoError(t, err)
+	assert.Equal(t, content, written)
+}
+
+func TestFileReceiver_SendsWriteAcks(t *testing.T) {
+	fileReceiver := NewFileReceiver(t.TempDir(), nil)
+	fileReceiver.SetExpectedBytes(10)
+	serializer := transfer.NewJSONSerializer()
+
+	var writeAcks []*transfer.ChunkMessage
+	fileReceiver.SetAcknowledger(func(data []byte) error {
+		ack, err := serializer.Unmarshal(data)
+		require.NoError(t, err)
+		if ack.Type == transfer.WriteAck {
+			writeAcks = append(writeAcks, ack)
+		}
+		return nil
+	})
+
+	content := []byte("0123456789")
+	send := func(offset int64, part []byte, seq uint32) {
+		data, err := serializer.Marshal(&transfer.ChunkMessage{
+			Type:         transfer.ChunkData,
+			FileID:       "/src/file.txt",
+			FileName:     "file.txt",
+			SequenceNo:   seq,
+			Offset:       offset,
+			Data:         part,
+			TotalSize:    int64(len(content)),
+			ExpectedHash: calculateTestHash(content),
+			IsLast:       offset+int64(len(part)) == int64(len(content)),
+		})
+		require.NoError(t, err)
+		require.NoError(t, fileReceiver.ProcessChunk(data))
+	}
+
+	send(0, content[:4], 1)
+	assert.Empty(t, writeAcks, "senders that did not ask for write ACKs get none")
+
+	fileReceiver.EnableWriteAcks()
+	send(4, content[4:6], 2)
+	require.Len(t, writeAcks, 1)
+	assert.Equal(t, int64(6), writeAcks[0].Offset)
+	assert.Equal(t, int64(10), writeAcks[0].TotalSize)
+
+	// The last write of a file is always reported, however soon it follows
+	send(6, content[6:], 3)
+	require.Len(t, writeAcks, 2)
+	assert.Equal(t, int64(10), writeAcks[1].Offset)
+}
+
+// TestFileReceiver_PublishesProgress tests that reception progress is published on the event bus
+func TestFileReceiver_PublishesProgress(t *testing.T) {
+	fileReceiver := NewFileReceiver(t.TempDir(), nil)
+	bus := events.NewBus()
+	defer bus.Close()
+	fileReceiver.SetEventBus(bus)
+	fileReceiver.SetExpectedFiles(2)
+	fileReceiver.SetExpectedBytes(10)
+	sub := bus.Subscribe(0, events.TopicFile, events.TopicSession)
+
+	serializer := transfer.NewJSONSerializer()
+	for _, name := range []string{"a.txt", "b.txt"} {
+		content := []byte("hello")
+		data, err := serializer.Marshal(&transfer.ChunkMessage{
+			Type:         transfer.ChunkData,
+			FileID:       name,
+			FileName:     name,
+			SequenceNo:   1,
+			Data:         content,
+			TotalSize:    int64(len(content)),
+			ExpectedHash: calculateTestHash(content),
+			IsLast:       true,
+		})
+		require.NoError(t, err)
+		require.NoError(t, fileReceiver.ProcessChunk(data))
+	}
+
+	var completed []string
+	var last events.SessionProgress
+	for len(sub.C()) > 0 {
+		switch e := (<-sub.C()).(type) {
+		case events.FileStatusChanged:
+			if e.State == transfer.TransferStateCompleted.String() {
+				completed = append(completed, e.FilePath)
+				assert.Equal(t, int64(5), e.BytesSent)
+			}
+		case events.SessionProgress:
+			last = e
+		}
+	}
+
+	assert.Equal(t, []string{"a.txt", "b.txt"}, completed)
+	assert.Equal(t, 2, last.CompletedFiles)
+	assert.Equal(t, int64(10), last.BytesCompleted)
+	assert.Equal(t, float64(100), last.OverallProgress)
+	assert.Equal(t, transfer.StatusSessionStateCompleted.String(), last.State)
+}
+
 // Helper function to calculate SHA256 hash for test data
 func calculateTestHash(data []byte) string {
 	hash := sha256.Sum256(data)
 	return hex.EncodeToString(hash[:])
-}
\ No newline at end of file
+}
+
+// TestFileReceiver_ResumeInterruptedFile tests that a partially received file is resumed from persisted state
+func TestFileReceiver_ResumeInterruptedFile(t *testing.T) {
+	tempDir := t.TempDir()
+	store := transfer.NewResumeStore(tempDir)
+	token := transfer.NewResumeToken()
+	serializer := transfer.NewJSONSerializer()
+
+	first := []byte("0123456789")
+	second := []byte("abcdefghij")
+	full := append(append([]byte{}, first...), second...)
+	chunk := func(seq uint32, offset int64, data []byte) []byte {
+		encoded, err := serializer.Marshal(&transfer.ChunkMessage{
+			Type:         transfer.ChunkData,
+			FileID:       "/src/data.bin",
+			FileName:     "data.bin",
+			SequenceNo:   seq,
+			Offset:       offset,
+			Data:         data,
+			TotalSize:    int64(len(full)),
+			ExpectedHash: calculateTestHash(full),
+		})
+		require.NoError(t, err)
+		return encoded
+	}
+
+	// First session receives only the first chunk before being interrupted
+	interrupted := NewFileReceiver(tempDir, nil)
+	interrupted.SetExpectedFiles(1)
+	interrupted.EnableResume(store, transfer.NewResumeState(token))
+	require.NoError(t, interrupted.ProcessChunk(chunk(1, 0, first)))
+	require.NoError(t, interrupted.PersistResumeState())
+	require.NoError(t, interrupted.currentFiles["/src/data.bin"].File.Close())
+
+	state, err := store.Load(token)
+	require.NoError(t, err)
+	assert.True(t, state.HasChunk("/src/data.bin", 1))
+	assert.False(t, state.HasChunk("/src/data.bin", 2))
+
+	// Second session only receives the missing chunk
+	resumed := NewFileReceiver(tempDir, nil)
+	resumed.SetExpectedFiles(1)
+	resumed.EnableResume(store, state)
+	require.NoError(t, resumed.ProcessChunk(chunk(2, int64(len(first)), second)))
+
+	content, err := os.ReadFile(filepath.Join(tempDir, "data.bin"))
+	require.NoError(t, err)
+	assert.Equal(t, full, content)
+
+	_, err = store.Load(token)
+	assert.ErrorIs(t, err, transfer.ErrResumeStateNotFound, "resume state should be deleted once the session completes")
+}
+
+func TestFileReceiver_StructureUpdate(t *testing.T) {
+	sourceDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "a.txt"), []byte("a"), 0644))
+
+	fsm := transfer.NewFileStructureManager()
+	node, err := fileInfo.CreateNode(sourceDir)
+	require.NoError(t, err)
+	require.NoError(t, fsm.AddFileNode(&node))
+
+	signer, err := crypto.NewFileStructureSigner()
+	require.NoError(t, err)
+	base, err := signer.SignFileStructureManager(fsm)
+	require.NoError(t, err)
+	chain, err := crypto.NewStructureChain(base)
+	require.NoError(t, err)
+
+	fileReceiver := NewFileReceiver(t.TempDir(), nil)
+	fileReceiver.SetExpectedFiles(1)
+	fileReceiver.SetStructureChain(chain)
+
+	newPath := filepath.Join(sourceDir, "b.txt")
+	require.NoError(t, os.WriteFile(newPath, []byte("b"), 0644))
+	added, err := fileInfo.CreateNode(newPath)
+	require.NoError(t, err)
+
+	delta, err := crypto.NewStructureDeltaSigner(signer, base).Sign([]crypto.StructureDeltaChange{
+		{Op: crypto.DeltaAdd, Path: node.Name + "/b.txt", Node: &added},
+	})
+	require.NoError(t, err)
+	deltaJSON, err := json.Marshal(delta)
+	require.NoError(t, err)
+
+	serializer := transfer.NewJSONSerializer()
+	data, err := serializer.Marshal(&transfer.ChunkMessage{Type: transfer.StructureUpdate, Data: deltaJSON})
+	require.NoError(t, err)
+
+	require.NoError(t, fileReceiver.ProcessChunk(data))
+	assert.Equal(t, 2, fileReceiver.expectedFiles)
+
+	// Replaying the same delta is rejected
+	assert.Error(t, fileReceiver.ProcessChunk(data))
+}
+
+func TestFileReceiver_RenamesCollidingNames(t *testing.T) {
+	tempDir := t.TempDir()
+	// Every file reports several status updates, the buffer holds all of them
+	uiMessages := make(chan tea.Msg, 32)
+	fileReceiver := NewFileReceiver(tempDir, uiMessages)
+	fileReceiver.SetCaseInsensitive(true)
+
+	serializer := transfer.NewJSONSerializer()
+	receive := func(fileID, name string, content []byte) {
+		data, err := serializer.Marshal(&transfer.ChunkMessage{
+			Type:         transfer.ChunkData,
+			FileID:       fileID,
+			FileName:     name,
+			SequenceNo:   1,
+			Data:         content,
+			TotalSize:    int64(len(content)),
+			ExpectedHash: calculateTestHash(content),
+			IsLast:       true,
+		})
+		require.NoError(t, err)
+		require.NoError(t, fileReceiver.ProcessChunk(data))
+	}
+
+	receive("/src/a/File.txt", "File.txt", []byte("upper"))
+	receive("/src/b/file.txt", "file.txt", []byte("lower"))
+	receive("/src/c/File.txt", "File.txt", []byte("again"))
+
+	for name, expected := range map[string]string{
+		"File.txt":     "upper",
+		"file (1).txt": "lower",
+		"File (2).txt": "again",
+	} {
+		content, err := os.ReadFile(filepath.Join(tempDir, name))
+		require.NoError(t, err, "missing %s", name)
+		assert.Equal(t, expected, string(content), "content of %s", name)
+	}
+
+	renamed := 0
+	for len(uiMessages) > 0 {
+		if msg, ok := (<-uiMessages).(receiver.StatusUpdateMsg); ok && strings.Contains(msg.Message, "collides") {
+			renamed++
+		}
+	}
+	assert.Equal(t, 2, renamed, "every rename should be reported")
+}
+
+func TestFileReceiver_IgnoresKeepalives(t *testing.T) {
+	tempDir := t.TempDir()
+	fileReceiver := NewFileReceiver(tempDir, nil)
+
+	data, err := transfer.NewJSONSerializer().Marshal(&transfer.ChunkMessage{Type: transfer.Keepalive})
+	require.NoError(t, err)
+	require.NoError(t, fileReceiver.ProcessChunk(data))
+
+	entries, err := os.ReadDir(tempDir)
+	require.NoError(t, err)
+	assert.Empty(t, entries, "a keepalive should not create a file")
+}
+
+func TestFileReceiver_RecordsRecentFiles(t *testing.T) {
+	tempDir := t.TempDir()
+	store := recent.Open(filepath.Join(t.TempDir(), recent.FileName))
+	fileReceiver := NewFileReceiver(tempDir, nil)
+	fileReceiver.SetExpectedFiles(2)
+	fileReceiver.SetRecentFiles(store, "laptop")
+
+	serializer := transfer.NewJSONSerializer()
+	receive := func(fileID, name string, content []byte) {
+		data, err := serializer.Marshal(&transfer.ChunkMessage{
+			Type:         transfer.ChunkData,
+			FileID:       fileID,
+			FileName:     name,
+			SequenceNo:   1,
+			Data:         content,
+			TotalSize:    int64(len(content)),
+			ExpectedHash: calculateTestHash(content),
+			IsLast:       true,
+		})
+		require.NoError(t, err)
+		require.NoError(t, fileReceiver.ProcessChunk(data))
+	}
+
+	receive("/src/a.txt", "a.txt", []byte("a"))
+	entries, err := store.List()
+	require.NoError(t, err)
+	assert.Empty(t, entries, "files are recorded once the session completes")
+
+	receive("/src/b.txt", "b.txt", []byte("bb"))
+	entries, err = store.List()
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, filepath.Join(tempDir, "b.txt"), entries[0].Path, "the newest file comes first")
+	assert.Equal(t, int64(2), entries[0].Size)
+	assert.Equal(t, "laptop", entries[0].Sender)
+	assert.Equal(t, filepath.Join(tempDir, "a.txt"), entries[1].Path)
+}
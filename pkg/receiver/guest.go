@@ -0,0 +1,25 @@
+package receiver
+
+import (
+	"log/slog"
+
+	"github.com/rescp17/lanFileSharer/api"
+	"github.com/rescp17/lanFileSharer/internal/app_events/receiver"
+)
+
+// IssueGuestPass lets one sender giving the token of the pass transfer up to the guest size
+// cap without asking, until api.GuestPassLifetime passes. The sender's key is not trusted.
+func (a *App) IssueGuestPass() (api.GuestPass, error) {
+	pass, err := a.api.IssueGuestPass(a.guestMax, api.GuestPassLifetime)
+	if err != nil {
+		return api.GuestPass{}, err
+	}
+	slog.Info("Issued a guest pass", "max_bytes", pass.MaxBytes, "expires", pass.Expires)
+	return pass, nil
+}
+
+// issueGuestPass answers receiver.IssueGuestPass for the UI
+func (a *App) issueGuestPass() {
+	pass, err := a.IssueGuestPass()
+	a.uiMessages <- receiver.GuestPassMsg{Token: pass.Token, MaxBytes: pass.MaxBytes, Expires: pass.Expires, Err: err}
+}
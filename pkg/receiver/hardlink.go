@@ -0,0 +1,137 @@
+package receiver
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/rescp17/lanFileSharer/pkg/transfer"
+)
+
+// completedOutput is a verified file of the session that later files may be hard links to
+type completedOutput struct {
+	path     string
+	checksum string
+}
+
+// linkTarget returns the verified file fileID was saved as, in this session or, when resuming,
+// in the interrupted one. Without fileID it returns the stored object with checksum; fr.mu must be held
+func (fr *FileReceiver) linkTarget(fileID, checksum string) (completedOutput, bool) {
+	if fileID == "" {
+		if fr.contentStore == nil || !fr.contentStore.Has(checksum) {
+			return completedOutput{}, false
+		}
+		object, err := fr.contentStore.ObjectPath(checksum)
+		return completedOutput{path: object, checksum: checksum}, err == nil
+	}
+	if target, ok := fr.completedOutputs[fileID]; ok {
+		return target, true
+	}
+	if fr.resumeState != nil {
+		if entry, ok := fr.resumeState.Files[fileID]; ok && entry.Completed {
+			return completedOutput{path: entry.OutputPath, checksum: entry.ExpectedHash}, true
+		}
+	}
+	return completedOutput{}, false
+}
+
+// processLink saves the file of a FileLink message as a hard link to the verified file it
+// names, or as a copy of it where the filesystem cannot link; fr.mu must be held
+func (fr *FileReceiver) processLink(msg *transfer.ChunkMessage) error {
+	fileReception := &FileReception{
+		FilePath:     msg.FileID,
+		FileName:     msg.FileName,
+		TotalSize:    msg.TotalSize,
+		ExpectedHash: msg.ExpectedHash,
+		Status:       StatusReceiving,
+	}
+	fail := func(err error) error {
+		fileReception.Status = StatusFailed
+		fr.sendFileAck(msg.FileID, "", err)
+		fr.failedFiles++
+		fr.publishProgress(fileReception, transfer.TransferStateFailed, err)
+		return err
+	}
+
+	// A link resent because its ACK was lost is saved already
+	if checksum, ok := fr.finishedFiles[msg.FileID]; ok && checksum == msg.ExpectedHash {
+		fr.sendFileAck(msg.FileID, checksum, nil)
+		return nil
+	}
+
+	target, ok := fr.linkTarget(msg.LinkTo, msg.ExpectedHash)
+	switch {
+	case !ok && msg.LinkTo == "":
+		return fail(fmt.Errorf("cannot link %s: its content is not stored", msg.FileName))
+	case !ok:
+		return fail(fmt.Errorf("cannot link %s: %s has not been received", msg.FileName, msg.LinkTo))
+	}
+	if target.checksum != msg.ExpectedHash {
+		return fail(fmt.Errorf("cannot link %s: %w: it differs from %s", msg.FileName, transfer.ErrChecksumMismatch, msg.LinkTo))
+	}
+	if err := fr.verifySignedFile(fileReception); err != nil {
+		return fail(err)
+	}
+
+	outputPath, err := fr.claimLinkPath(msg.FileID, msg.FileName)
+	if err != nil {
+		return fail(err)
+	}
+	fileReception.OutputPath = outputPath
+	if err := linkOrCopy(target.path, outputPath); err != nil {
+		fr.releaseOutputName(fileReception)
+		return fail(fmt.Errorf("failed to save %s: %w", msg.FileName, transfer.ClassifyIOError(err)))
+	}
+
+	fileReception.ReceivedSize = msg.TotalSize
+	fileReception.Status = StatusCompleted
+	fileReception.IsComplete = true
+	fr.receivedBytes += msg.TotalSize
+	slog.Info("Saved hard link to received file", "fileName", msg.FileName, "target", target.path)
+	fr.recordCompletedFile(fileReception)
+	return nil
+}
+
+// claimLinkPath is claimOutputPath for the file of a FileLink message, which is also renamed
+// when a file outside the session has its name, since a link cannot replace it; fr.mu must be held
+func (fr *FileReceiver) claimLinkPath(fileID, name string) (string, error) {
+	return fr.claimOutputPath(fileID, name, func(candidate string) bool {
+		_, err := os.Lstat(filepath.Join(fr.outputDir, candidate))
+		return err == nil
+	})
+}
+
+// linkOrCopy makes path, which claimLinkPath left free, a hard link to target. Filesystems
+// without hard links, or a path on another device, get a copy.
+func linkOrCopy(target, path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	if err := os.Link(target, path); err == nil {
+		return nil
+	}
+	slog.Debug("Hard link not possible, copying", "target", target, "path", path)
+
+	src, err := os.Open(target)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		os.Remove(path)
+		return err
+	}
+	if err := dst.Sync(); err != nil {
+		dst.Close()
+		os.Remove(path)
+		return err
+	}
+	return dst.Close()
+}
@@ -0,0 +1,174 @@
+package receiver
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rescp17/lanFileSharer/pkg/cas"
+	"github.com/rescp17/lanFileSharer/pkg/transfer"
+)
+
+func TestFileReceiver_FileLink(t *testing.T) {
+	tempDir := t.TempDir()
+	fileReceiver := NewFileReceiver(tempDir, nil)
+	serializer := transfer.NewJSONSerializer()
+
+	var acks []*transfer.ChunkMessage
+	fileReceiver.SetAcknowledger(func(data []byte) error {
+		ack, err := serializer.Unmarshal(data)
+		require.NoError(t, err)
+		acks = append(acks, ack)
+		return nil
+	})
+
+	process := func(msg *transfer.ChunkMessage) error {
+		data, err := serializer.Marshal(msg)
+		require.NoError(t, err)
+		return fileReceiver.ProcessChunk(data)
+	}
+
+	content := []byte("content shared by hard links")
+	hash := calculateTestHash(content)
+	link := &transfer.ChunkMessage{
+		Type:         transfer.FileLink,
+		FileID:       "/src/link.txt",
+		FileName:     "link.txt",
+		TotalSize:    int64(len(content)),
+		ExpectedHash: hash,
+		LinkTo:       "/src/original.txt",
+	}
+
+	// A link to a file the receiver does not have is rejected so the sender can send the content
+	assert.Error(t, process(link))
+	require.Len(t, acks, 1)
+	assert.NotEmpty(t, acks[0].ErrorMessage)
+
+	require.NoError(t, process(&transfer.ChunkMessage{
+		Type:         transfer.ChunkData,
+		FileID:       "/src/original.txt",
+		FileName:     "original.txt",
+		SequenceNo:   1,
+		Data:         content,
+		TotalSize:    int64(len(content)),
+		ExpectedHash: hash,
+		IsLast:       true,
+	}))
+	require.NoError(t, process(link))
+	require.Len(t, acks, 3)
+	assert.Equal(t, "/src/link.txt", acks[2].FileID)
+	assert.Empty(t, acks[2].ErrorMessage)
+
+	saved, err := os.ReadFile(filepath.Join(tempDir, "link.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, content, saved)
+	original, err := os.Stat(filepath.Join(tempDir, "original.txt"))
+	require.NoError(t, err)
+	linked, err := os.Stat(filepath.Join(tempDir, "link.txt"))
+	require.NoError(t, err)
+	assert.True(t, os.SameFile(original, linked), "the link shares the received file's content on disk")
+
+	// A link resent because its ACK was lost is acknowledged again, not saved twice
+	require.NoError(t, process(link))
+	require.Len(t, acks, 4)
+	assert.Empty(t, acks[3].ErrorMessage)
+	assert.NoFileExists(t, filepath.Join(tempDir, "link (1).txt"))
+
+	// A file already in the output directory is kept, the link is saved next to it
+	existing := filepath.Join(tempDir, "kept.txt")
+	require.NoError(t, os.WriteFile(existing, []byte("the user's file"), 0644))
+	kept := *link
+	kept.FileID = "/src/kept.txt"
+	kept.FileName = "kept.txt"
+	require.NoError(t, process(&kept))
+	saved, err = os.ReadFile(existing)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("the user's file"), saved)
+	renamed, err := os.Stat(filepath.Join(tempDir, "kept (1).txt"))
+	require.NoError(t, err)
+	assert.True(t, os.SameFile(original, renamed))
+
+	// A link whose checksum differs from its target is not trusted
+	mismatched := *link
+	mismatched.FileID = "/src/other.txt"
+	mismatched.FileName = "other.txt"
+	mismatched.ExpectedHash = calculateTestHash([]byte("other"))
+	assert.ErrorIs(t, process(&mismatched), transfer.ErrChecksumMismatch)
+}
+
+func TestFileReceiver_ContentStore(t *testing.T) {
+	tempDir := t.TempDir()
+	store, err := cas.Open(tempDir)
+	require.NoError(t, err)
+	fileReceiver := NewFileReceiver(tempDir, nil)
+	fileReceiver.SetContentStore(store)
+	serializer := transfer.NewJSONSerializer()
+
+	process := func(msg *transfer.ChunkMessage) error {
+		data, err := serializer.Marshal(msg)
+		require.NoError(t, err)
+		return fileReceiver.ProcessChunk(data)
+	}
+
+	content := []byte("content received in an earlier transfer")
+	hash := calculateTestHash(content)
+	require.NoError(t, process(&transfer.ChunkMessage{
+		Type:         transfer.ChunkData,
+		FileID:       "/src/report.pdf",
+		FileName:     "report.pdf",
+		SequenceNo:   1,
+		Data:         content,
+		TotalSize:    int64(len(content)),
+		ExpectedHash: hash,
+		IsLast:       true,
+	}))
+	require.True(t, store.Has(hash), "verified files are added to the store")
+
+	// Stored content is linked by checksum, under any name
+	require.NoError(t, process(&transfer.ChunkMessage{
+		Type:         transfer.FileLink,
+		FileID:       "/other/copy.pdf",
+		FileName:     "copy.pdf",
+		TotalSize:    int64(len(content)),
+		ExpectedHash: hash,
+	}))
+	original, err := os.Stat(filepath.Join(tempDir, "report.pdf"))
+	require.NoError(t, err)
+	linked, err := os.Stat(filepath.Join(tempDir, "copy.pdf"))
+	require.NoError(t, err)
+	assert.True(t, os.SameFile(original, linked))
+	assert.Len(t, store.Entries(), 2)
+
+	// A later session receiving new content under a linked name leaves the stored object untouched
+	fileReceiver = NewFileReceiver(tempDir, nil)
+	fileReceiver.SetContentStore(store)
+	changed := []byte("changed")
+	require.NoError(t, process(&transfer.ChunkMessage{
+		Type:         transfer.ChunkData,
+		FileID:       "/other/copy.pdf",
+		FileName:     "copy.pdf",
+		SequenceNo:   1,
+		Data:         changed,
+		TotalSize:    int64(len(changed)),
+		ExpectedHash: calculateTestHash(changed),
+		IsLast:       true,
+	}))
+	saved, err := os.ReadFile(filepath.Join(tempDir, "report.pdf"))
+	require.NoError(t, err)
+	assert.Equal(t, content, saved)
+	saved, err = os.ReadFile(filepath.Join(tempDir, "copy.pdf"))
+	require.NoError(t, err)
+	assert.Equal(t, changed, saved)
+
+	// Content the store does not have cannot be linked
+	assert.Error(t, process(&transfer.ChunkMessage{
+		Type:         transfer.FileLink,
+		FileID:       "/src/unknown.txt",
+		FileName:     "unknown.txt",
+		TotalSize:    5,
+		ExpectedHash: calculateTestHash([]byte("other")),
+	}))
+}
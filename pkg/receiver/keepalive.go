@@ -0,0 +1,83 @@
+package receiver
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+
+	"github.com/rescp17/lanFileSharer/internal/app_events/receiver"
+	"github.com/rescp17/lanFileSharer/pkg/transfer"
+	webrtcPkg "github.com/rescp17/lanFileSharer/pkg/webrtc"
+)
+
+// keepaliveMessage is the JSON of the keepalives sent to senders
+var keepaliveMessage, _ = transfer.NewJSONSerializer().Marshal(&transfer.ChunkMessage{Type: transfer.Keepalive})
+
+// idleSession identifies an accepted session to the keepalives of its primary channel
+type idleSession struct {
+	keepalive   bool   // The sender sends and expects keepalives
+	resumeToken string // Token the sender resumes the session with
+	fingerprint string // Key that signed the offer of the session
+}
+
+// watchIdle sends keepalives with send, which replies to the primary channel of receiverConn, and tears the session
+// down once the sender sent nothing for the idle timeout or this machine woke from sleep,
+// keeping what was received for the sender to resume without asking. It returns nil unless the sender asked for keepalives;
+// the caller touches the monitor with every message and calls stop once the primary channel closes.
+func (a *App) watchIdle(receiverConn webrtcPkg.ReceiverConnection, send func([]byte) error, session idleSession) (monitor *transfer.KeepaliveMonitor, stop func()) {
+	if !session.keepalive || a.idleTimeout <= 0 {
+		return nil, func() {}
+	}
+	monitor = transfer.NewKeepaliveMonitor(a.idleTimeout, func() error {
+		return send(keepaliveMessage)
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	var once sync.Once
+	stop = func() {
+		once.Do(func() {
+			monitor.Stop()
+			cancel()
+		})
+	}
+
+	monitor.Start()
+	// The sender gives up on a session while this machine sleeps, so waking ends it as well
+	suspends := transfer.NewSuspendDetector().Watch(ctx)
+	go func() {
+		select {
+		case <-monitor.Idle():
+			slog.Warn("Sender stopped responding, ending the session", "timeout", a.idleTimeout)
+			a.uiMessages <- receiver.StatusUpdateMsg{Message: "Sender stopped responding, the transfer resumes once it is back"}
+		case slept, ok := <-suspends:
+			if !ok {
+				return
+			}
+			slog.Warn("Woke from sleep, ending the session", "slept", slept)
+			a.uiMessages <- receiver.StatusUpdateMsg{Message: "Woke from sleep, the transfer resumes once the sender reconnects"}
+		case <-ctx.Done():
+			return
+		}
+		stop()
+		a.persistResumeState()
+		a.expectResume(session)
+		a.closeActiveConnectionIfSameConn(receiverConn)
+	}()
+	return monitor, stop
+}
+
+// expectResume lets the sender of session resume it without asking if the session ended before
+// all files arrived, such as when the sender went idle or moved to another network interface
+func (a *App) expectResume(session idleSession) {
+	if !session.keepalive || session.resumeToken == "" {
+		return
+	}
+	a.receiverMu.Lock()
+	complete := false
+	if a.fileReceiver != nil {
+		_, _, _, complete = a.fileReceiver.Outcome()
+	}
+	a.receiverMu.Unlock()
+	if !complete {
+		a.api.ExpectResume(session.resumeToken, session.fingerprint)
+	}
+}
@@ -0,0 +1,62 @@
+package receiver
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/rescp17/lanFileSharer/internal/app_events/receiver"
+	"github.com/rescp17/lanFileSharer/internal/util"
+	"github.com/rescp17/lanFileSharer/internal/version"
+	"github.com/rescp17/lanFileSharer/pkg/discovery"
+)
+
+// metaRefreshInterval is how often the advertised state is checked for changes
+const metaRefreshInterval = 10 * time.Second
+
+// serviceMeta returns the state advertised to senders. Free space is rounded down to
+// whole MiB so ongoing writes do not change the record on every check.
+func (a *App) serviceMeta() discovery.ServiceMeta {
+	meta := discovery.ServiceMeta{
+		Advertised:   true,
+		Version:      version.String(),
+		FreeBytes:    -1,
+		Busy:         a.stateManager.HasActiveRequest(),
+		DoNotDisturb: a.api.Availability() == receiver.DoNotDisturb,
+		Relay:        a.relay,
+		Fingerprint:  a.fingerprint,
+		Share:        a.sharing,
+		Wired:        len(util.WiredInterfaces()) > 0,
+		Icon:         a.icon,
+	}
+	if free, err := util.FreeSpace(a.outputPath); err == nil {
+		meta.FreeBytes = free &^ (1<<20 - 1)
+	}
+	return meta
+}
+
+// advertiseMeta keeps the announced metadata current while ctx is alive, if the registrar can update it
+func (a *App) advertiseMeta(ctx context.Context, advertised discovery.ServiceMeta) {
+	updater, ok := a.registrar.(discovery.MetaUpdater)
+	if !ok {
+		return
+	}
+	ticker := time.NewTicker(metaRefreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		meta := a.serviceMeta()
+		if meta == advertised {
+			continue
+		}
+		if err := updater.UpdateMeta(a.serviceName, meta); err != nil {
+			slog.Debug("Failed to update advertised state", "error", err)
+			continue
+		}
+		advertised = meta
+	}
+}
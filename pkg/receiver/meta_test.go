@@ -0,0 +1,19 @@
+package receiver
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServiceMeta(t *testing.T) {
+	app := NewApp(0, t.TempDir())
+	require.NotNil(t, app)
+
+	meta := app.serviceMeta()
+	assert.True(t, meta.Advertised)
+	assert.NotEmpty(t, meta.Version)
+	assert.False(t, meta.Busy)
+	assert.Zero(t, meta.FreeBytes%(1<<20), "free space is rounded to MiB")
+}
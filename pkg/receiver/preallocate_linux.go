@@ -0,0 +1,20 @@
+package receiver
+
+import (
+	"errors"
+	"log/slog"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// preallocate reserves size bytes for file so the disk running full fails the file before
+// its first chunk is written. Filesystems without fallocate are written without reserving.
+func preallocate(file *os.File, size int64) error {
+	err := unix.Fallocate(int(file.Fd()), 0, 0, size)
+	if errors.Is(err, unix.EOPNOTSUPP) || errors.Is(err, unix.ENOSYS) {
+		slog.Debug("Filesystem cannot preallocate, writing without reserving space", "file", file.Name())
+		return nil
+	}
+	return err
+}
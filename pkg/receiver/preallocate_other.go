@@ -0,0 +1,8 @@
+//go:build !linux && !windows
+
+package receiver
+
+import "os"
+
+// preallocate is not supported on this platform; files grow as their chunks are written
+func preallocate(file *os.File, size int64) error { return nil }
@@ -0,0 +1,50 @@
+package receiver
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rescp17/lanFileSharer/pkg/transfer"
+)
+
+func TestFileReceiver_PreallocatesFiles(t *testing.T) {
+	if runtime.GOOS != "linux" && runtime.GOOS != "windows" {
+		t.Skip("preallocation is not supported on " + runtime.GOOS)
+	}
+	tempDir := t.TempDir()
+	fileReceiver := NewFileReceiver(tempDir, nil)
+	serializer := transfer.NewJSONSerializer()
+
+	content := bytes.Repeat([]byte("p"), 4096)
+	send := func(seq uint32, offset int64, data []byte) {
+		encoded, err := serializer.Marshal(&transfer.ChunkMessage{
+			Type:         transfer.ChunkData,
+			FileID:       "/src/big.bin",
+			FileName:     "big.bin",
+			SequenceNo:   seq,
+			Offset:       offset,
+			Data:         data,
+			TotalSize:    int64(len(content)),
+			ExpectedHash: calculateTestHash(content),
+			IsLast:       offset+int64(len(data)) == int64(len(content)),
+		})
+		require.NoError(t, err)
+		require.NoError(t, fileReceiver.ProcessChunk(encoded))
+	}
+
+	send(1, 0, content[:1024])
+	info, err := os.Stat(filepath.Join(tempDir, "big.bin"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(content)), info.Size(), "the file has its final size before the rest arrives")
+
+	send(2, 1024, content[1024:])
+	saved, err := os.ReadFile(filepath.Join(tempDir, "big.bin"))
+	require.NoError(t, err)
+	assert.Equal(t, content, saved)
+}
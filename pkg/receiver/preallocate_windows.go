@@ -0,0 +1,21 @@
+package receiver
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+
+	"golang.org/x/sys/windows"
+)
+
+// preallocate reserves size bytes for file by setting its end of file, so the disk running
+// full fails the file before its first chunk is written
+func preallocate(file *os.File, size int64) error {
+	err := file.Truncate(size)
+	if errors.Is(err, windows.ERROR_DISK_FULL) || errors.Is(err, windows.ERROR_HANDLE_DISK_FULL) {
+		// Classified like ENOSPC elsewhere, so the sender is told the disk is full
+		return fmt.Errorf("%w: %w", syscall.ENOSPC, err)
+	}
+	return err
+}
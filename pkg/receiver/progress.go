@@ -0,0 +1,128 @@
+package receiver
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/rescp17/lanFileSharer/internal/app_events/receiver"
+	"github.com/rescp17/lanFileSharer/pkg/events"
+	"github.com/rescp17/lanFileSharer/pkg/fileInfo"
+	"github.com/rescp17/lanFileSharer/pkg/transfer"
+)
+
+// Events returns the bus the receiver publishes the progress of received files to
+func (a *App) Events() *events.Bus {
+	return a.bus
+}
+
+// expectedBytes returns the total size of files; streams of unknown size are not counted
+func expectedBytes(files []fileInfo.FileNode) int64 {
+	var total int64
+	for _, file := range files {
+		if file.Size > 0 {
+			total += file.Size
+		}
+	}
+	return total
+}
+
+// uiFrameInterval caps the progress messages sent to the UI at 10 a second. Every message
+// redraws the TUI, and fast transfers publish progress for every chunk.
+const uiFrameInterval = 100 * time.Millisecond
+
+// forwardProgress turns the reception events of sub into UI messages until sub is closed.
+// Events are coalesced into one message per file and frame; finished files and sessions
+// are shown right away.
+func (a *App) forwardProgress(ctx context.Context, sub *events.Subscription) {
+	ticker := time.NewTicker(uiFrameInterval)
+	defer ticker.Stop()
+
+	var batch progressBatch
+	for {
+		select {
+		case event, ok := <-sub.C():
+			if !ok {
+				a.sendProgress(ctx, batch.flush())
+				return
+			}
+			if batch.add(event) && !a.sendProgress(ctx, batch.flush()) {
+				return
+			}
+		case <-ticker.C:
+			if !a.sendProgress(ctx, batch.flush()) {
+				return
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// sendProgress hands msgs to the UI and reports whether ctx is still alive
+func (a *App) sendProgress(ctx context.Context, msgs []any) bool {
+	for _, msg := range msgs {
+		select {
+		case a.uiMessages <- msg:
+		case <-ctx.Done():
+			return false
+		}
+	}
+	return true
+}
+
+// progressBatch keeps the latest progress of the session and of each file between two UI frames
+type progressBatch struct {
+	session *receiver.ProgressUpdateMsg
+	files   []receiver.FileProgressMsg // In the order the files first reported progress
+}
+
+// add records event and reports whether it should be shown without waiting for the next frame
+func (b *progressBatch) add(event events.Event) bool {
+	switch e := event.(type) {
+	case events.SessionProgress:
+		b.session = &receiver.ProgressUpdateMsg{
+			TotalFiles:     e.TotalFiles,
+			CompletedFiles: e.CompletedFiles,
+			FailedFiles:    e.FailedFiles,
+			TotalBytes:     e.TotalBytes,
+			ReceivedBytes:  e.BytesCompleted,
+			CurrentFile:    e.CurrentFile,
+			TransferRate:   e.TransferRate,
+			FilesPerMinute: e.FilesPerMinute,
+			DiskWriteRate:  e.DiskWriteRate,
+			DiskBusy:       e.DiskBusy,
+			ETA:            e.ETA(),
+		}
+		return e.CompletedFiles+e.FailedFiles >= e.TotalFiles
+	case events.FileStatusChanged:
+		msg := receiver.FileProgressMsg{
+			FileName:      e.FilePath,
+			ReceivedBytes: e.BytesSent,
+			TotalBytes:    e.TotalBytes,
+			Completed:     e.State == transfer.TransferStateCompleted.String(),
+			Err:           e.Err,
+		}
+		i := slices.IndexFunc(b.files, func(f receiver.FileProgressMsg) bool { return f.FileName == msg.FileName })
+		if i < 0 {
+			b.files = append(b.files, msg)
+		} else {
+			b.files[i] = msg
+		}
+		return msg.Completed || msg.Err != nil
+	}
+	return false
+}
+
+// flush returns the pending messages, files first, and empties the batch
+func (b *progressBatch) flush() []any {
+	msgs := make([]any, 0, len(b.files)+1)
+	for _, file := range b.files {
+		msgs = append(msgs, file)
+	}
+	if b.session != nil {
+		msgs = append(msgs, *b.session)
+	}
+	b.session, b.files = nil, b.files[:0]
+	return msgs
+}
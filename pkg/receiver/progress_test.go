@@ -0,0 +1,39 @@
+package receiver
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rescp17/lanFileSharer/internal/app_events/receiver"
+	"github.com/rescp17/lanFileSharer/pkg/events"
+	"github.com/rescp17/lanFileSharer/pkg/transfer"
+)
+
+func TestProgressBatchCoalescesUpdates(t *testing.T) {
+	var batch progressBatch
+	assert.False(t, batch.add(events.FileStatusChanged{FilePath: "a", BytesSent: 10, TotalBytes: 100}))
+	assert.False(t, batch.add(events.FileStatusChanged{FilePath: "b", BytesSent: 5, TotalBytes: 50}))
+	assert.False(t, batch.add(events.FileStatusChanged{FilePath: "a", BytesSent: 20, TotalBytes: 100}))
+	assert.False(t, batch.add(events.SessionProgress{TotalFiles: 2, BytesCompleted: 10}))
+	assert.False(t, batch.add(events.SessionProgress{TotalFiles: 2, BytesCompleted: 25}))
+
+	msgs := batch.flush()
+	require.Len(t, msgs, 3)
+	assert.Equal(t, receiver.FileProgressMsg{FileName: "a", ReceivedBytes: 20, TotalBytes: 100}, msgs[0])
+	assert.Equal(t, "b", msgs[1].(receiver.FileProgressMsg).FileName)
+	assert.Equal(t, int64(25), msgs[2].(receiver.ProgressUpdateMsg).ReceivedBytes)
+
+	assert.Empty(t, batch.flush(), "a flushed batch starts over")
+}
+
+func TestProgressBatchShowsFinishedWorkRightAway(t *testing.T) {
+	var batch progressBatch
+	assert.True(t, batch.add(events.FileStatusChanged{
+		FilePath: "a", BytesSent: 100, TotalBytes: 100, State: transfer.TransferStateCompleted.String(),
+	}))
+	assert.True(t, batch.add(events.FileStatusChanged{FilePath: "b", Err: errors.New("disk full")}))
+	assert.True(t, batch.add(events.SessionProgress{TotalFiles: 2, CompletedFiles: 1, FailedFiles: 1}))
+}
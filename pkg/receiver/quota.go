@@ -0,0 +1,28 @@
+package receiver
+
+import (
+	"log/slog"
+	"path/filepath"
+
+	"github.com/rescp17/lanFileSharer/api"
+	"github.com/rescp17/lanFileSharer/internal/config"
+)
+
+// Quota returns the transfer limits configured in cfg, or nil if there are none.
+// Daily usage is kept in the config directory, or in memory if it cannot be read.
+func Quota(cfg config.Config) *api.Quota {
+	rules := api.QuotaRules{MaxTransferBytes: cfg.MaxTransferBytes(), DailyBytes: cfg.DailyQuotaBytes()}
+	var path string
+	if dir, err := config.Dir(); err != nil {
+		slog.Warn("Could not resolve quota path, daily usage is kept in memory", "error", err)
+	} else {
+		path = filepath.Join(dir, api.QuotaFileName)
+	}
+
+	quota, err := api.NewQuota(rules, path)
+	if err != nil {
+		slog.Warn("Could not load quota usage, daily usage is kept in memory", "error", err)
+		quota, _ = api.NewQuota(rules, "")
+	}
+	return quota
+}
@@ -0,0 +1,25 @@
+package receiver
+
+import "log/slog"
+
+// applyRecentFiles makes the new session record its files in the recent files list; a.receiverMu must be held
+func (a *App) applyRecentFiles() {
+	if a.recent == nil {
+		return
+	}
+	senderName, err := a.stateManager.GetSenderName()
+	if err != nil {
+		slog.Warn("Could not get sender name", "error", err)
+	}
+	a.fileReceiver.SetRecentFiles(a.recent, senderName)
+}
+
+// recordRecent adds the files the session completed so far to the recent files list, for
+// sessions that end before all files arrived
+func (a *App) recordRecent() {
+	a.receiverMu.Lock()
+	defer a.receiverMu.Unlock()
+	if a.fileReceiver != nil {
+		a.fileReceiver.RecordRecent()
+	}
+}
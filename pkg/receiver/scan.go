@@ -0,0 +1,46 @@
+package receiver
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/rescp17/lanFileSharer/internal/app_events/receiver"
+	"github.com/rescp17/lanFileSharer/pkg/scan"
+	"github.com/rescp17/lanFileSharer/pkg/transfer"
+)
+
+// scanFile runs the virus scanner on a received and verified file before it completes. Files
+// it flags, or cannot scan, are moved to the quarantine of the output directory rather than
+// left where the user would open them; fr.mu must be held.
+func (fr *FileReceiver) scanFile(fileReception *FileReception) error {
+	if fr.uiMessages != nil {
+		fr.uiMessages <- receiver.StatusUpdateMsg{Message: fmt.Sprintf("Scanning file: %s", fileReception.FileName)}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), scan.DefaultTimeout)
+	defer cancel()
+	result, err := fr.scanner.Scan(ctx, fileReception.OutputPath)
+	if err == nil && !result.Infected {
+		return nil
+	}
+	if err == nil {
+		err = transfer.ErrFileInfected
+		if result.Signature != "" {
+			err = fmt.Errorf("%w: %s", transfer.ErrFileInfected, result.Signature)
+		}
+	}
+
+	quarantined, quarantineErr := scan.Quarantine(fileReception.OutputPath, fr.outputDir)
+	if quarantineErr != nil {
+		slog.Error("Failed to quarantine file, removing it", "fileName", fileReception.FileName, "error", quarantineErr)
+		if cleanupErr := fr.cleanupCorruptedFile(fileReception); cleanupErr != nil {
+			slog.Error("Failed to remove file", "fileName", fileReception.FileName, "error", cleanupErr)
+		}
+	} else {
+		slog.Warn("File quarantined", "fileName", fileReception.FileName, "path", quarantined, "error", err)
+	}
+	if fr.uiMessages != nil {
+		fr.uiMessages <- receiver.StatusUpdateMsg{Message: fmt.Sprintf("File quarantined: %s - %v", fileReception.FileName, err)}
+	}
+	return fmt.Errorf("virus scan of %s: %w", fileReception.FileName, err)
+}
@@ -0,0 +1,65 @@
+package receiver
+
+import (
+	"log/slog"
+	"net"
+	"slices"
+
+	"github.com/rescp17/lanFileSharer/api"
+	"github.com/rescp17/lanFileSharer/internal/config"
+	"github.com/rescp17/lanFileSharer/pkg/discovery"
+)
+
+// ServiceScopes returns the scopes the receiver is announced in: the configured one and
+// one per extra service type in the same domain. Invalid entries are skipped.
+func ServiceScopes(cfg config.Config) []discovery.Scope {
+	primary, err := discovery.ParseScope(cfg.ServiceType, cfg.ServiceDomain)
+	if err != nil {
+		slog.Warn("Invalid service scope in config, using default", "error", err, "default", discovery.DefaultScope().Query())
+		primary = discovery.DefaultScope()
+	}
+	scopes := []discovery.Scope{primary}
+	for _, serviceType := range cfg.ExtraServiceTypes {
+		scope, err := discovery.ParseScope(serviceType, primary.Domain)
+		if err != nil {
+			slog.Warn("Skipping invalid extra service type in config", "error", err)
+			continue
+		}
+		if !slices.Contains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes
+}
+
+// PeerFilter returns the peer filter configured in cfg, or nil if no rules are set
+func PeerFilter(cfg config.Config) *api.PeerFilter {
+	rules := api.PeerFilterRules{
+		AllowSubnets:      cfg.AllowSubnets,
+		DenySubnets:       cfg.DenySubnets,
+		AllowFingerprints: cfg.AllowFingerprints,
+		DenyFingerprints:  cfg.DenyFingerprints,
+		LANOnly:           cfg.LANOnly,
+	}
+	if cfg.AirgapPeer != "" {
+		host, _, err := net.SplitHostPort(cfg.AirgapPeer)
+		if err != nil {
+			host = cfg.AirgapPeer
+		}
+		rules.AllowSubnets = append(rules.AllowSubnets, host)
+	}
+	return api.NewPeerFilter(rules)
+}
+
+// PSK returns the pre-shared key of air-gap mode, or nil if no passphrase was entered
+func PSK(cfg config.Config) *api.PSK {
+	if cfg.PSKPassphrase == "" {
+		return nil
+	}
+	psk, err := api.NewPSK(cfg.PSKPassphrase)
+	if err != nil {
+		slog.Warn("Ignoring pre-shared passphrase", "error", err)
+		return nil
+	}
+	return psk
+}
@@ -0,0 +1,25 @@
+package receiver
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/rescp17/lanFileSharer/internal/config"
+	"github.com/rescp17/lanFileSharer/pkg/discovery"
+)
+
+func TestServiceScopes(t *testing.T) {
+	assert.Equal(t, []discovery.Scope{discovery.DefaultScope()}, ServiceScopes(config.DefaultConfig()))
+
+	cfg := config.DefaultConfig()
+	cfg.ServiceType = "_acme-share._tcp"
+	cfg.ExtraServiceTypes = []string{discovery.DefaultServerType, "not a type", "_acme-share._tcp"}
+	assert.Equal(t, []discovery.Scope{
+		{Type: "_acme-share._tcp", Domain: "local"},
+		{Type: discovery.DefaultServerType, Domain: "local"},
+	}, ServiceScopes(cfg))
+
+	cfg.ServiceType = "acme"
+	assert.Equal(t, discovery.DefaultScope(), ServiceScopes(cfg)[0], "an invalid type falls back to the default")
+}
@@ -0,0 +1,153 @@
+package receiver
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/rescp17/lanFileSharer/pkg/crypto"
+	"github.com/rescp17/lanFileSharer/pkg/fileInfo"
+	"github.com/rescp17/lanFileSharer/pkg/transfer"
+)
+
+// errNoMerkleRoot is returned for requests whose files cannot be proven, as those of older
+// senders cannot
+var errNoMerkleRoot = errors.New("the request carries no signed Merkle root")
+
+// acceptedFiles returns the files of the accepted request that the session receives, with the
+// proofs that they belong to its signed Merkle root, verified. Files that a resumed session
+// completed already are not sent again, so they are left out.
+func (a *App) acceptedFiles(signedFiles *crypto.SignedFileStructure, resumeToken string) (*crypto.FileSubset, error) {
+	if signedFiles == nil || signedFiles.MerkleRoot == nil {
+		return nil, errNoMerkleRoot
+	}
+	completed := a.completedResumeFiles(signedFiles, resumeToken)
+	indices := make([]int, 0, len(signedFiles.Files))
+	for i, file := range signedFiles.Files {
+		if !completed[fmt.Sprintf("%s|%d|%s", file.Name, file.Size, file.Checksum)] {
+			indices = append(indices, i)
+		}
+	}
+	subset, err := signedFiles.Subset(indices)
+	if err != nil {
+		return nil, err
+	}
+	if err := crypto.VerifyFileSubset(subset); err != nil {
+		return nil, err
+	}
+	return subset, nil
+}
+
+// completedResumeFiles returns the name, size and checksum of the files completed by the
+// session resumed with resumeToken, if its state is reused for signedFiles
+func (a *App) completedResumeFiles(signedFiles *crypto.SignedFileStructure, resumeToken string) map[string]bool {
+	if resumeToken == "" || a.resumeStore == nil {
+		return nil
+	}
+	state, err := a.resumeStore.Load(resumeToken)
+	if err != nil || !resumeStateMatches(state, signedFiles) {
+		return nil
+	}
+	completed := make(map[string]bool)
+	for _, file := range state.Files {
+		if file.Completed {
+			completed[fmt.Sprintf("%s|%d|%s", file.FileName, file.TotalSize, file.ExpectedHash)] = true
+		}
+	}
+	return completed
+}
+
+// SetSignedFiles makes completed files prove into the signed Merkle root of subset, which must
+// have been verified with crypto.VerifyFileSubset, or match a file of a signed structure
+// update. Other files fail.
+func (fr *FileReceiver) SetSignedFiles(subset *crypto.FileSubset) {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	fr.deltaFiles = nil
+	if subset == nil {
+		fr.merkleRoot, fr.signedFiles = nil, nil
+		return
+	}
+	fr.merkleRoot = subset.MerkleRoot
+	fr.signedFiles = make(map[string][]crypto.ProvenFile, len(subset.Files))
+	for _, file := range subset.Files {
+		fr.signedFiles[file.Node.Name] = append(fr.signedFiles[file.Node.Name], file)
+	}
+}
+
+// addDeltaFiles records the files that the verified structure update delta adds or changes,
+// which are signed by it rather than by the Merkle root; fr.mu must be held
+func (fr *FileReceiver) addDeltaFiles(delta *crypto.SignedStructureDelta) {
+	if fr.merkleRoot == nil {
+		return
+	}
+	if fr.deltaFiles == nil {
+		fr.deltaFiles = make(map[string][]fileInfo.FileNode)
+	}
+	var add func(node *fileInfo.FileNode)
+	add = func(node *fileInfo.FileNode) {
+		if !node.IsDir {
+			fr.deltaFiles[node.Name] = append(fr.deltaFiles[node.Name], *node)
+			return
+		}
+		for i := range node.Children {
+			add(&node.Children[i])
+		}
+	}
+	for _, change := range delta.Changes {
+		if change.Op != crypto.DeltaRemove && change.Node != nil {
+			add(change.Node)
+		}
+	}
+}
+
+// checkAcceptedFile checks, before a file is written, that a file named name of size bytes is
+// one of the accepted files or one that a signed structure update added, so the sender cannot
+// write more than the user accepted; fr.mu must be held
+func (fr *FileReceiver) checkAcceptedFile(name string, size int64) error {
+	if fr.merkleRoot == nil {
+		return nil
+	}
+	for _, file := range fr.signedFiles[name] {
+		// Streams are signed before their size is known
+		if file.Node.IsStream() || file.Node.Size == size {
+			return nil
+		}
+	}
+	for _, node := range fr.deltaFiles[name] {
+		if node.IsStream() || node.Size == size {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %w: %s of %d bytes is not one of the accepted files",
+		transfer.ErrChecksumMismatch, crypto.ErrMerkleProofInvalid, name, size)
+}
+
+// verifySignedFile checks that a file whose content was verified is one of the accepted files
+// of its name, or one that a signed structure update added, so a sender cannot pass off a file
+// the user did not accept as one they did; fr.mu must be held
+func (fr *FileReceiver) verifySignedFile(fileReception *FileReception) error {
+	if fr.merkleRoot == nil {
+		return nil
+	}
+	name := fileReception.FileName
+	if fileReception.ExpectedHash == "" {
+		return fmt.Errorf("%w: %s was sent without a checksum", transfer.ErrChecksumMismatch, name)
+	}
+	received := fileInfo.FileNode{Name: name, Size: fileReception.TotalSize, Checksum: fileReception.ExpectedHash}
+	for _, node := range fr.deltaFiles[name] {
+		if node.IsStream() || node.Size == received.Size && node.Checksum == received.Checksum {
+			return nil
+		}
+	}
+	err := fmt.Errorf("%w: %s is not one of the accepted files", crypto.ErrMerkleProofInvalid, name)
+	for _, file := range fr.signedFiles[name] {
+		if file.Node.IsStream() {
+			// Streams are signed before their size and content are known
+			return nil
+		}
+		if err = fr.merkleRoot.VerifyFile(received, file.Proof); err == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %w", transfer.ErrChecksumMismatch, err)
+}
@@ -0,0 +1,187 @@
+package receiver
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rescp17/lanFileSharer/pkg/crypto"
+	"github.com/rescp17/lanFileSharer/pkg/fileInfo"
+	"github.com/rescp17/lanFileSharer/pkg/transfer"
+)
+
+func TestFileReceiver_VerifiesSignedFiles(t *testing.T) {
+	sourceDir := t.TempDir()
+	signedContent := []byte("the file the user accepted")
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "signed.txt"), signedContent, 0644))
+	signedFiles, err := crypto.CreateSignedFileStructure([]string{filepath.Join(sourceDir, "signed.txt")})
+	require.NoError(t, err)
+	subset, err := signedFiles.Subset([]int{0})
+	require.NoError(t, err)
+	require.NoError(t, crypto.VerifyFileSubset(subset))
+
+	tempDir := t.TempDir()
+	fileReceiver := NewFileReceiver(tempDir, nil)
+	fileReceiver.SetSignedFiles(subset)
+	serializer := transfer.NewJSONSerializer()
+	send := func(fileID, name string, content []byte) error {
+		data, err := serializer.Marshal(&transfer.ChunkMessage{
+			Type:         transfer.ChunkData,
+			FileID:       fileID,
+			FileName:     name,
+			Data:         content,
+			TotalSize:    int64(len(content)),
+			ExpectedHash: calculateTestHash(content),
+			IsLast:       true,
+		})
+		require.NoError(t, err)
+		return fileReceiver.ProcessChunk(data)
+	}
+
+	// A file sent with its own hash passes integrity checks, but is not the one signed
+	err = send("/src/other/signed.txt", "signed.txt", []byte("a file the user never saw"))
+	require.ErrorIs(t, err, transfer.ErrChecksumMismatch)
+	assert.ErrorIs(t, err, crypto.ErrMerkleProofInvalid)
+	assert.NoFileExists(t, filepath.Join(tempDir, "signed.txt"))
+
+	require.NoError(t, send("/src/signed.txt", "signed.txt", signedContent))
+	saved, err := os.ReadFile(filepath.Join(tempDir, "signed.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, signedContent, saved)
+
+	// Files the user never accepted fail, as do files sent without a checksum
+	err = send("/src/added.txt", "added.txt", []byte("never offered"))
+	assert.ErrorIs(t, err, crypto.ErrMerkleProofInvalid)
+	data, err := serializer.Marshal(&transfer.ChunkMessage{
+		Type:      transfer.ChunkData,
+		FileID:    "/src/unhashed/signed.txt",
+		FileName:  "signed.txt",
+		Data:      signedContent,
+		TotalSize: int64(len(signedContent)),
+		IsLast:    true,
+	})
+	require.NoError(t, err)
+	assert.ErrorIs(t, fileReceiver.ProcessChunk(data), transfer.ErrChecksumMismatch)
+
+	completed, failed, _, _ := fileReceiver.Outcome()
+	assert.Equal(t, 1, completed)
+	assert.Equal(t, 3, failed)
+}
+
+func TestFileReceiver_BoundsChunksByTheAcceptedFiles(t *testing.T) {
+	sourceDir := t.TempDir()
+	content := []byte("the file the user accepted")
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "signed.txt"), content, 0644))
+	signedFiles, err := crypto.CreateSignedFileStructure([]string{filepath.Join(sourceDir, "signed.txt")})
+	require.NoError(t, err)
+	subset, err := signedFiles.Subset([]int{0})
+	require.NoError(t, err)
+
+	tempDir := t.TempDir()
+	fileReceiver := NewFileReceiver(tempDir, nil)
+	fileReceiver.SetSignedFiles(subset)
+	size := int64(len(content))
+	chunk := func(fileID string, sequence uint32, offset int64, data []byte, totalSize int64) *transfer.ChunkMessage {
+		return &transfer.ChunkMessage{
+			Type:         transfer.ChunkData,
+			FileID:       fileID,
+			FileName:     "signed.txt",
+			SequenceNo:   sequence,
+			Offset:       offset,
+			Data:         data,
+			TotalSize:    totalSize,
+			ExpectedHash: calculateTestHash(content),
+			IsLast:       offset+int64(len(data)) == totalSize,
+		}
+	}
+
+	// The accepted file's name with another size is refused before anything is written
+	err = fileReceiver.ProcessMessage(chunk("/src/huge/signed.txt", 0, 0, content[:10], 10<<30))
+	assert.ErrorIs(t, err, crypto.ErrMerkleProofInvalid)
+	assert.NoFileExists(t, filepath.Join(tempDir, "signed.txt"))
+
+	require.NoError(t, fileReceiver.ProcessMessage(chunk("/src/signed.txt", 0, 0, content[:10], size)))
+	tests := []struct {
+		name string
+		msg  *transfer.ChunkMessage
+	}{
+		{"Past the end", chunk("/src/signed.txt", 1, 20, []byte("0123456789"), size)},
+		{"Negative offset", chunk("/src/signed.txt", 1, -10, content[:10], size)},
+		{"Other size", chunk("/src/signed.txt", 1, 10, content[10:], 10<<30)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.ErrorIs(t, fileReceiver.ProcessMessage(tt.msg), errChunkOutOfBounds)
+		})
+	}
+
+	require.NoError(t, fileReceiver.ProcessMessage(chunk("/src/signed.txt", 1, 10, content[10:], size)))
+	saved, err := os.ReadFile(filepath.Join(tempDir, "signed.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, content, saved)
+}
+
+func TestFileReceiver_VerifiesFilesOfStructureUpdates(t *testing.T) {
+	sourceDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "a.txt"), []byte("a"), 0644))
+	fsm := transfer.NewFileStructureManager()
+	node, err := fileInfo.CreateNode(sourceDir)
+	require.NoError(t, err)
+	require.NoError(t, fsm.AddFileNode(&node))
+	signer, err := crypto.NewFileStructureSigner()
+	require.NoError(t, err)
+	base, err := signer.SignFileStructureManager(fsm)
+	require.NoError(t, err)
+	chain, err := crypto.NewStructureChain(base)
+	require.NoError(t, err)
+	subset, err := base.Subset([]int{0})
+	require.NoError(t, err)
+
+	fileReceiver := NewFileReceiver(t.TempDir(), nil)
+	fileReceiver.SetSignedFiles(subset)
+	fileReceiver.SetStructureChain(chain)
+	serializer := transfer.NewJSONSerializer()
+	send := func(fileID, name string, content []byte) error {
+		data, err := serializer.Marshal(&transfer.ChunkMessage{
+			Type:         transfer.ChunkData,
+			FileID:       fileID,
+			FileName:     name,
+			Data:         content,
+			TotalSize:    int64(len(content)),
+			ExpectedHash: calculateTestHash(content),
+			IsLast:       true,
+		})
+		require.NoError(t, err)
+		return fileReceiver.ProcessChunk(data)
+	}
+
+	// Before the update adds it, b.txt is not one of the accepted files
+	assert.ErrorIs(t, send("/src/early/b.txt", "b.txt", []byte("b")), crypto.ErrMerkleProofInvalid)
+
+	newPath := filepath.Join(sourceDir, "b.txt")
+	require.NoError(t, os.WriteFile(newPath, []byte("b"), 0644))
+	added, err := fileInfo.CreateNode(newPath)
+	require.NoError(t, err)
+	delta, err := crypto.NewStructureDeltaSigner(signer, base).Sign([]crypto.StructureDeltaChange{
+		{Op: crypto.DeltaAdd, Path: node.Name + "/b.txt", Node: &added},
+	})
+	require.NoError(t, err)
+	deltaJSON, err := json.Marshal(delta)
+	require.NoError(t, err)
+	data, err := serializer.Marshal(&transfer.ChunkMessage{Type: transfer.StructureUpdate, Data: deltaJSON})
+	require.NoError(t, err)
+	require.NoError(t, fileReceiver.ProcessChunk(data))
+
+	// The update signs the content of b.txt, not just its name
+	assert.ErrorIs(t, send("/src/other/b.txt", "b.txt", []byte("not b")), crypto.ErrMerkleProofInvalid)
+	require.NoError(t, send(newPath, "b.txt", []byte("b")))
+	require.NoError(t, send(filepath.Join(sourceDir, "a.txt"), "a.txt", []byte("a")))
+
+	completed, failed, _, _ := fileReceiver.Outcome()
+	assert.Equal(t, 2, completed)
+	assert.Equal(t, 2, failed)
+}
@@ -0,0 +1,31 @@
+package receiver
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+
+	"github.com/rescp17/lanFileSharer/pkg/storage"
+)
+
+// storeFile hands a verified file to the storage backend, recording where it landed if it
+// left the output directory; fr.mu must be held
+func (fr *FileReceiver) storeFile(fileReception *FileReception) error {
+	key, err := filepath.Rel(fr.outputDir, fileReception.OutputPath)
+	if err != nil {
+		return fmt.Errorf("failed to store %s: %w", fileReception.FileName, err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), storage.DefaultTimeout)
+	defer cancel()
+	location, err := fr.storage.Store(ctx, fileReception.OutputPath, filepath.ToSlash(key), fileReception.ExpectedHash)
+	if err != nil {
+		slog.Error("Failed to store received file, keeping it in the output directory", "fileName", fileReception.FileName, "storage", fr.storage, "error", err)
+		return fmt.Errorf("failed to store %s in %s: %w", fileReception.FileName, fr.storage, err)
+	}
+	if location != fileReception.OutputPath {
+		slog.Info("Stored received file", "fileName", fileReception.FileName, "location", location)
+		fileReception.Location = location
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package receiver
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/rescp17/lanFileSharer/internal/config"
+)
+
+// templatePlaceholder matches the placeholders of an output template
+var templatePlaceholder = regexp.MustCompile(`\{[^{}]*\}`)
+
+// templateFields are the placeholders an output template may use
+var templateFields = map[string]func(TemplateValues) string{
+	"{date}":     func(v TemplateValues) string { return v.Time.Format("2006-01-02") },
+	"{time}":     func(v TemplateValues) string { return v.Time.Format("150405") },
+	"{sender}":   func(v TemplateValues) string { return v.Sender },
+	"{original}": func(v TemplateValues) string { return v.Original },
+	"{name}": func(v TemplateValues) string {
+		return strings.TrimSuffix(v.Original, filepath.Ext(v.Original))
+	},
+	"{ext}": func(v TemplateValues) string { return strings.TrimPrefix(filepath.Ext(v.Original), ".") },
+}
+
+// unknownSender replaces {sender} when the sender did not give its name
+const unknownSender = "unknown-sender"
+
+// OutputTemplate lays out received files under the output directory,
+// e.g. "{date}/{sender}/{original}" saves report.pdf as 2024-06-01/alices-laptop/report.pdf
+type OutputTemplate struct {
+	pattern string
+}
+
+// TemplateValues are what the placeholders of an OutputTemplate expand to
+type TemplateValues struct {
+	Time     time.Time // When the session was accepted
+	Sender   string    // Name the sender gave, usually its hostname
+	Original string    // File name as sent
+}
+
+// ParseOutputTemplate checks pattern and returns its template. The pattern must name
+// the file with {original} or {name}, so the files of a session do not all collide.
+func ParseOutputTemplate(pattern string) (*OutputTemplate, error) {
+	pattern = filepath.ToSlash(strings.TrimSpace(pattern))
+	if pattern == "" {
+		return nil, errors.New("output template is empty")
+	}
+	if strings.HasPrefix(pattern, "/") || filepath.IsAbs(pattern) {
+		return nil, fmt.Errorf("output template %q must be relative to the output directory", pattern)
+	}
+	for _, placeholder := range templatePlaceholder.FindAllString(pattern, -1) {
+		if _, ok := templateFields[placeholder]; !ok {
+			return nil, fmt.Errorf("output template %q: unknown placeholder %s", pattern, placeholder)
+		}
+	}
+	if strings.ContainsAny(templatePlaceholder.ReplaceAllString(pattern, ""), "{}") {
+		return nil, fmt.Errorf("output template %q has an unmatched brace", pattern)
+	}
+	if !strings.Contains(pattern, "{original}") && !strings.Contains(pattern, "{name}") {
+		return nil, fmt.Errorf("output template %q must contain {original} or {name}", pattern)
+	}
+	for _, segment := range strings.Split(pattern, "/") {
+		if segment == ".." {
+			return nil, fmt.Errorf("output template %q must not leave the output directory", pattern)
+		}
+	}
+	return &OutputTemplate{pattern: pattern}, nil
+}
+
+// String returns the template pattern
+func (t *OutputTemplate) String() string {
+	return t.pattern
+}
+
+// Expand returns the path of a received file relative to the output directory.
+// Values are stripped of path separators, so they cannot add or climb directories.
+func (t *OutputTemplate) Expand(values TemplateValues) (string, error) {
+	if values.Sender == "" {
+		values.Sender = unknownSender
+	}
+	expanded := templatePlaceholder.ReplaceAllStringFunc(t.pattern, func(placeholder string) string {
+		return sanitizeSegment(templateFields[placeholder](values))
+	})
+
+	var segments []string
+	for _, segment := range strings.Split(expanded, "/") {
+		switch segment {
+		case "", ".":
+			continue
+		case "..":
+			return "", fmt.Errorf("output template %q expands outside the output directory", t.pattern)
+		}
+		segments = append(segments, segment)
+	}
+	if len(segments) == 0 {
+		return "", fmt.Errorf("output template %q expands to an empty path", t.pattern)
+	}
+	return filepath.Join(segments...), nil
+}
+
+// sanitizeSegment makes value safe to use as part of a single path segment
+func sanitizeSegment(value string) string {
+	value = strings.Map(func(r rune) rune {
+		switch {
+		case r == '/' || r == '\\' || r == ':' || r < ' ':
+			return '_'
+		}
+		return r
+	}, value)
+	if value == "." || value == ".." {
+		return "_"
+	}
+	return value
+}
+
+// LoadOutputTemplate returns the output template configured in cfg, or nil to save files
+// directly in the output directory. Invalid templates are ignored with a warning.
+func LoadOutputTemplate(cfg config.Config) *OutputTemplate {
+	if cfg.OutputTemplate == "" {
+		return nil
+	}
+	template, err := ParseOutputTemplate(cfg.OutputTemplate)
+	if err != nil {
+		slog.Warn("Ignoring invalid output template", "error", err)
+		return nil
+	}
+	return template
+}
@@ -0,0 +1,61 @@
+package receiver
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOutputTemplate_Expand(t *testing.T) {
+	template, err := ParseOutputTemplate("{date}/{sender}/{original}")
+	require.NoError(t, err)
+
+	values := TemplateValues{
+		Time:     time.Date(2024, 6, 1, 14, 30, 5, 0, time.UTC),
+		Sender:   "alices-laptop",
+		Original: "report.pdf",
+	}
+	path, err := template.Expand(values)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join("2024-06-01", "alices-laptop", "report.pdf"), path)
+
+	template, err = ParseOutputTemplate("{name}-{time}.{ext}")
+	require.NoError(t, err)
+	path, err = template.Expand(values)
+	require.NoError(t, err)
+	assert.Equal(t, "report-143005.pdf", path)
+}
+
+func TestOutputTemplate_StaysInOutputDirectory(t *testing.T) {
+	template, err := ParseOutputTemplate("{sender}/{original}")
+	require.NoError(t, err)
+
+	path, err := template.Expand(TemplateValues{Sender: "../../etc", Original: "passwd"})
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(".._.._etc", "passwd"), path, "separators in values are replaced")
+
+	path, err = template.Expand(TemplateValues{Sender: "..", Original: "file.txt"})
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join("_", "file.txt"), path)
+
+	path, err = template.Expand(TemplateValues{Original: "file.txt"})
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(unknownSender, "file.txt"), path)
+}
+
+func TestParseOutputTemplate_Invalid(t *testing.T) {
+	for _, pattern := range []string{
+		"",
+		"/abs/{original}",
+		"{date}/{sender}",
+		"{date}/{unknown}/{original}",
+		"{date/{original}",
+		"../{original}",
+	} {
+		_, err := ParseOutputTemplate(pattern)
+		assert.Error(t, err, pattern)
+	}
+}
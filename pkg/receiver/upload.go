@@ -0,0 +1,167 @@
+package receiver
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime/multipart"
+	"time"
+
+	"github.com/rescp17/lanFileSharer/api"
+	"github.com/rescp17/lanFileSharer/internal/app_events/receiver"
+	"github.com/rescp17/lanFileSharer/pkg/fileInfo"
+	"github.com/rescp17/lanFileSharer/pkg/transfer"
+	"github.com/rescp17/lanFileSharer/pkg/webhook"
+)
+
+// receiveUpload saves the files of a browser upload from the device at sender like those of
+// any session: laid out by the output template, verified, scanned, stored and recorded as
+// recent files, with webhooks told about the upload as a session
+func (a *App) receiveUpload(ctx context.Context, sender string, files *multipart.Reader) (api.UploadResult, error) {
+	if a.storage != nil {
+		if err := a.storage.Validate(ctx); err != nil {
+			return api.UploadResult{}, fmt.Errorf("storage %s unavailable: %w", a.storage, err)
+		}
+	}
+	fr := NewFileReceiver(a.outputPath, a.uiMessages)
+	fr.SetEventBus(a.bus)
+	fr.SetWriteVerification(a.verifyWrites)
+	fr.SetContentStore(a.contentStore)
+	fr.SetScanner(a.scanner)
+	fr.SetStorage(a.storage)
+	if a.template != nil {
+		fr.SetOutputTemplate(a.template, TemplateValues{Time: time.Now(), Sender: sender})
+	}
+	fr.SetRecentFiles(a.recent, sender)
+
+	start := webhook.Event{Event: webhook.EventSessionStart, Role: webhook.RoleReceiver, Time: time.Now(), Peer: sender}
+	if a.webhooks != nil {
+		go a.webhooks.Notify(context.Background(), start)
+	}
+	slog.Info("Receiving a browser upload", "sender", sender)
+
+	var uploadErr error
+	for index := 0; ; {
+		part, err := files.NextPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			uploadErr = fmt.Errorf("failed to read upload: %w", err)
+			break
+		}
+		if part.FileName() == "" {
+			// Form fields carry no file
+			part.Close()
+			continue
+		}
+		err = receiveUploadedFile(fr, part, index)
+		index++
+		part.Close()
+		if err != nil {
+			uploadErr = err
+			break
+		}
+	}
+
+	fr.RecordRecent()
+	completed, failed, received, _ := fr.Outcome()
+	if a.webhooks != nil {
+		end := sessionEndEvent(start, failed, received, uploadErr == nil)
+		end.Files = completed + failed
+		go a.webhooks.Notify(context.Background(), end)
+	}
+	a.uiMessages <- receiver.UploadReceivedMsg{Sender: sender, Files: completed, Failed: failed, Bytes: received, Err: uploadErr}
+	return api.UploadResult{Files: completed, Failed: failed, Bytes: received}, uploadErr
+}
+
+// receiveUploadedFile feeds the file of part, the index-th of the upload, to fr as a stream of
+// chunks whose size and hash are known at its end, as those of a stdin stream are. Files fr
+// fails are counted by it and the upload goes on; the error is for an upload that broke off.
+func receiveUploadedFile(fr *FileReceiver, part *multipart.Part, index int) error {
+	name := part.FileName()
+	// Browsers send files of different folders under the same name, which must not be
+	// mistaken for chunks of one file
+	fileID := fmt.Sprintf("%d/%s", index, name)
+	hasher := sha256.New()
+	buf := make([]byte, transfer.DefaultChunkSize)
+	var offset int64
+	for sequence := uint32(0); ; sequence++ {
+		n, err := readChunk(part, buf)
+		last := errors.Is(err, io.EOF)
+		if err != nil && !last {
+			fr.discardUpload(fileID, err)
+			return fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		hasher.Write(buf[:n])
+		msg := &transfer.ChunkMessage{
+			Type:       transfer.ChunkData,
+			FileID:     fileID,
+			FileName:   name,
+			SequenceNo: sequence,
+			Offset:     offset,
+			Data:       buf[:n],
+			TotalSize:  fileInfo.UnknownSize,
+		}
+		offset += int64(n)
+		if last {
+			msg.IsLast = true
+			msg.TotalSize = offset
+			msg.ExpectedHash = hex.EncodeToString(hasher.Sum(nil))
+		}
+		if err := fr.ProcessMessage(msg); err != nil {
+			slog.Warn("Failed to save uploaded file", "fileName", name, "error", err)
+			// The rest of the part is skipped, so nothing else would close the file
+			fr.discardUpload(fileID, nil)
+			return nil
+		}
+		if last {
+			return nil
+		}
+	}
+}
+
+// readChunk fills buf from r, unless r ends first. Only io.EOF ends it: a body that broke off
+// fails with io.ErrUnexpectedEOF, which io.ReadFull would not tell from a short last chunk.
+func readChunk(r io.Reader, buf []byte) (int, error) {
+	n := 0
+	for n < len(buf) {
+		read, err := r.Read(buf[n:])
+		n += read
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+// discardUpload removes what was written of the file fileID of an upload that failed or broke
+// off, which unlike a session's file cannot be resumed. cause fails the file, unless it is nil
+// because fr failed it already.
+func (fr *FileReceiver) discardUpload(fileID string, cause error) {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	fileReception, ok := fr.currentFiles[fileID]
+	if !ok {
+		return
+	}
+	delete(fr.currentFiles, fileID)
+	if err := fileReception.File.Close(); err != nil {
+		slog.Warn("Failed to close partial upload", "fileName", fileReception.FileName, "error", err)
+	}
+	if err := fr.cleanupCorruptedFile(fileReception); err != nil {
+		slog.Error("Failed to cleanup partial upload", "fileName", fileReception.FileName, "error", err)
+	}
+	fr.releaseOutputName(fileReception)
+	fr.receivedBytes -= fileReception.ReceivedSize
+	if cause == nil {
+		return
+	}
+	fr.failedFiles++
+	fileReception.Status = StatusFailed
+	fr.publishProgress(fileReception, transfer.TransferStateFailed, cause)
+}
@@ -0,0 +1,105 @@
+package receiver
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// uploadReader returns a multipart reader of files, in order, with only the first limit bytes
+// of the body when limit is positive
+func uploadReader(t *testing.T, limit int, names []string, contents [][]byte) *multipart.Reader {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	for i, name := range names {
+		part, err := writer.CreateFormFile("files", name)
+		require.NoError(t, err)
+		_, err = part.Write(contents[i])
+		require.NoError(t, err)
+	}
+	require.NoError(t, writer.Close())
+	data := body.Bytes()
+	if limit > 0 {
+		data = data[:limit]
+	}
+	return multipart.NewReader(bytes.NewReader(data), writer.Boundary())
+}
+
+func TestReceiveUploadedFile(t *testing.T) {
+	dir := t.TempDir()
+	fr := NewFileReceiver(dir, nil)
+	// Several chunks, whose size is only known at the end
+	big := bytes.Repeat([]byte("0123456789"), 20000)
+	files := uploadReader(t, 0, []string{"big.bin", "../empty.txt"}, [][]byte{big, nil})
+
+	for index := 0; ; index++ {
+		part, err := files.NextPart()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		require.NoError(t, receiveUploadedFile(fr, part, index))
+	}
+
+	saved, err := os.ReadFile(filepath.Join(dir, "big.bin"))
+	require.NoError(t, err)
+	assert.Equal(t, big, saved)
+	info, err := os.Stat(filepath.Join(dir, "empty.txt"))
+	require.NoError(t, err, "the name is kept within the output directory")
+	assert.Zero(t, info.Size())
+
+	completed, failed, received, _ := fr.Outcome()
+	assert.Equal(t, 2, completed)
+	assert.Zero(t, failed)
+	assert.Equal(t, int64(len(big)), received)
+}
+
+func TestReceiveUploadedFile_BrokenOff(t *testing.T) {
+	dir := t.TempDir()
+	fr := NewFileReceiver(dir, nil)
+	big := bytes.Repeat([]byte("0123456789"), 20000)
+	files := uploadReader(t, 100000, []string{"big.bin"}, [][]byte{big})
+
+	part, err := files.NextPart()
+	require.NoError(t, err)
+	assert.Error(t, receiveUploadedFile(fr, part, 0))
+
+	_, err = os.Stat(filepath.Join(dir, "big.bin"))
+	assert.True(t, os.IsNotExist(err), "the partial file is removed")
+	completed, failed, received, _ := fr.Outcome()
+	assert.Zero(t, completed)
+	assert.Equal(t, 1, failed)
+	assert.Zero(t, received)
+}
+
+func TestReceiveUploadedFile_SameName(t *testing.T) {
+	dir := t.TempDir()
+	fr := NewFileReceiver(dir, nil)
+	files := uploadReader(t, 0, []string{"photo.jpg", "photo.jpg"}, [][]byte{[]byte("first"), []byte("second")})
+
+	for index := 0; ; index++ {
+		part, err := files.NextPart()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		require.NoError(t, receiveUploadedFile(fr, part, index))
+	}
+
+	first, err := os.ReadFile(filepath.Join(dir, "photo.jpg"))
+	require.NoError(t, err)
+	assert.Equal(t, "first", string(first))
+	second, err := os.ReadFile(filepath.Join(dir, "photo (1).jpg"))
+	require.NoError(t, err, "a file of the same name is saved next to the first")
+	assert.Equal(t, "second", string(second))
+
+	completed, failed, _, _ := fr.Outcome()
+	assert.Equal(t, 2, completed)
+	assert.Zero(t, failed)
+}
@@ -0,0 +1,63 @@
+package receiver
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/rescp17/lanFileSharer/pkg/crypto"
+	"github.com/rescp17/lanFileSharer/pkg/webhook"
+)
+
+// notifySessionStart tells webhooks that the accepted session with signedFiles starts and
+// remembers it so its outcome can be reported
+func (a *App) notifySessionStart(signedFiles *crypto.SignedFileStructure) {
+	if a.webhooks == nil {
+		return
+	}
+	senderName, err := a.stateManager.GetSenderName()
+	if err != nil {
+		slog.Warn("Could not get sender name", "error", err)
+	}
+	event := webhook.Event{Event: webhook.EventSessionStart, Role: webhook.RoleReceiver, Time: time.Now(), Peer: senderName}
+	if signedFiles != nil {
+		event.Files = len(signedFiles.Files)
+		event.Bytes = expectedBytes(signedFiles.Files)
+	}
+
+	a.receiverMu.Lock()
+	a.webhookStart = &event
+	a.receiverMu.Unlock()
+	// The answer to the sender must not wait for webhooks
+	go a.webhooks.Notify(context.Background(), event)
+}
+
+// notifySessionEnd tells webhooks how the started session ended; a.receiverMu must be held
+func (a *App) notifySessionEnd(failed int, received int64, complete bool) {
+	if a.webhookStart == nil {
+		return
+	}
+	event := sessionEndEvent(*a.webhookStart, failed, received, complete)
+	a.webhookStart = nil
+	go a.webhooks.Notify(context.Background(), event)
+}
+
+// sessionEndEvent describes the outcome of the session that started with start, in the
+// terms the audit log records it in
+func sessionEndEvent(start webhook.Event, failed int, received int64, complete bool) webhook.Event {
+	event := start
+	event.Event = webhook.EventSessionComplete
+	event.Time = time.Now()
+	event.Bytes = received
+	event.FailedFiles = failed
+	switch {
+	case !complete:
+		event.Event = webhook.EventSessionFail
+		event.Error = "connection closed before all files arrived"
+	case failed > 0:
+		event.Event = webhook.EventSessionFail
+		event.Error = fmt.Sprintf("%d files failed", failed)
+	}
+	return event
+}
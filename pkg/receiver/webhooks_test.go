@@ -0,0 +1,30 @@
+package receiver
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/rescp17/lanFileSharer/pkg/webhook"
+)
+
+func TestSessionEndEvent(t *testing.T) {
+	start := webhook.Event{Event: webhook.EventSessionStart, Role: webhook.RoleReceiver, Peer: "laptop", Files: 3, Bytes: 300}
+
+	completed := sessionEndEvent(start, 0, 300, true)
+	assert.Equal(t, webhook.EventSessionComplete, completed.Event)
+	assert.Equal(t, "laptop", completed.Peer)
+	assert.Equal(t, 3, completed.Files)
+	assert.Equal(t, int64(300), completed.Bytes)
+	assert.Empty(t, completed.Error)
+
+	withFailures := sessionEndEvent(start, 1, 200, true)
+	assert.Equal(t, webhook.EventSessionFail, withFailures.Event)
+	assert.Equal(t, 1, withFailures.FailedFiles)
+	assert.Equal(t, "1 files failed", withFailures.Error)
+
+	interrupted := sessionEndEvent(start, 0, 100, false)
+	assert.Equal(t, webhook.EventSessionFail, interrupted.Event)
+	assert.Equal(t, int64(100), interrupted.Bytes)
+	assert.Equal(t, "connection closed before all files arrived", interrupted.Error)
+}
@@ -0,0 +1,43 @@
+package receiver
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand/v2"
+	"os"
+
+	"github.com/rescp17/lanFileSharer/pkg/transfer"
+)
+
+// SetWriteVerification makes the receiver read back fraction (0-1) of the chunks it writes
+// and compare them with the data received, to catch storage that silently corrupts data
+func (fr *FileReceiver) SetWriteVerification(fraction float64) {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	fr.verifyFraction = fraction
+}
+
+// shouldVerifyWrite decides whether the chunk just written is read back
+func (fr *FileReceiver) shouldVerifyWrite() bool {
+	switch {
+	case fr.verifyFraction <= 0:
+		return false
+	case fr.verifyFraction >= 1:
+		return true
+	}
+	return rand.Float64() < fr.verifyFraction
+}
+
+// verifyWrite reads data back from file at offset and compares it with what was written.
+// The cached pages are dropped first where the platform allows, so the read reaches the device.
+func verifyWrite(file *os.File, offset int64, data []byte) error {
+	dropCachedPages(file, offset, int64(len(data)))
+	readBack := make([]byte, len(data))
+	if _, err := file.ReadAt(readBack, offset); err != nil {
+		return fmt.Errorf("failed to read back chunk at offset %d: %w", offset, transfer.ClassifyIOError(err))
+	}
+	if !bytes.Equal(readBack, data) {
+		return fmt.Errorf("%w: chunk at offset %d reads back differently from disk", transfer.ErrChecksumMismatch, offset)
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package receiver
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rescp17/lanFileSharer/pkg/transfer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyWrite(t *testing.T) {
+	file, err := os.Create(filepath.Join(t.TempDir(), "chunk.bin"))
+	require.NoError(t, err)
+	defer file.Close()
+	_, err = file.WriteAt([]byte("hello world"), 0)
+	require.NoError(t, err)
+	require.NoError(t, file.Sync())
+
+	assert.NoError(t, verifyWrite(file, 6, []byte("world")))
+	assert.ErrorIs(t, verifyWrite(file, 6, []byte("WORLD")), transfer.ErrChecksumMismatch)
+}
+
+func TestFileReceiver_WriteVerification(t *testing.T) {
+	fileReceiver := NewFileReceiver(t.TempDir(), nil)
+	assert.False(t, fileReceiver.shouldVerifyWrite(), "verification is off by default")
+	fileReceiver.SetWriteVerification(1)
+	assert.True(t, fileReceiver.shouldVerifyWrite())
+
+	content := []byte("verified on disk")
+	data, err := transfer.NewJSONSerializer().Marshal(&transfer.ChunkMessage{
+		Type:         transfer.ChunkData,
+		FileID:       "/src/file.txt",
+		FileName:     "file.txt",
+		SequenceNo:   1,
+		Data:         content,
+		TotalSize:    int64(len(content)),
+		ExpectedHash: calculateTestHash(content),
+		IsLast:       true,
+	})
+	require.NoError(t, err)
+	require.NoError(t, fileReceiver.ProcessChunk(data))
+	completed, failed, _, _ := fileReceiver.Outcome()
+	assert.Equal(t, 1, completed)
+	assert.Zero(t, failed)
+}
@@ -0,0 +1,146 @@
+// Package recent keeps the paths of the most recently received files, so they can be picked
+// again right after a transfer, for example to paste into another command.
+package recent
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/rescp17/lanFileSharer/internal/config"
+)
+
+// FileName is the list of recently received files in the application config directory
+const FileName = "recent.json"
+
+// MaxEntries is how many received files the list keeps, the oldest are dropped first
+const MaxEntries = 50
+
+// Entry is a received file
+type Entry struct {
+	Path       string    `json:"path"` // Absolute path the file was saved at
+	Size       int64     `json:"size"`
+	Sender     string    `json:"sender,omitempty"` // Name of the sending device, if it sent one
+	ReceivedAt time.Time `json:"received_at"`
+}
+
+// Store is the list of recently received files. Every change reads the file again, so
+// receivers running side by side, such as the TUI and a headless service, keep each other's entries.
+type Store struct {
+	path string
+	mu   sync.Mutex
+}
+
+// DefaultPath returns the default location of the list
+func DefaultPath() (string, error) {
+	dir, err := config.Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, FileName), nil
+}
+
+// OpenDefault opens the list in the config directory. If its location cannot be resolved,
+// which is logged, it returns nil and received files are not recorded.
+func OpenDefault() *Store {
+	path, err := DefaultPath()
+	if err != nil {
+		slog.Warn("Could not resolve the recent files list, received files are not recorded", "error", err)
+		return nil
+	}
+	return Open(path)
+}
+
+// Open returns the list at path, which is created with the first recorded file
+func Open(path string) *Store {
+	return &Store{path: path}
+}
+
+// List returns the recorded files, the most recently received first
+func (s *Store) List() ([]Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.load()
+}
+
+// Record adds received files to the front of the list, replacing earlier entries of the same
+// paths. A nil store records nothing.
+func (s *Store) Record(entries ...Entry) error {
+	if s == nil || len(entries) == 0 {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	existing, err := s.load()
+	if err != nil {
+		return err
+	}
+
+	merged := make([]Entry, 0, len(entries)+len(existing))
+	seen := make(map[string]bool, len(entries)+len(existing))
+	// The last of the recorded entries is the newest
+	for i := len(entries) - 1; i >= 0; i-- {
+		if !seen[entries[i].Path] {
+			seen[entries[i].Path] = true
+			merged = append(merged, entries[i])
+		}
+	}
+	for _, entry := range existing {
+		if !seen[entry.Path] {
+			seen[entry.Path] = true
+			merged = append(merged, entry)
+		}
+	}
+	if len(merged) > MaxEntries {
+		merged = merged[:MaxEntries]
+	}
+	return s.save(merged)
+}
+
+// Clear empties the list
+func (s *Store) Clear() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.save([]Entry{})
+}
+
+// load reads the list; a missing file is an empty list. s.mu must be held.
+func (s *Store) load() ([]Entry, error) {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read recent files: %w", err)
+	}
+	var entries []Entry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse recent files list %s: %w", s.path, err)
+	}
+	return entries, nil
+}
+
+// save writes entries, replacing the file in one step so a concurrent load never sees half
+// of it. s.mu must be held.
+func (s *Store) save(entries []Entry) error {
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal recent files: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("failed to create recent files directory: %w", err)
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write recent files: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("failed to write recent files: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package recent
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore_RecordNewestFirst(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	store := Open(path)
+
+	entries, err := store.List()
+	require.NoError(t, err)
+	assert.Empty(t, entries, "a missing list is empty")
+
+	now := time.Now()
+	require.NoError(t, store.Record(Entry{Path: "/in/a.txt", ReceivedAt: now}, Entry{Path: "/in/b.txt", ReceivedAt: now}))
+	require.NoError(t, store.Record(Entry{Path: "/in/a.txt", Size: 2, Sender: "laptop", ReceivedAt: now}))
+
+	// Another receiver sees the same list
+	entries, err = Open(path).List()
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "/in/a.txt", entries[0].Path, "received again, so newest")
+	assert.Equal(t, int64(2), entries[0].Size)
+	assert.Equal(t, "/in/b.txt", entries[1].Path)
+
+	require.NoError(t, store.Clear())
+	entries, err = store.List()
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestStore_KeepsMaxEntries(t *testing.T) {
+	store := Open(filepath.Join(t.TempDir(), FileName))
+	for i := range MaxEntries + 5 {
+		require.NoError(t, store.Record(Entry{Path: fmt.Sprintf("/in/%d", i)}))
+	}
+	entries, err := store.List()
+	require.NoError(t, err)
+	require.Len(t, entries, MaxEntries)
+	assert.Equal(t, fmt.Sprintf("/in/%d", MaxEntries+4), entries[0].Path)
+}
+
+func TestStore_NilRecordsNothing(t *testing.T) {
+	var store *Store
+	assert.NoError(t, store.Record(Entry{Path: "/in/a.txt"}))
+}
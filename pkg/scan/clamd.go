@@ -0,0 +1,103 @@
+package scan
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+)
+
+// clamdChunkSize is the size files are streamed to clamd in, well below its StreamMaxLength
+const clamdChunkSize = 64 * 1024
+
+// Clamd scans files with a clamd daemon. The content is streamed with INSTREAM, so clamd
+// needs no access to the output directory.
+type Clamd struct {
+	network string
+	address string
+}
+
+// NewClamd returns a scanner using clamd at address, a Unix socket path or host:port
+func NewClamd(address string) *Clamd {
+	network := "tcp"
+	if strings.ContainsRune(address, os.PathSeparator) || strings.HasPrefix(address, "/") {
+		network = "unix"
+	}
+	return &Clamd{network: network, address: address}
+}
+
+// Scan streams the file at path to clamd and returns its verdict
+func (c *Clamd) Scan(ctx context.Context, path string) (Result, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return Result{}, fmt.Errorf("%w: %w", ErrScanFailed, err)
+	}
+	defer file.Close()
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, c.network, c.address)
+	if err != nil {
+		return Result{}, fmt.Errorf("%w: failed to reach clamd: %w", ErrScanFailed, err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			return Result{}, fmt.Errorf("%w: %w", ErrScanFailed, err)
+		}
+	}
+
+	if err := streamToClamd(conn, file); err != nil {
+		return Result{}, fmt.Errorf("%w: failed to send file to clamd: %w", ErrScanFailed, err)
+	}
+	reply, err := io.ReadAll(conn)
+	if err != nil {
+		return Result{}, fmt.Errorf("%w: failed to read clamd reply: %w", ErrScanFailed, err)
+	}
+	return parseClamdReply(string(reply))
+}
+
+// streamToClamd sends the INSTREAM command followed by the content of r in length-prefixed
+// chunks, ending with an empty chunk
+func streamToClamd(w io.Writer, r io.Reader) error {
+	if _, err := io.WriteString(w, "zINSTREAM\x00"); err != nil {
+		return err
+	}
+	buf := make([]byte, 4+clamdChunkSize)
+	for {
+		n, err := r.Read(buf[4:])
+		if n > 0 {
+			binary.BigEndian.PutUint32(buf[:4], uint32(n))
+			if _, err := w.Write(buf[:4+n]); err != nil {
+				return err
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	_, err := w.Write([]byte{0, 0, 0, 0})
+	return err
+}
+
+// parseClamdReply reads the verdict from a reply such as "stream: OK" or
+// "stream: Eicar-Signature FOUND"
+func parseClamdReply(reply string) (Result, error) {
+	reply = strings.TrimSpace(strings.TrimRight(reply, "\x00"))
+	verdict := strings.TrimSpace(strings.TrimPrefix(reply, "stream:"))
+	switch {
+	case verdict == "OK":
+		return Result{}, nil
+	case strings.HasSuffix(verdict, " FOUND"):
+		return Result{Infected: true, Signature: strings.TrimSuffix(verdict, " FOUND")}, nil
+	case strings.HasSuffix(verdict, " ERROR"):
+		return Result{}, fmt.Errorf("%w: clamd: %s", ErrScanFailed, strings.TrimSuffix(verdict, " ERROR"))
+	default:
+		return Result{}, fmt.Errorf("%w: unexpected clamd reply %q", ErrScanFailed, reply)
+	}
+}
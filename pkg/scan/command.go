@@ -0,0 +1,56 @@
+package scan
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// Command scans files with an external command, such as "clamscan --no-summary", run through
+// the platform shell with the file's path appended. It exits 0 for clean files and 1 for
+// infected ones, as clamscan does; the first line it prints names what was found.
+type Command struct {
+	command string
+}
+
+// NewCommand returns a scanner running command
+func NewCommand(command string) *Command {
+	return &Command{command: command}
+}
+
+// Scan runs the command on the file at path and returns its verdict
+func (c *Command) Scan(ctx context.Context, path string) (Result, error) {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", c.command+` "`+path+`"`)
+	} else {
+		// The path is passed as an argument, never parsed by the shell
+		cmd = exec.CommandContext(ctx, "sh", "-c", c.command+` "$@"`, "sh", path)
+	}
+	cmd.Env = append(os.Environ(), "LANFILESHARER_FILE="+path)
+
+	output, err := cmd.CombinedOutput()
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		return Result{}, nil
+	case errors.As(err, &exitErr) && exitErr.ExitCode() == 1:
+		return Result{Infected: true, Signature: firstLine(string(output))}, nil
+	default:
+		return Result{}, fmt.Errorf("%w: %w (output: %s)", ErrScanFailed, err, strings.TrimSpace(string(output)))
+	}
+}
+
+// firstLine returns the first line of output that is not empty
+func firstLine(output string) string {
+	for _, line := range strings.Split(output, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			return line
+		}
+	}
+	return ""
+}
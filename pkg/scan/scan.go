@@ -0,0 +1,94 @@
+// Package scan checks received files for malware with clamd or an external command before
+// the receiver completes them, and moves the files it flags into quarantine.
+package scan
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rescp17/lanFileSharer/internal/config"
+)
+
+// QuarantineDir is the directory in the output directory flagged files are moved to
+const QuarantineDir = ".quarantine"
+
+// DefaultTimeout bounds the scan of one file
+const DefaultTimeout = 10 * time.Minute
+
+// ErrScanFailed is returned when a file could not be scanned, e.g. because clamd is down
+var ErrScanFailed = errors.New("virus scan failed")
+
+// Result is the verdict of a scanner on a file
+type Result struct {
+	Infected  bool
+	Signature string // Name of what was found, if the scanner told
+}
+
+// Scanner checks a file for malware
+type Scanner interface {
+	Scan(ctx context.Context, path string) (Result, error)
+}
+
+// New returns the scanner described by spec: "clamd:<address>" streams files to clamd at a
+// Unix socket path or host:port, "command:<command>" runs command with the file's path
+// appended. It returns nil if spec is empty.
+func New(spec string) (Scanner, error) {
+	if spec == "" {
+		return nil, nil
+	}
+	kind, arg, ok := strings.Cut(spec, ":")
+	if !ok || strings.TrimSpace(arg) == "" {
+		return nil, fmt.Errorf("invalid virus scanner %q: want clamd:<address> or command:<command>", spec)
+	}
+	switch kind {
+	case "clamd":
+		return NewClamd(strings.TrimSpace(arg)), nil
+	case "command":
+		return NewCommand(strings.TrimSpace(arg)), nil
+	default:
+		return nil, fmt.Errorf("invalid virus scanner %q: unknown kind %q", spec, kind)
+	}
+}
+
+// FromConfig returns the scanner of the virus_scan setting in cfg, or nil if there is none
+// or it is invalid, which is logged
+func FromConfig(cfg config.Config) Scanner {
+	scanner, err := New(cfg.VirusScan)
+	if err != nil {
+		slog.Warn("Invalid virus scanner in config, received files are not scanned", "error", err)
+		return nil
+	}
+	return scanner
+}
+
+// Quarantine moves the file at path into the quarantine directory under root, keeping its
+// name unless a quarantined file has it already, and returns where it went
+func Quarantine(path, root string) (string, error) {
+	dir := filepath.Join(root, QuarantineDir)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", fmt.Errorf("failed to create quarantine directory: %w", err)
+	}
+	name := filepath.Base(path)
+	ext := filepath.Ext(name)
+	dest := filepath.Join(dir, name)
+	for i := 1; ; i++ {
+		if _, err := os.Lstat(dest); os.IsNotExist(err) {
+			break
+		}
+		dest = filepath.Join(dir, fmt.Sprintf("%s (%d)%s", strings.TrimSuffix(name, ext), i, ext))
+	}
+	if err := os.Rename(path, dest); err != nil {
+		return "", fmt.Errorf("failed to quarantine %s: %w", path, err)
+	}
+	// Nobody should open it by accident
+	if err := os.Chmod(dest, 0400); err != nil {
+		slog.Warn("Failed to make quarantined file read-only", "path", dest, "error", err)
+	}
+	return dest, nil
+}
@@ -0,0 +1,152 @@
+package scan
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	scanner, err := New("")
+	assert.NoError(t, err)
+	assert.Nil(t, scanner, "an empty spec scans nothing")
+
+	scanner, err = New("clamd:/run/clamav/clamd.ctl")
+	require.NoError(t, err)
+	assert.Equal(t, &Clamd{network: "unix", address: "/run/clamav/clamd.ctl"}, scanner)
+
+	scanner, err = New("clamd:127.0.0.1:3310")
+	require.NoError(t, err)
+	assert.Equal(t, &Clamd{network: "tcp", address: "127.0.0.1:3310"}, scanner)
+
+	scanner, err = New("command: clamscan --no-summary")
+	require.NoError(t, err)
+	assert.Equal(t, &Command{command: "clamscan --no-summary"}, scanner)
+
+	for _, spec := range []string{"clamd", "clamd:", "antivirus:scan"} {
+		_, err := New(spec)
+		assert.Error(t, err, spec)
+	}
+}
+
+func TestParseClamdReply(t *testing.T) {
+	result, err := parseClamdReply("stream: OK\x00")
+	assert.NoError(t, err)
+	assert.False(t, result.Infected)
+
+	result, err = parseClamdReply("stream: Eicar-Test-Signature FOUND\x00")
+	assert.NoError(t, err)
+	assert.Equal(t, Result{Infected: true, Signature: "Eicar-Test-Signature"}, result)
+
+	_, err = parseClamdReply("INSTREAM size limit exceeded. ERROR\x00")
+	assert.ErrorIs(t, err, ErrScanFailed)
+
+	_, err = parseClamdReply("")
+	assert.ErrorIs(t, err, ErrScanFailed)
+}
+
+// serveClamd answers one INSTREAM request on listener with reply, returning the streamed content
+func serveClamd(t *testing.T, listener net.Listener, reply string) <-chan []byte {
+	received := make(chan []byte, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if !assert.NoError(t, err) {
+			return
+		}
+		defer conn.Close()
+		command := make([]byte, len("zINSTREAM\x00"))
+		_, err = io.ReadFull(conn, command)
+		assert.NoError(t, err)
+		assert.Equal(t, "zINSTREAM\x00", string(command))
+
+		var content bytes.Buffer
+		for {
+			var size uint32
+			if !assert.NoError(t, binary.Read(conn, binary.BigEndian, &size)) || size == 0 {
+				break
+			}
+			_, err := io.CopyN(&content, conn, int64(size))
+			assert.NoError(t, err)
+		}
+		_, err = io.WriteString(conn, reply)
+		assert.NoError(t, err)
+		received <- content.Bytes()
+	}()
+	return received
+}
+
+func TestClamd_Scan(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "report.pdf")
+	content := bytes.Repeat([]byte("lanfilesharer"), clamdChunkSize/4)
+	require.NoError(t, os.WriteFile(path, content, 0644))
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	scanner := NewClamd(listener.Addr().String())
+
+	received := serveClamd(t, listener, "stream: OK\x00")
+	result, err := scanner.Scan(context.Background(), path)
+	require.NoError(t, err)
+	assert.False(t, result.Infected)
+	assert.Equal(t, content, <-received, "the whole file is streamed in chunks")
+
+	received = serveClamd(t, listener, "stream: Eicar-Test-Signature FOUND\x00")
+	result, err = scanner.Scan(context.Background(), path)
+	require.NoError(t, err)
+	assert.Equal(t, Result{Infected: true, Signature: "Eicar-Test-Signature"}, result)
+	<-received
+
+	listener.Close()
+	_, err = scanner.Scan(context.Background(), path)
+	assert.ErrorIs(t, err, ErrScanFailed, "an unreachable clamd fails the scan")
+}
+
+func TestCommand_Scan(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a POSIX shell")
+	}
+	dir := t.TempDir()
+	path := filepath.Join(dir, "it's a file.txt")
+	require.NoError(t, os.WriteFile(path, []byte("hello"), 0644))
+
+	result, err := NewCommand("test -f").Scan(context.Background(), path)
+	require.NoError(t, err, "the path reaches the command as one argument")
+	assert.False(t, result.Infected)
+
+	result, err = NewCommand(`sh -c 'echo "$1: Eicar FOUND"; exit 1' scan`).Scan(context.Background(), path)
+	require.NoError(t, err)
+	assert.True(t, result.Infected)
+	assert.Equal(t, path+": Eicar FOUND", result.Signature)
+
+	_, err = NewCommand("exit 2 #").Scan(context.Background(), path)
+	assert.ErrorIs(t, err, ErrScanFailed)
+}
+
+func TestQuarantine(t *testing.T) {
+	root := t.TempDir()
+	for i := 0; i < 2; i++ {
+		path := filepath.Join(root, "invoice.exe")
+		require.NoError(t, os.WriteFile(path, []byte("payload"), 0644))
+
+		dest, err := Quarantine(path, root)
+		require.NoError(t, err)
+		assert.NoFileExists(t, path)
+		assert.FileExists(t, dest)
+		assert.Equal(t, filepath.Join(root, QuarantineDir), filepath.Dir(dest))
+		if i == 1 {
+			assert.True(t, strings.HasSuffix(dest, "invoice (1).exe"), "earlier quarantined files are kept")
+		}
+	}
+}
@@ -2,10 +2,12 @@ package sender
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
-	"net"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
@@ -15,6 +17,7 @@ import (
 	"github.com/rescp17/lanFileSharer/internal/app_events/sender"
 	"github.com/rescp17/lanFileSharer/pkg/concurrency"
 	"github.com/rescp17/lanFileSharer/pkg/discovery"
+	"github.com/rescp17/lanFileSharer/pkg/events"
 	"github.com/rescp17/lanFileSharer/pkg/fileInfo"
 	"github.com/rescp17/lanFileSharer/pkg/transfer"
 	webrtcPkg "github.com/rescp17/lanFileSharer/pkg/webrtc"
@@ -32,10 +35,28 @@ type App struct {
 	webrtcAPI       *webrtcPkg.WebrtcAPI
 	transferTimeout time.Duration
 	transferWG      sync.WaitGroup // Track active transfer goroutines
+	options         Options
+	bus             *events.Bus // Transfer status changes, consumed by the UI and other subscribers
 
 	// Transfer control
 	currentTransferManager *transfer.UnifiedTransferManager
-	transferMu             sync.RWMutex // Protects currentTransferManager
+	transferMu             sync.RWMutex // Protects the transfer control fields
+	// outcome collects the final status of the files from the sessions of the running transfer
+	outcome *transferOutcome
+	// Items of the running batch, with the connection and structure cancelled items are
+	// removed from, see StartBatchSendProcess
+	currentBatch     []transfer.BatchItem
+	currentConn      webrtcPkg.SenderConnection
+	currentStructure *transfer.FileStructureManager
+	// cancelPending aborts the running transfer before it has a transfer manager,
+	// e.g. while the speed probe runs
+	cancelPending context.CancelFunc
+	userCancelled atomic.Bool   // The running transfer was aborted through cancelPending
+	probedRate    atomic.Uint64 // Float64bits of the rate measured by the speed probe, zero if none
+	// cancelSession ends the running session of the transfer for another one to resume it
+	cancelSession context.CancelCauseFunc
+	// wiredInterfaces are the interfaces the user moved the running transfer to, see handleUseWiredPath
+	wiredInterfaces []string
 
 	// Note: Removed fileStructure field for stateless design
 	// Each transfer will create its own FileStructureManager
@@ -43,17 +64,32 @@ type App struct {
 
 // NewApp creates a new sender application instance.
 func NewApp(adapter discovery.Adapter) *App {
+	return NewAppWithOptions(adapter, Options{})
+}
+
+// NewAppWithOptions creates a new sender application instance with hooks and manifest options.
+func NewAppWithOptions(adapter discovery.Adapter, options Options) *App {
 	serviceID := uuid.New().String()
-	webrtcAPI := webrtcPkg.NewWebrtcAPI()
+	webrtcAPI := webrtcPkg.NewWebrtcAPIWithOptions(webrtcPkg.APIOptions{LANOnly: options.LANOnly, Offline: options.Offline})
+	apiClient := api.NewClient(serviceID)
+	if options.PSK != nil {
+		apiClient.SetPSK(options.PSK)
+	}
+	transferTimeout := options.TransferTimeout
+	if transferTimeout <= 0 {
+		transferTimeout = 2 * time.Minute
+	}
 	return &App{
 		serviceID:       serviceID,
 		guard:           concurrency.NewConcurrencyGuard(),
 		discoverer:      adapter,
-		apiClient:       api.NewClient(serviceID),
+		apiClient:       apiClient,
 		uiMessages:      make(chan tea.Msg, 10),
 		appEvents:       make(chan appevents.AppEvent),
 		webrtcAPI:       webrtcAPI,
-		transferTimeout: 2 * time.Minute,
+		transferTimeout: transferTimeout,
+		options:         options,
+		bus:             events.NewBus(),
 	}
 }
 
@@ -67,6 +103,40 @@ func (a *App) AppEvents() chan<- appevents.AppEvent {
 	return a.appEvents
 }
 
+// maxCollisionWarnings is the number of case collisions shown individually before summarizing
+const maxCollisionWarnings = 3
+
+// warnCaseCollisions warns about selected files whose names differ only in case. Receivers
+// write files under their base names, so on case-insensitive filesystems they collide and
+// are saved under numbered names.
+func (a *App) warnCaseCollisions(files []fileInfo.FileNode) {
+	var names []string
+	var collect func(nodes []fileInfo.FileNode)
+	collect = func(nodes []fileInfo.FileNode) {
+		for i := range nodes {
+			if nodes[i].IsDir {
+				collect(nodes[i].Children)
+				continue
+			}
+			names = append(names, nodes[i].Name)
+		}
+	}
+	collect(files)
+
+	collisions := transfer.FindCaseCollisions(names)
+	for i, collision := range collisions {
+		slog.Warn("Selected files differ only in case", "names", collision.Names)
+		if i == maxCollisionWarnings {
+			a.uiMessages <- sender.StatusUpdateMsg{Message: fmt.Sprintf(
+				"Warning: %d more groups of files differ only in case", len(collisions)-maxCollisionWarnings)}
+			break
+		}
+		a.uiMessages <- sender.StatusUpdateMsg{Message: fmt.Sprintf(
+			"Warning: %s differ only in case; receivers on case-insensitive filesystems will rename all but one",
+			strings.Join(collision.Names, ", "))}
+	}
+}
+
 // prepareFilesForTransfer creates a new FileStructureManager for a specific transfer
 // This function is stateless and creates a fresh manager for each transfer
 func (a *App) prepareFilesForTransfer(files []fileInfo.FileNode) (*transfer.FileStructureManager, error) {
@@ -115,12 +185,18 @@ func (a *App) Run(ctx context.Context) error {
 				case sender.SendFilesMsg:
 					// Show files to users and start the transfer process
 					a.StartSendProcess(ctx, e.Receiver, e.Files)
+				case sender.SendBatchMsg:
+					a.StartBatchSendProcess(ctx, e.Receiver, e.Items)
+				case sender.CancelBatchItemMsg:
+					a.CancelBatchItem(e.ItemID)
 				case sender.PauseTransferMsg:
 					a.handlePauseTransfer()
 				case sender.ResumeTransferMsg:
 					a.handleResumeTransfer()
 				case sender.CancelTransferMsg:
 					a.handleCancelTransfer()
+				case sender.UseWiredPathMsg:
+					a.handleUseWiredPath()
 				}
 			}
 		}
@@ -131,21 +207,40 @@ func (a *App) Run(ctx context.Context) error {
 // runDiscovery begins the process of finding receivers on the network.
 func (a *App) runDiscovery(ctx context.Context) error {
 	// TODO: Use HTTPS for secure communication
-	serviceChan := a.discoverer.Discover(ctx, fmt.Sprintf("%s.%s.", discovery.DefaultServerType, discovery.DefaultDomain))
+	serviceChan := a.discoverer.Discover(ctx, a.scope().Query())
 
 	for {
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
-		case result := <-serviceChan:
+		case result, ok := <-serviceChan:
+			if !ok {
+				// Discovery has ended, e.g. offline; a nil channel keeps the loop waiting for ctx
+				serviceChan = nil
+				continue
+			}
 			if result.Error != nil {
 				a.sendAndLogError("Failed to discover service", result.Error)
 				return result.Error
 			}
 
-			a.uiMessages <- sender.FoundServicesMsg{Services: result.Services}
+			a.uiMessages <- sender.FoundServicesMsg{Services: a.filterOwnService(a.filterServices(result.Services))}
+		}
+	}
+}
+
+// filterOwnService removes the receiver announced by this instance from services
+func (a *App) filterOwnService(services []discovery.ServiceInfo) []discovery.ServiceInfo {
+	if a.options.IgnoreService == "" {
+		return services
+	}
+	filtered := make([]discovery.ServiceInfo, 0, len(services))
+	for _, service := range services {
+		if service.Name != a.options.IgnoreService {
+			filtered = append(filtered, service)
 		}
 	}
+	return filtered
 }
 
 // sendAndLogError is a helper function to both log an error and send it to the UI.
@@ -156,46 +251,103 @@ func (a *App) sendAndLogError(baseMessage string, err error) {
 
 // StartSendProcess is the main entry point for starting a file transfer.
 func (a *App) StartSendProcess(ctx context.Context, receiver discovery.ServiceInfo, files []fileInfo.FileNode) {
-	task := func(taskCtx context.Context) error {
-		// Create a new FileStructureManager for this transfer (stateless)
-		fileStructure, err := a.prepareFilesForTransfer(files)
-		if err != nil {
-			return fmt.Errorf("failed to prepare files: %w", err)
-		}
-		// fileStructure will be garbage collected after this function returns
+	a.startSend(ctx, receiver, files, nil)
+}
 
-		// Use the shorter of the two timeouts: main context or transfer timeout
-		transferCtx, cancel := context.WithTimeout(taskCtx, a.transferTimeout)
-		defer cancel()
+// startSend transfers files to receiver; with batch, the files are the items of a batch
+func (a *App) startSend(ctx context.Context, receiver discovery.ServiceInfo, files []fileInfo.FileNode, batch []transfer.BatchItem) {
+	task := func(taskCtx context.Context) (err error) {
+		progress := a.bus.Subscribe(0, events.TopicSession)
+		forwarded := make(chan struct{})
+		go func() {
+			defer close(forwarded)
+			a.forwardProgress(progress)
+		}()
+		defer func() {
+			progress.Close()
+			<-forwarded
+		}()
 
-		a.uiMessages <- sender.TransferStartedMsg{}
-		// TODO: Use HTTPS for secure communication
-		receiverURL := fmt.Sprintf("http://%s", net.JoinHostPort(receiver.Addr.String(), fmt.Sprintf("%d", receiver.Port)))
+		// The files that failed are reported whether the transfer completes or not
+		outcome := newTransferOutcome()
+		defer func() {
+			a.reportFailedFiles(receiver, files, outcome)
+		}()
 
-		a.uiMessages <- sender.StatusUpdateMsg{Message: "Creating secure connection..."}
+		// Controls must not reach the manager of a previous transfer
+		a.SetTransferManager(nil)
+		a.setOutcome(outcome)
+		a.setBatch(batch, nil, nil)
+		defer a.setBatch(nil, nil, nil)
+		a.probedRate.Store(0)
+		a.userCancelled.Store(false)
+
+		cache, files := a.skipAlreadySent(receiver, files)
+		if len(files) == 0 {
+			a.uiMessages <- sender.StatusUpdateMsg{Message: fmt.Sprintf("All files were already sent to %s, nothing to do (use --force to resend)", receiver.Name)}
+			// Nothing was sent, which is still a completed transfer for headless sends and jobs
+			a.uiMessages <- sender.TransferCompleteMsg{}
+			return nil
+		}
 
-		config := webrtcPkg.Config{}
-		webrtcConn, err := a.webrtcAPI.NewSenderConnectionWithProgress(transferCtx, config, a.apiClient, receiverURL, a)
-		if err != nil {
-			return fmt.Errorf("failed to create webrtc connection: %w", err)
+		a.warnCaseCollisions(files)
+
+		if err := runHook(taskCtx, "on_send_start", a.options.OnSendStart, hookEnv{receiver: receiver, files: files}); err != nil {
+			return err
 		}
+		a.options.Webhooks.Notify(taskCtx, hookEnv{receiver: receiver, files: files}.webhookEvent(false))
 		defer func() {
-			if err := webrtcConn.Close(); err != nil {
-				slog.Error("Failed to close webrtc connection", "error", err)
+			env := hookEnv{receiver: receiver, files: files, err: err}
+			if hookErr := runHook(context.WithoutCancel(taskCtx), "on_send_complete", a.options.OnSendComplete, env); hookErr != nil {
+				slog.Warn("Send complete hook failed", "error", hookErr)
 			}
+			a.options.Webhooks.Notify(context.WithoutCancel(taskCtx), env.webhookEvent(true))
 		}()
 
-		a.uiMessages <- sender.StatusUpdateMsg{Message: "Establishing connection..."}
-		if err := webrtcConn.Establish(transferCtx, fileStructure); err != nil {
-			return fmt.Errorf("could not establish webrtc connection: %w", err)
+		sendFiles := files
+		if a.options.GenerateManifest {
+			withManifest, cleanup, err := prependManifest(files)
+			if err != nil {
+				return fmt.Errorf("failed to generate manifest: %w", err)
+			}
+			defer cleanup()
+			sendFiles = withManifest
+		}
+
+		// Create a new FileStructureManager for this transfer (stateless)
+		fileStructure, err := a.prepareFilesForTransfer(sendFiles)
+		if err != nil {
+			return fmt.Errorf("failed to prepare files: %w", transfer.ClassifyIOError(err))
 		}
+		// fileStructure will be garbage collected after this function returns
 
-		a.uiMessages <- sender.StatusUpdateMsg{Message: "Connection established. Preparing to send files..."}
+		// Use the shorter of the two timeouts: main context or transfer timeout
+		transferCtx, cancel := context.WithTimeout(taskCtx, a.transferTimeout)
+		defer cancel()
+		a.setCancelPending(cancel)
+		defer a.setCancelPending(nil)
 
-		transferFiles := fileStructure.GetAllFileEntities()
+		a.uiMessages <- sender.TransferStartedMsg{}
+		resumeToken := a.options.ResumeToken
+		if resumeToken == "" {
+			resumeToken = transfer.NewResumeToken()
+		}
+		attempt := sendAttempt{
+			receiver:      receiver,
+			resumeToken:   resumeToken,
+			fileStructure: fileStructure,
+			batch:         batch,
+		}
+		if err := a.sendWithReconnect(transferCtx, attempt); err != nil {
+			return err
+		}
+		a.reportBatch()
 
-		if err := webrtcConn.SendFiles(transferCtx, transferFiles, a.serviceID); err != nil {
-			return fmt.Errorf("failed to send files: %w", err)
+		if cache != nil {
+			cache.Record(PeerKey(receiver), a.withoutCancelledItems(files))
+			if err := cache.Save(); err != nil {
+				slog.Warn("Failed to save sent cache", "error", err)
+			}
 		}
 
 		return nil // Success
@@ -206,8 +358,13 @@ func (a *App) StartSendProcess(ctx context.Context, receiver discovery.ServiceIn
 		defer a.transferWG.Done()
 		err := a.guard.ExecuteWithContext(ctx, task)
 		if err != nil {
-			if err == concurrency.ErrBusy {
+			if a.userCancelled.Swap(false) {
+				slog.Info("Transfer aborted by user", "error", err)
+			} else if err == concurrency.ErrBusy {
 				a.sendAndLogError("A transfer is already in progress", err)
+			} else if errors.Is(err, api.ErrRequestTimedOut) {
+				slog.Info("Receiver did not answer the request in time", "receiver", receiver.Name)
+				a.uiMessages <- sender.RequestTimedOutMsg{Receiver: receiver}
 			} else {
 				a.sendAndLogError("Transfer failed", err)
 			}
@@ -217,6 +374,47 @@ func (a *App) StartSendProcess(ctx context.Context, receiver discovery.ServiceIn
 	}()
 }
 
+// skipAlreadySent drops files already delivered to receiver with unchanged content.
+// It returns the loaded cache, or nil when the cache is disabled or unreadable.
+func (a *App) skipAlreadySent(receiver discovery.ServiceInfo, files []fileInfo.FileNode) (*SentCache, []fileInfo.FileNode) {
+	if a.options.SentCachePath == "" {
+		return nil, files
+	}
+
+	cache, err := LoadSentCache(a.options.SentCachePath)
+	if err != nil {
+		slog.Warn("Failed to load sent cache, sending all files", "error", err)
+		return nil, files
+	}
+	if a.options.ForceResend {
+		return cache, files
+	}
+
+	pending, skipped := cache.Filter(PeerKey(receiver), files)
+	if skipped > 0 {
+		slog.Info("Skipping files already sent", "receiver", receiver.Name, "skipped", skipped)
+		a.uiMessages <- sender.StatusUpdateMsg{Message: fmt.Sprintf("Skipping %d unchanged files already sent to %s", skipped, receiver.Name)}
+	}
+	return cache, pending
+}
+
+// loadResumeState fetches the receiver's chunk bitmap so already received chunks are skipped.
+// Failures fall back to sending everything.
+func (a *App) loadResumeState(ctx context.Context, conn webrtcPkg.SenderConnection, receiverURL, token string) {
+	state, err := a.apiClient.FetchResumeState(ctx, receiverURL, token)
+	if err != nil {
+		if errors.Is(err, transfer.ErrResumeStateNotFound) {
+			a.uiMessages <- sender.StatusUpdateMsg{Message: "Nothing to resume, sending all files"}
+		} else {
+			slog.Warn("Failed to fetch resume state, sending all files", "error", err)
+		}
+		return
+	}
+
+	conn.SetResumeState(state)
+	a.uiMessages <- sender.StatusUpdateMsg{Message: fmt.Sprintf("Resuming session %s (%d files)", token, len(state.Files))}
+}
+
 // handlePauseTransfer pauses the current transfer
 func (a *App) handlePauseTransfer() {
 	a.transferMu.RLock()
@@ -229,13 +427,13 @@ func (a *App) handlePauseTransfer() {
 	}
 
 	if err := utm.PauseSession(); err != nil {
-		slog.Error("Failed to pause transfer", "error", err)
-		a.uiMessages <- appevents.Error{Err: fmt.Errorf("failed to pause transfer: %w", err)}
+		a.reportSessionControlError("pause", err)
 		return
 	}
 
-	slog.Info("Transfer paused by user")
-	a.uiMessages <- sender.TransferPausedMsg{}
+	files := utm.GetSessionStatus().ActiveFiles
+	slog.Info("Transfer paused by user", "files", files)
+	a.uiMessages <- sender.TransferPausedMsg{Files: files}
 }
 
 // handleResumeTransfer resumes the current transfer
@@ -250,13 +448,13 @@ func (a *App) handleResumeTransfer() {
 	}
 
 	if err := utm.ResumeSession(); err != nil {
-		slog.Error("Failed to resume transfer", "error", err)
-		a.uiMessages <- appevents.Error{Err: fmt.Errorf("failed to resume transfer: %w", err)}
+		a.reportSessionControlError("resume", err)
 		return
 	}
 
-	slog.Info("Transfer resumed by user")
-	a.uiMessages <- sender.TransferResumedMsg{}
+	files := utm.GetSessionStatus().ActiveFiles
+	slog.Info("Transfer resumed by user", "files", files)
+	a.uiMessages <- sender.TransferResumedMsg{Files: files}
 }
 
 // handleCancelTransfer cancels the current transfer
@@ -266,45 +464,73 @@ func (a *App) handleCancelTransfer() {
 	a.transferMu.RUnlock()
 
 	if utm == nil {
+		if a.abortPending() {
+			slog.Info("Transfer aborted by user before sending files")
+			a.uiMessages <- sender.TransferCancelledMsg{}
+			return
+		}
 		slog.Warn("No active transfer to cancel")
 		return
 	}
 
 	if err := utm.CancelSession(); err != nil {
-		slog.Error("Failed to cancel transfer", "error", err)
-		a.uiMessages <- appevents.Error{Err: fmt.Errorf("failed to cancel transfer: %w", err)}
+		a.reportSessionControlError("cancel", err)
 		return
 	}
+	// The send loop stops at the next chunk; its error is not a failure to report
+	a.userCancelled.Store(true)
 
 	slog.Info("Transfer cancelled by user")
 	a.uiMessages <- sender.TransferCancelledMsg{}
 }
 
-// SetTransferManager sets the current transfer manager (implements ProgressSignaler)
-func (a *App) SetTransferManager(utm *transfer.UnifiedTransferManager) {
+// reportSessionControlError tells the user why the session could not be paused, resumed or
+// cancelled. A session that changed state first, such as one that just finished, is not an
+// error that fails the transfer, so only the status line says so.
+func (a *App) reportSessionControlError(action string, err error) {
+	if errors.Is(err, transfer.ErrInvalidStateTransition) {
+		slog.Warn("Ignoring session control", "action", action, "error", err)
+		a.uiMessages <- sender.StatusUpdateMsg{Message: fmt.Sprintf("Could not %s the transfer: %v", action, err)}
+		return
+	}
+	slog.Error("Failed to control transfer", "action", action, "error", err)
+	a.uiMessages <- appevents.Error{Err: fmt.Errorf("failed to %s transfer: %w", action, err)}
+}
+
+// setCancelPending records how to abort the running transfer before it has a transfer manager
+func (a *App) setCancelPending(cancel context.CancelFunc) {
 	a.transferMu.Lock()
 	defer a.transferMu.Unlock()
-	a.currentTransferManager = utm
+	a.cancelPending = cancel
+}
+
+// abortPending cancels the running transfer if it has no transfer manager yet
+func (a *App) abortPending() bool {
+	a.transferMu.Lock()
+	defer a.transferMu.Unlock()
+	if a.cancelPending == nil || a.currentTransferManager != nil {
+		return false
+	}
+	a.userCancelled.Store(true)
+	a.cancelPending()
+	a.cancelPending = nil
+	return true
 }
 
-// SendProgressUpdate implements the ProgressSignaler interface
-func (a *App) SendProgressUpdate(totalFiles, completedFiles int, totalBytes, transferredBytes int64,
-	currentFile string, transferRate float64, eta string, overallProgress float64) {
-
-	// Send progress update to UI
-	select {
-	case a.uiMessages <- sender.ProgressUpdateMsg{
-		TotalFiles:       totalFiles,
-		CompletedFiles:   completedFiles,
-		TotalBytes:       totalBytes,
-		TransferredBytes: transferredBytes,
-		CurrentFile:      currentFile,
-		TransferRate:     transferRate,
-		ETA:              eta,
-		OverallProgress:  overallProgress,
-	}:
-	default:
-		// Don't block if UI channel is full
-		slog.Debug("UI channel full, skipping progress update")
+// SetTransferManager sets the current transfer manager, publishes its status changes
+// on the app's event bus and adds its files to the outcome of the running transfer
+// (implements ProgressSignaler)
+func (a *App) SetTransferManager(utm *transfer.UnifiedTransferManager) {
+	a.transferMu.Lock()
+	defer a.transferMu.Unlock()
+	a.currentTransferManager = utm
+	if utm != nil {
+		utm.SetEventBus(a.bus)
+		if a.outcome != nil {
+			a.outcome.watch(utm)
+		}
+		if len(a.currentBatch) > 0 {
+			utm.SetBatch(a.currentBatch)
+		}
 	}
 }
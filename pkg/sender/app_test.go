@@ -2,9 +2,11 @@ package sender
 
 import (
 	"context"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/rescp17/lanFileSharer/internal/app_events/sender"
 	"github.com/rescp17/lanFileSharer/pkg/discovery"
 	"github.com/rescp17/lanFileSharer/pkg/fileInfo"
 )
@@ -107,3 +109,45 @@ func TestTransferWaitGroup(t *testing.T) {
 		t.Error("transferWG.Wait() blocked, indicating goroutine didn't complete properly")
 	}
 }
+
+func TestFilterOwnService(t *testing.T) {
+	app := NewAppWithOptions(&MockDiscoveryAdapter{}, Options{IgnoreService: "me-1234"})
+
+	services := app.filterOwnService([]discovery.ServiceInfo{{Name: "peer-abcd"}, {Name: "me-1234"}})
+	if len(services) != 1 || services[0].Name != "peer-abcd" {
+		t.Errorf("Expected only peer-abcd, got %v", services)
+	}
+}
+
+func TestWarnCaseCollisions(t *testing.T) {
+	app := NewApp(&MockDiscoveryAdapter{})
+
+	app.warnCaseCollisions([]fileInfo.FileNode{
+		{Name: "README.md"},
+		{Name: "docs", IsDir: true, Children: []fileInfo.FileNode{
+			{Name: "readme.md"},
+			{Name: "guide.md"},
+		}},
+		{Name: "guide.md"},
+	})
+
+	select {
+	case msg := <-app.UIMessages():
+		status, ok := msg.(sender.StatusUpdateMsg)
+		if !ok {
+			t.Fatalf("expected StatusUpdateMsg, got %T", msg)
+		}
+		if !strings.Contains(status.Message, "README.md, readme.md") {
+			t.Errorf("unexpected warning %q", status.Message)
+		}
+	default:
+		t.Fatal("expected a warning about README.md and readme.md")
+	}
+
+	// Exact duplicates are not case collisions
+	select {
+	case msg := <-app.UIMessages():
+		t.Errorf("unexpected message %v", msg)
+	default:
+	}
+}
@@ -0,0 +1,51 @@
+package sender
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/rescp17/lanFileSharer/internal/config"
+	"github.com/rescp17/lanFileSharer/pkg/transfer"
+)
+
+// ParseBandwidthSchedule builds the bandwidth schedule of sends from the profiles in cfg
+func ParseBandwidthSchedule(cfg config.Config) (transfer.BandwidthSchedule, error) {
+	var schedule transfer.BandwidthSchedule
+	for i, profile := range cfg.BandwidthProfiles {
+		name := profile.Name
+		if name == "" {
+			name = fmt.Sprintf("profile %d", i+1)
+		}
+		start, err := transfer.ParseTimeOfDay(profile.Start)
+		if err != nil {
+			return nil, fmt.Errorf("bandwidth profile %q: start: %w", name, err)
+		}
+		end, err := transfer.ParseTimeOfDay(profile.End)
+		if err != nil {
+			return nil, fmt.Errorf("bandwidth profile %q: end: %w", name, err)
+		}
+		if start == end {
+			return nil, fmt.Errorf("bandwidth profile %q starts when it ends", name)
+		}
+		if profile.LimitKB < 0 {
+			return nil, fmt.Errorf("bandwidth profile %q: limit %d must not be negative", name, profile.LimitKB)
+		}
+		schedule = append(schedule, transfer.BandwidthProfile{
+			Name:           name,
+			Start:          start,
+			End:            end,
+			BytesPerSecond: int64(profile.LimitKB) << 10,
+		})
+	}
+	return schedule, nil
+}
+
+// BandwidthSchedule returns the configured bandwidth schedule of sends, or none if it is invalid
+func BandwidthSchedule(cfg config.Config) transfer.BandwidthSchedule {
+	schedule, err := ParseBandwidthSchedule(cfg)
+	if err != nil {
+		slog.Warn("Invalid bandwidth_profiles in config, sending without them", "error", err)
+		return nil
+	}
+	return schedule
+}
@@ -0,0 +1,36 @@
+package sender
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rescp17/lanFileSharer/internal/config"
+	"github.com/rescp17/lanFileSharer/pkg/transfer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBandwidthSchedule(t *testing.T) {
+	assert.Empty(t, BandwidthSchedule(config.DefaultConfig()))
+
+	cfg := config.DefaultConfig()
+	cfg.BandwidthProfiles = []config.BandwidthProfile{
+		{Name: "work", Start: "9:00", End: "18:00", LimitKB: 10 << 10},
+		{Start: "22:00", End: "6:00"},
+	}
+	schedule, err := ParseBandwidthSchedule(cfg)
+	require.NoError(t, err)
+	assert.Equal(t, transfer.BandwidthSchedule{
+		{Name: "work", Start: 9 * time.Hour, End: 18 * time.Hour, BytesPerSecond: 10 << 20},
+		{Name: "profile 2", Start: 22 * time.Hour, End: 6 * time.Hour},
+	}, schedule)
+
+	cfg.BandwidthProfiles = append(cfg.BandwidthProfiles, config.BandwidthProfile{Name: "broken", Start: "noon", End: "13:00"})
+	_, err = ParseBandwidthSchedule(cfg)
+	assert.ErrorContains(t, err, "broken")
+	assert.Empty(t, BandwidthSchedule(cfg))
+
+	cfg.BandwidthProfiles = []config.BandwidthProfile{{Start: "9:00", End: "9:00"}}
+	_, err = ParseBandwidthSchedule(cfg)
+	assert.Error(t, err)
+}
@@ -0,0 +1,127 @@
+package sender
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"strconv"
+
+	"github.com/rescp17/lanFileSharer/internal/app_events/sender"
+	"github.com/rescp17/lanFileSharer/pkg/discovery"
+	"github.com/rescp17/lanFileSharer/pkg/fileInfo"
+	"github.com/rescp17/lanFileSharer/pkg/transfer"
+	webrtcPkg "github.com/rescp17/lanFileSharer/pkg/webrtc"
+)
+
+// StartBatchSendProcess sends several independent selections to receiver as one batch. They
+// are offered in a single request, so the receiver accepts them once, and transferred as one
+// session with a child session per item; CancelBatchItem stops one item while the others
+// carry on.
+func (a *App) StartBatchSendProcess(ctx context.Context, receiver discovery.ServiceInfo, items []sender.BatchItem) {
+	files, batch := batchFiles(items)
+	a.startSend(ctx, receiver, files, batch)
+}
+
+// batchFiles combines the files of items into one selection and describes each item by the
+// top-level files and directories it contributes. Items are numbered from 1.
+func batchFiles(items []sender.BatchItem) ([]fileInfo.FileNode, []transfer.BatchItem) {
+	var files []fileInfo.FileNode
+	batch := make([]transfer.BatchItem, 0, len(items))
+	for i, item := range items {
+		batchItem := transfer.BatchItem{ID: strconv.Itoa(i + 1), Name: item.Name}
+		for _, file := range item.Files {
+			batchItem.Roots = append(batchItem.Roots, file.Path)
+		}
+		if batchItem.Name == "" && len(item.Files) > 0 {
+			batchItem.Name = filepath.Base(item.Files[0].Path)
+		}
+		files = append(files, item.Files...)
+		batch = append(batch, batchItem)
+	}
+	return files, batch
+}
+
+// setBatch records the items of the running batch, and once the receiver accepted them, the
+// connection and structure cancelled items are removed from
+func (a *App) setBatch(batch []transfer.BatchItem, conn webrtcPkg.SenderConnection, structure *transfer.FileStructureManager) {
+	a.transferMu.Lock()
+	defer a.transferMu.Unlock()
+	a.currentBatch = batch
+	a.currentConn = conn
+	a.currentStructure = structure
+}
+
+// CancelBatchItem cancels the item with id of the running batch. Its files are removed from
+// the offered structure too, so the receiver does not wait for them.
+func (a *App) CancelBatchItem(id string) {
+	a.transferMu.RLock()
+	utm, conn, structure := a.currentTransferManager, a.currentConn, a.currentStructure
+	a.transferMu.RUnlock()
+
+	if utm == nil {
+		slog.Warn("No active batch to cancel an item of", "item", id)
+		return
+	}
+
+	cancelled, err := utm.CancelBatchItem(id)
+	if err != nil {
+		// The rest of the batch is unaffected, so this is not an error of the transfer
+		slog.Warn("Failed to cancel batch item", "item", id, "error", err)
+		a.uiMessages <- sender.StatusUpdateMsg{Message: fmt.Sprintf("Could not cancel item %s: %v", id, err)}
+		return
+	}
+	slog.Info("Batch item cancelled by user", "item", id, "files", len(cancelled))
+
+	if conn != nil && structure != nil && len(cancelled) > 0 {
+		changes := make([]transfer.StructureChange, 0, len(cancelled))
+		for _, path := range cancelled {
+			changes = append(changes, transfer.StructureChange{
+				Type:         transfer.StructureNodeRemoved,
+				Path:         path,
+				RelativePath: structure.RelativePath(path),
+			})
+		}
+		if err := conn.SendStructureUpdate(changes); err != nil {
+			slog.Warn("Failed to withdraw the files of the cancelled item from the receiver", "item", id, "error", err)
+		}
+	}
+	a.uiMessages <- sender.StatusUpdateMsg{Message: fmt.Sprintf("Cancelled item %s (%d files not sent), the rest of the batch continues", id, len(cancelled))}
+}
+
+// reportBatch reports how each item of a finished batch went
+func (a *App) reportBatch() {
+	a.transferMu.RLock()
+	utm, batch := a.currentTransferManager, a.currentBatch
+	a.transferMu.RUnlock()
+	if utm == nil || len(batch) == 0 {
+		return
+	}
+
+	for _, child := range utm.GetSessionStatus().Children {
+		message := fmt.Sprintf("Item %s (%s): %d of %d files sent", child.SessionID, child.Name, child.CompletedFiles, child.TotalFiles)
+		if child.State == transfer.StatusSessionStateCancelled {
+			message += ", cancelled"
+		}
+		a.uiMessages <- sender.StatusUpdateMsg{Message: message}
+	}
+}
+
+// withoutCancelledItems drops the files of cancelled batch items from files, which must not
+// be remembered as sent
+func (a *App) withoutCancelledItems(files []fileInfo.FileNode) []fileInfo.FileNode {
+	a.transferMu.RLock()
+	utm := a.currentTransferManager
+	a.transferMu.RUnlock()
+	if utm == nil {
+		return files
+	}
+
+	kept := make([]fileInfo.FileNode, 0, len(files))
+	for _, file := range files {
+		if !utm.IsFileCancelled(file.Path) {
+			kept = append(kept, file)
+		}
+	}
+	return kept
+}
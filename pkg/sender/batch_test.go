@@ -0,0 +1,29 @@
+package sender
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rescp17/lanFileSharer/internal/app_events/sender"
+	"github.com/rescp17/lanFileSharer/pkg/fileInfo"
+)
+
+func TestBatchFiles(t *testing.T) {
+	items := []sender.BatchItem{
+		{Name: "reports", Files: []fileInfo.FileNode{{Name: "q1.pdf", Path: "/home/u/q1.pdf"}, {Name: "q2.pdf", Path: "/home/u/q2.pdf"}}},
+		{Files: []fileInfo.FileNode{{Name: "photos", Path: "/home/u/photos", IsDir: true}}},
+	}
+
+	files, batch := batchFiles(items)
+	require.Len(t, files, 3)
+	assert.Equal(t, "/home/u/photos", files[2].Path)
+
+	require.Len(t, batch, 2)
+	assert.Equal(t, "1", batch[0].ID)
+	assert.Equal(t, "reports", batch[0].Name)
+	assert.Equal(t, []string{"/home/u/q1.pdf", "/home/u/q2.pdf"}, batch[0].Roots)
+	assert.Equal(t, "2", batch[1].ID)
+	assert.Equal(t, "photos", batch[1].Name, "an unnamed item is named after its first file")
+}
@@ -0,0 +1,68 @@
+package sender
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/rescp17/lanFileSharer/internal/app_events/sender"
+	"github.com/rescp17/lanFileSharer/internal/config"
+	"github.com/rescp17/lanFileSharer/internal/util"
+)
+
+// BatteryPolicy is how large sends started while this machine runs on battery are treated
+type BatteryPolicy struct {
+	// WarnBytes is the size from which such a send is warned about; zero disables the checks
+	WarnBytes int64
+	// Saver sends them on a single stream, and no faster than BandwidthLimit bytes per second
+	// if that is set, to draw less power
+	Saver          bool
+	BandwidthLimit int64
+}
+
+// Battery returns the battery policy configured in cfg
+func Battery(cfg config.Config) BatteryPolicy {
+	return BatteryPolicy{
+		WarnBytes:      cfg.BatteryWarnBytes(),
+		Saver:          cfg.BatterySaver,
+		BandwidthLimit: int64(max(cfg.BatteryBandwidthKB, 0)) << 10,
+	}
+}
+
+// limit returns the bandwidth limit of a session saving power that would otherwise send at
+// most limit bytes per second, zero being unlimited
+func (p BatteryPolicy) limit(limit int64) int64 {
+	if p.BandwidthLimit > 0 && (limit == 0 || p.BandwidthLimit < limit) {
+		return p.BandwidthLimit
+	}
+	return limit
+}
+
+// powerSource reports what this machine runs on; tests replace it
+var powerSource = util.CurrentPowerSource
+
+// batterySaving reports whether the session of attempt should save power, as it sends a large
+// transfer on battery with the battery saver on. The power source is checked for every
+// session, so a transfer resumed after plugging in goes at full speed; only the first
+// session of a transfer warns.
+func (a *App) batterySaving(attempt sendAttempt) bool {
+	policy := a.options.Battery
+	if policy.WarnBytes <= 0 {
+		return false
+	}
+	total := attempt.fileStructure.GetTotalSize()
+	if total < policy.WarnBytes || powerSource() != util.PowerBattery {
+		return false
+	}
+	if !attempt.reconnect {
+		slog.Warn("Starting a large send on battery", "bytes", total, "batterySaver", policy.Saver)
+		message := fmt.Sprintf("Running on battery: sending %s may drain it, plug in for long transfers", util.FormatSize(total))
+		if policy.Saver {
+			message += "; battery saver sends on a single stream"
+			if policy.BandwidthLimit > 0 {
+				message += fmt.Sprintf(" at %s/s at most", util.FormatSize(policy.BandwidthLimit))
+			}
+		}
+		a.uiMessages <- sender.StatusUpdateMsg{Message: message}
+	}
+	return policy.Saver
+}
@@ -0,0 +1,55 @@
+package sender
+
+import (
+	"testing"
+
+	"github.com/rescp17/lanFileSharer/internal/app_events/sender"
+	"github.com/rescp17/lanFileSharer/internal/config"
+	"github.com/rescp17/lanFileSharer/internal/util"
+	"github.com/rescp17/lanFileSharer/pkg/fileInfo"
+	"github.com/rescp17/lanFileSharer/pkg/transfer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBattery(t *testing.T) {
+	policy := Battery(config.DefaultConfig())
+	assert.Equal(t, int64(2<<30), policy.WarnBytes)
+	assert.False(t, policy.Saver)
+
+	policy = Battery(config.Config{BatteryWarnMB: 100, BatterySaver: true, BatteryBandwidthKB: 1024})
+	assert.Equal(t, int64(1<<20), policy.BandwidthLimit)
+	assert.Equal(t, int64(1<<20), policy.limit(0), "unlimited sessions get the battery limit")
+	assert.Equal(t, int64(1<<20), policy.limit(10<<20))
+	assert.Equal(t, int64(512<<10), policy.limit(512<<10), "a lower limit saved for the peer stays")
+
+	assert.Equal(t, int64(10<<20), BatteryPolicy{Saver: true}.limit(10<<20), "no battery limit keeps the peer's")
+}
+
+func TestBatterySaving(t *testing.T) {
+	source := util.PowerBattery
+	powerSource = func() util.PowerSource { return source }
+	defer func() { powerSource = util.CurrentPowerSource }()
+
+	fsm := transfer.NewFileStructureManager()
+	require.NoError(t, fsm.AddFileNode(&fileInfo.FileNode{Name: "disk.img", Path: "/tmp/disk.img", Size: 3 << 30}))
+	attempt := sendAttempt{fileStructure: fsm}
+
+	app := NewAppWithOptions(&MockDiscoveryAdapter{}, Options{Battery: BatteryPolicy{WarnBytes: 2 << 30, Saver: true}})
+	assert.True(t, app.batterySaving(attempt))
+	msg := <-app.UIMessages()
+	assert.Contains(t, msg.(sender.StatusUpdateMsg).Message, "Running on battery")
+
+	attempt.reconnect = true
+	assert.True(t, app.batterySaving(attempt), "resumed sessions save power without warning again")
+	assert.Empty(t, app.UIMessages())
+
+	source = util.PowerAC
+	assert.False(t, app.batterySaving(attempt), "plugged in")
+
+	source = util.PowerBattery
+	small := NewAppWithOptions(&MockDiscoveryAdapter{}, Options{Battery: BatteryPolicy{WarnBytes: 4 << 30, Saver: true}})
+	assert.False(t, small.batterySaving(attempt))
+	disabled := NewAppWithOptions(&MockDiscoveryAdapter{}, Options{})
+	assert.False(t, disabled.batterySaving(attempt))
+}
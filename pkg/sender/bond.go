@@ -0,0 +1,134 @@
+package sender
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pion/webrtc/v4"
+	"github.com/rescp17/lanFileSharer/internal/app_events/sender"
+	"github.com/rescp17/lanFileSharer/internal/util"
+	"github.com/rescp17/lanFileSharer/pkg/discovery"
+	"github.com/rescp17/lanFileSharer/pkg/peers"
+	webrtcPkg "github.com/rescp17/lanFileSharer/pkg/webrtc"
+)
+
+const (
+	// bondDialTimeout bounds opening a bond path over one interface
+	bondDialTimeout = 15 * time.Second
+	// pathStatsInterval is how often the paths of a bonded transfer are reported
+	pathStatsInterval = time.Second
+)
+
+// addBondPaths opens a bond path to the receiver of attempt over every LAN interface but the
+// one the session's own connection goes over, for the experimental bonding mode. Interfaces
+// the receiver cannot be reached from are left out after bondDialTimeout.
+func (a *App) addBondPaths(ctx context.Context, conn webrtcPkg.SenderConnection, attempt sendAttempt, settings peers.Settings) {
+	primary, _ := util.RouteInterface(attempt.receiver.Addr)
+	url := receiverURL(attempt.receiver)
+	exchange := func(ctx context.Context, offer webrtc.SessionDescription, signature []byte) (*webrtc.SessionDescription, error) {
+		return a.apiClient.Bond(ctx, url, attempt.resumeToken, offer, signature)
+	}
+
+	var added atomic.Int32
+	var unsupported atomic.Bool
+	var wg sync.WaitGroup
+	for _, iface := range util.LANInterfaces() {
+		if iface.Name == primary.Name {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			dialCtx, cancel := context.WithTimeout(ctx, bondDialTimeout)
+			defer cancel()
+			api := webrtcPkg.NewWebrtcAPIWithOptions(webrtcPkg.APIOptions{
+				LANOnly:    settings.LANOnly() || a.options.LANOnly,
+				Offline:    a.options.Offline,
+				Interfaces: []string{iface.Name},
+			})
+			if err := conn.AddBondPath(dialCtx, api, exchange); err != nil {
+				if errors.Is(err, webrtcPkg.ErrBondUnsupported) {
+					unsupported.Store(true)
+					return
+				}
+				slog.Warn("Failed to add bond path", "interface", iface.Name, "error", err)
+				return
+			}
+			added.Add(1)
+		}()
+	}
+	wg.Wait()
+
+	switch {
+	case unsupported.Load():
+		a.uiMessages <- sender.StatusUpdateMsg{Message: "The receiver does not support bonding, sending over one path"}
+	case added.Load() == 0:
+		a.uiMessages <- sender.StatusUpdateMsg{Message: "Bonding: no other interface reaches the receiver, sending over one path"}
+	default:
+		a.uiMessages <- sender.StatusUpdateMsg{Message: fmt.Sprintf("Bonding: striping large files over %d paths", added.Load()+1)}
+	}
+}
+
+// reportPathStats sends the paths conn stripes files over to the UI every pathStatsInterval,
+// until the returned stop is called
+func (a *App) reportPathStats(ctx context.Context, conn webrtcPkg.SenderConnection, receiver discovery.ServiceInfo) (stop func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(pathStatsInterval)
+		defer ticker.Stop()
+		var previous []int64
+		last := time.Now()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				stats := conn.PathStats()
+				if len(stats) == 0 {
+					continue
+				}
+				paths := pathStats(stats, previous, now.Sub(last), receiver.Addr)
+				previous = make([]int64, len(stats))
+				for i, stat := range stats {
+					previous[i] = stat.Bytes
+				}
+				last = now
+				select {
+				case a.uiMessages <- sender.PathStatsMsg{Paths: paths}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+// pathStats describes stats for the UI, with the rate of each path since it carried previous
+// bytes elapsed ago. Paths are named by their local interface, found through fallback, the
+// address the receiver was discovered at, when the candidates carry mDNS names.
+func pathStats(stats []webrtcPkg.PathStats, previous []int64, elapsed time.Duration, fallback net.IP) []sender.PathStat {
+	paths := make([]sender.PathStat, 0, len(stats))
+	for i, stat := range stats {
+		path := sender.PathStat{Interface: stat.Local, Bytes: stat.Bytes}
+		if iface, ok := localInterface(stat.Path, fallback); ok {
+			path.Interface = iface.Name
+		}
+		if i < len(previous) && elapsed > 0 {
+			path.Rate = float64(stat.Bytes-previous[i]) / elapsed.Seconds()
+		}
+		paths = append(paths, path)
+	}
+	return paths
+}
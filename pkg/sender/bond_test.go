@@ -0,0 +1,28 @@
+package sender
+
+import (
+	"testing"
+	"time"
+
+	webrtcPkg "github.com/rescp17/lanFileSharer/pkg/webrtc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPathStats(t *testing.T) {
+	stats := []webrtcPkg.PathStats{
+		{Path: webrtcPkg.Path{Local: "127.0.0.1", Remote: "127.0.0.1"}, Bytes: 3000},
+		{Path: webrtcPkg.Path{Local: "192.0.2.1", Remote: "192.0.2.2"}, Bytes: 1000},
+	}
+
+	paths := pathStats(stats, nil, time.Second, nil)
+	require.Len(t, paths, 2)
+	assert.NotEqual(t, "127.0.0.1", paths[0].Interface, "a local address is named by its interface")
+	assert.Equal(t, "192.0.2.1", paths[1].Interface, "an address on no interface is shown as it is")
+	assert.Zero(t, paths[0].Rate, "there is no rate before a previous report")
+
+	paths = pathStats(stats, []int64{1000, 500}, 2*time.Second, nil)
+	assert.InDelta(t, 1000, paths[0].Rate, 0.001)
+	assert.InDelta(t, 250, paths[1].Rate, 0.001)
+	assert.Equal(t, int64(1000), paths[1].Bytes)
+}
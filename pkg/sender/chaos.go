@@ -0,0 +1,26 @@
+package sender
+
+import (
+	"log/slog"
+
+	"github.com/rescp17/lanFileSharer/internal/config"
+	"github.com/rescp17/lanFileSharer/pkg/transfer"
+)
+
+// ParseChaos parses the faults --chaos asked to inject into sent chunks
+func ParseChaos(cfg config.Config) (transfer.ChaosConfig, error) {
+	if cfg.Chaos == "" {
+		return transfer.ChaosConfig{}, nil
+	}
+	return transfer.ParseChaosConfig(cfg.Chaos)
+}
+
+// Chaos returns the faults to inject into sent chunks, none if the setting is invalid
+func Chaos(cfg config.Config) transfer.ChaosConfig {
+	chaos, err := ParseChaos(cfg)
+	if err != nil {
+		slog.Warn("Invalid chaos settings, injecting no faults", "error", err)
+		return transfer.ChaosConfig{}
+	}
+	return chaos
+}
@@ -0,0 +1,31 @@
+package sender
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rescp17/lanFileSharer/api"
+	"github.com/rescp17/lanFileSharer/pkg/crypto"
+	"github.com/rescp17/lanFileSharer/pkg/discovery"
+	"github.com/rescp17/lanFileSharer/pkg/fileInfo"
+)
+
+// CompareWithReceiver signs the structure of files like an offer and asks receiver how its
+// output differs from it. No content is sent and the receiver's user is not asked.
+func (a *App) CompareWithReceiver(ctx context.Context, receiver discovery.ServiceInfo, files []fileInfo.FileNode) (*api.CompareReport, error) {
+	structure, err := a.prepareFilesForTransfer(files)
+	if err != nil {
+		return nil, err
+	}
+	signer := a.deviceSigner()
+	if signer == nil {
+		if signer, err = crypto.NewFileStructureSignerWithAlgorithm(crypto.DefaultSignatureAlgorithm); err != nil {
+			return nil, fmt.Errorf("failed to create file structure signer: %w", err)
+		}
+	}
+	signed, err := signer.SignFileStructureManagerContext(ctx, structure)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign file structure: %w", err)
+	}
+	return a.apiClient.Compare(ctx, receiverURL(receiver), signed)
+}
@@ -0,0 +1,117 @@
+package sender
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"time"
+
+	"github.com/rescp17/lanFileSharer/internal/config"
+	"github.com/rescp17/lanFileSharer/pkg/crypto"
+)
+
+// DeviceKeyPath returns the default location of the device key store, or "" if it cannot be resolved
+func DeviceKeyPath() string {
+	dir, err := config.Dir()
+	if err != nil {
+		slog.Warn("Could not resolve device key path, signing with a one-time key", "error", err)
+		return ""
+	}
+	return filepath.Join(dir, crypto.DeviceKeyFileName)
+}
+
+// DeviceFingerprint returns the fingerprint of the device key, creating the key if there is
+// none yet, or "" if the key store cannot be used. Receivers advertise it so senders can
+// apply their settings for them.
+func DeviceFingerprint(cfg config.Config) string {
+	path := DeviceKeyPath()
+	if path == "" {
+		return ""
+	}
+	store := crypto.NewKeyStore(path, cfg.KeyLifetime(), cfg.KeyGrace())
+	store.SetAlgorithm(SignatureAlgorithm(cfg))
+	key, err := store.Current()
+	if err != nil {
+		slog.Warn("Failed to load device key, no fingerprint is advertised", "error", err)
+		return ""
+	}
+	fingerprint, err := key.Fingerprint()
+	if err != nil {
+		slog.Warn("Failed to fingerprint device key", "error", err)
+		return ""
+	}
+	return fingerprint
+}
+
+// DeviceSigner returns a signer for the device key, creating the key if there is none yet, so
+// that what it signs outside of a session, such as offer files, carries the key of the offers
+func DeviceSigner(cfg config.Config) (*crypto.FileStructureSigner, error) {
+	path := DeviceKeyPath()
+	if path == "" {
+		return nil, errors.New("no device key store")
+	}
+	store := crypto.NewKeyStore(path, cfg.KeyLifetime(), cfg.KeyGrace())
+	store.SetAlgorithm(SignatureAlgorithm(cfg))
+	key, err := store.Current()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load device key: %w", err)
+	}
+	return crypto.NewFileStructureSignerFromDeviceKey(key), nil
+}
+
+// SignatureAlgorithm returns the configured signature algorithm, falling back to the default if it is invalid
+func SignatureAlgorithm(cfg config.Config) crypto.SignatureAlgorithm {
+	algorithm, err := crypto.ParseSignatureAlgorithm(cfg.SignatureAlgorithm)
+	if err != nil {
+		slog.Warn("Invalid signature_algorithm in config, using default", "error", err, "default", crypto.DefaultSignatureAlgorithm)
+		return crypto.DefaultSignatureAlgorithm
+	}
+	return algorithm
+}
+
+// deviceSigner returns a signer for the persistent device key, rotating it if it expired.
+// Without a usable key store it returns a one-time signer of the configured algorithm,
+// or nil to let the connection pick its default.
+func (a *App) deviceSigner() *crypto.FileStructureSigner {
+	if a.options.DeviceKeyPath == "" {
+		return a.ephemeralSigner()
+	}
+
+	store := crypto.NewKeyStore(a.options.DeviceKeyPath, a.options.KeyLifetime, a.options.KeyGrace)
+	if a.options.SignatureAlgorithm != "" {
+		store.SetAlgorithm(a.options.SignatureAlgorithm)
+	}
+	key, err := store.Current()
+	if err != nil {
+		slog.Warn("Failed to load device key, signing with a one-time key", "error", err)
+		return a.ephemeralSigner()
+	}
+
+	fingerprint, err := key.Fingerprint()
+	if err != nil {
+		slog.Warn("Failed to fingerprint device key", "error", err)
+	}
+	slog.Info("Signing with device key", "fingerprint", fingerprint, "algorithm", key.Algorithm(), "expires_at", key.ExpiresAt)
+	signer := crypto.NewFileStructureSignerFromDeviceKey(key)
+	// Receivers that trusted a rotated key trust its replacement through these
+	endorsements, err := store.Endorsements(time.Now())
+	if err != nil {
+		slog.Warn("Failed to endorse device key with rotated keys", "error", err)
+	}
+	signer.SetEndorsements(endorsements)
+	return signer
+}
+
+// ephemeralSigner returns a one-time signer of the configured algorithm, or nil if none is configured
+func (a *App) ephemeralSigner() *crypto.FileStructureSigner {
+	if a.options.SignatureAlgorithm == "" {
+		return nil
+	}
+	signer, err := crypto.NewFileStructureSignerWithAlgorithm(a.options.SignatureAlgorithm)
+	if err != nil {
+		slog.Warn("Failed to create signer, using the default algorithm", "error", err)
+		return nil
+	}
+	return signer
+}
@@ -0,0 +1,120 @@
+package sender
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/rescp17/lanFileSharer/internal/app_events/sender"
+	"github.com/rescp17/lanFileSharer/pkg/discovery"
+	"github.com/rescp17/lanFileSharer/pkg/fileInfo"
+	"github.com/rescp17/lanFileSharer/pkg/transfer"
+)
+
+// transferOutcome collects the final status of the files of a transfer from the transfer
+// manager of each of its sessions. A file completed by a later session of the transfer,
+// after reconnecting, no longer counts as failed.
+type transferOutcome struct {
+	mu       sync.Mutex
+	managers []*transfer.UnifiedTransferManager
+}
+
+func newTransferOutcome() *transferOutcome {
+	return &transferOutcome{}
+}
+
+// watch adds the transfer manager of the next session of the transfer
+func (o *transferOutcome) watch(utm *transfer.UnifiedTransferManager) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.managers = append(o.managers, utm)
+}
+
+// files returns the final status of every finished file, the latest session's first
+func (o *transferOutcome) files() map[string]transfer.TransferStatus {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	files := make(map[string]transfer.TransferStatus)
+	for _, utm := range o.managers {
+		for path, status := range utm.GetFinishedFiles() {
+			files[path] = status
+		}
+	}
+	return files
+}
+
+// failed returns the files that failed, sorted by path
+func (o *transferOutcome) failed() []sender.FailedFile {
+	failed := make([]sender.FailedFile, 0)
+	for path, status := range o.files() {
+		if status.State != transfer.TransferStateFailed {
+			continue
+		}
+		reason := "unknown error"
+		if status.LastError != nil {
+			reason = status.LastError.Error()
+		}
+		failed = append(failed, sender.FailedFile{Path: path, Reason: reason})
+	}
+	sort.Slice(failed, func(i, j int) bool { return failed[i].Path < failed[j].Path })
+	return failed
+}
+
+// counts returns how many files were sent and how many failed
+func (o *transferOutcome) counts() (sent, failed int) {
+	for _, status := range o.files() {
+		switch status.State {
+		case transfer.TransferStateCompleted:
+			sent++
+		case transfer.TransferStateFailed:
+			failed++
+		}
+	}
+	return sent, failed
+}
+
+// setOutcome makes the sessions of the running transfer report to outcome
+func (a *App) setOutcome(outcome *transferOutcome) {
+	a.transferMu.Lock()
+	defer a.transferMu.Unlock()
+	a.outcome = outcome
+}
+
+// reportFailedFiles tells the UI which of files the transfer to receiver failed to deliver
+func (a *App) reportFailedFiles(receiver discovery.ServiceInfo, files []fileInfo.FileNode, outcome *transferOutcome) {
+	list := outcome.failed()
+	if len(list) == 0 {
+		return
+	}
+	paths := make(map[string]bool, len(list))
+	for _, file := range list {
+		paths[file.Path] = true
+	}
+	a.uiMessages <- sender.FilesFailedMsg{
+		Receiver: receiver,
+		Files:    list,
+		Retry:    onlyFiles(files, paths),
+	}
+}
+
+// onlyFiles returns files without the regular files not in paths, so they land where they
+// did the first time. Directories left without any file are dropped and the rest get their
+// size and checksum recomputed.
+func onlyFiles(files []fileInfo.FileNode, paths map[string]bool) []fileInfo.FileNode {
+	kept := make([]fileInfo.FileNode, 0, len(files))
+	for _, node := range files {
+		if !node.IsDir {
+			if paths[node.Path] {
+				kept = append(kept, node)
+			}
+			continue
+		}
+		children := onlyFiles(node.Children, paths)
+		if len(children) == 0 {
+			continue
+		}
+		node.Children = children
+		node.SummarizeDir()
+		kept = append(kept, node)
+	}
+	return kept
+}
@@ -0,0 +1,94 @@
+package sender
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rescp17/lanFileSharer/pkg/fileInfo"
+	"github.com/rescp17/lanFileSharer/pkg/transfer"
+)
+
+// finishSession runs a session over paths, failing the files in failures and completing the others
+func finishSession(t *testing.T, paths []string, failures map[string]error) *transfer.UnifiedTransferManager {
+	utm := transfer.NewUnifiedTransferManager("test-outcome")
+	t.Cleanup(utm.Shutdown)
+	for _, path := range paths {
+		node, err := fileInfo.CreateNode(path)
+		require.NoError(t, err)
+		require.NoError(t, utm.AddFile(&node))
+	}
+	for _, path := range paths {
+		require.NoError(t, utm.StartTransfer(path))
+		if err, ok := failures[path]; ok {
+			require.NoError(t, utm.FailTransfer(path, err))
+		} else {
+			require.NoError(t, utm.CompleteTransfer(path))
+		}
+	}
+	require.NoError(t, utm.Close())
+	return utm
+}
+
+func TestTransferOutcome_Failed(t *testing.T) {
+	dir := t.TempDir()
+	var paths []string
+	for _, name := range []string{"a.txt", "b.txt", "c.txt"} {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(name), 0644))
+		paths = append(paths, path)
+	}
+	a, b, c := paths[0], paths[1], paths[2]
+
+	outcome := newTransferOutcome()
+	outcome.watch(finishSession(t, paths, map[string]error{
+		a: fmt.Errorf("%w: no space left", transfer.ErrDiskFull),
+		c: fmt.Errorf("%w: bad data", transfer.ErrChecksumMismatch),
+	}))
+	// Sent after reconnecting
+	outcome.watch(finishSession(t, []string{c}, nil))
+
+	failed := outcome.failed()
+	require.Len(t, failed, 1, "%s was sent and %s was sent after reconnecting", b, c)
+	assert.Equal(t, a, failed[0].Path)
+	assert.Contains(t, failed[0].Reason, "no space left")
+
+	sent, failedCount := outcome.counts()
+	assert.Equal(t, []int{2, 1}, []int{sent, failedCount})
+}
+
+func TestOnlyFiles(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "sub"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "done"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "top.txt"), []byte("top"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "sub", "failed.txt"), []byte("failed"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "sub", "sent.txt"), []byte("sent"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "done", "sent.txt"), []byte("sent"), 0644))
+	single := filepath.Join(t.TempDir(), "single.txt")
+	require.NoError(t, os.WriteFile(single, []byte("single"), 0644))
+
+	root, err := fileInfo.CreateNode(dir)
+	require.NoError(t, err)
+	file, err := fileInfo.CreateNode(single)
+	require.NoError(t, err)
+
+	retry := onlyFiles([]fileInfo.FileNode{root, file}, map[string]bool{
+		filepath.Join(dir, "sub", "failed.txt"): true,
+	})
+	require.Len(t, retry, 1, "the fully sent file is dropped")
+	assert.Equal(t, root.Name, retry[0].Name)
+	require.Len(t, retry[0].Children, 1, "directories without failed files are dropped")
+	sub := retry[0].Children[0]
+	assert.Equal(t, "sub", sub.Name)
+	require.Len(t, sub.Children, 1)
+	assert.Equal(t, "failed.txt", sub.Children[0].Name)
+	assert.Equal(t, int64(len("failed")), sub.Size)
+	assert.Equal(t, int64(len("failed")), retry[0].Size)
+	assert.NotEqual(t, root.Checksum, retry[0].Checksum)
+	assert.Len(t, root.Children, 3, "the selection is left as it was")
+}
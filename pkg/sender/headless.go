@@ -0,0 +1,102 @@
+package sender
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/rescp17/lanFileSharer/api"
+	appevents "github.com/rescp17/lanFileSharer/internal/app_events"
+	"github.com/rescp17/lanFileSharer/internal/app_events/sender"
+	"github.com/rescp17/lanFileSharer/pkg/discovery"
+	"github.com/rescp17/lanFileSharer/pkg/fileInfo"
+)
+
+// ErrReceiverNotFound is returned when no receiver matching the requested name is discovered
+var ErrReceiverNotFound = errors.New("receiver not found")
+
+// matchesReceiver reports whether a discovered service matches the requested peer name.
+// Service names are "<hostname>-<id>", so a bare hostname matches as well.
+func matchesReceiver(service discovery.ServiceInfo, name string) bool {
+	if strings.EqualFold(service.Name, name) {
+		return true
+	}
+	return strings.HasPrefix(strings.ToLower(service.Name), strings.ToLower(name)+"-")
+}
+
+// FindReceiver discovers receivers until one matching name appears or ctx is done
+func (a *App) FindReceiver(ctx context.Context, name string) (discovery.ServiceInfo, error) {
+	dctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	serviceChan := a.discoverer.Discover(dctx, a.scope().Query())
+	for {
+		select {
+		case <-ctx.Done():
+			return discovery.ServiceInfo{}, fmt.Errorf("%w: %s: %w", ErrReceiverNotFound, name, ctx.Err())
+		case result, ok := <-serviceChan:
+			if !ok {
+				return discovery.ServiceInfo{}, fmt.Errorf("%w: %s", ErrReceiverNotFound, name)
+			}
+			if result.Error != nil {
+				return discovery.ServiceInfo{}, fmt.Errorf("failed to discover service: %w", result.Error)
+			}
+			for _, service := range a.filterServices(result.Services) {
+				if matchesReceiver(service, name) {
+					return service, nil
+				}
+			}
+		}
+	}
+}
+
+// SendHeadless sends files to receiver without the TUI and blocks until the transfer ends.
+// Every app message is passed to onMessage, which may be nil.
+func (a *App) SendHeadless(ctx context.Context, receiver discovery.ServiceInfo, files []fileInfo.FileNode, onMessage func(tea.Msg)) error {
+	a.StartSendProcess(ctx, receiver, files)
+	return a.awaitHeadless(ctx, onMessage)
+}
+
+// SendBatchHeadless is SendHeadless for a batch of items, see StartBatchSendProcess
+func (a *App) SendBatchHeadless(ctx context.Context, receiver discovery.ServiceInfo, items []sender.BatchItem, onMessage func(tea.Msg)) error {
+	a.StartBatchSendProcess(ctx, receiver, items)
+	return a.awaitHeadless(ctx, onMessage)
+}
+
+// FileCounts returns how many files the last transfer sent and how many failed, from the
+// final status of its files in every session
+func (a *App) FileCounts() (sent, failed int) {
+	a.transferMu.RLock()
+	outcome := a.outcome
+	a.transferMu.RUnlock()
+	if outcome == nil {
+		return 0, 0
+	}
+	return outcome.counts()
+}
+
+// awaitHeadless passes app messages to onMessage until the started transfer ends
+func (a *App) awaitHeadless(ctx context.Context, onMessage func(tea.Msg)) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case msg := <-a.uiMessages:
+			if onMessage != nil {
+				onMessage(msg)
+			}
+			switch m := msg.(type) {
+			case sender.TransferCompleteMsg:
+				return nil
+			case sender.TransferCancelledMsg:
+				return fmt.Errorf("transfer cancelled: %w", context.Canceled)
+			case sender.RequestTimedOutMsg:
+				return fmt.Errorf("%s did not answer: %w", m.Receiver.Name, api.ErrRequestTimedOut)
+			case appevents.Error:
+				return m.Err
+			}
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package sender
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rescp17/lanFileSharer/pkg/discovery"
+)
+
+// staticDiscoveryAdapter reports a fixed set of services once
+type staticDiscoveryAdapter struct {
+	services []discovery.ServiceInfo
+}
+
+func (s *staticDiscoveryAdapter) Announce(ctx context.Context, service discovery.ServiceInfo) error {
+	return nil
+}
+
+func (s *staticDiscoveryAdapter) Discover(ctx context.Context, service string) <-chan discovery.DiscoveryResult {
+	ch := make(chan discovery.DiscoveryResult, 1)
+	ch <- discovery.DiscoveryResult{Services: s.services}
+	go func() {
+		<-ctx.Done()
+		close(ch)
+	}()
+	return ch
+}
+
+func TestMatchesReceiver(t *testing.T) {
+	service := discovery.ServiceInfo{Name: "laptop-1a2b3c4d"}
+
+	assert.True(t, matchesReceiver(service, "laptop-1a2b3c4d"))
+	assert.True(t, matchesReceiver(service, "laptop"))
+	assert.True(t, matchesReceiver(service, "LAPTOP"))
+	assert.False(t, matchesReceiver(service, "lap"))
+	assert.False(t, matchesReceiver(service, "desktop"))
+}
+
+func TestFindReceiver(t *testing.T) {
+	app := NewApp(&staticDiscoveryAdapter{services: []discovery.ServiceInfo{
+		{Name: "desktop-00000000"},
+		{Name: "laptop-1a2b3c4d", Port: 8080},
+	}})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	service, err := app.FindReceiver(ctx, "laptop")
+	require.NoError(t, err)
+	assert.Equal(t, "laptop-1a2b3c4d", service.Name)
+	assert.Equal(t, 8080, service.Port)
+}
+
+func TestFindReceiver_NotFound(t *testing.T) {
+	app := NewApp(&staticDiscoveryAdapter{services: []discovery.ServiceInfo{{Name: "desktop-00000000"}}})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	_, err := app.FindReceiver(ctx, "laptop")
+	assert.True(t, errors.Is(err, ErrReceiverNotFound))
+}
+
+func TestFindReceiver_FiltersOtherServiceTypes(t *testing.T) {
+	scope := discovery.Scope{Type: "_acme-share._tcp", Domain: "local"}
+	app := NewAppWithOptions(&staticDiscoveryAdapter{services: []discovery.ServiceInfo{
+		{Name: "laptop-00000000", Type: discovery.DefaultServerType, Domain: "local"},
+		{Name: "laptop-1a2b3c4d", Type: "_acme-share._tcp", Domain: "local", Port: 8080},
+	}}, Options{Scope: scope})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	service, err := app.FindReceiver(ctx, "laptop")
+	require.NoError(t, err)
+	assert.Equal(t, "laptop-1a2b3c4d", service.Name, "the receiver of the other team is skipped")
+}
@@ -0,0 +1,176 @@
+package sender
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"time"
+
+	"github.com/rescp17/lanFileSharer/api"
+	"github.com/rescp17/lanFileSharer/pkg/crypto"
+	"github.com/rescp17/lanFileSharer/pkg/discovery"
+	"github.com/rescp17/lanFileSharer/pkg/fileInfo"
+	"github.com/rescp17/lanFileSharer/pkg/peers"
+	"github.com/rescp17/lanFileSharer/pkg/transfer"
+	"github.com/rescp17/lanFileSharer/pkg/webhook"
+)
+
+// Options configures optional sender behaviour
+type Options struct {
+	// OnSendStart is a shell command run before a transfer starts; a non-zero exit aborts the transfer
+	OnSendStart string
+	// OnSendComplete is a shell command run after a transfer finishes, successfully or not
+	OnSendComplete string
+	// Webhooks are notified when a transfer starts and when it completes or fails; nil
+	// notifies none
+	Webhooks *webhook.Notifier
+	// GenerateManifest prepends a checksums.sha256 manifest describing the sent tree
+	GenerateManifest bool
+	// TransferTimeout bounds a whole transfer; zero uses the default of two minutes
+	TransferTimeout time.Duration
+	// ResumeToken resumes an interrupted session; empty starts a new one
+	ResumeToken string
+	// SentCachePath is where files delivered to each peer are recorded so unchanged
+	// files are skipped next time; empty disables the cache
+	SentCachePath string
+	// ForceResend sends every file even if the sent cache says the peer already has it
+	ForceResend bool
+	// DeviceKeyPath is the key store holding the device signing key; empty signs each
+	// transfer with a one-time key
+	DeviceKeyPath string
+	// KeyLifetime and KeyGrace control device key rotation; zero uses the crypto defaults
+	KeyLifetime time.Duration
+	KeyGrace    time.Duration
+	// SignatureAlgorithm is the algorithm of new signing keys; empty uses the crypto default
+	SignatureAlgorithm crypto.SignatureAlgorithm
+	// RetryPolicy controls how failed files are retried; nil uses the transfer default
+	RetryPolicy *transfer.RetryPolicy
+	// IgnoreService is the name of a receiver announced by this instance, hidden from discovery
+	IgnoreService string
+	// SpeedProbeSize is how much random data is sent to measure the connection before
+	// large transfers; zero disables the probe
+	SpeedProbeSize int64
+	// ChunkSize is the size files are sent in; zero uses the transfer default
+	ChunkSize int32
+	// PeerSettings are the settings saved for individual receivers, applied when one is
+	// sent to; nil applies none
+	PeerSettings *peers.Store
+	// BandwidthSchedule limits how fast files are sent by time of day, together with the
+	// bandwidth limit saved for the receiver; nil leaves sending unlimited
+	BandwidthSchedule transfer.BandwidthSchedule
+	// Battery warns before large sends on battery and may slow them down to save power; the
+	// zero value checks nothing
+	Battery BatteryPolicy
+	// Scope is the service type and domain receivers are looked up in; zero uses the default
+	Scope discovery.Scope
+	// PeerFilter hides receivers outside its allowed subnets from discovery; nil shows all
+	PeerFilter *api.PeerFilter
+	// LANOnly keeps connections to private and link-local addresses, without STUN or TURN
+	LANOnly bool
+	// Offline skips STUN, TURN and multicast DNS, for receivers given by address
+	Offline bool
+	// PSK signs requests and checks answers with a pre-shared key; nil uses none
+	PSK *api.PSK
+	// Chaos drops, delays, reorders and corrupts sent chunks, for testing only; the zero
+	// value sends them untouched
+	Chaos transfer.ChaosConfig
+	// RelayTo is the receiver the files are for when the receiver sent to is a relay, which
+	// stores them and forwards them there; empty sends to the receiver itself
+	RelayTo string
+	// PullToken answers a pull of the receiver, which then accepts the files without asking;
+	// empty offers them unasked
+	PullToken string
+	// GuestToken is a guest pass the receiver issued, which accepts one transfer without
+	// asking; empty gives none
+	GuestToken string
+	// Icon is the emoji or glyph receivers show this device with when it asks to send; empty
+	// sends none
+	Icon string
+	// IdleTimeout ends a session whose receiver sent nothing, not even a keepalive, for this
+	// long and reconnects to resume it; zero never ends it
+	IdleTimeout time.Duration
+	// Bonding opens extra connections to the receiver over the other local interfaces and
+	// stripes large files over all of them; experimental
+	Bonding bool
+}
+
+// hookEnv describes a transfer to hook commands through environment variables
+type hookEnv struct {
+	receiver discovery.ServiceInfo
+	files    []fileInfo.FileNode
+	err      error
+}
+
+// totalSize returns the size of the files of the transfer
+func (h hookEnv) totalSize() int64 {
+	var totalSize int64
+	for _, f := range h.files {
+		totalSize += f.Size
+	}
+	return totalSize
+}
+
+func (h hookEnv) environ() []string {
+	totalSize := h.totalSize()
+
+	status := "success"
+	errMsg := ""
+	if h.err != nil {
+		status = "failed"
+		errMsg = h.err.Error()
+	}
+
+	return append(os.Environ(),
+		"LANFILESHARER_RECEIVER="+h.receiver.Name,
+		"LANFILESHARER_FILE_COUNT="+strconv.Itoa(len(h.files)),
+		"LANFILESHARER_TOTAL_SIZE="+strconv.FormatInt(totalSize, 10),
+		"LANFILESHARER_STATUS="+status,
+		"LANFILESHARER_ERROR="+errMsg,
+	)
+}
+
+// webhookEvent describes the transfer to webhooks: as started, or as completed or failed
+// when finished
+func (h hookEnv) webhookEvent(finished bool) webhook.Event {
+	event := webhook.Event{
+		Event: webhook.EventSessionStart,
+		Role:  webhook.RoleSender,
+		Peer:  h.receiver.Name,
+		Files: len(h.files),
+		Bytes: h.totalSize(),
+	}
+	if finished {
+		event.Event = webhook.EventSessionComplete
+		if h.err != nil {
+			event.Event = webhook.EventSessionFail
+			event.Error = h.err.Error()
+		}
+	}
+	return event
+}
+
+// runHook runs command through the platform shell with the transfer environment
+func runHook(ctx context.Context, name, command string, env hookEnv) error {
+	if command == "" {
+		return nil
+	}
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+	cmd.Env = env.environ()
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s hook failed: %w (output: %s)", name, err, output)
+	}
+	slog.Info("Hook completed", "hook", name, "output", string(output))
+	return nil
+}
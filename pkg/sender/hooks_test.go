@@ -0,0 +1,78 @@
+package sender
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rescp17/lanFileSharer/pkg/discovery"
+	"github.com/rescp17/lanFileSharer/pkg/fileInfo"
+	"github.com/rescp17/lanFileSharer/pkg/webhook"
+)
+
+func TestHookEnv(t *testing.T) {
+	env := hookEnv{
+		receiver: discovery.ServiceInfo{Name: "peer"},
+		files:    []fileInfo.FileNode{{Name: "a", Size: 10}, {Name: "b", Size: 5}},
+		err:      errors.New("boom"),
+	}.environ()
+
+	assert.Contains(t, env, "LANFILESHARER_RECEIVER=peer")
+	assert.Contains(t, env, "LANFILESHARER_FILE_COUNT=2")
+	assert.Contains(t, env, "LANFILESHARER_TOTAL_SIZE=15")
+	assert.Contains(t, env, "LANFILESHARER_STATUS=failed")
+	assert.Contains(t, env, "LANFILESHARER_ERROR=boom")
+}
+
+func TestHookEnv_WebhookEvent(t *testing.T) {
+	env := hookEnv{
+		receiver: discovery.ServiceInfo{Name: "peer"},
+		files:    []fileInfo.FileNode{{Name: "a", Size: 10}, {Name: "b", Size: 5}},
+	}
+
+	started := env.webhookEvent(false)
+	assert.Equal(t, webhook.EventSessionStart, started.Event)
+	assert.Equal(t, webhook.RoleSender, started.Role)
+	assert.Equal(t, "peer", started.Peer)
+	assert.Equal(t, 2, started.Files)
+	assert.Equal(t, int64(15), started.Bytes)
+
+	assert.Equal(t, webhook.EventSessionComplete, env.webhookEvent(true).Event)
+
+	env.err = errors.New("boom")
+	failed := env.webhookEvent(true)
+	assert.Equal(t, webhook.EventSessionFail, failed.Event)
+	assert.Equal(t, "boom", failed.Error)
+}
+
+func TestRunHook(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hook tests use POSIX shell syntax")
+	}
+
+	t.Run("empty command is a no-op", func(t *testing.T) {
+		assert.NoError(t, runHook(context.Background(), "noop", "", hookEnv{}))
+	})
+
+	t.Run("command receives environment", func(t *testing.T) {
+		out := filepath.Join(t.TempDir(), "out")
+		env := hookEnv{receiver: discovery.ServiceInfo{Name: "peer"}}
+		require.NoError(t, runHook(context.Background(), "test", `printf "$LANFILESHARER_RECEIVER" > `+out, env))
+
+		content, err := os.ReadFile(out)
+		require.NoError(t, err)
+		assert.Equal(t, "peer", string(content))
+	})
+
+	t.Run("non-zero exit returns error", func(t *testing.T) {
+		err := runHook(context.Background(), "test", "exit 3", hookEnv{})
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "test hook failed")
+	})
+}
@@ -0,0 +1,68 @@
+package sender
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/rescp17/lanFileSharer/pkg/fileInfo"
+)
+
+// ManifestFileName is the name of the generated manifest, compatible with `sha256sum -c`
+const ManifestFileName = "checksums.sha256"
+
+// buildManifest renders one "<sha256>  <relative path>" line per file in the tree
+func buildManifest(nodes []fileInfo.FileNode) string {
+	var b strings.Builder
+	var walk func(node fileInfo.FileNode, prefix string)
+	walk = func(node fileInfo.FileNode, prefix string) {
+		rel := path.Join(prefix, node.Name)
+		if node.IsDir {
+			for _, child := range node.Children {
+				walk(child, rel)
+			}
+			return
+		}
+		fmt.Fprintf(&b, "%s  %s\n", node.Checksum, rel)
+	}
+	for _, node := range nodes {
+		walk(node, "")
+	}
+	return b.String()
+}
+
+// writeManifest writes the manifest into dir and returns its file node
+func writeManifest(dir string, nodes []fileInfo.FileNode) (fileInfo.FileNode, error) {
+	manifestPath := filepath.Join(dir, ManifestFileName)
+	if err := os.WriteFile(manifestPath, []byte(buildManifest(nodes)), 0644); err != nil {
+		return fileInfo.FileNode{}, fmt.Errorf("failed to write manifest: %w", err)
+	}
+
+	node, err := fileInfo.CreateNode(manifestPath)
+	if err != nil {
+		return fileInfo.FileNode{}, fmt.Errorf("failed to create manifest node: %w", err)
+	}
+	return node, nil
+}
+
+// prependManifest generates a manifest for files in a temporary directory and prepends it.
+// The returned cleanup function removes the temporary directory.
+func prependManifest(files []fileInfo.FileNode) ([]fileInfo.FileNode, func(), error) {
+	dir, err := os.MkdirTemp("", "lanfilesharer-manifest-")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create manifest directory: %w", err)
+	}
+	cleanup := func() {
+		_ = os.RemoveAll(dir)
+	}
+
+	node, err := writeManifest(dir, files)
+	if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+
+	return append([]fileInfo.FileNode{node}, files...), cleanup, nil
+}
@@ -0,0 +1,53 @@
+package sender
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rescp17/lanFileSharer/pkg/fileInfo"
+)
+
+func TestBuildManifest(t *testing.T) {
+	nodes := []fileInfo.FileNode{
+		{Name: "a.txt", Checksum: "aaa"},
+		{
+			Name:  "dir",
+			IsDir: true,
+			Children: []fileInfo.FileNode{
+				{Name: "b.txt", Checksum: "bbb"},
+				{Name: "sub", IsDir: true, Children: []fileInfo.FileNode{{Name: "c.txt", Checksum: "ccc"}}},
+			},
+		},
+	}
+
+	expected := "aaa  a.txt\nbbb  dir/b.txt\nccc  dir/sub/c.txt\n"
+	assert.Equal(t, expected, buildManifest(nodes))
+}
+
+func TestPrependManifest(t *testing.T) {
+	dir := t.TempDir()
+	filePath := filepath.Join(dir, "data.txt")
+	require.NoError(t, os.WriteFile(filePath, []byte("hello"), 0644))
+
+	node, err := fileInfo.CreateNode(filePath)
+	require.NoError(t, err)
+
+	files, cleanup, err := prependManifest([]fileInfo.FileNode{node})
+	require.NoError(t, err)
+
+	require.Len(t, files, 2)
+	assert.Equal(t, ManifestFileName, files[0].Name)
+	assert.Equal(t, "data.txt", files[1].Name)
+
+	content, err := os.ReadFile(files[0].Path)
+	require.NoError(t, err)
+	assert.Equal(t, node.Checksum+"  data.txt\n", string(content))
+
+	cleanup()
+	_, err = os.Stat(files[0].Path)
+	assert.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,116 @@
+package sender
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/rescp17/lanFileSharer/internal/app_events/sender"
+	"github.com/rescp17/lanFileSharer/internal/util"
+	"github.com/rescp17/lanFileSharer/pkg/transfer"
+	webrtcPkg "github.com/rescp17/lanFileSharer/pkg/webrtc"
+)
+
+// wiredHintMinBytes is the size from which a transfer going over Wi-Fi is worth moving to a
+// wired interface
+const wiredHintMinBytes = 1 << 30
+
+// errWiredPath ends a session the user moves to the wired interfaces; a new session resumes it
+var errWiredPath = fmt.Errorf("%w: moving to the wired interfaces", transfer.ErrSessionIdle)
+
+// wiredPath returns the wired interfaces the user moved the running transfer to, nil if none
+func (a *App) wiredPath() []string {
+	a.transferMu.RLock()
+	defer a.transferMu.RUnlock()
+	return a.wiredInterfaces
+}
+
+// resetWiredPath lets the next session gather candidates on every interface again
+func (a *App) resetWiredPath() {
+	a.transferMu.Lock()
+	defer a.transferMu.Unlock()
+	a.wiredInterfaces = nil
+}
+
+// setSessionCancel records cancel to end the running session with, nil once it ended
+func (a *App) setSessionCancel(cancel context.CancelCauseFunc) {
+	a.transferMu.Lock()
+	defer a.transferMu.Unlock()
+	a.cancelSession = cancel
+}
+
+// watchPath warns the user once when ICE selects a Wi-Fi path for a large transfer while both
+// this machine and the receiver are also wired
+func (a *App) watchPath(conn webrtcPkg.SenderConnection, attempt sendAttempt) {
+	total := attempt.fileStructure.GetTotalSize()
+	if total < wiredHintMinBytes || !attempt.receiver.Meta.Wired || a.wiredPath() != nil {
+		return
+	}
+	var once sync.Once
+	conn.OnSelectedPath(func(path webrtcPkg.Path) {
+		iface, ok := localInterface(path, attempt.receiver.Addr)
+		if !ok || util.InterfaceLink(iface.Name) != util.LinkWireless {
+			return
+		}
+		wired := wiredInterfaceNames()
+		if len(wired) == 0 {
+			return
+		}
+		once.Do(func() {
+			slog.Info("Large transfer goes over Wi-Fi while wired interfaces exist", "interface", iface.Name, "wired", wired)
+			// The ICE agent runs this callback, so it must not wait on the UI
+			go func() {
+				a.uiMessages <- sender.WirelessPathMsg{Interface: iface.Name, Wired: wired, TotalBytes: total}
+			}()
+		})
+	})
+}
+
+// localInterface returns the interface of this machine that path leaves from. A local candidate
+// carrying an mDNS name is found by the route to the remote address, or to fallback, the address
+// the receiver was discovered at, when the remote candidate is an mDNS name as well.
+func localInterface(path webrtcPkg.Path, fallback net.IP) (net.Interface, bool) {
+	if ip := net.ParseIP(path.Local); ip != nil {
+		return util.InterfaceByIP(ip)
+	}
+	if ip := net.ParseIP(path.Remote); ip != nil {
+		return util.RouteInterface(ip)
+	}
+	return util.RouteInterface(fallback)
+}
+
+// wiredInterfaceNames returns the names of util.WiredInterfaces
+func wiredInterfaceNames() []string {
+	var names []string
+	for _, iface := range util.WiredInterfaces() {
+		names = append(names, iface.Name)
+	}
+	return names
+}
+
+// handleUseWiredPath moves the running transfer to the wired interfaces: its session ends and a
+// new one, gathering candidates on those interfaces only, resumes it
+func (a *App) handleUseWiredPath() {
+	wired := wiredInterfaceNames()
+	a.transferMu.Lock()
+	cancel := a.cancelSession
+	if cancel != nil && len(wired) > 0 {
+		a.wiredInterfaces = wired
+	}
+	a.transferMu.Unlock()
+
+	if cancel == nil {
+		slog.Warn("No active transfer to move to the wired interfaces")
+		return
+	}
+	if len(wired) == 0 {
+		a.uiMessages <- sender.StatusUpdateMsg{Message: "No wired interface is connected anymore"}
+		return
+	}
+	slog.Info("Moving transfer to the wired interfaces", "interfaces", wired)
+	a.uiMessages <- sender.StatusUpdateMsg{Message: fmt.Sprintf("Moving the transfer to %s...", strings.Join(wired, ", "))}
+	cancel(errWiredPath)
+}
@@ -0,0 +1,22 @@
+package sender
+
+import (
+	"net"
+	"testing"
+
+	webrtcPkg "github.com/rescp17/lanFileSharer/pkg/webrtc"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocalInterface(t *testing.T) {
+	for name, path := range map[string]webrtcPkg.Path{
+		"local address":   {Local: "127.0.0.1", Remote: "abc.local"},
+		"mDNS local name": {Local: "abc.local", Remote: "127.0.0.1"},
+		"mDNS both names": {Local: "abc.local", Remote: "def.local"},
+	} {
+		iface, ok := localInterface(path, net.ParseIP("127.0.0.1"))
+		if assert.True(t, ok, name) {
+			assert.NotZero(t, iface.Flags&net.FlagLoopback, name)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package sender
+
+import (
+	"fmt"
+
+	"github.com/rescp17/lanFileSharer/internal/app_events/sender"
+	"github.com/rescp17/lanFileSharer/pkg/discovery"
+	"github.com/rescp17/lanFileSharer/pkg/peers"
+	webrtcPkg "github.com/rescp17/lanFileSharer/pkg/webrtc"
+)
+
+// peerSettings returns the settings saved for receiver, looked up by the fingerprint it
+// advertises, and tells the user they apply. Receivers without settings get the zero value.
+func (a *App) peerSettings(receiver discovery.ServiceInfo) peers.Settings {
+	settings, ok := a.options.PeerSettings.Get(receiver.Meta.Fingerprint)
+	if !ok {
+		return peers.Settings{}
+	}
+	name := settings.Label
+	if name == "" {
+		name = receiver.Name
+	}
+	a.uiMessages <- sender.StatusUpdateMsg{Message: fmt.Sprintf("Applying the settings saved for %s", name)}
+	return settings
+}
+
+// webrtcAPIFor returns the WebRTC API to connect to a peer with settings: one gathering only on
+// the wired interfaces once the user moved the transfer there, one keeping the connection on the
+// local network if the settings ask for it, otherwise the app's own
+func (a *App) webrtcAPIFor(settings peers.Settings) *webrtcPkg.WebrtcAPI {
+	if wired := a.wiredPath(); len(wired) > 0 {
+		return webrtcPkg.NewWebrtcAPIWithOptions(webrtcPkg.APIOptions{
+			LANOnly:    settings.LANOnly() || a.options.LANOnly,
+			Offline:    a.options.Offline,
+			Interfaces: wired,
+		})
+	}
+	if settings.LANOnly() && !a.options.LANOnly {
+		return webrtcPkg.NewWebrtcAPIWithOptions(webrtcPkg.APIOptions{LANOnly: true, Offline: a.options.Offline})
+	}
+	return a.webrtcAPI
+}
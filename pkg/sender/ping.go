@@ -0,0 +1,47 @@
+package sender
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/pion/webrtc/v4"
+	"github.com/rescp17/lanFileSharer/api"
+	"github.com/rescp17/lanFileSharer/pkg/discovery"
+	webrtcPkg "github.com/rescp17/lanFileSharer/pkg/webrtc"
+)
+
+// receiverURL returns the base URL of the receiver's API
+func receiverURL(receiver discovery.ServiceInfo) string {
+	return fmt.Sprintf("http://%s", net.JoinHostPort(receiver.Addr.String(), fmt.Sprintf("%d", receiver.Port)))
+}
+
+// PingReceiver performs a signaling round trip to receiver and returns its answer and the round trip time
+func (a *App) PingReceiver(ctx context.Context, receiver discovery.ServiceInfo) (*api.PingResponse, time.Duration, error) {
+	start := time.Now()
+	ping, err := a.apiClient.Ping(ctx, receiverURL(receiver))
+	if err != nil {
+		return nil, 0, err
+	}
+	return ping, time.Since(start), nil
+}
+
+// EchoReceiver opens a data channel to receiver without asking its user and measures echo round trips
+func (a *App) EchoReceiver(ctx context.Context, receiver discovery.ServiceInfo, options webrtcPkg.EchoOptions) (*webrtcPkg.EchoResult, error) {
+	url := receiverURL(receiver)
+	exchange := func(ctx context.Context, offer webrtc.SessionDescription) (*webrtc.SessionDescription, error) {
+		return a.apiClient.Echo(ctx, url, offer)
+	}
+	return a.webrtcAPI.Echo(ctx, webrtcPkg.Config{}, exchange, options)
+}
+
+// TuneReceiver measures the throughput of several chunk sizes over a data channel to
+// receiver, without asking its user, and recommends one
+func (a *App) TuneReceiver(ctx context.Context, receiver discovery.ServiceInfo, options webrtcPkg.TuneOptions) (*webrtcPkg.TuneResult, error) {
+	url := receiverURL(receiver)
+	exchange := func(ctx context.Context, offer webrtc.SessionDescription) (*webrtc.SessionDescription, error) {
+		return a.apiClient.Echo(ctx, url, offer)
+	}
+	return a.webrtcAPI.TuneChunkSize(ctx, webrtcPkg.Config{}, exchange, options)
+}
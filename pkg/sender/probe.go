@@ -0,0 +1,61 @@
+package sender
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math"
+	"time"
+
+	"github.com/rescp17/lanFileSharer/internal/app_events/sender"
+	"github.com/rescp17/lanFileSharer/pkg/events"
+	webrtcPkg "github.com/rescp17/lanFileSharer/pkg/webrtc"
+)
+
+// speedProbeMinRatio is how many times larger than the probe a transfer must be to be
+// probed, which keeps the probe below a tenth of the data sent
+const speedProbeMinRatio = 10
+
+// probeSpeed measures the connection with SpeedProbeSize bytes of random data before a
+// large transfer and reports the rate and the resulting estimate, so the user can cancel
+// early on an unexpectedly slow path. A failed probe only loses the early estimate.
+func (a *App) probeSpeed(ctx context.Context, conn webrtcPkg.SenderConnection, totalBytes int64) {
+	size := a.options.SpeedProbeSize
+	if size <= 0 || totalBytes < size*speedProbeMinRatio {
+		return
+	}
+
+	a.uiMessages <- sender.StatusUpdateMsg{Message: "Measuring connection speed..."}
+	result, err := conn.ProbeSpeed(ctx, size)
+	if err != nil {
+		if errors.Is(err, webrtcPkg.ErrSpeedProbeUnsupported) {
+			slog.Info("Receiver does not support speed probes, skipping")
+		} else {
+			slog.Warn("Speed probe failed", "error", err)
+		}
+		return
+	}
+
+	rate := result.Rate()
+	if rate <= 0 {
+		return
+	}
+	a.probedRate.Store(math.Float64bits(rate))
+	eta := time.Duration(float64(totalBytes) / rate * float64(time.Second))
+	slog.Info("Speed probe finished", "bytes", result.Bytes, "duration", result.Duration, "rate", rate, "eta", eta)
+	a.uiMessages <- sender.SpeedProbeMsg{Rate: rate, TotalBytes: totalBytes, ETA: eta}
+}
+
+// estimateRemaining fills in the remaining time of progress from the probed rate until
+// the transfer measured a rate of its own
+func (a *App) estimateRemaining(progress events.SessionProgress) events.SessionProgress {
+	if progress.TransferRate > 0 || progress.RemainingTime > 0 {
+		return progress
+	}
+	rate := math.Float64frombits(a.probedRate.Load())
+	remaining := progress.TotalBytes - progress.BytesCompleted
+	if rate > 0 && remaining > 0 {
+		progress.RemainingTime = time.Duration(float64(remaining) / rate * float64(time.Second))
+	}
+	return progress
+}
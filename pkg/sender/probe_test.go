@@ -0,0 +1,42 @@
+package sender
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/rescp17/lanFileSharer/internal/app_events/sender"
+	"github.com/rescp17/lanFileSharer/pkg/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEstimateRemaining(t *testing.T) {
+	app := NewApp(nil)
+	progress := events.SessionProgress{TotalBytes: 1000, BytesCompleted: 200}
+
+	assert.Zero(t, app.estimateRemaining(progress).ETA(), "no estimate without a probe")
+
+	app.probedRate.Store(math.Float64bits(100))
+	assert.Equal(t, 8*time.Second, app.estimateRemaining(progress).ETA())
+
+	progress.TransferRate = 400
+	assert.Equal(t, 2*time.Second, app.estimateRemaining(progress).ETA(), "a measured rate wins over the probe")
+}
+
+func TestHandleCancelTransfer_AbortsPendingTransfer(t *testing.T) {
+	app := NewApp(nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	app.setCancelPending(cancel)
+
+	app.handleCancelTransfer()
+	assert.ErrorIs(t, ctx.Err(), context.Canceled)
+	assert.True(t, app.userCancelled.Load())
+	require.Len(t, app.uiMessages, 1)
+	assert.IsType(t, sender.TransferCancelledMsg{}, <-app.uiMessages)
+
+	// Nothing is left to cancel
+	app.handleCancelTransfer()
+	assert.Empty(t, app.uiMessages)
+}
@@ -0,0 +1,105 @@
+package sender
+
+import (
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"time"
+
+	"github.com/rescp17/lanFileSharer/internal/app_events/sender"
+	"github.com/rescp17/lanFileSharer/pkg/events"
+)
+
+// progressInterval throttles progress messages to the UI
+const progressInterval = 500 * time.Millisecond
+
+// Events returns the bus the sender's transfers publish their status changes to
+func (a *App) Events() *events.Bus {
+	return a.bus
+}
+
+// ReportWriteProgress publishes the bytes the receiver has written to disk (implements WriteProgressReporter)
+func (a *App) ReportWriteProgress(persisted, total int64, diskRate float64) {
+	a.bus.Publish(events.WriteProgress{
+		BytesPersisted: persisted,
+		TotalBytes:     total,
+		DiskWriteRate:  diskRate,
+		Time:           time.Now(),
+	})
+}
+
+// forwardProgress turns the session progress events of sub into UI messages until sub is closed.
+// Write progress reported by the receiver is merged into the latest session progress.
+func (a *App) forwardProgress(sub *events.Subscription) {
+	var (
+		last     time.Time
+		progress events.SessionProgress
+		written  events.WriteProgress
+	)
+	for event := range sub.C() {
+		switch e := event.(type) {
+		case events.SessionProgress:
+			if e.Time.Before(progress.Time) {
+				// Raised before the progress already seen, such as that of a file which finished since
+				continue
+			}
+			progress = e
+			// The last update is always forwarded so the UI does not stop short of 100%
+			finished := e.CompletedFiles+e.FailedFiles >= e.TotalFiles
+			if !finished && e.Time.Sub(last) < progressInterval {
+				continue
+			}
+			last = e.Time
+		case events.WriteProgress:
+			written = e
+			// The receiver may still be writing after the last chunk was sent
+			finished := e.TotalBytes > 0 && e.BytesPersisted >= e.TotalBytes
+			if progress.Time.IsZero() || !finished && e.Time.Sub(last) < progressInterval {
+				continue
+			}
+			last = e.Time
+		default:
+			continue
+		}
+
+		msg := progressUpdateMsg(a.estimateRemaining(progress))
+		msg.PersistedBytes, msg.ReceiverDiskRate = written.BytesPersisted, written.DiskWriteRate
+		select {
+		case a.uiMessages <- msg:
+		default:
+			// Don't block if UI channel is full
+			slog.Debug("UI channel full, skipping progress update")
+		}
+	}
+}
+
+// progressUpdateMsg converts a session progress event into the UI's progress message
+func progressUpdateMsg(progress events.SessionProgress) sender.ProgressUpdateMsg {
+	currentFile := ""
+	if progress.CurrentFile != "" {
+		currentFile = filepath.Base(progress.CurrentFile)
+	}
+	return sender.ProgressUpdateMsg{
+		TotalFiles:       progress.TotalFiles,
+		CompletedFiles:   progress.CompletedFiles,
+		TotalBytes:       progress.TotalBytes,
+		TransferredBytes: progress.BytesCompleted,
+		CurrentFile:      currentFile,
+		TransferRate:     progress.TransferRate,
+		FilesPerMinute:   progress.FilesPerMinute,
+		ETA:              formatETA(progress.ETA()),
+		OverallProgress:  progress.OverallProgress,
+		Phases:           progress.Phases,
+	}
+}
+
+// formatETA formats an ETA for display; unknown or very long estimates are left empty
+func formatETA(d time.Duration) string {
+	if d <= 0 || d >= time.Hour {
+		return ""
+	}
+	if d < time.Minute {
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	}
+	return fmt.Sprintf("%dm %ds", int(d.Minutes()), int(d.Seconds())%60)
+}
@@ -0,0 +1,67 @@
+package sender
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rescp17/lanFileSharer/internal/app_events/sender"
+	"github.com/rescp17/lanFileSharer/pkg/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestForwardProgress_MergesWriteProgress(t *testing.T) {
+	app := NewApp(nil)
+	sub := app.bus.Subscribe(0, events.TopicSession)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		app.forwardProgress(sub)
+	}()
+
+	now := time.Now()
+	// Write progress before any session progress has nothing to be merged into
+	app.bus.Publish(events.WriteProgress{BytesPersisted: 10, TotalBytes: 100, Time: now})
+	app.bus.Publish(events.SessionProgress{TotalFiles: 1, TotalBytes: 100, BytesCompleted: 100, Time: now})
+	// The receiver finishes writing after the sender has pushed every byte
+	app.bus.Publish(events.WriteProgress{BytesPersisted: 100, TotalBytes: 100, DiskWriteRate: 50, Time: now.Add(time.Millisecond)})
+
+	first := (<-app.uiMessages).(sender.ProgressUpdateMsg)
+	assert.Equal(t, int64(100), first.TransferredBytes)
+	assert.Equal(t, int64(10), first.PersistedBytes)
+
+	second, ok := (<-app.uiMessages).(sender.ProgressUpdateMsg)
+	require.True(t, ok)
+	assert.Equal(t, int64(100), second.PersistedBytes)
+	assert.Equal(t, 50.0, second.ReceiverDiskRate)
+
+	sub.Close()
+	<-done
+}
+
+func TestForwardProgress_IgnoresStaleProgress(t *testing.T) {
+	app := NewApp(nil)
+	sub := app.bus.Subscribe(0, events.TopicSession)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		app.forwardProgress(sub)
+	}()
+
+	now := time.Now()
+	app.bus.Publish(events.SessionProgress{TotalFiles: 1, CompletedFiles: 1, TotalBytes: 100, BytesCompleted: 100, Time: now})
+	app.bus.Publish(events.SessionProgress{TotalFiles: 1, TotalBytes: 100, BytesCompleted: 40, Time: now.Add(-time.Millisecond)})
+	app.bus.Publish(events.WriteProgress{BytesPersisted: 100, TotalBytes: 100, Time: now.Add(time.Millisecond)})
+
+	finished := (<-app.uiMessages).(sender.ProgressUpdateMsg)
+	assert.Equal(t, 1, finished.CompletedFiles)
+
+	// The stale update is neither forwarded nor merged with later write progress
+	written, ok := (<-app.uiMessages).(sender.ProgressUpdateMsg)
+	require.True(t, ok)
+	assert.Equal(t, 1, written.CompletedFiles)
+	assert.Equal(t, int64(100), written.TransferredBytes)
+
+	sub.Close()
+	<-done
+}
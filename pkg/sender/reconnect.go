@@ -0,0 +1,217 @@
+package sender
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/rescp17/lanFileSharer/api"
+	"github.com/rescp17/lanFileSharer/internal/app_events/sender"
+	"github.com/rescp17/lanFileSharer/internal/util"
+	"github.com/rescp17/lanFileSharer/pkg/crypto"
+	"github.com/rescp17/lanFileSharer/pkg/discovery"
+	"github.com/rescp17/lanFileSharer/pkg/transfer"
+	webrtcPkg "github.com/rescp17/lanFileSharer/pkg/webrtc"
+)
+
+const (
+	// reconnectBaseDelay is the wait before reconnecting to a receiver that went idle, doubled
+	// with every failed attempt
+	reconnectBaseDelay = time.Second
+	// reconnectMaxDelay bounds the wait between attempts while the receiver is still asleep
+	reconnectMaxDelay = 30 * time.Second
+)
+
+// sendAttempt is one session of a transfer with its receiver. A transfer starts another that
+// resumes the first when the receiver stopped responding.
+type sendAttempt struct {
+	receiver      discovery.ServiceInfo
+	resumeToken   string
+	fileStructure *transfer.FileStructureManager
+	batch         []transfer.BatchItem
+	reconnect     bool // Resumes a session that went idle
+}
+
+// reconnectDelay returns the wait before reconnect attempt n, counted from zero
+func reconnectDelay(n int) time.Duration {
+	if n >= 5 {
+		return reconnectMaxDelay
+	}
+	return min(reconnectBaseDelay<<n, reconnectMaxDelay)
+}
+
+// sendWithReconnect sends the files of attempt, reconnecting to resume the session each time
+// the receiver stops responding or this machine wakes from sleep, until ctx ends. Until the
+// receiver is back, only a refusal of the receiver stops the reconnect attempts.
+func (a *App) sendWithReconnect(ctx context.Context, attempt sendAttempt) error {
+	suspend := transfer.NewSuspendDetector()
+	defer a.resetWiredPath()
+	failed := 0
+	for {
+		accepted, err := a.sendSessionAwake(ctx, suspend, attempt)
+		if err == nil {
+			return nil
+		}
+		idle := errors.Is(err, transfer.ErrSessionIdle)
+		if ctx.Err() != nil || (!idle && (accepted || !attempt.reconnect || refused(err))) {
+			return err
+		}
+		switch {
+		case errors.Is(err, errWiredPath):
+			failed = 0
+			a.uiMessages <- sender.StatusUpdateMsg{Message: "Reconnecting over the wired interfaces to resume..."}
+		case errors.Is(err, transfer.ErrSystemSuspended):
+			failed = 0
+			a.uiMessages <- sender.StatusUpdateMsg{Message: "Woke from sleep, reconnecting to resume..."}
+		case idle:
+			failed = 0
+			a.uiMessages <- sender.StatusUpdateMsg{Message: "Receiver stopped responding, reconnecting to resume..."}
+		case a.wiredPath() != nil && !accepted:
+			// The receiver may not be reachable from the wired interfaces at all
+			a.resetWiredPath()
+			a.uiMessages <- sender.StatusUpdateMsg{Message: "Could not connect over the wired interfaces, reconnecting over any interface..."}
+		default:
+			slog.Info("Receiver is not back yet", "receiver", attempt.receiver.Name, "error", err)
+		}
+
+		delay := reconnectDelay(failed)
+		failed++
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return fmt.Errorf("%w: %w", err, ctx.Err())
+		}
+		attempt.reconnect = true
+	}
+}
+
+// sendSessionAwake is sendSession, ended with transfer.ErrSystemSuspended when this machine
+// slept meanwhile. Errors of a session that failed over a sleep, such as a lost connection,
+// are taken for the sleep as well, as the receiver gave up on the session while it lasted.
+func (a *App) sendSessionAwake(ctx context.Context, suspend *transfer.SuspendDetector, attempt sendAttempt) (bool, error) {
+	sessionCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	a.setSessionCancel(cancel)
+	defer a.setSessionCancel(nil)
+	suspend.Check()
+	go func() {
+		if _, ok := <-suspend.Watch(sessionCtx); ok {
+			cancel(transfer.ErrSystemSuspended)
+		}
+	}()
+
+	accepted, err := a.sendSession(sessionCtx, attempt)
+	if err == nil || ctx.Err() != nil {
+		return accepted, err
+	}
+	if errors.Is(context.Cause(sessionCtx), errWiredPath) {
+		return accepted, fmt.Errorf("%w: %w", errWiredPath, err)
+	}
+	if errors.Is(context.Cause(sessionCtx), transfer.ErrSystemSuspended) || suspend.Check() > 0 {
+		slog.Info("Session failed over a sleep of this machine", "error", err)
+		return accepted, fmt.Errorf("%w: %w", transfer.ErrSystemSuspended, err)
+	}
+	return accepted, err
+}
+
+// refused reports whether err is the receiver turning the transfer down, which ends reconnecting
+func refused(err error) bool {
+	return errors.Is(err, api.ErrTransferRejected) || errors.Is(err, api.ErrRequestTimedOut)
+}
+
+// sendSession connects to the receiver of attempt and sends the files. accepted reports whether
+// the receiver accepted the session before it failed.
+func (a *App) sendSession(ctx context.Context, attempt sendAttempt) (accepted bool, err error) {
+	// TODO: Use HTTPS for secure communication
+	receiverURL := receiverURL(attempt.receiver)
+
+	a.uiMessages <- sender.StatusUpdateMsg{Message: "Creating secure connection..."}
+
+	settings := a.peerSettings(attempt.receiver)
+	config := webrtcPkg.Config{}
+	webrtcConn, err := a.webrtcAPIFor(settings).NewSenderConnectionWithProgress(ctx, config, a.apiClient, receiverURL, a)
+	if err != nil {
+		return false, fmt.Errorf("failed to create webrtc connection: %w", err)
+	}
+	defer func() {
+		if err := webrtcConn.Close(); err != nil {
+			slog.Error("Failed to close webrtc connection", "error", err)
+		}
+	}()
+
+	// Every session of the transfer has the same token, so the receiver keeps its chunks
+	webrtcConn.SetResumeToken(attempt.resumeToken)
+	webrtcConn.SetIdleTimeout(a.options.IdleTimeout)
+	if a.options.RetryPolicy != nil {
+		webrtcConn.SetRetryPolicy(a.options.RetryPolicy)
+	}
+	if a.options.ChunkSize > 0 {
+		webrtcConn.SetChunkSize(a.options.ChunkSize)
+	}
+	bandwidthLimit := settings.BandwidthLimit()
+	if a.batterySaving(attempt) {
+		bandwidthLimit = a.options.Battery.limit(bandwidthLimit)
+		webrtcConn.SetParallelStreams(1)
+	}
+	if limiter := transfer.NewScheduledRateLimiter(bandwidthLimit, a.options.BandwidthSchedule, nil); limiter != nil {
+		webrtcConn.SetRateLimiter(limiter)
+	}
+	if a.options.Chaos.Enabled() {
+		webrtcConn.SetChaos(a.options.Chaos)
+	}
+	if a.options.RelayTo != "" {
+		webrtcConn.SetRelayTo(a.options.RelayTo)
+	}
+	if a.options.PullToken != "" {
+		webrtcConn.SetPullToken(a.options.PullToken)
+	}
+	if a.options.GuestToken != "" {
+		webrtcConn.SetGuestToken(a.options.GuestToken)
+	}
+	if icon := util.DeviceIcon(a.options.Icon); icon != "" {
+		webrtcConn.SetIcon(icon)
+	}
+	if !attempt.reconnect {
+		a.uiMessages <- sender.StatusUpdateMsg{Message: fmt.Sprintf("Resume token: %s", attempt.resumeToken)}
+	}
+
+	if signer := a.deviceSigner(); signer != nil {
+		webrtcConn.SetSigner(signer)
+		if publicKey, err := signer.GetPublicKeyBytes(); err == nil && !attempt.reconnect {
+			a.uiMessages <- sender.StatusUpdateMsg{Message: fmt.Sprintf("Key fingerprint: %s", crypto.PublicKeyFingerprint(publicKey))}
+		}
+	}
+
+	a.watchPath(webrtcConn, attempt)
+
+	a.uiMessages <- sender.StatusUpdateMsg{Message: "Establishing connection..."}
+	if err := webrtcConn.Establish(ctx, attempt.fileStructure); err != nil {
+		return false, fmt.Errorf("could not establish webrtc connection: %w", err)
+	}
+
+	if a.options.ResumeToken != "" || attempt.reconnect {
+		a.loadResumeState(ctx, webrtcConn, receiverURL, attempt.resumeToken)
+	}
+
+	a.setBatch(attempt.batch, webrtcConn, attempt.fileStructure)
+	if !attempt.reconnect {
+		a.uiMessages <- sender.ReceiverAcceptedMsg{}
+		a.probeSpeed(ctx, webrtcConn, attempt.fileStructure.GetTotalSize())
+	}
+
+	if a.options.Bonding {
+		a.addBondPaths(ctx, webrtcConn, attempt, settings)
+		defer a.reportPathStats(ctx, webrtcConn, attempt.receiver)()
+	}
+
+	a.uiMessages <- sender.StatusUpdateMsg{Message: "Connection established. Preparing to send files..."}
+
+	transferFiles := attempt.fileStructure.GetAllFileEntities()
+
+	if err := webrtcConn.SendFiles(ctx, transferFiles, a.serviceID); err != nil {
+		return true, fmt.Errorf("failed to send files: %w", err)
+	}
+	return true, nil
+}
@@ -0,0 +1,26 @@
+package sender
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/rescp17/lanFileSharer/api"
+	"github.com/rescp17/lanFileSharer/pkg/transfer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReconnectDelay(t *testing.T) {
+	assert.Equal(t, time.Second, reconnectDelay(0))
+	assert.Equal(t, 2*time.Second, reconnectDelay(1))
+	assert.Equal(t, 16*time.Second, reconnectDelay(4))
+	assert.Equal(t, reconnectMaxDelay, reconnectDelay(5))
+	assert.Equal(t, reconnectMaxDelay, reconnectDelay(100), "the delay must not overflow")
+}
+
+func TestRefused(t *testing.T) {
+	assert.True(t, refused(fmt.Errorf("could not establish webrtc connection: %w", api.ErrTransferRejected)))
+	assert.True(t, refused(api.ErrRequestTimedOut))
+	assert.False(t, refused(transfer.ErrSessionIdle))
+	assert.False(t, refused(transfer.ErrConnectionLost), "a receiver still asleep is tried again")
+}
@@ -0,0 +1,33 @@
+package sender
+
+import (
+	"log/slog"
+
+	"github.com/rescp17/lanFileSharer/internal/config"
+	"github.com/rescp17/lanFileSharer/pkg/transfer"
+)
+
+// ParseRetryPolicy builds the retry policy of failed files from cfg and validates it
+func ParseRetryPolicy(cfg config.Config) (*transfer.RetryPolicy, error) {
+	policy := transfer.DefaultRetryPolicy()
+	policy.MaxRetries = cfg.RetryMaxRetries
+	policy.InitialDelay = cfg.RetryInitialDelay()
+	policy.BackoffFactor = cfg.RetryBackoffFactor
+	policy.MaxDelay = cfg.RetryMaxDelay()
+	policy.RetryableClasses = append([]string{}, cfg.RetryOn...)
+
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+// RetryPolicy returns the configured retry policy of failed files, falling back to the default if it is invalid
+func RetryPolicy(cfg config.Config) *transfer.RetryPolicy {
+	policy, err := ParseRetryPolicy(cfg)
+	if err != nil {
+		slog.Warn("Invalid retry settings in config, using default policy", "error", err)
+		return transfer.DefaultRetryPolicy()
+	}
+	return policy
+}
@@ -0,0 +1,40 @@
+package sender
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rescp17/lanFileSharer/internal/config"
+	"github.com/rescp17/lanFileSharer/pkg/transfer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryPolicy(t *testing.T) {
+	assert.Equal(t, transfer.DefaultRetryPolicy(), RetryPolicy(config.DefaultConfig()))
+
+	cfg := config.DefaultConfig()
+	cfg.RetryMaxRetries = 5
+	cfg.RetryInitialDelayMs = 250
+	cfg.RetryMaxDelayMs = 4000
+	cfg.RetryOn = []string{"timeout", "disk_full"}
+	policy := RetryPolicy(cfg)
+	assert.Equal(t, 5, policy.MaxRetries)
+	assert.Equal(t, 250*time.Millisecond, policy.InitialDelay)
+	assert.Equal(t, 4*time.Second, policy.MaxDelay)
+	assert.True(t, policy.IsRetryable(transfer.ErrDiskFull))
+	assert.False(t, policy.IsRetryable(transfer.ErrConnectionLost))
+
+	// An empty list retries nothing rather than falling back to the default classes
+	cfg.RetryOn = []string{}
+	assert.False(t, RetryPolicy(cfg).IsRetryable(transfer.ErrTimeout))
+
+	cfg.RetryOn = []string{"sunspots"}
+	assert.Equal(t, transfer.DefaultRetryPolicy(), RetryPolicy(cfg))
+}
+
+func TestParseRetryPolicy_RejectsInvalidSettings(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.RetryBackoffFactor = 0.5
+	_, err := ParseRetryPolicy(cfg)
+	assert.ErrorIs(t, err, transfer.ErrInvalidConfiguration)
+}
@@ -0,0 +1,42 @@
+package sender
+
+import (
+	"log/slog"
+
+	"github.com/rescp17/lanFileSharer/internal/config"
+	"github.com/rescp17/lanFileSharer/pkg/discovery"
+)
+
+// ServiceScope returns the discovery scope configured in cfg, or the default if it is invalid
+func ServiceScope(cfg config.Config) discovery.Scope {
+	scope, err := discovery.ParseScope(cfg.ServiceType, cfg.ServiceDomain)
+	if err != nil {
+		slog.Warn("Invalid service scope in config, using default", "error", err, "default", discovery.DefaultScope().Query())
+		return discovery.DefaultScope()
+	}
+	return scope
+}
+
+// filterServices keeps the services announced in the configured scope whose address the
+// peer filter allows
+func (a *App) filterServices(services []discovery.ServiceInfo) []discovery.ServiceInfo {
+	services = a.scope().Filter(services)
+	if a.options.PeerFilter == nil {
+		return services
+	}
+	allowed := make([]discovery.ServiceInfo, 0, len(services))
+	for _, service := range services {
+		if a.options.PeerFilter.AllowsAddr(service.Addr) {
+			allowed = append(allowed, service)
+		}
+	}
+	return allowed
+}
+
+// scope returns the configured discovery scope, defaulting an unset one
+func (a *App) scope() discovery.Scope {
+	if a.options.Scope == (discovery.Scope{}) {
+		return discovery.DefaultScope()
+	}
+	return a.options.Scope
+}
@@ -0,0 +1,188 @@
+package sender
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rescp17/lanFileSharer/internal/config"
+	"github.com/rescp17/lanFileSharer/pkg/discovery"
+	"github.com/rescp17/lanFileSharer/pkg/fileInfo"
+)
+
+// SentCacheFileName is the file, next to the config file, recording what was sent to each peer
+const SentCacheFileName = "sent_cache.json"
+
+// serviceIDSuffix matches the per-run id receivers append to their hostname
+var serviceIDSuffix = regexp.MustCompile(`-[0-9a-f]{8}$`)
+
+// SentEntry records the content of a file the last time it was sent to a peer
+type SentEntry struct {
+	Checksum string    `json:"checksum"`
+	Size     int64     `json:"size"`
+	SentAt   time.Time `json:"sent_at"`
+}
+
+// SentCache is a persistent record of file contents already delivered to each peer,
+// used to skip unchanged files when the same files are sent again
+type SentCache struct {
+	path  string
+	mu    sync.Mutex
+	peers map[string]map[string]SentEntry // peer -> absolute path -> entry
+}
+
+// DefaultSentCachePath returns the default location of the sent cache
+func DefaultSentCachePath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get user config directory: %w", err)
+	}
+	return filepath.Join(dir, config.AppDirName, SentCacheFileName), nil
+}
+
+// SentCachePath returns the sent cache location for cfg, or "" when skipping sent files is disabled
+func SentCachePath(cfg config.Config) string {
+	if !cfg.SkipSentFiles {
+		return ""
+	}
+	path, err := DefaultSentCachePath()
+	if err != nil {
+		slog.Warn("Could not resolve sent cache path, sending all files", "error", err)
+		return ""
+	}
+	return path
+}
+
+// LoadSentCache reads the cache at path; a missing file yields an empty cache
+func LoadSentCache(path string) (*SentCache, error) {
+	cache := &SentCache{
+		path:  path,
+		peers: make(map[string]map[string]SentEntry),
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return cache, nil
+		}
+		return nil, fmt.Errorf("failed to read sent cache: %w", err)
+	}
+	if err := json.Unmarshal(data, &cache.peers); err != nil {
+		return nil, fmt.Errorf("failed to parse sent cache %s: %w", path, err)
+	}
+	return cache, nil
+}
+
+// PeerKey returns the stable cache key of a receiver, dropping the per-run service id
+func PeerKey(receiver discovery.ServiceInfo) string {
+	return strings.ToLower(serviceIDSuffix.ReplaceAllString(receiver.Name, ""))
+}
+
+// Filter returns files without the regular files already sent to peer with identical content.
+// Directories left without any file are dropped and the rest get their size and checksum recomputed.
+func (c *SentCache) Filter(peer string, files []fileInfo.FileNode) ([]fileInfo.FileNode, int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	sent := c.peers[peer]
+	if len(sent) == 0 {
+		return files, 0
+	}
+
+	skipped := 0
+	var filter func(nodes []fileInfo.FileNode) []fileInfo.FileNode
+	filter = func(nodes []fileInfo.FileNode) []fileInfo.FileNode {
+		kept := make([]fileInfo.FileNode, 0, len(nodes))
+		for _, node := range nodes {
+			if node.IsDir {
+				if len(node.Children) == 0 {
+					kept = append(kept, node)
+					continue
+				}
+				children := filter(node.Children)
+				if len(children) == 0 {
+					continue
+				}
+				if len(children) != len(node.Children) {
+					node.Children = children
+					node.SummarizeDir()
+				}
+				kept = append(kept, node)
+				continue
+			}
+
+			if node.IsStream() {
+				kept = append(kept, node)
+				continue
+			}
+			entry, ok := sent[absPath(node.Path)]
+			if ok && entry.Checksum == node.Checksum && entry.Size == node.Size {
+				skipped++
+				continue
+			}
+			kept = append(kept, node)
+		}
+		return kept
+	}
+
+	return filter(files), skipped
+}
+
+// Record marks every regular file in files as delivered to peer
+func (c *SentCache) Record(peer string, files []fileInfo.FileNode) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	sent, ok := c.peers[peer]
+	if !ok {
+		sent = make(map[string]SentEntry)
+		c.peers[peer] = sent
+	}
+
+	now := time.Now()
+	var record func(nodes []fileInfo.FileNode)
+	record = func(nodes []fileInfo.FileNode) {
+		for _, node := range nodes {
+			switch {
+			case node.IsDir:
+				record(node.Children)
+			case node.IsStream():
+			default:
+				sent[absPath(node.Path)] = SentEntry{Checksum: node.Checksum, Size: node.Size, SentAt: now}
+			}
+		}
+	}
+	record(files)
+}
+
+// Save writes the cache to disk, creating parent directories as needed
+func (c *SentCache) Save() error {
+	c.mu.Lock()
+	data, err := json.MarshalIndent(c.peers, "", "  ")
+	c.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to marshal sent cache: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return fmt.Errorf("failed to create sent cache directory: %w", err)
+	}
+	if err := os.WriteFile(c.path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write sent cache: %w", err)
+	}
+	return nil
+}
+
+func absPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
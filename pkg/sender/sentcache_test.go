@@ -0,0 +1,83 @@
+package sender
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rescp17/lanFileSharer/pkg/discovery"
+	"github.com/rescp17/lanFileSharer/pkg/fileInfo"
+)
+
+func TestPeerKey(t *testing.T) {
+	assert.Equal(t, "laptop", PeerKey(discovery.ServiceInfo{Name: "Laptop-1a2b3c4d"}))
+	assert.Equal(t, "my-desktop", PeerKey(discovery.ServiceInfo{Name: "my-desktop"}))
+}
+
+func TestSentCache_SkipsUnchangedFiles(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "same.txt"), []byte("same"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "changed.txt"), []byte("v1"), 0644))
+
+	root, err := fileInfo.CreateNode(dir)
+	require.NoError(t, err)
+
+	cachePath := filepath.Join(t.TempDir(), SentCacheFileName)
+	cache, err := LoadSentCache(cachePath)
+	require.NoError(t, err)
+	cache.Record("laptop", []fileInfo.FileNode{root})
+	require.NoError(t, cache.Save())
+
+	// Modify one file and resend the folder
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "changed.txt"), []byte("v2"), 0644))
+	root, err = fileInfo.CreateNode(dir)
+	require.NoError(t, err)
+
+	reloaded, err := LoadSentCache(cachePath)
+	require.NoError(t, err)
+
+	pending, skipped := reloaded.Filter("laptop", []fileInfo.FileNode{root})
+	assert.Equal(t, 1, skipped)
+	require.Len(t, pending, 1)
+	require.Len(t, pending[0].Children, 1)
+	assert.Equal(t, "changed.txt", pending[0].Children[0].Name)
+	assert.Equal(t, int64(2), pending[0].Size)
+
+	// Other peers are unaffected
+	pending, skipped = reloaded.Filter("desktop", []fileInfo.FileNode{root})
+	assert.Equal(t, 0, skipped)
+	assert.Len(t, pending[0].Children, 2)
+}
+
+func TestSentCache_DropsFullySentDirectories(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.txt"), []byte("a"), 0644))
+
+	root, err := fileInfo.CreateNode(dir)
+	require.NoError(t, err)
+
+	cache, err := LoadSentCache(filepath.Join(t.TempDir(), SentCacheFileName))
+	require.NoError(t, err)
+	cache.Record("laptop", []fileInfo.FileNode{root})
+
+	pending, skipped := cache.Filter("laptop", []fileInfo.FileNode{root})
+	assert.Equal(t, 1, skipped)
+	assert.Empty(t, pending)
+}
+
+func TestSummarizeDir_MatchesCalcChecksum(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "b.txt"), []byte("bb"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.txt"), []byte("a"), 0644))
+
+	root, err := fileInfo.CreateNode(dir)
+	require.NoError(t, err)
+
+	summarized := fileInfo.FileNode{Name: root.Name, IsDir: true, Children: root.Children}
+	summarized.SummarizeDir()
+	assert.Equal(t, root.Size, summarized.Size)
+	assert.Equal(t, root.Checksum, summarized.Checksum)
+}
@@ -0,0 +1,137 @@
+package share
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"path/filepath"
+	"time"
+
+	"github.com/rescp17/lanFileSharer/pkg/crypto"
+	"github.com/rescp17/lanFileSharer/pkg/transfer"
+)
+
+// OfferExtension is the extension of offer files
+const OfferExtension = ".lanshare"
+
+// offerVersion is the format of offer files
+const offerVersion = 1
+
+// ErrOfferExpired is returned for offers past their expiry
+var ErrOfferExpired = errors.New("offer expired")
+
+// Offer is a pending transfer of files of a share, written to a small file that moves to the
+// receiver by other means, such as a USB stick or email. Importing it pulls the files from the
+// share, which the receiver then accepts without asking if the same key signs them.
+type Offer struct {
+	Version int      `json:"version"`
+	Sender  string   `json:"sender"` // Service name of the sharing device
+	Addrs   []string `json:"addrs"`  // Addresses of the sharing device, tried in order
+	Port    int      `json:"port"`
+	// Token names the offer to the share when pulling it
+	Token string   `json:"token"`
+	Paths []string `json:"paths"` // Relative to the share, with forward slashes
+	// Structure describes the offered files, signed with the device key of the sharing device
+	Structure *crypto.SignedFileStructure `json:"structure"`
+	Created   time.Time                   `json:"created"`
+	Expires   time.Time                   `json:"expires"`
+}
+
+// NewOffer creates an offer of paths of the share, described by structure, that the sharing
+// device sender takes at addrs and port until lifetime passed
+func NewOffer(sender string, addrs []string, port int, paths []string, structure *crypto.SignedFileStructure, lifetime time.Duration) *Offer {
+	now := time.Now()
+	return &Offer{
+		Version:   offerVersion,
+		Sender:    sender,
+		Addrs:     addrs,
+		Port:      port,
+		Token:     transfer.NewResumeToken(),
+		Paths:     paths,
+		Structure: structure,
+		Created:   now,
+		Expires:   now.Add(lifetime),
+	}
+}
+
+// Expired reports whether the offer can no longer be pulled at now
+func (o *Offer) Expired(now time.Time) bool {
+	return now.After(o.Expires)
+}
+
+// Fingerprint returns the fingerprint of the key that signed the offered files
+func (o *Offer) Fingerprint() string {
+	return crypto.PublicKeyFingerprint(o.Structure.PublicKey)
+}
+
+// TotalSize returns the size of the offered files
+func (o *Offer) TotalSize() int64 {
+	var total int64
+	for _, file := range o.Structure.Files {
+		total += file.Size
+	}
+	return total
+}
+
+// Verify checks that the offer is complete, its files are signed and it did not expire at now
+func (o *Offer) Verify(now time.Time) error {
+	if o.Version != offerVersion {
+		return fmt.Errorf("unsupported offer version %d", o.Version)
+	}
+	if err := transfer.ValidateResumeToken(o.Token); err != nil {
+		return fmt.Errorf("invalid offer token: %w", err)
+	}
+	if o.Port <= 0 || o.Port > 65535 {
+		return fmt.Errorf("invalid offer port %d", o.Port)
+	}
+	if len(o.Addrs) == 0 {
+		return errors.New("offer has no address of the sharing device")
+	}
+	for _, addr := range o.Addrs {
+		if net.ParseIP(addr) == nil {
+			return fmt.Errorf("invalid offer address %q", addr)
+		}
+	}
+	if len(o.Paths) == 0 {
+		return errors.New("offer has no files")
+	}
+	for _, path := range o.Paths {
+		if !filepath.IsLocal(filepath.FromSlash(path)) {
+			return fmt.Errorf("%w: %s", ErrOutsideShare, path)
+		}
+	}
+	if err := crypto.VerifyFileStructure(o.Structure); err != nil {
+		return fmt.Errorf("offer is not signed by the sharing device: %w", err)
+	}
+	if o.Expired(now) {
+		return fmt.Errorf("%w at %s", ErrOfferExpired, o.Expires.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// Write writes the offer as JSON to w
+func (o *Offer) Write(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(o); err != nil {
+		return fmt.Errorf("failed to write offer: %w", err)
+	}
+	return nil
+}
+
+// ReadOffer reads an offer written by Write and verifies it
+func ReadOffer(r io.Reader) (*Offer, error) {
+	var offer Offer
+	if err := json.NewDecoder(r).Decode(&offer); err != nil {
+		return nil, fmt.Errorf("failed to read offer: %w", err)
+	}
+	if offer.Structure == nil {
+		return nil, errors.New("offer has no signed files")
+	}
+	if err := offer.Verify(time.Now()); err != nil {
+		return nil, err
+	}
+	return &offer, nil
+}
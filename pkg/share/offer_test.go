@@ -0,0 +1,59 @@
+package share
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rescp17/lanFileSharer/pkg/crypto"
+	"github.com/rescp17/lanFileSharer/pkg/transfer"
+)
+
+func newTestOffer(t *testing.T, lifetime time.Duration) *Offer {
+	t.Helper()
+	shared := newTestShare(t)
+	structure, err := transfer.NewFileStructureManagerFromPath(filepath.Join(shared.Root(), "notes.txt"))
+	require.NoError(t, err)
+	signer, err := crypto.NewEd25519FileStructureSigner()
+	require.NoError(t, err)
+	signed, err := signer.SignFileStructureManager(structure)
+	require.NoError(t, err)
+	return NewOffer("share-1", []string{"192.168.1.20"}, 8080, []string{"notes.txt"}, signed, lifetime)
+}
+
+func TestOffer_WriteRead(t *testing.T) {
+	offer := newTestOffer(t, time.Hour)
+
+	var buf bytes.Buffer
+	require.NoError(t, offer.Write(&buf))
+	read, err := ReadOffer(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, offer.Token, read.Token)
+	assert.Equal(t, []string{"notes.txt"}, read.Paths)
+	assert.Equal(t, offer.Fingerprint(), read.Fingerprint())
+	assert.Equal(t, int64(5), read.TotalSize())
+}
+
+func TestOffer_Verify(t *testing.T) {
+	offer := newTestOffer(t, time.Hour)
+	require.NoError(t, offer.Verify(time.Now()))
+
+	assert.ErrorIs(t, offer.Verify(time.Now().Add(2*time.Hour)), ErrOfferExpired)
+
+	offer.Structure.Files[0].Size++
+	assert.Error(t, offer.Verify(time.Now()), "altered files break the signature")
+	offer.Structure.Files[0].Size--
+
+	offer.Paths = []string{"../outside"}
+	assert.ErrorIs(t, offer.Verify(time.Now()), ErrOutsideShare)
+	offer.Paths = []string{"notes.txt"}
+
+	offer.Addrs = []string{"not-an-ip"}
+	assert.Error(t, offer.Verify(time.Now()))
+	offer.Addrs = nil
+	assert.Error(t, offer.Verify(time.Now()), "the sharing device must be reachable")
+}
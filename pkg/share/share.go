@@ -0,0 +1,168 @@
+// Package share publishes a directory read-only for pull mode: peers list it one directory
+// at a time and pick files out of it, which the sharing device then sends them.
+package share
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrOutsideShare is returned for paths that do not name something inside the share
+var ErrOutsideShare = errors.New("path is outside the share")
+
+// ErrNotAFile is returned when previewing a directory
+var ErrNotAFile = errors.New("path is not a file")
+
+// Entry is a file or directory in a listing of the share
+type Entry struct {
+	Name    string    `json:"name"`
+	Path    string    `json:"path"` // Relative to the share, with forward slashes
+	IsDir   bool      `json:"is_dir"`
+	Size    int64     `json:"size"` // Zero for directories, which are listed without walking them
+	ModTime time.Time `json:"mod_time"`
+}
+
+// Share is a directory published for pulling
+type Share struct {
+	root string
+}
+
+// New publishes the directory root
+func New(root string) (*Share, error) {
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve share directory: %w", err)
+	}
+	abs, err = filepath.EvalSymlinks(abs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve share directory: %w", err)
+	}
+	info, err := os.Stat(abs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open share directory: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("share %s is not a directory", root)
+	}
+	return &Share{root: abs}, nil
+}
+
+// Root returns the shared directory
+func (s *Share) Root() string {
+	return s.root
+}
+
+// Name returns the name of the shared directory, shown to peers browsing it
+func (s *Share) Name() string {
+	return filepath.Base(s.root)
+}
+
+// Resolve returns the local path of path, a path relative to the share with forward
+// slashes; empty is the share itself. Hidden paths, which List leaves out, and symbolic
+// links leading out of the share are refused.
+func (s *Share) Resolve(path string) (string, error) {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return s.root, nil
+	}
+	rel := filepath.FromSlash(path)
+	if !filepath.IsLocal(rel) {
+		return "", fmt.Errorf("%w: %s", ErrOutsideShare, path)
+	}
+	for _, name := range strings.Split(path, "/") {
+		if strings.HasPrefix(name, ".") {
+			return "", fmt.Errorf("%w: %s is hidden", ErrOutsideShare, path)
+		}
+	}
+	local, err := filepath.EvalSymlinks(filepath.Join(s.root, rel))
+	if err != nil {
+		return "", err
+	}
+	if inside, err := filepath.Rel(s.root, local); err != nil || !filepath.IsLocal(inside) {
+		return "", fmt.Errorf("%w: %s", ErrOutsideShare, path)
+	}
+	return local, nil
+}
+
+// List returns the entries of the directory path, directories first, each sorted by name.
+// Hidden entries and symbolic links are left out.
+func (s *Share) List(path string) ([]Entry, error) {
+	dir, err := s.Resolve(path)
+	if err != nil {
+		return nil, err
+	}
+	dirEntries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := strings.Trim(path, "/")
+	entries := make([]Entry, 0, len(dirEntries))
+	for _, dirEntry := range dirEntries {
+		if strings.HasPrefix(dirEntry.Name(), ".") || dirEntry.Type()&os.ModeSymlink != 0 {
+			continue
+		}
+		info, err := dirEntry.Info()
+		if err != nil {
+			// Removed since the directory was read
+			continue
+		}
+		entry := Entry{
+			Name:    dirEntry.Name(),
+			Path:    joinPath(prefix, dirEntry.Name()),
+			IsDir:   dirEntry.IsDir(),
+			ModTime: info.ModTime(),
+		}
+		if !entry.IsDir {
+			entry.Size = info.Size()
+		}
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].IsDir != entries[j].IsDir {
+			return entries[i].IsDir
+		}
+		return entries[i].Name < entries[j].Name
+	})
+	return entries, nil
+}
+
+// joinPath joins share paths with forward slashes
+func joinPath(dir, name string) string {
+	if dir == "" {
+		return name
+	}
+	return dir + "/" + name
+}
+
+// Preview returns up to n bytes from the start of the file path and the size of the whole file
+func (s *Share) Preview(path string, n int) ([]byte, int64, error) {
+	local, err := s.Resolve(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	file, err := os.Open(local)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, 0, err
+	}
+	if !info.Mode().IsRegular() {
+		return nil, 0, fmt.Errorf("%w: %s", ErrNotAFile, path)
+	}
+	data, err := io.ReadAll(io.LimitReader(file, int64(n)))
+	if err != nil {
+		return nil, 0, err
+	}
+	return data, info.Size(), nil
+}
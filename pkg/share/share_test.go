@@ -0,0 +1,119 @@
+package share
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestShare(t *testing.T) *Share {
+	t.Helper()
+	root := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "photos", "2024"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "notes.txt"), []byte("hello"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "photos", "cat.jpg"), []byte("meow"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, ".secret"), []byte("hidden"), 0644))
+
+	shared, err := New(root)
+	require.NoError(t, err)
+	return shared
+}
+
+func TestShare_List(t *testing.T) {
+	shared := newTestShare(t)
+
+	entries, err := shared.List("")
+	require.NoError(t, err)
+	require.Len(t, entries, 2, "hidden files are not listed")
+	assert.Equal(t, "photos", entries[0].Name, "directories come first")
+	assert.True(t, entries[0].IsDir)
+	assert.Zero(t, entries[0].Size)
+	assert.Equal(t, "notes.txt", entries[1].Path)
+	assert.Equal(t, int64(5), entries[1].Size)
+
+	entries, err = shared.List("photos")
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "photos/2024", entries[0].Path)
+	assert.Equal(t, "photos/cat.jpg", entries[1].Path)
+
+	_, err = shared.List("missing")
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestShare_Resolve(t *testing.T) {
+	shared := newTestShare(t)
+
+	root, err := shared.Resolve("")
+	require.NoError(t, err)
+	assert.Equal(t, shared.Root(), root)
+
+	local, err := shared.Resolve("photos/cat.jpg")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(shared.Root(), "photos", "cat.jpg"), local)
+
+	for _, path := range []string{"../etc/passwd", "photos/../../x"} {
+		_, err := shared.Resolve(path)
+		assert.ErrorIs(t, err, ErrOutsideShare, path)
+	}
+}
+
+func TestShare_ResolveRefusesHiddenPaths(t *testing.T) {
+	shared := newTestShare(t)
+	require.NoError(t, os.MkdirAll(filepath.Join(shared.Root(), ".ssh"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(shared.Root(), ".ssh", "id_rsa"), []byte("key"), 0600))
+
+	for _, path := range []string{".secret", ".ssh", ".ssh/id_rsa", "/.ssh/id_rsa", "photos/./cat.jpg"} {
+		_, err := shared.Resolve(path)
+		assert.ErrorIs(t, err, ErrOutsideShare, path)
+	}
+	_, _, err := shared.Preview(".secret", 16)
+	assert.ErrorIs(t, err, ErrOutsideShare)
+}
+
+func TestShare_ResolveRefusesLinksOutOfTheShare(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("creating symbolic links needs privileges on Windows")
+	}
+	shared := newTestShare(t)
+	outside := t.TempDir()
+	require.NoError(t, os.Symlink(outside, filepath.Join(shared.Root(), "escape")))
+
+	_, err := shared.Resolve("escape")
+	assert.ErrorIs(t, err, ErrOutsideShare)
+
+	entries, err := shared.List("")
+	require.NoError(t, err)
+	for _, entry := range entries {
+		assert.NotEqual(t, "escape", entry.Name, "symbolic links are not listed")
+	}
+}
+
+func TestShare_Preview(t *testing.T) {
+	shared := newTestShare(t)
+
+	data, size, err := shared.Preview("notes.txt", 3)
+	require.NoError(t, err)
+	assert.Equal(t, "hel", string(data))
+	assert.Equal(t, int64(5), size)
+
+	data, _, err = shared.Preview("notes.txt", 100)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(data), "short files are returned whole")
+
+	_, _, err = shared.Preview("photos", 10)
+	assert.ErrorIs(t, err, ErrNotAFile)
+	_, _, err = shared.Preview("../notes.txt", 10)
+	assert.ErrorIs(t, err, ErrOutsideShare)
+}
+
+func TestNew_RequiresDirectory(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "file")
+	require.NoError(t, os.WriteFile(file, nil, 0644))
+	_, err := New(file)
+	assert.Error(t, err)
+}
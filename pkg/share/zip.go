@@ -0,0 +1,78 @@
+package share
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// WriteZip writes the directory or file path of the share to w as a zip archive, for devices
+// that download it with a browser. Entries are named from path's own name down, and what List
+// leaves out, hidden entries and symbolic links, is left out. Files are streamed as they are
+// read, so the archive is never held in memory or on disk.
+func (s *Share) WriteZip(w io.Writer, path string) error {
+	local, err := s.Resolve(path)
+	if err != nil {
+		return err
+	}
+	base := filepath.Dir(local)
+	archive := zip.NewWriter(w)
+	err = filepath.WalkDir(local, func(file string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if file != local && (strings.HasPrefix(entry.Name(), ".") || entry.Type()&fs.ModeSymlink != 0) {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		if !entry.IsDir() && !info.Mode().IsRegular() {
+			// Devices, sockets and pipes have no content to download
+			return nil
+		}
+		name, err := filepath.Rel(base, file)
+		if err != nil {
+			return err
+		}
+		header, err := zip.FileInfoHeader(info)
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(name)
+		if entry.IsDir() {
+			header.Name += "/"
+			_, err := archive.CreateHeader(header)
+			return err
+		}
+		header.Method = zip.Deflate
+		writer, err := archive.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+		return copyFile(writer, file)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to zip %s: %w", path, err)
+	}
+	return archive.Close()
+}
+
+// copyFile writes the content of the file at path to w
+func copyFile(w io.Writer, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = io.Copy(w, file)
+	return err
+}
@@ -0,0 +1,52 @@
+package share
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// unzip returns the contents of the entries of archive by name
+func unzip(t *testing.T, archive []byte) map[string]string {
+	t.Helper()
+	reader, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	require.NoError(t, err)
+	contents := make(map[string]string)
+	for _, file := range reader.File {
+		rc, err := file.Open()
+		require.NoError(t, err)
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		require.NoError(t, err)
+		contents[file.Name] = string(data)
+	}
+	return contents
+}
+
+func TestShare_WriteZip(t *testing.T) {
+	shared := newTestShare(t)
+	require.NoError(t, os.Symlink(filepath.Join(shared.Root(), "notes.txt"), filepath.Join(shared.Root(), "photos", "link.txt")))
+
+	var archive bytes.Buffer
+	require.NoError(t, shared.WriteZip(&archive, ""))
+	name := shared.Name()
+	assert.Equal(t, map[string]string{
+		name + "/":               "",
+		name + "/notes.txt":      "hello",
+		name + "/photos/":        "",
+		name + "/photos/2024/":   "",
+		name + "/photos/cat.jpg": "meow",
+	}, unzip(t, archive.Bytes()), "hidden files and symbolic links are left out")
+
+	archive.Reset()
+	require.NoError(t, shared.WriteZip(&archive, "photos/cat.jpg"))
+	assert.Equal(t, map[string]string{"cat.jpg": "meow"}, unzip(t, archive.Bytes()))
+
+	assert.ErrorIs(t, shared.WriteZip(io.Discard, "../"), ErrOutsideShare)
+}
@@ -0,0 +1,60 @@
+// Package storage lands the files a receiver completed in their final storage: the output
+// directory itself, possibly a mounted network share, an S3-compatible bucket or an SFTP server.
+package storage
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/rescp17/lanFileSharer/internal/config"
+)
+
+// Kinds of backends in the storage setting
+const (
+	KindLocal = "local"
+	KindS3    = "s3"
+	KindSFTP  = "sftp"
+)
+
+// DefaultTimeout bounds landing one file in its storage
+const DefaultTimeout = 30 * time.Minute
+
+// WriteBackend is where a receiver's completed files end up. Files are received and verified
+// in the output directory first, which needs random access; the backend then takes them.
+type WriteBackend interface {
+	// Validate checks the backend can take files, before a request is accepted
+	Validate(ctx context.Context) error
+	// Store lands the verified file at localPath as key, its slash-separated path under the
+	// output directory; checksum is its hex SHA-256, or "" if unknown. It returns where the
+	// file is now, a local path or a URL.
+	Store(ctx context.Context, localPath, key, checksum string) (string, error)
+	// String describes the backend for logs, e.g. "s3://bucket/prefix"
+	String() string
+}
+
+// New returns the backend cfg sets up for the output directory dir
+func New(cfg config.Config, dir string) (WriteBackend, error) {
+	switch cfg.Storage {
+	case "", KindLocal:
+		return NewLocal(dir, cfg.RequireMount), nil
+	case KindS3:
+		return NewS3(cfg.S3)
+	case KindSFTP:
+		return NewSFTP(cfg.SFTP)
+	default:
+		return nil, fmt.Errorf("unknown storage %q: want %s, %s or %s", cfg.Storage, KindLocal, KindS3, KindSFTP)
+	}
+}
+
+// FromConfig returns the backend set up in cfg for the output directory dir. An invalid
+// setup is logged and falls back to keeping files in dir, as before.
+func FromConfig(cfg config.Config, dir string) WriteBackend {
+	backend, err := New(cfg, dir)
+	if err != nil {
+		slog.Warn("Invalid storage in config, keeping received files in the output directory", "error", err)
+		return NewLocal(dir, cfg.RequireMount)
+	}
+	return backend
+}
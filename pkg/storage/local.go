@@ -0,0 +1,60 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// ErrNotMounted is returned by Local.Validate when a directory that must be a mount point,
+// such as an SMB or NFS share, is not one, so files would land on the local disk instead
+var ErrNotMounted = errors.New("output directory is not mounted")
+
+// Local keeps received files where they were received, in the output directory
+type Local struct {
+	dir          string
+	requireMount bool
+}
+
+// NewLocal returns the backend keeping files in dir. With requireMount, dir must be the mount
+// point of a file system of its own, as a mounted network share is.
+func NewLocal(dir string, requireMount bool) *Local {
+	return &Local{dir: dir, requireMount: requireMount}
+}
+
+// Validate checks the output directory exists, is writable and, if required, is mounted
+func (l *Local) Validate(ctx context.Context) error {
+	info, err := os.Stat(l.dir)
+	if err != nil {
+		return fmt.Errorf("output directory unavailable: %w", err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("output directory %s is not a directory", l.dir)
+	}
+	if l.requireMount {
+		mounted, err := isMountPoint(l.dir)
+		if err != nil {
+			return fmt.Errorf("failed to check mount of %s: %w", l.dir, err)
+		}
+		if !mounted {
+			return fmt.Errorf("%w: %s", ErrNotMounted, l.dir)
+		}
+	}
+	probe, err := os.CreateTemp(l.dir, ".lanfilesharer-probe-*")
+	if err != nil {
+		return fmt.Errorf("output directory not writable: %w", err)
+	}
+	probe.Close()
+	return os.Remove(probe.Name())
+}
+
+// Store leaves the file where it is
+func (l *Local) Store(ctx context.Context, localPath, key, checksum string) (string, error) {
+	return localPath, nil
+}
+
+func (l *Local) String() string {
+	return filepath.Clean(l.dir)
+}
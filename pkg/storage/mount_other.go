@@ -0,0 +1,9 @@
+//go:build !unix
+
+package storage
+
+// isMountPoint cannot tell mounts apart on this platform; mapped network drives are
+// directories of their own drive letter, so every directory passes
+func isMountPoint(dir string) (bool, error) {
+	return true, nil
+}
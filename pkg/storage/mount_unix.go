@@ -0,0 +1,43 @@
+//go:build unix
+
+package storage
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// isMountPoint reports whether dir is on another device than its parent, or is the root
+func isMountPoint(dir string) (bool, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return false, err
+	}
+	parent := filepath.Dir(dir)
+	if parent == dir {
+		return true, nil
+	}
+	dirDev, err := device(dir)
+	if err != nil {
+		return false, err
+	}
+	parentDev, err := device(parent)
+	if err != nil {
+		return false, err
+	}
+	return dirDev != parentDev, nil
+}
+
+func device(path string) (uint64, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, err
+	}
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, fmt.Errorf("no device of %s", path)
+	}
+	return uint64(stat.Dev), nil //nolint:unconvert // Dev is not a uint64 everywhere
+}
@@ -0,0 +1,253 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"os/user"
+	"path"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/rescp17/lanFileSharer/internal/config"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// sftpDialTimeout bounds connecting and logging in to the server
+const sftpDialTimeout = 30 * time.Second
+
+// SFTP forwards received files to a directory on an SFTP server, making the receiver a
+// gateway from the LAN to the server. Files are forwarded one at a time as they complete,
+// while the sender waits, so a slow server slows the transfer rather than filling the
+// output directory.
+type SFTP struct {
+	address   string
+	user      string
+	dir       string
+	keepLocal bool
+	connect   func(ctx context.Context) (*sftpClient, error)
+
+	mu     sync.Mutex
+	client *sftpClient // Connection kept between files, nil until needed or after a failure
+}
+
+// NewSFTP returns the backend forwarding to the server of cfg
+func NewSFTP(cfg config.SFTPStorage) (*SFTP, error) {
+	if cfg.Address == "" {
+		return nil, errors.New("sftp storage needs an address")
+	}
+	address := cfg.Address
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		address = net.JoinHostPort(address, "22")
+	}
+	userName := cfg.User
+	if userName == "" {
+		current, err := user.Current()
+		if err != nil {
+			return nil, fmt.Errorf("sftp storage needs a user: %w", err)
+		}
+		userName = current.Username
+	}
+	dir := cfg.Path
+	if dir == "" {
+		dir = "."
+	}
+
+	home, _ := os.UserHomeDir()
+	knownHostsFile := cfg.KnownHostsFile
+	if knownHostsFile == "" {
+		knownHostsFile = filepath.Join(home, ".ssh", "known_hosts")
+	}
+	hostKeys, err := knownhosts.New(knownHostsFile)
+	if err != nil {
+		return nil, fmt.Errorf("sftp storage needs the server's host key in %s: %w", knownHostsFile, err)
+	}
+	auth, err := sftpAuth(cfg.IdentityFile, home)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &SFTP{address: address, user: userName, dir: dir, keepLocal: cfg.KeepLocal}
+	clientConfig := &ssh.ClientConfig{
+		User:            userName,
+		Auth:            auth,
+		HostKeyCallback: hostKeys,
+		Timeout:         sftpDialTimeout,
+	}
+	s.connect = func(ctx context.Context) (*sftpClient, error) {
+		return dialSFTP(ctx, address, clientConfig)
+	}
+	return s, nil
+}
+
+// sftpAuth returns the ways to log in: the identity file if set, else the SSH agent and
+// whichever default keys exist and need no passphrase
+func sftpAuth(identityFile, home string) ([]ssh.AuthMethod, error) {
+	if identityFile != "" {
+		signer, err := loadSigner(identityFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load sftp identity %s: %w", identityFile, err)
+		}
+		return []ssh.AuthMethod{ssh.PublicKeys(signer)}, nil
+	}
+
+	var auth []ssh.AuthMethod
+	if socket := os.Getenv("SSH_AUTH_SOCK"); socket != "" {
+		if conn, err := net.Dial("unix", socket); err == nil {
+			// The agent signs during every login, so its connection stays open
+			auth = append(auth, ssh.PublicKeysCallback(agent.NewClient(conn).Signers))
+		}
+	}
+	var signers []ssh.Signer
+	for _, name := range []string{"id_ed25519", "id_ecdsa", "id_rsa"} {
+		signer, err := loadSigner(filepath.Join(home, ".ssh", name))
+		if err == nil {
+			signers = append(signers, signer)
+		} else if !errors.Is(err, os.ErrNotExist) {
+			slog.Warn("Skipping SSH key for sftp storage", "key", name, "error", err)
+		}
+	}
+	if len(signers) > 0 {
+		auth = append(auth, ssh.PublicKeys(signers...))
+	}
+	if len(auth) == 0 {
+		return nil, errors.New("sftp storage needs a key: set identity_file, run an SSH agent or create ~/.ssh/id_ed25519")
+	}
+	return auth, nil
+}
+
+func loadSigner(path string) (ssh.Signer, error) {
+	key, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ssh.ParsePrivateKey(key)
+}
+
+// dialSFTP logs in to address and starts the sftp subsystem
+func dialSFTP(ctx context.Context, address string, clientConfig *ssh.ClientConfig) (*sftpClient, error) {
+	dialer := net.Dialer{Timeout: sftpDialTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("sftp server unreachable: %w", err)
+	}
+	sshConn, chans, reqs, err := ssh.NewClientConn(conn, address, clientConfig)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to log in to sftp server %s: %w", address, err)
+	}
+	client := ssh.NewClient(sshConn, chans, reqs)
+	session, err := client.NewSession()
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+	stdin, err := session.StdinPipe()
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+	stdout, err := session.StdoutPipe()
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+	if err := session.RequestSubsystem("sftp"); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("sftp server %s has no sftp subsystem: %w", address, err)
+	}
+	sftp, err := newSFTPClient(stdout, stdin, client)
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+	return sftp, nil
+}
+
+// withClient runs fn on the connection, connecting first if needed. A failed fn drops the
+// connection, so the next file starts afresh; ctx ending aborts fn by closing it.
+func (s *SFTP) withClient(ctx context.Context, fn func(c *sftpClient) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.client == nil {
+		client, err := s.connect(ctx)
+		if err != nil {
+			return err
+		}
+		s.client = client
+	}
+	client := s.client
+	stop := context.AfterFunc(ctx, func() { client.Close() })
+	err := fn(client)
+	if !stop() {
+		err = errors.Join(ctx.Err(), err)
+	}
+	if err != nil {
+		client.Close()
+		s.client = nil
+	}
+	return err
+}
+
+// Validate checks the server takes the login and the directory exists
+func (s *SFTP) Validate(ctx context.Context) error {
+	return s.withClient(ctx, func(c *sftpClient) error {
+		isDir, err := c.stat(s.dir)
+		if err != nil {
+			return err
+		}
+		if !isDir {
+			return fmt.Errorf("sftp path %s is not a directory", s.dir)
+		}
+		return nil
+	})
+}
+
+// Store uploads the file at localPath as key under the directory, next to it first so the
+// server never shows a partial file, then removes it locally unless kept
+func (s *SFTP) Store(ctx context.Context, localPath, key, checksum string) (string, error) {
+	file, err := os.Open(localPath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	remotePath := path.Join(s.dir, key)
+	err = s.withClient(ctx, func(c *sftpClient) error {
+		if err := c.mkdirAll(path.Dir(remotePath)); err != nil {
+			return err
+		}
+		partPath := remotePath + ".part"
+		if err := c.upload(partPath, file); err != nil {
+			if removeErr := c.remove(partPath); removeErr != nil {
+				slog.Debug("Failed to remove partial sftp upload", "path", partPath, "error", removeErr)
+			}
+			return err
+		}
+		return c.rename(partPath, remotePath)
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to forward %s: %w", key, err)
+	}
+
+	location := fmt.Sprintf("sftp://%s@%s/%s", s.user, s.address, remotePath)
+	if s.keepLocal {
+		slog.Info("Forwarded received file", "location", location)
+		return localPath, nil
+	}
+	file.Close()
+	if err := os.Remove(localPath); err != nil {
+		slog.Warn("Failed to remove forwarded file", "path", localPath, "error", err)
+	}
+	return location, nil
+}
+
+func (s *SFTP) String() string {
+	return fmt.Sprintf("sftp://%s@%s/%s", s.user, s.address, s.dir)
+}
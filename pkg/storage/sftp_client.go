@@ -0,0 +1,349 @@
+package storage
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+)
+
+// The parts of SFTP version 3 (draft-ietf-secsh-filexfer-02) forwarding files needs
+const (
+	sftpInit     = 1
+	sftpVersion  = 2
+	sftpOpen     = 3
+	sftpClose    = 4
+	sftpWrite    = 6
+	sftpRemove   = 13
+	sftpMkdir    = 14
+	sftpStat     = 17
+	sftpRename   = 18
+	sftpStatus   = 101
+	sftpHandle   = 102
+	sftpAttrs    = 105
+	sftpProtocol = 3
+
+	sftpFlagWrite  = 0x02
+	sftpFlagCreate = 0x08
+	sftpFlagTrunc  = 0x10
+
+	sftpAttrSize        = 0x01
+	sftpAttrUIDGID      = 0x02
+	sftpAttrPermissions = 0x04
+
+	sftpStatusOK         = 0
+	sftpStatusNoSuchFile = 2
+
+	// sftpChunkSize is the data of one write, which every server takes
+	sftpChunkSize = 32 << 10
+	// sftpWindow is how many writes are in flight, so uploads do not wait a round trip each
+	sftpWindow = 16
+	// sftpMaxPacket bounds the replies read, which are small
+	sftpMaxPacket = 256 << 10
+)
+
+// sftpStatusError is a request the server failed
+type sftpStatusError struct {
+	op, path string
+	code     uint32
+	message  string
+}
+
+func (e *sftpStatusError) Error() string {
+	return fmt.Sprintf("sftp %s %s: %s (status %d)", e.op, e.path, e.message, e.code)
+}
+
+// Is makes a missing path match fs.ErrNotExist
+func (e *sftpStatusError) Is(target error) bool {
+	return target == fs.ErrNotExist && e.code == sftpStatusNoSuchFile
+}
+
+// sftpPacket builds a request
+type sftpPacket []byte
+
+func (p sftpPacket) uint32(v uint32) sftpPacket { return binary.BigEndian.AppendUint32(p, v) }
+
+func (p sftpPacket) uint64(v uint64) sftpPacket { return binary.BigEndian.AppendUint64(p, v) }
+
+func (p sftpPacket) string(s string) sftpPacket { return append(p.uint32(uint32(len(s))), s...) }
+
+func (p sftpPacket) bytes(b []byte) sftpPacket { return append(p.uint32(uint32(len(b))), b...) }
+
+// sftpReply reads a reply
+type sftpReply struct {
+	data []byte
+	err  error
+}
+
+func (r *sftpReply) uint32() uint32 {
+	if len(r.data) < 4 {
+		r.err = errors.New("sftp reply too short")
+		return 0
+	}
+	v := binary.BigEndian.Uint32(r.data)
+	r.data = r.data[4:]
+	return v
+}
+
+func (r *sftpReply) uint64() uint64 {
+	return uint64(r.uint32())<<32 | uint64(r.uint32())
+}
+
+func (r *sftpReply) string() string {
+	n := r.uint32()
+	if r.err != nil || uint32(len(r.data)) < n {
+		r.err = errors.New("sftp reply too short")
+		return ""
+	}
+	s := string(r.data[:n])
+	r.data = r.data[n:]
+	return s
+}
+
+// sftpClient speaks SFTP over the streams of an SSH session's sftp subsystem. Requests are
+// sent one at a time, but for the writes of an upload.
+type sftpClient struct {
+	r      io.Reader
+	w      io.Writer
+	closer io.Closer
+	nextID uint32
+}
+
+// newSFTPClient starts the protocol on r and w; closer ends the session
+func newSFTPClient(r io.Reader, w io.Writer, closer io.Closer) (*sftpClient, error) {
+	c := &sftpClient{r: r, w: w, closer: closer}
+	if err := c.send(sftpPacket{sftpInit}.uint32(sftpProtocol)); err != nil {
+		return nil, err
+	}
+	typ, reply, err := c.read()
+	if err != nil {
+		return nil, err
+	}
+	if typ != sftpVersion {
+		return nil, fmt.Errorf("sftp server answered init with packet type %d", typ)
+	}
+	if version := reply.uint32(); version < sftpProtocol {
+		return nil, fmt.Errorf("sftp server speaks version %d, need %d", version, sftpProtocol)
+	}
+	return c, nil
+}
+
+func (c *sftpClient) Close() error {
+	if c.closer == nil {
+		return nil
+	}
+	return c.closer.Close()
+}
+
+// request starts a request of typ with a fresh id, for the caller to add its fields
+func (c *sftpClient) request(typ byte) (sftpPacket, uint32) {
+	c.nextID++
+	return sftpPacket{typ}.uint32(c.nextID), c.nextID
+}
+
+func (c *sftpClient) send(p sftpPacket) error {
+	_, err := c.w.Write(append(binary.BigEndian.AppendUint32(nil, uint32(len(p))), p...))
+	return err
+}
+
+func (c *sftpClient) read() (byte, *sftpReply, error) {
+	var header [5]byte
+	if _, err := io.ReadFull(c.r, header[:]); err != nil {
+		return 0, nil, fmt.Errorf("sftp connection lost: %w", err)
+	}
+	length := binary.BigEndian.Uint32(header[:4])
+	if length < 1 || length > sftpMaxPacket {
+		return 0, nil, fmt.Errorf("sftp reply of %d bytes", length)
+	}
+	data := make([]byte, length-1)
+	if _, err := io.ReadFull(c.r, data); err != nil {
+		return 0, nil, fmt.Errorf("sftp connection lost: %w", err)
+	}
+	return header[4], &sftpReply{data: data}, nil
+}
+
+// reply reads the reply to the request id, which must be of type want or a status; a
+// status reply is returned as its error unless it is OK
+func (c *sftpClient) reply(id uint32, want byte, op, name string) (*sftpReply, error) {
+	typ, reply, err := c.read()
+	if err != nil {
+		return nil, err
+	}
+	if replyID := reply.uint32(); replyID != id {
+		return nil, fmt.Errorf("sftp reply to request %d, expected %d", replyID, id)
+	}
+	if typ == sftpStatus {
+		return nil, statusError(reply, op, name)
+	}
+	if typ != want {
+		return nil, fmt.Errorf("sftp %s %s: unexpected packet type %d", op, name, typ)
+	}
+	return reply, reply.err
+}
+
+// statusError returns the error of a status reply after its id, nil for OK
+func statusError(reply *sftpReply, op, name string) error {
+	code := reply.uint32()
+	message := reply.string()
+	if reply.err != nil {
+		return reply.err
+	}
+	if code == sftpStatusOK {
+		return nil
+	}
+	return &sftpStatusError{op: op, path: name, code: code, message: message}
+}
+
+// simple sends a request answered by a status only
+func (c *sftpClient) simple(p sftpPacket, id uint32, op, name string) error {
+	if err := c.send(p); err != nil {
+		return err
+	}
+	_, err := c.reply(id, sftpStatus, op, name)
+	return err
+}
+
+// stat reports whether name exists and is a directory
+func (c *sftpClient) stat(name string) (isDir bool, err error) {
+	p, id := c.request(sftpStat)
+	if err := c.send(p.string(name)); err != nil {
+		return false, err
+	}
+	reply, err := c.reply(id, sftpAttrs, "stat", name)
+	if err != nil {
+		return false, err
+	}
+	flags := reply.uint32()
+	if flags&sftpAttrSize != 0 {
+		reply.uint64()
+	}
+	if flags&sftpAttrUIDGID != 0 {
+		reply.uint32()
+		reply.uint32()
+	}
+	if flags&sftpAttrPermissions == 0 {
+		return false, reply.err
+	}
+	mode := reply.uint32()
+	return mode&0170000 == 0040000, reply.err
+}
+
+// mkdirAll creates the directory name and any missing parents
+func (c *sftpClient) mkdirAll(name string) error {
+	isDir, err := c.stat(name)
+	if err == nil {
+		if !isDir {
+			return fmt.Errorf("sftp path %s is not a directory", name)
+		}
+		return nil
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if parent := path.Dir(name); parent != name {
+		if err := c.mkdirAll(parent); err != nil {
+			return err
+		}
+	}
+	p, id := c.request(sftpMkdir)
+	return c.simple(p.string(name).uint32(sftpAttrPermissions).uint32(0755), id, "mkdir", name)
+}
+
+// upload writes everything r yields to the file name, replacing it
+func (c *sftpClient) upload(name string, r io.Reader) error {
+	p, id := c.request(sftpOpen)
+	p = p.string(name).uint32(sftpFlagWrite | sftpFlagCreate | sftpFlagTrunc).uint32(sftpAttrPermissions).uint32(0644)
+	if err := c.send(p); err != nil {
+		return err
+	}
+	reply, err := c.reply(id, sftpHandle, "open", name)
+	if err != nil {
+		return err
+	}
+	handle := reply.string()
+	if reply.err != nil {
+		return reply.err
+	}
+
+	writeErr := c.writeAll(name, handle, r)
+	p, id = c.request(sftpClose)
+	if err := c.simple(p.string(handle), id, "close", name); err != nil && writeErr == nil {
+		writeErr = err
+	}
+	return writeErr
+}
+
+// writeAll writes r to the open handle, keeping up to sftpWindow writes in flight
+func (c *sftpClient) writeAll(name, handle string, r io.Reader) error {
+	pending := make(map[uint32]bool, sftpWindow)
+	var failed error
+	await := func() error {
+		typ, reply, err := c.read()
+		if err != nil {
+			return err
+		}
+		id := reply.uint32()
+		if !pending[id] || typ != sftpStatus {
+			return fmt.Errorf("sftp write %s: unexpected reply %d of type %d", name, id, typ)
+		}
+		delete(pending, id)
+		if err := statusError(reply, "write", name); err != nil && failed == nil {
+			failed = err
+		}
+		return nil
+	}
+
+	buf := make([]byte, sftpChunkSize)
+	var offset uint64
+	for failed == nil {
+		n, readErr := io.ReadFull(r, buf)
+		if n > 0 {
+			if len(pending) == sftpWindow {
+				if err := await(); err != nil {
+					return err
+				}
+			}
+			p, id := c.request(sftpWrite)
+			if err := c.send(p.string(handle).uint64(offset).bytes(buf[:n])); err != nil {
+				return err
+			}
+			pending[id] = true
+			offset += uint64(n)
+		}
+		if readErr == io.EOF || readErr == io.ErrUnexpectedEOF {
+			break
+		}
+		if readErr != nil {
+			failed = readErr
+		}
+	}
+	for len(pending) > 0 {
+		if err := await(); err != nil {
+			return err
+		}
+	}
+	return failed
+}
+
+// rename moves from to to, replacing to; SFTP version 3 refuses to replace
+func (c *sftpClient) rename(from, to string) error {
+	p, id := c.request(sftpRename)
+	err := c.simple(p.string(from).string(to), id, "rename", from)
+	if err == nil {
+		return nil
+	}
+	p, id = c.request(sftpRemove)
+	if removeErr := c.simple(p.string(to), id, "remove", to); removeErr != nil {
+		return err
+	}
+	p, id = c.request(sftpRename)
+	return c.simple(p.string(from).string(to), id, "rename", from)
+}
+
+// remove deletes the file name
+func (c *sftpClient) remove(name string) error {
+	p, id := c.request(sftpRemove)
+	return c.simple(p.string(name), id, "remove", name)
+}
@@ -0,0 +1,181 @@
+package storage
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rescp17/lanFileSharer/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSFTPServer serves the requests sftpClient sends from a local directory
+type fakeSFTPServer struct {
+	root    string
+	handles map[string]*os.File
+	writes  int
+}
+
+func (s *fakeSFTPServer) serve(conn net.Conn) {
+	defer conn.Close()
+	for {
+		var header [4]byte
+		if _, err := io.ReadFull(conn, header[:]); err != nil {
+			return
+		}
+		data := make([]byte, binary.BigEndian.Uint32(header[:]))
+		if _, err := io.ReadFull(conn, data); err != nil {
+			return
+		}
+		typ, req := data[0], &sftpReply{data: data[1:]}
+		if typ == sftpInit {
+			s.send(conn, sftpPacket{sftpVersion}.uint32(sftpProtocol))
+			continue
+		}
+		id := req.uint32()
+		status := func(err error) {
+			code, message := uint32(sftpStatusOK), "OK"
+			if os.IsNotExist(err) {
+				code, message = sftpStatusNoSuchFile, "No such file"
+			} else if err != nil {
+				code, message = 4, err.Error()
+			}
+			s.send(conn, sftpPacket{sftpStatus}.uint32(id).uint32(code).string(message).string(""))
+		}
+		switch typ {
+		case sftpStat:
+			info, err := os.Stat(s.path(req.string()))
+			if err != nil {
+				status(err)
+				continue
+			}
+			mode := uint32(0100644)
+			if info.IsDir() {
+				mode = 040755
+			}
+			s.send(conn, sftpPacket{sftpAttrs}.uint32(id).uint32(sftpAttrSize|sftpAttrPermissions).uint64(uint64(info.Size())).uint32(mode))
+		case sftpMkdir:
+			status(os.Mkdir(s.path(req.string()), 0755))
+		case sftpOpen:
+			name := req.string()
+			file, err := os.Create(s.path(name))
+			if err != nil {
+				status(err)
+				continue
+			}
+			s.handles[name] = file
+			s.send(conn, sftpPacket{sftpHandle}.uint32(id).string(name))
+		case sftpWrite:
+			file, offset := s.handles[req.string()], req.uint64()
+			s.writes++
+			_, err := file.WriteAt([]byte(req.string()), int64(offset))
+			status(err)
+		case sftpClose:
+			handle := req.string()
+			status(s.handles[handle].Close())
+			delete(s.handles, handle)
+		case sftpRename:
+			from, to := s.path(req.string()), s.path(req.string())
+			if _, err := os.Stat(to); err == nil {
+				status(os.ErrExist)
+				continue
+			}
+			status(os.Rename(from, to))
+		case sftpRemove:
+			status(os.Remove(s.path(req.string())))
+		default:
+			status(os.ErrInvalid)
+		}
+	}
+}
+
+func (s *fakeSFTPServer) path(name string) string {
+	return filepath.Join(s.root, filepath.FromSlash(name))
+}
+
+func (s *fakeSFTPServer) send(conn net.Conn, p sftpPacket) {
+	conn.Write(append(binary.BigEndian.AppendUint32(nil, uint32(len(p))), p...))
+}
+
+func newTestSFTP(t *testing.T, keepLocal bool) (*SFTP, *fakeSFTPServer) {
+	server := &fakeSFTPServer{root: t.TempDir(), handles: map[string]*os.File{}}
+	s := &SFTP{address: "files.example:22", user: "ingest", dir: "inbox", keepLocal: keepLocal}
+	// A TCP connection buffers like an SSH channel, where net.Pipe would block pipelined writes
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go server.serve(conn)
+		}
+	}()
+	s.connect = func(ctx context.Context) (*sftpClient, error) {
+		conn, err := net.Dial("tcp", listener.Addr().String())
+		if err != nil {
+			return nil, err
+		}
+		return newSFTPClient(conn, conn, conn)
+	}
+	return s, server
+}
+
+func TestNewSFTP(t *testing.T) {
+	_, err := NewSFTP(config.SFTPStorage{})
+	assert.Error(t, err, "sftp needs an address")
+
+	_, err = NewSFTP(config.SFTPStorage{Address: "files.example", KnownHostsFile: filepath.Join(t.TempDir(), "missing")})
+	assert.ErrorContains(t, err, "host key")
+}
+
+func TestSFTP_Store(t *testing.T) {
+	s, server := newTestSFTP(t, false)
+	assert.Error(t, s.Validate(context.Background()), "the directory does not exist yet")
+	require.NoError(t, os.Mkdir(filepath.Join(server.root, "inbox"), 0755))
+	require.NoError(t, s.Validate(context.Background()))
+
+	// Larger than the writes in flight, so the window fills
+	content := []byte(strings.Repeat("forwarded content ", 40000))
+	localPath := filepath.Join(t.TempDir(), "report.txt")
+	require.NoError(t, os.WriteFile(localPath, content, 0644))
+	location, err := s.Store(context.Background(), localPath, "docs/report.txt", "")
+	require.NoError(t, err)
+
+	assert.Equal(t, "sftp://ingest@files.example:22/inbox/docs/report.txt", location)
+	uploaded, err := os.ReadFile(filepath.Join(server.root, "inbox", "docs", "report.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, content, uploaded)
+	assert.Greater(t, server.writes, sftpWindow)
+	assert.NoFileExists(t, filepath.Join(server.root, "inbox", "docs", "report.txt.part"))
+	assert.NoFileExists(t, localPath, "forwarded files leave the output directory")
+
+	// A second file of the same name replaces the first
+	require.NoError(t, os.WriteFile(localPath, []byte("newer"), 0644))
+	_, err = s.Store(context.Background(), localPath, "docs/report.txt", "")
+	require.NoError(t, err)
+	uploaded, err = os.ReadFile(filepath.Join(server.root, "inbox", "docs", "report.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "newer", string(uploaded))
+}
+
+func TestSFTP_StoreKeepLocal(t *testing.T) {
+	s, server := newTestSFTP(t, true)
+	require.NoError(t, os.Mkdir(filepath.Join(server.root, "inbox"), 0755))
+
+	localPath := filepath.Join(t.TempDir(), "a.txt")
+	require.NoError(t, os.WriteFile(localPath, []byte("a"), 0644))
+	location, err := s.Store(context.Background(), localPath, "a.txt", "")
+	require.NoError(t, err)
+	assert.Equal(t, localPath, location)
+	assert.FileExists(t, localPath)
+	assert.FileExists(t, filepath.Join(server.root, "inbox", "a.txt"))
+}
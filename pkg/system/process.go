@@ -0,0 +1,151 @@
+package system
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// clockTicksPerSecond is the unit of the CPU times in /proc, fixed at 100 on all mainstream Linux builds
+const clockTicksPerSecond = 100
+
+// ProcessStats contains resource usage of this process as seen by the operating system
+type ProcessStats struct {
+	CPUPercent float64   `json:"cpu_percent"` // percent of one core since the previous sample
+	RSS        uint64    `json:"rss"`         // resident set size in bytes
+	OpenFiles  int       `json:"open_files"`  // open file descriptors
+	Supported  bool      `json:"supported"`   // false if the platform offers no process statistics
+	Timestamp  time.Time `json:"timestamp"`
+}
+
+// ProcessSampler samples ProcessStats. CPU usage is measured between consecutive samples,
+// so the first sample reports the average since the process started.
+type ProcessSampler struct {
+	mu         sync.Mutex
+	pid        int
+	startTime  time.Time
+	lastCPU    time.Duration
+	lastSample time.Time
+}
+
+// NewProcessSampler creates a sampler for the current process
+func NewProcessSampler() *ProcessSampler {
+	return &ProcessSampler{pid: os.Getpid(), startTime: time.Now()}
+}
+
+// Sample returns the current process statistics
+func (ps *ProcessSampler) Sample() ProcessStats {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	now := time.Now()
+	stats := ProcessStats{Timestamp: now}
+	switch runtime.GOOS {
+	case "linux":
+		cpu, rss, err := readProcStat()
+		if err != nil {
+			return stats
+		}
+		stats.Supported = true
+		stats.RSS = rss
+		stats.CPUPercent = ps.cpuPercent(cpu, now)
+		stats.OpenFiles = countOpenFiles("/proc/self/fd")
+	case "darwin", "freebsd":
+		// ps reports a decaying CPU average, which is close enough for a monitor
+		out, err := exec.Command("ps", "-o", "%cpu=,rss=", "-p", strconv.Itoa(ps.pid)).Output()
+		if err != nil {
+			return stats
+		}
+		fields := strings.Fields(string(out))
+		if len(fields) != 2 {
+			return stats
+		}
+		stats.Supported = true
+		stats.CPUPercent, _ = strconv.ParseFloat(fields[0], 64)
+		rssKB, _ := strconv.ParseUint(fields[1], 10, 64)
+		stats.RSS = rssKB * 1024
+		stats.OpenFiles = countOpenFiles("/dev/fd")
+	}
+	return stats
+}
+
+// cpuPercent returns the CPU usage since the previous sample given the total CPU time consumed
+func (ps *ProcessSampler) cpuPercent(cpu time.Duration, now time.Time) float64 {
+	since := ps.lastSample
+	used := cpu - ps.lastCPU
+	if since.IsZero() {
+		since = ps.startTime
+		used = cpu
+	}
+	ps.lastCPU = cpu
+	ps.lastSample = now
+
+	wall := now.Sub(since)
+	if wall <= 0 {
+		return 0
+	}
+	return float64(used) / float64(wall) * 100
+}
+
+// readProcStat reads the CPU time consumed by this process and its resident set size from /proc
+func readProcStat() (cpu time.Duration, rss uint64, err error) {
+	stat, err := os.ReadFile("/proc/self/stat")
+	if err != nil {
+		return 0, 0, err
+	}
+	cpu, err = parseProcStatCPU(string(stat))
+	if err != nil {
+		return 0, 0, err
+	}
+
+	statm, err := os.ReadFile("/proc/self/statm")
+	if err != nil {
+		return 0, 0, err
+	}
+	fields := strings.Fields(string(statm))
+	if len(fields) < 2 {
+		return 0, 0, fmt.Errorf("unexpected /proc/self/statm format")
+	}
+	pages, err := strconv.ParseUint(fields[1], 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to parse resident pages: %w", err)
+	}
+	return cpu, pages * uint64(os.Getpagesize()), nil
+}
+
+// parseProcStatCPU returns user plus system time from the contents of /proc/<pid>/stat
+func parseProcStatCPU(stat string) (time.Duration, error) {
+	// The command name may contain spaces, the fields after it start with the state
+	end := strings.LastIndexByte(stat, ')')
+	if end < 0 {
+		return 0, fmt.Errorf("unexpected /proc stat format")
+	}
+	fields := strings.Fields(stat[end+1:])
+	// utime and stime are fields 14 and 15, the state is field 3
+	if len(fields) < 13 {
+		return 0, fmt.Errorf("unexpected /proc stat format")
+	}
+	utime, err := strconv.ParseUint(fields[11], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse utime: %w", err)
+	}
+	stime, err := strconv.ParseUint(fields[12], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse stime: %w", err)
+	}
+	return time.Duration(utime+stime) * time.Second / clockTicksPerSecond, nil
+}
+
+// countOpenFiles counts the descriptors listed in dir, excluding the one used to list it
+func countOpenFiles(dir string) int {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return 0
+	}
+	return max(len(entries)-1, 0)
+}
@@ -0,0 +1,115 @@
+package system
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"strconv"
+	"time"
+)
+
+// DefaultMemoryLogInterval is how often the memory watermark is checked when profiling is enabled
+const DefaultMemoryLogInterval = 30 * time.Second
+
+// StartPprofServer serves net/http/pprof on localhost:port until ctx is done.
+// It returns the address it listens on, which differs from port if port is zero.
+func StartPprofServer(ctx context.Context, port int) (string, error) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+
+	// Profiles reveal file names and memory contents, so they are never exposed to the LAN
+	listener, err := net.Listen("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(port)))
+	if err != nil {
+		return "", fmt.Errorf("failed to listen for pprof: %w", err)
+	}
+
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("pprof server stopped", "error", err)
+		}
+	}()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		_ = server.Shutdown(shutdownCtx)
+	}()
+
+	addr := listener.Addr().String()
+	slog.Info("pprof listening", "url", "http://"+addr+"/debug/pprof/")
+	return addr, nil
+}
+
+// MemoryWatermark tracks the highest memory use seen by the process
+type MemoryWatermark struct {
+	sampler  *ProcessSampler
+	peakHeap uint64
+	peakRSS  uint64
+}
+
+// NewMemoryWatermark creates a watermark with no peaks recorded yet
+func NewMemoryWatermark() *MemoryWatermark {
+	return &MemoryWatermark{sampler: NewProcessSampler()}
+}
+
+// Check samples memory use and logs it, at Info level when a new peak was reached.
+// It reports whether a peak was exceeded.
+func (mw *MemoryWatermark) Check() bool {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	proc := mw.sampler.Sample()
+
+	raised := false
+	if m.HeapInuse > mw.peakHeap {
+		mw.peakHeap = m.HeapInuse
+		raised = true
+	}
+	if proc.RSS > mw.peakRSS {
+		mw.peakRSS = proc.RSS
+		raised = true
+	}
+
+	level := slog.LevelDebug
+	msg := "Memory usage"
+	if raised {
+		level = slog.LevelInfo
+		msg = "New memory watermark"
+	}
+	slog.Log(context.Background(), level, msg,
+		"heap_inuse", m.HeapInuse,
+		"heap_objects", m.HeapObjects,
+		"sys", m.Sys,
+		"rss", proc.RSS,
+		"peak_heap", mw.peakHeap,
+		"peak_rss", mw.peakRSS,
+		"goroutines", runtime.NumGoroutine(),
+		"num_gc", m.NumGC,
+	)
+	return raised
+}
+
+// Run checks the watermark every interval until ctx is done
+func (mw *MemoryWatermark) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	mw.Check()
+	for {
+		select {
+		case <-ticker.C:
+			mw.Check()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package transfer
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BandwidthProfile limits sending to BytesPerSecond between two times of day, e.g. during
+// work hours. Times are local; a profile whose End is before its Start spans midnight.
+type BandwidthProfile struct {
+	Name           string
+	Start          time.Duration // Since midnight
+	End            time.Duration // Since midnight, exclusive
+	BytesPerSecond int64         // Zero is unlimited
+}
+
+// Covers reports whether t falls within the hours of the profile
+func (p BandwidthProfile) Covers(t time.Time) bool {
+	now := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second
+	if p.Start <= p.End {
+		return now >= p.Start && now < p.End
+	}
+	return now >= p.Start || now < p.End
+}
+
+// BandwidthSchedule is a list of profiles; the first covering a time applies, and sending is
+// unlimited when none does
+type BandwidthSchedule []BandwidthProfile
+
+// Active returns the profile that applies at t, if any
+func (s BandwidthSchedule) Active(t time.Time) (BandwidthProfile, bool) {
+	for _, profile := range s {
+		if profile.Covers(t) {
+			return profile, true
+		}
+	}
+	return BandwidthProfile{}, false
+}
+
+// Limit returns the bytes per second allowed at t, zero if unlimited
+func (s BandwidthSchedule) Limit(t time.Time) int64 {
+	profile, _ := s.Active(t)
+	return profile.BytesPerSecond
+}
+
+// ParseTimeOfDay parses a time of day such as "9:00" or "18:30" into the time since midnight;
+// "24:00" is the end of the day
+func ParseTimeOfDay(s string) (time.Duration, error) {
+	hours, minutes, ok := strings.Cut(strings.TrimSpace(s), ":")
+	if !ok {
+		return 0, fmt.Errorf("invalid time of day %q, use HH:MM", s)
+	}
+	h, err := strconv.Atoi(hours)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q, use HH:MM", s)
+	}
+	m, err := strconv.Atoi(minutes)
+	if err != nil || len(minutes) != 2 {
+		return 0, fmt.Errorf("invalid time of day %q, use HH:MM", s)
+	}
+	if h < 0 || m < 0 || m > 59 || h > 24 || (h == 24 && m != 0) {
+		return 0, fmt.Errorf("time of day %q is out of range", s)
+	}
+	return time.Duration(h)*time.Hour + time.Duration(m)*time.Minute, nil
+}
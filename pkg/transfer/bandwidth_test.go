@@ -0,0 +1,51 @@
+package transfer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBandwidthSchedule_Active(t *testing.T) {
+	schedule := BandwidthSchedule{
+		{Name: "work", Start: 9 * time.Hour, End: 18 * time.Hour, BytesPerSecond: 10 << 20},
+		{Name: "night", Start: 22 * time.Hour, End: 6 * time.Hour},
+	}
+	at := func(hour, minute int) time.Time { return time.Date(2024, 5, 15, hour, minute, 0, 0, time.Local) }
+
+	profile, ok := schedule.Active(at(9, 0))
+	require.True(t, ok)
+	assert.Equal(t, "work", profile.Name)
+	assert.Equal(t, int64(10<<20), schedule.Limit(at(17, 59)))
+
+	_, ok = schedule.Active(at(18, 0))
+	assert.False(t, ok, "the end is exclusive")
+	assert.Zero(t, schedule.Limit(at(20, 0)))
+
+	for _, tm := range []time.Time{at(23, 0), at(0, 30), at(5, 59)} {
+		profile, ok := schedule.Active(tm)
+		require.True(t, ok, tm)
+		assert.Equal(t, "night", profile.Name, "profiles span midnight")
+	}
+	_, ok = schedule.Active(at(6, 0))
+	assert.False(t, ok)
+}
+
+func TestParseTimeOfDay(t *testing.T) {
+	for input, want := range map[string]time.Duration{
+		"9:00":  9 * time.Hour,
+		"18:30": 18*time.Hour + 30*time.Minute,
+		"00:00": 0,
+		"24:00": 24 * time.Hour,
+	} {
+		got, err := ParseTimeOfDay(input)
+		require.NoError(t, err, input)
+		assert.Equal(t, want, got, input)
+	}
+	for _, input := range []string{"", "9", "9:5", "25:00", "24:30", "12:60", "ab:cd"} {
+		_, err := ParseTimeOfDay(input)
+		assert.Error(t, err, input)
+	}
+}
@@ -0,0 +1,208 @@
+package transfer
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ErrBatchItemCancelled is the error of the files of a cancelled batch item. It wraps
+// ErrTransferCancelled, so the files are not retried.
+var ErrBatchItemCancelled = fmt.Errorf("batch item cancelled: %w", ErrTransferCancelled)
+
+// BatchItem is one of several sends to the same receiver that are offered together and
+// transferred as one session. Its files are the ones at or below its roots.
+type BatchItem struct {
+	ID    string
+	Name  string
+	Roots []string // Paths of the top-level files and directories of the item
+}
+
+// contains reports whether the file at path belongs to the item
+func (item BatchItem) contains(path string) bool {
+	for _, root := range item.Roots {
+		if path == root || strings.HasPrefix(path, root+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// SetBatch makes the session a batch of items, each reported as a child session by
+// GetSessionStatus and cancellable on its own with CancelBatchItem. Files outside every
+// item, like a generated manifest, belong to the batch only.
+func (utm *UnifiedTransferManager) SetBatch(items []BatchItem) {
+	utm.queueMu.Lock()
+	defer utm.queueMu.Unlock()
+	utm.batch = append([]BatchItem(nil), items...)
+	utm.cancelledItems = make(map[string]bool)
+}
+
+// BatchItemOf returns the ID of the batch item the file at path belongs to, if any
+func (utm *UnifiedTransferManager) BatchItemOf(path string) (string, bool) {
+	utm.queueMu.RLock()
+	defer utm.queueMu.RUnlock()
+	return utm.batchItemOfLocked(path)
+}
+
+// batchItemOfLocked returns the ID of the batch item of path; queueMu must be held
+func (utm *UnifiedTransferManager) batchItemOfLocked(path string) (string, bool) {
+	for _, item := range utm.batch {
+		if item.contains(path) {
+			return item.ID, true
+		}
+	}
+	return "", false
+}
+
+// IsFileCancelled reports whether the batch item of the file at path was cancelled, so
+// sending it should stop
+func (utm *UnifiedTransferManager) IsFileCancelled(path string) bool {
+	utm.queueMu.RLock()
+	defer utm.queueMu.RUnlock()
+	id, ok := utm.batchItemOfLocked(path)
+	return ok && utm.cancelledItems[id]
+}
+
+// CancelBatchItem cancels the files of the batch item id that are not sent yet, while the
+// other items carry on. Its pending files are marked cancelled right away; the file being
+// sent, if it belongs to the item, is marked cancelled and stops at its next chunk, see
+// IsFileCancelled. It returns the paths of the cancelled files, sorted.
+func (utm *UnifiedTransferManager) CancelBatchItem(id string) ([]string, error) {
+	utm.queueMu.Lock()
+	utm.statusMu.Lock()
+	defer utm.statusMu.Unlock()
+	defer utm.queueMu.Unlock()
+
+	found := false
+	for _, item := range utm.batch {
+		found = found || item.ID == id
+	}
+	if !found {
+		return nil, fmt.Errorf("%w: batch item %s", ErrTransferNotFound, id)
+	}
+	if utm.sessionStatus.State == StatusSessionStateCompleted ||
+		utm.sessionStatus.State == StatusSessionStateFailed ||
+		utm.sessionStatus.State == StatusSessionStateCancelled {
+		return nil, fmt.Errorf("cannot cancel batch item: session is already finished (current state: %s)", utm.sessionStatus.State)
+	}
+	if utm.cancelledItems[id] {
+		return nil, nil
+	}
+	utm.cancelledItems[id] = true
+
+	var cancelled []string
+	for path := range utm.pendingFiles {
+		if itemID, ok := utm.batchItemOfLocked(path); ok && itemID == id {
+			cancelled = append(cancelled, path)
+		}
+	}
+	sort.Strings(cancelled)
+
+	oldSessionStatus := *utm.sessionStatus
+	now := utm.now()
+	for _, path := range cancelled {
+		if current, ok := utm.activeFiles[path]; ok {
+			// The send loop fails it with ErrBatchItemCancelled, which updates the counters
+			current.State = TransferStateCancelled
+			current.LastError = ErrBatchItemCancelled
+			current.LastUpdateTime = now
+			continue
+		}
+
+		// A file waiting for a retry is pending too
+		utm.retryScheduler.CancelRetry(path)
+		utm.moveFileInQueue(path, FileQueueStatePending, FileQueueStateFailed)
+		utm.sessionStatus.FailedFiles++
+		utm.sessionStatus.PendingFiles--
+
+		fileStatus := &TransferStatus{
+			FilePath:       path,
+			SessionID:      utm.sessionStatus.SessionID,
+			State:          TransferStateCancelled,
+			LastError:      ErrBatchItemCancelled,
+			LastUpdateTime: now,
+			clock:          utm.clock,
+		}
+		if node, ok := utm.structure.GetFile(path); ok {
+			fileStatus.TotalBytes, fileStatus.FileSize = node.Size, node.Size
+		}
+		utm.dispatch(func() { utm.notifyFileStatusChanged(path, nil, fileStatus) })
+	}
+
+	utm.sessionStatus.LastUpdateTime = now
+	utm.sessionStatus.OverallProgress = utm.sessionStatus.GetSessionProgressPercentage()
+	if len(utm.activeFiles) == 0 && utm.sessionStatus.IsSessionComplete() {
+		utm.sessionStatus.CompletionTime = &now
+		utm.sessionStatus.State = StatusSessionStateCompleted
+	}
+
+	newSessionStatus := *utm.sessionStatus
+	utm.dispatch(func() { utm.notifySessionStatusChanged(&oldSessionStatus, &newSessionStatus) })
+
+	return cancelled, nil
+}
+
+// batchStatusLocked returns the child sessions of a batch session, one per item, or nil
+// for other sessions; queueMu and statusMu must be held
+func (utm *UnifiedTransferManager) batchStatusLocked() []*SessionTransferStatus {
+	if len(utm.batch) == 0 {
+		return nil
+	}
+
+	children := make([]*SessionTransferStatus, len(utm.batch))
+	byID := make(map[string]*SessionTransferStatus, len(utm.batch))
+	for i, item := range utm.batch {
+		children[i] = &SessionTransferStatus{
+			SessionID:      item.ID,
+			ParentID:       utm.sessionStatus.SessionID,
+			Name:           item.Name,
+			StartTime:      utm.sessionStatus.StartTime,
+			LastUpdateTime: utm.sessionStatus.LastUpdateTime,
+			State:          utm.sessionStatus.State,
+		}
+		byID[item.ID] = children[i]
+	}
+
+	for _, node := range utm.structure.GetAllFiles() {
+		id, ok := utm.batchItemOfLocked(node.Path)
+		if !ok {
+			continue
+		}
+		child := byID[id]
+		child.TotalFiles++
+		if node.Size > 0 {
+			child.TotalBytes += node.Size
+		}
+		switch {
+		case utm.completedFiles[node.Path]:
+			child.CompletedFiles++
+			child.BytesCompleted += max(node.Size, 0)
+		case utm.failedFiles[node.Path]:
+			child.FailedFiles++
+		default:
+			child.PendingFiles++
+		}
+		if current := utm.sessionStatus.CurrentFile; current != nil && current.FilePath == node.Path {
+			currentCopy := *current
+			child.CurrentFile = &currentCopy
+		}
+	}
+
+	for _, child := range children {
+		child.OverallProgress = child.GetSessionProgressPercentage()
+		switch {
+		case utm.cancelledItems[child.SessionID]:
+			child.State = StatusSessionStateCancelled
+		case child.TotalFiles > 0 && child.IsSessionComplete() && child.CompletedFiles == 0:
+			child.State = StatusSessionStateFailed
+		case child.TotalFiles > 0 && child.IsSessionComplete():
+			child.State = StatusSessionStateCompleted
+		case utm.sessionStatus.State == StatusSessionStateCompleted:
+			// An item with nothing left to send once the batch is done is done too
+			child.State = StatusSessionStateCompleted
+		}
+	}
+	return children
+}
@@ -0,0 +1,118 @@
+package transfer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rescp17/lanFileSharer/pkg/fileInfo"
+)
+
+// addBatchItem adds an item with the files names in a directory of its own to manager
+func addBatchItem(t *testing.T, manager *UnifiedTransferManager, id string, names ...string) (BatchItem, []string) {
+	dir := filepath.Join(t.TempDir(), id)
+	require.NoError(t, os.Mkdir(dir, 0755))
+	paths := make([]string, 0, len(names))
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte("content of "+name), 0644))
+		node, err := fileInfo.CreateNode(path)
+		require.NoError(t, err)
+		require.NoError(t, manager.AddFile(&node))
+		paths = append(paths, path)
+	}
+	return BatchItem{ID: id, Name: id, Roots: []string{dir}}, paths
+}
+
+func newBatchManager(t *testing.T) *UnifiedTransferManager {
+	manager := NewUnifiedTransferManager("test-batch")
+	t.Cleanup(func() {
+		manager.Close()
+		manager.Shutdown()
+	})
+	manager.SetTransport(NewQueueTransport())
+	return manager
+}
+
+func TestUnifiedTransferManager_CancelBatchItem(t *testing.T) {
+	manager := newBatchManager(t)
+	docs, docPaths := addBatchItem(t, manager, "docs", "a.txt", "b.txt")
+	photos, photoPaths := addBatchItem(t, manager, "photos", "c.jpg", "d.jpg")
+	manager.SetBatch([]BatchItem{docs, photos})
+
+	id, ok := manager.BatchItemOf(photoPaths[1])
+	require.True(t, ok)
+	assert.Equal(t, "photos", id)
+
+	require.NoError(t, manager.StartTransfer(docPaths[0]))
+	require.NoError(t, manager.CompleteTransfer(docPaths[0]))
+
+	cancelled, err := manager.CancelBatchItem("photos")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, photoPaths, cancelled)
+	assert.True(t, manager.IsFileCancelled(photoPaths[0]))
+	assert.False(t, manager.IsFileCancelled(docPaths[1]))
+
+	// Only the other item is left to send
+	next, ok := manager.GetNextPendingFile()
+	require.True(t, ok)
+	assert.Equal(t, docPaths[1], next.Path)
+
+	status := manager.GetSessionStatus()
+	assert.Equal(t, 4, status.TotalFiles)
+	assert.Equal(t, 2, status.FailedFiles)
+	assert.Equal(t, StatusSessionStateActive, status.State, "the batch carries on")
+	require.Len(t, status.Children, 2)
+	assert.Equal(t, "docs", status.Children[0].SessionID)
+	assert.Equal(t, "test-batch", status.Children[0].ParentID)
+	assert.Equal(t, 1, status.Children[0].CompletedFiles)
+	assert.Equal(t, 1, status.Children[0].PendingFiles)
+	assert.Equal(t, StatusSessionStateActive, status.Children[0].State)
+	assert.Equal(t, 2, status.Children[1].FailedFiles)
+	assert.Equal(t, StatusSessionStateCancelled, status.Children[1].State)
+
+	require.NoError(t, manager.StartTransfer(docPaths[1]))
+	require.NoError(t, manager.CompleteTransfer(docPaths[1]))
+	status = manager.GetSessionStatus()
+	assert.Equal(t, StatusSessionStateCompleted, status.State)
+	assert.Equal(t, StatusSessionStateCompleted, status.Children[0].State)
+	assert.InDelta(t, 100.0, status.Children[0].OverallProgress, 0.001)
+
+	_, err = manager.CancelBatchItem("missing")
+	assert.ErrorIs(t, err, ErrTransferNotFound)
+}
+
+func TestUnifiedTransferManager_CancelBatchItemOfCurrentFile(t *testing.T) {
+	manager := newBatchManager(t)
+	docs, docPaths := addBatchItem(t, manager, "docs", "a.txt")
+	music, musicPaths := addBatchItem(t, manager, "music", "song.mp3")
+	manager.SetBatch([]BatchItem{docs, music})
+
+	require.NoError(t, manager.StartTransfer(musicPaths[0]))
+	cancelled, err := manager.CancelBatchItem("music")
+	require.NoError(t, err)
+	assert.Equal(t, musicPaths, cancelled)
+	// The file being sent stops at its next chunk, the send loop then fails it
+	assert.True(t, manager.IsFileCancelled(musicPaths[0]))
+	require.NoError(t, manager.FailTransfer(musicPaths[0], ErrBatchItemCancelled))
+
+	_, scheduled := manager.GetRetryStatus(musicPaths[0])
+	assert.False(t, scheduled, "cancelled files are not retried")
+
+	status := manager.GetSessionStatus()
+	assert.Equal(t, 1, status.FailedFiles)
+	assert.Equal(t, 1, status.PendingFiles)
+	assert.Equal(t, StatusSessionStateCancelled, status.Children[1].State)
+
+	next, ok := manager.GetNextPendingFile()
+	require.True(t, ok)
+	assert.Equal(t, docPaths[0], next.Path)
+
+	// Cancelling again changes nothing
+	cancelled, err = manager.CancelBatchItem("music")
+	require.NoError(t, err)
+	assert.Empty(t, cancelled)
+}
@@ -0,0 +1,201 @@
+package transfer
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ChaosConfig sets how often a FaultInjector tampers with the chunks it passes on.
+// Rates are probabilities between 0 and 1, applied to every chunk independently.
+type ChaosConfig struct {
+	DropRate    float64       // Chunks never sent
+	DelayRate   float64       // Chunks sent after Delay
+	Delay       time.Duration // How long delayed chunks are held
+	ReorderRate float64       // Chunks held back and sent after the next one
+	CorruptRate float64       // Chunks sent with one byte of their data flipped
+	Seed        int64         // Seed of the fault sequence; zero picks one from the clock
+}
+
+// defaultChaosDelay is the delay of delayed chunks when the spec gives none
+const defaultChaosDelay = 50 * time.Millisecond
+
+// ParseChaosConfig parses a fault spec such as "drop=0.01,delay=0.05:20ms,reorder=0.02,corrupt=0.01,seed=42".
+// Every key is optional; delay takes the rate and, after a colon, the delay.
+func ParseChaosConfig(spec string) (ChaosConfig, error) {
+	cfg := ChaosConfig{Delay: defaultChaosDelay}
+	for _, field := range strings.Split(spec, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(field, "=")
+		if !ok {
+			return ChaosConfig{}, fmt.Errorf("%w: chaos setting %q is not key=value", ErrInvalidConfiguration, field)
+		}
+
+		var err error
+		switch key {
+		case "drop":
+			cfg.DropRate, err = parseChaosRate(value)
+		case "delay":
+			rate, delay, hasDelay := strings.Cut(value, ":")
+			cfg.DelayRate, err = parseChaosRate(rate)
+			if err == nil && hasDelay {
+				cfg.Delay, err = time.ParseDuration(delay)
+				if err == nil && cfg.Delay <= 0 {
+					err = fmt.Errorf("delay must be positive")
+				}
+			}
+		case "reorder":
+			cfg.ReorderRate, err = parseChaosRate(value)
+		case "corrupt":
+			cfg.CorruptRate, err = parseChaosRate(value)
+		case "seed":
+			cfg.Seed, err = strconv.ParseInt(value, 10, 64)
+		default:
+			return ChaosConfig{}, fmt.Errorf("%w: unknown chaos setting %q", ErrInvalidConfiguration, key)
+		}
+		if err != nil {
+			return ChaosConfig{}, fmt.Errorf("%w: chaos setting %s: %w", ErrInvalidConfiguration, key, err)
+		}
+	}
+	return cfg, nil
+}
+
+func parseChaosRate(value string) (float64, error) {
+	rate, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, err
+	}
+	if rate < 0 || rate > 1 {
+		return 0, fmt.Errorf("rate %v is not between 0 and 1", rate)
+	}
+	return rate, nil
+}
+
+// Enabled reports whether any fault is injected
+func (c ChaosConfig) Enabled() bool {
+	return c.DropRate > 0 || c.DelayRate > 0 || c.ReorderRate > 0 || c.CorruptRate > 0
+}
+
+// String describes the configuration in the form ParseChaosConfig accepts
+func (c ChaosConfig) String() string {
+	return fmt.Sprintf("drop=%g,delay=%g:%s,reorder=%g,corrupt=%g,seed=%d",
+		c.DropRate, c.DelayRate, c.Delay, c.ReorderRate, c.CorruptRate, c.Seed)
+}
+
+// ChaosStats counts the faults a FaultInjector injected
+type ChaosStats struct {
+	Chunks    int // Chunks passed to Send
+	Dropped   int
+	Delayed   int
+	Reordered int
+	Corrupted int
+}
+
+// FaultInjector tampers with chunk messages on their way to the receiver, to test that
+// retries and verification recover from lossy or faulty links. It is safe for concurrent use.
+type FaultInjector struct {
+	cfg ChaosConfig
+
+	mu    sync.Mutex
+	rng   *rand.Rand
+	held  *heldChunk // Chunk held back to be sent after the next one
+	stats ChaosStats
+}
+
+type heldChunk struct {
+	msg  *ChunkMessage
+	send func(*ChunkMessage) error
+}
+
+// NewFaultInjector creates an injector that applies the faults of cfg
+func NewFaultInjector(cfg ChaosConfig) *FaultInjector {
+	seed := cfg.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	return &FaultInjector{cfg: cfg, rng: rand.New(rand.NewSource(seed))}
+}
+
+// Send passes msg to send unless it is dropped. It may first be corrupted, delayed, or held
+// back and sent after the next chunk. Messages other than chunk data are sent unchanged,
+// after any held chunk. A dropped chunk counts as sent, like a packet lost on the wire.
+func (f *FaultInjector) Send(msg *ChunkMessage, send func(*ChunkMessage) error) error {
+	if msg.Type != ChunkData {
+		if err := f.Flush(); err != nil {
+			return err
+		}
+		return send(msg)
+	}
+
+	f.mu.Lock()
+	f.stats.Chunks++
+	if f.roll(f.cfg.DropRate) {
+		f.stats.Dropped++
+		f.mu.Unlock()
+		return nil
+	}
+	if f.roll(f.cfg.CorruptRate) && len(msg.Data) > 0 {
+		f.stats.Corrupted++
+		corrupted := *msg
+		corrupted.Data = append([]byte(nil), msg.Data...)
+		corrupted.Data[f.rng.Intn(len(corrupted.Data))] ^= 0xff
+		msg = &corrupted
+	}
+	var delay time.Duration
+	if f.roll(f.cfg.DelayRate) {
+		f.stats.Delayed++
+		delay = f.cfg.Delay
+	}
+	// The last chunk of a file is never held, so nothing is left behind when the file ends
+	held := f.held
+	if held == nil && !msg.IsLast && f.roll(f.cfg.ReorderRate) {
+		f.stats.Reordered++
+		f.held = &heldChunk{msg: msg, send: send}
+		f.mu.Unlock()
+		return nil
+	}
+	f.held = nil
+	f.mu.Unlock()
+
+	if delay > 0 {
+		time.Sleep(delay)
+	}
+	if err := send(msg); err != nil {
+		return err
+	}
+	if held != nil {
+		return held.send(held.msg)
+	}
+	return nil
+}
+
+// Flush sends the chunk held back for reordering, if any
+func (f *FaultInjector) Flush() error {
+	f.mu.Lock()
+	held := f.held
+	f.held = nil
+	f.mu.Unlock()
+
+	if held == nil {
+		return nil
+	}
+	return held.send(held.msg)
+}
+
+// Stats returns the number of chunks seen and faults injected so far
+func (f *FaultInjector) Stats() ChaosStats {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.stats
+}
+
+// roll reports whether a fault of the given rate happens; f.mu must be held
+func (f *FaultInjector) roll(rate float64) bool {
+	return rate > 0 && f.rng.Float64() < rate
+}
@@ -0,0 +1,93 @@
+package transfer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseChaosConfig(t *testing.T) {
+	cfg, err := ParseChaosConfig("drop=0.01, delay=0.05:20ms,reorder=0.02,corrupt=0.5,seed=42")
+	require.NoError(t, err)
+	assert.Equal(t, ChaosConfig{
+		DropRate:    0.01,
+		DelayRate:   0.05,
+		Delay:       20 * time.Millisecond,
+		ReorderRate: 0.02,
+		CorruptRate: 0.5,
+		Seed:        42,
+	}, cfg)
+	assert.True(t, cfg.Enabled())
+
+	reparsed, err := ParseChaosConfig(cfg.String())
+	require.NoError(t, err)
+	assert.Equal(t, cfg, reparsed)
+
+	cfg, err = ParseChaosConfig("delay=0.1")
+	require.NoError(t, err)
+	assert.Equal(t, defaultChaosDelay, cfg.Delay)
+
+	cfg, err = ParseChaosConfig("")
+	require.NoError(t, err)
+	assert.False(t, cfg.Enabled())
+
+	for _, spec := range []string{"drop", "drop=2", "drop=x", "delay=0.1:-1s", "jitter=0.1", "seed=abc"} {
+		_, err := ParseChaosConfig(spec)
+		assert.ErrorIs(t, err, ErrInvalidConfiguration, spec)
+	}
+}
+
+func chaosChunks(n int) []*ChunkMessage {
+	msgs := make([]*ChunkMessage, n)
+	for i := range msgs {
+		msgs[i] = &ChunkMessage{Type: ChunkData, SequenceNo: uint32(i + 1), Data: []byte{byte(i), 1, 2, 3}, IsLast: i == n-1}
+	}
+	return msgs
+}
+
+func TestFaultInjector(t *testing.T) {
+	t.Run("drop", func(t *testing.T) {
+		injector := NewFaultInjector(ChaosConfig{DropRate: 1, Seed: 1})
+		var sent []*ChunkMessage
+		send := func(msg *ChunkMessage) error { sent = append(sent, msg); return nil }
+		for _, msg := range chaosChunks(3) {
+			require.NoError(t, injector.Send(msg, send))
+		}
+		require.NoError(t, injector.Send(&ChunkMessage{Type: StructureUpdate}, send))
+		require.Len(t, sent, 1, "only chunk data is dropped")
+		assert.Equal(t, StructureUpdate, sent[0].Type)
+		assert.Equal(t, ChaosStats{Chunks: 3, Dropped: 3}, injector.Stats())
+	})
+
+	t.Run("corrupt", func(t *testing.T) {
+		injector := NewFaultInjector(ChaosConfig{CorruptRate: 1, Seed: 1})
+		original := chaosChunks(1)[0]
+		data := append([]byte(nil), original.Data...)
+		var sent *ChunkMessage
+		require.NoError(t, injector.Send(original, func(msg *ChunkMessage) error { sent = msg; return nil }))
+		assert.NotEqual(t, data, sent.Data)
+		assert.Equal(t, data, original.Data, "the caller's chunk is left intact for retries")
+		assert.Equal(t, 1, injector.Stats().Corrupted)
+	})
+
+	t.Run("reorder", func(t *testing.T) {
+		injector := NewFaultInjector(ChaosConfig{ReorderRate: 1, Seed: 1})
+		var order []uint32
+		send := func(msg *ChunkMessage) error { order = append(order, msg.SequenceNo); return nil }
+		for _, msg := range chaosChunks(4) {
+			require.NoError(t, injector.Send(msg, send))
+		}
+		require.NoError(t, injector.Flush())
+		assert.Equal(t, []uint32{2, 1, 4, 3}, order, "every held chunk follows the next one, and the last is never held")
+	})
+
+	t.Run("delay", func(t *testing.T) {
+		injector := NewFaultInjector(ChaosConfig{DelayRate: 1, Delay: 10 * time.Millisecond, Seed: 1})
+		start := time.Now()
+		require.NoError(t, injector.Send(chaosChunks(1)[0], func(*ChunkMessage) error { return nil }))
+		assert.GreaterOrEqual(t, time.Since(start), 10*time.Millisecond)
+		assert.Equal(t, 1, injector.Stats().Delayed)
+	})
+}
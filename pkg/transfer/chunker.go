@@ -1,14 +1,20 @@
 package transfer
 
 import (
+	"context"
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
+	"hash"
 	"io"
 	"os"
-	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/rescp17/lanFileSharer/pkg/fileInfo"
+	"golang.org/x/sync/errgroup"
 )
 
 type Chunk struct {
@@ -21,17 +27,35 @@ type Chunk struct {
 }
 
 type Chunker struct {
+	mu            sync.Mutex // Guards file, reader and lastUsed against idle eviction
+	path          string
 	file          *os.File
+	reader        io.Reader
 	expectedHash  string
 	chunkSize     int32
 	currentSeq    uint32
 	totalByteSize int64
+	modTime       time.Time // Modification time the file is sent with; a change fails the file
 	bytesRead     int64
 	buffer        []byte
+
+	// Streaming support for sources of unknown length
+	streaming bool
+	hasher    hash.Hash // Running hash of the whole stream
+	finished  bool      // Whether the terminal chunk has been emitted
+
+	lastUsed time.Time
+	closed   bool
+	readers  int // ReadChunkAt calls using file; the handle is not released while positive
+
+	onRead func(bytes int64, err error) // Observes every read; set before the chunker is shared
 }
 
 var ErrIsDir = errors.New("cannot chunk a directory")
 
+// ErrNotSeekable is returned by ReadChunkAt for streaming chunkers
+var ErrNotSeekable = errors.New("cannot read a stream at an offset")
+
 // Chunk size constants are now defined in config.go to avoid duplication
 
 func NewChunkerFromFileNode(node *fileInfo.FileNode, chunkSize int32) (*Chunker, error) {
@@ -42,30 +66,154 @@ func NewChunkerFromFileNode(node *fileInfo.FileNode, chunkSize int32) (*Chunker,
 	if chunkSize < MinChunkSize || chunkSize > MaxChunkSize {
 		return nil, fmt.Errorf("chunk size must be between %d and %d", MinChunkSize, MaxChunkSize)
 	}
+	if node.IsStream() {
+		if node.Path != fileInfo.StdinPath {
+			return nil, fmt.Errorf("unsupported stream source: %s", node.Path)
+		}
+		return NewStreamChunker(os.Stdin, chunkSize)
+	}
+
 	file, err := os.Open(node.Path)
 	if err != nil {
-		return nil, err
+		return nil, ClassifyIOError(err)
+	}
+	// Nodes that were not scanned here are checked against the file as it is now
+	modTime := node.ModTime()
+	if modTime.IsZero() {
+		if info, err := file.Stat(); err == nil {
+			modTime = info.ModTime()
+		}
 	}
 
 	return &Chunker{
+		path:          node.Path,
 		file:          file,
+		reader:        file,
 		expectedHash:  node.Checksum,
 		chunkSize:     chunkSize,
 		currentSeq:    0,
 		totalByteSize: node.Size,
+		modTime:       modTime,
 		bytesRead:     0,
 		buffer:        make([]byte, chunkSize),
+		lastUsed:      time.Now(),
 	}, nil
 }
 
+// NewStreamChunker creates a chunker for a reader whose length is not known upfront.
+// The last chunk is flagged IsLast once the reader is exhausted; it may carry no data.
+func NewStreamChunker(r io.Reader, chunkSize int32) (*Chunker, error) {
+	if chunkSize < MinChunkSize || chunkSize > MaxChunkSize {
+		return nil, fmt.Errorf("chunk size must be between %d and %d", MinChunkSize, MaxChunkSize)
+	}
+
+	return &Chunker{
+		reader:        r,
+		chunkSize:     chunkSize,
+		totalByteSize: fileInfo.UnknownSize,
+		buffer:        make([]byte, chunkSize),
+		streaming:     true,
+		hasher:        sha256.New(),
+		lastUsed:      time.Now(),
+	}, nil
+}
+
+// IsStreaming reports whether the chunker reads a source of unknown length
+func (c *Chunker) IsStreaming() bool {
+	return c.streaming
+}
+
+// BytesRead returns the number of bytes read from the source so far
+func (c *Chunker) BytesRead() int64 {
+	return c.bytesRead
+}
+
+// StreamHash returns the SHA256 of all data read so far from a streaming source
+func (c *Chunker) StreamHash() string {
+	if c.hasher == nil {
+		return c.expectedHash
+	}
+	return hex.EncodeToString(c.hasher.Sum(nil))
+}
+
+// IsOpen reports whether the chunker currently holds an open file handle
+func (c *Chunker) IsOpen() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.file != nil
+}
+
+// LastUsed returns when the chunker was last read from or reopened
+func (c *Chunker) LastUsed() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lastUsed
+}
+
+// Release closes the underlying file handle while keeping the read position.
+// The file is reopened on the next call to Next. Streaming chunkers cannot be released.
+func (c *Chunker) Release() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.streaming || c.file == nil || c.readers > 0 {
+		return nil
+	}
+	err := c.file.Close()
+	c.file = nil
+	c.reader = nil
+	return err
+}
+
+// reopen restores a released file handle at the current read position
+func (c *Chunker) reopen() error {
+	file, err := os.Open(c.path)
+	if err != nil {
+		return fmt.Errorf("failed to reopen %s: %w", c.path, ClassifyIOError(err))
+	}
+	if _, err := file.Seek(c.bytesRead, io.SeekStart); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to seek %s to offset %d: %w", c.path, c.bytesRead, err)
+	}
+	c.file = file
+	c.reader = file
+	return nil
+}
+
 func (c *Chunker) Next() (*Chunk, error) {
+	chunk, err := c.next()
+	c.reportRead(chunk, err)
+	return chunk, err
+}
+
+func (c *Chunker) next() (*Chunk, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lastUsed = time.Now()
+
+	if c.streaming {
+		return c.nextStream()
+	}
+
 	if c.bytesRead >= c.totalByteSize {
 		return nil, io.EOF
 	}
 
-	n, err := c.file.Read(c.buffer)
+	if c.file == nil {
+		if c.closed {
+			return nil, os.ErrClosed
+		}
+		if err := c.reopen(); err != nil {
+			return nil, err
+		}
+	}
+
+	n, err := c.reader.Read(c.buffer)
 
 	if n > 0 {
+		if err := c.checkUnchanged(c.file); err != nil {
+			return nil, err
+		}
 		c.bytesRead += int64(n)
 		c.currentSeq++
 
@@ -93,9 +241,233 @@ func (c *Chunker) Next() (*Chunk, error) {
 		return nil, io.EOF
 	}
 	
-	return nil, err
+	return nil, ClassifyIOError(err)
+}
+
+// ChunkCount returns the number of chunks of a file of known size, zero for streams.
+// Chunks are numbered from 1 by Next and ReadChunkAt.
+func (c *Chunker) ChunkCount() uint32 {
+	if c.streaming || c.totalByteSize <= 0 {
+		return 0
+	}
+	return uint32((c.totalByteSize + int64(c.chunkSize) - 1) / int64(c.chunkSize))
+}
+
+// ReadChunkAt reads chunk seq at its offset without moving the read position of Next.
+// It is safe for concurrent use, so several goroutines can read different parts of one file.
+// It returns io.EOF for sequence numbers beyond the last chunk.
+func (c *Chunker) ReadChunkAt(seq uint32) (*Chunk, error) {
+	chunk, err := c.readChunkAt(seq)
+	c.reportRead(chunk, err)
+	return chunk, err
+}
+
+func (c *Chunker) readChunkAt(seq uint32) (*Chunk, error) {
+	if c.streaming {
+		return nil, ErrNotSeekable
+	}
+	if seq == 0 || seq > c.ChunkCount() {
+		return nil, io.EOF
+	}
+	offset := int64(seq-1) * int64(c.chunkSize)
+	size := min(int64(c.chunkSize), c.totalByteSize-offset)
+
+	file, err := c.acquireFile()
+	if err != nil {
+		return nil, err
+	}
+	defer c.releaseFile()
+
+	data := make([]byte, size)
+	n, err := file.ReadAt(data, offset)
+	if int64(n) < size {
+		if err == nil || err == io.EOF {
+			return nil, fmt.Errorf("%s shrank while reading chunk %d: %w", c.path, seq, io.ErrUnexpectedEOF)
+		}
+		return nil, ClassifyIOError(err)
+	}
+	if err := c.checkUnchanged(file); err != nil {
+		return nil, err
+	}
+
+	hash := sha256.Sum256(data)
+	return &Chunk{
+		SequenceNo: seq,
+		Offset:     offset,
+		Data:       data,
+		Hash:       hex.EncodeToString(hash[:]),
+		IsLast:     offset+size >= c.totalByteSize,
+		Size:       int32(size),
+	}, nil
+}
+
+// reportRead passes the bytes a read returned, or its error, to onRead. Reaching the end of
+// the file is not an error.
+func (c *Chunker) reportRead(chunk *Chunk, err error) {
+	if c.onRead == nil || err == io.EOF {
+		return
+	}
+	var n int64
+	if chunk != nil {
+		n = int64(len(chunk.Data))
+	}
+	c.onRead(n, err)
+}
+
+// checkUnchanged returns ErrSourceModified if file no longer has the size and modification
+// time it is sent with. It runs after each read, so a chunk that may mix old and new content
+// is never sent.
+func (c *Chunker) checkUnchanged(file *os.File) error {
+	info, err := file.Stat()
+	if err != nil {
+		return ClassifyIOError(err)
+	}
+	if info.Size() != c.totalByteSize || !info.ModTime().Equal(c.modTime) {
+		return fmt.Errorf("%w: %s changed after it was offered, send it again", ErrSourceModified, c.path)
+	}
+	return nil
+}
+
+// acquireFile returns the open file handle, reopening a released one, and keeps it
+// from being released until releaseFile is called
+func (c *Chunker) acquireFile() (*os.File, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lastUsed = time.Now()
+
+	if c.file == nil {
+		if c.closed {
+			return nil, os.ErrClosed
+		}
+		if err := c.reopen(); err != nil {
+			return nil, err
+		}
+	}
+	c.readers++
+	return c.file, nil
+}
+
+func (c *Chunker) releaseFile() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.readers--
+}
+
+// ReadChunksParallel reads all chunks of c with workers goroutines and passes each to fn
+// together with the index of the worker that read it. Worker w reads chunks w+1,
+// w+1+workers and so on, so every worker covers a stripe of the file. The first error
+// returned by fn or a read stops all workers.
+func ReadChunksParallel(ctx context.Context, c *Chunker, workers int, fn func(worker int, chunk *Chunk) error) error {
+	if c.IsStreaming() {
+		return ErrNotSeekable
+	}
+	if workers < 1 {
+		workers = 1
+	}
+
+	count := c.ChunkCount()
+	g, ctx := errgroup.WithContext(ctx)
+	for w := 0; w < workers; w++ {
+		g.Go(func() error {
+			for seq := uint32(w) + 1; seq <= count; seq += uint32(workers) {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+				chunk, err := c.ReadChunkAt(seq)
+				if err != nil {
+					return fmt.Errorf("failed to read chunk %d: %w", seq, err)
+				}
+				if err := fn(w, chunk); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+	return g.Wait()
+}
+
+// ReadChunksShared reads all chunks of c with workers goroutines like ReadChunksParallel, but
+// every worker takes the next chunk nobody read yet, so a worker whose fn returns sooner reads
+// more of the file. It suits workers sending over paths of different speeds.
+func ReadChunksShared(ctx context.Context, c *Chunker, workers int, fn func(worker int, chunk *Chunk) error) error {
+	if c.IsStreaming() {
+		return ErrNotSeekable
+	}
+	if workers < 1 {
+		workers = 1
+	}
+
+	count := c.ChunkCount()
+	var next atomic.Uint32
+	g, ctx := errgroup.WithContext(ctx)
+	for w := 0; w < workers; w++ {
+		g.Go(func() error {
+			for seq := next.Add(1); seq <= count; seq = next.Add(1) {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+				chunk, err := c.ReadChunkAt(seq)
+				if err != nil {
+					return fmt.Errorf("failed to read chunk %d: %w", seq, err)
+				}
+				if err := fn(w, chunk); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+	return g.Wait()
+}
+
+// nextStream fills a whole chunk from the stream; a short read marks the last chunk
+func (c *Chunker) nextStream() (*Chunk, error) {
+	if c.finished {
+		return nil, io.EOF
+	}
+
+	n, err := io.ReadFull(c.reader, c.buffer)
+	isLast := false
+	switch {
+	case err == io.EOF || err == io.ErrUnexpectedEOF:
+		isLast = true
+	case err != nil:
+		return nil, ClassifyIOError(err)
+	}
+
+	offset := c.bytesRead
+	c.bytesRead += int64(n)
+	c.currentSeq++
+	c.hasher.Write(c.buffer[:n])
+
+	hash := sha256.Sum256(c.buffer[:n])
+	data := make([]byte, n)
+	copy(data, c.buffer[:n])
+
+	if isLast {
+		c.finished = true
+	}
+
+	return &Chunk{
+		SequenceNo: c.currentSeq,
+		Offset:     offset,
+		Data:       data,
+		Hash:       hex.EncodeToString(hash[:]),
+		IsLast:     isLast,
+		Size:       int32(n),
+	}, nil
 }
 
 func (c *Chunker) Close() error {
-	return c.file.Close()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.closed = true
+	if c.file == nil {
+		return nil
+	}
+	err := c.file.Close()
+	c.file = nil
+	return err
 }
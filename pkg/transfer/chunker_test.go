@@ -1,12 +1,17 @@
 package transfer
 
 import (
+	"context"
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"io"
 	"os"
 	"path/filepath"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 	"bytes"
 
 	"github.com/rescp17/lanFileSharer/pkg/fileInfo"
@@ -443,3 +448,301 @@ func BenchmarkChunker_Next_LargeChunks(b *testing.B) {
 		chunker.currentSeq = 0
 	}
 }
+
+func TestStreamChunker(t *testing.T) {
+	content := bytes.Repeat([]byte("stream-data-"), 1000) // 12000 bytes
+	chunker, err := NewStreamChunker(bytes.NewReader(content), MinChunkSize)
+	require.NoError(t, err)
+	assert.True(t, chunker.IsStreaming())
+
+	var received []byte
+	var last *Chunk
+	for {
+		chunk, err := chunker.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		assert.Equal(t, int64(len(received)), chunk.Offset)
+		received = append(received, chunk.Data...)
+		last = chunk
+	}
+
+	require.NotNil(t, last)
+	assert.True(t, last.IsLast)
+	assert.Equal(t, content, received)
+	assert.Equal(t, int64(len(content)), chunker.BytesRead())
+
+	expected := sha256.Sum256(content)
+	assert.Equal(t, hex.EncodeToString(expected[:]), chunker.StreamHash())
+	assert.NoError(t, chunker.Close())
+}
+
+func TestStreamChunker_ExactMultipleEmitsEmptyLastChunk(t *testing.T) {
+	content := bytes.Repeat([]byte{1}, int(MinChunkSize)*2)
+	chunker, err := NewStreamChunker(bytes.NewReader(content), MinChunkSize)
+	require.NoError(t, err)
+
+	var chunks []*Chunk
+	for {
+		chunk, err := chunker.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		chunks = append(chunks, chunk)
+	}
+
+	require.Len(t, chunks, 3)
+	assert.False(t, chunks[1].IsLast)
+	assert.True(t, chunks[2].IsLast)
+	assert.Empty(t, chunks[2].Data)
+	assert.Equal(t, int64(len(content)), chunks[2].Offset)
+}
+
+func TestNewChunkerFromFileNode_StdinNode(t *testing.T) {
+	node := fileInfo.NewStdinNode("backup.sql")
+	chunker, err := NewChunkerFromFileNode(&node, DefaultChunkSize)
+	require.NoError(t, err)
+	assert.True(t, chunker.IsStreaming())
+}
+
+func TestChunker_ReleaseReopensAtOffset(t *testing.T) {
+	content := bytes.Repeat([]byte("abcdefgh"), int(MinChunkSize)/2)
+	filePath, cleanup := setupTestFile(t, content)
+	defer cleanup()
+
+	chunker, err := NewChunkerFromFileNode(createFileNode(t, filePath), MinChunkSize)
+	require.NoError(t, err)
+	defer chunker.Close()
+
+	first, err := chunker.Next()
+	require.NoError(t, err)
+
+	require.NoError(t, chunker.Release())
+	assert.False(t, chunker.IsOpen())
+
+	second, err := chunker.Next()
+	require.NoError(t, err)
+	assert.True(t, chunker.IsOpen())
+	assert.Equal(t, int64(MinChunkSize), second.Offset)
+
+	var got []byte
+	got = append(got, first.Data...)
+	got = append(got, second.Data...)
+	for {
+		chunk, err := chunker.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		got = append(got, chunk.Data...)
+	}
+	assert.Equal(t, content, got)
+}
+
+func TestChunker_NextAfterCloseFails(t *testing.T) {
+	filePath, cleanup := setupTestFile(t, []byte("closed"))
+	defer cleanup()
+
+	chunker, err := NewChunkerFromFileNode(createFileNode(t, filePath), MinChunkSize)
+	require.NoError(t, err)
+	require.NoError(t, chunker.Close())
+
+	_, err = chunker.Next()
+	assert.ErrorIs(t, err, os.ErrClosed)
+}
+
+func TestChunker_ReadChunkAtMatchesNext(t *testing.T) {
+	content := make([]byte, 3*MinChunkSize+123)
+	for i := range content {
+		content[i] = byte(i * 7)
+	}
+	filePath, cleanup := setupTestFile(t, content)
+	defer cleanup()
+
+	sequential, err := NewChunkerFromFileNode(createFileNode(t, filePath), MinChunkSize)
+	require.NoError(t, err)
+	defer sequential.Close()
+	random, err := NewChunkerFromFileNode(createFileNode(t, filePath), MinChunkSize)
+	require.NoError(t, err)
+	defer random.Close()
+
+	require.Equal(t, uint32(4), random.ChunkCount())
+	// Read backwards to show reads don't depend on each other
+	for seq := random.ChunkCount(); seq >= 1; seq-- {
+		chunk, err := random.ReadChunkAt(seq)
+		require.NoError(t, err)
+		assert.Equal(t, seq, chunk.SequenceNo)
+		assert.Equal(t, seq == 4, chunk.IsLast)
+	}
+
+	for {
+		want, err := sequential.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		got, err := random.ReadChunkAt(want.SequenceNo)
+		require.NoError(t, err)
+		assert.Equal(t, want, got)
+	}
+
+	_, err = random.ReadChunkAt(5)
+	assert.ErrorIs(t, err, io.EOF)
+	_, err = random.ReadChunkAt(0)
+	assert.ErrorIs(t, err, io.EOF)
+}
+
+func TestChunker_ReadChunkAtAfterRelease(t *testing.T) {
+	content := bytes.Repeat([]byte("x"), 2*int(MinChunkSize))
+	filePath, cleanup := setupTestFile(t, content)
+	defer cleanup()
+
+	chunker, err := NewChunkerFromFileNode(createFileNode(t, filePath), MinChunkSize)
+	require.NoError(t, err)
+	require.NoError(t, chunker.Release())
+
+	chunk, err := chunker.ReadChunkAt(2)
+	require.NoError(t, err)
+	assert.Equal(t, int64(MinChunkSize), chunk.Offset)
+	assert.True(t, chunker.IsOpen())
+
+	require.NoError(t, chunker.Close())
+	_, err = chunker.ReadChunkAt(1)
+	assert.ErrorIs(t, err, os.ErrClosed)
+}
+
+func TestChunker_ReadChunkAtRejectsStreams(t *testing.T) {
+	chunker, err := NewStreamChunker(bytes.NewReader([]byte("stream")), MinChunkSize)
+	require.NoError(t, err)
+
+	_, err = chunker.ReadChunkAt(1)
+	assert.ErrorIs(t, err, ErrNotSeekable)
+	assert.ErrorIs(t, ReadChunksParallel(context.Background(), chunker, 2, nil), ErrNotSeekable)
+}
+
+func TestReadChunksParallel_ReassemblesFile(t *testing.T) {
+	content := make([]byte, 10*MinChunkSize+17)
+	for i := range content {
+		content[i] = byte(i % 251)
+	}
+	filePath, cleanup := setupTestFile(t, content)
+	defer cleanup()
+
+	chunker, err := NewChunkerFromFileNode(createFileNode(t, filePath), MinChunkSize)
+	require.NoError(t, err)
+	defer chunker.Close()
+
+	var mu sync.Mutex
+	got := make([]byte, len(content))
+	workersSeen := make(map[int]int)
+	err = ReadChunksParallel(context.Background(), chunker, 3, func(worker int, chunk *Chunk) error {
+		assert.Equal(t, worker, int(chunk.SequenceNo-1)%3, "chunks should be striped over the workers")
+		hash := sha256.Sum256(chunk.Data)
+		assert.Equal(t, hex.EncodeToString(hash[:]), chunk.Hash)
+
+		mu.Lock()
+		defer mu.Unlock()
+		copy(got[chunk.Offset:], chunk.Data)
+		workersSeen[worker]++
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, content, got)
+	assert.Len(t, workersSeen, 3)
+}
+
+func TestReadChunksParallel_StopsOnError(t *testing.T) {
+	filePath, cleanup := setupTestFile(t, make([]byte, 20*MinChunkSize))
+	defer cleanup()
+
+	chunker, err := NewChunkerFromFileNode(createFileNode(t, filePath), MinChunkSize)
+	require.NoError(t, err)
+	defer chunker.Close()
+
+	sendErr := errors.New("channel closed")
+	var calls atomic.Int32
+	err = ReadChunksParallel(context.Background(), chunker, 2, func(worker int, chunk *Chunk) error {
+		calls.Add(1)
+		return sendErr
+	})
+	assert.ErrorIs(t, err, sendErr)
+	assert.LessOrEqual(t, calls.Load(), int32(2), "each worker should stop after its first failure")
+}
+
+func TestReadChunksShared_FastWorkerReadsMore(t *testing.T) {
+	content := make([]byte, 20*MinChunkSize+5)
+	for i := range content {
+		content[i] = byte(i % 251)
+	}
+	filePath, cleanup := setupTestFile(t, content)
+	defer cleanup()
+
+	chunker, err := NewChunkerFromFileNode(createFileNode(t, filePath), MinChunkSize)
+	require.NoError(t, err)
+	defer chunker.Close()
+
+	var mu sync.Mutex
+	got := make([]byte, len(content))
+	perWorker := make(map[int]int)
+	err = ReadChunksShared(context.Background(), chunker, 2, func(worker int, chunk *Chunk) error {
+		if worker == 1 {
+			time.Sleep(20 * time.Millisecond)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		copy(got[chunk.Offset:], chunk.Data)
+		perWorker[worker]++
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, content, got)
+	assert.Greater(t, perWorker[0], perWorker[1], "the faster worker should take more chunks")
+}
+
+func TestChunker_FailsFilesModifiedWhileSent(t *testing.T) {
+	content := bytes.Repeat([]byte("a"), 3*MinChunkSize)
+	filePath, cleanup := setupTestFile(t, content)
+	defer cleanup()
+	node := createFileNode(t, filePath)
+
+	chunker, err := NewChunkerFromFileNode(node, MinChunkSize)
+	require.NoError(t, err)
+	defer chunker.Close()
+
+	_, err = chunker.Next()
+	require.NoError(t, err)
+
+	// Appending changes the size, the rest of the file is not sent
+	file, err := os.OpenFile(filePath, os.O_APPEND|os.O_WRONLY, 0644)
+	require.NoError(t, err)
+	_, err = file.Write([]byte("more"))
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	_, err = chunker.Next()
+	assert.ErrorIs(t, err, ErrSourceModified)
+	_, err = chunker.ReadChunkAt(2)
+	assert.ErrorIs(t, err, ErrSourceModified)
+}
+
+func TestChunker_FailsFilesRewrittenInPlace(t *testing.T) {
+	content := bytes.Repeat([]byte("a"), 2*MinChunkSize)
+	filePath, cleanup := setupTestFile(t, content)
+	defer cleanup()
+	node := createFileNode(t, filePath)
+
+	// Same size, new content and modification time
+	require.NoError(t, os.WriteFile(filePath, bytes.Repeat([]byte("b"), len(content)), 0644))
+	later := node.ModTime().Add(time.Minute)
+	require.NoError(t, os.Chtimes(filePath, later, later))
+
+	chunker, err := NewChunkerFromFileNode(node, MinChunkSize)
+	require.NoError(t, err)
+	defer chunker.Close()
+
+	_, err = chunker.Next()
+	assert.ErrorIs(t, err, ErrSourceModified, "changes since the file was scanned are caught too")
+	assert.Equal(t, "source_modified", ErrorCode(err))
+}
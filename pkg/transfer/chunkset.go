@@ -0,0 +1,69 @@
+package transfer
+
+import "math/bits"
+
+// ChunkSet is a bitmap of the chunks of one file that have been written, by sequence number.
+// Chunks may be added in any order. The zero value is an empty set.
+type ChunkSet struct {
+	words []uint64
+	count int
+}
+
+// NewChunkSet returns a set holding seqs, such as the chunks of a ResumeFileState
+func NewChunkSet(seqs []uint32) ChunkSet {
+	var s ChunkSet
+	for _, seq := range seqs {
+		s.Add(seq)
+	}
+	return s
+}
+
+// Add marks chunk seq as written and reports whether it was not already
+func (s *ChunkSet) Add(seq uint32) bool {
+	word, bit := seq/64, seq%64
+	if int(word) >= len(s.words) {
+		words := make([]uint64, word+1)
+		copy(words, s.words)
+		s.words = words
+	}
+	if s.words[word]&(1<<bit) != 0 {
+		return false
+	}
+	s.words[word] |= 1 << bit
+	s.count++
+	return true
+}
+
+// Has reports whether chunk seq has been written
+func (s *ChunkSet) Has(seq uint32) bool {
+	word, bit := seq/64, seq%64
+	return int(word) < len(s.words) && s.words[word]&(1<<bit) != 0
+}
+
+// Len returns the number of chunks written
+func (s *ChunkSet) Len() int {
+	return s.count
+}
+
+// Complete reports whether all chunks 1 to count have been written
+func (s *ChunkSet) Complete(count uint32) bool {
+	for seq := uint32(1); seq <= count; seq++ {
+		if !s.Has(seq) {
+			return false
+		}
+	}
+	return true
+}
+
+// Sequences returns the written chunks in ascending order, as stored in ResumeFileState
+func (s *ChunkSet) Sequences() []uint32 {
+	seqs := make([]uint32, 0, s.count)
+	for i, word := range s.words {
+		for word != 0 {
+			bit := bits.TrailingZeros64(word)
+			seqs = append(seqs, uint32(i*64+bit))
+			word &= word - 1
+		}
+	}
+	return seqs
+}
@@ -0,0 +1,31 @@
+package transfer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChunkSet(t *testing.T) {
+	var set ChunkSet
+	assert.False(t, set.Has(1))
+	assert.Empty(t, set.Sequences())
+
+	// Chunks arrive out of order, as they do over parallel channels and retransmits
+	for _, seq := range []uint32{3, 130, 1, 64} {
+		assert.True(t, set.Add(seq))
+	}
+	assert.False(t, set.Add(3), "a retransmitted chunk is already in the set")
+	assert.Equal(t, 4, set.Len())
+	assert.True(t, set.Has(130))
+	assert.False(t, set.Has(2))
+	assert.Equal(t, []uint32{1, 3, 64, 130}, set.Sequences())
+
+	assert.False(t, set.Complete(3))
+	set.Add(2)
+	assert.True(t, set.Complete(3))
+
+	restored := NewChunkSet(set.Sequences())
+	assert.Equal(t, set.Sequences(), restored.Sequences())
+	assert.Equal(t, set.Len(), restored.Len())
+}
@@ -0,0 +1,68 @@
+package transfer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rescp17/lanFileSharer/pkg/fileInfo"
+)
+
+func TestManualClock_AdvanceRunsDueFunctionsInOrder(t *testing.T) {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	clock := NewManualClock(start)
+
+	var ran []string
+	clock.AfterFunc(3*time.Second, func() { ran = append(ran, "third") })
+	clock.AfterFunc(time.Second, func() { ran = append(ran, "first") })
+	clock.AfterFunc(2*time.Second, func() { ran = append(ran, "second") })
+	stopped := clock.AfterFunc(2*time.Second, func() { ran = append(ran, "stopped") })
+
+	assert.True(t, stopped.Stop())
+	assert.False(t, stopped.Stop(), "a stopped function cannot be stopped again")
+
+	clock.Advance(2 * time.Second)
+	assert.Equal(t, []string{"first", "second"}, ran)
+	assert.Equal(t, start.Add(2*time.Second), clock.Now())
+	assert.Equal(t, 1, clock.Pending())
+
+	clock.Advance(time.Second)
+	assert.Equal(t, []string{"first", "second", "third"}, ran)
+	assert.Zero(t, clock.Pending())
+}
+
+func TestUnifiedTransferManager_RetriesOnManualClock(t *testing.T) {
+	clock := NewManualClock(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	manager := NewUnifiedTransferManager("test-clock")
+	defer manager.Shutdown()
+	manager.SetClock(clock)
+	manager.SetTransport(NewQueueTransport())
+
+	path := filepath.Join(t.TempDir(), "retry.txt")
+	require.NoError(t, os.WriteFile(path, []byte("retry me"), 0644))
+	node, err := fileInfo.CreateNode(path)
+	require.NoError(t, err)
+	require.NoError(t, manager.AddFile(&node))
+
+	require.NoError(t, manager.StartTransfer(path))
+	require.NoError(t, manager.FailTransfer(path, fmt.Errorf("%w: no ACK", ErrTimeout)))
+
+	task, scheduled := manager.GetRetryStatus(path)
+	require.True(t, scheduled, "a timeout should be retried")
+	assert.True(t, task.NextAttempt.After(clock.Now()))
+	assert.Nil(t, manager.GetSessionStatus().CurrentFile, "the file should wait for its retry")
+
+	clock.Advance(task.NextAttempt.Sub(clock.Now()))
+
+	_, scheduled = manager.GetRetryStatus(path)
+	assert.False(t, scheduled, "the retry should have run")
+	current := manager.GetSessionStatus().CurrentFile
+	require.NotNil(t, current, "the retry should restart the file")
+	assert.Equal(t, TransferStateActive, current.State)
+	assert.Equal(t, clock.Now(), current.StartTime)
+}
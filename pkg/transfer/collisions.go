@@ -0,0 +1,87 @@
+package transfer
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// maxUniqueNameAttempts bounds the numbered names UniqueName tries before giving up
+const maxUniqueNameAttempts = 10000
+
+// NameKey returns the key under which two output names refer to the same file:
+// the name itself, or its lower-case form on case-insensitive filesystems
+func NameKey(name string, caseInsensitive bool) string {
+	if caseInsensitive {
+		return strings.ToLower(name)
+	}
+	return name
+}
+
+// NameCollision is a group of distinct offered entries that would be written to the same file
+type NameCollision struct {
+	// Names are the colliding names in the order they were offered; exact duplicates repeat
+	Names []string
+}
+
+// FindNameCollisions groups names that would be written to the same file. With
+// caseInsensitive set, names differing only in case collide as well.
+func FindNameCollisions(names []string, caseInsensitive bool) []NameCollision {
+	groups := make(map[string][]string)
+	var order []string
+	for _, name := range names {
+		key := NameKey(name, caseInsensitive)
+		if _, seen := groups[key]; !seen {
+			order = append(order, key)
+		}
+		groups[key] = append(groups[key], name)
+	}
+
+	var collisions []NameCollision
+	for _, key := range order {
+		if len(groups[key]) > 1 {
+			collisions = append(collisions, NameCollision{Names: groups[key]})
+		}
+	}
+	return collisions
+}
+
+// FindCaseCollisions groups names that differ only in case, ignoring exact duplicates.
+// These are the names that only collide on case-insensitive filesystems.
+func FindCaseCollisions(names []string) []NameCollision {
+	var collisions []NameCollision
+	for _, collision := range FindNameCollisions(names, true) {
+		distinct := make(map[string]struct{}, len(collision.Names))
+		var unique []string
+		for _, name := range collision.Names {
+			if _, ok := distinct[name]; !ok {
+				distinct[name] = struct{}{}
+				unique = append(unique, name)
+			}
+		}
+		if len(unique) > 1 {
+			sort.Strings(unique)
+			collisions = append(collisions, NameCollision{Names: unique})
+		}
+	}
+	return collisions
+}
+
+// UniqueName returns name if taken reports it as free, otherwise the first free
+// numbered variant like "report (1).pdf", "report (2).pdf" and so on
+func UniqueName(name string, taken func(string) bool) (string, error) {
+	if !taken(name) {
+		return name, nil
+	}
+
+	ext := filepath.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	for i := 1; i <= maxUniqueNameAttempts; i++ {
+		candidate := fmt.Sprintf("%s (%d)%s", base, i, ext)
+		if !taken(candidate) {
+			return candidate, nil
+		}
+	}
+	return "", fmt.Errorf("no free name for %s after %d attempts", name, maxUniqueNameAttempts)
+}
@@ -0,0 +1,51 @@
+package transfer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindNameCollisions(t *testing.T) {
+	names := []string{"File.txt", "other.txt", "file.txt", "a.txt", "a.txt"}
+
+	sensitive := FindNameCollisions(names, false)
+	require.Len(t, sensitive, 1, "only exact duplicates collide on case-sensitive filesystems")
+	assert.Equal(t, []string{"a.txt", "a.txt"}, sensitive[0].Names)
+
+	insensitive := FindNameCollisions(names, true)
+	require.Len(t, insensitive, 2)
+	assert.Equal(t, []string{"File.txt", "file.txt"}, insensitive[0].Names)
+	assert.Equal(t, []string{"a.txt", "a.txt"}, insensitive[1].Names)
+}
+
+func TestFindCaseCollisions(t *testing.T) {
+	collisions := FindCaseCollisions([]string{"readme.md", "README.md", "a.txt", "a.txt", "Readme.md"})
+	require.Len(t, collisions, 1, "exact duplicates are not case collisions")
+	assert.Equal(t, []string{"README.md", "Readme.md", "readme.md"}, collisions[0].Names)
+
+	assert.Empty(t, FindCaseCollisions([]string{"a.txt", "b.txt"}))
+}
+
+func TestUniqueName(t *testing.T) {
+	taken := map[string]bool{"report.pdf": true, "report (1).pdf": true}
+	isTaken := func(name string) bool { return taken[strings.ToLower(name)] }
+
+	name, err := UniqueName("notes.txt", isTaken)
+	require.NoError(t, err)
+	assert.Equal(t, "notes.txt", name, "a free name is kept")
+
+	name, err = UniqueName("Report.pdf", isTaken)
+	require.NoError(t, err)
+	assert.Equal(t, "Report (2).pdf", name)
+
+	_, err = UniqueName("x", func(string) bool { return true })
+	assert.Error(t, err)
+}
+
+func TestNameKey(t *testing.T) {
+	assert.Equal(t, "File.TXT", NameKey("File.TXT", false))
+	assert.Equal(t, "file.txt", NameKey("File.TXT", true))
+}
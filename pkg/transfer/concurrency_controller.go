@@ -0,0 +1,244 @@
+package transfer
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultConcurrencyWindow is how long the controller measures throughput before adjusting
+	DefaultConcurrencyWindow = 250 * time.Millisecond
+
+	// concurrencyMinSamples is the number of operations a window needs to be judged
+	concurrencyMinSamples = 8
+	// concurrencyErrorThreshold is the error rate above which concurrency is halved
+	concurrencyErrorThreshold = 0.1
+	// concurrencyTolerance is the relative throughput change treated as noise
+	concurrencyTolerance = 0.05
+	// maxConcurrencyDecisions bounds the decision history kept for GetStats
+	maxConcurrencyDecisions = 32
+)
+
+// ConcurrencyDecision records one evaluation of the concurrency controller
+type ConcurrencyDecision struct {
+	Time       time.Time `json:"time"`
+	From       int64     `json:"from"`
+	To         int64     `json:"to"`
+	Throughput float64   `json:"throughput"` // bytes per second, or operations per second without byte counts
+	ErrorRate  float64   `json:"error_rate"`
+	Reason     string    `json:"reason"`
+}
+
+// ConcurrencyController adjusts a concurrency limit from the throughput and error rate
+// of the operations it observes. It climbs in the direction that improved throughput,
+// turns around when throughput drops and halves the limit when errors pile up.
+type ConcurrencyController struct {
+	mu       sync.Mutex
+	limit    int64
+	minLimit int64
+	maxLimit int64
+	window   time.Duration
+
+	windowStart    time.Time
+	ops            int64
+	errs           int64
+	bytes          int64
+	lastThroughput float64
+	lastErrorRate  float64
+	direction      int64 // +1 while growing, -1 while shrinking
+
+	decisions []ConcurrencyDecision
+}
+
+// NewConcurrencyController creates a controller starting at initial and kept within [minLimit, maxLimit]
+func NewConcurrencyController(initial, minLimit, maxLimit int64) *ConcurrencyController {
+	if minLimit < 1 {
+		minLimit = 1
+	}
+	if maxLimit < minLimit {
+		maxLimit = minLimit
+	}
+	cc := &ConcurrencyController{
+		minLimit:  minLimit,
+		maxLimit:  maxLimit,
+		window:    DefaultConcurrencyWindow,
+		direction: 1,
+	}
+	cc.limit = cc.clamp(initial)
+	return cc
+}
+
+// Limit returns the current concurrency limit
+func (cc *ConcurrencyController) Limit() int64 {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	return cc.limit
+}
+
+// SetMax changes the upper bound, lowering the current limit if needed
+func (cc *ConcurrencyController) SetMax(maxLimit int64) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+
+	cc.maxLimit = maxLimit
+	if cc.maxLimit < cc.minLimit {
+		cc.maxLimit = cc.minLimit
+	}
+	cc.limit = cc.clamp(cc.limit)
+}
+
+// Record observes a finished operation that moved bytes (zero if it moved none) and failed with err
+func (cc *ConcurrencyController) Record(bytes int64, err error) {
+	cc.record(time.Now(), bytes, err)
+}
+
+func (cc *ConcurrencyController) record(now time.Time, bytes int64, err error) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+
+	if cc.windowStart.IsZero() {
+		cc.windowStart = now
+	}
+	cc.ops++
+	cc.bytes += bytes
+	if err != nil {
+		cc.errs++
+	}
+
+	elapsed := now.Sub(cc.windowStart)
+	if elapsed < cc.window || cc.ops < concurrencyMinSamples {
+		return
+	}
+	cc.adjustLocked(now, elapsed)
+}
+
+// adjustLocked evaluates the window that just ended and starts a new one; callers hold mu
+func (cc *ConcurrencyController) adjustLocked(now time.Time, elapsed time.Duration) {
+	throughput := float64(cc.ops) / elapsed.Seconds()
+	if cc.bytes > 0 {
+		throughput = float64(cc.bytes) / elapsed.Seconds()
+	}
+	errorRate := float64(cc.errs) / float64(cc.ops)
+
+	from := cc.limit
+	var reason string
+	switch {
+	case errorRate > concurrencyErrorThreshold:
+		cc.limit = cc.clamp(cc.limit / 2)
+		cc.direction = 1
+		reason = fmt.Sprintf("error rate %.0f%%, backing off", errorRate*100)
+	case cc.lastThroughput == 0:
+		cc.limit = cc.clamp(cc.limit + cc.direction)
+		reason = "probing"
+	case throughput > cc.lastThroughput*(1+concurrencyTolerance):
+		cc.limit = cc.clamp(cc.limit + cc.direction)
+		reason = "throughput improved"
+	case throughput < cc.lastThroughput*(1-concurrencyTolerance):
+		cc.direction = -cc.direction
+		cc.limit = cc.clamp(cc.limit + cc.direction)
+		reason = "throughput dropped, reversing"
+	default:
+		reason = "throughput steady"
+	}
+	// At a bound, try the other way next time
+	if cc.limit == cc.maxLimit {
+		cc.direction = -1
+	} else if cc.limit == cc.minLimit {
+		cc.direction = 1
+	}
+
+	cc.decisions = append(cc.decisions, ConcurrencyDecision{
+		Time:       now,
+		From:       from,
+		To:         cc.limit,
+		Throughput: throughput,
+		ErrorRate:  errorRate,
+		Reason:     reason,
+	})
+	if len(cc.decisions) > maxConcurrencyDecisions {
+		cc.decisions = cc.decisions[len(cc.decisions)-maxConcurrencyDecisions:]
+	}
+
+	cc.lastThroughput = throughput
+	cc.lastErrorRate = errorRate
+	cc.windowStart = now
+	cc.ops, cc.errs, cc.bytes = 0, 0, 0
+}
+
+// Decisions returns the most recent adjustments, oldest first
+func (cc *ConcurrencyController) Decisions() []ConcurrencyDecision {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+
+	result := make([]ConcurrencyDecision, len(cc.decisions))
+	copy(result, cc.decisions)
+	return result
+}
+
+// Stats returns the controller state for debugging
+func (cc *ConcurrencyController) Stats() map[string]interface{} {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+
+	return map[string]interface{}{
+		"limit":           cc.limit,
+		"min":             cc.minLimit,
+		"max":             cc.maxLimit,
+		"last_throughput": cc.lastThroughput,
+		"last_error_rate": cc.lastErrorRate,
+		"decisions":       len(cc.decisions),
+	}
+}
+
+func (cc *ConcurrencyController) clamp(limit int64) int64 {
+	if limit < cc.minLimit {
+		return cc.minLimit
+	}
+	if limit > cc.maxLimit {
+		return cc.maxLimit
+	}
+	return limit
+}
+
+// adaptiveGate admits up to limit() holders at once, picking up limit changes as holders leave
+type adaptiveGate struct {
+	mu       sync.Mutex
+	inFlight int64
+	limit    func() int64
+	wake     chan struct{}
+}
+
+func newAdaptiveGate(limit func() int64) *adaptiveGate {
+	return &adaptiveGate{limit: limit, wake: make(chan struct{})}
+}
+
+// acquire waits for a free slot or for ctx to be done
+func (g *adaptiveGate) acquire(ctx context.Context) error {
+	for {
+		g.mu.Lock()
+		if g.inFlight < g.limit() {
+			g.inFlight++
+			g.mu.Unlock()
+			return nil
+		}
+		wake := g.wake
+		g.mu.Unlock()
+
+		select {
+		case <-wake:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// release frees a slot and wakes the waiters to recheck the limit
+func (g *adaptiveGate) release() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.inFlight--
+	close(g.wake)
+	g.wake = make(chan struct{})
+}
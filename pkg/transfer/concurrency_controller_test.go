@@ -0,0 +1,131 @@
+package transfer
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// runWindow feeds the controller one window of ops operations moving bytes each, errs of which fail
+func runWindow(cc *ConcurrencyController, start time.Time, ops int, bytes int64, errs int) time.Time {
+	if cc.windowStart.IsZero() {
+		cc.windowStart = start
+	}
+	step := cc.window / time.Duration(ops)
+	now := start
+	for i := 0; i < ops; i++ {
+		now = now.Add(step)
+		var err error
+		if i < errs {
+			err = errors.New("read failed")
+		}
+		cc.record(now, bytes, err)
+	}
+	return now
+}
+
+func TestConcurrencyController_ClimbsWhileThroughputImproves(t *testing.T) {
+	cc := NewConcurrencyController(4, 1, 16)
+	now := time.Now()
+
+	now = runWindow(cc, now, 10, 1000, 0)
+	assert.Equal(t, int64(5), cc.Limit(), "first window should probe upwards")
+
+	now = runWindow(cc, now, 20, 1000, 0)
+	assert.Equal(t, int64(6), cc.Limit(), "better throughput should keep climbing")
+
+	runWindow(cc, now, 10, 1000, 0)
+	assert.Equal(t, int64(5), cc.Limit(), "worse throughput should turn around")
+
+	decisions := cc.Decisions()
+	require.Len(t, decisions, 3)
+	assert.Equal(t, "probing", decisions[0].Reason)
+	assert.Equal(t, "throughput improved", decisions[1].Reason)
+	assert.Equal(t, "throughput dropped, reversing", decisions[2].Reason)
+	assert.Equal(t, int64(6), decisions[2].From)
+	assert.Equal(t, int64(5), decisions[2].To)
+}
+
+func TestConcurrencyController_HoldsOnSteadyThroughput(t *testing.T) {
+	cc := NewConcurrencyController(4, 1, 16)
+	now := runWindow(cc, time.Now(), 10, 1000, 0)
+	runWindow(cc, now, 10, 1000, 0)
+
+	assert.Equal(t, int64(5), cc.Limit())
+	decisions := cc.Decisions()
+	require.Len(t, decisions, 2)
+	assert.Equal(t, "throughput steady", decisions[1].Reason)
+}
+
+func TestConcurrencyController_BacksOffOnErrors(t *testing.T) {
+	cc := NewConcurrencyController(8, 1, 16)
+	runWindow(cc, time.Now(), 10, 1000, 5)
+
+	assert.Equal(t, int64(4), cc.Limit())
+	decisions := cc.Decisions()
+	require.Len(t, decisions, 1)
+	assert.InDelta(t, 0.5, decisions[0].ErrorRate, 0.001)
+}
+
+func TestConcurrencyController_StaysWithinBounds(t *testing.T) {
+	cc := NewConcurrencyController(100, 2, 8)
+	assert.Equal(t, int64(8), cc.Limit(), "initial limit should be clamped")
+
+	now := time.Now()
+	for i := 0; i < 5; i++ {
+		now = runWindow(cc, now, 10, 1000, 10)
+	}
+	assert.Equal(t, int64(2), cc.Limit(), "errors should not push the limit below the minimum")
+
+	cc.SetMax(1)
+	assert.Equal(t, int64(2), cc.Limit(), "the maximum cannot drop below the minimum")
+}
+
+func TestConcurrencyController_WaitsForEnoughSamples(t *testing.T) {
+	cc := NewConcurrencyController(4, 1, 16)
+	now := time.Now()
+	for i := 0; i < concurrencyMinSamples-1; i++ {
+		now = now.Add(cc.window)
+		cc.record(now, 1000, nil)
+	}
+	assert.Equal(t, int64(4), cc.Limit())
+	assert.Empty(t, cc.Decisions())
+}
+
+func TestAdaptiveGate_FollowsLimit(t *testing.T) {
+	var limit atomic.Int64
+	limit.Store(2)
+	gate := newAdaptiveGate(limit.Load)
+
+	require.NoError(t, gate.acquire(context.Background()))
+	require.NoError(t, gate.acquire(context.Background()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, gate.acquire(ctx), context.DeadlineExceeded, "a full gate should block")
+
+	// After the limit drops, one release is not enough to admit a waiter
+	limit.Store(1)
+	acquired := make(chan error, 1)
+	go func() { acquired <- gate.acquire(context.Background()) }()
+
+	gate.release()
+	select {
+	case <-acquired:
+		t.Fatal("waiter admitted above the lowered limit")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	gate.release()
+	select {
+	case err := <-acquired:
+		require.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("waiter not admitted after the gate drained")
+	}
+}
@@ -17,6 +17,10 @@ type TransferConfig struct {
 	MaxConcurrentTransfers int `json:"max_concurrent_transfers"`
 	MaxConcurrentChunks    int `json:"max_concurrent_chunks"`
 
+	// Large files are read by ParallelStreams readers, each sending on its own data channel
+	ParallelStreams   int   `json:"parallel_streams"`   // 0 or 1 sends every file on a single stream
+	ParallelThreshold int64 `json:"parallel_threshold"` // Minimum file size to read in parallel
+
 	// Performance settings
 	BufferSize            int           `json:"buffer_size"`
 	RateCalculationWindow time.Duration `json:"rate_calculation_window"`
@@ -51,6 +55,8 @@ func DefaultTransferConfig() *TransferConfig {
 		// Concurrency settings
 		MaxConcurrentTransfers: 10,
 		MaxConcurrentChunks:    50,
+		ParallelStreams:        4,
+		ParallelThreshold:      64 * 1024 * 1024, // 64MB
 
 		// Performance settings
 		BufferSize:            8192, // 8KB
@@ -98,6 +104,9 @@ func (tc *TransferConfig) Validate() error {
 	if tc.MaxConcurrentChunks <= 0 {
 		return errors.New("max_concurrent_chunks must be positive")
 	}
+	if tc.ParallelStreams < 0 {
+		return errors.New("parallel_streams cannot be negative")
+	}
 
 	// Validate performance settings
 	if tc.BufferSize <= 0 {
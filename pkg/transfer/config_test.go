@@ -52,6 +52,22 @@ func TestTransferConfig_Validate(t *testing.T) {
 			expectError: true,
 			errorMsg:    "chunk_size must be positive",
 		},
+		{
+			name: "invalid parallel streams - negative",
+			config: &TransferConfig{
+				ChunkSize:              DefaultChunkSize,
+				MinChunkSize:           MinChunkSize,
+				MaxChunkSize:           MaxChunkSize,
+				MaxConcurrentTransfers: 10,
+				MaxConcurrentChunks:    10,
+				ParallelStreams:        -1,
+				BufferSize:             1024,
+				DefaultRetryPolicy:     DefaultRetryPolicy(),
+				EventBufferSize:        10,
+			},
+			expectError: true,
+			errorMsg:    "parallel_streams cannot be negative",
+		},
 		{
 			name: "invalid chunk size - too small",
 			config: &TransferConfig{
@@ -88,6 +88,14 @@ func (h *DefaultErrorHandler) HandleError(filePath string, err error, retryCount
 		return ErrorActionRetry // Should not happen, but safe default
 	}
 
+	// Errors of the taxonomy are retried if the policy lists their class
+	if isClassified(err) {
+		if retryCount < h.retryPolicy.MaxRetries && h.retryPolicy.IsRetryable(err) {
+			return ErrorActionRetry
+		}
+		return ErrorActionFail
+	}
+
 	category := h.CategorizeError(err)
 
 	switch category {
@@ -118,6 +126,34 @@ func (h *DefaultErrorHandler) CategorizeError(err error) ErrorCategory {
 		return ErrorCategoryRecoverable
 	}
 
+	// Check for specific error types
+	switch {
+	case IsTransient(err):
+		return ErrorCategoryRecoverable
+	case errors.Is(err, ErrDiskFull),
+		errors.Is(err, ErrPermissionDenied),
+		errors.Is(err, ErrFileNotFound),
+		errors.Is(err, ErrChecksumMismatch),
+		errors.Is(err, ErrSourceModified),
+		errors.Is(err, ErrFileInfected):
+		return ErrorCategoryNonRecoverable
+	case errors.Is(err, ErrTransferNotFound):
+		return ErrorCategoryNonRecoverable
+	case errors.Is(err, ErrInvalidStateTransition):
+		return ErrorCategoryNonRecoverable
+	case errors.Is(err, ErrTransferAlreadyExists):
+		return ErrorCategoryNonRecoverable
+	case errors.Is(err, ErrSessionNotFound):
+		return ErrorCategoryNonRecoverable
+	case errors.Is(err, ErrMaxTransfersExceeded):
+		return ErrorCategoryRecoverable // Might be able to retry later
+	case errors.Is(err, ErrInvalidConfiguration):
+		return ErrorCategorySystem
+	case errors.Is(err, ErrTransferCancelled):
+		return ErrorCategoryNonRecoverable
+	}
+
+	// Errors from outside the transfer stack carry no type, so fall back to their message
 	errMsg := strings.ToLower(err.Error())
 
 	// Check for non-recoverable errors first
@@ -181,24 +217,6 @@ func (h *DefaultErrorHandler) CategorizeError(err error) ErrorCategory {
 		}
 	}
 
-	// Check for specific error types
-	switch {
-	case errors.Is(err, ErrTransferNotFound):
-		return ErrorCategoryNonRecoverable
-	case errors.Is(err, ErrInvalidStateTransition):
-		return ErrorCategoryNonRecoverable
-	case errors.Is(err, ErrTransferAlreadyExists):
-		return ErrorCategoryNonRecoverable
-	case errors.Is(err, ErrSessionNotFound):
-		return ErrorCategoryNonRecoverable
-	case errors.Is(err, ErrMaxTransfersExceeded):
-		return ErrorCategoryRecoverable // Might be able to retry later
-	case errors.Is(err, ErrInvalidConfiguration):
-		return ErrorCategorySystem
-	case errors.Is(err, ErrTransferCancelled):
-		return ErrorCategoryNonRecoverable
-	}
-
 	// Default to recoverable for unknown errors (conservative approach)
 	return ErrorCategoryRecoverable
 }
@@ -0,0 +1,127 @@
+package transfer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"syscall"
+)
+
+// Error taxonomy shared by the transfer stack. Errors are wrapped so callers
+// can dispatch with errors.Is while keeping the original cause in the chain.
+var (
+	// ErrPeerUnreachable is returned when the other device cannot be contacted at all
+	ErrPeerUnreachable = errors.New("peer unreachable")
+
+	// ErrConnectionLost is returned when an established connection to the peer breaks
+	ErrConnectionLost = errors.New("connection lost")
+
+	// ErrTimeout is returned when the peer or an operation did not respond in time
+	ErrTimeout = errors.New("operation timed out")
+
+	// ErrDiskFull is returned when there is no space left to store a file
+	ErrDiskFull = errors.New("disk full")
+
+	// ErrPermissionDenied is returned when a file cannot be read or written for lack of permission
+	ErrPermissionDenied = errors.New("permission denied")
+
+	// ErrFileNotFound is returned when a file to send no longer exists
+	ErrFileNotFound = errors.New("file not found")
+
+	// ErrChecksumMismatch is returned when received data does not match its expected hash
+	ErrChecksumMismatch = errors.New("checksum mismatch")
+
+	// ErrSourceModified is returned when a file changes while it is sent, so what the
+	// receiver got would mix old and new content
+	ErrSourceModified = errors.New("file modified during transfer")
+
+	// ErrFileInfected is returned when the receiver's virus scanner flagged a received file,
+	// which was quarantined instead of saved
+	ErrFileInfected = errors.New("file infected")
+)
+
+// ClassifyIOError wraps a file system or context error with the matching error of the
+// taxonomy. Errors that are already classified, or that match none, are returned unchanged.
+func ClassifyIOError(err error) error {
+	if err == nil || isClassified(err) {
+		return err
+	}
+
+	var kind error
+	switch {
+	case errors.Is(err, syscall.ENOSPC):
+		kind = ErrDiskFull
+	case errors.Is(err, fs.ErrPermission):
+		kind = ErrPermissionDenied
+	case errors.Is(err, fs.ErrNotExist):
+		kind = ErrFileNotFound
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, os.ErrDeadlineExceeded):
+		kind = ErrTimeout
+	default:
+		return err
+	}
+	return fmt.Errorf("%w: %w", kind, err)
+}
+
+// errorCodes names the errors of the taxonomy on the wire, so a peer can report them
+var errorCodes = []struct {
+	code string
+	err  error
+}{
+	{"peer_unreachable", ErrPeerUnreachable},
+	{"connection_lost", ErrConnectionLost},
+	{"timeout", ErrTimeout},
+	{"disk_full", ErrDiskFull},
+	{"permission_denied", ErrPermissionDenied},
+	{"file_not_found", ErrFileNotFound},
+	{"checksum_mismatch", ErrChecksumMismatch},
+	{"source_modified", ErrSourceModified},
+	{"infected", ErrFileInfected},
+}
+
+// ErrorCode returns the wire code of the taxonomy error carried by err, or "" if there is none
+func ErrorCode(err error) string {
+	if err == nil {
+		return ""
+	}
+	for _, entry := range errorCodes {
+		if errors.Is(err, entry.err) {
+			return entry.code
+		}
+	}
+	return ""
+}
+
+// ErrorFromCode rebuilds an error reported by a peer, wrapping the taxonomy error for code
+func ErrorFromCode(code, message string) error {
+	for _, entry := range errorCodes {
+		if entry.code == code {
+			return fmt.Errorf("%w: %s", entry.err, message)
+		}
+	}
+	return errors.New(message)
+}
+
+// IsErrorCode reports whether code names an error of the taxonomy
+func IsErrorCode(code string) bool {
+	for _, entry := range errorCodes {
+		if entry.code == code {
+			return true
+		}
+	}
+	return false
+}
+
+// isClassified reports whether err already carries an error of the taxonomy
+func isClassified(err error) bool {
+	return ErrorCode(err) != ""
+}
+
+// IsTransient reports whether err is a failure that may succeed when tried again
+func IsTransient(err error) bool {
+	return errors.Is(err, ErrPeerUnreachable) ||
+		errors.Is(err, ErrConnectionLost) ||
+		errors.Is(err, ErrTimeout)
+}
@@ -0,0 +1,80 @@
+package transfer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClassifyIOError(t *testing.T) {
+	_, openErr := os.Open(filepath.Join(t.TempDir(), "missing.txt"))
+	require.Error(t, openErr)
+
+	tests := []struct {
+		name     string
+		err      error
+		expected error
+	}{
+		{"missing file", openErr, ErrFileNotFound},
+		{"no space", &fs.PathError{Op: "write", Path: "/data/out", Err: syscall.ENOSPC}, ErrDiskFull},
+		{"permission", &fs.PathError{Op: "open", Path: "/root/secret", Err: fs.ErrPermission}, ErrPermissionDenied},
+		{"deadline", fmt.Errorf("reading: %w", context.DeadlineExceeded), ErrTimeout},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			classified := ClassifyIOError(tt.err)
+			assert.ErrorIs(t, classified, tt.expected)
+			// The original cause stays in the chain
+			assert.ErrorIs(t, classified, tt.err)
+		})
+	}
+
+	plain := errors.New("something else")
+	assert.Equal(t, plain, ClassifyIOError(plain))
+	assert.NoError(t, ClassifyIOError(nil))
+
+	// Already classified errors are not wrapped twice
+	classified := ClassifyIOError(openErr)
+	assert.Equal(t, classified, ClassifyIOError(classified))
+}
+
+func TestErrorCode_RoundTrip(t *testing.T) {
+	err := fmt.Errorf("failed to write chunk: %w", ClassifyIOError(&fs.PathError{Op: "write", Path: "/out", Err: syscall.ENOSPC}))
+	code := ErrorCode(err)
+	assert.Equal(t, "disk_full", code)
+
+	remote := ErrorFromCode(code, err.Error())
+	assert.ErrorIs(t, remote, ErrDiskFull)
+	assert.Contains(t, remote.Error(), "failed to write chunk")
+
+	assert.Empty(t, ErrorCode(errors.New("untyped")))
+	assert.EqualError(t, ErrorFromCode("", "untyped"), "untyped")
+	assert.EqualError(t, ErrorFromCode("from_a_newer_peer", "untyped"), "untyped")
+}
+
+func TestTypedErrors_DriveRetryDecisions(t *testing.T) {
+	handler := NewDefaultErrorHandler(nil)
+	policy := DefaultRetryPolicy()
+
+	for _, err := range []error{ErrPeerUnreachable, ErrConnectionLost, ErrTimeout} {
+		// No message pattern matches these wrappers, only their type
+		wrapped := fmt.Errorf("sending chunk 7: %w", err)
+		assert.Equal(t, ErrorCategoryRecoverable, handler.CategorizeError(wrapped), "%v", err)
+		assert.True(t, policy.IsRetryable(wrapped), "%v", err)
+	}
+
+	for _, err := range []error{ErrDiskFull, ErrPermissionDenied, ErrFileNotFound, ErrChecksumMismatch} {
+		// A transient sounding message must not override the type
+		wrapped := fmt.Errorf("temporary failure: %w", err)
+		assert.Equal(t, ErrorCategoryNonRecoverable, handler.CategorizeError(wrapped), "%v", err)
+		assert.False(t, policy.IsRetryable(wrapped), "%v", err)
+	}
+}
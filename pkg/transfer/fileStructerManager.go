@@ -1,17 +1,72 @@
 package transfer
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"log/slog"
+	"path/filepath"
+	"strings"
 	"sync"
 
+	"github.com/rescp17/lanFileSharer/pkg/events"
 	"github.com/rescp17/lanFileSharer/pkg/fileInfo"
 )
 
+// ErrNodeNotFound is returned when no file or directory is registered at a path
+var ErrNodeNotFound = errors.New("node not found in file structure")
+
 type FileStructureManager struct {
 	RootNodes []*fileInfo.FileNode
 	fileMap   map[string]*fileInfo.FileNode
 	dirMap    map[string]*fileInfo.FileNode
 	mu        sync.RWMutex
+
+	// Change notifications
+	changeMu    sync.Mutex // Serializes mutations with their notifications so listeners see them in order
+	listeners   []StructureListener
+	listenersMu sync.RWMutex
+
+	eventBus *events.Bus // Optional bus AddPath publishes its preparation progress to
+}
+
+// StructureChangeType describes how the structure changed
+type StructureChangeType int
+
+const (
+	StructureNodeAdded StructureChangeType = iota
+	StructureNodeRemoved
+	StructureNodeUpdated
+)
+
+func (t StructureChangeType) String() string {
+	switch t {
+	case StructureNodeAdded:
+		return "added"
+	case StructureNodeRemoved:
+		return "removed"
+	case StructureNodeUpdated:
+		return "updated"
+	default:
+		return "unknown"
+	}
+}
+
+// StructureChange describes a single mutation of the structure
+type StructureChange struct {
+	Type StructureChangeType
+	Path string
+	// RelativePath is the slash separated path from the root node's name, as seen by the receiver
+	RelativePath string
+	// Node is the new node for added and updated entries, and the removed node otherwise
+	Node fileInfo.FileNode
+}
+
+// StructureListener is notified synchronously of structure changes, in the order they are applied.
+// Listeners may read from the manager but must not mutate it.
+type StructureListener interface {
+	ID() string
+	OnStructureChanged(change StructureChange)
 }
 
 func NewFileStructureManager() *FileStructureManager {
@@ -52,41 +107,254 @@ func NewFileStructureManagerFromPath(path string) (*FileStructureManager, error)
 	return fsm, nil
 }
 
-func (fsm *FileStructureManager) AddPath(path string) error {
+// SetEventBus makes AddPath publish PreparationProgress events to bus while it scans and hashes
+func (fsm *FileStructureManager) SetEventBus(bus *events.Bus) {
 	fsm.mu.Lock()
 	defer fsm.mu.Unlock()
+	fsm.eventBus = bus
+}
 
-	node, err := fileInfo.CreateNode(path)
+func (fsm *FileStructureManager) AddPath(path string) error {
+	return fsm.AddPathContext(context.Background(), path)
+}
+
+// AddPathContext scans, hashes and adds path like AddPath, stopping as soon as ctx is done
+func (fsm *FileStructureManager) AddPathContext(ctx context.Context, path string) error {
+	fsm.mu.RLock()
+	bus := fsm.eventBus
+	fsm.mu.RUnlock()
+
+	var progress PrepareFunc
+	if bus != nil {
+		progress = func(p events.PreparationProgress) { bus.Publish(p) }
+	}
+	nodes, err := PrepareNodes(ctx, []string{path}, 1, progress)
 	if err != nil {
 		return fmt.Errorf("failed to create node from path %s: %w", path, err)
 	}
 
+	return fsm.AddFileNode(&nodes[0])
+}
+
+func (fsm *FileStructureManager) AddFileNode(node *fileInfo.FileNode) error {
+	fsm.changeMu.Lock()
+	defer fsm.changeMu.Unlock()
+
+	fsm.mu.Lock()
 	// Add to internal maps
-	err = fsm.addFileNodeUnsafe(&node)
+	err := fsm.addFileNodeUnsafe(node)
 	if err != nil {
+		fsm.mu.Unlock()
 		return err
 	}
 
 	// Add to RootNodes to maintain consistency
-	fsm.RootNodes = append(fsm.RootNodes, &node)
+	fsm.RootNodes = append(fsm.RootNodes, node)
+	fsm.mu.Unlock()
+
+	fsm.notifyStructureChanged(StructureChange{Type: StructureNodeAdded, Path: node.Path, RelativePath: node.Name, Node: *node})
 	return nil
 }
 
-func (fsm *FileStructureManager) AddFileNode(node *fileInfo.FileNode) error {
+// RemoveFileNode removes node, and everything below it if it is a directory
+func (fsm *FileStructureManager) RemoveFileNode(node *fileInfo.FileNode) error {
+	if node == nil {
+		return fmt.Errorf("node cannot be nil")
+	}
+	return fsm.RemovePath(node.Path)
+}
+
+// RemovePath removes the file or directory at path, and everything below it.
+// Sizes and checksums of the parent directories are updated without re-reading files.
+func (fsm *FileStructureManager) RemovePath(path string) error {
+	fsm.changeMu.Lock()
+	defer fsm.changeMu.Unlock()
+
 	fsm.mu.Lock()
-	defer fsm.mu.Unlock()
+	target, ok := fsm.lookupUnsafe(path)
+	if !ok {
+		fsm.mu.Unlock()
+		return fmt.Errorf("%w: %s", ErrNodeNotFound, path)
+	}
+	removed := *target
+	relativePath := fsm.relativePathUnsafe(path)
+	fsm.forgetSubtreeUnsafe(target)
+
+	if i := fsm.rootIndexUnsafe(target); i >= 0 {
+		fsm.RootNodes = append(fsm.RootNodes[:i], fsm.RootNodes[i+1:]...)
+	} else if parent := fsm.parentOfUnsafe(path); parent != nil {
+		// Removing from Children moves siblings in memory, so the parent is re-indexed
+		fsm.forgetSubtreeUnsafe(parent)
+		for i := range parent.Children {
+			if parent.Children[i].Path == path {
+				parent.Children = append(parent.Children[:i], parent.Children[i+1:]...)
+				break
+			}
+		}
+		fsm.addFileNodeUnsafe(parent)
+		fsm.refreshAncestorsUnsafe(parent)
+	}
+	fsm.mu.Unlock()
 
-	// Add to internal maps
-	err := fsm.addFileNodeUnsafe(node)
-	if err != nil {
-		return err
+	fsm.notifyStructureChanged(StructureChange{Type: StructureNodeRemoved, Path: path, RelativePath: relativePath, Node: removed})
+	return nil
+}
+
+// UpdateFileNode replaces the node registered at node.Path with node, e.g. after the file changed on disk.
+// Sizes and checksums of an updated directory and its parents are recomputed without re-reading files.
+func (fsm *FileStructureManager) UpdateFileNode(node *fileInfo.FileNode) error {
+	if node == nil {
+		return fmt.Errorf("node cannot be nil")
 	}
 
-	// Add to RootNodes to maintain consistency
-	fsm.RootNodes = append(fsm.RootNodes, node)
+	fsm.changeMu.Lock()
+	defer fsm.changeMu.Unlock()
+
+	fsm.mu.Lock()
+	target, ok := fsm.lookupUnsafe(node.Path)
+	if !ok {
+		fsm.mu.Unlock()
+		return fmt.Errorf("%w: %s", ErrNodeNotFound, node.Path)
+	}
+
+	// Copy in place so pointers held by RootNodes and parents stay valid
+	fsm.forgetSubtreeUnsafe(target)
+	*target = *node
+	target.SummarizeDir()
+	fsm.addFileNodeUnsafe(target)
+	if parent := fsm.parentOfUnsafe(node.Path); parent != nil {
+		fsm.refreshAncestorsUnsafe(parent)
+	}
+	updated := *target
+	relativePath := fsm.relativePathUnsafe(node.Path)
+	fsm.mu.Unlock()
+
+	fsm.notifyStructureChanged(StructureChange{Type: StructureNodeUpdated, Path: node.Path, RelativePath: relativePath, Node: updated})
+	return nil
+}
+
+// lookupUnsafe finds the file or directory registered at path
+func (fsm *FileStructureManager) lookupUnsafe(path string) (*fileInfo.FileNode, bool) {
+	if node, ok := fsm.fileMap[path]; ok {
+		return node, true
+	}
+	node, ok := fsm.dirMap[path]
+	return node, ok
+}
+
+// RelativePath converts path to the slash separated path starting at its root node's name,
+// which is how receivers and structure changes name it
+func (fsm *FileStructureManager) RelativePath(path string) string {
+	fsm.mu.RLock()
+	defer fsm.mu.RUnlock()
+	return fsm.relativePathUnsafe(path)
+}
+
+// relativePathUnsafe converts path to a slash separated path starting at its root node's name
+func (fsm *FileStructureManager) relativePathUnsafe(path string) string {
+	for _, root := range fsm.RootNodes {
+		if root.Path == path {
+			return root.Name
+		}
+		if rel, err := filepath.Rel(root.Path, path); err == nil && rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return root.Name + "/" + filepath.ToSlash(rel)
+		}
+	}
+	return filepath.Base(path)
+}
+
+// rootIndexUnsafe returns the position of node in RootNodes, or -1
+func (fsm *FileStructureManager) rootIndexUnsafe(node *fileInfo.FileNode) int {
+	for i, root := range fsm.RootNodes {
+		if root == node {
+			return i
+		}
+	}
+	return -1
+}
+
+// parentOfUnsafe returns the directory whose children include path, or nil for root nodes
+func (fsm *FileStructureManager) parentOfUnsafe(path string) *fileInfo.FileNode {
+	isChild := func(dir *fileInfo.FileNode) bool {
+		for i := range dir.Children {
+			if dir.Children[i].Path == path {
+				return true
+			}
+		}
+		return false
+	}
+
+	if dir, ok := fsm.dirMap[filepath.Dir(path)]; ok && isChild(dir) {
+		return dir
+	}
+	// Paths of nodes built by hand may not follow the file system layout
+	for _, dir := range fsm.dirMap {
+		if isChild(dir) {
+			return dir
+		}
+	}
 	return nil
 }
 
+// forgetSubtreeUnsafe removes node and its descendants from the lookup maps
+func (fsm *FileStructureManager) forgetSubtreeUnsafe(node *fileInfo.FileNode) {
+	if !node.IsDir {
+		delete(fsm.fileMap, node.Path)
+		return
+	}
+	delete(fsm.dirMap, node.Path)
+	for i := range node.Children {
+		fsm.forgetSubtreeUnsafe(&node.Children[i])
+	}
+}
+
+// refreshAncestorsUnsafe recomputes the size and checksum of dir and every directory above it
+func (fsm *FileStructureManager) refreshAncestorsUnsafe(dir *fileInfo.FileNode) {
+	for dir != nil {
+		dir.SummarizeDir()
+		dir = fsm.parentOfUnsafe(dir.Path)
+	}
+}
+
+// AddStructureListener registers a listener for structure changes
+func (fsm *FileStructureManager) AddStructureListener(listener StructureListener) {
+	fsm.listenersMu.Lock()
+	defer fsm.listenersMu.Unlock()
+
+	fsm.listeners = append(fsm.listeners, listener)
+}
+
+// RemoveStructureListener unregisters the listener with the given ID
+func (fsm *FileStructureManager) RemoveStructureListener(id string) {
+	fsm.listenersMu.Lock()
+	defer fsm.listenersMu.Unlock()
+
+	for i, listener := range fsm.listeners {
+		if listener.ID() == id {
+			fsm.listeners = append(fsm.listeners[:i], fsm.listeners[i+1:]...)
+			return
+		}
+	}
+}
+
+func (fsm *FileStructureManager) notifyStructureChanged(change StructureChange) {
+	fsm.listenersMu.RLock()
+	listenersCopy := make([]StructureListener, len(fsm.listeners))
+	copy(listenersCopy, fsm.listeners)
+	fsm.listenersMu.RUnlock()
+
+	for _, listener := range listenersCopy {
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					slog.Error("Panic in structure listener", "listener", listener.ID(), "panic", r)
+				}
+			}()
+			listener.OnStructureChanged(change)
+		}()
+	}
+}
+
 func (fsm *FileStructureManager) addFileNodeUnsafe(node *fileInfo.FileNode) error {
 	if node == nil {
 		return fmt.Errorf("node cannot be nil")
@@ -188,6 +456,9 @@ func (fsm *FileStructureManager) GetTotalSize() int64 {
 
 	var totalSize int64
 	for _, node := range fsm.fileMap {
+		if node.IsStream() {
+			continue
+		}
 		totalSize += node.Size
 	}
 	return totalSize
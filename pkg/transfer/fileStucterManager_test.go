@@ -856,4 +856,103 @@ func TestFileStructureManager_ConcurrentAccessWithSubtests(t *testing.T) {
 		t.Logf("Final state: %d files, %d dirs", 
 			fsm.GetFileCount(), fsm.GetDirCount())
 	})
-}
\ No newline at end of file
+}
+// recordingStructureListener collects structure changes for assertions
+type recordingStructureListener struct {
+	mu      sync.Mutex
+	changes []StructureChange
+}
+
+func (l *recordingStructureListener) ID() string { return "recorder" }
+
+func (l *recordingStructureListener) OnStructureChanged(change StructureChange) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.changes = append(l.changes, change)
+}
+
+// TestFileStructureManager_RemovePath tests removing files and directories in place
+func TestFileStructureManager_RemovePath(t *testing.T) {
+	tempDir := setupTestDir(t)
+	node, err := fileInfo.CreateNode(tempDir)
+	require.NoError(t, err)
+
+	fsm := NewFileStructureManager()
+	require.NoError(t, fsm.AddFileNode(&node))
+	listener := &recordingStructureListener{}
+	fsm.AddStructureListener(listener)
+
+	// Removing a nested file keeps sibling lookups valid and refreshes parent summaries
+	removedPath := filepath.Join(tempDir, "subdir", "sub1.txt")
+	require.NoError(t, fsm.RemovePath(removedPath))
+
+	_, exists := fsm.GetFile(removedPath)
+	assert.False(t, exists)
+	sibling, exists := fsm.GetFile(filepath.Join(tempDir, "subdir", "sub2.txt"))
+	require.True(t, exists)
+	assert.Equal(t, "sub2.txt", sibling.Name)
+	assert.Equal(t, 4, fsm.GetFileCount())
+
+	require.NoError(t, os.Remove(removedPath))
+	expected, err := fileInfo.CreateNode(tempDir)
+	require.NoError(t, err)
+	assert.Equal(t, expected.Checksum, fsm.RootNodes[0].Checksum)
+	assert.Equal(t, expected.Size, fsm.RootNodes[0].Size)
+
+	// Removing a directory drops its whole subtree
+	require.NoError(t, fsm.RemovePath(filepath.Join(tempDir, "subdir")))
+	assert.Equal(t, 3, fsm.GetFileCount())
+	assert.Equal(t, 1, fsm.GetDirCount())
+
+	// Removing a root node empties RootNodes
+	require.NoError(t, fsm.RemoveFileNode(fsm.RootNodes[0]))
+	assert.Empty(t, fsm.RootNodes)
+	assert.Equal(t, 0, fsm.GetFileCount())
+
+	assert.ErrorIs(t, fsm.RemovePath(removedPath), ErrNodeNotFound)
+
+	require.Len(t, listener.changes, 3)
+	assert.Equal(t, StructureNodeRemoved, listener.changes[0].Type)
+	assert.Equal(t, removedPath, listener.changes[0].Path)
+	assert.Equal(t, "sub1.txt", listener.changes[0].Node.Name)
+}
+
+// TestFileStructureManager_UpdateFileNode tests replacing a changed file
+func TestFileStructureManager_UpdateFileNode(t *testing.T) {
+	tempDir := setupTestDir(t)
+	node, err := fileInfo.CreateNode(tempDir)
+	require.NoError(t, err)
+
+	fsm := NewFileStructureManager()
+	listener := &recordingStructureListener{}
+	fsm.AddStructureListener(listener)
+	require.NoError(t, fsm.AddFileNode(&node))
+
+	filePath := filepath.Join(tempDir, "file1.txt")
+	require.NoError(t, os.WriteFile(filePath, []byte("Changed content"), 0644))
+	changed, err := fileInfo.CreateNode(filePath)
+	require.NoError(t, err)
+
+	require.NoError(t, fsm.UpdateFileNode(&changed))
+
+	got, exists := fsm.GetFile(filePath)
+	require.True(t, exists)
+	assert.Equal(t, changed.Checksum, got.Checksum)
+
+	expected, err := fileInfo.CreateNode(tempDir)
+	require.NoError(t, err)
+	assert.Equal(t, expected.Checksum, fsm.RootNodes[0].Checksum)
+	assert.Equal(t, expected.Size, fsm.GetTotalSize())
+
+	missing := fileInfo.FileNode{Name: "missing.txt", Path: filepath.Join(tempDir, "missing.txt")}
+	assert.ErrorIs(t, fsm.UpdateFileNode(&missing), ErrNodeNotFound)
+
+	require.Len(t, listener.changes, 2)
+	assert.Equal(t, StructureNodeAdded, listener.changes[0].Type)
+	assert.Equal(t, StructureNodeUpdated, listener.changes[1].Type)
+	assert.Equal(t, changed.Checksum, listener.changes[1].Node.Checksum)
+
+	fsm.RemoveStructureListener(listener.ID())
+	require.NoError(t, fsm.RemovePath(filePath))
+	assert.Len(t, listener.changes, 2)
+}
@@ -3,33 +3,49 @@ package transfer
 import (
 	"context"
 	"fmt"
+	"log/slog"
 	"runtime"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/rescp17/lanFileSharer/pkg/fileInfo"
-	"golang.org/x/sync/semaphore"
 )
 
 const (
 	// MaxSupportedFiles defines the maximum number of files that can be managed
 	// This prevents potential integer overflow and memory issues
 	MaxSupportedFiles = 1000000
+
+	// DefaultChunkerIdleTTL is how long a chunker may sit unused before its file handle is released
+	DefaultChunkerIdleTTL = 30 * time.Second
 )
 
 type FileTransferManager struct {
 	chunkers        map[string]*Chunker
 	mu              sync.RWMutex
-	maxConcurrency  int64  // Dynamic concurrency limit
+	maxConcurrency  int64  // Upper bound for the concurrency controller
+	controller      *ConcurrencyController
+
+	// Idle handle eviction
+	idleTTL     time.Duration // Zero disables eviction
+	janitorStop chan struct{}
+	evicted     atomic.Int64
 }
 
 func NewFileTransferManager() *FileTransferManager {
+	maxConcurrency := calculateOptimalConcurrency()
+	// Start low and let the controller climb to what the disk sustains
+	initial := int64(runtime.NumCPU())
 	return &FileTransferManager{
 		chunkers:       make(map[string]*Chunker),
-		maxConcurrency: calculateOptimalConcurrency(),
+		maxConcurrency: maxConcurrency,
+		controller:     NewConcurrencyController(initial, 1, maxConcurrency),
+		idleTTL:        DefaultChunkerIdleTTL,
 	}
 }
 
-// calculateOptimalConcurrency dynamically determines the optimal concurrency level
+// calculateOptimalConcurrency determines the upper bound of the concurrency level
 func calculateOptimalConcurrency() int64 {
 	numCPU := runtime.NumCPU()
 	
@@ -63,12 +79,21 @@ func calculateOptimalConcurrency() int64 {
 }
 
 func (ftm *FileTransferManager) AddFileNode(node *fileInfo.FileNode) error {
+	return ftm.AddFileNodeContext(context.Background(), node)
+}
+
+// AddFileNodeContext adds a file or directory like AddFileNode, giving up on the
+// rest of a directory as soon as ctx is done
+func (ftm *FileTransferManager) AddFileNodeContext(ctx context.Context, node *fileInfo.FileNode) error {
 	if node == nil {
 		return fmt.Errorf("node cannot be nil")
 	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 
 	if node.IsDir {
-		return ftm.processDirConcurrent(node)
+		return ftm.processDirConcurrent(ctx, node)
 	}
 	return ftm.addSingleFileWithLimitCheck(node)
 }
@@ -79,6 +104,8 @@ func (ftm *FileTransferManager) addSingleFileWithLimitCheck(node *fileInfo.FileN
 	if err != nil {
 		return err
 	}
+	// Chunks read from the file drive the concurrency controller with real byte counts
+	chunker.onRead = ftm.RecordRead
 
 	ftm.mu.Lock()
 	defer ftm.mu.Unlock()
@@ -92,15 +119,20 @@ func (ftm *FileTransferManager) addSingleFileWithLimitCheck(node *fileInfo.FileN
 	if oldChunker, exists := ftm.chunkers[node.Path]; exists {
 		oldChunker.Close()
 	}
+	// With eviction enabled, handles are opened on demand so large trees
+	// don't hold one descriptor per file
+	if ftm.idleTTL > 0 {
+		chunker.Release()
+		ftm.startJanitorLocked()
+	}
 	ftm.chunkers[node.Path] = chunker
 	return nil
 }
 
-func (ftm *FileTransferManager) processDirConcurrent(node *fileInfo.FileNode) error {
-	// Adaptive concurrency based on workload characteristics
-	concurrency := ftm.calculateAdaptiveConcurrency(node)
-	semaphore := semaphore.NewWeighted(concurrency)
-	ctx, cancel := context.WithCancel(context.Background())
+func (ftm *FileTransferManager) processDirConcurrent(parent context.Context, node *fileInfo.FileNode) error {
+	// The limit follows the controller as it reacts to throughput and errors
+	gate := newAdaptiveGate(func() int64 { return ftm.calculateAdaptiveConcurrency(node) })
+	ctx, cancel := context.WithCancel(parent)
 	defer cancel()
 
 	var wg sync.WaitGroup
@@ -111,13 +143,13 @@ func (ftm *FileTransferManager) processDirConcurrent(node *fileInfo.FileNode) er
 
 		go func(child *fileInfo.FileNode) {
 			defer wg.Done()
-			if err := semaphore.Acquire(ctx, 1); err != nil {
+			if err := gate.acquire(ctx); err != nil {
 				if err != context.Canceled {
 					errChan <- err
 				}
 				return
 			}
-			defer semaphore.Release(1)
+			defer gate.release()
 
 			select {
 			case <-ctx.Done():
@@ -126,13 +158,15 @@ func (ftm *FileTransferManager) processDirConcurrent(node *fileInfo.FileNode) er
 			}
 
 			if child.IsDir {
-				if err := ftm.AddFileNode(child); err != nil {
+				if err := ftm.AddFileNodeContext(ctx, child); err != nil {
 					errChan <- err
 					cancel()
 					return
 				}
 			} else {
 				if err := ftm.addSingleFileWithLimitCheck(child); err != nil {
+					// Failed opens, such as running out of descriptors, count against the limit
+					ftm.RecordRead(0, err)
 					errChan <- err
 					cancel()
 					return
@@ -150,14 +184,14 @@ func (ftm *FileTransferManager) processDirConcurrent(node *fileInfo.FileNode) er
 		}
 	}
 
-	return nil
+	// Children skipped after a cancel report nothing, so the caller's cancel is reported here
+	return parent.Err()
 }
 
-// calculateAdaptiveConcurrency determines optimal concurrency based on workload
+// calculateAdaptiveConcurrency determines the concurrency for a directory from the
+// controller's current limit and the size of the workload
 func (ftm *FileTransferManager) calculateAdaptiveConcurrency(node *fileInfo.FileNode) int64 {
-	ftm.mu.RLock()
-	baseConcurrency := ftm.maxConcurrency
-	ftm.mu.RUnlock()
+	baseConcurrency := ftm.GetConcurrencyLimit()
 	
 	childCount := int64(len(node.Children))
 	
@@ -214,6 +248,18 @@ func (ftm *FileTransferManager) SetMaxConcurrency(maxConcurrency int64) {
 	ftm.mu.Lock()
 	ftm.maxConcurrency = maxConcurrency
 	ftm.mu.Unlock()
+	ftm.controller.SetMax(maxConcurrency)
+}
+
+// GetConcurrencyLimit returns the limit the concurrency controller currently allows
+func (ftm *FileTransferManager) GetConcurrencyLimit() int64 {
+	return ftm.controller.Limit()
+}
+
+// RecordRead reports a read of bytes from a managed file so the concurrency
+// controller can account for it. Chunkers of the manager's files call it on every read.
+func (ftm *FileTransferManager) RecordRead(bytes int64, err error) {
+	ftm.controller.Record(bytes, err)
 }
 
 // GetMaxConcurrency returns the current concurrency limit
@@ -223,16 +269,112 @@ func (ftm *FileTransferManager) GetMaxConcurrency() int64 {
 	return ftm.maxConcurrency
 }
 
+// SetIdleTTL sets how long an unused chunker keeps its file handle open.
+// A zero or negative TTL disables eviction.
+func (ftm *FileTransferManager) SetIdleTTL(ttl time.Duration) {
+	if ttl < 0 {
+		ttl = 0
+	}
+
+	ftm.mu.Lock()
+	defer ftm.mu.Unlock()
+
+	ftm.idleTTL = ttl
+	ftm.stopJanitorLocked()
+	if ttl > 0 && len(ftm.chunkers) > 0 {
+		ftm.startJanitorLocked()
+	}
+}
+
+// GetIdleTTL returns the current idle eviction TTL
+func (ftm *FileTransferManager) GetIdleTTL() time.Duration {
+	ftm.mu.RLock()
+	defer ftm.mu.RUnlock()
+	return ftm.idleTTL
+}
+
+// EvictIdleChunkers releases the file handles of chunkers unused for longer than the idle TTL.
+// Evicted chunkers reopen their file on the next read. It returns the number of handles released.
+func (ftm *FileTransferManager) EvictIdleChunkers() int {
+	ftm.mu.RLock()
+	defer ftm.mu.RUnlock()
+
+	if ftm.idleTTL <= 0 {
+		return 0
+	}
+
+	cutoff := time.Now().Add(-ftm.idleTTL)
+	evicted := 0
+	for _, chunker := range ftm.chunkers {
+		if !chunker.IsOpen() || chunker.LastUsed().After(cutoff) {
+			continue
+		}
+		if err := chunker.Release(); err != nil {
+			slog.Warn("Failed to release idle chunker", "path", chunker.path, "error", err)
+		}
+		evicted++
+	}
+	ftm.evicted.Add(int64(evicted))
+	return evicted
+}
+
+// startJanitorLocked starts the background eviction loop if it isn't running; callers hold mu
+func (ftm *FileTransferManager) startJanitorLocked() {
+	if ftm.janitorStop != nil {
+		return
+	}
+
+	interval := ftm.idleTTL / 2
+	if interval < time.Second {
+		interval = time.Second
+	}
+
+	stop := make(chan struct{})
+	ftm.janitorStop = stop
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				ftm.EvictIdleChunkers()
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// stopJanitorLocked stops the background eviction loop; callers hold mu
+func (ftm *FileTransferManager) stopJanitorLocked() {
+	if ftm.janitorStop != nil {
+		close(ftm.janitorStop)
+		ftm.janitorStop = nil
+	}
+}
+
 // GetStats returns current statistics about the file transfer manager
 func (ftm *FileTransferManager) GetStats() map[string]interface{} {
 	ftm.mu.RLock()
 	defer ftm.mu.RUnlock()
+
+	openHandles := 0
+	for _, chunker := range ftm.chunkers {
+		if chunker.IsOpen() {
+			openHandles++
+		}
+	}
 	
 	return map[string]interface{}{
-		"total_files":      len(ftm.chunkers),
-		"max_concurrency":  ftm.maxConcurrency,
-		"cpu_count":        runtime.NumCPU(),
-		"goroutines":       runtime.NumGoroutine(),
+		"total_files":           len(ftm.chunkers),
+		"open_handles":          openHandles,
+		"evicted_handles":       ftm.evicted.Load(),
+		"idle_ttl":              ftm.idleTTL,
+		"max_concurrency":       ftm.maxConcurrency,
+		"concurrency":           ftm.controller.Stats(),
+		"concurrency_decisions": ftm.controller.Decisions(),
+		"cpu_count":             runtime.NumCPU(),
+		"goroutines":            runtime.NumGoroutine(),
 	}
 }
 
@@ -240,6 +382,7 @@ func (ftm *FileTransferManager) Close() error {
 	ftm.mu.Lock()
 	defer ftm.mu.Unlock()
 
+	ftm.stopJanitorLocked()
 	for _, chunker := range ftm.chunkers {
 		chunker.Close()
 	}
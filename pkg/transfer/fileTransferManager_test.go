@@ -1,7 +1,9 @@
 package transfer
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -941,6 +943,38 @@ func TestFileTransferManager_ConcurrencyAdjustment(t *testing.T) {
 	assert.Equal(t, int64(128), ftm.GetMaxConcurrency(), "Should clamp to maximum 128")
 }
 
+// TestFileTransferManager_ChunkReadsFeedController tests that the controller sees the bytes read from managed files
+func TestFileTransferManager_ChunkReadsFeedController(t *testing.T) {
+	ftm := NewFileTransferManager()
+	tempDir := setupTestDir(t)
+	t.Cleanup(func() {
+		ftm.Close()
+	})
+
+	filePath := filepath.Join(tempDir, "file1.txt")
+	node, err := fileInfo.CreateNode(filePath)
+	require.NoError(t, err)
+	require.NoError(t, ftm.AddFileNode(&node))
+	chunker, exists := ftm.GetChunker(filePath)
+	require.True(t, exists)
+
+	for {
+		_, err := chunker.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+	}
+	_, err = chunker.ReadChunkAt(1)
+	require.NoError(t, err)
+
+	ftm.controller.mu.Lock()
+	defer ftm.controller.mu.Unlock()
+	assert.Equal(t, int64(2), ftm.controller.ops, "each chunk read should be one operation; the end of the file is not")
+	assert.Equal(t, 2*node.Size, ftm.controller.bytes)
+	assert.Zero(t, ftm.controller.errs)
+}
+
 // TestFileTransferManager_AdaptiveConcurrency tests workload-based concurrency adaptation
 func TestFileTransferManager_AdaptiveConcurrency(t *testing.T) {
 	ftm := NewFileTransferManager()
@@ -1670,3 +1704,73 @@ func TestFileTransferManager_BoundaryConditions(t *testing.T) {
 		})
 	}
 }
+
+func TestFileTransferManager_IdleEviction(t *testing.T) {
+	ftm := NewFileTransferManager()
+	tempDir := setupTestDir(t)
+	t.Cleanup(func() {
+		ftm.Close()
+	})
+
+	filePath := filepath.Join(tempDir, "file1.txt")
+	node, err := fileInfo.CreateNode(filePath)
+	require.NoError(t, err)
+	require.NoError(t, ftm.AddFileNode(&node))
+
+	// Handles are opened on demand
+	stats := ftm.GetStats()
+	assert.Equal(t, 0, stats["open_handles"])
+
+	chunker, exists := ftm.GetChunker(filePath)
+	require.True(t, exists)
+	chunk, err := chunker.Next()
+	require.NoError(t, err)
+	assert.Equal(t, "Hello World 1", string(chunk.Data))
+	assert.Equal(t, 1, ftm.GetStats()["open_handles"])
+
+	// Recently used chunkers are kept open
+	assert.Equal(t, 0, ftm.EvictIdleChunkers())
+
+	ftm.SetIdleTTL(time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	assert.Equal(t, 1, ftm.EvictIdleChunkers())
+
+	stats = ftm.GetStats()
+	assert.Equal(t, 0, stats["open_handles"])
+	assert.Equal(t, int64(1), stats["evicted_handles"])
+}
+
+func TestFileTransferManager_IdleEvictionDisabled(t *testing.T) {
+	ftm := NewFileTransferManager()
+	ftm.SetIdleTTL(0)
+	tempDir := setupTestDir(t)
+	t.Cleanup(func() {
+		ftm.Close()
+	})
+
+	node, err := fileInfo.CreateNode(filepath.Join(tempDir, "file1.txt"))
+	require.NoError(t, err)
+	require.NoError(t, ftm.AddFileNode(&node))
+
+	assert.Equal(t, 1, ftm.GetStats()["open_handles"])
+	assert.Equal(t, 0, ftm.EvictIdleChunkers())
+	assert.Equal(t, time.Duration(0), ftm.GetIdleTTL())
+}
+
+func TestFileTransferManager_AddFileNodeContextCancelled(t *testing.T) {
+	ftm := NewFileTransferManager()
+	t.Cleanup(func() {
+		ftm.Close()
+	})
+	tempDir := setupTestDir(t)
+
+	node, err := fileInfo.CreateNode(tempDir)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, ftm.AddFileNodeContext(ctx, &node), context.Canceled)
+
+	_, exists := ftm.GetChunker(filepath.Join(tempDir, "file1.txt"))
+	assert.False(t, exists, "no file should be added after the context is cancelled")
+}
@@ -2,6 +2,7 @@ package transfer
 
 import (
 	"encoding/json"
+	"time"
 )
 
 type JSONSerializer struct{}
@@ -22,6 +23,11 @@ type JSONChunkMessage struct {
 	TotalSize    int64           `json:"total_size,omitempty"`
 	ExpectedHash string          `json:"expected_hash,omitempty"`
 	ErrorMessage string          `json:"error_message,omitempty"`
+	ErrorCode    string          `json:"error_code,omitempty"`
+	IsLast       bool            `json:"is_last,omitempty"`
+	DiskRate     float64         `json:"disk_rate,omitempty"`
+	WriteTime    time.Duration   `json:"write_time,omitempty"`
+	LinkTo       string          `json:"link_to,omitempty"`
 }
 
 func (j *JSONSerializer) Marshal(msg *ChunkMessage) ([]byte, error) {
@@ -37,6 +43,11 @@ func (j *JSONSerializer) Marshal(msg *ChunkMessage) ([]byte, error) {
 		TotalSize:    msg.TotalSize,
 		ExpectedHash: msg.ExpectedHash,
 		ErrorMessage: msg.ErrorMessage,
+		ErrorCode:    msg.ErrorCode,
+		IsLast:       msg.IsLast,
+		DiskRate:     msg.DiskRate,
+		WriteTime:    msg.WriteTime,
+		LinkTo:       msg.LinkTo,
 	})
 }
 
@@ -57,6 +68,11 @@ func (j *JSONSerializer) Unmarshal(data []byte) (*ChunkMessage, error) {
 		TotalSize:    jsonMsg.TotalSize,
 		ExpectedHash: jsonMsg.ExpectedHash,
 		ErrorMessage: jsonMsg.ErrorMessage,
+		ErrorCode:    jsonMsg.ErrorCode,
+		IsLast:       jsonMsg.IsLast,
+		DiskRate:     jsonMsg.DiskRate,
+		WriteTime:    jsonMsg.WriteTime,
+		LinkTo:       jsonMsg.LinkTo,
 	}, nil
 }
 
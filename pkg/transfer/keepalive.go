@@ -0,0 +1,117 @@
+package transfer
+
+import (
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// ErrSessionIdle is returned when the peer of a session sent nothing, not even a keepalive,
+// for longer than the idle timeout, e.g. because the laptop on the other end went to sleep
+var ErrSessionIdle = fmt.Errorf("%w: peer stopped responding", ErrConnectionLost)
+
+const (
+	// DefaultIdleTimeout is how long a session may go without a message from the peer
+	DefaultIdleTimeout = 60 * time.Second
+	// maxKeepaliveInterval is how often keepalives are sent with long idle timeouts
+	maxKeepaliveInterval = 5 * time.Second
+)
+
+// KeepaliveInterval returns how often keepalives are sent to a peer that ends the session
+// after timeout, often enough that a few can be lost or delayed behind chunks
+func KeepaliveInterval(timeout time.Duration) time.Duration {
+	if interval := timeout / 3; interval < maxKeepaliveInterval {
+		return interval
+	}
+	return maxKeepaliveInterval
+}
+
+// KeepaliveMonitor sends Keepalive messages to the peer of a session and notices when the peer
+// stops sending anything. Every message from the peer counts, so a peer busy sending
+// chunks is never idle.
+type KeepaliveMonitor struct {
+	clock    Clock
+	timeout  time.Duration
+	interval time.Duration
+	send     func() error
+
+	mu      sync.Mutex
+	last    time.Time // Last message from the peer
+	timer   Timer
+	stopped bool
+	idle    chan struct{}
+}
+
+// NewKeepaliveMonitor creates a KeepaliveMonitor that sends with send and ends the session after timeout
+func NewKeepaliveMonitor(timeout time.Duration, send func() error) *KeepaliveMonitor {
+	return NewKeepaliveMonitorWithClock(timeout, send, SystemClock{})
+}
+
+// NewKeepaliveMonitorWithClock is NewKeepaliveMonitor with the time told by clock
+func NewKeepaliveMonitorWithClock(timeout time.Duration, send func() error, clock Clock) *KeepaliveMonitor {
+	return &KeepaliveMonitor{
+		clock:    clock,
+		timeout:  timeout,
+		interval: KeepaliveInterval(timeout),
+		send:     send,
+		idle:     make(chan struct{}),
+	}
+}
+
+// Start sends keepalives until Stop, counting the idle time from now
+func (k *KeepaliveMonitor) Start() {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.last = k.clock.Now()
+	k.timer = k.clock.AfterFunc(k.interval, k.tick)
+}
+
+// Touch records that a message arrived from the peer
+func (k *KeepaliveMonitor) Touch() {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.last = k.clock.Now()
+}
+
+// Idle is closed once the peer sent nothing for the idle timeout
+func (k *KeepaliveMonitor) Idle() <-chan struct{} {
+	return k.idle
+}
+
+// Stop stops sending keepalives
+func (k *KeepaliveMonitor) Stop() {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.stopped = true
+	if k.timer != nil {
+		k.timer.Stop()
+	}
+}
+
+func (k *KeepaliveMonitor) tick() {
+	k.mu.Lock()
+	if k.stopped {
+		k.mu.Unlock()
+		return
+	}
+	if quiet := k.clock.Now().Sub(k.last); quiet >= k.timeout {
+		slog.Warn("Peer sent nothing within the idle timeout, ending the session", "quiet", quiet, "timeout", k.timeout)
+		k.stopped = true
+		close(k.idle)
+		k.mu.Unlock()
+		return
+	}
+	k.mu.Unlock()
+
+	// A failed send is not fatal, the peer going quiet is
+	if err := k.send(); err != nil {
+		slog.Debug("Failed to send keepalive", "error", err)
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if !k.stopped {
+		k.timer = k.clock.AfterFunc(k.interval, k.tick)
+	}
+}
@@ -0,0 +1,73 @@
+package transfer
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeepaliveInterval(t *testing.T) {
+	assert.Equal(t, 5*time.Second, KeepaliveInterval(DefaultIdleTimeout))
+	assert.Equal(t, 2*time.Second, KeepaliveInterval(6*time.Second), "short timeouts get a few keepalives each")
+}
+
+func TestKeepaliveMonitor_SendsAndStaysAliveWhileThePeerTalks(t *testing.T) {
+	clock := NewManualClock(time.Unix(0, 0))
+	var sent atomic.Int32
+	keepalive := NewKeepaliveMonitorWithClock(6*time.Second, func() error {
+		sent.Add(1)
+		return nil
+	}, clock)
+	keepalive.Start()
+	defer keepalive.Stop()
+
+	for i := 0; i < 5; i++ {
+		clock.Advance(2 * time.Second)
+		keepalive.Touch()
+	}
+	assert.Equal(t, int32(5), sent.Load())
+	select {
+	case <-keepalive.Idle():
+		t.Fatal("a peer that keeps sending is not idle")
+	default:
+	}
+}
+
+func TestKeepaliveMonitor_IdleOnceThePeerGoesQuiet(t *testing.T) {
+	clock := NewManualClock(time.Unix(0, 0))
+	keepalive := NewKeepaliveMonitorWithClock(6*time.Second, func() error { return nil }, clock)
+	keepalive.Start()
+	defer keepalive.Stop()
+
+	clock.Advance(2 * time.Second)
+	clock.Advance(2 * time.Second)
+	select {
+	case <-keepalive.Idle():
+		t.Fatal("idle before the timeout")
+	default:
+	}
+	clock.Advance(2 * time.Second)
+	select {
+	case <-keepalive.Idle():
+	default:
+		t.Fatal("not idle after the timeout")
+	}
+	assert.Zero(t, clock.Pending(), "no keepalives are sent once idle")
+}
+
+func TestKeepaliveMonitor_Stop(t *testing.T) {
+	clock := NewManualClock(time.Unix(0, 0))
+	keepalive := NewKeepaliveMonitorWithClock(6*time.Second, func() error { return nil }, clock)
+	keepalive.Start()
+	keepalive.Stop()
+
+	assert.Zero(t, clock.Pending())
+	clock.Advance(time.Minute)
+	select {
+	case <-keepalive.Idle():
+		t.Fatal("a stopped keepalive does not end the session")
+	default:
+	}
+}
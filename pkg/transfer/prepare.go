@@ -0,0 +1,159 @@
+package transfer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/rescp17/lanFileSharer/pkg/events"
+	"github.com/rescp17/lanFileSharer/pkg/fileInfo"
+)
+
+// preparationInterval throttles PreparationProgress events; phase changes are always published
+const preparationInterval = 100 * time.Millisecond
+
+// ErrAllLocked is returned by PrepareNodes when every selected file is locked by another process
+var ErrAllLocked = errors.New("every selected file is locked by another process")
+
+// PrepareFunc receives the progress of PrepareNodes
+type PrepareFunc func(events.PreparationProgress)
+
+// PrepareNodes scans every path and then hashes each file once with up to workers at a time.
+// Scanning everything first makes the total known before hashing starts. Files locked by
+// other processes are left out, instead of failing the transfer when they are read, and
+// listed in the final update. Hard links between the selected files are marked with
+// fileInfo.MarkHardLinks. progress, if set, receives throttled updates and always the final one.
+func PrepareNodes(ctx context.Context, paths []string, workers int, progress PrepareFunc) ([]fileInfo.FileNode, error) {
+	reporter := &preparationReporter{fn: progress}
+	reporter.update(func(p *events.PreparationProgress) {
+		p.Phase = events.PreparationScanning
+	}, true)
+
+	nodes := make([]fileInfo.FileNode, 0, len(paths))
+	var scannedFiles, scannedBytes int64
+	for _, path := range paths {
+		node, err := fileInfo.ScanNodeWithProgress(path, func(files, bytes int64) {
+			reporter.update(func(p *events.PreparationProgress) {
+				p.FilesScanned = scannedFiles + files
+				p.BytesScanned = scannedBytes + bytes
+			}, false)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan %s: %w", path, err)
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
+		scannedFiles, scannedBytes = reporter.scanned()
+	}
+
+	nodes, locked := skipLocked(nodes)
+	lockedPaths := make([]string, 0, len(locked))
+	var lockedBytes int64
+	for _, l := range locked {
+		lockedPaths = append(lockedPaths, l.Path)
+		lockedBytes += l.Size
+	}
+	if len(locked) > 0 {
+		slog.Warn("Skipping files locked by other processes", "files", lockedPaths)
+		if len(nodes) == 0 {
+			return nil, ErrAllLocked
+		}
+		reporter.update(func(p *events.PreparationProgress) {
+			p.FilesScanned -= int64(len(locked))
+			p.BytesScanned -= lockedBytes
+		}, false)
+	}
+
+	reporter.update(func(p *events.PreparationProgress) {
+		p.Phase = events.PreparationHashing
+	}, true)
+
+	var hashedBytes int64
+	for i := range nodes {
+		err := nodes[i].HashTree(ctx, workers, func(hashed, _ int64) {
+			reporter.update(func(p *events.PreparationProgress) {
+				p.BytesHashed = hashedBytes + hashed
+			}, false)
+		})
+		if err != nil {
+			return nil, err
+		}
+		hashedBytes = reporter.hashed()
+	}
+	if links := fileInfo.MarkHardLinks(nodes); links > 0 {
+		slog.Info("Found hard links, sending their content once", "links", links)
+	}
+
+	reporter.update(func(p *events.PreparationProgress) {
+		p.Phase = events.PreparationDone
+		if len(lockedPaths) > 0 {
+			p.Locked = lockedPaths
+		}
+	}, true)
+	return nodes, nil
+}
+
+// skipLocked removes the files locked by other processes from nodes and the sizes of the
+// directories holding them, and returns what is left along with the removed files
+func skipLocked(nodes []fileInfo.FileNode) ([]fileInfo.FileNode, []fileInfo.FileNode) {
+	var locked []fileInfo.FileNode
+	kept := nodes[:0]
+	for _, node := range nodes {
+		if !node.IsDir {
+			if fileInfo.IsLocked(node.Path) {
+				locked = append(locked, node)
+				continue
+			}
+			kept = append(kept, node)
+			continue
+		}
+		var removed []fileInfo.FileNode
+		node.Children, removed = skipLocked(node.Children)
+		for _, r := range removed {
+			node.Size -= r.Size
+		}
+		locked = append(locked, removed...)
+		kept = append(kept, node)
+	}
+	return kept, locked
+}
+
+// preparationReporter keeps the running totals of PrepareNodes and throttles reporting them
+type preparationReporter struct {
+	mu       sync.Mutex
+	fn       PrepareFunc
+	progress events.PreparationProgress
+	last     time.Time
+}
+
+// update applies change to the totals and reports them if force is set or enough time has passed
+func (r *preparationReporter) update(change func(*events.PreparationProgress), force bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	change(&r.progress)
+	now := time.Now()
+	if r.fn == nil || (!force && now.Sub(r.last) < preparationInterval) {
+		return
+	}
+	r.last = now
+	r.progress.Time = now
+	r.fn(r.progress)
+}
+
+func (r *preparationReporter) scanned() (files, bytes int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.progress.FilesScanned, r.progress.BytesScanned
+}
+
+func (r *preparationReporter) hashed() int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.progress.BytesHashed
+}
@@ -0,0 +1,92 @@
+package transfer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rescp17/lanFileSharer/pkg/events"
+	"github.com/rescp17/lanFileSharer/pkg/fileInfo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writePrepareTree creates a directory with three files in two levels and returns its path
+func writePrepareTree(t *testing.T) string {
+	t.Helper()
+	root := filepath.Join(t.TempDir(), "root")
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "sub"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "a.txt"), []byte("hello"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "b.txt"), []byte("world!"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "sub", "c.txt"), []byte("nested file"), 0644))
+	return root
+}
+
+func TestPrepareNodes_ReportsPhasesAndTotals(t *testing.T) {
+	root := writePrepareTree(t)
+
+	var updates []events.PreparationProgress
+	nodes, err := PrepareNodes(context.Background(), []string{root}, 2, func(p events.PreparationProgress) {
+		updates = append(updates, p)
+	})
+	require.NoError(t, err)
+	require.Len(t, nodes, 1)
+
+	require.NotEmpty(t, updates)
+	assert.Equal(t, events.PreparationScanning, updates[0].Phase)
+	final := updates[len(updates)-1]
+	assert.Equal(t, events.PreparationDone, final.Phase)
+	assert.Equal(t, int64(3), final.FilesScanned)
+	assert.Equal(t, int64(len("hello")+len("world!")+len("nested file")), final.BytesScanned)
+	assert.Equal(t, final.BytesScanned, final.BytesHashed, "every scanned byte should be hashed once")
+
+	// The checksums match the ones computed the slow way
+	expected, err := fileInfo.CreateNode(root)
+	require.NoError(t, err)
+	assert.Equal(t, expected.Checksum, nodes[0].Checksum)
+	assert.Equal(t, expected.Size, nodes[0].Size)
+}
+
+func TestPrepareNodes_Cancelled(t *testing.T) {
+	root := writePrepareTree(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := PrepareNodes(ctx, []string{root}, 1, nil)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestPrepareNodes_MissingPath(t *testing.T) {
+	_, err := PrepareNodes(context.Background(), []string{filepath.Join(t.TempDir(), "missing")}, 1, nil)
+	assert.Error(t, err)
+}
+
+func TestFileStructureManager_AddPathPublishesPreparation(t *testing.T) {
+	root := writePrepareTree(t)
+
+	bus := events.NewBus()
+	defer bus.Close()
+	sub := bus.Subscribe(64, events.TopicPrepare)
+
+	fsm := NewFileStructureManager()
+	fsm.SetEventBus(bus)
+	require.NoError(t, fsm.AddPath(root))
+	assert.Equal(t, 3, fsm.GetFileCount())
+
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case event := <-sub.C():
+			progress, ok := event.(events.PreparationProgress)
+			require.True(t, ok)
+			if progress.Phase == events.PreparationDone {
+				assert.Equal(t, int64(3), progress.FilesScanned)
+				return
+			}
+		case <-timeout:
+			t.Fatal("no done event published")
+		}
+	}
+}
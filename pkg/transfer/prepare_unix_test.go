@@ -0,0 +1,53 @@
+//go:build linux || darwin
+
+package transfer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rescp17/lanFileSharer/pkg/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/unix"
+)
+
+// lockFile holds an exclusive lock on path until the test ends, as another program would
+func lockFile(t *testing.T, path string) {
+	t.Helper()
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	t.Cleanup(func() { file.Close() })
+	require.NoError(t, unix.Flock(int(file.Fd()), unix.LOCK_EX|unix.LOCK_NB))
+}
+
+func TestPrepareNodes_SkipsLockedFiles(t *testing.T) {
+	root := writePrepareTree(t)
+	unlocked, err := PrepareNodes(context.Background(), []string{root}, 1, nil)
+	require.NoError(t, err)
+	locked := filepath.Join(root, "sub", "c.txt")
+	lockFile(t, locked)
+
+	var final events.PreparationProgress
+	nodes, err := PrepareNodes(context.Background(), []string{root}, 2, func(p events.PreparationProgress) {
+		final = p
+	})
+	require.NoError(t, err)
+	require.Len(t, nodes, 1)
+
+	assert.Equal(t, events.PreparationDone, final.Phase)
+	assert.Equal(t, []string{locked}, final.Locked)
+	assert.Equal(t, int64(2), final.FilesScanned)
+	assert.Equal(t, int64(len("hello")+len("world!")), final.BytesScanned)
+	assert.Equal(t, unlocked[0].Size-int64(len("nested file")), nodes[0].Size, "the locked file no longer counts towards its directory")
+	for _, child := range nodes[0].Children {
+		if child.Name == "sub" {
+			assert.Empty(t, child.Children)
+		}
+	}
+
+	_, err = PrepareNodes(context.Background(), []string{locked}, 1, nil)
+	assert.ErrorIs(t, err, ErrAllLocked)
+}
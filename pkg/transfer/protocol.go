@@ -1,5 +1,7 @@
 package transfer
 
+import "time"
+
 type MessageType string
 
 const (
@@ -11,6 +13,27 @@ const (
 	TransferCancel    MessageType = "transfer_cancel"
 	TransferComplete  MessageType = "transfer_complete"
 	ProgressUpdate    MessageType = "progress_update"
+	// StructureUpdate carries a signed structure delta in Data
+	StructureUpdate MessageType = "structure_update"
+	// FileAck is sent by the receiver once a file is written and verified. ExpectedHash
+	// carries the checksum it verified; ErrorMessage and ErrorCode are set when the file failed.
+	FileAck MessageType = "file_ack"
+	// WriteAck is sent by the receiver while it writes, to senders that asked for it.
+	// Offset carries the session bytes persisted to disk, TotalSize the session's expected
+	// bytes and DiskRate the rate the disk accepted data at.
+	WriteAck MessageType = "write_ack"
+	// FileLink replaces the chunks of a file that is a hard link to the completed file LinkTo,
+	// which the receiver links or copies instead of receiving the content again. Without
+	// LinkTo it names the content the receiver announced it stores, by ExpectedHash.
+	FileLink MessageType = "file_link"
+	// Keepalive carries nothing; peers that negotiated it send one regularly, so the other
+	// side notices when the session went idle, see KeepaliveMonitor
+	Keepalive MessageType = "keepalive"
+	// TransferPause and TransferResume tell the receiver the sender paused or resumed the
+	// session; TransferCancel that it was cancelled, so chunks still in flight are dropped.
+	// They go out on the control channel only, which older receivers do not open.
+	TransferPause  MessageType = "transfer_pause"
+	TransferResume MessageType = "transfer_resume"
 )
 
 type ChunkMessage struct {
@@ -25,6 +48,17 @@ type ChunkMessage struct {
 	TotalSize    int64
 	ExpectedHash string
 	ErrorMessage string
+	// ErrorCode is the ErrorCode of the error in ErrorMessage, so the peer can match it with errors.Is
+	ErrorCode string
+	// IsLast marks the final chunk of a file; for streams of unknown length it
+	// carries the final TotalSize and ExpectedHash
+	IsLast bool
+	// DiskRate is the receiver's disk throughput in bytes per second, set in WriteAck
+	DiskRate float64
+	// WriteTime is the receiver's time writing the file to disk, set in FileAck
+	WriteTime time.Duration
+	// LinkTo is the FileID of the file a FileLink message links to, empty for stored content
+	LinkTo string
 }
 
 type MessageSerializer interface {
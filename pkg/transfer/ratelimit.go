@@ -0,0 +1,87 @@
+package transfer
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// RateLimiter paces sent data to a number of bytes per second. Every reservation moves
+// the time the next one may start by its share of a second, so concurrent senders, such
+// as the channels of a parallel transfer, share the limit. A nil RateLimiter is unlimited.
+type RateLimiter struct {
+	bytesPerSecond int64             // Fixed limit, zero if there is none
+	schedule       BandwidthSchedule // Limits by time of day, applied with the fixed limit
+	clock          Clock
+
+	mu   sync.Mutex
+	next time.Time // When the next reservation may be sent
+}
+
+// NewRateLimiter creates a limiter of bytesPerSecond; it returns nil, which does not
+// limit, if bytesPerSecond is zero or less
+func NewRateLimiter(bytesPerSecond int64, clock Clock) *RateLimiter {
+	return NewScheduledRateLimiter(bytesPerSecond, nil, clock)
+}
+
+// NewScheduledRateLimiter creates a limiter of bytesPerSecond that also keeps to the profile
+// of schedule active at the time, the lower of the two applying. It returns nil if neither
+// limits anything.
+func NewScheduledRateLimiter(bytesPerSecond int64, schedule BandwidthSchedule, clock Clock) *RateLimiter {
+	if bytesPerSecond <= 0 && len(schedule) == 0 {
+		return nil
+	}
+	if clock == nil {
+		clock = SystemClock{}
+	}
+	return &RateLimiter{bytesPerSecond: max(bytesPerSecond, 0), schedule: schedule, clock: clock}
+}
+
+// rateAt returns the bytes per second allowed at now, zero if unlimited
+func (l *RateLimiter) rateAt(now time.Time) int64 {
+	rate := l.bytesPerSecond
+	if scheduled := l.schedule.Limit(now); scheduled > 0 && (rate == 0 || scheduled < rate) {
+		rate = scheduled
+	}
+	return rate
+}
+
+// Reserve books n bytes and returns how long to wait before sending them
+func (l *RateLimiter) Reserve(n int) time.Duration {
+	if l == nil {
+		return 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.clock.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	rate := l.rateAt(now)
+	if rate == 0 {
+		// Unlimited for now, such as outside every profile; nothing is owed later
+		l.next = now
+		return 0
+	}
+	delay := l.next.Sub(now)
+	l.next = l.next.Add(time.Duration(float64(n) / float64(rate) * float64(time.Second)))
+	return delay
+}
+
+// Wait blocks until n bytes may be sent or ctx is done
+func (l *RateLimiter) Wait(ctx context.Context, n int) error {
+	delay := l.Reserve(n)
+	if delay <= 0 {
+		return ctx.Err()
+	}
+	ready := make(chan struct{})
+	timer := l.clock.AfterFunc(delay, func() { close(ready) })
+	defer timer.Stop()
+	select {
+	case <-ready:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
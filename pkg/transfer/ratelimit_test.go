@@ -0,0 +1,70 @@
+package transfer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimiter_Reserve(t *testing.T) {
+	clock := NewManualClock(time.Date(2024, 5, 15, 0, 0, 0, 0, time.UTC))
+	limiter := NewRateLimiter(1000, clock)
+
+	assert.Zero(t, limiter.Reserve(500))
+	assert.Equal(t, 500*time.Millisecond, limiter.Reserve(1000))
+	assert.Equal(t, 1500*time.Millisecond, limiter.Reserve(100))
+
+	clock.Advance(5 * time.Second)
+	assert.Zero(t, limiter.Reserve(100), "idle time is not saved up")
+}
+
+func TestRateLimiter_Wait(t *testing.T) {
+	clock := NewManualClock(time.Date(2024, 5, 15, 0, 0, 0, 0, time.UTC))
+	limiter := NewRateLimiter(1000, clock)
+	require.NoError(t, limiter.Wait(context.Background(), 1000))
+
+	done := make(chan error, 1)
+	go func() { done <- limiter.Wait(context.Background(), 1000) }()
+	require.Eventually(t, func() bool { return clock.Pending() == 1 }, time.Second, time.Millisecond)
+	select {
+	case <-done:
+		t.Fatal("Wait returned before the limit allowed")
+	default:
+	}
+	clock.Advance(time.Second)
+	require.NoError(t, <-done)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, limiter.Wait(ctx, 1000), context.Canceled)
+}
+
+func TestRateLimiter_NilIsUnlimited(t *testing.T) {
+	limiter := NewRateLimiter(0, nil)
+	assert.Nil(t, limiter)
+	assert.Zero(t, limiter.Reserve(1<<30))
+	assert.NoError(t, limiter.Wait(context.Background(), 1<<30))
+}
+
+func TestRateLimiter_FollowsSchedule(t *testing.T) {
+	workHours := BandwidthProfile{Name: "work", Start: 9 * time.Hour, End: 18 * time.Hour, BytesPerSecond: 1000}
+	clock := NewManualClock(time.Date(2024, 5, 15, 8, 59, 0, 0, time.UTC))
+	limiter := NewScheduledRateLimiter(0, BandwidthSchedule{workHours}, clock)
+	require.NotNil(t, limiter)
+
+	assert.Zero(t, limiter.Reserve(1<<20), "unlimited before work hours")
+	assert.Zero(t, limiter.Reserve(1<<20))
+
+	clock.Advance(time.Minute)
+	assert.Zero(t, limiter.Reserve(1000))
+	assert.Equal(t, time.Second, limiter.Reserve(1000), "limited during work hours")
+
+	fixed := NewScheduledRateLimiter(500, BandwidthSchedule{workHours}, clock)
+	assert.Zero(t, fixed.Reserve(500))
+	assert.Equal(t, time.Second, fixed.Reserve(500), "the lower limit applies")
+
+	assert.Nil(t, NewScheduledRateLimiter(0, nil, clock))
+}
@@ -0,0 +1,182 @@
+package transfer
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ResumeDirName is the directory, relative to the output directory, holding resume state
+const ResumeDirName = ".lanfilesharer/resume"
+
+var (
+	ErrResumeStateNotFound = errors.New("resume state not found")
+	ErrInvalidResumeToken  = errors.New("invalid resume token")
+)
+
+// ResumeState is the persisted progress of an interrupted session, keyed by its share token
+type ResumeState struct {
+	Token     string                      `json:"token"`
+	UpdatedAt int64                       `json:"updated_at"`
+	Files     map[string]*ResumeFileState `json:"files"` // FileID -> state
+}
+
+// ResumeFileState records which chunks of a single file have been written to disk
+type ResumeFileState struct {
+	FileName       string   `json:"file_name"`
+	OutputPath     string   `json:"output_path"`
+	TotalSize      int64    `json:"total_size"`
+	ExpectedHash   string   `json:"expected_hash"`
+	ReceivedSize   int64    `json:"received_size"`
+	ReceivedChunks []uint32 `json:"received_chunks,omitempty"`
+	Completed      bool     `json:"completed"`
+}
+
+// NewResumeState creates an empty resume state for token
+func NewResumeState(token string) *ResumeState {
+	return &ResumeState{
+		Token: token,
+		Files: make(map[string]*ResumeFileState),
+	}
+}
+
+// HasChunk reports whether the chunk of fileID has already been received
+func (s *ResumeState) HasChunk(fileID string, sequenceNo uint32) bool {
+	if s == nil {
+		return false
+	}
+	file, ok := s.Files[fileID]
+	if !ok {
+		return false
+	}
+	if file.Completed {
+		return true
+	}
+	i := sort.Search(len(file.ReceivedChunks), func(i int) bool { return file.ReceivedChunks[i] >= sequenceNo })
+	return i < len(file.ReceivedChunks) && file.ReceivedChunks[i] == sequenceNo
+}
+
+// IsFileCompleted reports whether fileID was fully received and verified
+func (s *ResumeState) IsFileCompleted(fileID string) bool {
+	if s == nil {
+		return false
+	}
+	file, ok := s.Files[fileID]
+	return ok && file.Completed
+}
+
+// ChunkBitmap converts a set of received chunks into the sorted list stored in ResumeFileState
+func ChunkBitmap(chunks map[uint32]bool) []uint32 {
+	seqs := make([]uint32, 0, len(chunks))
+	for seq, ok := range chunks {
+		if ok {
+			seqs = append(seqs, seq)
+		}
+	}
+	sort.Slice(seqs, func(i, j int) bool { return seqs[i] < seqs[j] })
+	return seqs
+}
+
+// NewResumeToken generates a random share token identifying a transfer session
+func NewResumeToken() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		// crypto/rand never fails on supported platforms; fall back to the clock just in case
+		return fmt.Sprintf("%016x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
+
+// ValidateResumeToken rejects tokens that could escape the resume directory
+func ValidateResumeToken(token string) error {
+	if token == "" || len(token) > 64 {
+		return ErrInvalidResumeToken
+	}
+	for _, r := range token {
+		if !strings.ContainsRune("0123456789abcdefABCDEF-", r) {
+			return ErrInvalidResumeToken
+		}
+	}
+	return nil
+}
+
+// ResumeStore persists resume states as JSON files in a directory
+type ResumeStore struct {
+	dir string
+}
+
+// NewResumeStore creates a store rooted at outputDir/ResumeDirName
+func NewResumeStore(outputDir string) *ResumeStore {
+	return &ResumeStore{dir: filepath.Join(outputDir, ResumeDirName)}
+}
+
+func (rs *ResumeStore) path(token string) string {
+	return filepath.Join(rs.dir, token+".json")
+}
+
+// Save writes state atomically
+func (rs *ResumeStore) Save(state *ResumeState) error {
+	if err := ValidateResumeToken(state.Token); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(rs.dir, 0755); err != nil {
+		return fmt.Errorf("failed to create resume directory: %w", err)
+	}
+
+	state.UpdatedAt = time.Now().Unix()
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to marshal resume state: %w", err)
+	}
+
+	tmp := rs.path(state.Token) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write resume state: %w", err)
+	}
+	if err := os.Rename(tmp, rs.path(state.Token)); err != nil {
+		return fmt.Errorf("failed to commit resume state: %w", err)
+	}
+	return nil
+}
+
+// Load reads the state for token
+func (rs *ResumeStore) Load(token string) (*ResumeState, error) {
+	if err := ValidateResumeToken(token); err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(rs.path(token))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrResumeStateNotFound
+		}
+		return nil, fmt.Errorf("failed to read resume state: %w", err)
+	}
+
+	var state ResumeState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to parse resume state: %w", err)
+	}
+	if state.Files == nil {
+		state.Files = make(map[string]*ResumeFileState)
+	}
+	return &state, nil
+}
+
+// Delete removes the state for token; a missing state is not an error
+func (rs *ResumeStore) Delete(token string) error {
+	if err := ValidateResumeToken(token); err != nil {
+		return err
+	}
+	if err := os.Remove(rs.path(token)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to delete resume state: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package transfer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResumeStore_SaveLoadDelete(t *testing.T) {
+	store := NewResumeStore(t.TempDir())
+	token := NewResumeToken()
+
+	state := NewResumeState(token)
+	state.Files["a.txt"] = &ResumeFileState{
+		FileName:       "a.txt",
+		TotalSize:      100,
+		ReceivedSize:   40,
+		ReceivedChunks: ChunkBitmap(map[uint32]bool{3: true, 1: true, 2: false}),
+	}
+	require.NoError(t, store.Save(state))
+
+	loaded, err := store.Load(token)
+	require.NoError(t, err)
+	assert.Equal(t, token, loaded.Token)
+	assert.Equal(t, []uint32{1, 3}, loaded.Files["a.txt"].ReceivedChunks)
+	assert.True(t, loaded.HasChunk("a.txt", 3))
+	assert.False(t, loaded.HasChunk("a.txt", 2))
+	assert.False(t, loaded.HasChunk("b.txt", 1))
+
+	require.NoError(t, store.Delete(token))
+	_, err = store.Load(token)
+	assert.ErrorIs(t, err, ErrResumeStateNotFound)
+	assert.NoError(t, store.Delete(token), "deleting a missing state should succeed")
+}
+
+func TestValidateResumeToken(t *testing.T) {
+	assert.NoError(t, ValidateResumeToken(NewResumeToken()))
+	assert.ErrorIs(t, ValidateResumeToken(""), ErrInvalidResumeToken)
+	assert.ErrorIs(t, ValidateResumeToken("../../etc/passwd"), ErrInvalidResumeToken)
+
+	_, err := NewResumeStore(t.TempDir()).Load("../escape")
+	assert.ErrorIs(t, err, ErrInvalidResumeToken)
+}
+
+func TestResumeState_HasChunkNil(t *testing.T) {
+	var state *ResumeState
+	assert.False(t, state.HasChunk("a.txt", 1))
+}
@@ -13,6 +13,7 @@ type RetryScheduler struct {
 	retryQueue   map[string]*RetryTask
 	errorHandler ErrorHandler
 	manager      *UnifiedTransferManager
+	clock        Clock
 	ctx          context.Context
 	cancel       context.CancelFunc
 	wg           sync.WaitGroup
@@ -25,7 +26,7 @@ type RetryTask struct {
 	NextAttempt  time.Time
 	LastError    error
 	ErrorContext *ErrorContext
-	Timer        *time.Timer
+	Timer        Timer
 }
 
 // NewRetryScheduler creates a new retry scheduler
@@ -36,11 +37,19 @@ func NewRetryScheduler(manager *UnifiedTransferManager, errorHandler ErrorHandle
 		retryQueue:   make(map[string]*RetryTask),
 		errorHandler: errorHandler,
 		manager:      manager,
+		clock:        SystemClock{},
 		ctx:          ctx,
 		cancel:       cancel,
 	}
 }
 
+// setClock schedules retries by clock from now on
+func (rs *RetryScheduler) setClock(clock Clock) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	rs.clock = clock
+}
+
 // Start begins the retry scheduler background processing
 func (rs *RetryScheduler) Start() {
 	rs.wg.Add(1)
@@ -78,7 +87,7 @@ func (rs *RetryScheduler) ScheduleRetry(filePath string, err error, retryCount i
 	// Get retry delay
 	retryPolicy := rs.manager.config.DefaultRetryPolicy
 	delay := rs.errorHandler.GetRetryDelay(retryCount, retryPolicy)
-	nextAttempt := time.Now().Add(delay)
+	nextAttempt := rs.clock.Now().Add(delay)
 
 	// Create or update retry task
 	task, exists := rs.retryQueue[filePath]
@@ -112,7 +121,7 @@ func (rs *RetryScheduler) ScheduleRetry(filePath string, err error, retryCount i
 	}
 
 	// Schedule new timer
-	task.Timer = time.AfterFunc(delay, func() {
+	task.Timer = rs.clock.AfterFunc(delay, func() {
 		rs.executeRetry(filePath)
 	})
 
@@ -267,7 +276,7 @@ func (rs *RetryScheduler) GetRetryStatistics() RetryStatistics {
 		TotalScheduled: len(rs.retryQueue),
 	}
 
-	now := time.Now()
+	now := rs.clock.Now()
 	for _, task := range rs.retryQueue {
 		if task.NextAttempt.After(now) {
 			stats.PendingRetries++
@@ -0,0 +1,192 @@
+package transfer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/rescp17/lanFileSharer/pkg/fileInfo"
+)
+
+// snapshotVersion is the version of the snapshot format written by Marshal
+const snapshotVersion = 1
+
+// ErrInvalidSnapshot is returned when a manager snapshot cannot be restored
+var ErrInvalidSnapshot = errors.New("invalid transfer manager snapshot")
+
+// ManagerSnapshot is the state of a UnifiedTransferManager at one point, enough to rebuild
+// it after a crash: its files and their queue states, the chunks sent of each and the
+// session counters. The file being sent when the snapshot was taken is pending in it.
+type ManagerSnapshot struct {
+	Version int                   `json:"version"`
+	TakenAt time.Time             `json:"taken_at"`
+	Session TransferSession       `json:"session"`
+	Status  SessionTransferStatus `json:"status"` // Without its current file
+	Files   []SnapshotFile        `json:"files"`
+}
+
+// SnapshotFile is a file of a ManagerSnapshot
+type SnapshotFile struct {
+	Path       string            `json:"path"`
+	LinkTo     string            `json:"link_to,omitempty"`
+	Node       fileInfo.FileNode `json:"node"`
+	State      string            `json:"state"` // pending, completed or failed
+	SentChunks []uint32          `json:"sent_chunks,omitempty"`
+}
+
+// Queue states of a SnapshotFile
+const (
+	snapshotPending   = "pending"
+	snapshotCompleted = "completed"
+	snapshotFailed    = "failed"
+)
+
+// Snapshot returns the current state of the manager
+func (utm *UnifiedTransferManager) Snapshot() *ManagerSnapshot {
+	utm.filesMu.RLock()
+	utm.queueMu.RLock()
+	utm.statusMu.RLock()
+	defer utm.filesMu.RUnlock()
+	defer utm.queueMu.RUnlock()
+	defer utm.statusMu.RUnlock()
+
+	snapshot := &ManagerSnapshot{
+		Version: snapshotVersion,
+		TakenAt: utm.now(),
+		Session: *utm.session,
+		Status:  *utm.sessionStatus,
+	}
+	// The current file's error does not survive JSON, and no file is being sent once restored
+	snapshot.Status.CurrentFile = nil
+	snapshot.Status.ActiveFiles = 0
+
+	for _, node := range utm.structure.GetAllFiles() {
+		file := SnapshotFile{
+			Path:   node.Path,
+			LinkTo: node.LinkTo,
+			Node:   *node,
+			State:  snapshotPending,
+		}
+		file.Node.Children = nil
+		switch {
+		case utm.completedFiles[node.Path]:
+			file.State = snapshotCompleted
+		case utm.failedFiles[node.Path]:
+			file.State = snapshotFailed
+		}
+		if sent, ok := utm.sentChunks[node.Path]; ok {
+			file.SentChunks = sent.Sequences()
+		}
+		snapshot.Files = append(snapshot.Files, file)
+	}
+	sort.Slice(snapshot.Files, func(i, j int) bool { return snapshot.Files[i].Path < snapshot.Files[j].Path })
+	return snapshot
+}
+
+// Marshal encodes the state of the manager as JSON, to be restored with UnmarshalUnifiedTransferManager
+func (utm *UnifiedTransferManager) Marshal() ([]byte, error) {
+	data, err := json.Marshal(utm.Snapshot())
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal transfer manager: %w", err)
+	}
+	return data, nil
+}
+
+// UnmarshalUnifiedTransferManager rebuilds a manager from the output of Marshal
+func UnmarshalUnifiedTransferManager(data []byte, config *TransferConfig) (*UnifiedTransferManager, error) {
+	var snapshot ManagerSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidSnapshot, err)
+	}
+	return RestoreUnifiedTransferManager(&snapshot, config)
+}
+
+// RestoreUnifiedTransferManager creates a manager in the state of snapshot. Its files are
+// reopened, so they must still exist; pending streams cannot be read again and are refused.
+func RestoreUnifiedTransferManager(snapshot *ManagerSnapshot, config *TransferConfig) (*UnifiedTransferManager, error) {
+	if snapshot.Version != snapshotVersion {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrInvalidSnapshot, snapshot.Version)
+	}
+
+	for _, file := range snapshot.Files {
+		switch file.State {
+		case snapshotPending:
+			if file.Node.IsStream() {
+				return nil, fmt.Errorf("%w: stream %s cannot be read again", ErrInvalidSnapshot, file.Path)
+			}
+		case snapshotCompleted, snapshotFailed:
+		default:
+			return nil, fmt.Errorf("%w: file %s has unknown state %q", ErrInvalidSnapshot, file.Path, file.State)
+		}
+	}
+
+	utm := NewUnifiedTransferManagerWithConfig(snapshot.Session.ServiceID, config)
+	session := snapshot.Session
+	utm.session = &session
+
+	for _, file := range snapshot.Files {
+		node := file.Node
+		node.Path = file.Path
+		node.LinkTo = file.LinkTo
+		if err := utm.addSingleFile(&node); err != nil {
+			utm.Close()
+			utm.Shutdown()
+			return nil, fmt.Errorf("failed to restore %s: %w", file.Path, err)
+		}
+	}
+
+	utm.filesMu.Lock()
+	utm.queueMu.Lock()
+	utm.statusMu.Lock()
+	defer utm.filesMu.Unlock()
+	defer utm.queueMu.Unlock()
+	defer utm.statusMu.Unlock()
+
+	for _, file := range snapshot.Files {
+		switch file.State {
+		case snapshotCompleted:
+			utm.moveFileInQueue(file.Path, FileQueueStatePending, FileQueueStateCompleted)
+		case snapshotFailed:
+			utm.moveFileInQueue(file.Path, FileQueueStatePending, FileQueueStateFailed)
+		}
+		if len(file.SentChunks) > 0 {
+			sent := NewChunkSet(file.SentChunks)
+			utm.sentChunks[file.Path] = &sent
+		}
+	}
+
+	status := snapshot.Status
+	status.CurrentFile = nil
+	status.ActiveFiles = 0
+	*utm.sessionStatus = status
+	utm.updateSessionTotals()
+	utm.sessionStatus.OverallProgress = utm.sessionStatus.GetSessionProgressPercentage()
+	return utm, nil
+}
+
+// RecordChunk records that chunk seq of a file was sent, for snapshots
+func (utm *UnifiedTransferManager) RecordChunk(filePath string, seq uint32) {
+	utm.filesMu.Lock()
+	defer utm.filesMu.Unlock()
+
+	sent, ok := utm.sentChunks[filePath]
+	if !ok {
+		sent = &ChunkSet{}
+		utm.sentChunks[filePath] = sent
+	}
+	sent.Add(seq)
+}
+
+// SentChunks returns the sequence numbers of the chunks of a file recorded as sent, in order
+func (utm *UnifiedTransferManager) SentChunks(filePath string) []uint32 {
+	utm.filesMu.RLock()
+	defer utm.filesMu.RUnlock()
+
+	sent, ok := utm.sentChunks[filePath]
+	if !ok {
+		return nil
+	}
+	return sent.Sequences()
+}
@@ -0,0 +1,116 @@
+package transfer
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rescp17/lanFileSharer/pkg/fileInfo"
+)
+
+func addSnapshotFiles(t *testing.T, manager *UnifiedTransferManager, names ...string) []string {
+	dir := t.TempDir()
+	paths := make([]string, 0, len(names))
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte("content of "+name), 0644))
+		node, err := fileInfo.CreateNode(path)
+		require.NoError(t, err)
+		require.NoError(t, manager.AddFile(&node))
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+func TestUnifiedTransferManager_MarshalRestoresState(t *testing.T) {
+	manager := NewUnifiedTransferManager("test-snapshot")
+	defer manager.Shutdown()
+	defer manager.Close()
+	manager.SetTransport(NewQueueTransport())
+
+	paths := addSnapshotFiles(t, manager, "done.txt", "broken.txt", "pending.txt")
+	done, broken, pending := paths[0], paths[1], paths[2]
+
+	require.NoError(t, manager.StartTransfer(done))
+	manager.RecordChunk(done, 0)
+	require.NoError(t, manager.CompleteTransfer(done))
+
+	require.NoError(t, manager.StartTransfer(broken))
+	require.NoError(t, manager.FailTransfer(broken, fmt.Errorf("%w: bad data", ErrChecksumMismatch)))
+
+	// The file being sent at the time of the crash
+	require.NoError(t, manager.StartTransfer(pending))
+	manager.RecordChunk(pending, 0)
+	manager.RecordChunk(pending, 2)
+
+	data, err := manager.Marshal()
+	require.NoError(t, err)
+
+	restored, err := UnmarshalUnifiedTransferManager(data, DefaultTransferConfig())
+	require.NoError(t, err)
+	defer restored.Shutdown()
+	defer restored.Close()
+
+	pendingCount, completedCount, failedCount := restored.GetQueueStatus()
+	assert.Equal(t, []int{1, 1, 1}, []int{pendingCount, completedCount, failedCount})
+
+	next, ok := restored.GetNextPendingFile()
+	require.True(t, ok)
+	assert.Equal(t, pending, next.Path, "the interrupted file should be sent again")
+	assert.Equal(t, []uint32{0, 2}, restored.SentChunks(pending))
+	assert.Equal(t, []uint32{0}, restored.SentChunks(done))
+
+	original, status := manager.GetSessionStatus(), restored.GetSessionStatus()
+	assert.Equal(t, original.SessionID, status.SessionID)
+	assert.Equal(t, original.StartTime.UTC(), status.StartTime.UTC())
+	assert.Equal(t, 3, status.TotalFiles)
+	assert.Equal(t, 1, status.CompletedFiles)
+	assert.Equal(t, 1, status.FailedFiles)
+	assert.Equal(t, original.BytesCompleted, status.BytesCompleted)
+	assert.Nil(t, status.CurrentFile, "no file is being sent after a restore")
+
+	_, ok = restored.GetChunker(pending)
+	assert.True(t, ok, "restored files should be reopened")
+}
+
+func TestUnmarshalUnifiedTransferManager_Invalid(t *testing.T) {
+	manager := NewUnifiedTransferManager("test-snapshot-invalid")
+	defer manager.Shutdown()
+	defer manager.Close()
+	paths := addSnapshotFiles(t, manager, "gone.txt")
+
+	snapshot := manager.Snapshot()
+	require.Len(t, snapshot.Files, 1)
+
+	t.Run("not_json", func(t *testing.T) {
+		_, err := UnmarshalUnifiedTransferManager([]byte("{"), DefaultTransferConfig())
+		assert.ErrorIs(t, err, ErrInvalidSnapshot)
+	})
+
+	t.Run("unknown_version", func(t *testing.T) {
+		future := *snapshot
+		future.Version = snapshotVersion + 1
+		data, err := json.Marshal(future)
+		require.NoError(t, err)
+		_, err = UnmarshalUnifiedTransferManager(data, DefaultTransferConfig())
+		assert.ErrorIs(t, err, ErrInvalidSnapshot)
+	})
+
+	t.Run("pending_stream", func(t *testing.T) {
+		stream := *snapshot
+		stream.Files = []SnapshotFile{{Path: fileInfo.StdinPath, Node: fileInfo.FileNode{Name: "stdin", Size: fileInfo.UnknownSize}, State: "pending"}}
+		_, err := RestoreUnifiedTransferManager(&stream, DefaultTransferConfig())
+		assert.ErrorIs(t, err, ErrInvalidSnapshot)
+	})
+
+	t.Run("missing_file", func(t *testing.T) {
+		require.NoError(t, os.Remove(paths[0]))
+		_, err := RestoreUnifiedTransferManager(snapshot, DefaultTransferConfig())
+		assert.Error(t, err)
+	})
+}
@@ -2,8 +2,10 @@ package transfer
 
 import (
 	"errors"
-	"time"
+	"fmt"
+	"slices"
 	"strings"
+	"time"
 )
 
 // This file defines data structures for transfer STATUS MANAGEMENT.
@@ -105,6 +107,8 @@ type TransferStatus struct {
 	StartTime      time.Time  `json:"start_time"`
 	LastUpdateTime time.Time  `json:"last_update_time"`
 	CompletionTime *time.Time `json:"completion_time,omitempty"`
+	// Phases is where the time of the transfer went, see TransferPhase
+	Phases PhaseTimings `json:"phases"`
 
 	// Error handling
 	LastError  error `json:"last_error,omitempty"`
@@ -115,6 +119,20 @@ type TransferStatus struct {
 	FileSize     int64  `json:"file_size"`
 	FileChecksum string `json:"file_checksum"`
 	Priority     int    `json:"priority"`
+
+	clock Clock // Tells the time of updates; nil uses the wall clock
+}
+
+// SetClock makes the status tell the time of updates, and so its rate and ETA, by clock
+func (ts *TransferStatus) SetClock(clock Clock) {
+	ts.clock = clock
+}
+
+func (ts *TransferStatus) now() time.Time {
+	if ts.clock == nil {
+		return time.Now()
+	}
+	return ts.clock.Now()
 }
 
 // GetProgressPercentage calculates the completion percentage (0-100)
@@ -143,7 +161,7 @@ func (ts *TransferStatus) IsComplete() bool {
 func (ts *TransferStatus) UpdateProgress(bytesSent int64, chunksSent int) {
 	ts.BytesSent = bytesSent
 	ts.ChunksSent = chunksSent
-	ts.LastUpdateTime = time.Now()
+	ts.LastUpdateTime = ts.now()
 
 	// Recalculate transfer rate and ETA
 	ts.calculateMetrics()
@@ -155,7 +173,7 @@ func (ts *TransferStatus) calculateMetrics() {
 		return
 	}
 
-	elapsed := time.Since(ts.StartTime)
+	elapsed := ts.now().Sub(ts.StartTime)
 	if elapsed.Seconds() > 0 {
 		ts.TransferRate = float64(ts.BytesSent) / elapsed.Seconds()
 
@@ -233,13 +251,27 @@ func (ss StatusSessionState) String() string {
 	}
 }
 
+// IsTerminal returns true if the session is finished (completed, failed, or canceled)
+func (ss StatusSessionState) IsTerminal() bool {
+	return ss == StatusSessionStateCompleted || ss == StatusSessionStateFailed || ss == StatusSessionStateCancelled
+}
+
 // RetryPolicy defines the retry behavior for failed transfers
 type RetryPolicy struct {
 	MaxRetries      int           `json:"max_retries"`
 	InitialDelay    time.Duration `json:"initial_delay"`
 	BackoffFactor   float64       `json:"backoff_factor"`
 	MaxDelay        time.Duration `json:"max_delay"`
-	RetryableErrors []string      `json:"retryable_errors"` // Error message patterns
+	RetryableErrors []string      `json:"retryable_errors"` // Message patterns for errors without a type
+	// RetryableClasses are the error codes of the taxonomy that are retried, see ErrorCode.
+	// Nil retries the transient classes.
+	RetryableClasses []string `json:"retryable_classes,omitempty"`
+}
+
+// DefaultRetryableClasses returns the error classes retried by default: those that may
+// succeed when tried again
+func DefaultRetryableClasses() []string {
+	return []string{"peer_unreachable", "connection_lost", "timeout"}
 }
 
 // DefaultRetryPolicy returns a sensible default retry policy
@@ -255,9 +287,48 @@ func DefaultRetryPolicy() *RetryPolicy {
 			"network unreachable",
 			"connection reset",
 		},
+		RetryableClasses: DefaultRetryableClasses(),
 	}
 }
 
+// Validate checks that the policy describes a usable retry schedule
+func (rp *RetryPolicy) Validate() error {
+	if rp.MaxRetries < 0 {
+		return fmt.Errorf("%w: max retries cannot be negative", ErrInvalidConfiguration)
+	}
+	if rp.InitialDelay <= 0 {
+		return fmt.Errorf("%w: initial retry delay must be positive", ErrInvalidConfiguration)
+	}
+	if rp.BackoffFactor < 1 {
+		return fmt.Errorf("%w: backoff factor must be at least 1", ErrInvalidConfiguration)
+	}
+	if rp.MaxDelay < rp.InitialDelay {
+		return fmt.Errorf("%w: max retry delay cannot be less than the initial delay", ErrInvalidConfiguration)
+	}
+	for _, class := range rp.RetryableClasses {
+		if !IsErrorCode(class) {
+			return fmt.Errorf("%w: unknown retryable error class %q", ErrInvalidConfiguration, class)
+		}
+	}
+	return nil
+}
+
+// String summarizes the policy for display, e.g. "3 retries, 1s ×2 up to 30s on timeout"
+func (rp *RetryPolicy) String() string {
+	if rp.MaxRetries == 0 {
+		return "no retries"
+	}
+	classes := rp.RetryableClasses
+	if classes == nil {
+		classes = DefaultRetryableClasses()
+	}
+	on := "nothing"
+	if len(classes) > 0 {
+		on = strings.Join(classes, ", ")
+	}
+	return fmt.Sprintf("%d retries, %s ×%g up to %s on %s", rp.MaxRetries, rp.InitialDelay, rp.BackoffFactor, rp.MaxDelay, on)
+}
+
 // GetRetryDelay calculates the delay before the next retry attempt
 func (rp *RetryPolicy) GetRetryDelay(retryCount int) time.Duration {
 	if retryCount <= 0 {
@@ -279,6 +350,12 @@ func (rp *RetryPolicy) IsRetryable(err error) bool {
 	if err == nil {
 		return false
 	}
+	if code := ErrorCode(err); code != "" {
+		if rp.RetryableClasses == nil {
+			return IsTransient(err)
+		}
+		return slices.Contains(rp.RetryableClasses, code)
+	}
 
 	errMsg := strings.ToLower(err.Error())
 	for _, pattern := range rp.RetryableErrors {
@@ -335,16 +412,27 @@ type SessionTransferStatus struct {
 	BytesCompleted  int64   `json:"bytes_completed"`
 	OverallProgress float64 `json:"overall_progress"` // 0-100 percentage
 
-	// Current file being transferred
+	// Current file being transferred, the most recently started of the files in flight
 	CurrentFile *TransferStatus `json:"current_file,omitempty"`
+	// ActiveFiles is the number of files started and not yet finished; pausing, resuming or
+	// cancelling the session moves all of them with it
+	ActiveFiles int `json:"active_files"`
 
 	// Session timing
 	StartTime      time.Time  `json:"start_time"`
 	LastUpdateTime time.Time  `json:"last_update_time"`
 	CompletionTime *time.Time `json:"completion_time,omitempty"`
+	// Phases sums the phase timings of the finished files; its queued time is the wait
+	// before the first file started
+	Phases PhaseTimings `json:"phases"`
 
 	// Session state
 	State StatusSessionState `json:"state"`
+
+	// Batch sessions have a child session for each item sent in them
+	ParentID string                   `json:"parent_id,omitempty"`
+	Name     string                   `json:"name,omitempty"` // Name of the batch item of a child session
+	Children []*SessionTransferStatus `json:"children,omitempty"`
 }
 
 // GetSessionProgressPercentage calculates the overall session progress percentage
@@ -382,6 +470,44 @@ func (sts *SessionTransferStatus) GetRemainingBytes() int64 {
 	return remaining
 }
 
+// EstimateRemaining estimates the time left from the remaining bytes at byteRate plus the
+// remaining files at the per-file overhead observed so far (opening, verifying and
+// acknowledging a file), which dominates sessions of many small files. It returns zero if
+// nothing is known yet.
+func EstimateRemaining(elapsed time.Duration, bytesDone, bytesLeft int64, filesDone, filesLeft int, byteRate float64) time.Duration {
+	if byteRate <= 0 && elapsed > 0 && bytesDone > 0 {
+		byteRate = float64(bytesDone) / elapsed.Seconds()
+	}
+	if byteRate <= 0 || (bytesLeft <= 0 && filesLeft <= 0) {
+		return 0
+	}
+
+	remaining := float64(max(bytesLeft, 0)) / byteRate
+	if filesDone > 0 && filesLeft > 0 {
+		overhead := (elapsed.Seconds() - float64(bytesDone)/byteRate) / float64(filesDone)
+		remaining += max(overhead, 0) * float64(filesLeft)
+	}
+	return time.Duration(remaining * float64(time.Second))
+}
+
+// FilesPerMinute returns how many files were finished per minute since the session started
+func (sts *SessionTransferStatus) FilesPerMinute(now time.Time) float64 {
+	elapsed := now.Sub(sts.StartTime)
+	if sts.StartTime.IsZero() || elapsed <= 0 {
+		return 0
+	}
+	return float64(sts.CompletedFiles) / elapsed.Minutes()
+}
+
+// EstimateRemaining estimates the time left in the session at byteRate, accounting for per-file overhead
+func (sts *SessionTransferStatus) EstimateRemaining(byteRate float64, now time.Time) time.Duration {
+	if sts.StartTime.IsZero() {
+		return 0
+	}
+	return EstimateRemaining(now.Sub(sts.StartTime), sts.BytesCompleted, sts.GetRemainingBytes(),
+		sts.CompletedFiles+sts.FailedFiles, sts.GetRemainingFiles(), byteRate)
+}
+
 // IsSessionComplete returns true if all files have been processed (completed or failed)
 func (sts *SessionTransferStatus) IsSessionComplete() bool {
 	return sts.CompletedFiles+sts.FailedFiles >= sts.TotalFiles
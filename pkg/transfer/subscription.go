@@ -0,0 +1,215 @@
+package transfer
+
+import (
+	"log/slog"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/google/uuid"
+)
+
+// DefaultSubscriptionBuffer is the buffer of a status subscription created with a size of zero or less
+const DefaultSubscriptionBuffer = 64
+
+// StatusFilter selects the files a status query or subscription covers. Empty fields
+// select everything.
+type StatusFilter struct {
+	Paths  []string        // Files, or directories whose files are selected
+	States []TransferState // States the file is in, or changed to
+	// Sessions also delivers the session's status changes to a subscription
+	Sessions bool
+}
+
+// matchesPath reports whether the file at path is selected by the filter's paths
+func (f StatusFilter) matchesPath(path string) bool {
+	if len(f.Paths) == 0 {
+		return true
+	}
+	for _, selected := range f.Paths {
+		if path == selected || strings.HasPrefix(path, selected+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesFile reports whether the filter selects the file at path in status
+func (f StatusFilter) matchesFile(path string, status *TransferStatus) bool {
+	if status == nil || !f.matchesPath(path) {
+		return false
+	}
+	return len(f.States) == 0 || slices.Contains(f.States, status.State)
+}
+
+// StatusUpdate is a status change delivered by a StatusSubscription, either of a file, with
+// FilePath and File set, or of the session, with Session set. The statuses are copies the
+// subscriber owns.
+type StatusUpdate struct {
+	FilePath   string
+	OldFile    *TransferStatus // Nil when the file had no status before
+	File       *TransferStatus
+	OldSession *SessionTransferStatus
+	Session    *SessionTransferStatus
+}
+
+// IsSession reports whether the update is a change of the session rather than of a file
+func (u StatusUpdate) IsSession() bool {
+	return u.Session != nil
+}
+
+// StatusSubscription receives the status changes selected by its filter on C until it is
+// closed. It is a StatusListener of its manager, so frontends can follow a transfer without
+// implementing one. Delivery never blocks the manager: a subscriber that falls behind loses
+// updates, which are counted in Dropped. Updates are delivered in the order they were
+// raised only if the manager's transport preserves it.
+type StatusSubscription struct {
+	id      string
+	utm     *UnifiedTransferManager
+	filter  StatusFilter
+	ch      chan StatusUpdate
+	dropped atomic.Uint64
+
+	mu     sync.Mutex // Serializes delivery with Close
+	closed bool
+}
+
+// SubscribeStatus returns a subscription to the status changes selected by filter.
+// buffer is the number of updates held for a slow subscriber.
+func (utm *UnifiedTransferManager) SubscribeStatus(buffer int, filter StatusFilter) *StatusSubscription {
+	if buffer <= 0 {
+		buffer = DefaultSubscriptionBuffer
+	}
+	sub := &StatusSubscription{
+		id:     uuid.New().String(),
+		utm:    utm,
+		filter: filter,
+		ch:     make(chan StatusUpdate, buffer),
+	}
+	utm.AddStatusListener(sub)
+	return sub
+}
+
+// QueryFileStatus returns the current status of every file selected by filter, sorted by path
+func (utm *UnifiedTransferManager) QueryFileStatus(filter StatusFilter) []*TransferStatus {
+	utm.queueMu.RLock()
+	defer utm.queueMu.RUnlock()
+
+	var statuses []*TransferStatus
+	for _, node := range utm.structure.GetAllFiles() {
+		if !filter.matchesPath(node.Path) {
+			continue
+		}
+		status, err := utm.GetFileStatus(node.Path)
+		if err != nil || !filter.matchesFile(node.Path, status) {
+			continue
+		}
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].FilePath < statuses[j].FilePath })
+	return statuses
+}
+
+// RemoveStatusListener removes the listener with id
+func (utm *UnifiedTransferManager) RemoveStatusListener(id string) {
+	utm.eventsMu.Lock()
+	defer utm.eventsMu.Unlock()
+
+	utm.listeners = slices.DeleteFunc(utm.listeners, func(l StatusListener) bool { return l.ID() == id })
+}
+
+// C returns the channel updates are delivered on; it is closed with the subscription
+func (s *StatusSubscription) C() <-chan StatusUpdate {
+	return s.ch
+}
+
+// Dropped returns how many updates were lost because the subscription's buffer was full
+func (s *StatusSubscription) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+// Close stops delivery and closes C
+func (s *StatusSubscription) Close() {
+	s.utm.RemoveStatusListener(s.id)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.closed {
+		s.closed = true
+		close(s.ch)
+	}
+}
+
+// ID returns the identifier of the subscription as a listener (implements StatusListener)
+func (s *StatusSubscription) ID() string {
+	return s.id
+}
+
+// OnFileStatusChanged delivers a selected file status change (implements StatusListener)
+func (s *StatusSubscription) OnFileStatusChanged(filePath string, oldStatus, newStatus *TransferStatus) {
+	if !s.filter.matchesFile(filePath, newStatus) {
+		return
+	}
+	update := StatusUpdate{FilePath: filePath, File: copyTransferStatus(newStatus), OldFile: copyTransferStatus(oldStatus)}
+	s.deliver(update)
+}
+
+// OnSessionStatusChanged delivers a session status change if the filter asks for them
+// (implements StatusListener)
+func (s *StatusSubscription) OnSessionStatusChanged(oldStatus, newStatus *SessionTransferStatus) {
+	if !s.filter.Sessions || newStatus == nil {
+		return
+	}
+	s.deliver(StatusUpdate{Session: copySessionStatus(newStatus), OldSession: copySessionStatus(oldStatus)})
+}
+
+func (s *StatusSubscription) deliver(update StatusUpdate) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	select {
+	case s.ch <- update:
+	default:
+		if s.dropped.Add(1) == 1 {
+			slog.Debug("Status subscriber is falling behind, dropping updates", "subscription", s.id)
+		}
+	}
+}
+
+// copyTransferStatus copies status, which listeners may receive shared with the manager
+func copyTransferStatus(status *TransferStatus) *TransferStatus {
+	if status == nil {
+		return nil
+	}
+	statusCopy := *status
+	if status.CompletionTime != nil {
+		completion := *status.CompletionTime
+		statusCopy.CompletionTime = &completion
+	}
+	return &statusCopy
+}
+
+// copySessionStatus deep copies status, which listeners may receive shared with the manager
+func copySessionStatus(status *SessionTransferStatus) *SessionTransferStatus {
+	if status == nil {
+		return nil
+	}
+	statusCopy := *status
+	statusCopy.CurrentFile = copyTransferStatus(status.CurrentFile)
+	if status.CompletionTime != nil {
+		completion := *status.CompletionTime
+		statusCopy.CompletionTime = &completion
+	}
+	if status.Children != nil {
+		statusCopy.Children = make([]*SessionTransferStatus, len(status.Children))
+		for i, child := range status.Children {
+			statusCopy.Children[i] = copySessionStatus(child)
+		}
+	}
+	return &statusCopy
+}
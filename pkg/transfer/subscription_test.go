@@ -0,0 +1,118 @@
+package transfer
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// drainUpdates returns the updates waiting on sub
+func drainUpdates(sub *StatusSubscription) []StatusUpdate {
+	var updates []StatusUpdate
+	for {
+		select {
+		case update := <-sub.C():
+			updates = append(updates, update)
+		default:
+			return updates
+		}
+	}
+}
+
+func TestStatusSubscription_FiltersUpdates(t *testing.T) {
+	manager := NewUnifiedTransferManager("test-subscription")
+	defer manager.Shutdown()
+	defer manager.Close()
+	transport := NewQueueTransport()
+	manager.SetTransport(transport)
+
+	paths := addSnapshotFiles(t, manager, "a.txt", "b.txt")
+	all := manager.SubscribeStatus(0, StatusFilter{Sessions: true})
+	defer all.Close()
+	onlyB := manager.SubscribeStatus(0, StatusFilter{Paths: []string{paths[1]}})
+	defer onlyB.Close()
+	completions := manager.SubscribeStatus(0, StatusFilter{States: []TransferState{TransferStateCompleted}})
+	defer completions.Close()
+
+	require.NoError(t, manager.StartTransfer(paths[0]))
+	require.NoError(t, manager.CompleteTransfer(paths[0]))
+	require.NoError(t, manager.StartTransfer(paths[1]))
+	require.NoError(t, manager.FailTransfer(paths[1], errors.New("file not found")))
+	transport.Flush()
+
+	updates := drainUpdates(all)
+	var files, sessions int
+	for _, update := range updates {
+		if update.IsSession() {
+			sessions++
+		} else {
+			files++
+		}
+	}
+	assert.Equal(t, 4, files)
+	assert.Positive(t, sessions)
+
+	bUpdates := drainUpdates(onlyB)
+	require.Len(t, bUpdates, 2)
+	for _, update := range bUpdates {
+		assert.Equal(t, paths[1], update.FilePath)
+		assert.False(t, update.IsSession())
+	}
+	assert.Equal(t, TransferStateFailed, bUpdates[1].File.State)
+
+	completed := drainUpdates(completions)
+	require.Len(t, completed, 1)
+	assert.Equal(t, paths[0], completed[0].FilePath)
+	assert.Equal(t, TransferStateActive, completed[0].OldFile.State)
+}
+
+func TestStatusSubscription_Close(t *testing.T) {
+	manager := NewUnifiedTransferManager("test-subscription-close")
+	defer manager.Shutdown()
+	defer manager.Close()
+	transport := NewQueueTransport()
+	manager.SetTransport(transport)
+
+	paths := addSnapshotFiles(t, manager, "a.txt")
+	sub := manager.SubscribeStatus(1, StatusFilter{})
+	require.NoError(t, manager.StartTransfer(paths[0]))
+	require.NoError(t, manager.UpdateProgress(paths[0], 3))
+	transport.Flush()
+	assert.Equal(t, uint64(1), sub.Dropped(), "the second update does not fit the buffer")
+
+	sub.Close()
+	sub.Close()
+	require.NoError(t, manager.CompleteTransfer(paths[0]))
+	transport.Flush()
+
+	update, ok := <-sub.C()
+	require.True(t, ok, "updates delivered before Close stay readable")
+	assert.Equal(t, TransferStateActive, update.File.State)
+	_, ok = <-sub.C()
+	assert.False(t, ok)
+}
+
+func TestUnifiedTransferManager_QueryFileStatus(t *testing.T) {
+	manager := NewUnifiedTransferManager("test-query")
+	defer manager.Shutdown()
+	defer manager.Close()
+	manager.SetTransport(NewQueueTransport())
+
+	paths := addSnapshotFiles(t, manager, "a.txt", "b.txt", "c.txt")
+	require.NoError(t, manager.StartTransfer(paths[0]))
+	require.NoError(t, manager.CompleteTransfer(paths[0]))
+	require.NoError(t, manager.StartTransfer(paths[1]))
+
+	statuses := manager.QueryFileStatus(StatusFilter{})
+	require.Len(t, statuses, 3)
+	assert.Equal(t, []TransferState{TransferStateCompleted, TransferStateActive, TransferStatePending},
+		[]TransferState{statuses[0].State, statuses[1].State, statuses[2].State})
+
+	pending := manager.QueryFileStatus(StatusFilter{States: []TransferState{TransferStatePending}})
+	require.Len(t, pending, 1)
+	assert.Equal(t, paths[2], pending[0].FilePath)
+
+	assert.Len(t, manager.QueryFileStatus(StatusFilter{Paths: []string{paths[1]}}), 1)
+}
@@ -0,0 +1,92 @@
+package transfer
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// ErrSystemSuspended ends a session that was running while this machine slept. The peer has
+// likely given up on the session meanwhile, so it is resumed in a new one like an idle session.
+var ErrSystemSuspended = fmt.Errorf("%w: system was suspended", ErrSessionIdle)
+
+const (
+	// suspendCheckInterval is how often a SuspendDetector compares the clocks while it watches
+	suspendCheckInterval = 2 * time.Second
+	// minSuspend is the smallest clock jump taken for a suspend rather than a busy scheduler
+	minSuspend = 5 * time.Second
+)
+
+// clockReading is the time on the wall clock and on the monotonic clock at one instant
+type clockReading struct {
+	wall time.Time
+	mono time.Duration
+}
+
+// SuspendDetector notices that this machine was suspended or hibernated. The monotonic clock
+// stops while the system sleeps but the wall clock does not, so after a wake-up the wall clock
+// has moved further than the monotonic one.
+type SuspendDetector struct {
+	read func() clockReading
+
+	mu   sync.Mutex
+	last clockReading
+}
+
+// NewSuspendDetector creates a SuspendDetector measuring from now
+func NewSuspendDetector() *SuspendDetector {
+	start := time.Now()
+	return newSuspendDetector(func() clockReading {
+		now := time.Now()
+		return clockReading{wall: now.Round(0), mono: now.Sub(start)}
+	})
+}
+
+func newSuspendDetector(read func() clockReading) *SuspendDetector {
+	return &SuspendDetector{read: read, last: read()}
+}
+
+// Check returns how long the system slept since the previous Check, zero if it did not.
+// Changes of the wall clock that move it back, like NTP corrections, are not suspends.
+func (d *SuspendDetector) Check() time.Duration {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	now := d.read()
+	slept := now.wall.Sub(d.last.wall) - (now.mono - d.last.mono)
+	d.last = now
+	if slept < minSuspend {
+		return 0
+	}
+	return slept
+}
+
+// Watch checks for suspends until ctx ends and reports each one it finds, with how long the
+// system slept, on the returned channel, which is closed once ctx ends
+func (d *SuspendDetector) Watch(ctx context.Context) <-chan time.Duration {
+	suspends := make(chan time.Duration, 1)
+	go func() {
+		defer close(suspends)
+		ticker := time.NewTicker(suspendCheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			slept := d.Check()
+			if slept == 0 {
+				continue
+			}
+			slog.Info("System woke from sleep", "slept", slept)
+			select {
+			case suspends <- slept:
+			default:
+				// The last suspend is still unread, which reports it as well
+			}
+		}
+	}()
+	return suspends
+}
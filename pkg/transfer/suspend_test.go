@@ -0,0 +1,38 @@
+package transfer
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSuspendDetector_Check(t *testing.T) {
+	wall := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	reading := clockReading{wall: wall}
+	detector := newSuspendDetector(func() clockReading { return reading })
+
+	advance := func(wallStep, monoStep time.Duration) {
+		reading.wall = reading.wall.Add(wallStep)
+		reading.mono += monoStep
+	}
+
+	advance(2*time.Second, 2*time.Second)
+	assert.Zero(t, detector.Check(), "both clocks moved alike")
+
+	advance(3*time.Second, 2*time.Second)
+	assert.Zero(t, detector.Check(), "a late tick is not a suspend")
+
+	advance(time.Hour, 2*time.Second)
+	assert.Equal(t, time.Hour-2*time.Second, detector.Check())
+	assert.Zero(t, detector.Check(), "a suspend is reported once")
+
+	advance(-time.Hour, 2*time.Second)
+	assert.Zero(t, detector.Check(), "setting the clock back is not a suspend")
+}
+
+func TestErrSystemSuspended(t *testing.T) {
+	assert.True(t, errors.Is(ErrSystemSuspended, ErrSessionIdle), "suspended sessions are resumed like idle ones")
+	assert.True(t, errors.Is(ErrSystemSuspended, ErrConnectionLost))
+}
@@ -0,0 +1,127 @@
+package transfer
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// TransferPhase is a step in the transfer of a file that takes time of its own
+type TransferPhase string
+
+const (
+	// PhaseQueued is the wait behind the other files of the session before a file starts
+	PhaseQueued TransferPhase = "queued"
+	// PhaseHashing is the time spent reading the file and hashing its chunks
+	PhaseHashing TransferPhase = "hashing"
+	// PhaseTransferring is the time spent sending the chunks, including waits on the rate
+	// limit and full channels. Files striped over several channels read and hash their
+	// chunks while sending, so their reading counts here too.
+	PhaseTransferring TransferPhase = "transferring"
+	// PhaseVerifying is the wait for the receiver to verify the file after its last chunk
+	PhaseVerifying TransferPhase = "verifying"
+	// PhaseWriting is the receiver's time writing the file to disk. It mostly overlaps
+	// transferring, so a writing time close to it points at the receiver's disk.
+	PhaseWriting TransferPhase = "writing"
+)
+
+// TransferPhases lists the phases in the order a file goes through them
+var TransferPhases = []TransferPhase{PhaseQueued, PhaseHashing, PhaseTransferring, PhaseVerifying, PhaseWriting}
+
+// PhaseTimings is the time a file, or all files of a session, spent in each phase
+type PhaseTimings struct {
+	Queued       time.Duration `json:"queued,omitempty"`
+	Hashing      time.Duration `json:"hashing,omitempty"`
+	Transferring time.Duration `json:"transferring,omitempty"`
+	Verifying    time.Duration `json:"verifying,omitempty"`
+	Writing      time.Duration `json:"writing,omitempty"`
+}
+
+// field returns the duration of phase, or nil for an unknown phase
+func (pt *PhaseTimings) field(phase TransferPhase) *time.Duration {
+	switch phase {
+	case PhaseQueued:
+		return &pt.Queued
+	case PhaseHashing:
+		return &pt.Hashing
+	case PhaseTransferring:
+		return &pt.Transferring
+	case PhaseVerifying:
+		return &pt.Verifying
+	case PhaseWriting:
+		return &pt.Writing
+	default:
+		return nil
+	}
+}
+
+// Add adds d to the time spent in phase; unknown phases are ignored
+func (pt *PhaseTimings) Add(phase TransferPhase, d time.Duration) {
+	if field := pt.field(phase); field != nil && d > 0 {
+		*field += d
+	}
+}
+
+// Get returns the time spent in phase
+func (pt PhaseTimings) Get(phase TransferPhase) time.Duration {
+	if field := pt.field(phase); field != nil {
+		return *field
+	}
+	return 0
+}
+
+// Merge adds the times of other, keeping the queued time unless it has none yet
+func (pt *PhaseTimings) Merge(other PhaseTimings) {
+	for _, phase := range TransferPhases {
+		if phase == PhaseQueued && pt.Queued > 0 {
+			continue
+		}
+		pt.Add(phase, other.Get(phase))
+	}
+}
+
+// IsZero reports whether no time was recorded
+func (pt PhaseTimings) IsZero() bool {
+	return pt == PhaseTimings{}
+}
+
+// Slowest returns the phase that took the longest, or "" if none took any time.
+// Writing overlaps transferring, so it is only the slowest when it took longer.
+func (pt PhaseTimings) Slowest() TransferPhase {
+	var slowest TransferPhase
+	var longest time.Duration
+	for _, phase := range TransferPhases {
+		if d := pt.Get(phase); d > longest {
+			slowest, longest = phase, d
+		}
+	}
+	return slowest
+}
+
+// Map returns the phases that took time by name, the form carried by events
+func (pt PhaseTimings) Map() map[string]time.Duration {
+	if pt.IsZero() {
+		return nil
+	}
+	phases := make(map[string]time.Duration, len(TransferPhases))
+	for _, phase := range TransferPhases {
+		if d := pt.Get(phase); d > 0 {
+			phases[string(phase)] = d
+		}
+	}
+	return phases
+}
+
+// String lists the phases that took time, e.g. "queued 2s, transferring 1m30s, verifying 4s"
+func (pt PhaseTimings) String() string {
+	parts := make([]string, 0, len(TransferPhases))
+	for _, phase := range TransferPhases {
+		if d := pt.Get(phase); d > 0 {
+			parts = append(parts, fmt.Sprintf("%s %s", phase, d.Round(time.Millisecond)))
+		}
+	}
+	if len(parts) == 0 {
+		return "no time recorded"
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,36 @@
+package transfer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPhaseTimings_AddAndSlowest(t *testing.T) {
+	var timings PhaseTimings
+	assert.True(t, timings.IsZero())
+	assert.Equal(t, TransferPhase(""), timings.Slowest())
+	assert.Equal(t, "no time recorded", timings.String())
+
+	timings.Add(PhaseHashing, 2*time.Second)
+	timings.Add(PhaseTransferring, 90*time.Second)
+	timings.Add(PhaseHashing, time.Second)
+	timings.Add(PhaseVerifying, -time.Second)
+	timings.Add(TransferPhase("unknown"), time.Hour)
+
+	assert.Equal(t, 3*time.Second, timings.Get(PhaseHashing))
+	assert.Zero(t, timings.Get(PhaseVerifying), "negative times are ignored")
+	assert.Equal(t, PhaseTransferring, timings.Slowest())
+	assert.Equal(t, "hashing 3s, transferring 1m30s", timings.String())
+	assert.Equal(t, map[string]time.Duration{"hashing": 3 * time.Second, "transferring": 90 * time.Second}, timings.Map())
+}
+
+func TestPhaseTimings_MergeKeepsFirstQueued(t *testing.T) {
+	session := PhaseTimings{Queued: time.Second}
+	session.Merge(PhaseTimings{Queued: time.Minute, Writing: 2 * time.Second})
+	session.Merge(PhaseTimings{Writing: 3 * time.Second})
+
+	assert.Equal(t, time.Second, session.Queued, "the wait before the first file is kept")
+	assert.Equal(t, 5*time.Second, session.Writing)
+}
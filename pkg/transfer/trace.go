@@ -0,0 +1,134 @@
+package transfer
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// TraceFormatVersion is the version of the trace format, see docs/PROTOCOL_TRACE.md. It
+// changes only when fields change meaning or go away; new fields may appear at any time.
+const TraceFormatVersion = 1
+
+// Directions of a TraceRecord, from the side writing the trace
+const (
+	TraceOut = "out"
+	TraceIn  = "in"
+)
+
+// Layers of a TraceRecord
+const (
+	TraceLayerTrace     = "trace"     // Records of the trace itself
+	TraceLayerSignaling = "signaling" // HTTP requests and server-sent events before the connection
+	TraceLayerData      = "data"      // ChunkMessages on the data channels
+)
+
+// TraceRecord is one line of a protocol trace. Message contents are left out, sizes are in
+// bytes: Size as sent or received, Payload of the message's Data.
+type TraceRecord struct {
+	Seq        uint64    `json:"seq"` // Position in the trace, from 1
+	Time       time.Time `json:"time"`
+	Dir        string    `json:"dir,omitempty"`
+	Layer      string    `json:"layer"`
+	Channel    string    `json:"channel,omitempty"` // Data channel label, or HTTP path of signaling
+	Type       string    `json:"type"`
+	Size       int       `json:"size"`
+	Payload    int       `json:"payload,omitempty"`
+	Session    string    `json:"session,omitempty"`
+	File       string    `json:"file,omitempty"`
+	SequenceNo *uint32   `json:"sequence_no,omitempty"` // Set for chunk_data
+	Offset     int64     `json:"offset,omitempty"`
+	TotalSize  int64     `json:"total_size,omitempty"`
+	Last       bool      `json:"last,omitempty"`
+	ErrorCode  string    `json:"error_code,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	Version    int       `json:"version,omitempty"` // Set in the trace_start record
+}
+
+// ProtocolTracer writes a record of every signaling and data channel message as
+// newline-delimited JSON. It is safe for concurrent use.
+type ProtocolTracer struct {
+	mu      sync.Mutex
+	encoder *json.Encoder
+	seq     uint64
+	failed  bool // Set once a write failed, so the failure is logged once
+}
+
+// NewProtocolTracer returns a tracer writing to w, starting with a trace_start record
+func NewProtocolTracer(w io.Writer) *ProtocolTracer {
+	t := &ProtocolTracer{encoder: json.NewEncoder(w)}
+	t.write(TraceRecord{Layer: TraceLayerTrace, Type: "trace_start", Version: TraceFormatVersion})
+	return t
+}
+
+func (t *ProtocolTracer) write(record TraceRecord) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.seq++
+	record.Seq = t.seq
+	if record.Time.IsZero() {
+		record.Time = time.Now()
+	}
+	if err := t.encoder.Encode(record); err != nil && !t.failed {
+		t.failed = true
+		slog.Warn("Failed to write protocol trace, later records may be missing", "error", err)
+	}
+}
+
+// protocolTracer is the tracer of the process, nil when tracing is off
+var protocolTracer atomic.Pointer[ProtocolTracer]
+
+// SetProtocolTracer makes t trace the protocol messages of every connection of the process;
+// nil turns tracing off
+func SetProtocolTracer(t *ProtocolTracer) {
+	protocolTracer.Store(t)
+}
+
+// Tracing reports whether protocol messages are traced, so callers can skip work only the trace needs
+func Tracing() bool {
+	return protocolTracer.Load() != nil
+}
+
+// TraceSignal traces a signaling message of kind, such as an HTTP request or a server-sent
+// event, exchanged on the HTTP path endpoint
+func TraceSignal(dir, endpoint, kind string, size int) {
+	t := protocolTracer.Load()
+	if t == nil {
+		return
+	}
+	t.write(TraceRecord{Dir: dir, Layer: TraceLayerSignaling, Channel: endpoint, Type: kind, Size: size})
+}
+
+// TraceMessage traces msg, sent or received on the data channel labeled channel as size bytes
+func TraceMessage(dir, channel string, msg *ChunkMessage, size int) {
+	t := protocolTracer.Load()
+	if t == nil {
+		return
+	}
+	record := TraceRecord{
+		Dir: dir, Layer: TraceLayerData, Channel: channel, Type: string(msg.Type), Size: size,
+		Payload: len(msg.Data), Session: msg.Session.SessionID, File: msg.FileID, Offset: msg.Offset,
+		TotalSize: msg.TotalSize, Last: msg.IsLast, ErrorCode: msg.ErrorCode, Error: msg.ErrorMessage,
+	}
+	if msg.Type == ChunkData {
+		seq := msg.SequenceNo
+		record.SequenceNo = &seq
+	}
+	t.write(record)
+}
+
+// TraceWire traces the serialized message data, decoding it only while tracing. Data that
+// does not decode is traced with the type "undecodable".
+func TraceWire(dir, channel string, data []byte) {
+	if !Tracing() {
+		return
+	}
+	msg, err := NewJSONSerializer().Unmarshal(data)
+	if err != nil {
+		msg = &ChunkMessage{Type: "undecodable", ErrorMessage: err.Error()}
+	}
+	TraceMessage(dir, channel, msg, len(data))
+}
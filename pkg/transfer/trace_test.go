@@ -0,0 +1,64 @@
+package transfer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProtocolTracer_RecordsMessages(t *testing.T) {
+	var out bytes.Buffer
+	SetProtocolTracer(NewProtocolTracer(&out))
+	defer SetProtocolTracer(nil)
+
+	TraceSignal(TraceOut, "/ask", "ask", 512)
+	TraceMessage(TraceOut, "file-transfer", &ChunkMessage{
+		Type: ChunkData, FileID: "a.txt", SequenceNo: 0, Offset: 0, Data: make([]byte, 10), TotalSize: 10, IsLast: true,
+	}, 64)
+	ack, err := NewJSONSerializer().Marshal(&ChunkMessage{Type: FileAck, FileID: "a.txt", ErrorCode: "checksum_mismatch"})
+	require.NoError(t, err)
+	TraceWire(TraceIn, "control", ack)
+	TraceWire(TraceIn, "control", []byte("not json"))
+
+	var records []TraceRecord
+	scanner := bufio.NewScanner(&out)
+	for scanner.Scan() {
+		var record TraceRecord
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &record), "every line is one JSON object")
+		records = append(records, record)
+	}
+	require.Len(t, records, 5)
+
+	assert.Equal(t, "trace_start", records[0].Type)
+	assert.Equal(t, TraceFormatVersion, records[0].Version)
+	for i, record := range records {
+		assert.Equal(t, uint64(i+1), record.Seq)
+		assert.False(t, record.Time.IsZero())
+	}
+
+	assert.Equal(t, TraceRecord{Seq: 2, Time: records[1].Time, Dir: TraceOut, Layer: TraceLayerSignaling, Channel: "/ask", Type: "ask", Size: 512}, records[1])
+
+	chunk := records[2]
+	assert.Equal(t, TraceLayerData, chunk.Layer)
+	require.NotNil(t, chunk.SequenceNo, "chunks carry their sequence number, even the first")
+	assert.Equal(t, uint32(0), *chunk.SequenceNo)
+	assert.Equal(t, 10, chunk.Payload)
+	assert.Equal(t, 64, chunk.Size)
+	assert.True(t, chunk.Last)
+
+	assert.Equal(t, string(FileAck), records[3].Type)
+	assert.Equal(t, len(ack), records[3].Size)
+	assert.Equal(t, "checksum_mismatch", records[3].ErrorCode)
+	assert.Nil(t, records[3].SequenceNo)
+
+	assert.Equal(t, "undecodable", records[4].Type)
+}
+
+func TestProtocolTracer_OffByDefault(t *testing.T) {
+	assert.False(t, Tracing())
+	TraceSignal(TraceOut, "/ask", "ask", 1) // Must not panic without a tracer
+}
@@ -0,0 +1,98 @@
+package transfer
+
+import "sync"
+
+// Transport carries the transfer manager's status notifications to its listeners and event
+// bus. Notifications are raised while the manager holds its locks, so a transport must not
+// deliver them on the calling goroutine.
+type Transport interface {
+	Dispatch(deliver func())
+}
+
+// SerialTransport delivers notifications in the order they were raised, from one goroutine
+// at a time, so listeners never see a file's progress after it finished
+type SerialTransport struct {
+	mu       sync.Mutex
+	queue    []func()
+	draining bool
+}
+
+// NewSerialTransport creates a serial transport
+func NewSerialTransport() *SerialTransport {
+	return &SerialTransport{}
+}
+
+// Dispatch queues deliver, starting a goroutine to drain the queue unless one is running
+func (s *SerialTransport) Dispatch(deliver func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.queue = append(s.queue, deliver)
+	if !s.draining {
+		s.draining = true
+		go s.drain()
+	}
+}
+
+// drain delivers queued notifications until the queue is empty
+func (s *SerialTransport) drain() {
+	for {
+		s.mu.Lock()
+		if len(s.queue) == 0 {
+			s.draining = false
+			s.mu.Unlock()
+			return
+		}
+		deliver := s.queue[0]
+		s.queue[0] = nil
+		s.queue = s.queue[1:]
+		s.mu.Unlock()
+
+		deliver()
+	}
+}
+
+// QueueTransport holds notifications until Flush delivers them, in the order they were
+// raised, so tests can check what listeners saw without waiting for goroutines
+type QueueTransport struct {
+	mu    sync.Mutex
+	queue []func()
+}
+
+// NewQueueTransport creates an empty queue transport
+func NewQueueTransport() *QueueTransport {
+	return &QueueTransport{}
+}
+
+// Dispatch queues deliver until the next Flush
+func (q *QueueTransport) Dispatch(deliver func()) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.queue = append(q.queue, deliver)
+}
+
+// Pending returns the number of notifications waiting to be delivered
+func (q *QueueTransport) Pending() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.queue)
+}
+
+// Flush delivers the queued notifications on the calling goroutine, including those raised
+// while delivering, and returns how many were delivered
+func (q *QueueTransport) Flush() int {
+	delivered := 0
+	for {
+		q.mu.Lock()
+		queue := q.queue
+		q.queue = nil
+		q.mu.Unlock()
+
+		if len(queue) == 0 {
+			return delivered
+		}
+		for _, deliver := range queue {
+			deliver()
+			delivered++
+		}
+	}
+}
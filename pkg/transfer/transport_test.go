@@ -0,0 +1,59 @@
+package transfer
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueueTransport_FlushDeliversInOrder(t *testing.T) {
+	transport := NewQueueTransport()
+
+	var delivered []int
+	transport.Dispatch(func() { delivered = append(delivered, 1) })
+	transport.Dispatch(func() {
+		delivered = append(delivered, 2)
+		// Notifications raised while delivering are delivered by the same flush
+		transport.Dispatch(func() { delivered = append(delivered, 3) })
+	})
+
+	assert.Empty(t, delivered, "nothing should be delivered before Flush")
+	assert.Equal(t, 2, transport.Pending())
+	assert.Equal(t, 3, transport.Flush())
+	assert.Equal(t, []int{1, 2, 3}, delivered)
+	assert.Zero(t, transport.Pending())
+	assert.Zero(t, transport.Flush())
+}
+
+func TestSerialTransport_DeliversInOrder(t *testing.T) {
+	transport := NewSerialTransport()
+
+	var (
+		mu        sync.Mutex
+		delivered []int
+		wg        sync.WaitGroup
+	)
+	const count = 1000
+	wg.Add(count)
+	for i := 0; i < count; i++ {
+		transport.Dispatch(func() {
+			defer wg.Done()
+			if i%100 == 0 {
+				// A slow listener holds up later notifications instead of being overtaken
+				time.Sleep(time.Millisecond)
+			}
+			mu.Lock()
+			delivered = append(delivered, i)
+			mu.Unlock()
+		})
+	}
+	wg.Wait()
+
+	require.Len(t, delivered, count)
+	for i, got := range delivered {
+		assert.Equal(t, i, got)
+	}
+}
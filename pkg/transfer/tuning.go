@@ -0,0 +1,83 @@
+package transfer
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// tuneTolerance is how much slower than the fastest chunk size a smaller one may be and still
+// be recommended, since smaller chunks lose less on a retry and need less memory
+const tuneTolerance = 0.05
+
+// DefaultTuneChunkSizes are the chunk sizes a tuning run measures
+var DefaultTuneChunkSizes = []int32{16 * 1024, 32 * 1024, DefaultChunkSize, 128 * 1024, MaxChunkSize}
+
+// ChunkSizeSample is the throughput measured for one chunk size
+type ChunkSizeSample struct {
+	ChunkSize   int32
+	MessageSize int           // Bytes of one encoded chunk message on the data channel
+	Bytes       int64         // Chunk data that made the round trip
+	Duration    time.Duration // From the first message sent until the last reply
+	Lost        int           // Messages without a reply
+	Skipped     bool          // The encoded message does not fit the largest message size
+}
+
+// Rate returns the chunk data throughput in bytes per second, or zero if nothing was measured
+func (s ChunkSizeSample) Rate() float64 {
+	if s.Skipped || s.Duration <= 0 {
+		return 0
+	}
+	return float64(s.Bytes) / s.Duration.Seconds()
+}
+
+// RecommendChunkSize returns the chunk size to send files in given the measured samples:
+// the fastest one without losses, or a smaller one within tuneTolerance of it. It returns
+// DefaultChunkSize and false if no sample is usable.
+func RecommendChunkSize(samples []ChunkSizeSample) (int32, bool) {
+	var best float64
+	for _, sample := range samples {
+		if sample.Lost == 0 {
+			best = max(best, sample.Rate())
+		}
+	}
+	if best == 0 {
+		return DefaultChunkSize, false
+	}
+
+	var recommended int32
+	for _, sample := range samples {
+		if sample.Lost > 0 || sample.Rate() < best*(1-tuneTolerance) {
+			continue
+		}
+		if recommended == 0 || sample.ChunkSize < recommended {
+			recommended = sample.ChunkSize
+		}
+	}
+	return recommended, true
+}
+
+// EncodedChunkSize returns the size of a chunk message carrying chunkSize bytes of data as
+// encoded by serializer, which is what the data channel has to carry per chunk
+func EncodedChunkSize(serializer MessageSerializer, chunkSize int32) (int, error) {
+	// A long path and hashes, so the estimate errs on the large side
+	path := filepath.Join(strings.Repeat("d", 64), strings.Repeat("f", 64))
+	data, err := serializer.Marshal(&ChunkMessage{
+		Type:         ChunkData,
+		Session:      *NewTransferSession("tune"),
+		FileID:       path,
+		FileName:     filepath.Base(path),
+		SequenceNo:   1 << 31,
+		Offset:       1 << 40,
+		Data:         make([]byte, chunkSize),
+		ChunkHash:    strings.Repeat("0", 64),
+		TotalSize:    1 << 40,
+		ExpectedHash: strings.Repeat("0", 64),
+		IsLast:       true,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode chunk of %d bytes: %w", chunkSize, err)
+	}
+	return len(data), nil
+}
@@ -0,0 +1,54 @@
+package transfer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecommendChunkSize(t *testing.T) {
+	sample := func(chunkSize int32, mbPerSecond float64) ChunkSizeSample {
+		return ChunkSizeSample{ChunkSize: chunkSize, Bytes: int64(mbPerSecond * (1 << 20)), Duration: time.Second}
+	}
+
+	t.Run("fastest", func(t *testing.T) {
+		size, ok := RecommendChunkSize([]ChunkSizeSample{sample(16<<10, 20), sample(64<<10, 40), sample(256<<10, 30)})
+		require.True(t, ok)
+		assert.Equal(t, int32(64<<10), size)
+	})
+
+	t.Run("smaller within tolerance", func(t *testing.T) {
+		size, ok := RecommendChunkSize([]ChunkSizeSample{sample(64<<10, 39), sample(128<<10, 40)})
+		require.True(t, ok)
+		assert.Equal(t, int32(64<<10), size)
+	})
+
+	t.Run("lossy and skipped samples", func(t *testing.T) {
+		lossy := sample(256<<10, 80)
+		lossy.Lost = 1
+		skipped := sample(128<<10, 60)
+		skipped.Skipped = true
+		size, ok := RecommendChunkSize([]ChunkSizeSample{sample(32<<10, 30), skipped, lossy})
+		require.True(t, ok)
+		assert.Equal(t, int32(32<<10), size)
+	})
+
+	t.Run("nothing measured", func(t *testing.T) {
+		size, ok := RecommendChunkSize([]ChunkSizeSample{{ChunkSize: 16 << 10, Lost: 3}})
+		assert.False(t, ok)
+		assert.Equal(t, int32(DefaultChunkSize), size)
+	})
+}
+
+func TestEncodedChunkSize(t *testing.T) {
+	small, err := EncodedChunkSize(NewJSONSerializer(), MinChunkSize)
+	require.NoError(t, err)
+	large, err := EncodedChunkSize(NewJSONSerializer(), MaxChunkSize)
+	require.NoError(t, err)
+
+	assert.Greater(t, small, MinChunkSize*4/3, "the data is base64 encoded")
+	assert.Greater(t, large, MaxChunkSize*4/3)
+	assert.Equal(t, large-small, (MaxChunkSize-MinChunkSize)*4/3, "the envelope does not depend on the chunk size")
+}
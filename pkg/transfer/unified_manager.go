@@ -1,10 +1,14 @@
 package transfer
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"sort"
 	"sync"
 	"time"
 
+	"github.com/rescp17/lanFileSharer/pkg/events"
 	"github.com/rescp17/lanFileSharer/pkg/fileInfo"
 )
 
@@ -17,26 +21,38 @@ type UnifiedTransferManager struct {
 	structure *FileStructureManager // File structure organization
 
 	// Chunking management
-	chunkers map[string]*Chunker // File path -> Chunker
-	filesMu  sync.RWMutex
+	chunkers   map[string]*Chunker  // File path -> Chunker
+	sentChunks map[string]*ChunkSet // File path -> chunks recorded as sent
+	filesMu    sync.RWMutex
 
 	// Transfer queue management - using maps for O(1) operations
 	pendingFiles   map[string]bool // Set of pending file paths
 	completedFiles map[string]bool // Set of completed file paths
 	failedFiles    map[string]bool // Set of failed file paths
+	batch          []BatchItem     // Items of a batch session, see SetBatch
+	cancelledItems map[string]bool // IDs of the cancelled batch items
 	queueMu        sync.RWMutex
 
 	// Session status tracking
 	sessionStatus *SessionTransferStatus
+	activeFiles   map[string]*TransferStatus // Files started and not yet finished, including CurrentFile
+	fileTimings   map[string]PhaseTimings    // Phase timings of the finished files
+	finished      map[string]TransferStatus  // Final status of the finished files, kept by Close
+	stateChanged  chan struct{}              // Closed and replaced when the session is paused, resumed or cancelled
 	statusMu      sync.RWMutex
 
 	// Event system
 	listeners []StatusListener
+	eventBus  *events.Bus // Optional bus status changes are published to
 	eventsMu  sync.RWMutex
 
 	// Error handling and retry system
 	errorHandler   ErrorHandler
 	retryScheduler *RetryScheduler
+
+	// Time and delivery of status notifications, replaced by tests
+	clock     Clock
+	transport Transport
 }
 
 // ManagedFile is no longer needed since we use FileStructureManager
@@ -60,6 +76,7 @@ func NewUnifiedTransferManager(serviceID string) *UnifiedTransferManager {
 // NewUnifiedTransferManagerWithConfig creates a manager with custom config
 func NewUnifiedTransferManagerWithConfig(serviceID string, config *TransferConfig) *UnifiedTransferManager {
 	session := NewTransferSession(serviceID)
+	clock := SystemClock{}
 
 	// Initialize session status
 	sessionStatus := &SessionTransferStatus{
@@ -71,8 +88,8 @@ func NewUnifiedTransferManagerWithConfig(serviceID string, config *TransferConfi
 		TotalBytes:      0,
 		BytesCompleted:  0,
 		OverallProgress: 0.0,
-		StartTime:       time.Now(),
-		LastUpdateTime:  time.Now(),
+		StartTime:       clock.Now(),
+		LastUpdateTime:  clock.Now(),
 		State:           StatusSessionStateActive,
 	}
 
@@ -81,11 +98,18 @@ func NewUnifiedTransferManagerWithConfig(serviceID string, config *TransferConfi
 		config:         config,
 		structure:      NewFileStructureManager(),
 		chunkers:       make(map[string]*Chunker),
+		sentChunks:     make(map[string]*ChunkSet),
 		pendingFiles:   make(map[string]bool),
 		completedFiles: make(map[string]bool),
 		failedFiles:    make(map[string]bool),
 		sessionStatus:  sessionStatus,
+		activeFiles:    make(map[string]*TransferStatus),
+		fileTimings:    make(map[string]PhaseTimings),
+		finished:       make(map[string]TransferStatus),
+		stateChanged:   make(chan struct{}),
 		listeners:      make([]StatusListener, 0),
+		clock:          clock,
+		transport:      NewSerialTransport(),
 	}
 
 	// Initialize error handling system
@@ -99,13 +123,21 @@ func NewUnifiedTransferManagerWithConfig(serviceID string, config *TransferConfi
 // AddFile adds a file to the transfer queue (focused on file management only)
 // AddFile adds a file or directory to the transfer queue
 func (utm *UnifiedTransferManager) AddFile(node *fileInfo.FileNode) error {
+	return utm.AddFileContext(context.Background(), node)
+}
+
+// AddFileContext adds a file or directory like AddFile, stopping between files once ctx is done
+func (utm *UnifiedTransferManager) AddFileContext(ctx context.Context, node *fileInfo.FileNode) error {
 	if node == nil {
 		return fmt.Errorf("file node cannot be nil")
 	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 
 	// Handle directories by adding all their files
 	if node.IsDir {
-		return utm.addDirectory(node)
+		return utm.addDirectory(ctx, node)
 	}
 
 	// Handle regular files
@@ -143,8 +175,10 @@ func (utm *UnifiedTransferManager) addSingleFile(node *fileInfo.FileNode) error
 	utm.statusMu.Lock()
 	utm.sessionStatus.TotalFiles = utm.GetFileCount()
 	utm.sessionStatus.PendingFiles = len(utm.pendingFiles)
-	utm.sessionStatus.TotalBytes += node.Size
-	utm.sessionStatus.LastUpdateTime = time.Now()
+	if !node.IsStream() {
+		utm.sessionStatus.TotalBytes += node.Size
+	}
+	utm.sessionStatus.LastUpdateTime = utm.now()
 	utm.statusMu.Unlock()
 
 	return nil
@@ -155,14 +189,14 @@ func (utm *UnifiedTransferManager) GetFileCount() int {
 }
 
 // addDirectory recursively adds all files in a directory to the transfer queue
-func (utm *UnifiedTransferManager) addDirectory(dirNode *fileInfo.FileNode) error {
+func (utm *UnifiedTransferManager) addDirectory(ctx context.Context, dirNode *fileInfo.FileNode) error {
 	if !dirNode.IsDir {
 		return fmt.Errorf("node is not a directory: %s", dirNode.Path)
 	}
 
 	// Recursively add all files in the directory
 	for _, child := range dirNode.Children {
-		if err := utm.AddFile(&child); err != nil {
+		if err := utm.AddFileContext(ctx, &child); err != nil {
 			return fmt.Errorf("failed to add child %s: %w", child.Path, err)
 		}
 	}
@@ -209,7 +243,7 @@ func (utm *UnifiedTransferManager) MarkFileCompleted(filePath string) error {
 	utm.sessionStatus.CompletedFiles = completed
 	utm.sessionStatus.PendingFiles = pending
 	utm.sessionStatus.FailedFiles = failed
-	utm.sessionStatus.LastUpdateTime = time.Now()
+	utm.sessionStatus.LastUpdateTime = utm.now()
 	utm.statusMu.Unlock()
 
 	return nil
@@ -235,7 +269,7 @@ func (utm *UnifiedTransferManager) MarkFileFailed(filePath string) error {
 	utm.sessionStatus.CompletedFiles = completed
 	utm.sessionStatus.PendingFiles = pending
 	utm.sessionStatus.FailedFiles = failed
-	utm.sessionStatus.LastUpdateTime = time.Now()
+	utm.sessionStatus.LastUpdateTime = utm.now()
 	utm.statusMu.Unlock()
 
 	return nil
@@ -285,6 +319,7 @@ func (utm *UnifiedTransferManager) Close() error {
 	// Clear all data
 	utm.structure.Clear()
 	utm.chunkers = make(map[string]*Chunker)
+	utm.sentChunks = make(map[string]*ChunkSet)
 	utm.pendingFiles = make(map[string]bool)
 	utm.completedFiles = make(map[string]bool)
 	utm.failedFiles = make(map[string]bool)
@@ -294,6 +329,8 @@ func (utm *UnifiedTransferManager) Close() error {
 
 // GetSessionStatus returns a copy of the current session status
 func (utm *UnifiedTransferManager) GetSessionStatus() *SessionTransferStatus {
+	utm.queueMu.RLock()
+	defer utm.queueMu.RUnlock()
 	utm.statusMu.RLock()
 	defer utm.statusMu.RUnlock()
 
@@ -303,6 +340,7 @@ func (utm *UnifiedTransferManager) GetSessionStatus() *SessionTransferStatus {
 		currentFileCopy := *utm.sessionStatus.CurrentFile
 		statusCopy.CurrentFile = &currentFileCopy
 	}
+	statusCopy.Children = utm.batchStatusLocked()
 
 	return &statusCopy
 }
@@ -331,27 +369,53 @@ func (utm *UnifiedTransferManager) StartTransfer(filePath string) error {
 		State:          TransferStateActive,
 		TotalBytes:     managedFile.Size,
 		FileSize:       managedFile.Size,
-		StartTime:      time.Now(),
-		LastUpdateTime: time.Now(),
+		StartTime:      utm.now(),
+		LastUpdateTime: utm.now(),
 		MaxRetries:     utm.config.DefaultRetryPolicy.MaxRetries,
+		clock:          utm.clock,
+	}
+	// A file started while the session is paused waits with the others
+	if utm.sessionStatus.State == StatusSessionStatePaused {
+		currentFile.State = TransferStatePaused
+	}
+	currentFile.Phases.Add(PhaseQueued, currentFile.StartTime.Sub(utm.sessionStatus.StartTime))
+	if utm.sessionStatus.Phases.Queued == 0 {
+		utm.sessionStatus.Phases.Queued = currentFile.Phases.Queued
 	}
 
 	oldSessionStatus := *utm.sessionStatus
-	oldCurrentFile := utm.sessionStatus.CurrentFile
+	oldCurrentFile := utm.activeFiles[filePath]
 
+	utm.activeFiles[filePath] = currentFile
 	utm.sessionStatus.CurrentFile = currentFile
-	utm.sessionStatus.LastUpdateTime = time.Now()
+	utm.sessionStatus.ActiveFiles = len(utm.activeFiles)
+	utm.sessionStatus.LastUpdateTime = utm.now()
 
 	// Update session totals if this is the first time we're seeing this file
 	utm.updateSessionTotals()
 
 	// Create copy for session status notification to avoid race conditions
 	newSessionStatus := *utm.sessionStatus
+	newFileStatus := *currentFile
 
 	// Notify listeners with copies
-	go utm.notifyFileStatusChanged(filePath, oldCurrentFile, currentFile)
-	go utm.notifySessionStatusChanged(&oldSessionStatus, &newSessionStatus)
+	utm.dispatch(func() { utm.notifyFileStatusChanged(filePath, oldCurrentFile, &newFileStatus) })
+	utm.dispatch(func() { utm.notifySessionStatusChanged(&oldSessionStatus, &newSessionStatus) })
+
+	return nil
+}
 
+// RecordPhase adds d to the time the file in flight at filePath spent in phase. The time
+// is published with the next status change of the file.
+func (utm *UnifiedTransferManager) RecordPhase(filePath string, phase TransferPhase, d time.Duration) error {
+	utm.statusMu.Lock()
+	defer utm.statusMu.Unlock()
+
+	file, ok := utm.activeFiles[filePath]
+	if !ok {
+		return ErrTransferNotFound
+	}
+	file.Phases.Add(phase, d)
 	return nil
 }
 
@@ -364,29 +428,30 @@ func (utm *UnifiedTransferManager) UpdateProgress(filePath string, bytesSent int
 	utm.statusMu.Lock()
 	defer utm.statusMu.Unlock()
 
-	if utm.sessionStatus.CurrentFile == nil || utm.sessionStatus.CurrentFile.FilePath != filePath {
+	file, ok := utm.activeFiles[filePath]
+	if !ok {
 		return ErrTransferNotFound
 	}
 
 	oldSessionStatus := *utm.sessionStatus
-	oldFileStatus := *utm.sessionStatus.CurrentFile
+	oldFileStatus := *file
 
-	// Update current file progress
-	utm.sessionStatus.CurrentFile.BytesSent = bytesSent
-	utm.sessionStatus.CurrentFile.LastUpdateTime = time.Now()
-	utm.sessionStatus.CurrentFile.calculateMetrics()
+	// Update the file's progress
+	file.BytesSent = bytesSent
+	file.LastUpdateTime = utm.now()
+	file.calculateMetrics()
 
 	// Update overall progress
 	utm.sessionStatus.OverallProgress = utm.sessionStatus.GetSessionProgressPercentage()
-	utm.sessionStatus.LastUpdateTime = time.Now()
+	utm.sessionStatus.LastUpdateTime = utm.now()
 
 	// Create copies for notification to avoid race conditions
-	newFileStatus := *utm.sessionStatus.CurrentFile
+	newFileStatus := *file
 	newSessionStatus := *utm.sessionStatus
 
 	// Notify listeners with copies
-	go utm.notifyFileStatusChanged(filePath, &oldFileStatus, &newFileStatus)
-	go utm.notifySessionStatusChanged(&oldSessionStatus, &newSessionStatus)
+	utm.dispatch(func() { utm.notifyFileStatusChanged(filePath, &oldFileStatus, &newFileStatus) })
+	utm.dispatch(func() { utm.notifySessionStatusChanged(&oldSessionStatus, &newSessionStatus) })
 
 	return nil
 }
@@ -400,25 +465,33 @@ func (utm *UnifiedTransferManager) CompleteTransfer(filePath string) error {
 	defer utm.statusMu.Unlock()
 	defer utm.queueMu.Unlock()
 
-	if utm.sessionStatus.CurrentFile == nil || utm.sessionStatus.CurrentFile.FilePath != filePath {
+	completedFile, ok := utm.activeFiles[filePath]
+	if !ok {
 		return ErrTransferNotFound
 	}
 
 	oldSessionStatus := *utm.sessionStatus
-	oldFileStatus := *utm.sessionStatus.CurrentFile
+	oldFileStatus := *completedFile
 
-	// Mark current file as completed
-	utm.sessionStatus.CurrentFile.State = TransferStateCompleted
-	now := time.Now()
-	utm.sessionStatus.CurrentFile.CompletionTime = &now
+	// Mark the file as completed
+	completedFile.State = TransferStateCompleted
+	now := utm.now()
+	completedFile.CompletionTime = &now
 
 	// Update session counters
 	utm.sessionStatus.CompletedFiles++
 	utm.sessionStatus.PendingFiles--
-	utm.sessionStatus.BytesCompleted += utm.sessionStatus.CurrentFile.TotalBytes
+	completedBytes := completedFile.TotalBytes
+	if completedBytes == fileInfo.UnknownSize {
+		// Streams only know their size once fully sent
+		completedBytes = completedFile.BytesSent
+	}
+	utm.sessionStatus.BytesCompleted += completedBytes
+	utm.sessionStatus.Phases.Merge(completedFile.Phases)
+	utm.fileTimings[filePath] = completedFile.Phases
+	utm.finished[filePath] = *completedFile
 
-	completedFile := utm.sessionStatus.CurrentFile
-	utm.sessionStatus.CurrentFile = nil // No current file until next one starts
+	utm.finishActiveFileLocked(filePath)
 	utm.sessionStatus.LastUpdateTime = now
 
 	// Update overall progress
@@ -437,8 +510,8 @@ func (utm *UnifiedTransferManager) CompleteTransfer(filePath string) error {
 	newSessionStatus := *utm.sessionStatus
 
 	// Notify listeners with copies
-	go utm.notifyFileStatusChanged(filePath, &oldFileStatus, completedFile)
-	go utm.notifySessionStatusChanged(&oldSessionStatus, &newSessionStatus)
+	utm.dispatch(func() { utm.notifyFileStatusChanged(filePath, &oldFileStatus, completedFile) })
+	utm.dispatch(func() { utm.notifySessionStatusChanged(&oldSessionStatus, &newSessionStatus) })
 
 	return nil
 }
@@ -452,24 +525,25 @@ func (utm *UnifiedTransferManager) FailTransfer(filePath string, err error) erro
 	defer utm.statusMu.Unlock()
 	defer utm.queueMu.Unlock()
 
-	if utm.sessionStatus.CurrentFile == nil || utm.sessionStatus.CurrentFile.FilePath != filePath {
+	failedFile, ok := utm.activeFiles[filePath]
+	if !ok {
 		return ErrTransferNotFound
 	}
 
 	oldSessionStatus := *utm.sessionStatus
-	oldFileStatus := *utm.sessionStatus.CurrentFile
+	oldFileStatus := *failedFile
 
 	// Increment retry count
-	retryCount := utm.sessionStatus.CurrentFile.RetryCount + 1
-	utm.sessionStatus.CurrentFile.RetryCount = retryCount
+	retryCount := failedFile.RetryCount + 1
+	failedFile.RetryCount = retryCount
 
 	// Check if we should schedule a retry
 	if utm.retryScheduler.ScheduleRetry(filePath, err, retryCount) {
 		// Retry scheduled, update status but don't mark as failed yet
-		utm.sessionStatus.CurrentFile.LastError = err
-		utm.sessionStatus.CurrentFile.State = TransferStatePaused // Temporarily paused for retry
-		utm.sessionStatus.CurrentFile = nil                       // No current file until retry
-		utm.sessionStatus.LastUpdateTime = time.Now()
+		failedFile.LastError = err
+		failedFile.State = TransferStatePaused // Temporarily paused for retry
+		utm.finishActiveFileLocked(filePath)   // Not in flight until retried
+		utm.sessionStatus.LastUpdateTime = utm.now()
 
 		// Create copy for notification
 		pausedFile := oldFileStatus
@@ -478,29 +552,34 @@ func (utm *UnifiedTransferManager) FailTransfer(filePath string, err error) erro
 		pausedFile.RetryCount = retryCount
 
 		// Notify listeners
-		go utm.notifyFileStatusChanged(filePath, &oldFileStatus, &pausedFile)
+		utm.dispatch(func() { utm.notifyFileStatusChanged(filePath, &oldFileStatus, &pausedFile) })
 
 		return nil
 	}
 
-	// No retry scheduled, mark as failed
-	utm.sessionStatus.CurrentFile.State = TransferStateFailed
-	utm.sessionStatus.CurrentFile.LastError = err
+	// No retry scheduled, mark as failed, or as cancelled when its batch item was
+	failedFile.State = TransferStateFailed
+	if errors.Is(err, ErrTransferCancelled) {
+		failedFile.State = TransferStateCancelled
+	}
+	failedFile.LastError = err
 
 	// Update session counters
 	utm.sessionStatus.FailedFiles++
 	utm.sessionStatus.PendingFiles--
+	utm.sessionStatus.Phases.Merge(failedFile.Phases)
+	utm.fileTimings[filePath] = failedFile.Phases
+	utm.finished[filePath] = *failedFile
 
-	failedFile := utm.sessionStatus.CurrentFile
-	utm.sessionStatus.CurrentFile = nil // No current file until next one starts
-	utm.sessionStatus.LastUpdateTime = time.Now()
+	utm.finishActiveFileLocked(filePath)
+	utm.sessionStatus.LastUpdateTime = utm.now()
 
 	// Update overall progress
 	utm.sessionStatus.OverallProgress = utm.sessionStatus.GetSessionProgressPercentage()
 
 	// Check if session should be marked as failed (all files failed)
 	if utm.sessionStatus.FailedFiles >= utm.sessionStatus.TotalFiles {
-		now := time.Now()
+		now := utm.now()
 		utm.sessionStatus.CompletionTime = &now
 		utm.sessionStatus.State = StatusSessionStateFailed
 	}
@@ -512,70 +591,72 @@ func (utm *UnifiedTransferManager) FailTransfer(filePath string, err error) erro
 	newSessionStatus := *utm.sessionStatus
 
 	// Notify listeners with copies
-	go utm.notifyFileStatusChanged(filePath, &oldFileStatus, failedFile)
-	go utm.notifySessionStatusChanged(&oldSessionStatus, &newSessionStatus)
+	utm.dispatch(func() { utm.notifyFileStatusChanged(filePath, &oldFileStatus, failedFile) })
+	utm.dispatch(func() { utm.notifySessionStatusChanged(&oldSessionStatus, &newSessionStatus) })
 
 	return nil
 }
 
-// PauseTransfer pauses the current file transfer
+// PauseTransfer pauses the transfer of a file in flight
 func (utm *UnifiedTransferManager) PauseTransfer(filePath string) error {
 	utm.statusMu.Lock()
 	defer utm.statusMu.Unlock()
 
-	if utm.sessionStatus.CurrentFile == nil || utm.sessionStatus.CurrentFile.FilePath != filePath {
+	file, ok := utm.activeFiles[filePath]
+	if !ok {
 		return ErrTransferNotFound
 	}
 
-	if utm.sessionStatus.CurrentFile.State != TransferStateActive {
+	if file.State != TransferStateActive {
 		return ErrInvalidStateTransition
 	}
 
 	oldSessionStatus := *utm.sessionStatus
-	oldFileStatus := *utm.sessionStatus.CurrentFile
+	oldFileStatus := *file
 
-	utm.sessionStatus.CurrentFile.State = TransferStatePaused
-	utm.sessionStatus.CurrentFile.LastUpdateTime = time.Now()
-	utm.sessionStatus.LastUpdateTime = time.Now()
+	file.State = TransferStatePaused
+	file.LastUpdateTime = utm.now()
+	utm.sessionStatus.LastUpdateTime = utm.now()
 
 	// Create copies for notification to avoid race conditions
-	newFileStatus := *utm.sessionStatus.CurrentFile
+	newFileStatus := *file
 	newSessionStatus := *utm.sessionStatus
 
 	// Notify listeners with copies
-	go utm.notifyFileStatusChanged(filePath, &oldFileStatus, &newFileStatus)
-	go utm.notifySessionStatusChanged(&oldSessionStatus, &newSessionStatus)
+	utm.dispatch(func() { utm.notifyFileStatusChanged(filePath, &oldFileStatus, &newFileStatus) })
+	utm.dispatch(func() { utm.notifySessionStatusChanged(&oldSessionStatus, &newSessionStatus) })
 
 	return nil
 }
 
-// ResumeTransfer resumes a paused file transfer
+// ResumeTransfer resumes the paused transfer of a file in flight
 func (utm *UnifiedTransferManager) ResumeTransfer(filePath string) error {
 	utm.statusMu.Lock()
 	defer utm.statusMu.Unlock()
 
-	if utm.sessionStatus.CurrentFile == nil || utm.sessionStatus.CurrentFile.FilePath != filePath {
+	file, ok := utm.activeFiles[filePath]
+	if !ok {
 		return ErrTransferNotFound
 	}
 
-	if utm.sessionStatus.CurrentFile.State != TransferStatePaused {
+	if file.State != TransferStatePaused {
 		return ErrInvalidStateTransition
 	}
 
 	oldSessionStatus := *utm.sessionStatus
-	oldFileStatus := *utm.sessionStatus.CurrentFile
+	oldFileStatus := *file
 
-	utm.sessionStatus.CurrentFile.State = TransferStateActive
-	utm.sessionStatus.CurrentFile.LastUpdateTime = time.Now()
-	utm.sessionStatus.LastUpdateTime = time.Now()
+	file.State = TransferStateActive
+	file.LastUpdateTime = utm.now()
+	utm.sessionStatus.LastUpdateTime = utm.now()
 
 	// Create copies for notification to avoid race conditions
-	newFileStatus := *utm.sessionStatus.CurrentFile
+	newFileStatus := *file
 	newSessionStatus := *utm.sessionStatus
 
 	// Notify listeners with copies
-	go utm.notifyFileStatusChanged(filePath, &oldFileStatus, &newFileStatus)
-	go utm.notifySessionStatusChanged(&oldSessionStatus, &newSessionStatus)
+	utm.dispatch(func() { utm.notifyFileStatusChanged(filePath, &oldFileStatus, &newFileStatus) })
+	utm.dispatch(func() { utm.notifySessionStatusChanged(&oldSessionStatus, &newSessionStatus) })
 
 	return nil
 }
@@ -585,9 +666,9 @@ func (utm *UnifiedTransferManager) GetFileStatus(filePath string) (*TransferStat
 	utm.statusMu.RLock()
 	defer utm.statusMu.RUnlock()
 
-	// Check if it's the current file (active transfer)
-	if utm.sessionStatus.CurrentFile != nil && utm.sessionStatus.CurrentFile.FilePath == filePath {
-		statusCopy := *utm.sessionStatus.CurrentFile
+	// Files in flight have their live status
+	if file, ok := utm.activeFiles[filePath]; ok {
+		statusCopy := *file
 		return &statusCopy, nil
 	}
 
@@ -604,6 +685,7 @@ func (utm *UnifiedTransferManager) GetFileStatus(filePath string) (*TransferStat
 		SessionID:      utm.sessionStatus.SessionID,
 		TotalBytes:     fileNode.Size,
 		LastUpdateTime: utm.sessionStatus.LastUpdateTime,
+		Phases:         utm.fileTimings[filePath],
 	}
 
 	// Determine state and set appropriate fields
@@ -654,6 +736,19 @@ func (utm *UnifiedTransferManager) GetFileStatus(filePath string) (*TransferStat
 	return nil, ErrTransferNotFound
 }
 
+// GetFinishedFiles returns the final status of every file that completed, failed or was
+// cancelled. Close keeps them, so the outcome of a transfer can be read once it is over.
+func (utm *UnifiedTransferManager) GetFinishedFiles() map[string]TransferStatus {
+	utm.statusMu.RLock()
+	defer utm.statusMu.RUnlock()
+
+	finished := make(map[string]TransferStatus, len(utm.finished))
+	for path, status := range utm.finished {
+		finished[path] = status
+	}
+	return finished
+}
+
 // AddStatusListener adds a status change listener
 func (utm *UnifiedTransferManager) AddStatusListener(listener StatusListener) {
 	utm.eventsMu.Lock()
@@ -662,8 +757,50 @@ func (utm *UnifiedTransferManager) AddStatusListener(listener StatusListener) {
 	utm.listeners = append(utm.listeners, listener)
 }
 
+// SetEventBus publishes every file and session status change to bus as well
+func (utm *UnifiedTransferManager) SetEventBus(bus *events.Bus) {
+	utm.eventsMu.Lock()
+	defer utm.eventsMu.Unlock()
+
+	utm.eventBus = bus
+}
+
+// SetClock makes the manager, the statuses of files it starts and its retry scheduler tell
+// time by clock. It must be called before files are added.
+func (utm *UnifiedTransferManager) SetClock(clock Clock) {
+	utm.statusMu.Lock()
+	defer utm.statusMu.Unlock()
+
+	utm.clock = clock
+	utm.sessionStatus.StartTime = clock.Now()
+	utm.sessionStatus.LastUpdateTime = clock.Now()
+	utm.retryScheduler.setClock(clock)
+}
+
+// SetTransport delivers status notifications through transport instead of a SerialTransport.
+// It must be called before files are added.
+func (utm *UnifiedTransferManager) SetTransport(transport Transport) {
+	utm.eventsMu.Lock()
+	defer utm.eventsMu.Unlock()
+
+	utm.transport = transport
+}
+
 // Helper methods
 
+func (utm *UnifiedTransferManager) now() time.Time {
+	return utm.clock.Now()
+}
+
+// dispatch hands a status notification to the transport
+func (utm *UnifiedTransferManager) dispatch(deliver func()) {
+	utm.eventsMu.RLock()
+	transport := utm.transport
+	utm.eventsMu.RUnlock()
+
+	transport.Dispatch(deliver)
+}
+
 func (utm *UnifiedTransferManager) updateSessionTotals() {
 	// This method assumes statusMu is already locked
 	utm.sessionStatus.TotalFiles = utm.GetFileCount()
@@ -679,11 +816,28 @@ func (utm *UnifiedTransferManager) notifyFileStatusChanged(filePath string, oldS
 	utm.eventsMu.RLock()
 	listenersCopy := make([]StatusListener, len(utm.listeners))
 	copy(listenersCopy, utm.listeners)
+	bus := utm.eventBus
 	utm.eventsMu.RUnlock()
 
-	// Notify each listener in its own goroutine to prevent blocking
+	if bus != nil && newStatus != nil {
+		bus.Publish(events.FileStatusChanged{
+			SessionID:    newStatus.SessionID,
+			FilePath:     filePath,
+			State:        newStatus.State.String(),
+			BytesSent:    newStatus.BytesSent,
+			TotalBytes:   newStatus.TotalBytes,
+			TransferRate: newStatus.TransferRate,
+			RetryCount:   newStatus.RetryCount,
+			Phases:       newStatus.Phases.Map(),
+			Err:          newStatus.LastError,
+			Time:         utm.now(),
+		})
+	}
+
+	// Notify each listener separately so one cannot block the others
 	for _, listener := range listenersCopy {
-		go func(l StatusListener) {
+		l := listener
+		utm.dispatch(func() {
 			// Use defer to recover from panics in listener code
 			defer func() {
 				if r := recover(); r != nil {
@@ -693,7 +847,7 @@ func (utm *UnifiedTransferManager) notifyFileStatusChanged(filePath string, oldS
 				}
 			}()
 			l.OnFileStatusChanged(filePath, oldStatus, newStatus)
-		}(listener)
+		})
 	}
 }
 
@@ -702,11 +856,36 @@ func (utm *UnifiedTransferManager) notifySessionStatusChanged(oldStatus, newStat
 	utm.eventsMu.RLock()
 	listenersCopy := make([]StatusListener, len(utm.listeners))
 	copy(listenersCopy, utm.listeners)
+	bus := utm.eventBus
 	utm.eventsMu.RUnlock()
 
-	// Notify each listener in its own goroutine to prevent blocking
+	if bus != nil && newStatus != nil {
+		now := utm.now()
+		progress := events.SessionProgress{
+			SessionID:       newStatus.SessionID,
+			State:           newStatus.State.String(),
+			TotalFiles:      newStatus.TotalFiles,
+			CompletedFiles:  newStatus.CompletedFiles,
+			FailedFiles:     newStatus.FailedFiles,
+			TotalBytes:      newStatus.TotalBytes,
+			BytesCompleted:  newStatus.BytesCompleted,
+			OverallProgress: newStatus.OverallProgress,
+			FilesPerMinute:  newStatus.FilesPerMinute(now),
+			Phases:          newStatus.Phases.Map(),
+			Time:            now,
+		}
+		if newStatus.CurrentFile != nil {
+			progress.CurrentFile = newStatus.CurrentFile.FilePath
+			progress.TransferRate = newStatus.CurrentFile.TransferRate
+		}
+		progress.RemainingTime = newStatus.EstimateRemaining(progress.TransferRate, now)
+		bus.Publish(progress)
+	}
+
+	// Notify each listener separately so one cannot block the others
 	for _, listener := range listenersCopy {
-		go func(l StatusListener) {
+		l := listener
+		utm.dispatch(func() {
 			// Use defer to recover from panics in listener code
 			defer func() {
 				if r := recover(); r != nil {
@@ -716,7 +895,7 @@ func (utm *UnifiedTransferManager) notifySessionStatusChanged(oldStatus, newStat
 				}
 			}()
 			l.OnSessionStatusChanged(oldStatus, newStatus)
-		}(listener)
+		})
 	}
 }
 
@@ -868,75 +1047,40 @@ func (utm *UnifiedTransferManager) GetErrorHandler() ErrorHandler {
 	return utm.errorHandler
 }
 
-// PauseSession pauses the entire transfer session
+// PauseSession pauses the session and every file in flight at once
 func (utm *UnifiedTransferManager) PauseSession() error {
 	utm.statusMu.Lock()
 	defer utm.statusMu.Unlock()
 
 	if utm.sessionStatus.State != StatusSessionStateActive {
-		return fmt.Errorf("cannot pause session: session is not active (current state: %s)", utm.sessionStatus.State)
+		return fmt.Errorf("cannot pause session: %w: session is not active (current state: %s)",
+			ErrInvalidStateTransition, utm.sessionStatus.State)
 	}
-
-	oldStatus := *utm.sessionStatus
-	utm.sessionStatus.State = StatusSessionStatePaused
-	utm.sessionStatus.LastUpdateTime = time.Now()
-
-	// Pause current file if any
-	if utm.sessionStatus.CurrentFile != nil {
-		utm.sessionStatus.CurrentFile.State = TransferStatePaused
-		utm.sessionStatus.CurrentFile.LastUpdateTime = time.Now()
-	}
-
-	// Notify listeners
-	go utm.notifySessionStatusChanged(&oldStatus, utm.sessionStatus)
-
+	utm.setSessionStateLocked(StatusSessionStatePaused, TransferStatePaused)
 	return nil
 }
 
-// ResumeSession resumes a paused transfer session
+// ResumeSession resumes a paused session and every file in flight at once
 func (utm *UnifiedTransferManager) ResumeSession() error {
 	utm.statusMu.Lock()
 	defer utm.statusMu.Unlock()
 
 	if utm.sessionStatus.State != StatusSessionStatePaused {
-		return fmt.Errorf("cannot resume session: session is not paused (current state: %s)", utm.sessionStatus.State)
-	}
-
-	oldStatus := *utm.sessionStatus
-	utm.sessionStatus.State = StatusSessionStateActive
-	utm.sessionStatus.LastUpdateTime = time.Now()
-
-	// Resume current file if any
-	if utm.sessionStatus.CurrentFile != nil {
-		utm.sessionStatus.CurrentFile.State = TransferStateActive
-		utm.sessionStatus.CurrentFile.LastUpdateTime = time.Now()
+		return fmt.Errorf("cannot resume session: %w: session is not paused (current state: %s)",
+			ErrInvalidStateTransition, utm.sessionStatus.State)
 	}
-
-	// Notify listeners
-	go utm.notifySessionStatusChanged(&oldStatus, utm.sessionStatus)
-
+	utm.setSessionStateLocked(StatusSessionStateActive, TransferStateActive)
 	return nil
 }
 
-// CancelSession cancels the entire transfer session
+// CancelSession cancels the session and every file in flight at once
 func (utm *UnifiedTransferManager) CancelSession() error {
 	utm.statusMu.Lock()
 	defer utm.statusMu.Unlock()
 
-	if utm.sessionStatus.State == StatusSessionStateCompleted ||
-		utm.sessionStatus.State == StatusSessionStateFailed ||
-		utm.sessionStatus.State == StatusSessionStateCancelled {
-		return fmt.Errorf("cannot cancel session: session is already finished (current state: %s)", utm.sessionStatus.State)
-	}
-
-	oldStatus := *utm.sessionStatus
-	utm.sessionStatus.State = StatusSessionStateCancelled
-	utm.sessionStatus.LastUpdateTime = time.Now()
-
-	// Cancel current file if any
-	if utm.sessionStatus.CurrentFile != nil {
-		utm.sessionStatus.CurrentFile.State = TransferStateCancelled
-		utm.sessionStatus.CurrentFile.LastUpdateTime = time.Now()
+	if utm.sessionStatus.State.IsTerminal() {
+		return fmt.Errorf("cannot cancel session: %w: session is already finished (current state: %s)",
+			ErrInvalidStateTransition, utm.sessionStatus.State)
 	}
 
 	// Cancel all pending retries
@@ -944,12 +1088,73 @@ func (utm *UnifiedTransferManager) CancelSession() error {
 		utm.retryScheduler.CancelRetry(filePath)
 	}
 
-	// Notify listeners
-	go utm.notifySessionStatusChanged(&oldStatus, utm.sessionStatus)
-
+	utm.setSessionStateLocked(StatusSessionStateCancelled, TransferStateCancelled)
 	return nil
 }
 
+// setSessionStateLocked moves the session to state and every file in flight that can make
+// the move to fileState in one step, so listeners never see a paused session with an active
+// file, and wakes the senders waiting on SessionStateChanged. statusMu must be held.
+func (utm *UnifiedTransferManager) setSessionStateLocked(state StatusSessionState, fileState TransferState) {
+	oldStatus := *utm.sessionStatus
+	now := utm.now()
+
+	paths := make([]string, 0, len(utm.activeFiles))
+	for path := range utm.activeFiles {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		file := utm.activeFiles[path]
+		if !file.State.CanTransitionTo(fileState) {
+			continue
+		}
+		oldFile := *file
+		file.State = fileState
+		file.LastUpdateTime = now
+		newFile := *file
+		utm.dispatch(func() { utm.notifyFileStatusChanged(path, &oldFile, &newFile) })
+	}
+
+	utm.sessionStatus.State = state
+	utm.sessionStatus.LastUpdateTime = now
+	close(utm.stateChanged)
+	utm.stateChanged = make(chan struct{})
+
+	// Notify listeners with a copy, the notification is delivered after the lock is released
+	newStatus := *utm.sessionStatus
+	utm.dispatch(func() { utm.notifySessionStatusChanged(&oldStatus, &newStatus) })
+}
+
+// SessionStateChanged returns a channel closed the next time the session is paused, resumed
+// or cancelled, so senders waiting for a paused session do not need to poll it. Take the
+// channel before checking the state, or a change in between is missed.
+func (utm *UnifiedTransferManager) SessionStateChanged() <-chan struct{} {
+	utm.statusMu.RLock()
+	defer utm.statusMu.RUnlock()
+	return utm.stateChanged
+}
+
+// finishActiveFileLocked takes the file at path out of the files in flight. If it was the
+// current file, the most recently started of the others becomes current. statusMu must be held.
+func (utm *UnifiedTransferManager) finishActiveFileLocked(path string) {
+	file := utm.activeFiles[path]
+	delete(utm.activeFiles, path)
+	utm.sessionStatus.ActiveFiles = len(utm.activeFiles)
+	if utm.sessionStatus.CurrentFile != file {
+		return
+	}
+
+	var current *TransferStatus
+	for _, other := range utm.activeFiles {
+		if current == nil || other.StartTime.After(current.StartTime) ||
+			(other.StartTime.Equal(current.StartTime) && other.FilePath > current.FilePath) {
+			current = other
+		}
+	}
+	utm.sessionStatus.CurrentFile = current
+}
+
 // IsSessionPaused returns true if the session is currently paused
 func (utm *UnifiedTransferManager) IsSessionPaused() bool {
 	utm.statusMu.RLock()
@@ -1,6 +1,7 @@
 package transfer
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os"
@@ -11,6 +12,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/rescp17/lanFileSharer/pkg/events"
 	"github.com/rescp17/lanFileSharer/pkg/fileInfo"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -123,6 +125,70 @@ func TestUnifiedTransferManager_PauseResume(t *testing.T) {
 	}
 }
 
+func TestUnifiedTransferManager_SessionControlMovesAllFilesInFlight(t *testing.T) {
+	manager := NewUnifiedTransferManager("test-service")
+	defer manager.Close()
+
+	tempDir := t.TempDir()
+	var paths []string
+	for _, name := range []string{"a.txt", "b.txt", "c.txt"} {
+		path := filepath.Join(tempDir, name)
+		require.NoError(t, os.WriteFile(path, []byte("content of "+name), 0644))
+		node, err := fileInfo.CreateNode(path)
+		require.NoError(t, err)
+		require.NoError(t, manager.AddFile(&node))
+		paths = append(paths, path)
+	}
+
+	// Two files in flight at once, as parallel transfers send them
+	require.NoError(t, manager.StartTransfer(paths[0]))
+	require.NoError(t, manager.StartTransfer(paths[1]))
+	require.NoError(t, manager.UpdateProgress(paths[0], 4))
+	assert.Equal(t, 2, manager.GetSessionStatus().ActiveFiles)
+
+	changed := manager.SessionStateChanged()
+	require.NoError(t, manager.PauseSession())
+	select {
+	case <-changed:
+	default:
+		t.Fatal("pausing did not signal the state change")
+	}
+	for _, path := range paths[:2] {
+		status, err := manager.GetFileStatus(path)
+		require.NoError(t, err)
+		assert.Equal(t, TransferStatePaused, status.State, path)
+	}
+	assert.ErrorIs(t, manager.PauseSession(), ErrInvalidStateTransition)
+
+	// A file started while paused waits with the others
+	require.NoError(t, manager.StartTransfer(paths[2]))
+	status, err := manager.GetFileStatus(paths[2])
+	require.NoError(t, err)
+	assert.Equal(t, TransferStatePaused, status.State)
+
+	require.NoError(t, manager.ResumeSession())
+	for _, path := range paths {
+		status, err := manager.GetFileStatus(path)
+		require.NoError(t, err)
+		assert.Equal(t, TransferStateActive, status.State, path)
+	}
+
+	require.NoError(t, manager.CompleteTransfer(paths[2]))
+	session := manager.GetSessionStatus()
+	assert.Equal(t, 2, session.ActiveFiles)
+	require.NotNil(t, session.CurrentFile)
+	assert.Equal(t, paths[1], session.CurrentFile.FilePath, "the most recently started file in flight becomes current")
+
+	require.NoError(t, manager.CancelSession())
+	for _, path := range paths[:2] {
+		status, err := manager.GetFileStatus(path)
+		require.NoError(t, err)
+		assert.Equal(t, TransferStateCancelled, status.State, path)
+	}
+	assert.ErrorIs(t, manager.ResumeSession(), ErrInvalidStateTransition)
+	assert.ErrorIs(t, manager.CancelSession(), ErrInvalidStateTransition)
+}
+
 func TestUnifiedTransferManager_MultipleFiles(t *testing.T) {
 	manager := NewUnifiedTransferManager("test-service")
 	defer manager.Close()
@@ -272,6 +338,8 @@ func TestUnifiedTransferManager_StatusListener(t *testing.T) {
 	t.Run("complete_transfer_lifecycle_events", func(t *testing.T) {
 		manager := NewUnifiedTransferManager("test-service")
 		defer manager.Close()
+		transport := NewQueueTransport()
+		manager.SetTransport(transport)
 
 		listener := newTestStatusListener()
 		manager.AddStatusListener(listener)
@@ -302,14 +370,19 @@ func TestUnifiedTransferManager_StatusListener(t *testing.T) {
 		err = manager.CompleteTransfer(testFile)
 		require.NoError(t, err, "CompleteTransfer failed")
 
-		// Give time for async events to be processed
-		time.Sleep(50 * time.Millisecond)
+		// Deliver the queued status events
+		transport.Flush()
 
 		// Verify file events in correct order
 		fileEvents := listener.GetFileEvents()
 		require.NotEmpty(t, fileEvents, "Should have received file status events")
 
 		t.Logf("File events received: %v", fileEvents)
+		assert.Equal(t, []string{
+			testFile + ": nil -> active",
+			testFile + ": active -> active",
+			testFile + ": active -> completed",
+		}, fileEvents, "Queued events should be delivered in the order they were raised")
 
 		// Analyze the actual sequence of events
 		// Events may come in different orders due to async processing
@@ -354,6 +427,8 @@ func TestUnifiedTransferManager_StatusListener(t *testing.T) {
 	t.Run("failed_transfer_events", func(t *testing.T) {
 		manager := NewUnifiedTransferManager("test-service-fail")
 		defer manager.Close()
+		transport := NewQueueTransport()
+		manager.SetTransport(transport)
 
 		listener := newTestStatusListener()
 		manager.AddStatusListener(listener)
@@ -379,8 +454,8 @@ func TestUnifiedTransferManager_StatusListener(t *testing.T) {
 		err = manager.FailTransfer(testFile, testError)
 		require.NoError(t, err, "FailTransfer failed")
 
-		// Give time for async events
-		time.Sleep(50 * time.Millisecond)
+		// Deliver the queued status events
+		transport.Flush()
 
 		// Verify file events
 		fileEvents := listener.GetFileEvents()
@@ -406,6 +481,8 @@ func TestUnifiedTransferManager_StatusListener(t *testing.T) {
 	t.Run("multiple_files_independent_events", func(t *testing.T) {
 		manager := NewUnifiedTransferManager("test-service-multi")
 		defer manager.Close()
+		transport := NewQueueTransport()
+		manager.SetTransport(transport)
 
 		listener := newTestStatusListener()
 		manager.AddStatusListener(listener)
@@ -441,8 +518,8 @@ func TestUnifiedTransferManager_StatusListener(t *testing.T) {
 
 		// File 2: Leave pending (just add, don't start)
 
-		// Give time for async events
-		time.Sleep(50 * time.Millisecond)
+		// Deliver the queued status events
+		transport.Flush()
 
 		// Verify events for each file
 		fileEvents := listener.GetFileEvents()
@@ -496,6 +573,8 @@ func TestUnifiedTransferManager_StatusListener(t *testing.T) {
 	t.Run("detailed_state_transition_verification", func(t *testing.T) {
 		manager := NewUnifiedTransferManager("test-service-detailed")
 		defer manager.Close()
+		transport := NewQueueTransport()
+		manager.SetTransport(transport)
 
 		listener := newTestStatusListener()
 		manager.AddStatusListener(listener)
@@ -533,8 +612,8 @@ func TestUnifiedTransferManager_StatusListener(t *testing.T) {
 		err = manager.CompleteTransfer(testFile)
 		require.NoError(t, err, "CompleteTransfer failed")
 
-		// Give time for async events
-		time.Sleep(100 * time.Millisecond)
+		// Deliver the queued status events
+		transport.Flush()
 
 		// Analyze events in detail
 		fileEvents := listener.GetFileEvents()
@@ -596,6 +675,71 @@ func TestUnifiedTransferManager_StatusListener(t *testing.T) {
 	})
 }
 
+func TestUnifiedTransferManager_PublishesToEventBus(t *testing.T) {
+	manager := NewUnifiedTransferManager("test-service")
+	defer manager.Close()
+
+	bus := events.NewBus()
+	sub := bus.Subscribe(32)
+	defer sub.Close()
+	manager.SetEventBus(bus)
+
+	testFile := filepath.Join(t.TempDir(), "test.txt")
+	require.NoError(t, os.WriteFile(testFile, []byte("event bus content"), 0644))
+	node, err := fileInfo.CreateNode(testFile)
+	require.NoError(t, err)
+	require.NoError(t, manager.AddFile(&node))
+	require.NoError(t, manager.StartTransfer(testFile))
+	require.NoError(t, manager.CompleteTransfer(testFile))
+
+	// Notifications are asynchronous, so wait for the completion of both topics
+	var fileCompleted, sessionCompleted bool
+	timeout := time.After(time.Second)
+	for !fileCompleted || !sessionCompleted {
+		select {
+		case event := <-sub.C():
+			switch e := event.(type) {
+			case events.FileStatusChanged:
+				assert.Equal(t, testFile, e.FilePath)
+				fileCompleted = fileCompleted || e.State == TransferStateCompleted.String()
+			case events.SessionProgress:
+				assert.Equal(t, "test-service", e.SessionID)
+				sessionCompleted = sessionCompleted || e.CompletedFiles == 1
+			}
+		case <-timeout:
+			t.Fatalf("missing events: file completed %v, session completed %v", fileCompleted, sessionCompleted)
+		}
+	}
+}
+
+func TestUnifiedTransferManager_RecordsPhaseTimings(t *testing.T) {
+	clock := NewManualClock(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	manager := NewUnifiedTransferManager("test-phases")
+	defer manager.Shutdown()
+	manager.SetClock(clock)
+	manager.SetTransport(NewQueueTransport())
+
+	path := filepath.Join(t.TempDir(), "phases.txt")
+	require.NoError(t, os.WriteFile(path, []byte("phases"), 0644))
+	node, err := fileInfo.CreateNode(path)
+	require.NoError(t, err)
+	require.NoError(t, manager.AddFile(&node))
+
+	assert.ErrorIs(t, manager.RecordPhase(path, PhaseHashing, time.Second), ErrTransferNotFound, "the file has not started")
+
+	clock.Advance(3 * time.Second)
+	require.NoError(t, manager.StartTransfer(path))
+	require.NoError(t, manager.RecordPhase(path, PhaseHashing, time.Second))
+	require.NoError(t, manager.RecordPhase(path, PhaseTransferring, 4*time.Second))
+	require.NoError(t, manager.RecordPhase(path, PhaseVerifying, 2*time.Second))
+	require.NoError(t, manager.CompleteTransfer(path))
+
+	status, err := manager.GetFileStatus(path)
+	require.NoError(t, err)
+	assert.Equal(t, PhaseTimings{Queued: 3 * time.Second, Hashing: time.Second, Transferring: 4 * time.Second, Verifying: 2 * time.Second}, status.Phases)
+	assert.Equal(t, status.Phases, manager.GetSessionStatus().Phases, "the session sums its only file")
+}
+
 func TestUnifiedTransferManager_GetChunker(t *testing.T) {
 	manager := NewUnifiedTransferManager("test-service")
 	defer manager.Close()
@@ -620,3 +764,39 @@ func TestUnifiedTransferManager_GetChunker(t *testing.T) {
 	_, exists = manager.GetChunker("/non/existent/file")
 	require.False(t, exists, "Chunker should not exist for non-existent file")
 }
+
+func TestUnifiedTransferManager_AddFileContextCancelled(t *testing.T) {
+	manager := NewUnifiedTransferManager("test-service")
+	defer manager.Close()
+
+	tempDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "a.txt"), []byte("a"), 0644))
+	node, err := fileInfo.CreateNode(tempDir)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, manager.AddFileContext(ctx, &node), context.Canceled)
+	assert.Equal(t, 0, manager.GetFileCount())
+}
+
+func TestUnifiedTransferManager_FinishedFilesOutliveClose(t *testing.T) {
+	manager := NewUnifiedTransferManager("test-finished")
+	defer manager.Shutdown()
+	manager.SetTransport(NewQueueTransport())
+
+	paths := addSnapshotFiles(t, manager, "done.txt", "broken.txt", "pending.txt")
+	done, broken := paths[0], paths[1]
+
+	require.NoError(t, manager.StartTransfer(done))
+	require.NoError(t, manager.CompleteTransfer(done))
+	require.NoError(t, manager.StartTransfer(broken))
+	require.NoError(t, manager.FailTransfer(broken, fmt.Errorf("%w: bad data", ErrChecksumMismatch)))
+	require.NoError(t, manager.Close())
+
+	finished := manager.GetFinishedFiles()
+	require.Len(t, finished, 2, "only finished files have a final status")
+	assert.Equal(t, TransferStateCompleted, finished[done].State)
+	assert.Equal(t, TransferStateFailed, finished[broken].State)
+	assert.ErrorIs(t, finished[broken].LastError, ErrChecksumMismatch)
+}
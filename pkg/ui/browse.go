@@ -0,0 +1,263 @@
+package ui
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	receiverEvent "github.com/rescp17/lanFileSharer/internal/app_events/receiver"
+	"github.com/rescp17/lanFileSharer/internal/style"
+	"github.com/rescp17/lanFileSharer/internal/util"
+	"github.com/rescp17/lanFileSharer/pkg/fileTree"
+)
+
+const (
+	// previewLines is how many lines of a text file the preview shows
+	previewLines = 15
+	// previewDumpBytes is how many bytes of a binary file the preview dumps
+	previewDumpBytes = 64
+)
+
+// startBrowsing shows the devices sharing files, which the receiver app starts looking for
+func (m *model) startBrowsing() (tea.Model, tea.Cmd) {
+	r := &m.receiver
+	r.state = browsingShares
+	r.shares = nil
+	r.shareCursor = 0
+	r.browsedShare = nil
+	r.browseErr = nil
+	r.notice = ""
+	m.receiverController.AppEvents() <- receiverEvent.BrowseShares{}
+	return m, nil
+}
+
+// stopBrowsing returns to awaiting requests
+func (m *model) stopBrowsing() {
+	m.receiverController.AppEvents() <- receiverEvent.StopBrowsing{}
+	m.receiver.state = awaitingConnection
+	m.receiver.browsedShare = nil
+	m.receiver.browseErr = nil
+}
+
+func (m *model) updateBrowsing(msg tea.Msg) (tea.Model, tea.Cmd) {
+	r := &m.receiver
+	switch msg := msg.(type) {
+	case receiverEvent.FileNodeUpdateMsg:
+		// Pulled files, or a request of another sender, take over the screen
+		m.stopBrowsing()
+		return m.updateAwaitingConnection(msg)
+	case receiverEvent.SharesFoundMsg:
+		r.shares = msg.Shares
+		r.browseErr = msg.Err
+		r.shareCursor = min(r.shareCursor, max(len(r.shares)-1, 0))
+		return m, nil
+	case receiverEvent.ShareListingMsg:
+		if r.state != browsingShare || r.browsedShare == nil || msg.Share != r.browsedShare.Name {
+			return m, nil
+		}
+		if msg.Err != nil {
+			r.fileTree.CancelLoad()
+			r.browseErr = msg.Err
+			return m, nil
+		}
+		r.browseErr = nil
+		r.fileTree.SetChildren(msg.Path, msg.Nodes)
+		return m, nil
+	case receiverEvent.SharePreviewMsg:
+		if r.state != browsingShare || r.browsedShare == nil || msg.Share != r.browsedShare.Name {
+			return m, nil
+		}
+		if msg.Err != nil {
+			r.browseErr = msg.Err
+			return m, nil
+		}
+		r.browseErr = nil
+		r.preview = &msg
+		return m, nil
+	case receiverEvent.PullRequestedMsg:
+		if msg.Err != nil {
+			r.browseErr = msg.Err
+			return m, nil
+		}
+		m.stopBrowsing()
+		r.notice = fmt.Sprintf("Asked %s for %d items, they are received without asking once it sends them.", util.SanitizeText(msg.Share), msg.Files)
+		return m, r.spinner.Tick
+	case fileTree.LoadChildrenMsg:
+		if r.browsedShare != nil {
+			m.receiverController.AppEvents() <- receiverEvent.ListShare{Share: *r.browsedShare, Path: msg.Path}
+		}
+		return m, nil
+	case tea.MouseMsg:
+		if r.state == browsingShare {
+			var cmd tea.Cmd
+			r.fileTree, cmd = m.updateFileTreeMouse(r.fileTree, msg)
+			return m, cmd
+		}
+		return m, nil
+	case tea.KeyMsg:
+		if r.state == browsingShares {
+			return m.updateBrowsingShares(msg)
+		}
+		return m.updateBrowsingShare(msg)
+	default:
+		var cmd tea.Cmd
+		r.spinner, cmd = r.spinner.Update(msg)
+		return m, cmd
+	}
+}
+
+// updateBrowsingShares moves through the devices sharing files and opens one
+func (m *model) updateBrowsingShares(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	r := &m.receiver
+	switch {
+	case key.Matches(msg, DefaultKeyMap.Back):
+		m.stopBrowsing()
+		return m, r.spinner.Tick
+	case key.Matches(msg, fileTree.DefaultKeyMap.Up):
+		if r.shareCursor > 0 {
+			r.shareCursor--
+		}
+	case key.Matches(msg, fileTree.DefaultKeyMap.Down):
+		if r.shareCursor < len(r.shares)-1 {
+			r.shareCursor++
+		}
+	case key.Matches(msg, fileTree.DefaultKeyMap.GoToChild):
+		if len(r.shares) == 0 {
+			return m, nil
+		}
+		shared := r.shares[r.shareCursor]
+		r.browsedShare = &shared
+		r.browseErr = nil
+		r.fileTree = fileTree.NewLazyFileTree(fmt.Sprintf("Shared by %s:", util.SanitizeText(shared.Name)))
+		r.state = browsingShare
+		return m, r.fileTree.Init()
+	}
+	return m, nil
+}
+
+// updateBrowsingShare navigates the share being browsed and pulls from it
+func (m *model) updateBrowsingShare(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	r := &m.receiver
+	if r.preview != nil {
+		// Any key closes the preview, esc does nothing else
+		r.preview = nil
+		if key.Matches(msg, DefaultKeyMap.Back) {
+			return m, nil
+		}
+	}
+	switch {
+	case key.Matches(msg, DefaultKeyMap.Back):
+		r.state = browsingShares
+		r.browsedShare = nil
+		r.browseErr = nil
+		return m, nil
+	case key.Matches(msg, DefaultKeyMap.Pull):
+		nodes := r.fileTree.Selected()
+		if len(nodes) == 0 {
+			if node := r.fileTree.GetSelectedNode(); node != nil {
+				nodes = append(nodes, *node)
+			}
+		}
+		if len(nodes) == 0 || r.browsedShare == nil {
+			return m, nil
+		}
+		paths := make([]string, 0, len(nodes))
+		for _, node := range nodes {
+			paths = append(paths, node.Path)
+		}
+		m.receiverController.AppEvents() <- receiverEvent.PullFiles{Share: *r.browsedShare, Paths: paths}
+		return m, nil
+	case key.Matches(msg, DefaultKeyMap.Preview):
+		node := r.fileTree.GetSelectedNode()
+		if node == nil || node.IsDir || r.browsedShare == nil {
+			return m, nil
+		}
+		m.receiverController.AppEvents() <- receiverEvent.PreviewShareFile{Share: *r.browsedShare, Path: node.Path}
+		return m, nil
+	}
+	newFileTree, cmd := r.fileTree.Update(msg)
+	r.fileTree = newFileTree.(fileTree.Model)
+	return m, cmd
+}
+
+// browseView renders the devices sharing files, or the share being browsed
+func (m model) browseView() string {
+	r := m.receiver
+	var b strings.Builder
+	if r.state == browsingShare {
+		b.WriteString(r.fileTree.View())
+		b.WriteString("\n")
+		if r.preview != nil {
+			b.WriteString("\n" + previewView(*r.preview) + "\n")
+		}
+	} else {
+		b.WriteString("\n\n " + style.TitleStyle.Render("Devices sharing files") + "\n\n")
+		if len(r.shares) == 0 {
+			b.WriteString(fmt.Sprintf(" %s Looking for shares...\n", r.spinner.View()))
+		}
+		for i, shared := range r.shares {
+			cursor := style.NoCursorStyle.String()
+			if i == r.shareCursor {
+				cursor = style.CursorStyle.String()
+			}
+			name := util.WithIcon(util.DeviceIcon(shared.Meta.Icon), util.SanitizeText(shared.Name))
+			b.WriteString(fmt.Sprintf("%s %s %s\n", cursor, name, style.HelpStyle.Render(shared.Addr.String())))
+		}
+	}
+	if r.browseErr != nil {
+		b.WriteString("\n " + style.ErrorStyle.Render(r.browseErr.Error()) + "\n")
+	}
+
+	help := fmt.Sprintf("  enter/Browse  %s/%s \n", DefaultKeyMap.Back.Help().Key, DefaultKeyMap.Back.Help().Desc)
+	if r.state == browsingShare {
+		help = fmt.Sprintf("  %s/%s  %s/%s  %s/%s \n",
+			DefaultKeyMap.Pull.Help().Key, DefaultKeyMap.Pull.Help().Desc,
+			DefaultKeyMap.Preview.Help().Key, DefaultKeyMap.Preview.Help().Desc,
+			DefaultKeyMap.Back.Help().Key, DefaultKeyMap.Back.Help().Desc,
+		)
+	}
+	b.WriteString("\n" + style.HelpStyle.Render(help))
+	return b.String()
+}
+
+// previewView renders the start of a file of a share: the first lines of text, or the
+// detected type and a hex dump of the first bytes of anything else
+func previewView(preview receiverEvent.SharePreviewMsg) string {
+	var b strings.Builder
+	b.WriteString(style.TitleStyle.Render(fmt.Sprintf("Preview of %s (first %s of %s)",
+		preview.Path, util.FormatSize(int64(len(preview.Data))), util.FormatSize(preview.Size))))
+	b.WriteString("\n\n")
+
+	if text, ok := previewText(preview.Data); ok {
+		lines := strings.Split(text, "\n")
+		if len(lines) > previewLines {
+			lines = append(lines[:previewLines], "...")
+		}
+		b.WriteString(strings.Join(lines, "\n"))
+		return b.String()
+	}
+	b.WriteString(style.HelpStyle.Render(http.DetectContentType(preview.Data)))
+	b.WriteString("\n")
+	b.WriteString(hex.Dump(preview.Data[:min(len(preview.Data), previewDumpBytes)]))
+	return b.String()
+}
+
+// previewText returns data as text if it is, ignoring a character cut off at its end
+func previewText(data []byte) (string, bool) {
+	if bytes.IndexByte(data, 0) >= 0 {
+		return "", false
+	}
+	// The fetched bytes may end in the middle of a character
+	for i := 0; i < utf8.UTFMax-1 && len(data) > 0 && !utf8.Valid(data); i++ {
+		data = data[:len(data)-1]
+	}
+	if !utf8.Valid(data) {
+		return "", false
+	}
+	return strings.ReplaceAll(string(data), "\t", "    "), true
+}
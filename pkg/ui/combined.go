@@ -0,0 +1,236 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/spinner"
+	tea "github.com/charmbracelet/bubbletea"
+	appevents "github.com/rescp17/lanFileSharer/internal/app_events"
+	receiverEvent "github.com/rescp17/lanFileSharer/internal/app_events/receiver"
+	senderEvent "github.com/rescp17/lanFileSharer/internal/app_events/sender"
+	"github.com/rescp17/lanFileSharer/internal/style"
+	"github.com/rescp17/lanFileSharer/pkg/ui/components"
+)
+
+// combinedTab identifies a tab of the combined mode
+type combinedTab int
+
+const (
+	sendTab combinedTab = iota
+	receiveTab
+	historyTab
+)
+
+// historyEntry is a finished transfer shown in the History tab
+type historyEntry struct {
+	time     time.Time
+	incoming bool
+	summary  string
+	err      error
+}
+
+// combinedModel holds the state of Both mode beyond the sender and receiver models
+type combinedModel struct {
+	tabs    *components.TabBar
+	history []historyEntry
+}
+
+// CombinedKeyMap holds the keys that switch tabs in combined mode
+type CombinedKeyMap struct {
+	SendTab    key.Binding
+	ReceiveTab key.Binding
+	HistoryTab key.Binding
+	NextTab    key.Binding
+}
+
+// DefaultCombinedKeyMap provides the default tab keybindings.
+var DefaultCombinedKeyMap = CombinedKeyMap{
+	SendTab:    key.NewBinding(key.WithKeys("alt+1"), key.WithHelp("alt+1", "Send")),
+	ReceiveTab: key.NewBinding(key.WithKeys("alt+2"), key.WithHelp("alt+2", "Receive")),
+	HistoryTab: key.NewBinding(key.WithKeys("alt+3"), key.WithHelp("alt+3", "History")),
+	NextTab:    key.NewBinding(key.WithKeys("ctrl+n"), key.WithHelp("ctrl+n", "Next tab")),
+}
+
+func initCombinedModel() combinedModel {
+	tabs := components.NewTabBar()
+	tabs.AddTab("Send", "send", "📤", true)
+	tabs.AddTab("Receive", "receive", "📥", true)
+	tabs.AddTab("History", "history", "📜", true)
+	return combinedModel{tabs: tabs}
+}
+
+func (m model) activeTab() combinedTab {
+	switch m.combined.tabs.GetActiveTab().Value {
+	case "receive":
+		return receiveTab
+	case "history":
+		return historyTab
+	default:
+		return sendTab
+	}
+}
+
+func (m *model) updateCombined(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if keyMsg, ok := msg.(tea.KeyMsg); ok {
+		switch {
+		case keyMsg.Type == tea.KeyCtrlC:
+			m.cancel()
+			return m, tea.Quit
+		case key.Matches(keyMsg, DefaultCombinedKeyMap.SendTab):
+			m.combined.tabs.SetActiveTab(int(sendTab))
+			return m, nil
+		case key.Matches(keyMsg, DefaultCombinedKeyMap.ReceiveTab):
+			m.combined.tabs.SetActiveTab(int(receiveTab))
+			m.updateTabBadges()
+			return m, nil
+		case key.Matches(keyMsg, DefaultCombinedKeyMap.HistoryTab):
+			m.combined.tabs.SetActiveTab(int(historyTab))
+			return m, nil
+		case key.Matches(keyMsg, DefaultCombinedKeyMap.NextTab):
+			m.combined.tabs.NextTab()
+			m.updateTabBadges()
+			return m, nil
+		}
+
+		// Keys only reach the side that is shown
+		switch m.activeTab() {
+		case sendTab:
+			return m.updateSender(msg)
+		case receiveTab:
+			return m.updateReceiver(msg)
+		}
+		return m, nil
+	}
+
+	if _, ok := msg.(tea.MouseMsg); ok {
+		// Like keys, clicks only reach the side that is shown
+		switch m.activeTab() {
+		case sendTab:
+			return m.updateSender(msg)
+		case receiveTab:
+			return m.updateReceiver(msg)
+		}
+		return m, nil
+	}
+
+	if isReceiverUIMessage(msg) || isReceiverMessage(msg) {
+		m.recordReceiverHistory(msg)
+		model, cmd := m.updateReceiver(msg)
+		m.updateTabBadges()
+		return model, cmd
+	}
+
+	m.recordSenderHistory(msg)
+	_, cmd := m.updateSender(msg)
+	switch msg.(type) {
+	case spinner.TickMsg, tea.WindowSizeMsg:
+		// Both sides animate and lay out even while hidden
+		_, receiverCmd := m.updateReceiver(msg)
+		cmd = tea.Batch(cmd, receiverCmd)
+	}
+	m.updateTabBadges()
+	return m, cmd
+}
+
+// recordReceiverHistory adds finished or failed receptions to the history
+func (m *model) recordReceiverHistory(msg tea.Msg) {
+	switch msg := msg.(type) {
+	case receiverEvent.TransferFinishedMsg:
+		summary := fmt.Sprintf("Received %d file(s)", m.receiver.lastProgress.CompletedFiles)
+		if msg.OutputPath != "" {
+			summary += " into " + msg.OutputPath
+		}
+		m.addHistory(historyEntry{incoming: true, summary: summary, err: msg.Err})
+	case receiverFailedMsg:
+		m.addHistory(historyEntry{incoming: true, summary: "Receive failed", err: msg.err})
+	}
+}
+
+// recordSenderHistory adds finished, cancelled or failed sends to the history
+func (m *model) recordSenderHistory(msg tea.Msg) {
+	peer := m.sender.receiverName()
+	switch msg := msg.(type) {
+	case senderEvent.TransferCompleteMsg:
+		summary := fmt.Sprintf("Sent to %s", peer)
+		if p := m.sender.transferProgress; p != nil {
+			summary = fmt.Sprintf("Sent %d file(s) to %s", p.TotalFiles, peer)
+		}
+		m.addHistory(historyEntry{summary: summary})
+	case senderEvent.TransferCancelledMsg:
+		m.addHistory(historyEntry{summary: fmt.Sprintf("Send to %s cancelled", peer)})
+	case appevents.Error:
+		m.addHistory(historyEntry{summary: "Send failed", err: msg.Err})
+	}
+}
+
+func (m *model) addHistory(entry historyEntry) {
+	entry.time = time.Now()
+	m.combined.history = append(m.combined.history, entry)
+}
+
+// updateTabBadges flags an incoming request on the Receive tab and counts the history
+func (m *model) updateTabBadges() {
+	tabs := m.combined.tabs
+	if m.receiver.state == awaitingConfirmation && m.activeTab() != receiveTab {
+		tabs.SetTabBadge(int(receiveTab), "!", style.ErrorStyle)
+	} else {
+		tabs.SetTabBadge(int(receiveTab), "", style.ErrorStyle)
+	}
+	if n := len(m.combined.history); n > 0 {
+		tabs.SetTabBadge(int(historyTab), fmt.Sprintf("%d", n), style.HighlightFontStyle)
+	}
+}
+
+func (m model) combinedView() string {
+	var b strings.Builder
+	b.WriteString(m.combined.tabs.Render())
+	b.WriteString("\n")
+
+	switch m.activeTab() {
+	case sendTab:
+		b.WriteString(m.senderView())
+	case receiveTab:
+		b.WriteString(m.receiverView())
+	case historyTab:
+		b.WriteString(m.historyView())
+	}
+
+	keys := DefaultCombinedKeyMap
+	help := fmt.Sprintf("  %s/%s  %s/%s  %s/%s  %s/%s \n",
+		keys.SendTab.Help().Key, keys.SendTab.Help().Desc,
+		keys.ReceiveTab.Help().Key, keys.ReceiveTab.Help().Desc,
+		keys.HistoryTab.Help().Key, keys.HistoryTab.Help().Desc,
+		keys.NextTab.Help().Key, keys.NextTab.Help().Desc,
+	)
+	b.WriteString("\n")
+	b.WriteString(style.HelpStyle.Render(help))
+	return b.String()
+}
+
+// historyView lists the transfers of this session, newest first
+func (m model) historyView() string {
+	if len(m.combined.history) == 0 {
+		return "\n No transfers yet.\n"
+	}
+
+	var b strings.Builder
+	b.WriteString("\n")
+	for i := len(m.combined.history) - 1; i >= 0; i-- {
+		entry := m.combined.history[i]
+		arrow := "↑"
+		if entry.incoming {
+			arrow = "↓"
+		}
+		line := fmt.Sprintf(" %s %s %s", entry.time.Format("15:04:05"), arrow, entry.summary)
+		if entry.err != nil {
+			b.WriteString(style.ErrorStyle.Render(fmt.Sprintf("%s: %v", line, entry.err)))
+		} else {
+			b.WriteString(line)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
@@ -81,6 +81,31 @@ func (lc *LineChart) AddPoint(x, y float64, label string) {
 	}
 }
 
+// SetHistory replaces the points with a whole rate history, averaged into at most
+// maxPoints buckets so that a long session still fits the chart
+func (lc *LineChart) SetHistory(history []RatePoint) {
+	lc.points = lc.points[:0]
+	buckets := min(len(history), lc.maxPoints)
+	for i := 0; i < buckets; i++ {
+		start := i * len(history) / buckets
+		end := (i + 1) * len(history) / buckets
+		var sum float64
+		for _, point := range history[start:end] {
+			sum += point.Rate
+		}
+		last := history[end-1]
+		lc.points = append(lc.points, ChartPoint{
+			X:    float64(last.Timestamp.Unix()),
+			Y:    sum / float64(end-start),
+			Time: last.Timestamp,
+		})
+	}
+
+	if lc.autoScale {
+		lc.updateScale()
+	}
+}
+
 // updateScale updates the Y-axis scale based on current points
 func (lc *LineChart) updateScale() {
 	if len(lc.points) == 0 {
@@ -105,7 +130,12 @@ func (lc *LineChart) updateScale() {
 		range_ = 1
 	}
 	padding := range_ * 0.1
-	lc.minY -= padding
+	// Rates and sizes are never negative, so the padding does not go below zero
+	if lc.minY >= 0 {
+		lc.minY = max(lc.minY-padding, 0)
+	} else {
+		lc.minY -= padding
+	}
 	lc.maxY += padding
 }
 
@@ -216,11 +246,12 @@ func (lc *LineChart) addYAxisLabels(lines []string) {
 		lineIndex := chartHeight - int(float64(i)/4.0*float64(chartHeight)) // Invert Y
 
 		if lineIndex >= 1 && lineIndex < lc.height-1 {
-			label := lc.formatYValue(y)
+			label := []rune(lc.formatYValue(y))
 			// Place label on the left side
-			if len(label) < lc.width-3 {
-				line := lines[lineIndex]
-				lines[lineIndex] = line[:1] + label + line[1+len(label):]
+			line := []rune(lines[lineIndex])
+			if len(label) < len(line)-2 {
+				copy(line[1:], label)
+				lines[lineIndex] = string(line)
 			}
 		}
 	}
@@ -228,12 +259,20 @@ func (lc *LineChart) addYAxisLabels(lines []string) {
 
 // formatYValue formats a Y-axis value
 func (lc *LineChart) formatYValue(value float64) string {
-	if lc.yAxisLabel == "rate" {
-		return lc.formatRateValue(value)[:6] // Truncate for space
-	} else if lc.yAxisLabel == "bytes" {
-		return lc.formatBytesValue(int64(value))[:6]
-	}
-	return fmt.Sprintf("%.1f", value)[:6]
+	var label string
+	switch lc.yAxisLabel {
+	case "rate":
+		label = lc.formatRateValue(value)
+	case "bytes":
+		label = lc.formatBytesValue(int64(value))
+	default:
+		label = fmt.Sprintf("%.1f", value)
+	}
+	// Truncate for space
+	if len(label) > 6 {
+		label = label[:6]
+	}
+	return label
 }
 
 // formatRateValue formats a rate value
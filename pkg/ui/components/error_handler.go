@@ -19,6 +19,8 @@ const (
 	ErrorTypeTimeout
 	ErrorTypeUserCancelled
 	ErrorTypeUnknown
+	// ErrorTypeIntegrity is received data that failed checksum verification
+	ErrorTypeIntegrity
 )
 
 // ErrorInfo contains detailed information about an error
@@ -216,6 +218,8 @@ func (eh *ErrorHandler) getErrorIcon(errorType ErrorType) string {
 		return "⏰"
 	case ErrorTypeUserCancelled:
 		return "🚫"
+	case ErrorTypeIntegrity:
+		return "🧩"
 	default:
 		return "❌"
 	}
@@ -234,6 +238,8 @@ func (eh *ErrorHandler) getErrorTypeString(errorType ErrorType) string {
 		return "Timeout Error"
 	case ErrorTypeUserCancelled:
 		return "User Cancelled"
+	case ErrorTypeIntegrity:
+		return "Integrity Error"
 	default:
 		return "Unknown Error"
 	}
@@ -252,6 +258,8 @@ func (eh *ErrorHandler) getErrorStyle(errorType ErrorType) lipgloss.Style {
 		return lipgloss.NewStyle().Foreground(lipgloss.Color("226")) // Yellow
 	case ErrorTypeUserCancelled:
 		return lipgloss.NewStyle().Foreground(lipgloss.Color("244")) // Gray
+	case ErrorTypeIntegrity:
+		return lipgloss.NewStyle().Foreground(lipgloss.Color("201")) // Magenta
 	default:
 		return style.ErrorStyle
 	}
@@ -270,6 +278,8 @@ func (eh *ErrorHandler) getMaxRetriesForType(errorType ErrorType) int {
 		return 2
 	case ErrorTypeUserCancelled:
 		return 0
+	case ErrorTypeIntegrity:
+		return 2
 	default:
 		return 1
 	}
@@ -310,6 +320,12 @@ func (eh *ErrorHandler) getSuggestionsForType(errorType ErrorType) []string {
 			"Press Enter to try again",
 			"Select different files if needed",
 		}
+	case ErrorTypeIntegrity:
+		return []string{
+			"Try sending the file again",
+			"Check that the file is not being modified while it is sent",
+			"Check the receiver's disk for errors",
+		}
 	default:
 		return []string{
 			"Try restarting the application",
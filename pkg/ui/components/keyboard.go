@@ -113,6 +113,10 @@ func (km *KeyboardManager) initializeDefaultBindings() {
 			{[]string{"a"}, KeyActionSelect, "Select all", "file_selection", true, false},
 			{[]string{"ctrl+a"}, KeyActionSelect, "Select all", "file_selection", true, false},
 		},
+		"confirm_send": {
+			{[]string{"y"}, KeyActionConfirm, "Send the files", "confirm_send", true, false},
+			{[]string{"n", "esc"}, KeyActionBack, "Change the selection", "confirm_send", true, false},
+		},
 		"transfer": {
 			{[]string{"p"}, KeyActionPause, "Pause transfer", "transfer", true, false},
 			{[]string{"r"}, KeyActionResume, "Resume transfer", "transfer", true, false},
@@ -133,6 +137,10 @@ func (km *KeyboardManager) initializeDefaultBindings() {
 			{[]string{"r", "enter"}, KeyActionRetry, "Retry operation", "error", true, false},
 			{[]string{"c", "esc"}, KeyActionCancel, "Cancel", "error", true, false},
 		},
+		"timeout": {
+			{[]string{"r", "enter"}, KeyActionRetry, "Send the request again", "timeout", true, false},
+			{[]string{"esc"}, KeyActionBack, "Choose other files", "timeout", true, false},
+		},
 		"complete": {
 			{[]string{"enter"}, KeyActionConfirm, "Continue", "complete", true, false},
 			{[]string{"esc"}, KeyActionBack, "Go back", "complete", true, false},
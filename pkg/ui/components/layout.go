@@ -5,6 +5,7 @@ import (
 	"strings"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/rescp17/lanFileSharer/internal/util"
 )
 
 // LayoutBreakpoint represents different screen size breakpoints
@@ -322,12 +323,9 @@ func (rl *ResponsiveLayout) GetContentHeight() int {
 	return height
 }
 
-// TruncateText truncates text based on current layout settings
+// TruncateText truncates text based on current layout settings, by its width on screen
 func (rl *ResponsiveLayout) TruncateText(text string) string {
-	if len(text) <= rl.truncateLength {
-		return text
-	}
-	return text[:rl.truncateLength-3] + "..."
+	return util.Truncate(text, rl.truncateLength)
 }
 
 // FormatText formats text based on current layout settings
@@ -336,15 +334,8 @@ func (rl *ResponsiveLayout) FormatText(text string, maxWidth int) string {
 		maxWidth = rl.GetContentWidth()
 	}
 	
-	if len(text) <= maxWidth {
-		return text
-	}
-	
-	if maxWidth <= 3 {
-		return "..."
-	}
-	
-	return text[:maxWidth-3] + "..."
+	// Too narrow for more than the ellipsis
+	return util.Truncate(text, max(maxWidth, 3))
 }
 
 // CreateColumns creates a multi-column layout
@@ -6,6 +6,7 @@ import (
 
 	"github.com/charmbracelet/lipgloss"
 	"github.com/rescp17/lanFileSharer/internal/style"
+	"github.com/rescp17/lanFileSharer/internal/util"
 )
 
 // NavigationMode represents different navigation modes
@@ -47,7 +48,7 @@ func NewBreadcrumb(maxItems int) *Breadcrumb {
 // AddItem adds an item to the breadcrumb
 func (b *Breadcrumb) AddItem(label, value, icon string, clickable bool) {
 	item := BreadcrumbItem{
-		Label:     label,
+		Label:     util.SanitizeText(label),
 		Value:     value,
 		Icon:      icon,
 		Clickable: clickable,
@@ -359,7 +360,7 @@ func NewStatusBar(width int) *StatusBar {
 // AddLeftItem adds an item to the left side of the status bar
 func (sb *StatusBar) AddLeftItem(text, icon string, itemStyle lipgloss.Style) {
 	item := StatusItem{
-		Text:  text,
+		Text:  util.SanitizeText(text),
 		Icon:  icon,
 		Style: itemStyle,
 	}
@@ -369,7 +370,7 @@ func (sb *StatusBar) AddLeftItem(text, icon string, itemStyle lipgloss.Style) {
 // AddRightItem adds an item to the right side of the status bar
 func (sb *StatusBar) AddRightItem(text, icon string, itemStyle lipgloss.Style) {
 	item := StatusItem{
-		Text:  text,
+		Text:  util.SanitizeText(text),
 		Icon:  icon,
 		Style: itemStyle,
 	}
@@ -379,7 +380,7 @@ func (sb *StatusBar) AddRightItem(text, icon string, itemStyle lipgloss.Style) {
 // AddCenterItem adds an item to the center of the status bar
 func (sb *StatusBar) AddCenterItem(text, icon string, itemStyle lipgloss.Style) {
 	item := StatusItem{
-		Text:  text,
+		Text:  util.SanitizeText(text),
 		Icon:  icon,
 		Style: itemStyle,
 	}
@@ -410,8 +411,10 @@ func (sb *StatusBar) Render() string {
 	centerText := sb.renderItems(sb.centerItems)
 
 	// Calculate spacing
-	leftLen := lipgloss.Width(leftText)
 	rightLen := lipgloss.Width(rightText)
+	// Long items are cut to leave room for the right side rather than wrapping the bar
+	leftText = util.Truncate(leftText, max(sb.width-rightLen, 0))
+	leftLen := lipgloss.Width(leftText)
 	centerLen := lipgloss.Width(centerText)
 
 	availableWidth := sb.width - leftLen - rightLen
@@ -429,7 +432,7 @@ func (sb *StatusBar) Render() string {
 		result.WriteString(strings.Repeat(" ", rightPadding))
 	} else {
 		// Fill remaining space
-		result.WriteString(strings.Repeat(" ", availableWidth))
+		result.WriteString(strings.Repeat(" ", max(availableWidth, 0)))
 	}
 
 	result.WriteString(rightText)
@@ -572,6 +575,37 @@ func (cm *ContextualMenu) Navigate(action KeyAction) bool {
 	return false
 }
 
+// ClearItems removes all items from the menu
+func (cm *ContextualMenu) ClearItems() {
+	cm.items = cm.items[:0]
+	cm.selectedIndex = 0
+}
+
+// ItemAt returns the index of the item drawn on line y of the rendered menu, or -1
+func (cm *ContextualMenu) ItemAt(y int) int {
+	// The top border comes first, then the title and its separator
+	first := 1
+	if cm.title != "" {
+		first = 3
+	}
+	i := y - first
+	if i < 0 || i >= len(cm.items) {
+		return -1
+	}
+	return i
+}
+
+// SelectAt selects and activates the item drawn on line y of the rendered menu,
+// as if it had been chosen with the keyboard. It returns false if there is no enabled item there.
+func (cm *ContextualMenu) SelectAt(y int) bool {
+	i := cm.ItemAt(y)
+	if !cm.visible || i < 0 || cm.items[i].Separator || !cm.items[i].Enabled {
+		return false
+	}
+	cm.selectedIndex = i
+	return cm.Navigate(KeyActionSelect)
+}
+
 // GetSelectedItem returns the currently selected menu item
 func (cm *ContextualMenu) GetSelectedItem() *MenuItem {
 	if cm.selectedIndex >= 0 && cm.selectedIndex < len(cm.items) {
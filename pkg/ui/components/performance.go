@@ -9,8 +9,13 @@ import (
 
 	"github.com/charmbracelet/lipgloss"
 	"github.com/rescp17/lanFileSharer/internal/style"
+	"github.com/rescp17/lanFileSharer/pkg/system"
 )
 
+// diskBottleneckThreshold is the fraction of time spent writing to disk above which
+// the disk rather than the network limits a reception
+const diskBottleneckThreshold = 0.8
+
 // PerformanceMetrics contains system performance metrics
 type PerformanceMetrics struct {
 	CPUUsage        float64       `json:"cpu_usage"`
@@ -24,9 +29,26 @@ type PerformanceMetrics struct {
 	TransferRate    float64       `json:"transfer_rate"`
 	ConnectionCount int           `json:"connection_count"`
 	ErrorRate       float64       `json:"error_rate"`
+	RSS             uint64        `json:"rss"`
+	OpenFiles       int           `json:"open_files"`
+	DiskWriteRate   float64       `json:"disk_write_rate"` // bytes per second while writing
+	DiskBusy        float64       `json:"disk_busy"`       // fraction of time spent writing (0-1)
+	Paths           []PathMetrics `json:"paths,omitempty"` // paths of a bonded transfer
 	Timestamp       time.Time     `json:"timestamp"`
 }
 
+// PathMetrics is what one network path of a transfer striped over several interfaces carried
+type PathMetrics struct {
+	Name  string  `json:"name"`
+	Bytes int64   `json:"bytes"`
+	Rate  float64 `json:"rate"` // bytes per second
+}
+
+// DiskBound reports whether writing to disk, not the network, limits the transfer
+func (pm PerformanceMetrics) DiskBound() bool {
+	return pm.DiskBusy > diskBottleneckThreshold
+}
+
 // PerformanceOptimizer manages performance optimization
 type PerformanceOptimizer struct {
 	mu                sync.RWMutex
@@ -55,6 +77,18 @@ type PerformanceOptimizer struct {
 
 	// Optimization recommendations
 	recommendations []OptimizationRecommendation
+
+	// Process statistics and the latest transfer figures reported by the UI
+	sampler       processSampler
+	transferRate  float64
+	diskWriteRate float64
+	diskBusy      float64
+	paths         []PathMetrics
+}
+
+// processSampler samples the resource usage of the process, see system.ProcessSampler
+type processSampler interface {
+	Sample() system.ProcessStats
 }
 
 // OptimizationLevel represents different levels of optimization
@@ -110,9 +144,33 @@ func NewPerformanceOptimizer() *PerformanceOptimizer {
 		memoryThreshold:   80.0,
 		latencyThreshold:  200 * time.Millisecond,
 		recommendations:   make([]OptimizationRecommendation, 0),
+		sampler:           system.NewProcessSampler(),
 	}
 }
 
+// RecordTransferRate records the current transfer rate for the next metrics sample
+func (po *PerformanceOptimizer) RecordTransferRate(rate float64) {
+	po.mu.Lock()
+	defer po.mu.Unlock()
+	po.transferRate = rate
+}
+
+// RecordDiskWrite records the receiver's disk write rate and the fraction of time
+// it spent writing for the next metrics sample
+func (po *PerformanceOptimizer) RecordDiskWrite(rate, busy float64) {
+	po.mu.Lock()
+	defer po.mu.Unlock()
+	po.diskWriteRate = rate
+	po.diskBusy = busy
+}
+
+// RecordPaths records the paths of a bonded transfer for the next metrics sample
+func (po *PerformanceOptimizer) RecordPaths(paths []PathMetrics) {
+	po.mu.Lock()
+	defer po.mu.Unlock()
+	po.paths = paths
+}
+
 // CollectMetrics collects current performance metrics
 func (po *PerformanceOptimizer) CollectMetrics() PerformanceMetrics {
 	var m runtime.MemStats
@@ -125,10 +183,20 @@ func (po *PerformanceOptimizer) CollectMetrics() PerformanceMetrics {
 		GCPauseTime:    time.Duration(m.PauseNs[(m.NumGC+255)%256]),
 		Timestamp:      time.Now(),
 	}
+	if proc := po.sampler.Sample(); proc.Supported {
+		metrics.CPUUsage = proc.CPUPercent
+		metrics.RSS = proc.RSS
+		metrics.OpenFiles = proc.OpenFiles
+	}
 
 	po.mu.Lock()
 	defer po.mu.Unlock()
 
+	metrics.TransferRate = po.transferRate
+	metrics.DiskWriteRate = po.diskWriteRate
+	metrics.DiskBusy = po.diskBusy
+	metrics.Paths = po.paths
+
 	// Add to metrics history
 	po.metrics = append(po.metrics, metrics)
 	if len(po.metrics) > po.maxMetrics {
@@ -189,6 +257,31 @@ func (po *PerformanceOptimizer) generateRecommendations(metrics PerformanceMetri
 		})
 	}
 
+	// High CPU usage recommendation
+	if metrics.CPUUsage > po.cpuThreshold {
+		po.addRecommendation(OptimizationRecommendation{
+			Type:        "cpu",
+			Title:       "High CPU Usage",
+			Description: fmt.Sprintf("CPU usage is at %.1f%%, consider reducing concurrency", metrics.CPUUsage),
+			Impact:      "medium",
+			Action:      "reduce_concurrency",
+			Timestamp:   now,
+		})
+	}
+
+	// Disk bottleneck recommendation
+	if metrics.DiskBound() {
+		po.addRecommendation(OptimizationRecommendation{
+			Type:  "disk",
+			Title: "Disk Is the Bottleneck",
+			Description: fmt.Sprintf("Writing to disk %.0f%% of the time at %s, the disk rather than the network limits the transfer",
+				metrics.DiskBusy*100, formatRateSimple(metrics.DiskWriteRate)),
+			Impact:    "high",
+			Action:    "check_disk",
+			Timestamp: now,
+		})
+	}
+
 	// High goroutine count recommendation
 	if metrics.GoroutineCount > po.maxGoroutines {
 		po.addRecommendation(OptimizationRecommendation{
@@ -213,8 +306,8 @@ func (po *PerformanceOptimizer) generateRecommendations(metrics PerformanceMetri
 		})
 	}
 
-	// Low transfer rate recommendation
-	if po.peakTransferRate > 0 && metrics.TransferRate < po.peakTransferRate*0.5 {
+	// Low transfer rate recommendation, unless the disk already explains it
+	if po.peakTransferRate > 0 && metrics.TransferRate < po.peakTransferRate*0.5 && !metrics.DiskBound() {
 		po.addRecommendation(OptimizationRecommendation{
 			Type:        "transfer",
 			Title:       "Low Transfer Rate",
@@ -527,6 +620,13 @@ func (pp *PerformancePanel) renderMetricsTab() string {
 		formatBytesSimple(int64(latest.MemoryTotal)),
 		memPercent))
 
+	// Process statistics from the operating system
+	if latest.RSS > 0 {
+		result.WriteString(fmt.Sprintf("CPU: %.1f%%\n", latest.CPUUsage))
+		result.WriteString(fmt.Sprintf("RSS: %s\n", formatBytesSimple(int64(latest.RSS))))
+		result.WriteString(fmt.Sprintf("Open Files: %d\n", latest.OpenFiles))
+	}
+
 	// Goroutines
 	result.WriteString(fmt.Sprintf("Goroutines: %d\n", latest.GoroutineCount))
 
@@ -544,11 +644,32 @@ func (pp *PerformancePanel) renderMetricsTab() string {
 		result.WriteString(fmt.Sprintf("Transfer Rate: %s\n", formatRateSimple(latest.TransferRate)))
 	}
 
+	// Paths of a transfer striped over several interfaces
+	if len(latest.Paths) > 0 {
+		result.WriteString("Paths:\n")
+		for _, path := range latest.Paths {
+			result.WriteString(fmt.Sprintf("  %s: %s, %s sent\n",
+				path.Name, formatRateSimple(path.Rate), formatBytesSimple(path.Bytes)))
+		}
+	}
+
+	// Disk write throughput of a reception
+	if latest.DiskWriteRate > 0 {
+		result.WriteString(fmt.Sprintf("Disk Write: %s (busy %.0f%%)\n",
+			formatRateSimple(latest.DiskWriteRate), latest.DiskBusy*100))
+	}
+
 	// Connection count
 	if latest.ConnectionCount > 0 {
 		result.WriteString(fmt.Sprintf("Connections: %d\n", latest.ConnectionCount))
 	}
 
+	if latest.DiskBound() {
+		result.WriteString("\n")
+		result.WriteString(style.ErrorStyle.Render("⚠ Disk is the bottleneck, not the network"))
+		result.WriteString("\n")
+	}
+
 	return result.String()
 }
 
@@ -0,0 +1,144 @@
+package components
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rescp17/lanFileSharer/pkg/system"
+)
+
+// fakeSampler reports stats instead of the usage of the test process
+type fakeSampler struct {
+	stats system.ProcessStats
+}
+
+func (f *fakeSampler) Sample() system.ProcessStats { return f.stats }
+
+// newTestOptimizer returns an optimizer sampling sampler
+func newTestOptimizer(sampler *fakeSampler) *PerformanceOptimizer {
+	po := NewPerformanceOptimizer()
+	po.sampler = sampler
+	return po
+}
+
+// recommendationTypes returns the types of the recommendations of po
+func recommendationTypes(po *PerformanceOptimizer) []string {
+	var types []string
+	for _, rec := range po.GetRecommendations() {
+		types = append(types, rec.Type)
+	}
+	return types
+}
+
+// countType returns how many of types are want
+func countType(types []string, want string) int {
+	n := 0
+	for _, typ := range types {
+		if typ == want {
+			n++
+		}
+	}
+	return n
+}
+
+func TestPerformanceOptimizer_ProcessStats(t *testing.T) {
+	sampler := &fakeSampler{stats: system.ProcessStats{CPUPercent: 42, RSS: 64 << 20, OpenFiles: 17, Supported: true}}
+	po := newTestOptimizer(sampler)
+	po.RecordTransferRate(5 << 20)
+	po.RecordDiskWrite(8<<20, 0.5)
+
+	metrics := po.CollectMetrics()
+	assert.Equal(t, 42.0, metrics.CPUUsage)
+	assert.Equal(t, uint64(64<<20), metrics.RSS)
+	assert.Equal(t, 17, metrics.OpenFiles)
+	assert.Equal(t, float64(5<<20), metrics.TransferRate)
+	assert.Equal(t, float64(8<<20), metrics.DiskWriteRate)
+	assert.Equal(t, 0.5, metrics.DiskBusy)
+
+	// Platforms without process statistics report none, rather than a sample of zeros
+	sampler.stats = system.ProcessStats{CPUPercent: 99, RSS: 1, Supported: false}
+	metrics = po.CollectMetrics()
+	assert.Zero(t, metrics.CPUUsage)
+	assert.Zero(t, metrics.RSS)
+	assert.Zero(t, metrics.OpenFiles)
+}
+
+func TestPerformanceOptimizer_Alerts(t *testing.T) {
+	tests := []struct {
+		name     string
+		cpu      float64
+		diskBusy float64
+		alerts   []string
+		quiet    []string
+	}{
+		{"Idle", 10, 0.2, nil, []string{"cpu", "disk"}},
+		{"CPU at the threshold", 80, 0, nil, []string{"cpu"}},
+		{"High CPU", 95, 0, []string{"cpu"}, []string{"disk"}},
+		{"Disk at the threshold", 0, diskBottleneckThreshold, nil, []string{"disk"}},
+		{"Disk bound", 0, 0.9, []string{"disk"}, []string{"cpu"}},
+		{"CPU and disk", 95, 0.95, []string{"cpu", "disk"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			po := newTestOptimizer(&fakeSampler{stats: system.ProcessStats{CPUPercent: tt.cpu, Supported: true}})
+			po.RecordDiskWrite(20<<20, tt.diskBusy)
+
+			metrics := po.CollectMetrics()
+			assert.Equal(t, tt.diskBusy > diskBottleneckThreshold, metrics.DiskBound())
+			types := recommendationTypes(po)
+			for _, alert := range tt.alerts {
+				assert.Contains(t, types, alert)
+			}
+			for _, alert := range tt.quiet {
+				assert.NotContains(t, types, alert)
+			}
+		})
+	}
+}
+
+func TestPerformanceOptimizer_AlertsOnce(t *testing.T) {
+	po := newTestOptimizer(&fakeSampler{stats: system.ProcessStats{CPUPercent: 95, Supported: true}})
+	po.RecordDiskWrite(20<<20, 0.9)
+	po.CollectMetrics()
+	po.CollectMetrics()
+
+	var disk []OptimizationRecommendation
+	for _, rec := range po.GetRecommendations() {
+		if rec.Type == "disk" {
+			disk = append(disk, rec)
+		}
+	}
+	require.Len(t, disk, 1, "a lasting bottleneck is reported once")
+	assert.Equal(t, "Writing to disk 90% of the time at 20.0 MB/s, the disk rather than the network limits the transfer", disk[0].Description)
+	assert.Equal(t, 1, countType(recommendationTypes(po), "cpu"))
+}
+
+func TestPerformanceOptimizer_DiskExplainsLowRate(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		diskBusy float64
+		alert    string
+		quiet    string
+	}{
+		{"Network", 0.3, "transfer", "disk"},
+		{"Disk", 0.9, "disk", "transfer"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			po := newTestOptimizer(&fakeSampler{})
+			po.RecordTransferRate(100 << 20)
+			po.CollectMetrics()
+
+			// The rate drops to a tenth of its peak
+			po.RecordTransferRate(10 << 20)
+			po.RecordDiskWrite(10<<20, tt.diskBusy)
+			po.CollectMetrics()
+
+			types := recommendationTypes(po)
+			assert.Contains(t, types, tt.alert)
+			assert.NotContains(t, types, tt.quiet)
+		})
+	}
+}
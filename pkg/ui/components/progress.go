@@ -7,6 +7,7 @@ import (
 
 	"github.com/charmbracelet/lipgloss"
 	"github.com/rescp17/lanFileSharer/internal/style"
+	"github.com/rescp17/lanFileSharer/internal/util"
 )
 
 // ProgressBarConfig defines the configuration for a progress bar
@@ -100,7 +101,7 @@ func (pb *ProgressBar) renderFull() string {
 
 	// Add label if provided
 	if pb.data.Label != "" {
-		result.WriteString(fmt.Sprintf("%s\n", style.HeaderStyle.Render(pb.data.Label)))
+		result.WriteString(fmt.Sprintf("%s\n", style.HeaderStyle.Render(util.SanitizeText(pb.data.Label))))
 	}
 
 	// Render the progress bar
@@ -330,7 +331,7 @@ func (mfp *MultiFileProgress) Render() string {
 				statusIcon := mfp.getFileStatusIcon(file.Status)
 				result.WriteString(fmt.Sprintf("%s %s %s\n", 
 					statusIcon, 
-					style.FileStyle.Render(file.Name), 
+					style.FileStyle.Render(util.SanitizeText(file.Name)), 
 					fileBar.Render()))
 				activeFiles++
 			}
@@ -3,11 +3,13 @@ package components
 import (
 	"fmt"
 	"math"
+	"slices"
 	"sort"
 	"strings"
 	"time"
 
 	"github.com/rescp17/lanFileSharer/internal/style"
+	"github.com/rescp17/lanFileSharer/pkg/transfer"
 )
 
 // TransferMetrics contains detailed transfer metrics
@@ -51,6 +53,20 @@ type FileMetrics struct {
 	RetryCount       int
 }
 
+// maxSessionHistory bounds the session rate history. Beyond it neighbouring points
+// are merged, so the history keeps covering the whole session at a coarser step.
+const maxSessionHistory = 3600
+
+// RateSummary describes the transfer rate over a whole session
+type RateSummary struct {
+	Samples  int
+	Min      float64 // bytes per second
+	Average  float64
+	Peak     float64
+	StdDev   float64
+	Duration time.Duration
+}
+
 // AdvancedStatsCollector collects and analyzes transfer statistics
 type AdvancedStatsCollector struct {
 	metrics        TransferMetrics
@@ -58,6 +74,19 @@ type AdvancedStatsCollector struct {
 	maxHistory     int
 	updateInterval time.Duration
 	lastUpdate     time.Time
+
+	// Session history, unlike RateHistory, is never trimmed
+	sessionHistory []RatePoint
+	sessionStep    int // rate samples averaged into one session point
+	pendingRate    float64
+	pendingCount   int
+	summary        RateSummary
+	rateMean       float64
+	rateM2         float64 // sum of squared deviations, for the standard deviation
+	firstSample    time.Time
+
+	// Time the finished files spent in each transfer phase, by phase name
+	phases map[string]time.Duration
 }
 
 // NewAdvancedStatsCollector creates a new advanced statistics collector
@@ -72,6 +101,7 @@ func NewAdvancedStatsCollector(maxHistory int, updateInterval time.Duration) *Ad
 		maxHistory:     maxHistory,
 		updateInterval: updateInterval,
 		lastUpdate:     time.Now(),
+		sessionStep:    1,
 	}
 }
 
@@ -161,6 +191,85 @@ func (asc *AdvancedStatsCollector) addRatePoint(timestamp time.Time, rate float6
 	if len(asc.metrics.RateHistory) > asc.maxHistory {
 		asc.metrics.RateHistory = asc.metrics.RateHistory[1:]
 	}
+
+	asc.addSessionPoint(point)
+}
+
+// addSessionPoint records point in the session summary and history
+func (asc *AdvancedStatsCollector) addSessionPoint(point RatePoint) {
+	// Welford's algorithm keeps the statistics exact however the history is compacted
+	s := &asc.summary
+	if s.Samples == 0 {
+		asc.firstSample = point.Timestamp
+		s.Min, s.Peak = point.Rate, point.Rate
+	}
+	s.Samples++
+	delta := point.Rate - asc.rateMean
+	asc.rateMean += delta / float64(s.Samples)
+	asc.rateM2 += delta * (point.Rate - asc.rateMean)
+	s.Min = math.Min(s.Min, point.Rate)
+	s.Peak = math.Max(s.Peak, point.Rate)
+	s.Average = asc.rateMean
+	s.StdDev = math.Sqrt(asc.rateM2 / float64(s.Samples))
+	s.Duration = point.Timestamp.Sub(asc.firstSample)
+
+	asc.pendingRate += point.Rate
+	asc.pendingCount++
+	if asc.pendingCount < asc.sessionStep {
+		return
+	}
+	asc.sessionHistory = append(asc.sessionHistory, RatePoint{
+		Timestamp: point.Timestamp,
+		Rate:      asc.pendingRate / float64(asc.pendingCount),
+		Bytes:     point.Bytes,
+	})
+	asc.pendingRate, asc.pendingCount = 0, 0
+
+	if len(asc.sessionHistory) > maxSessionHistory {
+		merged := asc.sessionHistory[:0]
+		for i := 0; i+1 < len(asc.sessionHistory); i += 2 {
+			a, b := asc.sessionHistory[i], asc.sessionHistory[i+1]
+			merged = append(merged, RatePoint{Timestamp: b.Timestamp, Rate: (a.Rate + b.Rate) / 2, Bytes: b.Bytes})
+		}
+		asc.sessionHistory = merged
+		asc.sessionStep *= 2
+	}
+}
+
+// SessionHistory returns the rate history of the whole session, oldest first
+func (asc *AdvancedStatsCollector) SessionHistory() []RatePoint {
+	return slices.Clone(asc.sessionHistory)
+}
+
+// SessionSummary returns the min, average, peak and standard deviation of the rate over the session
+func (asc *AdvancedStatsCollector) SessionSummary() RateSummary {
+	return asc.summary
+}
+
+// RenderSessionSummary renders a graph of the rate over the whole session followed by its
+// statistics, or "" if no rate was recorded
+func (asc *AdvancedStatsCollector) RenderSessionSummary(width, height int) string {
+	if asc.summary.Samples == 0 {
+		return ""
+	}
+
+	chart := NewLineChart("📊 Session Bandwidth", width, height, width-2)
+	chart.SetLabels("Time", "rate")
+	chart.SetHistory(asc.sessionHistory)
+
+	s := asc.summary
+	return chart.Render() + fmt.Sprintf("Min %s  Avg %s  Peak %s  σ %s  over %s\n",
+		formatRate(s.Min), formatRate(s.Average), formatRate(s.Peak), formatRate(s.StdDev), formatDuration(s.Duration))
+}
+
+// UpdatePhaseTimings replaces the time the finished files spent in each transfer phase
+func (asc *AdvancedStatsCollector) UpdatePhaseTimings(phases map[string]time.Duration) {
+	asc.phases = phases
+}
+
+// GetPhaseTimings returns the time the finished files spent in each transfer phase
+func (asc *AdvancedStatsCollector) GetPhaseTimings() map[string]time.Duration {
+	return asc.phases
 }
 
 // GetMetrics returns the current transfer metrics
@@ -377,10 +486,46 @@ func (rtsp *RealTimeStatsPanel) renderOverview() string {
 	return result.String()
 }
 
-// renderDetailed renders detailed statistics
+// renderDetailed renders the overview followed by where the time of the session went:
+// each transfer phase with its share of the elapsed time
 func (rtsp *RealTimeStatsPanel) renderDetailed() string {
-	// Implementation for detailed view
-	return rtsp.renderOverview() + "\n\n" + "Press 'D' for detailed view (coming soon)"
+	var result strings.Builder
+	result.WriteString(rtsp.renderOverview())
+	result.WriteString("\n")
+	result.WriteString(style.HeaderStyle.Render("⏳ Where the time went"))
+	result.WriteString("\n")
+
+	phases := rtsp.collector.GetPhaseTimings()
+	if len(phases) == 0 {
+		result.WriteString("No file finished yet\n")
+		return result.String()
+	}
+
+	const barWidth = 20
+	elapsed := time.Since(rtsp.collector.GetMetrics().StartTime)
+	var slowest string
+	for _, phase := range transfer.TransferPhases {
+		d := phases[string(phase)]
+		if d <= 0 {
+			continue
+		}
+		if slowest == "" || d > phases[slowest] {
+			slowest = string(phase)
+		}
+		share := 0.0
+		if elapsed > 0 {
+			share = min(d.Seconds()/elapsed.Seconds(), 1)
+		}
+		bar := int(share * barWidth)
+		result.WriteString(fmt.Sprintf("%-13s %s%s %8s %3.0f%%\n", phase,
+			strings.Repeat("█", bar), strings.Repeat("░", barWidth-bar), formatDuration(d), share*100))
+	}
+	result.WriteString(fmt.Sprintf("Most time: %s", slowest))
+	if phases[string(transfer.PhaseWriting)] > 0 {
+		result.WriteString(" (writing overlaps transferring)")
+	}
+	result.WriteString("\n")
+	return result.String()
 }
 
 // renderFiles renders file-specific statistics
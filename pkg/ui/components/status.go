@@ -7,6 +7,7 @@ import (
 
 	"github.com/charmbracelet/lipgloss"
 	"github.com/rescp17/lanFileSharer/internal/style"
+	"github.com/rescp17/lanFileSharer/internal/util"
 )
 
 // StatusLevel represents the severity level of a status
@@ -60,9 +61,9 @@ func (si *StatusIndicator) AddMessage(level StatusLevel, message string) {
 func (si *StatusIndicator) AddDetailedMessage(level StatusLevel, message, details, action string) {
 	msg := StatusMessage{
 		Level:     level,
-		Message:   message,
+		Message:   util.SanitizeText(message),
 		Timestamp: time.Now(),
-		Details:   details,
+		Details:   util.SanitizeText(details),
 		Action:    action,
 	}
 
@@ -326,6 +327,7 @@ type TransferStatsPanel struct {
 	averageRate     float64
 	peakRate        float64
 	networkQuality  *NetworkQualityIndicator
+	retryPolicy     string
 	compact         bool
 }
 
@@ -342,6 +344,11 @@ func (tsp *TransferStatsPanel) SetCompact(compact bool) {
 	tsp.compact = compact
 }
 
+// SetRetryPolicy sets the summary of the active retry policy shown in the panel
+func (tsp *TransferStatsPanel) SetRetryPolicy(summary string) {
+	tsp.retryPolicy = summary
+}
+
 // Update updates the transfer statistics
 func (tsp *TransferStatsPanel) Update(totalFiles, completedFiles, failedFiles int, 
 	totalBytes, transferredBytes int64, currentRate, averageRate, peakRate float64) {
@@ -396,6 +403,9 @@ func (tsp *TransferStatsPanel) renderFull() string {
 	// Time statistics
 	elapsed := time.Since(tsp.startTime)
 	result.WriteString(fmt.Sprintf("Elapsed: %s\n", formatDuration(elapsed)))
+	if tsp.retryPolicy != "" {
+		result.WriteString(fmt.Sprintf("Retry Policy: %s\n", tsp.retryPolicy))
+	}
 
 	// Network quality
 	result.WriteString(fmt.Sprintf("\n%s\n", tsp.networkQuality.Render()))
@@ -441,10 +451,11 @@ func formatRate(rate float64) string {
 }
 
 func formatDuration(d time.Duration) string {
+	d = d.Round(time.Second)
 	if d < time.Minute {
-		return fmt.Sprintf("%.0fs", d.Seconds())
+		return fmt.Sprintf("%ds", int(d.Seconds()))
 	} else if d < time.Hour {
-		return fmt.Sprintf("%.0fm %.0fs", d.Minutes(), d.Seconds()-60*d.Minutes())
+		return fmt.Sprintf("%dm %ds", int(d.Minutes()), int(d.Seconds())%60)
 	}
-	return fmt.Sprintf("%.0fh %.0fm", d.Hours(), d.Minutes()-60*d.Hours())
+	return fmt.Sprintf("%dh %dm", int(d.Hours()), int(d.Minutes())%60)
 }
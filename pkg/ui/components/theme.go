@@ -1,11 +1,19 @@
 package components
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/lipgloss"
 )
@@ -63,12 +71,19 @@ type Theme struct {
 	AnimationSpeed   int  `json:"animation_speed"` // milliseconds
 }
 
+// ThemesDirName is the directory custom themes are kept in, under the config directory
+const ThemesDirName = "themes"
+
 // ThemeManager manages themes and provides styling utilities
 type ThemeManager struct {
 	currentTheme    *Theme
 	availableThemes map[string]*Theme
+	builtinThemes   map[string]bool
 	configDir       string
 	styles          *ThemeStyles
+
+	// themeFiles is the modification time of every custom theme file when it was loaded
+	themeFiles map[string]time.Time
 }
 
 // ThemeStyles contains pre-computed lipgloss styles for the current theme
@@ -117,16 +132,29 @@ func NewThemeManager(configDir string) *ThemeManager {
 
 	// Initialize built-in themes
 	tm.initializeBuiltinThemes()
+	tm.builtinThemes = make(map[string]bool, len(tm.availableThemes))
+	for name := range tm.availableThemes {
+		tm.builtinThemes[name] = true
+	}
 
 	// Load custom themes from config directory
 	tm.loadCustomThemes()
 
-	// Set default theme
-	tm.SetTheme("default")
+	// Set the default theme that is readable on the terminal background
+	tm.SetTheme(DefaultThemeName())
 
 	return tm
 }
 
+// DefaultThemeName returns the built-in theme for the terminal background: "light" if the
+// terminal reports a light background, through an OSC 11 query or COLORFGBG, and "default" otherwise
+func DefaultThemeName() string {
+	if lipgloss.HasDarkBackground() {
+		return "default"
+	}
+	return "light"
+}
+
 // initializeBuiltinThemes creates the built-in themes
 func (tm *ThemeManager) initializeBuiltinThemes() {
 	// Default theme (dark)
@@ -279,45 +307,231 @@ func (tm *ThemeManager) initializeBuiltinThemes() {
 	tm.availableThemes["compact"] = compactTheme
 }
 
-// loadCustomThemes loads custom themes from the config directory
-func (tm *ThemeManager) loadCustomThemes() {
+// ThemesDir returns the directory custom themes are loaded from, or "" without a config directory
+func (tm *ThemeManager) ThemesDir() string {
 	if tm.configDir == "" {
-		return
+		return ""
+	}
+	return filepath.Join(tm.configDir, ThemesDirName)
+}
+
+// loadCustomThemes loads custom themes from the config directory.
+// Invalid theme files are skipped, and their errors returned.
+func (tm *ThemeManager) loadCustomThemes() []error {
+	tm.themeFiles = tm.scanThemeFiles()
+
+	var errs []error
+	for themePath := range tm.themeFiles {
+		theme, err := loadThemeFromFile(themePath)
+		if err != nil {
+			slog.Warn("Skipping invalid theme", "error", err)
+			errs = append(errs, err)
+			continue
+		}
+		tm.availableThemes[theme.Name] = theme
 	}
+	return errs
+}
 
-	themesDir := filepath.Join(tm.configDir, "themes")
-	if _, err := os.Stat(themesDir); os.IsNotExist(err) {
-		return
+// scanThemeFiles returns the modification time of every theme file in the themes directory
+func (tm *ThemeManager) scanThemeFiles() map[string]time.Time {
+	files := make(map[string]time.Time)
+	themesDir := tm.ThemesDir()
+	if themesDir == "" {
+		return files
 	}
 
-	files, err := os.ReadDir(themesDir)
+	entries, err := os.ReadDir(themesDir)
 	if err != nil {
-		return
+		return files
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		files[filepath.Join(themesDir, entry.Name())] = info.ModTime()
 	}
+	return files
+}
 
-	for _, file := range files {
-		if filepath.Ext(file.Name()) == ".json" {
-			themePath := filepath.Join(themesDir, file.Name())
-			if theme := tm.loadThemeFromFile(themePath); theme != nil {
-				tm.availableThemes[theme.Name] = theme
+// ReloadCustomThemes loads the custom themes again if a theme file was added, changed or
+// removed since they were loaded, so theme authors see their edits without a restart.
+// It reports whether anything changed, and the errors of invalid theme files.
+func (tm *ThemeManager) ReloadCustomThemes() (bool, []error) {
+	files := tm.scanThemeFiles()
+	if len(files) == len(tm.themeFiles) {
+		unchanged := true
+		for path, modTime := range files {
+			if loaded, ok := tm.themeFiles[path]; !ok || !loaded.Equal(modTime) {
+				unchanged = false
+				break
 			}
 		}
+		if unchanged {
+			return false, nil
+		}
+	}
+
+	for name := range tm.availableThemes {
+		if !tm.builtinThemes[name] {
+			delete(tm.availableThemes, name)
+		}
 	}
+	tm.initializeBuiltinThemes()
+	errs := tm.loadCustomThemes()
+
+	// Keep showing the current theme, or its new version; a removed theme stays until another is chosen
+	if tm.currentTheme != nil {
+		if theme, ok := tm.availableThemes[tm.currentTheme.Name]; ok {
+			tm.currentTheme = theme
+			tm.updateStyles()
+		}
+	}
+	return true, errs
 }
 
-// loadThemeFromFile loads a theme from a JSON file
-func (tm *ThemeManager) loadThemeFromFile(path string) *Theme {
+// loadThemeFromFile loads and validates a theme from a JSON file
+func loadThemeFromFile(path string) (*Theme, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
-		return nil
+		return nil, fmt.Errorf("failed to read theme: %w", err)
 	}
 
+	theme, err := ParseTheme(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return theme, nil
+}
+
+// themeColor matches the colors a theme may use: hex RGB, RGBA or ANSI color numbers
+var themeColor = regexp.MustCompile(`^(#[0-9a-fA-F]{3}|#[0-9a-fA-F]{6}|#[0-9a-fA-F]{8}|[0-9]{1,3})$`)
+
+// ParseTheme decodes and validates a theme. Errors point at the line and field at fault.
+func ParseTheme(data []byte) (*Theme, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
 	var theme Theme
-	if err := json.Unmarshal(data, &theme); err != nil {
-		return nil
+	if err := decoder.Decode(&theme); err != nil {
+		var syntaxErr *json.SyntaxError
+		var typeErr *json.UnmarshalTypeError
+		switch {
+		case errors.As(err, &syntaxErr):
+			// The offset is past the byte at fault
+			line, column := lineColumn(data, max(syntaxErr.Offset-1, 0))
+			return nil, fmt.Errorf("invalid JSON at line %d, column %d: %w", line, column, err)
+		case errors.As(err, &typeErr):
+			line, _ := lineColumn(data, typeErr.Offset)
+			return nil, fmt.Errorf("line %d: %q must be a %s, not a %s", line, typeErr.Field, typeErr.Type, typeErr.Value)
+		case errors.Is(err, io.EOF):
+			return nil, errors.New("theme file is empty")
+		case strings.HasPrefix(err.Error(), "json: unknown field"):
+			return nil, fmt.Errorf("%s, check its spelling", strings.TrimPrefix(err.Error(), "json: "))
+		}
+		return nil, fmt.Errorf("invalid theme: %w", err)
+	}
+
+	if err := validateTheme(&theme); err != nil {
+		return nil, err
+	}
+	return &theme, nil
+}
+
+// validateTheme checks that theme has a usable name and a valid value for every color
+func validateTheme(theme *Theme) error {
+	switch {
+	case theme.Name == "":
+		return errors.New(`"name" is missing`)
+	case strings.ContainsAny(theme.Name, `/\`) || theme.Name == "." || theme.Name == "..":
+		return fmt.Errorf(`"name" %q must not contain path separators`, theme.Name)
+	}
+
+	cs := theme.ColorScheme
+	colors := []struct {
+		field string
+		value string
+	}{
+		{"primary", cs.Primary}, {"secondary", cs.Secondary}, {"accent", cs.Accent},
+		{"background", cs.Background}, {"surface", cs.Surface},
+		{"text_primary", cs.TextPrimary}, {"text_secondary", cs.TextSecondary}, {"text_muted", cs.TextMuted},
+		{"success", cs.Success}, {"warning", cs.Warning}, {"error", cs.Error}, {"info", cs.Info},
+		{"border", cs.Border}, {"highlight", cs.Highlight}, {"selection", cs.Selection}, {"progress", cs.Progress},
+	}
+	for _, color := range colors {
+		if color.value == "" {
+			return fmt.Errorf(`"color_scheme.%s" is missing`, color.field)
+		}
+		if !themeColor.MatchString(color.value) {
+			return fmt.Errorf(`"color_scheme.%s" is %q, use a hex color like "#58a6ff" or an ANSI color number`,
+				color.field, color.value)
+		}
+		if n, err := strconv.Atoi(color.value); err == nil && n > 255 {
+			return fmt.Errorf(`"color_scheme.%s" is %d, ANSI color numbers go up to 255`, color.field, n)
+		}
+	}
+	return nil
+}
+
+// lineColumn returns the 1-based line and column of the byte at offset in data
+func lineColumn(data []byte, offset int64) (int, int) {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	before := data[:offset]
+	line := bytes.Count(before, []byte("\n")) + 1
+	column := len(before) - bytes.LastIndexByte(before, '\n')
+	return line, column
+}
+
+// ImportTheme validates the theme file at path and copies it to the themes directory.
+// Built-in themes cannot be replaced; an imported theme of the same name is.
+func (tm *ThemeManager) ImportTheme(path string) (*Theme, error) {
+	theme, err := loadThemeFromFile(path)
+	if err != nil {
+		return nil, err
 	}
+	if tm.builtinThemes[theme.Name] {
+		return nil, fmt.Errorf("%q is a built-in theme, give the theme another name", theme.Name)
+	}
+	if err := tm.SaveTheme(theme); err != nil {
+		return nil, fmt.Errorf("failed to save theme: %w", err)
+	}
+	tm.availableThemes[theme.Name] = theme
+	return theme, nil
+}
+
+// ExportTheme writes the theme called name to w as JSON, ready to be edited and imported
+func (tm *ThemeManager) ExportTheme(name string, w io.Writer) error {
+	theme, exists := tm.availableThemes[name]
+	if !exists {
+		return fmt.Errorf("theme '%s' not found", name)
+	}
+	data, err := json.MarshalIndent(theme, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
 
-	return &theme
+// ThemeNames returns the names of all available themes, sorted
+func (tm *ThemeManager) ThemeNames() []string {
+	names := make([]string, 0, len(tm.availableThemes))
+	for name := range tm.availableThemes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// IsBuiltinTheme reports whether name is one of the themes that come with the application
+func (tm *ThemeManager) IsBuiltinTheme(name string) bool {
+	return tm.builtinThemes[name]
 }
 
 // SetTheme sets the current theme
@@ -467,7 +681,7 @@ func (tm *ThemeManager) SaveTheme(theme *Theme) error {
 		return fmt.Errorf("config directory not set")
 	}
 
-	themesDir := filepath.Join(tm.configDir, "themes")
+	themesDir := tm.ThemesDir()
 	if err := os.MkdirAll(themesDir, 0755); err != nil {
 		return err
 	}
@@ -542,6 +756,14 @@ func NewThemeSelector(themeManager *ThemeManager) *ThemeSelector {
 
 // Show shows the theme selector
 func (ts *ThemeSelector) Show() {
+	// Themes may have been added or removed since the list was built
+	ts.themes = ts.themeManager.ThemeNames()
+	ts.selectedIndex = 0
+	for i, name := range ts.themes {
+		if name == ts.themeManager.GetCurrentTheme().Name {
+			ts.selectedIndex = i
+		}
+	}
 	ts.visible = true
 	ts.originalTheme = ts.themeManager.GetCurrentTheme().Name
 }
@@ -0,0 +1,131 @@
+package components
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// themeJSON returns an importable theme as JSON, after edit changes it
+func themeJSON(t *testing.T, edit func(theme *Theme)) string {
+	t.Helper()
+	theme := *NewThemeManager("").GetAvailableThemes()["default"]
+	theme.Name = "ocean"
+	if edit != nil {
+		edit(&theme)
+	}
+	data, err := json.MarshalIndent(theme, "", "  ")
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestThemeManager_ImportThemeErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		message string
+	}{
+		{
+			name:    "Empty file",
+			data:    "",
+			message: "theme file is empty",
+		},
+		{
+			name:    "Trailing comma",
+			data:    "{\n  \"name\": \"ocean\",\n}",
+			message: "invalid JSON at line 3, column 1: invalid character '}' looking for beginning of object key string",
+		},
+		{
+			name:    "Missing comma",
+			data:    "{\n  \"name\": \"ocean\"\n  \"dark_mode\": true\n}",
+			message: "invalid JSON at line 3, column 3: invalid character '\"' after object key:value pair",
+		},
+		{
+			name:    "Unknown key",
+			data:    strings.Replace(themeJSON(t, nil), `"dark_mode"`, `"darkmode"`, 1),
+			message: `unknown field "darkmode", check its spelling`,
+		},
+		{
+			name:    "Unknown color key",
+			data:    strings.Replace(themeJSON(t, nil), `"primary"`, `"primary_color"`, 1),
+			message: `unknown field "primary_color", check its spelling`,
+		},
+		{
+			name:    "Wrong type",
+			data:    "{\n  \"name\": \"ocean\",\n  \"font_size\": \"large\"\n}",
+			message: `line 3: "font_size" must be a int, not a string`,
+		},
+		{
+			name:    "Missing name",
+			data:    themeJSON(t, func(theme *Theme) { theme.Name = "" }),
+			message: `"name" is missing`,
+		},
+		{
+			name:    "Name with a path",
+			data:    themeJSON(t, func(theme *Theme) { theme.Name = "../ocean" }),
+			message: `"name" "../ocean" must not contain path separators`,
+		},
+		{
+			name:    "Missing color",
+			data:    themeJSON(t, func(theme *Theme) { theme.ColorScheme.Border = "" }),
+			message: `"color_scheme.border" is missing`,
+		},
+		{
+			name:    "Color name",
+			data:    themeJSON(t, func(theme *Theme) { theme.ColorScheme.Primary = "blue" }),
+			message: `"color_scheme.primary" is "blue", use a hex color like "#58a6ff" or an ANSI color number`,
+		},
+		{
+			name:    "Short hex color",
+			data:    themeJSON(t, func(theme *Theme) { theme.ColorScheme.Accent = "#12345" }),
+			message: `"color_scheme.accent" is "#12345", use a hex color like "#58a6ff" or an ANSI color number`,
+		},
+		{
+			name:    "ANSI color out of range",
+			data:    themeJSON(t, func(theme *Theme) { theme.ColorScheme.Error = "300" }),
+			message: `"color_scheme.error" is 300, ANSI color numbers go up to 255`,
+		},
+		{
+			name:    "Built-in name",
+			data:    themeJSON(t, func(theme *Theme) { theme.Name = "light" }),
+			message: `"light" is a built-in theme, give the theme another name`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tm := NewThemeManager(t.TempDir())
+			path := filepath.Join(t.TempDir(), "ocean.json")
+			require.NoError(t, os.WriteFile(path, []byte(tt.data), 0644))
+
+			_, err := tm.ImportTheme(path)
+			require.Error(t, err)
+			// Errors of the file itself name it, so the user knows which file to fix
+			assert.Equal(t, tt.message, strings.TrimPrefix(err.Error(), path+": "))
+			assert.NotContains(t, tm.ThemeNames(), "ocean")
+			entries, _ := os.ReadDir(tm.ThemesDir())
+			assert.Empty(t, entries, "nothing is installed")
+		})
+	}
+}
+
+func TestThemeManager_ImportTheme(t *testing.T) {
+	configDir := t.TempDir()
+	tm := NewThemeManager(configDir)
+	path := filepath.Join(t.TempDir(), "ocean.json")
+	require.NoError(t, os.WriteFile(path, []byte(themeJSON(t, func(theme *Theme) {
+		theme.ColorScheme.Primary = "39"
+		theme.ColorScheme.Highlight = "#fff"
+	})), 0644))
+
+	theme, err := tm.ImportTheme(path)
+	require.NoError(t, err)
+	assert.Equal(t, "ocean", theme.Name)
+	assert.FileExists(t, filepath.Join(configDir, ThemesDirName, "ocean.json"))
+	assert.Contains(t, NewThemeManager(configDir).ThemeNames(), "ocean", "the imported theme is loaded on the next start")
+}
@@ -0,0 +1,631 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"net"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	appevents "github.com/rescp17/lanFileSharer/internal/app_events"
+	receiverEvent "github.com/rescp17/lanFileSharer/internal/app_events/receiver"
+	senderEvent "github.com/rescp17/lanFileSharer/internal/app_events/sender"
+	"github.com/rescp17/lanFileSharer/internal/config"
+	"github.com/rescp17/lanFileSharer/internal/version"
+	"github.com/rescp17/lanFileSharer/pkg/discovery"
+	"github.com/rescp17/lanFileSharer/pkg/events"
+	"github.com/rescp17/lanFileSharer/pkg/fileInfo"
+)
+
+const (
+	// demoTick is how often simulated transfers report progress
+	demoTick = 100 * time.Millisecond
+	// demoTransferTime is roughly how long a simulated transfer takes, whatever its size, so
+	// large selections finish and small ones can still be watched
+	demoTransferTime = 12 * time.Second
+	// demoAcceptDelay is how long simulated receivers take to accept a request
+	demoAcceptDelay = 1500 * time.Millisecond
+	// demoRequestInterval is the pause between the requests of the simulated senders
+	demoRequestInterval = 4 * time.Second
+	// demoAcceptTimeout is how long a simulated request waits for the user before it expires
+	demoAcceptTimeout = 30 * time.Second
+)
+
+// InitialDemoModel is InitialModel driven by simulated apps instead of the real ones: senders
+// find made-up receivers, receivers get requests from made-up senders, and transfers report
+// synthetic progress. Nothing is announced, discovered, sent or written, so the UI and themes
+// can be worked on without a second machine. Files are still picked from the local disk.
+func InitialDemoModel(m Mode, port int, outputPath string, cfg config.Config) model {
+	var senderController, receiverController AppController
+	var sender senderModel
+	var receiver receiverModel
+	var combined combinedModel
+
+	switch m {
+	case Sender:
+		senderController, sender = newDemoSender(cfg)
+	case Receiver:
+		receiverController, receiver = newDemoReceiver(port, outputPath)
+	case Both:
+		senderController, sender = newDemoSender(cfg)
+		receiverController, receiver = newDemoReceiver(port, outputPath)
+		combined = initCombinedModel()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return model{
+		mode:               m,
+		senderController:   senderController,
+		receiverController: receiverController,
+		sender:             sender,
+		receiver:           receiver,
+		combined:           combined,
+		config:             cfg,
+		ctx:                ctx,
+		cancel:             cancel,
+		terminal:           newTerminalReporter(),
+	}
+}
+
+// demoApp is the part of the simulated apps that talks to the UI
+type demoApp struct {
+	uiMessages chan tea.Msg
+	appEvents  chan appevents.AppEvent
+}
+
+func newDemoApp() demoApp {
+	return demoApp{uiMessages: make(chan tea.Msg, 32), appEvents: make(chan appevents.AppEvent)}
+}
+
+// UIMessages returns the messages of the simulated app
+func (d *demoApp) UIMessages() <-chan tea.Msg {
+	return d.uiMessages
+}
+
+// AppEvents returns the channel the UI sends events to the simulated app on
+func (d *demoApp) AppEvents() chan<- appevents.AppEvent {
+	return d.appEvents
+}
+
+// send hands msg to the UI, reporting false if ctx ended first
+func (d *demoApp) send(ctx context.Context, msg tea.Msg) bool {
+	select {
+	case d.uiMessages <- msg:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// demoSleep waits for duration, reporting false if ctx ended first
+func demoSleep(ctx context.Context, duration time.Duration) bool {
+	select {
+	case <-time.After(duration):
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// demoReceivers are the receivers the simulated sender finds, one for each state the
+// receiver list shows
+var demoReceivers = []discovery.ServiceInfo{
+	{Name: "studio-imac", Addr: net.IPv4(192, 168, 1, 20), Port: 8080, Meta: discovery.ServiceMeta{
+		Advertised: true, Version: version.Version, FreeBytes: 412 << 30, Wired: true, Icon: "🖥️",
+	}},
+	{Name: "pixel-tablet", Addr: net.IPv4(192, 168, 1, 34), Port: 8080, Meta: discovery.ServiceMeta{
+		Advertised: true, Version: version.Version, FreeBytes: 23 << 30, AutoAccept: true, Icon: "📱",
+	}},
+	{Name: "nas", Addr: net.IPv4(192, 168, 1, 2), Port: 8080, Meta: discovery.ServiceMeta{
+		Advertised: true, Version: version.Version, FreeBytes: 3 << 40, Relay: true, Icon: "🗄️",
+	}},
+	{Name: "meeting-room", Addr: net.IPv4(192, 168, 1, 57), Port: 8080, Meta: discovery.ServiceMeta{
+		Advertised: true, Version: version.Version, FreeBytes: -1, DoNotDisturb: true,
+	}},
+	{Name: "old-laptop", Addr: net.IPv4(192, 168, 1, 81), Port: 8080},
+}
+
+// demoSender simulates the sender app: it finds demoReceivers and pretends to send the
+// selected files to them
+type demoSender struct {
+	demoApp
+
+	mu       sync.Mutex
+	paused   bool
+	wired    bool               // The running transfer was moved to the wired path
+	cancelTx context.CancelFunc // Stops the running transfer, nil if there is none
+}
+
+// newDemoSender creates the simulated sender app and its model
+func newDemoSender(cfg config.Config) (AppController, senderModel) {
+	sender := initSenderModel()
+	if cfg.Theme != "" {
+		if err := sender.themeManager.SetTheme(cfg.Theme); err != nil {
+			slog.Warn("Using the default theme", "error", err)
+		}
+	}
+	sender.fp.SetHashWorkers(cfg.HashWorkerCount())
+	sender.fp.SetEventBus(events.NewBus())
+	return &demoSender{demoApp: newDemoApp()}, sender
+}
+
+// Run finds the receivers and handles the events of the UI until ctx ends
+func (d *demoSender) Run(ctx context.Context) error {
+	go d.discover(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event := <-d.appEvents:
+			switch e := event.(type) {
+			case senderEvent.SendFilesMsg:
+				d.startTransfer(ctx, e.Receiver, e.Files)
+			case senderEvent.SendBatchMsg:
+				var files []fileInfo.FileNode
+				for _, item := range e.Items {
+					files = append(files, item.Files...)
+				}
+				d.startTransfer(ctx, e.Receiver, files)
+			case senderEvent.PauseTransferMsg:
+				d.setPaused(true)
+				d.send(ctx, senderEvent.TransferPausedMsg{Files: 1})
+			case senderEvent.ResumeTransferMsg:
+				d.setPaused(false)
+				d.send(ctx, senderEvent.TransferResumedMsg{Files: 1})
+			case senderEvent.CancelTransferMsg:
+				d.stopTransfer()
+				d.send(ctx, senderEvent.TransferCancelledMsg{})
+			case senderEvent.UseWiredPathMsg:
+				d.mu.Lock()
+				d.wired = true
+				d.mu.Unlock()
+				d.send(ctx, senderEvent.StatusUpdateMsg{Message: "Moved the transfer to eth0"})
+			}
+		}
+	}
+}
+
+// discover reports demoReceivers one after the other, as real receivers answer discovery
+func (d *demoSender) discover(ctx context.Context) {
+	for found := 1; found <= len(demoReceivers); found++ {
+		if !demoSleep(ctx, 700*time.Millisecond) {
+			return
+		}
+		d.send(ctx, senderEvent.FoundServicesMsg{Services: demoReceivers[:found]})
+	}
+}
+
+func (d *demoSender) setPaused(paused bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.paused = paused
+}
+
+// startTransfer stops the running transfer and simulates sending files to receiver
+func (d *demoSender) startTransfer(ctx context.Context, receiver discovery.ServiceInfo, files []fileInfo.FileNode) {
+	d.stopTransfer()
+	ctx, cancel := context.WithCancel(ctx)
+	d.mu.Lock()
+	d.cancelTx, d.paused, d.wired = cancel, false, false
+	d.mu.Unlock()
+	go d.transfer(ctx, receiver, files)
+}
+
+func (d *demoSender) stopTransfer() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.cancelTx != nil {
+		d.cancelTx()
+		d.cancelTx = nil
+	}
+}
+
+// transfer walks through a send as the sender app reports it: the request, its acceptance,
+// the speed probe, progress and the outcome. With three files or more the last one fails,
+// to show the list of failed files.
+func (d *demoSender) transfer(ctx context.Context, receiver discovery.ServiceInfo, files []fileInfo.FileNode) {
+	d.send(ctx, senderEvent.TransferStartedMsg{})
+	if receiver.Meta.DoNotDisturb {
+		if demoSleep(ctx, demoAcceptDelay) {
+			d.send(ctx, appevents.Error{Err: fmt.Errorf("%s declined the request: Do not disturb, try again later", receiver.Name)})
+		}
+		return
+	}
+	if !receiver.Meta.AutoAccept && !demoSleep(ctx, demoAcceptDelay) {
+		return
+	}
+	d.send(ctx, senderEvent.ReceiverAcceptedMsg{})
+
+	flat := demoFlatten(files, "")
+	var total int64
+	for _, file := range flat {
+		total += file.Size
+	}
+	rate := max(float64(total)/demoTransferTime.Seconds(), 256<<10)
+	d.send(ctx, senderEvent.SpeedProbeMsg{Rate: rate, TotalBytes: total, ETA: demoTransferTime})
+	if receiver.Meta.Wired {
+		d.send(ctx, senderEvent.WirelessPathMsg{Interface: "wlan0", Wired: []string{"eth0"}, TotalBytes: total})
+	}
+
+	var failed *fileInfo.FileNode
+	if len(flat) >= 3 {
+		failed = &flat[len(flat)-1]
+	}
+	started := time.Now()
+	var sent, fileSent int64
+	completed := 0
+	ticker := time.NewTicker(demoTick)
+	defer ticker.Stop()
+	for completed < len(flat) {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		d.mu.Lock()
+		paused, wired := d.paused, d.wired
+		d.mu.Unlock()
+		if paused {
+			continue
+		}
+
+		tickRate := rate * (0.8 + 0.4*rand.Float64())
+		if wired {
+			tickRate *= 1.5
+		}
+		budget := int64(tickRate * demoTick.Seconds())
+		for budget > 0 && completed < len(flat) {
+			step := min(budget, flat[completed].Size-fileSent)
+			fileSent += step
+			sent += step
+			budget -= step
+			if fileSent >= flat[completed].Size {
+				completed++
+				fileSent = 0
+			}
+		}
+
+		current := ""
+		if completed < len(flat) {
+			current = flat[completed].Path
+		}
+		elapsed := time.Since(started)
+		d.send(ctx, senderEvent.ProgressUpdateMsg{
+			TotalFiles:       len(flat),
+			CompletedFiles:   completed,
+			TotalBytes:       total,
+			TransferredBytes: sent,
+			CurrentFile:      current,
+			TransferRate:     tickRate,
+			FilesPerMinute:   float64(completed) / elapsed.Minutes(),
+			ETA:              (time.Duration(float64(total-sent)/tickRate) * time.Second).Round(time.Second).String(),
+			OverallProgress:  float64(sent) / float64(max(total, 1)) * 100,
+			PersistedBytes:   sent - min(sent, int64(tickRate/4)),
+			ReceiverDiskRate: tickRate,
+		})
+	}
+
+	if failed != nil {
+		d.send(ctx, senderEvent.FilesFailedMsg{
+			Receiver: receiver,
+			Files:    []senderEvent.FailedFile{{Path: failed.Path, Reason: "connection lost (simulated)"}},
+			Retry:    []fileInfo.FileNode{*failed},
+		})
+	}
+	d.send(ctx, senderEvent.TransferCompleteMsg{})
+}
+
+// demoFlatten returns the files of nodes, with their paths below dir when the nodes have none
+func demoFlatten(nodes []fileInfo.FileNode, dir string) []fileInfo.FileNode {
+	var files []fileInfo.FileNode
+	for _, node := range nodes {
+		if node.Path == "" {
+			node.Path = path.Join(dir, node.Name)
+		}
+		if node.IsDir {
+			files = append(files, demoFlatten(node.Children, node.Path)...)
+			continue
+		}
+		files = append(files, node)
+	}
+	return files
+}
+
+// demoRequest is a request of a simulated sender
+type demoRequest struct {
+	sender      string
+	icon        string
+	fingerprint string
+	trust       string
+	nodes       []fileInfo.FileNode
+}
+
+// demoFiles returns count files named pattern, numbered from 1, of about size bytes each
+func demoFiles(pattern string, count int, size int64) []fileInfo.FileNode {
+	files := make([]fileInfo.FileNode, count)
+	for i := range files {
+		files[i] = fileInfo.FileNode{Name: fmt.Sprintf(pattern, i+1), Size: size + int64(i%5)*size/7}
+	}
+	return files
+}
+
+// demoDir returns a directory node of children, sized by their sum
+func demoDir(name string, children ...fileInfo.FileNode) fileInfo.FileNode {
+	dir := fileInfo.FileNode{Name: name, IsDir: true, Children: children}
+	for _, child := range children {
+		dir.Size += child.Size
+	}
+	return dir
+}
+
+// demoRequests are the requests the simulated receiver gets in turn, one for each trust
+// status of the sender key
+var demoRequests = []demoRequest{
+	{
+		sender: "dana-laptop", icon: "💻", fingerprint: "3f9a1c0e5b7d2468ace13579bdf02468", trust: "trusted",
+		nodes: []fileInfo.FileNode{demoDir("Holiday photos", demoFiles("IMG_%04d.jpg", 24, 4<<20)...)},
+	},
+	{
+		sender: "ws-042", fingerprint: "b0c4e2f7a19d3856c2e4f6a8b0d2e4f6", trust: "unknown",
+		nodes: []fileInfo.FileNode{{Name: "quarterly-report.pdf", Size: 2516582}},
+	},
+	{
+		sender: "ci-runner", icon: "🛠️", fingerprint: "7e6d5c4b3a2918070f1e2d3c4b5a6978", trust: "stale",
+		nodes: []fileInfo.FileNode{demoDir("build",
+			demoDir("bin", demoFiles("tool-%d", 3, 18<<20)...),
+			demoDir("logs", demoFiles("step-%02d.log", 9, 96<<10)...),
+			fileInfo.FileNode{Name: "checksums.sha256", Size: 812},
+		)},
+	},
+}
+
+// demoShares are the devices the simulated receiver finds sharing files
+var demoShares = []discovery.ServiceInfo{
+	{Name: "dana-laptop", Addr: net.IPv4(192, 168, 1, 44), Port: 8080, Meta: discovery.ServiceMeta{
+		Advertised: true, Version: version.Version, FreeBytes: -1, Share: true, Icon: "💻",
+	}},
+}
+
+// demoShareTree is what demoShares share
+var demoShareTree = []fileInfo.FileNode{
+	demoDir("Designs", demoFiles("mockup-%d.png", 6, 900<<10)...),
+	demoDir("Docs", fileInfo.FileNode{Name: "README.md", Size: 4 << 10}, fileInfo.FileNode{Name: "notes.txt", Size: 1 << 10}),
+	{Name: "palette.json", Size: 2 << 10},
+}
+
+// demoReceiver simulates the receiver app: made-up senders ask to send demoRequests, which
+// are received with synthetic progress once accepted
+type demoReceiver struct {
+	demoApp
+	outputPath string
+	decisions  chan bool                // Answers to the pending request
+	pulls      chan []fileInfo.FileNode // Files of pulls, offered without asking
+	mu         sync.Mutex
+	available  receiverEvent.Availability // Whether requests are put to the user
+}
+
+// newDemoReceiver creates the simulated receiver app and its model
+func newDemoReceiver(port int, outputPath string) (AppController, receiverModel) {
+	d := &demoReceiver{
+		demoApp:    newDemoApp(),
+		outputPath: outputPath,
+		decisions:  make(chan bool, 1),
+		pulls:      make(chan []fileInfo.FileNode, 1),
+	}
+	return d, initReceiverModel(port)
+}
+
+// Run sends the requests of the simulated senders and handles the events of the UI until ctx ends
+func (d *demoReceiver) Run(ctx context.Context) error {
+	go d.request(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event := <-d.appEvents:
+			switch e := event.(type) {
+			case receiverEvent.FileRequestAccepted:
+				d.decide(true)
+			case receiverEvent.FileRequestRejected:
+				d.decide(false)
+			case receiverEvent.SetAvailability:
+				d.mu.Lock()
+				d.available = e.Availability
+				d.mu.Unlock()
+			case receiverEvent.BrowseShares:
+				d.send(ctx, receiverEvent.SharesFoundMsg{Shares: demoShares})
+			case receiverEvent.ListShare:
+				d.send(ctx, receiverEvent.ShareListingMsg{Share: e.Share.Name, Path: e.Path, Nodes: demoListing(e.Path)})
+			case receiverEvent.PullFiles:
+				d.send(ctx, receiverEvent.PullRequestedMsg{Share: e.Share.Name, Files: len(e.Paths)})
+				d.pull(demoPulled(e.Paths))
+			case receiverEvent.PullOffer:
+				d.send(ctx, receiverEvent.PullRequestedMsg{Share: e.Offer.Sender, Files: len(e.Offer.Paths)})
+				if e.Offer.Structure != nil {
+					d.pull(e.Offer.Structure.Files)
+				}
+			case receiverEvent.PreviewShareFile:
+				data := []byte(fmt.Sprintf("# %s\n\nThis is a simulated preview of a shared file.\n", path.Base(e.Path)))
+				d.send(ctx, receiverEvent.SharePreviewMsg{Share: e.Share.Name, Path: e.Path, Data: data, Size: int64(len(data))})
+			case receiverEvent.IssueGuestPass:
+				d.send(ctx, receiverEvent.GuestPassMsg{Token: "K7QD-M2XP", MaxBytes: 1 << 30, Expires: time.Now().Add(15 * time.Minute)})
+			}
+		}
+	}
+}
+
+// decide answers the pending request, dropping answers nobody waits for
+func (d *demoReceiver) decide(accepted bool) {
+	select {
+	case d.decisions <- accepted:
+	default:
+	}
+}
+
+// pull offers files as the answer to a pull, dropping them if another pull is pending
+func (d *demoReceiver) pull(files []fileInfo.FileNode) {
+	select {
+	case d.pulls <- files:
+	default:
+	}
+}
+
+// request sends demoRequests in turn, and the files of pulls, waiting for each to be
+// answered, to time out or to be received before the next
+func (d *demoReceiver) request(ctx context.Context) {
+	for next := 0; ; {
+		var update receiverEvent.FileNodeUpdateMsg
+		select {
+		case <-ctx.Done():
+			return
+		case files := <-d.pulls:
+			update = receiverEvent.FileNodeUpdateMsg{
+				Nodes: files, SenderFingerprint: demoRequests[0].fingerprint, SenderTrust: demoRequests[0].trust,
+				AutoAccept: true, Pulled: true, SenderName: demoShares[0].Name, SenderIcon: demoShares[0].Meta.Icon,
+			}
+		case <-time.After(demoRequestInterval):
+			d.mu.Lock()
+			availability := d.available
+			d.mu.Unlock()
+			if availability != receiverEvent.Available {
+				d.send(ctx, receiverEvent.RequestDeclinedMsg{Availability: availability})
+				continue
+			}
+			request := demoRequests[next%len(demoRequests)]
+			next++
+			update = receiverEvent.FileNodeUpdateMsg{
+				Nodes: request.nodes, SenderFingerprint: request.fingerprint, SenderTrust: request.trust,
+				SenderName: request.sender, SenderIcon: request.icon,
+			}
+		}
+
+		// Answers to requests that already expired are stale
+		select {
+		case <-d.decisions:
+		default:
+		}
+		d.send(ctx, update)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(demoAcceptTimeout):
+			d.send(ctx, receiverEvent.RequestTimedOutMsg{Timeout: demoAcceptTimeout})
+		case accepted := <-d.decisions:
+			if accepted {
+				d.receive(ctx, update.Nodes)
+			}
+		}
+	}
+}
+
+// receive reports synthetic progress of receiving nodes, then the end of the transfer
+func (d *demoReceiver) receive(ctx context.Context, nodes []fileInfo.FileNode) {
+	flat := demoFlatten(nodes, "")
+	var total int64
+	for _, file := range flat {
+		total += file.Size
+	}
+	rate := max(float64(total)/demoTransferTime.Seconds(), 256<<10)
+
+	var received, fileReceived int64
+	completed := 0
+	started := time.Now()
+	ticker := time.NewTicker(demoTick)
+	defer ticker.Stop()
+	for completed < len(flat) {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		tickRate := rate * (0.8 + 0.4*rand.Float64())
+		budget := int64(tickRate * demoTick.Seconds())
+		for budget > 0 && completed < len(flat) {
+			file := flat[completed]
+			step := min(budget, file.Size-fileReceived)
+			fileReceived += step
+			received += step
+			budget -= step
+			done := fileReceived >= file.Size
+			d.send(ctx, receiverEvent.FileProgressMsg{FileName: file.Path, ReceivedBytes: fileReceived, TotalBytes: file.Size, Completed: done})
+			if done {
+				completed++
+				fileReceived = 0
+			}
+		}
+
+		current := ""
+		if completed < len(flat) {
+			current = flat[completed].Path
+		}
+		d.send(ctx, receiverEvent.ProgressUpdateMsg{
+			TotalFiles:     len(flat),
+			CompletedFiles: completed,
+			TotalBytes:     total,
+			ReceivedBytes:  received,
+			CurrentFile:    current,
+			TransferRate:   tickRate,
+			FilesPerMinute: float64(completed) / time.Since(started).Minutes(),
+			DiskWriteRate:  tickRate * 3,
+			DiskBusy:       0.2 + 0.2*rand.Float64(),
+			ETA:            (time.Duration(float64(total-received)/tickRate) * time.Second).Round(time.Second),
+		})
+	}
+
+	outputPath := d.outputPath
+	if len(nodes) == 1 {
+		outputPath = filepath.Join(outputPath, nodes[0].Name)
+	}
+	d.send(ctx, receiverEvent.TransferFinishedMsg{OutputPath: outputPath})
+}
+
+// demoListing returns the entries of the directory at dir of demoShareTree, without their
+// children and with paths relative to the share, as shares list them
+func demoListing(dir string) []fileInfo.FileNode {
+	nodes := demoShareTree
+	for _, name := range strings.Split(path.Clean(dir), "/") {
+		if name == "." || name == "" {
+			continue
+		}
+		found := false
+		for _, node := range nodes {
+			if node.IsDir && node.Name == name {
+				nodes, found = node.Children, true
+				break
+			}
+		}
+		if !found {
+			return nil
+		}
+	}
+	listing := make([]fileInfo.FileNode, len(nodes))
+	for i, node := range nodes {
+		listing[i] = fileInfo.FileNode{Name: node.Name, IsDir: node.IsDir, Size: node.Size, Path: path.Join(dir, node.Name)}
+	}
+	return listing
+}
+
+// demoPulled returns the nodes of demoShareTree at paths
+func demoPulled(paths []string) []fileInfo.FileNode {
+	var nodes []fileInfo.FileNode
+	for _, p := range paths {
+		entries := demoListing(path.Dir(p))
+		for _, entry := range entries {
+			if entry.Path != path.Clean(p) {
+				continue
+			}
+			if entry.IsDir {
+				entry.Children = demoListing(entry.Path)
+			}
+			entry.Path = ""
+			nodes = append(nodes, entry)
+		}
+	}
+	return nodes
+}
@@ -0,0 +1,167 @@
+package ui
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/bubbles/table"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/ansi"
+
+	"github.com/rescp17/lanFileSharer/pkg/fileTree"
+	"github.com/rescp17/lanFileSharer/pkg/ui/components"
+)
+
+// viewLine returns line y of view without styling, borders and the space around it
+func viewLine(view string, y int) string {
+	lines := strings.Split(view, "\n")
+	if y < 0 || y >= len(lines) {
+		return ""
+	}
+	return plainLine(lines[y])
+}
+
+// plainLine strips styling, borders and the space around line
+func plainLine(line string) string {
+	return strings.Trim(ansi.Strip(line), " │┃║╭╮╰╯")
+}
+
+// componentTop returns the line of view that the first line of component is drawn on, or -1.
+// Lines are compared without styling and borders, and a line cut short by wrapping still matches,
+// so components nested in containers are found too.
+func componentTop(view, component string) int {
+	viewLines := strings.Split(view, "\n")
+	for i := range viewLines {
+		viewLines[i] = plainLine(viewLines[i])
+	}
+	lines := strings.Split(component, "\n")
+	for i := range lines {
+		lines[i] = plainLine(lines[i])
+	}
+
+	for top := 0; top+len(lines) <= len(viewLines); top++ {
+		if blockMatches(viewLines[top:], lines) {
+			return top
+		}
+	}
+	return -1
+}
+
+// blockMatches reports whether the shown lines start with the lines of a component;
+// blank component lines match anything
+func blockMatches(shown, lines []string) bool {
+	for i, line := range lines {
+		if line == "" {
+			continue
+		}
+		if shown[i] == "" || !strings.HasPrefix(line, shown[i]) && !strings.HasPrefix(shown[i], line) {
+			return false
+		}
+	}
+	return true
+}
+
+// tableRowAt returns the row of t shown on line y of view, recognized by its first column
+func tableRowAt(t table.Model, view string, y int) (int, bool) {
+	top := componentTop(view, strings.SplitN(t.View(), "\n", 2)[0])
+	if top < 0 || y <= top {
+		return 0, false
+	}
+	fields := strings.Fields(viewLine(view, y))
+	if len(fields) == 0 {
+		return 0, false
+	}
+	for i, row := range t.Rows() {
+		if len(row) > 0 && row[0] == fields[0] {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// handleSenderMouse handles clicks and the wheel on the sender side: the receiver table,
+// and the context menu opened with the right button
+func (m *model) handleSenderMouse(msg tea.MouseMsg) tea.Cmd {
+	if msg.Action != tea.MouseActionPress {
+		return nil
+	}
+
+	menu := m.sender.contextMenu
+	if menu.IsVisible() {
+		switch msg.Button {
+		case tea.MouseButtonWheelUp:
+			menu.Navigate(components.KeyActionNavigateUp)
+		case tea.MouseButtonWheelDown:
+			menu.Navigate(components.KeyActionNavigateDown)
+		case tea.MouseButtonLeft:
+			top := componentTop(m.View(), menu.Render())
+			if top >= 0 && menu.SelectAt(msg.Y-top) {
+				if item := menu.GetSelectedItem(); item != nil {
+					return m.handleMenuAction(item.Action)
+				}
+			}
+			if top < 0 || menu.ItemAt(msg.Y-top) < 0 {
+				// A click outside the items closes the menu
+				menu.Hide()
+			}
+		default:
+			menu.Hide()
+		}
+		return nil
+	}
+
+	if msg.Button == tea.MouseButtonRight {
+		m.showContextMenu(msg.X, msg.Y)
+		return nil
+	}
+
+	if m.sender.state == selectingReceiver {
+		switch msg.Button {
+		case tea.MouseButtonWheelUp:
+			m.sender.table.MoveUp(1)
+		case tea.MouseButtonWheelDown:
+			m.sender.table.MoveDown(1)
+		case tea.MouseButtonLeft:
+			// The first click selects a receiver, a click on the selected one chooses it
+			i, ok := tableRowAt(m.sender.table, m.View(), msg.Y)
+			if !ok {
+				return nil
+			}
+			if i == m.sender.table.Cursor() {
+				m.chooseReceiver()
+			} else {
+				m.sender.table.SetCursor(i)
+			}
+		}
+	}
+	return nil
+}
+
+// showContextMenu opens the context menu with the actions of the current state, if it has any
+func (m *model) showContextMenu(x, y int) {
+	menu := m.sender.contextMenu
+	menu.ClearItems()
+	switch m.sender.state {
+	case sendingFiles:
+		menu.AddItem("Pause", "pause", "⏸️", true, components.KeyActionPause, "p")
+		menu.AddItem("Cancel", "cancel", "✖", true, components.KeyActionCancel, "c")
+	case transferPaused:
+		menu.AddItem("Resume", "resume", "▶", true, components.KeyActionPause, "r")
+		menu.AddItem("Cancel", "cancel", "✖", true, components.KeyActionCancel, "c")
+	case transferFailed:
+		menu.AddItem("Retry", "retry", "↻", true, components.KeyActionRetry, "r")
+	default:
+		return
+	}
+	menu.Show(x, y)
+}
+
+// updateFileTreeMouse hands a mouse message to tree with its Y made relative to the tree
+func (m *model) updateFileTreeMouse(tree fileTree.Model, msg tea.MouseMsg) (fileTree.Model, tea.Cmd) {
+	top := componentTop(m.View(), tree.View())
+	if top < 0 {
+		return tree, nil
+	}
+	msg.Y -= top
+	newFileTree, cmd := tree.Update(msg)
+	return newFileTree.(fileTree.Model), cmd
+}
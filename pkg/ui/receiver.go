@@ -2,6 +2,9 @@ package ui
 
 import (
 	"fmt"
+	"log/slog"
+	"strings"
+	"time"
 
 	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/spinner"
@@ -9,7 +12,11 @@ import (
 	appevents "github.com/rescp17/lanFileSharer/internal/app_events"
 	receiverEvent "github.com/rescp17/lanFileSharer/internal/app_events/receiver"
 	"github.com/rescp17/lanFileSharer/internal/style"
+	"github.com/rescp17/lanFileSharer/internal/util"
+	"github.com/rescp17/lanFileSharer/pkg/discovery"
 	"github.com/rescp17/lanFileSharer/pkg/fileTree"
+	"github.com/rescp17/lanFileSharer/pkg/recent"
+	"github.com/rescp17/lanFileSharer/pkg/ui/components"
 )
 
 // receiverState defines the different states of the receiver UI
@@ -21,6 +28,9 @@ const (
 	receivingFiles
 	receiveComplete
 	receiveFailed
+	browsingShares // Choosing a device sharing files
+	browsingShare  // Picking files to pull from the chosen share
+	browsingRecent // Picking a recently received file
 )
 
 type receiverModel struct {
@@ -29,50 +39,232 @@ type receiverModel struct {
 	port      int
 	fileTree  fileTree.Model
 	lastError error
+	// outputPath is the received file or folder reported when the transfer finished
+	outputPath string
+	openErr    error
+	// resumeToken identifies the session so an interrupted transfer can be resumed
+	resumeToken string
+	// senderFingerprint and senderTrust describe the key that signed the offer
+	senderFingerprint string
+	senderTrust       string
+	// trustReadOnly is set when accepting will not trust a new key, see config.Config.TrustReadOnly
+	trustReadOnly bool
+	// senderName is the sender's icon and hostname, as it gave them, to tell its devices apart
+	senderName string
+	// notice explains why the last request was dropped, shown while awaiting the next one
+	notice string
+	// availability is whether requests are put to the user, kept across resets
+	availability receiverEvent.Availability
+	// guestPass is the last guest pass issued, shown until a guest uses it and kept across
+	// resets; nil if there is none
+	guestPass *receiverEvent.GuestPassMsg
+
+	// Shares of other devices, browsed to pull files from them
+	shares       []discovery.ServiceInfo
+	shareCursor  int
+	browsedShare *discovery.ServiceInfo // Share shown in fileTree, nil while choosing one
+	browseErr    error
+	preview      *receiverEvent.SharePreviewMsg // Start of the file previewed in the share, nil if none is
+
+	// Recently received files, picked to copy their path or open them
+	recentFiles  []recent.Entry
+	recentCursor int
+	recentErr    error
+	recentNotice string
+	recentReturn receiverState // State the picker was opened from
+
+	// Reception progress, driven by the receiver's transfer events
+	progressBar     *components.MultiFileProgress
+	statusIndicator *components.StatusIndicator
+	statsPanel      *components.TransferStatsPanel
+	rateChart       *components.LineChart
+	sparkLine       *components.SparkLine
+	statsCollector  *components.AdvancedStatsCollector
+	lastProgress    receiverEvent.ProgressUpdateMsg
+	startedAt       time.Time
+	peakRate        float64
+
+	// Process and disk metrics, shown with P while receiving
+	performanceOptimizer *components.PerformanceOptimizer
+	performancePanel     *components.PerformancePanel
+	diskAlerted          bool
 }
 
 type KeyMap struct {
-	Accept key.Binding
-	Reject key.Binding
+	Accept       key.Binding
+	Reject       key.Binding
+	Open         key.Binding
+	Perf         key.Binding
+	Availability key.Binding
+	Browse       key.Binding
+	Pull         key.Binding
+	Preview      key.Binding
+	Back         key.Binding
+	Recent       key.Binding
+	Copy         key.Binding
+	Guest        key.Binding
 }
 
 // DefaultKeyMap provides sensible default keybindings.
 var DefaultKeyMap = KeyMap{
-	Accept: key.NewBinding(key.WithKeys("y"), key.WithHelp("y", "Accept")),
-	Reject: key.NewBinding(key.WithKeys("n"), key.WithHelp("n", "Reject")),
+	Accept:       key.NewBinding(key.WithKeys("y"), key.WithHelp("y", "Accept")),
+	Reject:       key.NewBinding(key.WithKeys("n"), key.WithHelp("n", "Reject")),
+	Open:         key.NewBinding(key.WithKeys("o"), key.WithHelp("o", "Open received files")),
+	Perf:         key.NewBinding(key.WithKeys("p", "P"), key.WithHelp("p", "Performance")),
+	Availability: key.NewBinding(key.WithKeys("d"), key.WithHelp("d", "Do not disturb / hide")),
+	Browse:       key.NewBinding(key.WithKeys("b"), key.WithHelp("b", "Browse shares")),
+	Pull:         key.NewBinding(key.WithKeys("p"), key.WithHelp("p", "Pull selected")),
+	Preview:      key.NewBinding(key.WithKeys("v"), key.WithHelp("v", "Preview")),
+	Back:         key.NewBinding(key.WithKeys("esc"), key.WithHelp("esc", "Back")),
+	Recent:       key.NewBinding(key.WithKeys("r"), key.WithHelp("r", "Recent files")),
+	Copy:         key.NewBinding(key.WithKeys("c", "enter"), key.WithHelp("c", "Copy path")),
+	Guest:        key.NewBinding(key.WithKeys("g"), key.WithHelp("g", "Guest pass")),
+}
+
+// openResultMsg reports the outcome of opening the received files
+type openResultMsg struct {
+	err error
+}
+
+// openReceivedCmd opens the received file or folder with the OS default handler
+func openReceivedCmd(path string) tea.Cmd {
+	return func() tea.Msg {
+		return openResultMsg{err: util.OpenPath(path)}
+	}
 }
 
 func initReceiverModel(port int) receiverModel {
 	s := style.NewSpinner()
 
+	rateChart := components.NewLineChart("📈 Incoming Rate", 60, 10, 60)
+	rateChart.SetLabels("Time", "rate")
+	performanceOptimizer := components.NewPerformanceOptimizer()
+
 	return receiverModel{
-		spinner: s,
-		port:    port,
-		state:   awaitingConnection,
+		spinner:         s,
+		port:            port,
+		state:           awaitingConnection,
+		progressBar:     components.NewMultiFileProgress(components.DefaultProgressConfig()),
+		statusIndicator: components.NewStatusIndicator(5, true),
+		statsPanel:      components.NewTransferStatsPanel(),
+		rateChart:       rateChart,
+		sparkLine:       components.NewSparkLine(40, 40),
+		statsCollector:  components.NewAdvancedStatsCollector(100, time.Second),
+
+		performanceOptimizer: performanceOptimizer,
+		performancePanel:     components.NewPerformancePanel(performanceOptimizer),
 	}
 }
 
+// receiverFailedMsg reports a receiver error in combined mode, where it must not end the program
+type receiverFailedMsg struct {
+	err error
+}
+
+func receiverFailed(err error) tea.Msg {
+	return receiverFailedMsg{err: err}
+}
+
 func (m *model) initReceiver() tea.Cmd {
 	return tea.Batch(
 		m.receiver.spinner.Tick,
-		m.listenForAppMessages(),
+		m.listenForReceiverMessages(),
 	)
 }
 
+// listenForReceiverMessages is a command that waits for the next message of the receiver app
+func (m *model) listenForReceiverMessages() tea.Cmd {
+	return func() tea.Msg {
+		msg := <-m.receiverController.UIMessages()
+		if e, ok := msg.(appevents.Error); ok && m.mode == Both {
+			return receiverFailed(e.Err)
+		}
+		return msg
+	}
+}
+
+// isReceiverMessage reports whether msg came from the receiver app
+func isReceiverMessage(msg tea.Msg) bool {
+	switch msg.(type) {
+	case receiverEvent.FileNodeUpdateMsg, receiverEvent.TransferFinishedMsg, receiverEvent.StatusUpdateMsg,
+		receiverEvent.ProgressUpdateMsg, receiverEvent.FileProgressMsg, receiverEvent.RequestTimedOutMsg,
+		receiverEvent.RequestDeclinedMsg, receiverEvent.SharesFoundMsg, receiverEvent.ShareListingMsg,
+		receiverEvent.PullRequestedMsg, receiverEvent.SharePreviewMsg, receiverEvent.GuestPassMsg, receiverFailedMsg:
+		return true
+	}
+	return false
+}
+
+// isReceiverUIMessage reports whether msg was produced by the receiver UI for itself
+func isReceiverUIMessage(msg tea.Msg) bool {
+	switch msg.(type) {
+	case openResultMsg, fileTree.LoadChildrenMsg, recentLoadedMsg, recentCopiedMsg:
+		return true
+	}
+	return false
+}
+
 func (m model) receiverView() string {
+	if m.receiver.performancePanel.IsVisible() {
+		return m.receiver.performancePanel.Render()
+	}
+
 	switch m.receiver.state {
 	case awaitingConnection:
-		return fmt.Sprintf("\n\n %s Awaiting sender connection on port %d...", m.receiver.spinner.View(), m.receiver.port)
+		view := fmt.Sprintf("\n\n %s Awaiting sender connection on port %d...", m.receiver.spinner.View(), m.receiver.port)
+		switch m.receiver.availability {
+		case receiverEvent.DoNotDisturb:
+			view = "\n\n " + style.HighlightFontStyle.Render("Do not disturb: requests are declined without asking")
+		case receiverEvent.Hidden:
+			view = "\n\n " + style.HighlightFontStyle.Render("Hidden: not advertised, requests are declined without asking")
+		}
+		if m.receiver.notice != "" {
+			view += "\n\n " + style.HelpStyle.Render(m.receiver.notice)
+		}
+		view += m.guestPassView()
+		help := fmt.Sprintf("  %s/%s (now %s)  %s/%s  %s/%s  %s/%s \n",
+			DefaultKeyMap.Availability.Help().Key, DefaultKeyMap.Availability.Help().Desc, m.receiver.availability,
+			DefaultKeyMap.Browse.Help().Key, DefaultKeyMap.Browse.Help().Desc,
+			DefaultKeyMap.Recent.Help().Key, DefaultKeyMap.Recent.Help().Desc,
+			DefaultKeyMap.Guest.Help().Key, DefaultKeyMap.Guest.Help().Desc,
+		)
+		return view + "\n\n" + style.HelpStyle.Render(help)
 	case awaitingConfirmation:
 		help := fmt.Sprintf("  %s/%s  %s/%s \n",
 			DefaultKeyMap.Accept.Help().Key, DefaultKeyMap.Accept.Help().Desc,
 			DefaultKeyMap.Reject.Help().Key, DefaultKeyMap.Reject.Help().Desc,
 		)
-		return fmt.Sprintf("%s\n%s", m.receiver.fileTree.View(), style.HelpStyle.Render(help))
+		return fmt.Sprintf("%s\n%s%s%s%s", m.receiver.fileTree.View(), m.senderNameView(), m.senderKeyView(), m.resumeTokenView(), style.HelpStyle.Render(help))
 	case receivingFiles:
-		return fmt.Sprintf("\n\n %s Receiving files...", m.receiver.spinner.View())
+		help := fmt.Sprintf("  %s/%s \n", DefaultKeyMap.Perf.Help().Key, DefaultKeyMap.Perf.Help().Desc)
+		return fmt.Sprintf("\n\n %s Receiving files...\n%s\n%s%s", m.receiver.spinner.View(), m.resumeTokenView(), m.receiveProgressView(), style.HelpStyle.Render(help))
 	case receiveComplete: // Add this new case
-		return "\nFile transfer complete!\n\nPress Enter to exit."
+		s := "\nFile transfer complete!\n"
+		if m.receiver.lastProgress.TotalFiles > 0 {
+			m.receiver.statsPanel.SetCompact(false)
+			s += "\n" + m.receiver.statsPanel.Render() + "\n"
+			if summary := m.receiver.statsCollector.RenderSessionSummary(60, 10); summary != "" {
+				s += "\n" + summary
+			}
+		}
+		if m.receiver.outputPath != "" {
+			s += fmt.Sprintf("\nSaved to: %s\n", m.receiver.outputPath)
+		}
+		if m.receiver.openErr != nil {
+			s += "\n" + style.ErrorStyle.Render(m.receiver.openErr.Error()) + "\n"
+		}
+		exit := "Exit"
+		if m.mode == Both {
+			exit = "Receive more"
+		}
+		help := fmt.Sprintf("  %s/%s  %s/%s  enter/%s \n",
+			DefaultKeyMap.Open.Help().Key, DefaultKeyMap.Open.Help().Desc,
+			DefaultKeyMap.Recent.Help().Key, DefaultKeyMap.Recent.Help().Desc, exit)
+		return s + "\n" + style.HelpStyle.Render(help)
+	case browsingShares, browsingShare:
+		return m.browseView()
+	case browsingRecent:
+		return m.recentView()
 	case receiveFailed:
 		return fmt.Sprintf("\nAn error occurred: %v\n\nPress Enter to restart.", style.ErrorStyle.Render(m.receiver.lastError.Error()))
 	default:
@@ -80,21 +272,175 @@ func (m model) receiverView() string {
 	}
 }
 
+// receiveProgressView renders the overall and per-file progress, the incoming rate and the status history
+func (m model) receiveProgressView() string {
+	var b strings.Builder
+	b.WriteString(m.receiver.progressBar.Render())
+	b.WriteString("\n")
+
+	progress := m.receiver.lastProgress
+	b.WriteString("📈 Rate: ")
+	b.WriteString(m.receiver.sparkLine.Render())
+	b.WriteString(fmt.Sprintf(" %s%s", formatRate(progress.TransferRate), formatFilesPerMinute(progress.FilesPerMinute)))
+	if progress.ETA > 0 {
+		b.WriteString(fmt.Sprintf("  ETA %s", progress.ETA.Round(time.Second)))
+	}
+	b.WriteString("\n\n")
+
+	if m.receiver.lastProgress.TotalBytes > 0 {
+		b.WriteString(m.receiver.rateChart.Render())
+		b.WriteString("\n")
+	}
+
+	if status := m.receiver.statusIndicator.Render(); status != "" {
+		b.WriteString(status)
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// resumeTokenView shows the share token the sender can pass to `send --resume`
+func (m model) resumeTokenView() string {
+	if m.receiver.resumeToken == "" {
+		return ""
+	}
+	return style.HelpStyle.Render(fmt.Sprintf(" Resume token: %s", m.receiver.resumeToken)) + "\n"
+}
+
+// guestPassView shows the token of the last guest pass issued, or why it could not be
+func (m model) guestPassView() string {
+	pass := m.receiver.guestPass
+	switch {
+	case pass == nil:
+		return ""
+	case pass.Err != nil:
+		return "\n\n " + style.ErrorStyle.Render("Failed to issue a guest pass: "+pass.Err.Error())
+	case time.Now().After(pass.Expires):
+		return "\n\n " + style.HelpStyle.Render(fmt.Sprintf("Guest token %s expired at %s", pass.Token, pass.Expires.Format("15:04")))
+	}
+	limit := ""
+	if pass.MaxBytes > 0 {
+		limit = " of up to " + util.FormatSize(pass.MaxBytes)
+	}
+	return "\n\n " + style.HighlightFontStyle.Render("Guest token: "+pass.Token) + "\n " +
+		style.HelpStyle.Render(fmt.Sprintf("Accepts one transfer%s without asking until %s, the sender is not trusted", limit, pass.Expires.Format("15:04")))
+}
+
+// senderNameView shows who the request is from, as the sender named itself
+func (m model) senderNameView() string {
+	if m.receiver.senderName == "" {
+		return ""
+	}
+	return style.HighlightFontStyle.Render(" From: "+m.receiver.senderName) + "\n"
+}
+
+// senderKeyView shows the sender's key fingerprint, asking to verify it when it is new or stale
+func (m model) senderKeyView() string {
+	if m.receiver.senderFingerprint == "" {
+		return ""
+	}
+	line := fmt.Sprintf(" Sender key: %s", m.receiver.senderFingerprint)
+	switch m.receiver.senderTrust {
+	case "trusted":
+		return style.SuccessStyle.Render(line+" (trusted)") + "\n"
+	case "stale":
+		if m.receiver.trustReadOnly {
+			return style.ErrorStyle.Render(line+" (provisioned trust expired, accepting will not renew it)") + "\n"
+		}
+		return style.ErrorStyle.Render(line+" (verification expired, confirm the fingerprint with the sender)") + "\n"
+	default:
+		if m.receiver.trustReadOnly {
+			return style.ErrorStyle.Render(line+" (not a provisioned key, accepting will not trust it)") + "\n"
+		}
+		return style.HelpStyle.Render(line+" (new key, confirm the fingerprint with the sender)") + "\n"
+	}
+}
+
 func (m *model) resetReceiver() (tea.Model, tea.Cmd) {
+	// The receiver app keeps running and is still being listened to
+	availability, guestPass := m.receiver.availability, m.receiver.guestPass
 	m.receiver = initReceiverModel(m.receiver.port)
-	return m, m.Init()
+	m.receiver.availability = availability
+	m.receiver.guestPass = guestPass
+	return m, m.receiver.spinner.Tick
 }
 
 func (m *model) updateReceiver(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if isReceiverMessage(msg) {
+		// Every app message has been taken off the channel, so listen for the next one
+		model, cmd := m.handleReceiverMessage(msg)
+		return model, tea.Batch(cmd, m.listenForReceiverMessages())
+	}
+	return m.handleReceiverMessage(msg)
+}
+
+func (m *model) handleReceiverMessage(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	// Handle global events first
 	case appevents.Error:
 		m.receiver.lastError = msg.Err
 		m.receiver.state = receiveFailed
 		return m, nil
+	case receiverFailedMsg:
+		m.receiver.lastError = msg.err
+		m.receiver.state = receiveFailed
+		return m, nil
 	case receiverEvent.TransferFinishedMsg:
 		m.receiver.state = receiveComplete
+		m.receiver.outputPath = msg.OutputPath
+		m.receiver.statusIndicator.AddMessage(components.StatusSuccess, "Transfer completed successfully! 🎉")
+		if m.config.AutoOpen && msg.OutputPath != "" {
+			return m, openReceivedCmd(msg.OutputPath)
+		}
+		return m, nil
+	case receiverEvent.RequestTimedOutMsg:
+		// The user's answer, if any, was refused and the sender was told the request
+		// timed out, so drop the prompt even if it was accepted a moment too late
+		if m.receiver.state == awaitingConnection || m.receiver.state == receiveComplete {
+			return m, nil
+		}
+		model, cmd := m.resetReceiver()
+		m.receiver.notice = fmt.Sprintf("The last request timed out after %s without an answer.", msg.Timeout)
+		return model, cmd
+	case receiverEvent.RequestDeclinedMsg:
+		if msg.Reason != "" {
+			m.receiver.notice = fmt.Sprintf("Declined a request at %s: %s.", time.Now().Format("15:04"), msg.Reason)
+			return m, nil
+		}
+		m.receiver.notice = fmt.Sprintf("Declined a request at %s while %s.", time.Now().Format("15:04"), msg.Availability)
+		return m, nil
+	case receiverEvent.StatusUpdateMsg:
+		m.receiver.statusIndicator.AddMessage(components.StatusInfo, msg.Message)
 		return m, nil
+	case receiverEvent.GuestPassMsg:
+		m.receiver.guestPass = &msg
+		return m, nil
+	case receiverEvent.FileNodeUpdateMsg:
+		if msg.Resumed && (m.receiver.state == receivingFiles || m.receiver.state == receiveFailed) {
+			// The sender of the session that went idle is back and resumes it
+			m.receiver.state = receivingFiles
+			m.receiver.lastError = nil
+			m.receiver.fileTree = fileTree.NewFileTree("Received files info:", msg.Nodes)
+			m.receiver.statusIndicator.AddMessage(components.StatusInfo, "Sender is back, resuming the transfer")
+			m.receiverController.AppEvents() <- receiverEvent.FileRequestAccepted{}
+			return m, nil
+		}
+	case receiverEvent.ProgressUpdateMsg:
+		m.updateReceiveProgress(msg)
+		return m, nil
+	case receiverEvent.FileProgressMsg:
+		m.updateFileProgress(msg)
+		return m, nil
+	case openResultMsg:
+		m.receiver.openErr = msg.err
+		if msg.err != nil {
+			slog.Error("Failed to open received files", "path", m.receiver.outputPath, "error", msg.err)
+		}
+		return m, nil
+	case tea.KeyMsg:
+		if m.updatePerformancePanel(msg) {
+			return m, nil
+		}
 	}
 
 	switch m.receiver.state {
@@ -106,11 +452,113 @@ func (m *model) updateReceiver(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m.updateReceivingFiles(msg)
 	case receiveComplete, receiveFailed:
 		return m.updateReceiveFinishedOrFailed(msg)
+	case browsingShares, browsingShare:
+		return m.updateBrowsing(msg)
+	case browsingRecent:
+		return m.updateRecent(msg)
 	}
 
 	return m, nil
 }
 
+// updateReceiveProgress feeds the overall progress of the reception into the progress components
+func (m *model) updateReceiveProgress(msg receiverEvent.ProgressUpdateMsg) {
+	r := &m.receiver
+	if r.startedAt.IsZero() {
+		r.startedAt = time.Now()
+	}
+	r.lastProgress = msg
+	r.peakRate = max(r.peakRate, msg.TransferRate)
+
+	r.progressBar.UpdateOverall(components.ProgressData{
+		Current:     msg.ReceivedBytes,
+		Total:       msg.TotalBytes,
+		Rate:        msg.TransferRate,
+		ETA:         msg.ETA,
+		Label:       "Overall Progress",
+		Status:      "active",
+		StartTime:   r.startedAt,
+		CurrentFile: msg.CurrentFile,
+	})
+
+	var averageRate float64
+	if elapsed := time.Since(r.startedAt).Seconds(); elapsed > 0 {
+		averageRate = float64(msg.ReceivedBytes) / elapsed
+	}
+	r.statsPanel.Update(
+		msg.TotalFiles, msg.CompletedFiles, msg.FailedFiles,
+		msg.TotalBytes, msg.ReceivedBytes,
+		msg.TransferRate, averageRate, r.peakRate,
+	)
+
+	r.statsCollector.UpdateTransferMetrics(msg.TotalBytes, msg.ReceivedBytes, msg.TransferRate)
+	r.rateChart.AddPoint(float64(time.Now().Unix()), msg.TransferRate, "")
+	r.sparkLine.AddValue(msg.TransferRate)
+
+	r.performanceOptimizer.RecordTransferRate(msg.TransferRate)
+	r.performanceOptimizer.RecordDiskWrite(msg.DiskWriteRate, msg.DiskBusy)
+	if (components.PerformanceMetrics{DiskBusy: msg.DiskBusy}).DiskBound() && !r.diskAlerted {
+		r.diskAlerted = true
+		r.statusIndicator.AddDetailedMessage(components.StatusWarning,
+			"Disk is the bottleneck rather than the network",
+			fmt.Sprintf("Writing %.0f%% of the time at %s", msg.DiskBusy*100, formatRate(msg.DiskWriteRate)),
+			"Press P for details")
+	}
+}
+
+// updatePerformancePanel shows, navigates and hides the performance panel, reporting
+// whether the key was consumed
+func (m *model) updatePerformancePanel(msg tea.KeyMsg) bool {
+	panel := m.receiver.performancePanel
+	if !panel.IsVisible() {
+		if key.Matches(msg, DefaultKeyMap.Perf) && (m.receiver.state == receivingFiles || m.receiver.state == receiveComplete) {
+			panel.Show()
+			return true
+		}
+		return false
+	}
+
+	switch msg.String() {
+	case "left", "h":
+		panel.Navigate(components.KeyActionNavigateLeft)
+	case "right", "l":
+		panel.Navigate(components.KeyActionNavigateRight)
+	case "up", "k":
+		panel.Navigate(components.KeyActionNavigateUp)
+	case "down", "j":
+		panel.Navigate(components.KeyActionNavigateDown)
+	case "enter":
+		panel.Navigate(components.KeyActionSelect)
+	case "esc", "p", "P":
+		panel.Hide()
+	case "ctrl+c":
+		return false
+	}
+	// The panel is modal, other keys are swallowed while it is shown
+	return true
+}
+
+// updateFileProgress updates the progress of a single received file
+func (m *model) updateFileProgress(msg receiverEvent.FileProgressMsg) {
+	status := "active"
+	switch {
+	case msg.Err != nil:
+		status = "error"
+		m.receiver.statusIndicator.AddDetailedMessage(components.StatusError,
+			fmt.Sprintf("Failed to receive %s", msg.FileName), msg.Err.Error(), "")
+	case msg.Completed:
+		status = "complete"
+		m.receiver.statusIndicator.AddMessage(components.StatusSuccess, fmt.Sprintf("Received %s", msg.FileName))
+	}
+
+	m.receiver.progressBar.UpdateFile(msg.FileName, components.ProgressData{
+		Current: msg.ReceivedBytes,
+		Total:   msg.TotalBytes,
+		Label:   msg.FileName,
+		Status:  status,
+	}, status)
+}
+
 func (m *model) updateReceivingFiles(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case receiverEvent.FileNodeUpdateMsg:
@@ -129,6 +577,31 @@ func (m *model) updateAwaitingConnection(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case receiverEvent.FileNodeUpdateMsg:
 		m.receiver.state = awaitingConfirmation
 		m.receiver.fileTree = fileTree.NewFileTree("Received files info:", msg.Nodes)
+		m.receiver.resumeToken = msg.ResumeToken
+		m.receiver.senderFingerprint = msg.SenderFingerprint
+		m.receiver.senderTrust = msg.SenderTrust
+		m.receiver.trustReadOnly = msg.TrustReadOnly
+		m.receiver.senderName = util.WithIcon(msg.SenderIcon, msg.SenderName)
+		if msg.Guest {
+			m.receiver.guestPass = nil
+		}
+		if msg.AutoAccept {
+			m.receiverController.AppEvents() <- receiverEvent.FileRequestAccepted{}
+			m.receiver.state = receivingFiles
+		}
+		return m, nil
+	case tea.KeyMsg:
+		switch {
+		case key.Matches(msg, DefaultKeyMap.Availability):
+			m.receiver.availability = m.receiver.availability.Next()
+			m.receiverController.AppEvents() <- receiverEvent.SetAvailability{Availability: m.receiver.availability}
+		case key.Matches(msg, DefaultKeyMap.Browse):
+			return m.startBrowsing()
+		case key.Matches(msg, DefaultKeyMap.Recent):
+			return m.showRecent()
+		case key.Matches(msg, DefaultKeyMap.Guest):
+			m.receiverController.AppEvents() <- receiverEvent.IssueGuestPass{}
+		}
 		return m, nil
 	default:
 		var cmd tea.Cmd
@@ -138,14 +611,19 @@ func (m *model) updateAwaitingConnection(msg tea.Msg) (tea.Model, tea.Cmd) {
 }
 
 func (m *model) updateAwaitingConfirmation(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if mouseMsg, ok := msg.(tea.MouseMsg); ok {
+		var cmd tea.Cmd
+		m.receiver.fileTree, cmd = m.updateFileTreeMouse(m.receiver.fileTree, mouseMsg)
+		return m, cmd
+	}
 	if keyMsg, ok := msg.(tea.KeyMsg); ok {
 		switch {
 		case key.Matches(keyMsg, DefaultKeyMap.Accept):
-			m.appController.AppEvents() <- receiverEvent.FileRequestAccepted{}
+			m.receiverController.AppEvents() <- receiverEvent.FileRequestAccepted{}
 			m.receiver.state = receivingFiles
 			return m, nil
 		case key.Matches(keyMsg, DefaultKeyMap.Reject):
-			m.appController.AppEvents() <- receiverEvent.FileRequestRejected{}
+			m.receiverController.AppEvents() <- receiverEvent.FileRequestRejected{}
 			return m.resetReceiver()
 		default:
 			newFileTree, cmd := m.receiver.fileTree.Update(msg)
@@ -161,8 +639,18 @@ func (m *model) updateReceiveFinishedOrFailed(msg tea.Msg) (tea.Model, tea.Cmd)
 		switch m.receiver.state {
 		case receiveComplete:
 			if keyMsg.Type == tea.KeyEnter {
+				if m.mode == Both {
+					// Keep receiving, the other tabs are still in use
+					return m.resetReceiver()
+				}
 				return m, tea.Quit
 			}
+			if key.Matches(keyMsg, DefaultKeyMap.Open) && m.receiver.outputPath != "" {
+				return m, openReceivedCmd(m.receiver.outputPath)
+			}
+			if key.Matches(keyMsg, DefaultKeyMap.Recent) {
+				return m.showRecent()
+			}
 		case receiveFailed:
 			if keyMsg.Type == tea.KeyEnter {
 				return m.resetReceiver()
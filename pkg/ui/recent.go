@@ -0,0 +1,153 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	receiverEvent "github.com/rescp17/lanFileSharer/internal/app_events/receiver"
+	"github.com/rescp17/lanFileSharer/internal/style"
+	"github.com/rescp17/lanFileSharer/internal/util"
+	"github.com/rescp17/lanFileSharer/pkg/fileTree"
+	"github.com/rescp17/lanFileSharer/pkg/recent"
+)
+
+// recentLoadedMsg carries the recently received files read for the picker
+type recentLoadedMsg struct {
+	entries []recent.Entry
+	err     error
+}
+
+// recentCopiedMsg reports the outcome of copying the path of a received file
+type recentCopiedMsg struct {
+	path string
+	err  error
+}
+
+// loadRecentCmd reads the recently received files of every receiver of this user
+func loadRecentCmd() tea.Cmd {
+	return func() tea.Msg {
+		store := recent.OpenDefault()
+		if store == nil {
+			return recentLoadedMsg{}
+		}
+		entries, err := store.List()
+		return recentLoadedMsg{entries: entries, err: err}
+	}
+}
+
+// copyPathCmd puts path on the clipboard
+func copyPathCmd(path string) tea.Cmd {
+	return func() tea.Msg {
+		return recentCopiedMsg{path: path, err: util.CopyToClipboard(path)}
+	}
+}
+
+// showRecent opens the picker of recently received files, returning to the current state when closed
+func (m *model) showRecent() (tea.Model, tea.Cmd) {
+	r := &m.receiver
+	r.recentReturn = r.state
+	r.state = browsingRecent
+	r.recentFiles = nil
+	r.recentCursor = 0
+	r.recentErr = nil
+	r.recentNotice = ""
+	r.openErr = nil
+	return m, loadRecentCmd()
+}
+
+// closeRecent returns to the state the picker was opened from
+func (m *model) closeRecent() {
+	m.receiver.state = m.receiver.recentReturn
+	m.receiver.recentErr = nil
+	m.receiver.openErr = nil
+}
+
+func (m *model) updateRecent(msg tea.Msg) (tea.Model, tea.Cmd) {
+	r := &m.receiver
+	switch msg := msg.(type) {
+	case receiverEvent.FileNodeUpdateMsg:
+		// A request takes over the screen, as it would have without the picker
+		m.closeRecent()
+		if r.state == awaitingConnection {
+			return m.updateAwaitingConnection(msg)
+		}
+		return m, nil
+	case recentLoadedMsg:
+		r.recentFiles = msg.entries
+		r.recentErr = msg.err
+		return m, nil
+	case recentCopiedMsg:
+		r.recentErr = msg.err
+		if msg.err == nil {
+			r.recentNotice = fmt.Sprintf("Copied %s", msg.path)
+		}
+		return m, nil
+	case tea.KeyMsg:
+		switch {
+		case key.Matches(msg, DefaultKeyMap.Back):
+			m.closeRecent()
+			return m, r.spinner.Tick
+		case key.Matches(msg, fileTree.DefaultKeyMap.Up):
+			if r.recentCursor > 0 {
+				r.recentCursor--
+			}
+		case key.Matches(msg, fileTree.DefaultKeyMap.Down):
+			if r.recentCursor < len(r.recentFiles)-1 {
+				r.recentCursor++
+			}
+		case key.Matches(msg, DefaultKeyMap.Copy):
+			if len(r.recentFiles) > 0 {
+				return m, copyPathCmd(r.recentFiles[r.recentCursor].Path)
+			}
+		case key.Matches(msg, DefaultKeyMap.Open):
+			if len(r.recentFiles) > 0 {
+				return m, openReceivedCmd(r.recentFiles[r.recentCursor].Path)
+			}
+		}
+		return m, nil
+	default:
+		var cmd tea.Cmd
+		r.spinner, cmd = r.spinner.Update(msg)
+		return m, cmd
+	}
+}
+
+// recentView renders the recently received files, the newest first
+func (m model) recentView() string {
+	r := m.receiver
+	var b strings.Builder
+	b.WriteString("\n\n " + style.TitleStyle.Render("Recently received files") + "\n\n")
+	if len(r.recentFiles) == 0 && r.recentErr == nil {
+		b.WriteString(" " + style.HelpStyle.Render("No files received yet") + "\n")
+	}
+	for i, entry := range r.recentFiles {
+		cursor := style.NoCursorStyle.String()
+		if i == r.recentCursor {
+			cursor = style.CursorStyle.String()
+		}
+		details := fmt.Sprintf("%s  %s", util.FormatSize(entry.Size), entry.ReceivedAt.Format(time.DateTime))
+		if entry.Sender != "" {
+			details += "  from " + util.SanitizeText(entry.Sender)
+		}
+		b.WriteString(fmt.Sprintf("%s %s %s\n", cursor, util.SanitizeText(entry.Path), style.HelpStyle.Render(details)))
+	}
+	if r.recentNotice != "" {
+		b.WriteString("\n " + style.SuccessStyle.Render(r.recentNotice) + "\n")
+	}
+	for _, err := range []error{r.recentErr, r.openErr} {
+		if err != nil {
+			b.WriteString("\n " + style.ErrorStyle.Render(err.Error()) + "\n")
+		}
+	}
+
+	help := fmt.Sprintf("  %s/%s  %s/%s  %s/%s \n",
+		DefaultKeyMap.Copy.Help().Key, DefaultKeyMap.Copy.Help().Desc,
+		DefaultKeyMap.Open.Help().Key, "Open",
+		DefaultKeyMap.Back.Help().Key, DefaultKeyMap.Back.Help().Desc,
+	)
+	b.WriteString("\n" + style.HelpStyle.Render(help))
+	return b.String()
+}
@@ -0,0 +1,270 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/rescp17/lanFileSharer/internal/style"
+	"github.com/rescp17/lanFileSharer/internal/util"
+	"github.com/rescp17/lanFileSharer/pkg/events"
+	"github.com/rescp17/lanFileSharer/pkg/ui/components"
+)
+
+const (
+	// replayTick is how often the playhead moves while playing
+	replayTick = 100 * time.Millisecond
+	// replaySeek is how far left and right move the playhead
+	replaySeek = 5 * time.Second
+	// replayStatuses is the number of the latest status messages shown
+	replayStatuses = 5
+)
+
+// replaySpeeds are the playback speeds stepped through with + and -
+var replaySpeeds = []float64{0.25, 0.5, 1, 2, 4, 8, 16}
+
+// ReplayKeyMap holds the keys controlling the playback of a recorded session
+type ReplayKeyMap struct {
+	Play   key.Binding
+	Back   key.Binding
+	Ahead  key.Binding
+	Start  key.Binding
+	End    key.Binding
+	Faster key.Binding
+	Slower key.Binding
+	Quit   key.Binding
+}
+
+// DefaultReplayKeyMap provides the default playback keybindings.
+var DefaultReplayKeyMap = ReplayKeyMap{
+	Play:   key.NewBinding(key.WithKeys(" "), key.WithHelp("space", "Play/pause")),
+	Back:   key.NewBinding(key.WithKeys("left", "h"), key.WithHelp("←", "-5s")),
+	Ahead:  key.NewBinding(key.WithKeys("right", "l"), key.WithHelp("→", "+5s")),
+	Start:  key.NewBinding(key.WithKeys("home", "g"), key.WithHelp("home", "Start")),
+	End:    key.NewBinding(key.WithKeys("end", "G"), key.WithHelp("end", "End")),
+	Faster: key.NewBinding(key.WithKeys("+", "="), key.WithHelp("+", "Faster")),
+	Slower: key.NewBinding(key.WithKeys("-"), key.WithHelp("-", "Slower")),
+	Quit:   key.NewBinding(key.WithKeys("q", "esc", "ctrl+c"), key.WithHelp("q", "Quit")),
+}
+
+// replayTickMsg moves the playhead of a playing replay
+type replayTickMsg struct {
+	at time.Time
+}
+
+// replayModel plays back a recorded progress stream, showing the transfer as it was at the playhead
+type replayModel struct {
+	name     string
+	replay   *events.Replay
+	state    events.ReplayState
+	position time.Duration
+	playing  bool
+	speed    int // Index into replaySpeeds
+	lastTick time.Time
+}
+
+// NewReplayModel returns a TUI playing back records, the progress stream of a session recorded
+// with send --progress-json. name labels the recording.
+func NewReplayModel(name string, records []events.ProgressRecord) tea.Model {
+	m := replayModel{name: name, replay: events.NewReplay(records), speed: 2}
+	m.state = m.replay.StateAt(0)
+	return m
+}
+
+func replayTickCmd() tea.Cmd {
+	return tea.Tick(replayTick, func(t time.Time) tea.Msg { return replayTickMsg{at: t} })
+}
+
+func (m replayModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m replayModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	keys := DefaultReplayKeyMap
+	switch msg := msg.(type) {
+	case replayTickMsg:
+		if !m.playing {
+			return m, nil
+		}
+		elapsed := msg.at.Sub(m.lastTick)
+		m.lastTick = msg.at
+		m.seek(m.position + time.Duration(float64(elapsed)*replaySpeeds[m.speed]))
+		if m.position >= m.replay.Duration() {
+			m.playing = false
+			return m, nil
+		}
+		return m, replayTickCmd()
+	case tea.KeyMsg:
+		switch {
+		case key.Matches(msg, keys.Quit):
+			return m, tea.Quit
+		case key.Matches(msg, keys.Play):
+			if m.playing {
+				m.playing = false
+				return m, nil
+			}
+			if m.position >= m.replay.Duration() {
+				m.seek(0)
+			}
+			m.playing = true
+			m.lastTick = time.Now()
+			return m, replayTickCmd()
+		case key.Matches(msg, keys.Back):
+			m.seek(m.position - replaySeek)
+		case key.Matches(msg, keys.Ahead):
+			m.seek(m.position + replaySeek)
+		case key.Matches(msg, keys.Start):
+			m.seek(0)
+		case key.Matches(msg, keys.End):
+			m.seek(m.replay.Duration())
+		case key.Matches(msg, keys.Faster):
+			m.speed = min(m.speed+1, len(replaySpeeds)-1)
+		case key.Matches(msg, keys.Slower):
+			m.speed = max(m.speed-1, 0)
+		}
+	}
+	return m, nil
+}
+
+// seek moves the playhead to position, kept within the recording
+func (m *replayModel) seek(position time.Duration) {
+	m.position = min(max(position, 0), m.replay.Duration())
+	m.state = m.replay.StateAt(m.position)
+}
+
+func (m replayModel) View() string {
+	var b strings.Builder
+	b.WriteString(style.TitleStyle.Render("Replay: "+util.SanitizeText(m.name)) + "\n\n")
+	if m.replay.Len() == 0 {
+		b.WriteString(style.ErrorStyle.Render("The recording has no progress records") + "\n\n")
+		b.WriteString(style.HelpStyle.Render("  q/Quit"))
+		return b.String()
+	}
+
+	b.WriteString(m.timelineView() + "\n\n")
+	b.WriteString(m.transferView())
+	b.WriteString("\n" + m.helpView())
+	return b.String()
+}
+
+// timelineView shows the playhead within the recording and the playback speed
+func (m replayModel) timelineView() string {
+	const width = 40
+	icon := "⏸"
+	if m.playing {
+		icon = "▶"
+	}
+	duration := m.replay.Duration()
+	filled := width
+	if duration > 0 {
+		filled = int(float64(width) * float64(m.position) / float64(duration))
+	}
+	bar := strings.Repeat("━", filled) + strings.Repeat("─", width-filled)
+	at := m.replay.Start().Add(m.position).Local().Format("15:04:05")
+	return fmt.Sprintf("%s %s / %s  %s  %gx  %s  %d/%d records", icon,
+		formatReplayOffset(m.position), formatReplayOffset(duration), bar, replaySpeeds[m.speed],
+		style.HelpStyle.Render(at), m.state.Applied, m.replay.Len())
+}
+
+// transferView shows the transfer as the records up to the playhead reported it
+func (m replayModel) transferView() string {
+	var b strings.Builder
+	state := m.state
+	if prepare := state.Prepare; prepare != nil && state.Session == nil {
+		b.WriteString(fmt.Sprintf("Preparing (%s): %d files, %s of %s hashed\n\n", prepare.State,
+			prepare.Files, util.FormatSize(prepare.Bytes), util.FormatSize(prepare.TotalBytes)))
+	}
+
+	progress := components.NewMultiFileProgress(components.DefaultProgressConfig())
+	if session := state.Session; session != nil {
+		progress.UpdateOverall(components.ProgressData{
+			Current:     session.Bytes,
+			Total:       session.TotalBytes,
+			Rate:        session.Rate,
+			ETA:         time.Duration(session.ETASeconds * float64(time.Second)),
+			Status:      replayProgressStatus(session.State),
+			StartTime:   m.replay.Start(),
+			CurrentFile: session.File,
+		})
+	}
+	// The newest files first, so the ones shown are those in flight at the playhead
+	for i := len(state.Files) - 1; i >= 0; i-- {
+		file := state.Files[i]
+		status := replayProgressStatus(file.State)
+		progress.UpdateFile(file.File, components.ProgressData{
+			Current: file.Bytes, Total: file.TotalBytes, Rate: file.Rate, Status: status,
+		}, status)
+	}
+	b.WriteString(progress.Render())
+	if session := state.Session; session != nil {
+		b.WriteString(fmt.Sprintf("%d of %d files done", session.CompletedFiles, session.Files))
+		if session.FailedFiles > 0 {
+			b.WriteString(style.ErrorStyle.Render(fmt.Sprintf(", %d failed", session.FailedFiles)))
+		}
+		b.WriteString("\n")
+	}
+	if written := state.Written; written != nil {
+		b.WriteString(fmt.Sprintf("Written to disk: %s of %s at %s\n",
+			util.FormatSize(written.Bytes), util.FormatSize(written.TotalBytes), formatRate(written.Rate)))
+	}
+
+	for _, file := range state.Files {
+		if file.Error == "" {
+			continue
+		}
+		line := fmt.Sprintf("%s (%s): %s", util.SanitizeText(file.File), file.State, util.SanitizeText(file.Error))
+		if file.Retries > 0 {
+			line += fmt.Sprintf(", %d retries", file.Retries)
+		}
+		b.WriteString(style.ErrorStyle.Render(line) + "\n")
+	}
+
+	statuses := state.Statuses[max(len(state.Statuses)-replayStatuses, 0):]
+	if len(statuses) > 0 {
+		b.WriteString("\n")
+	}
+	for _, status := range statuses {
+		offset := formatReplayOffset(status.Time.Sub(m.replay.Start()))
+		b.WriteString(style.HelpStyle.Render(offset) + " " + util.SanitizeText(status.Message) + "\n")
+	}
+
+	if complete := state.Complete; complete != nil {
+		b.WriteString("\n")
+		if complete.Error != "" {
+			b.WriteString(style.ErrorStyle.Render("Transfer failed: "+util.SanitizeText(complete.Error)) + "\n")
+		} else {
+			b.WriteString(style.SuccessStyle.Render("Transfer complete") + "\n")
+		}
+	}
+	return b.String()
+}
+
+func (m replayModel) helpView() string {
+	keys := DefaultReplayKeyMap
+	var help []string
+	for _, binding := range []key.Binding{keys.Play, keys.Back, keys.Ahead, keys.Start, keys.End, keys.Faster, keys.Slower, keys.Quit} {
+		help = append(help, binding.Help().Key+"/"+binding.Help().Desc)
+	}
+	return style.HelpStyle.Render("  " + strings.Join(help, "  "))
+}
+
+// replayProgressStatus maps a recorded transfer state to the status of a progress bar
+func replayProgressStatus(state string) string {
+	switch state {
+	case "completed":
+		return "complete"
+	case "failed", "canceled":
+		return "error"
+	case "paused", "pending":
+		return state
+	}
+	return "active"
+}
+
+// formatReplayOffset formats an offset into a recording as m:ss
+func formatReplayOffset(d time.Duration) string {
+	d = d.Round(time.Second)
+	return fmt.Sprintf("%d:%02d", int(d.Minutes()), int(d.Seconds())%60)
+}
@@ -1,8 +1,11 @@
 package ui
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -13,9 +16,13 @@ import (
 	"github.com/charmbracelet/lipgloss"
 	appevents "github.com/rescp17/lanFileSharer/internal/app_events"
 	senderEvent "github.com/rescp17/lanFileSharer/internal/app_events/sender"
+	"github.com/rescp17/lanFileSharer/internal/config"
 	"github.com/rescp17/lanFileSharer/internal/style"
+	"github.com/rescp17/lanFileSharer/internal/util"
 	"github.com/rescp17/lanFileSharer/pkg/discovery"
+	"github.com/rescp17/lanFileSharer/pkg/fileInfo"
 	"github.com/rescp17/lanFileSharer/pkg/multiFilePicker"
+	"github.com/rescp17/lanFileSharer/pkg/transfer"
 	"github.com/rescp17/lanFileSharer/pkg/ui/components"
 )
 
@@ -26,11 +33,13 @@ const (
 	findingReceivers senderState = iota
 	selectingReceiver
 	selectingFiles
+	confirmingSend
 	waitingForReceiverConfirmation
 	sendingFiles
 	transferPaused
 	transferComplete
 	transferFailed
+	requestTimedOut
 )
 
 type senderModel struct {
@@ -73,6 +82,25 @@ type senderModel struct {
 
 	// Transfer progress tracking (legacy - will be replaced)
 	transferProgress *TransferProgress
+
+	// requestedFiles are the files of the last request, sent again after it timed out
+	requestedFiles []fileInfo.FileNode
+	// lockedFiles are the selected files left out because other processes lock them
+	lockedFiles []string
+	// failedFiles are the files the last transfer could not deliver; F sends retryFiles,
+	// which hold just those, to retryReceiver again
+	failedFiles   []senderEvent.FailedFile
+	retryFiles    []fileInfo.FileNode
+	retryReceiver discovery.ServiceInfo
+
+	// lastRate is the last transfer rate seen, in bytes per second, used to estimate how long a send takes
+	lastRate float64
+
+	// wiredPathOffered is set while the app offers to move the transfer off Wi-Fi, see WirelessPathMsg
+	wiredPathOffered bool
+
+	// bandwidthSchedule limits sends by time of day; its active profile is shown in the status bar
+	bandwidthSchedule transfer.BandwidthSchedule
 }
 
 // TransferProgress tracks the overall transfer progress
@@ -83,8 +111,11 @@ type TransferProgress struct {
 	TransferredBytes int64
 	CurrentFile      string
 	TransferRate     float64 // bytes per second
+	FilesPerMinute   float64
 	ETA              string  // estimated time remaining
 	OverallProgress  float64 // percentage 0-100
+	PersistedBytes   int64   // written to disk by the receiver; zero if it does not report it
+	ReceiverDiskRate float64 // bytes per second
 }
 
 var columns = []table.Column{
@@ -92,6 +123,31 @@ var columns = []table.Column{
 	{Title: "Name", Width: 20},
 	{Title: "Address", Width: 20},
 	{Title: "Port", Width: 10},
+	{Title: "Status", Width: 20},
+}
+
+// lowFreeSpace is the free space below which a receiver is flagged in the table
+const lowFreeSpace = 5 << 30
+
+// receiverStatus summarizes the state a receiver advertised, or "" if it advertised none
+func receiverStatus(meta discovery.ServiceMeta) string {
+	switch {
+	case !meta.Advertised:
+		return ""
+	case meta.DoNotDisturb:
+		return "Do not disturb"
+	case meta.Busy:
+		return "Receiver busy"
+	case meta.FreeBytes >= 0 && meta.FreeBytes < lowFreeSpace:
+		return "Only " + util.FormatSize(meta.FreeBytes) + " free"
+	case meta.Relay:
+		// Relays only take files for another receiver, see send --via
+		return "Relay only"
+	case meta.AutoAccept:
+		return "Ready, auto-accepts"
+	default:
+		return "Ready"
+	}
 }
 
 func initSenderModel() senderModel {
@@ -131,7 +187,11 @@ func initSenderModel() senderModel {
 	contextMenu := components.NewContextualMenu("Actions")
 
 	// Initialize theme and layout components
-	themeManager := components.NewThemeManager("") // No config dir for now
+	configDir, err := config.Dir()
+	if err != nil {
+		slog.Warn("Custom themes are not loaded", "error", err)
+	}
+	themeManager := components.NewThemeManager(configDir)
 	responsiveLayout := components.NewResponsiveLayout(themeManager)
 	themeSelector := components.NewThemeSelector(themeManager)
 
@@ -180,7 +240,7 @@ func initSenderModel() senderModel {
 // listenForAppMessages is a command that listens for messages from the app controller.
 func (m *model) listenForAppMessages() tea.Cmd {
 	return func() tea.Msg {
-		return <-m.appController.UIMessages()
+		return <-m.senderController.UIMessages()
 	}
 }
 
@@ -188,12 +248,39 @@ func (m *model) initSender() tea.Cmd {
 	return tea.Batch(m.sender.spinner.Tick, m.listenForAppMessages())
 }
 
+// themeReloadInterval is how often the theme files are checked for changes
+const themeReloadInterval = 2 * time.Second
+
+// themeReloadMsg asks the sender to reload the theme files that changed
+type themeReloadMsg struct{}
+
+// watchThemes schedules the next check of the theme files
+func watchThemes() tea.Cmd {
+	return tea.Tick(themeReloadInterval, func(time.Time) tea.Msg { return themeReloadMsg{} })
+}
+
+// reloadThemes applies edits to the theme files and reports the files that are invalid
+func (m *model) reloadThemes() {
+	changed, errs := m.sender.themeManager.ReloadCustomThemes()
+	for _, err := range errs {
+		m.sender.statusIndicator.AddMessage(components.StatusWarning, err.Error())
+	}
+	if changed && len(errs) == 0 {
+		m.sender.statusIndicator.AddMessage(components.StatusInfo, "Themes reloaded")
+	}
+}
+
+// receiverName is the name of the chosen receiver, safe to display
+func (s *senderModel) receiverName() string {
+	return util.SanitizeText(s.selectedService.Name)
+}
+
 func (m *model) updateReceiverTable(services []discovery.ServiceInfo) {
 	m.sender.services = services
 	rows := []table.Row{}
 	for index, svc := range services {
 		rows = append(rows, table.Row{
-			strconv.Itoa(index), svc.Name, svc.Addr.String(), strconv.Itoa(svc.Port),
+			strconv.Itoa(index), util.WithIcon(util.DeviceIcon(svc.Meta.Icon), util.SanitizeText(svc.Name)), svc.Addr.String(), strconv.Itoa(svc.Port), receiverStatus(svc.Meta),
 		})
 	}
 	m.sender.table.SetRows(rows)
@@ -210,10 +297,19 @@ func (m *model) updateSender(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, nil
 	}
 
+	if _, ok := msg.(themeReloadMsg); ok {
+		m.reloadThemes()
+		return m, watchThemes()
+	}
+
 	if cmd, processed := m.handleSenderAppEvent(msg); processed {
 		return m, cmd
 	}
 
+	if mouseMsg, ok := msg.(tea.MouseMsg); ok {
+		return m, m.handleSenderMouse(mouseMsg)
+	}
+
 	// Handle keyboard input through the keyboard manager
 	if keyMsg, ok := msg.(tea.KeyMsg); ok {
 		action := m.sender.keyboardManager.ProcessKey(keyMsg)
@@ -247,15 +343,24 @@ func (m *model) updateSender(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 		// Handle context menu if visible
 		if m.sender.contextMenu.IsVisible() {
-			if m.sender.contextMenu.Navigate(action) {
+			if m.sender.contextMenu.Navigate(action) && action == components.KeyActionSelect {
 				selectedItem := m.sender.contextMenu.GetSelectedItem()
 				if selectedItem != nil {
 					return m, m.handleMenuAction(selectedItem.Action)
 				}
 			}
+			if keyMsg.Type == tea.KeyEsc {
+				m.sender.contextMenu.Hide()
+			}
 			return m, nil
 		}
 
+		// Send the files the transfer failed to deliver again (F key, once it has ended)
+		if (keyMsg.String() == "f" || keyMsg.String() == "F") && len(m.sender.retryFiles) > 0 &&
+			(m.sender.state == transferComplete || m.sender.state == transferFailed) {
+			return m, m.retryFailedFiles()
+		}
+
 		// Handle retry dialog if visible
 		if m.sender.retryDialog.IsVisible() {
 			switch action {
@@ -278,9 +383,18 @@ func (m *model) updateSender(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, nil
 		}
 
-		// Handle performance panel (P key when not in transfer)
-		if (keyMsg.String() == "p" || keyMsg.String() == "P") &&
-			m.sender.state != sendingFiles && m.sender.state != transferPaused {
+		// Move the transfer to the wired interfaces (W key, once the app offers it)
+		if (keyMsg.String() == "w" || keyMsg.String() == "W") && m.sender.wiredPathOffered &&
+			(m.sender.state == sendingFiles || m.sender.state == transferPaused) {
+			m.sender.wiredPathOffered = false
+			m.senderController.AppEvents() <- senderEvent.UseWiredPathMsg{}
+			return m, nil
+		}
+
+		// Handle performance panel (P key when not in transfer, M during one, where P pauses)
+		inTransfer := m.sender.state == sendingFiles || m.sender.state == transferPaused
+		if ((keyMsg.String() == "p" || keyMsg.String() == "P") && !inTransfer) ||
+			((keyMsg.String() == "m" || keyMsg.String() == "M") && inTransfer) {
 			m.sender.performancePanel.Show()
 			return m, nil
 		}
@@ -359,8 +473,15 @@ func (m *model) handleSenderAppEvent(msg tea.Msg) (tea.Cmd, bool) {
 		return m.listenForAppMessages(), true // Continue listening
 	case senderEvent.TransferStartedMsg:
 		m.sender.state = waitingForReceiverConfirmation
+		m.sender.failedFiles, m.sender.retryFiles = nil, nil
 		m.sender.statusIndicator.AddMessage(components.StatusInfo, "Transfer request sent, waiting for confirmation...")
 		return m.listenForAppMessages(), true
+	case senderEvent.RequestTimedOutMsg:
+		m.sender.state = requestTimedOut
+		m.sender.keyboardManager.SetContext("timeout")
+		m.sender.statusIndicator.AddMessage(components.StatusWarning,
+			fmt.Sprintf("%s did not answer the request in time", msg.Receiver.Name))
+		return m.listenForAppMessages(), true
 	case senderEvent.ReceiverAcceptedMsg:
 		m.sender.state = sendingFiles
 		m.sender.helpPanel.SetContext(components.HelpContextTransfer)
@@ -368,6 +489,29 @@ func (m *model) handleSenderAppEvent(msg tea.Msg) (tea.Cmd, bool) {
 		m.sender.breadcrumb.AddItem("Transferring", "transfer", "🚀", false)
 		m.sender.statusIndicator.AddMessage(components.StatusSuccess, "Transfer accepted! Starting file transfer...")
 		return m.listenForAppMessages(), true
+	case senderEvent.SpeedProbeMsg:
+		m.sender.lastRate = msg.Rate
+		m.sender.statusIndicator.AddMessage(components.StatusInfo,
+			fmt.Sprintf("Connection speed %s, about %s for %s. Press c to cancel if that is too slow",
+				formatRate(msg.Rate), msg.ETA.Round(time.Second), util.FormatSize(msg.TotalBytes)))
+		return m.listenForAppMessages(), true
+	case senderEvent.WirelessPathMsg:
+		m.sender.wiredPathOffered = true
+		m.sender.statusIndicator.AddMessage(components.StatusWarning,
+			fmt.Sprintf("%s goes over Wi-Fi (%s) although both machines are wired. Press w to switch to %s",
+				util.FormatSize(msg.TotalBytes), msg.Interface, strings.Join(msg.Wired, ", ")))
+		return m.listenForAppMessages(), true
+	case senderEvent.PathStatsMsg:
+		paths := make([]components.PathMetrics, 0, len(msg.Paths))
+		for _, path := range msg.Paths {
+			paths = append(paths, components.PathMetrics{Name: path.Interface, Bytes: path.Bytes, Rate: path.Rate})
+		}
+		m.sender.performanceOptimizer.RecordPaths(paths)
+		return m.listenForAppMessages(), true
+	case senderEvent.PauseTransferMsg, senderEvent.ResumeTransferMsg, senderEvent.CancelTransferMsg:
+		// Control commands returned by key handlers are handed to the app
+		m.senderController.AppEvents() <- msg.(appevents.AppEvent)
+		return nil, true
 	case senderEvent.StatusUpdateMsg:
 		// Update status indicator with the message
 		m.sender.statusIndicator.AddMessage(components.StatusInfo, msg.Message)
@@ -382,8 +526,14 @@ func (m *model) handleSenderAppEvent(msg tea.Msg) (tea.Cmd, bool) {
 			TransferredBytes: msg.TransferredBytes,
 			CurrentFile:      msg.CurrentFile,
 			TransferRate:     msg.TransferRate,
+			FilesPerMinute:   msg.FilesPerMinute,
 			ETA:              msg.ETA,
 			OverallProgress:  msg.OverallProgress,
+			PersistedBytes:   msg.PersistedBytes,
+			ReceiverDiskRate: msg.ReceiverDiskRate,
+		}
+		if msg.TransferRate > 0 {
+			m.sender.lastRate = msg.TransferRate
 		}
 
 		// Update enhanced UI components
@@ -397,6 +547,7 @@ func (m *model) handleSenderAppEvent(msg tea.Msg) (tea.Cmd, bool) {
 			CurrentFile: msg.CurrentFile,
 		}
 		m.sender.progressBar.UpdateOverall(overallProgress)
+		m.sender.performanceOptimizer.RecordTransferRate(msg.TransferRate)
 
 		// Update statistics panel
 		m.sender.statsPanel.Update(
@@ -407,6 +558,7 @@ func (m *model) handleSenderAppEvent(msg tea.Msg) (tea.Cmd, bool) {
 
 		// Update advanced statistics collector
 		m.sender.statsCollector.UpdateTransferMetrics(msg.TotalBytes, msg.TransferredBytes, msg.TransferRate)
+		m.sender.statsCollector.UpdatePhaseTimings(msg.Phases)
 
 		// Update current file metrics if available
 		if msg.CurrentFile != "" {
@@ -420,10 +572,20 @@ func (m *model) handleSenderAppEvent(msg tea.Msg) (tea.Cmd, bool) {
 		// Update sparkline
 		m.sender.sparkLine.AddValue(msg.TransferRate)
 
+		return m.listenForAppMessages(), true
+	case senderEvent.FilesFailedMsg:
+		m.sender.failedFiles = msg.Files
+		m.sender.retryFiles = msg.Retry
+		m.sender.retryReceiver = msg.Receiver
 		return m.listenForAppMessages(), true
 	case senderEvent.TransferCompleteMsg:
 		m.sender.state = transferComplete
-		m.sender.statusIndicator.AddMessage(components.StatusSuccess, "Transfer completed successfully! 🎉")
+		if len(m.sender.failedFiles) > 0 {
+			m.sender.statusIndicator.AddMessage(components.StatusWarning,
+				fmt.Sprintf("Transfer completed, but %d files failed", len(m.sender.failedFiles)))
+		} else {
+			m.sender.statusIndicator.AddMessage(components.StatusSuccess, "Transfer completed successfully! 🎉")
+		}
 		// Update progress bar to complete status
 		if m.sender.transferProgress != nil {
 			completeProgress := components.ProgressData{
@@ -438,12 +600,12 @@ func (m *model) handleSenderAppEvent(msg tea.Msg) (tea.Cmd, bool) {
 	case senderEvent.TransferPausedMsg:
 		m.sender.state = transferPaused
 		m.sender.keyboardManager.SetContext("paused")
-		m.sender.statusIndicator.AddMessage(components.StatusWarning, "Transfer paused")
+		m.sender.statusIndicator.AddMessage(components.StatusWarning, "Transfer paused"+filesInFlight(msg.Files))
 		return m.listenForAppMessages(), true
 	case senderEvent.TransferResumedMsg:
 		m.sender.state = sendingFiles
 		m.sender.keyboardManager.SetContext("transfer")
-		m.sender.statusIndicator.AddMessage(components.StatusInfo, "Transfer resumed")
+		m.sender.statusIndicator.AddMessage(components.StatusInfo, "Transfer resumed"+filesInFlight(msg.Files))
 		return m.listenForAppMessages(), true
 	case senderEvent.TransferCancelledMsg:
 		m.sender.state = transferFailed // Treat cancellation as failure for UI purposes
@@ -483,17 +645,7 @@ func (m *model) updateSelectingReceiverState(msg tea.Msg) tea.Cmd {
 		switch msg.Type {
 		case tea.KeyEnter:
 			if len(m.sender.services) > 0 {
-				selectedIndex := m.sender.table.Cursor()
-				if selectedIndex >= 0 && selectedIndex < len(m.sender.services) {
-					m.err = nil // Reset any previous error
-					m.sender.selectedService = m.sender.services[selectedIndex]
-					m.sender.state = selectingFiles
-				} else {
-					// This case should ideally not be hit, but good to have for safety
-					err := fmt.Errorf("internal error: cursor %d is out of sync with services list (len %d)", selectedIndex, len(m.sender.services))
-					slog.Error("Cursor out of sync", "error", err)
-					m.err = err
-				}
+				m.chooseReceiver()
 				_, cmd := m.sender.table.Update(msg)
 				return cmd
 			}
@@ -504,14 +656,29 @@ func (m *model) updateSelectingReceiverState(msg tea.Msg) tea.Cmd {
 	return cmd
 }
 
+// chooseReceiver moves on to file selection with the receiver under the table cursor
+func (m *model) chooseReceiver() {
+	selectedIndex := m.sender.table.Cursor()
+	if selectedIndex >= 0 && selectedIndex < len(m.sender.services) {
+		m.err = nil // Reset any previous error
+		m.sender.selectedService = m.sender.services[selectedIndex]
+		m.sender.state = selectingFiles
+	} else {
+		// This case should ideally not be hit, but good to have for safety
+		err := fmt.Errorf("internal error: cursor %d is out of sync with services list (len %d)", selectedIndex, len(m.sender.services))
+		slog.Error("Cursor out of sync", "error", err)
+		m.err = err
+	}
+}
+
 func (m *model) updateSelectingFilesState(msg tea.Msg) tea.Cmd {
 	switch msg := msg.(type) {
 	case multiFilePicker.SelectedFileNodeMsg:
-		// The app will now send messages about the transfer progress
-		m.appController.AppEvents() <- senderEvent.SendFilesMsg{
-			Receiver: m.sender.selectedService,
-			Files:    msg.Files,
-		}
+		// Nothing is sent until the summary is confirmed
+		m.sender.requestedFiles = msg.Files
+		m.sender.lockedFiles = msg.Locked
+		m.sender.state = confirmingSend
+		m.sender.keyboardManager.SetContext("confirm_send")
 	}
 	newFpModel, cmd := m.sender.fp.Update(msg)
 	m.sender.fp = newFpModel.(multiFilePicker.Model)
@@ -549,13 +716,15 @@ func (m *model) senderView() string {
 			mainContent += "Use arrow keys to navigate, Enter to select."
 		}
 	case selectingFiles:
-		receiverInfo := fmt.Sprintf("Receiver: %s", style.HighlightFontStyle.Render(m.sender.selectedService.Name))
+		receiverInfo := fmt.Sprintf("Receiver: %s", style.HighlightFontStyle.Render(m.sender.receiverName()))
 		if m.sender.responsiveLayout.IsCompactMode() {
 			receiverInfo = m.sender.responsiveLayout.TruncateText(receiverInfo)
 		}
 		mainContent = receiverInfo + "\n" + m.sender.fp.View() + "\n"
+	case confirmingSend:
+		mainContent = m.renderSendSummary()
 	case waitingForReceiverConfirmation:
-		receiverName := m.sender.selectedService.Name
+		receiverName := m.sender.receiverName()
 		if m.sender.responsiveLayout.IsCompactMode() {
 			receiverName = m.sender.responsiveLayout.TruncateText(receiverName)
 		}
@@ -570,6 +739,13 @@ func (m *model) senderView() string {
 		mainContent = m.renderTransferComplete()
 	case transferFailed:
 		mainContent = m.renderTransferFailed()
+	case requestTimedOut:
+		receiverName := m.sender.receiverName()
+		if m.sender.responsiveLayout.IsCompactMode() {
+			receiverName = m.sender.responsiveLayout.TruncateText(receiverName)
+		}
+		mainContent = fmt.Sprintf("\n⏱ %s did not answer the request in time.\n\nPress r to send it again, or Esc to choose other files.",
+			style.HighlightFontStyle.Render(receiverName))
 	default:
 		mainContent = "Internal error: unknown sender state"
 	}
@@ -636,7 +812,14 @@ func (m *model) senderView() string {
 }
 
 func (m *senderModel) reset() {
+	fp := m.fp
+	lastRate := m.lastRate
+	theme := m.themeManager.GetCurrentTheme().Name
 	*m = initSenderModel()
+	m.lastRate = lastRate
+	m.themeManager.SetTheme(theme)
+	m.fp.SetHashWorkers(fp.HashWorkers())
+	m.fp.SetEventBus(fp.EventBus())
 }
 
 func (m *senderModel) adjustTableCursor(newRowCount int) {
@@ -657,12 +840,100 @@ func (m *senderModel) adjustTableCursor(newRowCount int) {
 	}
 }
 
+// summaryLargestFiles is how many of the largest files the send summary lists
+const summaryLargestFiles = 3
+
+// sendSummary describes the files of a request before it is sent
+type sendSummary struct {
+	Files        int
+	TotalBytes   int64
+	UnknownSizes int                 // Streams whose size is only known once sent
+	Largest      []fileInfo.FileNode // Largest files first
+}
+
+// summarizeFiles counts the files under nodes and picks the largest of them
+func summarizeFiles(nodes []fileInfo.FileNode) sendSummary {
+	var summary sendSummary
+	var files []fileInfo.FileNode
+	var walk func(node fileInfo.FileNode)
+	walk = func(node fileInfo.FileNode) {
+		if node.IsDir {
+			for _, child := range node.Children {
+				walk(child)
+			}
+			return
+		}
+		summary.Files++
+		if node.Size == fileInfo.UnknownSize {
+			summary.UnknownSizes++
+			return
+		}
+		summary.TotalBytes += node.Size
+		files = append(files, node)
+	}
+	for _, node := range nodes {
+		walk(node)
+	}
+
+	sort.SliceStable(files, func(i, j int) bool { return files[i].Size > files[j].Size })
+	if len(files) > summaryLargestFiles {
+		files = files[:summaryLargestFiles]
+	}
+	summary.Largest = files
+	return summary
+}
+
+// renderSendSummary renders what is about to be sent, so a stray key press cannot start a huge transfer
+func (m *model) renderSendSummary() string {
+	var result strings.Builder
+	summary := summarizeFiles(m.sender.requestedFiles)
+
+	receiverName := m.sender.receiverName()
+	if m.sender.responsiveLayout.IsCompactMode() {
+		receiverName = m.sender.responsiveLayout.TruncateText(receiverName)
+	}
+	result.WriteString(fmt.Sprintf("\nSend to %s?\n\n", style.HighlightFontStyle.Render(receiverName)))
+
+	result.WriteString(fmt.Sprintf("Files: %d, %s", summary.Files, util.FormatSize(summary.TotalBytes)))
+	if summary.UnknownSizes > 0 {
+		result.WriteString(fmt.Sprintf(" plus %d of unknown size", summary.UnknownSizes))
+	}
+	result.WriteString("\n")
+
+	if len(m.sender.lockedFiles) > 0 {
+		result.WriteString(style.ErrorStyle.Render(fmt.Sprintf("Skipping %d files locked by other programs:", len(m.sender.lockedFiles))))
+		result.WriteString("\n")
+		for _, path := range m.sender.lockedFiles {
+			result.WriteString(fmt.Sprintf("  %s\n", m.sender.responsiveLayout.TruncateText(path)))
+		}
+	}
+
+	if len(summary.Largest) > 0 && summary.Files > 1 {
+		result.WriteString("Largest:\n")
+		for _, file := range summary.Largest {
+			result.WriteString(fmt.Sprintf("  %s  %s\n",
+				m.sender.responsiveLayout.TruncateText(file.Name), util.FormatSize(file.Size)))
+		}
+	}
+
+	if m.sender.lastRate > 0 && summary.TotalBytes > 0 {
+		eta := time.Duration(float64(summary.TotalBytes) / m.sender.lastRate * float64(time.Second))
+		result.WriteString(fmt.Sprintf("Estimated time: about %s at the last rate of %s\n",
+			eta.Round(time.Second), formatRate(m.sender.lastRate)))
+	} else {
+		result.WriteString("Estimated time: unknown until a transfer has run\n")
+	}
+
+	result.WriteString("\nPress y to send, or n/Esc to change the selection.")
+	return result.String()
+}
+
 // renderTransferProgress renders the enhanced transfer progress display
 func (m *model) renderTransferProgress() string {
 	var result strings.Builder
 
 	// Header with receiver info (adapt to layout)
-	receiverName := m.sender.selectedService.Name
+	receiverName := m.sender.receiverName()
 	if m.sender.responsiveLayout.IsCompactMode() {
 		receiverName = m.sender.responsiveLayout.TruncateText(receiverName)
 	}
@@ -705,8 +976,14 @@ func (m *model) renderTransferProgress() string {
 		}
 
 		result.WriteString(m.sender.sparkLine.Render())
-		if m.sender.transferProgress != nil {
-			result.WriteString(fmt.Sprintf(" %s", formatRate(m.sender.transferProgress.TransferRate)))
+		if p := m.sender.transferProgress; p != nil {
+			result.WriteString(fmt.Sprintf(" %s%s", formatRate(p.TransferRate), formatFilesPerMinute(p.FilesPerMinute)))
+			if p.ETA != "" {
+				result.WriteString(fmt.Sprintf("  ETA %s", p.ETA))
+			}
+			if p.PersistedBytes > 0 {
+				result.WriteString(fmt.Sprintf("  Written %s (disk %s)", util.FormatSize(p.PersistedBytes), formatRate(p.ReceiverDiskRate)))
+			}
 		}
 		result.WriteString("\n\n")
 	}
@@ -722,10 +999,14 @@ func (m *model) renderTransferProgress() string {
 	}
 
 	// Control hints (adapt to layout)
+	wired := ""
+	if m.sender.wiredPathOffered {
+		wired = " | W=Use wired"
+	}
 	if m.sender.responsiveLayout.IsCompactMode() {
-		result.WriteString(style.FileStyle.Render("P=Pause | C=Cancel"))
+		result.WriteString(style.FileStyle.Render("P=Pause | C=Cancel" + wired))
 	} else {
-		result.WriteString(style.FileStyle.Render("Controls: P=Pause | C=Cancel | 1-5=Stats Views | ?=Help"))
+		result.WriteString(style.FileStyle.Render("Controls: P=Pause | C=Cancel" + wired + " | M=Metrics | 1-5=Stats Views | ?=Help"))
 	}
 
 	return result.String()
@@ -737,7 +1018,7 @@ func (m *model) renderTransferPaused() string {
 
 	// Header with pause indicator
 	result.WriteString(fmt.Sprintf("\n⏸️  Transfer paused to %s\n\n",
-		style.HighlightFontStyle.Render(m.sender.selectedService.Name)))
+		style.HighlightFontStyle.Render(m.sender.receiverName())))
 
 	// Enhanced progress display with paused status
 	if m.sender.progressBar != nil {
@@ -827,8 +1108,13 @@ func (m *model) renderTransferComplete() string {
 	var result strings.Builder
 
 	// Success header
-	result.WriteString(fmt.Sprintf("\n✅ Transfer completed successfully to %s!\n\n",
-		style.HighlightFontStyle.Render(m.sender.selectedService.Name)))
+	if len(m.sender.failedFiles) > 0 {
+		result.WriteString(fmt.Sprintf("\n⚠️ Transfer to %s completed, but some files failed\n\n",
+			style.HighlightFontStyle.Render(m.sender.receiverName())))
+	} else {
+		result.WriteString(fmt.Sprintf("\n✅ Transfer completed successfully to %s!\n\n",
+			style.HighlightFontStyle.Render(m.sender.receiverName())))
+	}
 
 	// Final progress display (complete status)
 	if m.sender.progressBar != nil {
@@ -843,6 +1129,12 @@ func (m *model) renderTransferComplete() string {
 		result.WriteString("\n")
 	}
 
+	// Bandwidth over the whole session
+	if summary := m.sender.statsCollector.RenderSessionSummary(60, 10); summary != "" {
+		result.WriteString(summary)
+		result.WriteString("\n")
+	}
+
 	// Success status message
 	if m.sender.statusIndicator != nil {
 		m.sender.statusIndicator.SetCompact(false)
@@ -853,8 +1145,10 @@ func (m *model) renderTransferComplete() string {
 		}
 	}
 
+	result.WriteString(m.renderFailedFiles())
+
 	// Control hints for completion
-	result.WriteString(style.FileStyle.Render("Controls: Enter=Send More Files | Q=Quit"))
+	result.WriteString(style.FileStyle.Render("Controls: Enter=Send More Files" + m.retryFailedHint() + " | Q=Quit"))
 
 	return result.String()
 }
@@ -897,61 +1191,85 @@ func (m *model) renderTransferFailed() string {
 		result.WriteString(fmt.Sprintf("Error: %s\n\n", style.ErrorStyle.Render(m.err.Error())))
 	}
 
+	result.WriteString(m.renderFailedFiles())
+
 	// Control hints for failure
-	result.WriteString(style.FileStyle.Render("Controls: Enter=Try Again | Q=Quit"))
+	result.WriteString(style.FileStyle.Render("Controls: Enter=Try Again" + m.retryFailedHint() + " | Q=Quit"))
 
 	return result.String()
 }
 
-// classifyError classifies an error into a specific error type for better handling
-func (m *model) classifyError(err error) components.ErrorType {
-	if err == nil {
-		return components.ErrorTypeUnknown
-	}
-
-	errStr := strings.ToLower(err.Error())
+// maxFailedFilesShown is how many failed files the end of a transfer lists
+const maxFailedFilesShown = 10
 
-	// Network-related errors
-	if strings.Contains(errStr, "network") ||
-		strings.Contains(errStr, "connection") ||
-		strings.Contains(errStr, "dial") ||
-		strings.Contains(errStr, "refused") ||
-		strings.Contains(errStr, "unreachable") {
-		return components.ErrorTypeNetwork
+// renderFailedFiles lists the files the last transfer failed to deliver with the reasons
+func (m *model) renderFailedFiles() string {
+	if len(m.sender.failedFiles) == 0 {
+		return ""
 	}
-
-	// Timeout errors
-	if strings.Contains(errStr, "timeout") ||
-		strings.Contains(errStr, "deadline") ||
-		strings.Contains(errStr, "context canceled") {
-		return components.ErrorTypeTimeout
+	var result strings.Builder
+	result.WriteString(style.ErrorStyle.Render(fmt.Sprintf("%d files failed:", len(m.sender.failedFiles))))
+	result.WriteString("\n")
+	for i, file := range m.sender.failedFiles {
+		if i == maxFailedFilesShown {
+			result.WriteString(fmt.Sprintf("  ... and %d more\n", len(m.sender.failedFiles)-i))
+			break
+		}
+		result.WriteString(fmt.Sprintf("  %s: %s\n", file.Path, file.Reason))
 	}
+	result.WriteString("\n")
+	return result.String()
+}
 
-	// File system errors
-	if strings.Contains(errStr, "file") ||
-		strings.Contains(errStr, "directory") ||
-		strings.Contains(errStr, "no such") ||
-		strings.Contains(errStr, "not found") ||
-		strings.Contains(errStr, "exists") {
-		return components.ErrorTypeFileSystem
+// retryFailedHint is the control hint for sending the failed files again, if there are any
+func (m *model) retryFailedHint() string {
+	if len(m.sender.retryFiles) == 0 {
+		return ""
 	}
+	return fmt.Sprintf(" | F=Retry %d Failed", len(m.sender.failedFiles))
+}
 
-	// Permission errors
-	if strings.Contains(errStr, "permission") ||
-		strings.Contains(errStr, "denied") ||
-		strings.Contains(errStr, "access") ||
-		strings.Contains(errStr, "forbidden") {
-		return components.ErrorTypePermission
+// retryFailedFiles sends just the files the last transfer failed to deliver to the same
+// receiver, in a new session, without selecting them again
+func (m *model) retryFailedFiles() tea.Cmd {
+	files, receiver := m.sender.retryFiles, m.sender.retryReceiver
+	m.sender.failedFiles, m.sender.retryFiles = nil, nil
+	m.sender.retryDialog.Hide()
+	m.sender.errorHandler.Clear()
+	m.err = nil
+
+	m.sender.selectedService = receiver
+	m.sender.requestedFiles = files
+	m.sender.state = waitingForReceiverConfirmation
+	m.sender.statusIndicator.AddMessage(components.StatusInfo, "Sending the failed files again...")
+	m.senderController.AppEvents() <- senderEvent.SendFilesMsg{
+		Receiver: receiver,
+		Files:    files,
 	}
+	return nil
+}
 
-	// User cancellation
-	if strings.Contains(errStr, "cancel") ||
-		strings.Contains(errStr, "abort") ||
-		strings.Contains(errStr, "interrupt") {
+// classifyError maps an error to the error type shown in the UI, using the typed
+// errors of the transfer stack
+func (m *model) classifyError(err error) components.ErrorType {
+	switch {
+	case err == nil:
+		return components.ErrorTypeUnknown
+	case errors.Is(err, context.Canceled), errors.Is(err, transfer.ErrTransferCancelled):
 		return components.ErrorTypeUserCancelled
+	case errors.Is(err, transfer.ErrTimeout), errors.Is(err, context.DeadlineExceeded):
+		return components.ErrorTypeTimeout
+	case errors.Is(err, transfer.ErrPeerUnreachable), errors.Is(err, transfer.ErrConnectionLost):
+		return components.ErrorTypeNetwork
+	case errors.Is(err, transfer.ErrPermissionDenied):
+		return components.ErrorTypePermission
+	case errors.Is(err, transfer.ErrDiskFull), errors.Is(err, transfer.ErrFileNotFound), errors.Is(err, transfer.ErrSourceModified):
+		return components.ErrorTypeFileSystem
+	case errors.Is(err, transfer.ErrChecksumMismatch), errors.Is(err, transfer.ErrFileInfected):
+		return components.ErrorTypeIntegrity
+	default:
+		return components.ErrorTypeUnknown
 	}
-
-	return components.ErrorTypeUnknown
 }
 
 // retryLastOperation attempts to retry the last failed operation
@@ -987,6 +1305,22 @@ func formatRate(rate float64) string {
 	return fmt.Sprintf("%.0f B/s", rate)
 }
 
+// formatBandwidthProfile names a bandwidth profile with its limit
+func formatBandwidthProfile(profile transfer.BandwidthProfile) string {
+	if profile.BytesPerSecond == 0 {
+		return profile.Name + ": unlimited"
+	}
+	return profile.Name + ": " + formatRate(float64(profile.BytesPerSecond))
+}
+
+// formatFilesPerMinute formats the file rate shown next to the byte rate, or "" before a file finished
+func formatFilesPerMinute(rate float64) string {
+	if rate <= 0 {
+		return ""
+	}
+	return fmt.Sprintf(" · %.1f files/min", rate)
+}
+
 // handleRefresh handles refresh actions
 func (m *model) handleRefresh() tea.Cmd {
 	switch m.sender.state {
@@ -1026,10 +1360,14 @@ func (m *model) handleStateSpecificAction(action components.KeyAction) tea.Cmd {
 		return m.handleSelectionAction(action)
 	case selectingFiles:
 		return m.handleFileSelectionAction(action)
+	case confirmingSend:
+		return m.handleConfirmSendAction(action)
 	case sendingFiles, transferPaused:
 		return m.handleTransferAction(action)
 	case transferFailed:
 		return m.handleErrorAction(action)
+	case requestTimedOut:
+		return m.handleTimedOutAction(action)
 	case transferComplete:
 		return m.handleCompleteAction(action)
 	default:
@@ -1073,6 +1411,7 @@ func (m *model) handleFileSelectionAction(action components.KeyAction) tea.Cmd {
 		// Confirm file selection and start transfer
 		return nil
 	case components.KeyActionBack:
+		m.sender.fp.CancelHashing()
 		m.sender.state = selectingReceiver
 		m.sender.keyboardManager.SetContext("selection")
 		return nil
@@ -1107,6 +1446,47 @@ func (m *model) handleErrorAction(action components.KeyAction) tea.Cmd {
 	}
 }
 
+// handleConfirmSendAction handles actions on the summary shown before a request is sent
+func (m *model) handleConfirmSendAction(action components.KeyAction) tea.Cmd {
+	switch action {
+	case components.KeyActionConfirm:
+		// The app will now send messages about the transfer progress
+		m.sender.state = waitingForReceiverConfirmation
+		m.senderController.AppEvents() <- senderEvent.SendFilesMsg{
+			Receiver: m.sender.selectedService,
+			Files:    m.sender.requestedFiles,
+		}
+		return nil
+	case components.KeyActionBack:
+		m.sender.state = selectingFiles
+		m.sender.keyboardManager.SetContext("file_selection")
+		return nil
+	default:
+		return nil
+	}
+}
+
+// handleTimedOutAction handles actions after the receiver did not answer the request
+func (m *model) handleTimedOutAction(action components.KeyAction) tea.Cmd {
+	switch action {
+	case components.KeyActionRetry:
+		// Send the same files again; the app reports TransferStartedMsg once it is under way
+		m.sender.state = waitingForReceiverConfirmation
+		m.sender.statusIndicator.AddMessage(components.StatusInfo, "Sending the request again...")
+		m.senderController.AppEvents() <- senderEvent.SendFilesMsg{
+			Receiver: m.sender.selectedService,
+			Files:    m.sender.requestedFiles,
+		}
+		return nil
+	case components.KeyActionBack:
+		m.sender.state = selectingFiles
+		m.sender.keyboardManager.SetContext("file_selection")
+		return nil
+	default:
+		return nil
+	}
+}
+
 // handleCompleteAction handles actions after transfer completion
 func (m *model) handleCompleteAction(action components.KeyAction) tea.Cmd {
 	switch action {
@@ -1123,19 +1503,14 @@ func (m *model) handleCompleteAction(action components.KeyAction) tea.Cmd {
 	}
 }
 
-// handlePauseResume handles pause/resume actions
+// handlePauseResume handles pause/resume actions. The state changes once the app reports
+// the session paused or resumed, which covers every file in flight.
 func (m *model) handlePauseResume() tea.Cmd {
 	if m.sender.state == sendingFiles {
-		// Pause transfer
-		m.sender.state = transferPaused
-		m.sender.keyboardManager.SetContext("paused")
 		return func() tea.Msg {
 			return senderEvent.PauseTransferMsg{}
 		}
 	} else if m.sender.state == transferPaused {
-		// Resume transfer
-		m.sender.state = sendingFiles
-		m.sender.keyboardManager.SetContext("transfer")
 		return func() tea.Msg {
 			return senderEvent.ResumeTransferMsg{}
 		}
@@ -1155,7 +1530,13 @@ func (m *model) updateStatusBar() {
 	case selectingReceiver:
 		m.sender.statusBar.AddLeftItem(fmt.Sprintf("%d receivers found", len(m.sender.services)), "📡", style.FileStyle)
 	case selectingFiles:
-		m.sender.statusBar.AddLeftItem("Select files", "📁", style.FileStyle)
+		if m.sender.fp.Hashing() {
+			m.sender.statusBar.AddLeftItem("Preparing files", "⏳", style.FileStyle)
+		} else {
+			m.sender.statusBar.AddLeftItem("Select files", "📁", style.FileStyle)
+		}
+	case confirmingSend:
+		m.sender.statusBar.AddLeftItem("Confirm send", "❓", style.FileStyle)
 	case waitingForReceiverConfirmation:
 		m.sender.statusBar.AddLeftItem("Waiting for confirmation", "⏳", style.FileStyle)
 	case sendingFiles:
@@ -1171,24 +1552,23 @@ func (m *model) updateStatusBar() {
 		m.sender.statusBar.AddLeftItem("Complete", "✅", style.SuccessStyle)
 	case transferFailed:
 		m.sender.statusBar.AddLeftItem("Failed", "❌", style.ErrorStyle)
+	case requestTimedOut:
+		m.sender.statusBar.AddLeftItem("Timed out", "⏱", lipgloss.NewStyle().Foreground(lipgloss.Color("214")))
 	}
 
 	// Center - current file or receiver info
 	if m.sender.state == sendingFiles && m.sender.transferProgress != nil && m.sender.transferProgress.CurrentFile != "" {
-		filename := m.sender.transferProgress.CurrentFile
-		if len(filename) > 30 {
-			filename = filename[:27] + "..."
-		}
+		filename := util.Truncate(util.SanitizeText(m.sender.transferProgress.CurrentFile), 30)
 		m.sender.statusBar.AddCenterItem(filename, "📄", style.FileStyle)
 	} else if m.sender.selectedService.Name != "" {
-		receiverName := m.sender.selectedService.Name
-		if len(receiverName) > 20 {
-			receiverName = receiverName[:17] + "..."
-		}
+		receiverName := util.Truncate(m.sender.receiverName(), 20)
 		m.sender.statusBar.AddCenterItem(receiverName, "📡", style.HighlightFontStyle)
 	}
 
-	// Right side - transfer rate or time
+	// Right side - the bandwidth profile in effect, then transfer rate or time
+	if profile, ok := m.sender.bandwidthSchedule.Active(time.Now()); ok {
+		m.sender.statusBar.AddRightItem(formatBandwidthProfile(profile), "🚦", style.FileStyle)
+	}
 	if m.sender.state == sendingFiles && m.sender.transferProgress != nil {
 		rate := formatRate(m.sender.transferProgress.TransferRate)
 		m.sender.statusBar.AddRightItem(rate, "⚡", style.FileStyle)
@@ -1211,3 +1591,11 @@ func (m *model) handleCancel() tea.Cmd {
 	}
 	return nil
 }
+
+// filesInFlight describes how many files a session change applied to, if more than one
+func filesInFlight(files int) string {
+	if files <= 1 {
+		return ""
+	}
+	return fmt.Sprintf(" (%d files)", files)
+}
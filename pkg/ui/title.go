@@ -0,0 +1,168 @@
+package ui
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// appTitle is the terminal title while nothing is transferred
+const appTitle = "lanFileSharer"
+
+// taskbarState is the state of an OSC 9;4 progress report
+type taskbarState int
+
+const (
+	taskbarHidden taskbarState = iota
+	taskbarNormal
+	taskbarError
+	taskbarIndeterminate
+	taskbarPaused
+)
+
+// terminalStatus is what the terminal title and its tab or taskbar entry show
+type terminalStatus struct {
+	title   string
+	state   taskbarState
+	percent int
+}
+
+// terminalReporter writes the status of transfers to the terminal, so it shows while the
+// window is in the background. It is shared by the copies of the model.
+type terminalReporter struct {
+	mu      sync.Mutex
+	out     io.Writer
+	taskbar bool
+	last    terminalStatus
+}
+
+func newTerminalReporter() *terminalReporter {
+	return &terminalReporter{out: os.Stdout, taskbar: taskbarSupported(os.Getenv)}
+}
+
+// taskbarSupported reports whether the terminal shows OSC 9;4 progress. It is not sent to
+// others, as some, like iTerm2, show OSC 9 as a notification instead.
+func taskbarSupported(getenv func(string) string) bool {
+	switch {
+	case getenv("WT_SESSION") != "", getenv("ConEmuPID") != "":
+		return true
+	}
+	switch getenv("TERM_PROGRAM") {
+	case "ghostty", "WezTerm":
+		return true
+	}
+	return false
+}
+
+// update returns the commands that show status, if it changed since the last one
+func (r *terminalReporter) update(status terminalStatus) tea.Cmd {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if status == r.last {
+		return nil
+	}
+	previous := r.last
+	r.last = status
+
+	var cmds []tea.Cmd
+	if status.title != previous.title {
+		cmds = append(cmds, tea.SetWindowTitle(status.title))
+	}
+	if r.taskbar && (status.state != previous.state || status.percent != previous.percent) {
+		cmds = append(cmds, func() tea.Msg {
+			r.writeTaskbar()
+			return nil
+		})
+	}
+	return tea.Batch(cmds...)
+}
+
+// reset hides the progress from the taskbar once the program ends
+func (r *terminalReporter) reset() {
+	if r == nil || !r.taskbar {
+		return
+	}
+	r.mu.Lock()
+	r.last = terminalStatus{}
+	r.mu.Unlock()
+	r.writeTaskbar()
+}
+
+// writeTaskbar sends the latest progress, which commands run out of order cannot undo
+func (r *terminalReporter) writeTaskbar() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	fmt.Fprintf(r.out, "\x1b]9;4;%d;%d\x07", r.last.state, r.last.percent)
+}
+
+// ResetTerminal removes the progress the final model of the program left in the taskbar
+func ResetTerminal(final tea.Model) {
+	switch m := final.(type) {
+	case model:
+		m.terminal.reset()
+	case *model:
+		m.terminal.reset()
+	}
+}
+
+// terminalStatus reports the transfer in progress, preferring the one on the tab in view
+func (m model) terminalStatus() terminalStatus {
+	send, receive := m.sendStatus(), m.receiveStatus()
+	switch m.mode {
+	case Sender:
+		return send
+	case Receiver:
+		return receive
+	case Both:
+		if send.state == taskbarHidden || (receive.state != taskbarHidden && m.activeTab() == receiveTab) {
+			return receive
+		}
+		return send
+	}
+	return terminalStatus{title: appTitle}
+}
+
+func (m model) sendStatus() terminalStatus {
+	percent := 0
+	if p := m.sender.transferProgress; p != nil {
+		percent = min(max(int(p.OverallProgress), 0), 100)
+	}
+	switch m.sender.state {
+	case waitingForReceiverConfirmation:
+		return terminalStatus{title: "Waiting for " + m.sender.receiverName() + " - " + appTitle, state: taskbarIndeterminate}
+	case sendingFiles:
+		return terminalStatus{title: fmt.Sprintf("Sending %d%% - %s", percent, appTitle), state: taskbarNormal, percent: percent}
+	case transferPaused:
+		return terminalStatus{title: fmt.Sprintf("Paused %d%% - %s", percent, appTitle), state: taskbarPaused, percent: percent}
+	case transferFailed:
+		return terminalStatus{title: "Send failed - " + appTitle, state: taskbarError, percent: percent}
+	case transferComplete:
+		return terminalStatus{title: "Sent - " + appTitle}
+	}
+	return terminalStatus{title: appTitle}
+}
+
+func (m model) receiveStatus() terminalStatus {
+	progress := m.receiver.lastProgress
+	percent := 0
+	if progress.TotalBytes > 0 {
+		percent = min(max(int(progress.ReceivedBytes*100/progress.TotalBytes), 0), 100)
+	}
+	switch m.receiver.state {
+	case awaitingConfirmation:
+		return terminalStatus{title: "Incoming files - " + appTitle, state: taskbarIndeterminate}
+	case receivingFiles:
+		return terminalStatus{title: fmt.Sprintf("Receiving %d%% - %s", percent, appTitle), state: taskbarNormal, percent: percent}
+	case receiveFailed:
+		return terminalStatus{title: "Receive failed - " + appTitle, state: taskbarError, percent: percent}
+	case receiveComplete:
+		return terminalStatus{title: "Received - " + appTitle}
+	}
+	return terminalStatus{title: appTitle}
+}
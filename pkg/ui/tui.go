@@ -7,10 +7,16 @@ import (
 
 	tea "github.com/charmbracelet/bubbletea"
 	appevents "github.com/rescp17/lanFileSharer/internal/app_events"
+	"github.com/rescp17/lanFileSharer/internal/config"
 	"github.com/rescp17/lanFileSharer/internal/style"
 	"github.com/rescp17/lanFileSharer/pkg/discovery"
+	"github.com/rescp17/lanFileSharer/pkg/peers"
 	receiverApp "github.com/rescp17/lanFileSharer/pkg/receiver"
+	"github.com/rescp17/lanFileSharer/pkg/recent"
+	"github.com/rescp17/lanFileSharer/pkg/scan"
 	senderApp "github.com/rescp17/lanFileSharer/pkg/sender"
+	"github.com/rescp17/lanFileSharer/pkg/storage"
+	"github.com/rescp17/lanFileSharer/pkg/webhook"
 )
 
 type Mode int
@@ -19,74 +25,183 @@ const (
 	None Mode = iota
 	Sender
 	Receiver
+	// Both sends and receives from one instance, with a tab for each side
+	Both
 )
 
 type model struct {
-	mode          Mode
-	appController AppController
-	sender        senderModel
-	receiver      receiverModel
-	ctx           context.Context
-	cancel        context.CancelFunc
-	err           error
+	mode               Mode
+	senderController   AppController
+	receiverController AppController
+	sender             senderModel
+	receiver           receiverModel
+	combined           combinedModel
+	config             config.Config
+	ctx                context.Context
+	cancel             context.CancelFunc
+	err                error
+	// terminal shows the progress in the terminal title and taskbar
+	terminal *terminalReporter
 }
 
-func InitialModel(m Mode, port int, outputPath string) model {
-	var appController AppController
+func InitialModel(m Mode, port int, outputPath string, cfg config.Config) model {
+	var senderController, receiverController AppController
 	var sender senderModel
 	var receiver receiverModel
+	var combined combinedModel
 
+	// Both sides share one discovery adapter and the device identity in the config directory
+	adapter := &discovery.MDNSAdapter{}
 	switch m {
 	case Sender:
-		appController = senderApp.NewApp(&discovery.MDNSAdapter{})
-		sender = initSenderModel()
+		senderController, sender = newSender(cfg, adapter, "")
 	case Receiver:
-		appController = receiverApp.NewApp(port, outputPath)
-		receiver = initReceiverModel(port)
+		receiverController, receiver = newReceiver(cfg, adapter, port, outputPath, "")
+	case Both:
+		serviceName, err := receiverApp.NewServiceName()
+		if err != nil {
+			slog.Warn("Could not name the receiver service", "error", err)
+		}
+		senderController, sender = newSender(cfg, adapter, serviceName)
+		receiverController, receiver = newReceiver(cfg, adapter, port, outputPath, serviceName)
+		combined = initCombinedModel()
 	}
 
 	ctx, cancel := context.WithCancel(context.Background())
 
 	return model{
-		mode:          m,
-		appController: appController,
-		sender:        sender,
-		receiver:      receiver,
-		ctx:           ctx,
-		cancel:        cancel,
+		mode:               m,
+		senderController:   senderController,
+		receiverController: receiverController,
+		sender:             sender,
+		receiver:           receiver,
+		combined:           combined,
+		config:             cfg,
+		ctx:                ctx,
+		cancel:             cancel,
+		terminal:           newTerminalReporter(),
+	}
+}
+
+// newSender creates the sender app and its model; ignoreService hides this instance's own receiver
+func newSender(cfg config.Config, adapter discovery.Adapter, ignoreService string) (AppController, senderModel) {
+	retryPolicy := senderApp.RetryPolicy(cfg)
+	bandwidthSchedule := senderApp.BandwidthSchedule(cfg)
+	controller := senderApp.NewAppWithOptions(adapter, senderApp.Options{
+		OnSendStart:        cfg.OnSendStart,
+		OnSendComplete:     cfg.OnSendComplete,
+		GenerateManifest:   cfg.GenerateManifest,
+		SentCachePath:      senderApp.SentCachePath(cfg),
+		ForceResend:        cfg.ForceResend,
+		DeviceKeyPath:      senderApp.DeviceKeyPath(),
+		KeyLifetime:        cfg.KeyLifetime(),
+		KeyGrace:           cfg.KeyGrace(),
+		SignatureAlgorithm: senderApp.SignatureAlgorithm(cfg),
+		RetryPolicy:        retryPolicy,
+		IgnoreService:      ignoreService,
+		SpeedProbeSize:     cfg.SpeedProbeSize(),
+		ChunkSize:          cfg.ChunkSize(),
+		IdleTimeout:        cfg.SessionIdleTimeout(),
+		Bonding:            cfg.ExperimentalBonding,
+		PeerSettings:       peers.LoadDefault(),
+		Webhooks:           webhook.FromConfig(cfg),
+		BandwidthSchedule:  bandwidthSchedule,
+		Battery:            senderApp.Battery(cfg),
+		Scope:              senderApp.ServiceScope(cfg),
+		PeerFilter:         receiverApp.PeerFilter(cfg),
+		LANOnly:            cfg.LANOnly,
+		Offline:            cfg.AirgapPeer != "",
+		PSK:                receiverApp.PSK(cfg),
+		Icon:               cfg.Icon,
+	})
+	sender := initSenderModel()
+	if cfg.Theme != "" {
+		if err := sender.themeManager.SetTheme(cfg.Theme); err != nil {
+			slog.Warn("Using the default theme", "error", err)
+		}
 	}
+	sender.statsPanel.SetRetryPolicy(retryPolicy.String())
+	sender.bandwidthSchedule = bandwidthSchedule
+	sender.fp.SetHashWorkers(cfg.HashWorkerCount())
+	sender.fp.SetEventBus(controller.Events())
+	return controller, sender
+}
+
+// newReceiver creates the receiver app and its model, announced as serviceName if it is set
+func newReceiver(cfg config.Config, adapter discovery.Adapter, port int, outputPath, serviceName string) (AppController, receiverModel) {
+	controller := receiverApp.NewAppWithOptions(port, outputPath, receiverApp.Options{
+		TrustStorePath:      receiverApp.TrustStorePath(),
+		TrustMaxAge:         cfg.TrustMaxAge(),
+		TrustReadOnly:       cfg.TrustReadOnly,
+		Lockdown:            cfg.Lockdown,
+		GuestMaxBytes:       cfg.GuestMaxBytes(),
+		AcceptTimeout:       cfg.AcceptTimeout(),
+		IdleTimeout:         cfg.SessionIdleTimeout(),
+		Registrar:           adapter,
+		ServiceName:         serviceName,
+		DoNotDisturbMessage: cfg.DoNotDisturbMessage,
+		Scopes:              receiverApp.ServiceScopes(cfg),
+		PeerFilter:          receiverApp.PeerFilter(cfg),
+		AuditLog:            receiverApp.AuditLog(cfg),
+		VerifyWrites:        cfg.VerifyWritesFraction(),
+		OutputTemplate:      receiverApp.LoadOutputTemplate(cfg),
+		ContentAddressed:    cfg.ContentAddressed,
+		Quota:               receiverApp.Quota(cfg),
+		LANOnly:             cfg.LANOnly,
+		Offline:             cfg.AirgapPeer != "",
+		PSK:                 receiverApp.PSK(cfg),
+		PeerSettings:        peers.LoadDefault(),
+		Webhooks:            webhook.FromConfig(cfg),
+		Fingerprint:         senderApp.DeviceFingerprint(cfg),
+		Icon:                cfg.Icon,
+		Recent:              recent.OpenDefault(),
+		Scanner:             scan.FromConfig(cfg),
+		Storage:             storage.FromConfig(cfg, outputPath),
+	})
+	return controller, initReceiverModel(port)
 }
 
 func (m model) Init() tea.Cmd {
-	if m.appController == nil {
+	if m.senderController == nil && m.receiverController == nil {
 		return tea.Quit
 	}
 
-	var initCmd tea.Cmd
+	var cmds []tea.Cmd
 	switch m.mode {
 	case Sender:
-		initCmd = m.initSender()
+		cmds = append(cmds, m.initSender(), m.runCmd(m.senderController, nil), watchThemes())
 	case Receiver:
-		initCmd = m.initReceiver()
+		cmds = append(cmds, m.initReceiver(), m.runCmd(m.receiverController, nil))
+	case Both:
+		cmds = append(cmds,
+			m.initSender(), m.runCmd(m.senderController, nil), watchThemes(),
+			m.initReceiver(), m.runCmd(m.receiverController, receiverFailed),
+		)
 	}
+	return tea.Batch(cmds...)
+}
 
-	runCmd := func() tea.Msg {
-		if err := m.appController.Run(m.ctx); err != nil {
+// runCmd runs controller until the program ends. A runtime error is reported with
+// onErr if it is set, otherwise as a fatal appevents.Error.
+func (m model) runCmd(controller AppController, onErr func(error) tea.Msg) tea.Cmd {
+	return func() tea.Msg {
+		if err := controller.Run(m.ctx); err != nil {
 			slog.Error("App runtime error", "error", err)
 
 			if errors.Is(err, context.Canceled) {
 				return appevents.AppFinishedMsg{}
 			}
+			if onErr != nil {
+				return onErr(err)
+			}
 			return appevents.Error{Err: err}
 		}
 		return appevents.AppFinishedMsg{}
 	}
-
-	return tea.Batch(initCmd, runCmd)
 }
 
 func (m model) View() string {
-	if m.err != nil {
+	if m.err != nil && m.mode != Both {
 		return style.ErrorStyle.Render(m.err.Error()) + "\n\nPress ctrl+c to quit."
 	}
 	var s string
@@ -95,6 +210,8 @@ func (m model) View() string {
 		s += m.senderView()
 	case Receiver:
 		s += m.receiverView()
+	case Both:
+		s += m.combinedView()
 	default:
 		return ""
 	}
@@ -104,6 +221,20 @@ func (m model) View() string {
 }
 
 func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	next, cmd := m.update(msg)
+	var status terminalStatus
+	switch n := next.(type) {
+	case model:
+		status = n.terminalStatus()
+	case *model:
+		status = n.terminalStatus()
+	default:
+		return next, cmd
+	}
+	return next, tea.Batch(cmd, m.terminal.update(status))
+}
+
+func (m model) update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	// case tea.KeyMsg:
 	// 	switch msg.Type {
@@ -120,6 +251,10 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 		return m, tea.Quit
 	case appevents.Error:
+		if m.mode == Both {
+			// Only the side that failed is affected, the other keeps running
+			break
+		}
 		m.err = msg.Err
 		return m, tea.Quit
 	case appevents.AppFinishedMsg:
@@ -127,6 +262,8 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	}
 
 	switch m.mode {
+	case Both:
+		return m.updateCombined(msg)
 	case Sender:
 		return m.updateSender(msg)
 	case Receiver:
@@ -0,0 +1,182 @@
+// Package webhook posts JSON notifications of transfer sessions to HTTP endpoints, so chat
+// services and home automation can follow transfers without a consumer of the control API.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/rescp17/lanFileSharer/internal/config"
+	"github.com/rescp17/lanFileSharer/internal/util"
+)
+
+// Events posted to webhooks
+const (
+	EventSessionStart    = "session.start"
+	EventSessionComplete = "session.complete"
+	EventSessionFail     = "session.fail"
+)
+
+// Roles of the app posting an event
+const (
+	RoleSender   = "sender"
+	RoleReceiver = "receiver"
+)
+
+// DefaultTimeout bounds the delivery of an event to one webhook
+const DefaultTimeout = 5 * time.Second
+
+// Event describes a transfer session starting or ending
+type Event struct {
+	Event       string    `json:"event"`
+	Role        string    `json:"role"`
+	Time        time.Time `json:"time"`
+	Peer        string    `json:"peer,omitempty"` // The receiver sent to, or the sender received from
+	Files       int       `json:"files"`
+	FailedFiles int       `json:"failed_files,omitempty"`
+	Bytes       int64     `json:"bytes"`
+	Error       string    `json:"error,omitempty"`
+}
+
+// Summary describes the event in a sentence
+func (e Event) Summary() string {
+	peer := e.Peer
+	if peer == "" {
+		peer = "a peer"
+	}
+	files := fmt.Sprintf("%d files (%s)", e.Files, util.FormatSize(e.Bytes))
+	verb, preposition := "Sending", "to"
+	if e.Role == RoleReceiver {
+		verb, preposition = "Receiving", "from"
+	}
+
+	switch e.Event {
+	case EventSessionStart:
+		return fmt.Sprintf("%s %s %s %s", verb, files, preposition, peer)
+	case EventSessionComplete:
+		return fmt.Sprintf("%s %s %s %s completed", verb, files, preposition, peer)
+	default:
+		summary := fmt.Sprintf("%s %s %s %s failed", verb, files, preposition, peer)
+		if e.FailedFiles > 0 {
+			summary += fmt.Sprintf(", %d of %d files failed", e.FailedFiles, e.Files)
+		}
+		if e.Error != "" {
+			summary += ": " + e.Error
+		}
+		return summary
+	}
+}
+
+// payload is the body posted for an event. The summary goes in "text" and "content" as well,
+// which Slack and Discord incoming webhooks post as the message.
+type payload struct {
+	Event
+	Text    string `json:"text"`
+	Content string `json:"content"`
+}
+
+// Notifier posts events to a list of webhook URLs. A nil Notifier posts nothing.
+type Notifier struct {
+	urls    []string
+	client  *http.Client
+	timeout time.Duration
+}
+
+// New creates a notifier posting to urls, which must be absolute http or https URLs.
+// It returns nil if urls is empty.
+func New(urls []string) (*Notifier, error) {
+	for _, raw := range urls {
+		u, err := url.Parse(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid webhook URL %q: %w", raw, err)
+		}
+		if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("invalid webhook URL %q: must be an http or https URL", raw)
+		}
+	}
+	if len(urls) == 0 {
+		return nil, nil
+	}
+	return &Notifier{urls: append([]string(nil), urls...), client: &http.Client{}, timeout: DefaultTimeout}, nil
+}
+
+// FromConfig returns a notifier for the webhooks in cfg, or nil if there are none or they
+// are invalid, which is logged
+func FromConfig(cfg config.Config) *Notifier {
+	notifier, err := New(cfg.Webhooks)
+	if err != nil {
+		slog.Warn("Invalid webhooks in config, sending no notifications", "error", err)
+		return nil
+	}
+	return notifier
+}
+
+// Notify posts event to every webhook at once and waits for them, each for up to the
+// delivery timeout. Failed deliveries are logged, never returned, so a webhook that is down
+// does not affect transfers.
+func (n *Notifier) Notify(ctx context.Context, event Event) {
+	if n == nil {
+		return
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	summary := event.Summary()
+	body, err := json.Marshal(payload{Event: event, Text: summary, Content: summary})
+	if err != nil {
+		slog.Warn("Failed to encode webhook event", "event", event.Event, "error", err)
+		return
+	}
+
+	var wg sync.WaitGroup
+	for _, u := range n.urls {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := n.post(ctx, u, body); err != nil {
+				slog.Warn("Webhook delivery failed", "event", event.Event, "url", redact(u), "error", err)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// post delivers body to the webhook at u
+func (n *Notifier) post(ctx context.Context, u string, body []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, n.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook answered %s", resp.Status)
+	}
+	return nil
+}
+
+// redact drops the path and query of a webhook URL for logs, as chat webhooks carry their
+// secret token there
+func redact(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "invalid URL"
+	}
+	return u.Scheme + "://" + u.Host
+}
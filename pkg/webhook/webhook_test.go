@@ -0,0 +1,111 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotifier_PostsEventToEveryWebhook(t *testing.T) {
+	var mu sync.Mutex
+	var bodies []map[string]any
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		var body map[string]any
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		mu.Lock()
+		bodies = append(bodies, body)
+		mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	})
+	first := httptest.NewServer(handler)
+	defer first.Close()
+	second := httptest.NewServer(handler)
+	defer second.Close()
+
+	notifier, err := New([]string{first.URL + "/hook", second.URL})
+	require.NoError(t, err)
+	notifier.Notify(context.Background(), Event{
+		Event: EventSessionFail,
+		Role:  RoleSender,
+		Time:  time.Date(2024, 5, 15, 9, 0, 0, 0, time.UTC),
+		Peer:  "laptop",
+		Files: 3,
+		Bytes: 2048,
+		Error: "connection lost",
+	})
+
+	require.Len(t, bodies, 2, "Notify waits for every delivery")
+	for _, body := range bodies {
+		assert.Equal(t, "session.fail", body["event"])
+		assert.Equal(t, "sender", body["role"])
+		assert.Equal(t, "laptop", body["peer"])
+		assert.Equal(t, float64(3), body["files"])
+		assert.Equal(t, "2024-05-15T09:00:00Z", body["time"])
+		assert.Equal(t, "Sending 3 files (2 KB) to laptop failed: connection lost", body["text"])
+		assert.Equal(t, body["text"], body["content"])
+	}
+}
+
+func TestNotifier_IgnoresFailingWebhooks(t *testing.T) {
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+	release := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer slow.Close()
+	defer close(release)
+
+	notifier, err := New([]string{failing.URL, slow.URL})
+	require.NoError(t, err)
+	notifier.timeout = 50 * time.Millisecond
+
+	done := make(chan struct{})
+	go func() {
+		notifier.Notify(context.Background(), Event{Event: EventSessionStart, Role: RoleReceiver})
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Notify did not give up on a slow webhook")
+	}
+}
+
+func TestNew(t *testing.T) {
+	notifier, err := New(nil)
+	require.NoError(t, err)
+	assert.Nil(t, notifier)
+	notifier.Notify(context.Background(), Event{Event: EventSessionStart})
+
+	_, err = New([]string{"ftp://example.com/hook"})
+	assert.Error(t, err)
+	_, err = New([]string{"/hook"})
+	assert.Error(t, err)
+}
+
+func TestEvent_Summary(t *testing.T) {
+	event := Event{Event: EventSessionStart, Role: RoleReceiver, Files: 2, Bytes: 1024}
+	assert.Equal(t, "Receiving 2 files (1 KB) from a peer", event.Summary())
+
+	event.Event, event.Peer = EventSessionComplete, "desktop"
+	assert.Equal(t, "Receiving 2 files (1 KB) from desktop completed", event.Summary())
+
+	event.Event, event.FailedFiles = EventSessionFail, 1
+	assert.Equal(t, "Receiving 2 files (1 KB) from desktop failed, 1 of 2 files failed", event.Summary())
+}
+
+func TestRedact(t *testing.T) {
+	assert.Equal(t, "https://hooks.slack.com", redact("https://hooks.slack.com/services/T000/B000/secret"))
+}
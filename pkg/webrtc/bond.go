@@ -0,0 +1,238 @@
+package webrtc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pion/webrtc/v4"
+	"github.com/rescp17/lanFileSharer/pkg/fileInfo"
+	"github.com/rescp17/lanFileSharer/pkg/transfer"
+)
+
+const (
+	// bondLabel prefixes the channels of bond connections, which the receiver takes for
+	// secondary file transfer channels
+	bondLabel = "file-transfer-bond"
+	// bondMaxBuffered is how much a path of a bonded session may have queued before it takes
+	// another chunk, so that faster paths take more of them
+	bondMaxBuffered = 4 << 20
+)
+
+// ErrBondUnsupported is returned by AddBondPath when the receiver does not take bond connections
+var ErrBondUnsupported = errors.New("receiver does not take bond connections")
+
+// BondExchange delivers a complete bond offer with its signature to the receiver and returns its
+// complete answer
+type BondExchange func(ctx context.Context, offer webrtc.SessionDescription, signature []byte) (*webrtc.SessionDescription, error)
+
+// bondReporter is implemented by signalers that learn from the answer whether the receiver takes bond connections
+type bondReporter interface {
+	BondSupported() bool
+}
+
+// PathStats reports what one path of a bonded session carried of the striped files
+type PathStats struct {
+	Path
+	Bytes int64
+}
+
+// pathChannel is the channel one path of a bonded session sends on
+type pathChannel struct {
+	pc      *webrtc.PeerConnection // Connection of the path, whose selected pair describes it
+	dc      *webrtc.DataChannel
+	drained chan struct{}
+	bytes   atomic.Int64 // Sent bytes of striped files
+}
+
+func newPathChannel(pc *webrtc.PeerConnection, dc *webrtc.DataChannel) *pathChannel {
+	p := &pathChannel{pc: pc, dc: dc, drained: make(chan struct{}, 1)}
+	dc.SetBufferedAmountLowThreshold(bondMaxBuffered / 2)
+	dc.OnBufferedAmountLow(func() {
+		select {
+		case p.drained <- struct{}{}:
+		default:
+		}
+	})
+	return p
+}
+
+// wait blocks until the path sent most of what it has queued
+func (p *pathChannel) wait(ctx context.Context) error {
+	for p.dc.BufferedAmount() > bondMaxBuffered {
+		select {
+		case <-p.drained:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+func (p *pathChannel) stats() PathStats {
+	stats := PathStats{Bytes: p.bytes.Load()}
+	if pair, err := p.pc.SCTP().Transport().ICETransport().GetSelectedCandidatePair(); err == nil && pair != nil {
+		stats.Path = Path{Local: pair.Local.Address, Remote: pair.Remote.Address}
+	}
+	return stats
+}
+
+// AddBondPath opens an extra connection to the receiver through api, which is meant to gather
+// candidates on another interface than the connection's own, exchanging complete descriptions
+// with exchange. The offer is signed with the key of the offered structure, which the receiver
+// takes instead of asking the user again. Large files sent afterwards are striped over the
+// connection's own path and every bond path, each taking chunks as fast as it sends them. It
+// needs an established connection and returns ErrBondUnsupported if the receiver does not take
+// bond connections.
+func (c *SenderConn) AddBondPath(ctx context.Context, api *WebrtcAPI, exchange BondExchange) error {
+	if !c.bond {
+		return ErrBondUnsupported
+	}
+	if c.offerSigner == nil || c.resumeToken == "" {
+		return errors.New("bond paths need an established session with a resume token")
+	}
+	signed := func(ctx context.Context, offer webrtc.SessionDescription) (*webrtc.SessionDescription, error) {
+		signature, err := c.offerSigner.SignBondOffer(c.resumeToken, offer.SDP)
+		if err != nil {
+			return nil, err
+		}
+		return exchange(ctx, offer, signature)
+	}
+	c.bondMu.Lock()
+	label := fmt.Sprintf("%s-%d", bondLabel, len(c.bonds)+1)
+	c.bondMu.Unlock()
+
+	conn, err := api.dialChannel(ctx, Config{}, label, &webrtc.DataChannelInit{Ordered: &[]bool{true}[0]}, signed)
+	if err != nil {
+		return fmt.Errorf("failed to open bond path: %w", err)
+	}
+	slog.Info("Bond path opened", "label", label, "path", conn.path)
+
+	c.bondMu.Lock()
+	defer c.bondMu.Unlock()
+	c.bonds = append(c.bonds, newPathChannel(conn.pc, conn.dc))
+	return nil
+}
+
+// PathStats returns what each path of a bonded session carried of the striped files, the
+// connection's own path first; nil while no file is striped over bond paths
+func (c *SenderConn) PathStats() []PathStats {
+	c.bondMu.Lock()
+	defer c.bondMu.Unlock()
+	if c.paths == nil {
+		return nil
+	}
+	stats := make([]PathStats, 0, len(c.paths))
+	for _, path := range c.paths {
+		stats = append(stats, path.stats())
+	}
+	return stats
+}
+
+// startBond sets up the paths large files are striped over, the connection's own path on
+// primary followed by the bond paths, and returns them; nil without bond paths
+func (c *SenderConn) startBond(primary *webrtc.DataChannel) []*pathChannel {
+	c.bondMu.Lock()
+	defer c.bondMu.Unlock()
+	if len(c.bonds) == 0 {
+		return nil
+	}
+	if c.paths == nil {
+		c.paths = append([]*pathChannel{newPathChannel(c.Peer(), primary)}, c.bonds...)
+	}
+	for _, path := range c.bonds {
+		// File ACKs come back on the channel the last chunk of a file went out on
+		c.handleReplies(path.dc)
+	}
+	return c.paths
+}
+
+// closeBonds closes the connections of the bond paths
+func (c *SenderConn) closeBonds() {
+	c.bondMu.Lock()
+	defer c.bondMu.Unlock()
+	for _, path := range c.bonds {
+		if err := path.pc.Close(); err != nil {
+			slog.Warn("Failed to close bond connection", "error", err)
+		}
+	}
+	c.bonds = nil
+	c.paths = nil
+}
+
+// Close closes the bond connections and the connection itself
+func (c *SenderConn) Close() error {
+	c.closeBonds()
+	return c.Connection.Close()
+}
+
+// transferFileChunksBonded sends a file over paths, each taking the next chunk once it sent
+// most of what it has queued. The receiver writes chunks at their offsets, so order doesn't matter.
+func (c *SenderConn) transferFileChunksBonded(ctx context.Context, paths []*pathChannel, utm *transfer.UnifiedTransferManager, fileNode *fileInfo.FileNode, chunker *transfer.Chunker, serviceID string) error {
+	// Reading overlaps sending, so it all counts as transferring
+	defer func(start time.Time) {
+		recordPhase(utm, fileNode.Path, transfer.PhaseTransferring, time.Since(start))
+	}(time.Now())
+	var mu sync.Mutex
+	var totalBytesSent int64
+	addProgress := func(n int64, report bool) {
+		mu.Lock()
+		defer mu.Unlock()
+		totalBytesSent += n
+		if !report {
+			return
+		}
+		if err := utm.UpdateProgress(fileNode.Path, totalBytesSent); err != nil {
+			slog.Warn("Failed to update progress", "file", fileNode.Path, "error", err)
+		}
+	}
+
+	return transfer.ReadChunksShared(ctx, chunker, len(paths), func(worker int, chunk *transfer.Chunk) error {
+		if err := awaitFile(ctx, utm, fileNode.Path); err != nil {
+			return err
+		}
+
+		// Skip chunks the receiver persisted before the interruption, except the last
+		if !chunk.IsLast && c.resumeState.HasChunk(fileNode.Path, chunk.SequenceNo) {
+			addProgress(int64(len(chunk.Data)), false)
+			return nil
+		}
+
+		path := paths[worker]
+		if err := path.wait(ctx); err != nil {
+			return err
+		}
+		if err := c.limiter.Wait(ctx, len(chunk.Data)); err != nil {
+			return err
+		}
+		if err := c.sendMessage(path.dc, newChunkMessage(serviceID, fileNode, chunk)); err != nil {
+			return fmt.Errorf("failed to send chunk %d: %w", chunk.SequenceNo, err)
+		}
+		path.bytes.Add(int64(len(chunk.Data)))
+		utm.RecordChunk(fileNode.Path, chunk.SequenceNo)
+		addProgress(int64(len(chunk.Data)), true)
+		return nil
+	})
+}
+
+// AnswerBond answers the offer of a bond connection with a complete description and hands its
+// data channels to onChannel. The caller closes the returned connection once the session ends.
+func (a *WebrtcAPI) AnswerBond(ctx context.Context, offer webrtc.SessionDescription, onChannel func(*webrtc.DataChannel)) (*webrtc.PeerConnection, *webrtc.SessionDescription, error) {
+	pc, err := a.createPeerConnection(Config{})
+	if err != nil {
+		return nil, nil, err
+	}
+	pc.OnDataChannel(onChannel)
+	answer, err := answerEcho(ctx, pc, lanDescription(offer, a.lanOnly))
+	if err != nil {
+		if closeErr := pc.Close(); closeErr != nil {
+			slog.Warn("Failed to close bond connection", "error", closeErr)
+		}
+		return nil, nil, err
+	}
+	return pc, answer, nil
+}
@@ -0,0 +1,36 @@
+package webrtc
+
+import (
+	"log/slog"
+
+	"github.com/rescp17/lanFileSharer/pkg/transfer"
+)
+
+// SetChaos makes SendFiles drop, delay, reorder and corrupt chunks as cfg says, to test
+// recovery over a faulty link. It is meant for testing only.
+func (s *SenderConn) SetChaos(cfg transfer.ChaosConfig) {
+	if !cfg.Enabled() {
+		s.faults = nil
+		return
+	}
+	slog.Warn("Injecting faults into sent chunks", "chaos", cfg.String())
+	s.faults = transfer.NewFaultInjector(cfg)
+}
+
+// flushFaults sends the chunk the fault injector held back, once a file's chunks are all sent
+func (c *SenderConn) flushFaults() error {
+	if c.faults == nil {
+		return nil
+	}
+	return c.faults.Flush()
+}
+
+// logFaults logs how many faults were injected during SendFiles
+func (c *SenderConn) logFaults() {
+	if c.faults == nil {
+		return
+	}
+	stats := c.faults.Stats()
+	slog.Info("Injected faults", "chunks", stats.Chunks, "dropped", stats.Dropped, "delayed", stats.Delayed,
+		"reordered", stats.Reordered, "corrupted", stats.Corrupted)
+}
@@ -2,16 +2,20 @@ package webrtc
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"log/slog"
+	"net"
+	"slices"
 	"sync"
 	"time"
 
 	"github.com/pion/ice/v4"
 	"github.com/pion/webrtc/v4"
 	"github.com/rescp17/lanFileSharer/api"
+	"github.com/rescp17/lanFileSharer/internal/util"
 	"github.com/rescp17/lanFileSharer/pkg/crypto"
 	"github.com/rescp17/lanFileSharer/pkg/fileInfo"
 	"github.com/rescp17/lanFileSharer/pkg/transfer"
@@ -27,6 +31,24 @@ type SenderConnection interface {
 	Establish(ctx context.Context, fileNodes *transfer.FileStructureManager) error
 	CreateDataChannel(label string, options *webrtc.DataChannelInit) (*webrtc.DataChannel, error)
 	SendFiles(ctx context.Context, files []fileInfo.FileNode, serviceID string) error
+	SetResumeToken(token string)
+	SetResumeState(state *transfer.ResumeState)
+	SetSigner(signer *crypto.FileStructureSigner)
+	SetRetryPolicy(policy *transfer.RetryPolicy)
+	SetChunkSize(size int32)
+	SetParallelStreams(streams int)
+	SetRateLimiter(limiter *transfer.RateLimiter)
+	SetChaos(cfg transfer.ChaosConfig)
+	SetRelayTo(name string)
+	SetPullToken(token string)
+	SetGuestToken(token string)
+	SetIcon(icon string)
+	SetIdleTimeout(timeout time.Duration)
+	OnSelectedPath(f func(Path))
+	AddBondPath(ctx context.Context, api *WebrtcAPI, exchange BondExchange) error
+	PathStats() []PathStats
+	SendStructureUpdate(changes []transfer.StructureChange) error
+	ProbeSpeed(ctx context.Context, size int64) (*SpeedProbeResult, error)
 }
 
 type ReceiverConnection interface {
@@ -36,11 +58,19 @@ type ReceiverConnection interface {
 
 const (
 	MTU uint = 1400
+
+	// fileTransferLabel labels the data channel carrying files; additional
+	// channels of a parallel transfer append a stream number
+	fileTransferLabel = "file-transfer"
 )
 
 // Connection wraps a single WebRTC peer connection and its state.
 type Connection struct {
 	peerConnection *webrtc.PeerConnection
+	lanOnly        bool // Remote candidates outside the LAN are dropped
+
+	pathMu sync.Mutex
+	onPath func(Path) // Called with every path ICE selects
 }
 
 // Peer returns the underlying webrtc.PeerConnection object.
@@ -58,9 +88,74 @@ func (c *Connection) Close() error {
 
 type SenderConn struct {
 	*Connection
-	signaler         Signaler // Used to send signals to the remote peer
-	serializer       transfer.MessageSerializer
-	progressSignaler ProgressSignaler // Optional progress signaler
+	signaler          Signaler // Used to send signals to the remote peer
+	serializer        transfer.MessageSerializer
+	progressSignaler  ProgressSignaler            // Optional progress signaler
+	resumeToken       string                      // Share token sent with the offer
+	relayTo           string                      // Receiver a relay is asked to forward the files to
+	pullToken         string                      // Token of the receiver's pull the offer answers
+	guestToken        string                      // Guest pass of the receiver the offer gives
+	icon              string                      // Emoji or glyph the receiver shows this device with
+	parallelStreams   int                         // Streams large files are striped over, zero for the default
+	resumeState       *transfer.ResumeState       // Chunks the receiver already has
+	signer            *crypto.FileStructureSigner // Device key signer; nil signs with an ephemeral key
+	fileAcks          bool                        // Receiver acknowledges every verified file
+	speedProbe        bool                        // Receiver confirms speed probes
+	hardLinks         bool                        // Receiver recreates hard links from FileLink messages
+	keepalive         bool                        // Receiver sends keepalives
+	controlChannel    bool                        // Receiver takes a control channel, see ControlLabel
+	idleTimeout       time.Duration               // Silence of the receiver that ends the session; zero never does
+	monitor           *transfer.KeepaliveMonitor  // Keepalives of the active file transfer, nil without them
+	stored            map[string]bool             // Checksums of content the receiver already stores
+	acks              *fileAckTracker             // ACKs awaited by the active file transfer
+	retryPolicy       *transfer.RetryPolicy       // Retry policy of failed files; nil uses the default
+	chunkSize         int32                       // Size files are sent in; zero uses the default
+	limiter           *transfer.RateLimiter       // Paces sent chunks; nil sends as fast as possible
+	parallelThreshold int64                       // Minimum size of files striped over several channels
+	faults            *transfer.FaultInjector     // Faults injected into sent chunks; nil sends them untouched
+	bond              bool                        // Receiver takes bond connections, see AddBondPath
+
+	bondMu sync.Mutex
+	bonds  []*pathChannel // Extra connections to the receiver, see AddBondPath
+	paths  []*pathChannel // Paths large files are striped over, nil without bonds
+
+	// Structure updates after the offer, signed as a chain rooted at the offered structure
+	deltaSigner   *crypto.StructureDeltaSigner
+	offerSigner   *crypto.FileStructureSigner // Signed the offered structure, and signs bond offers
+	dataChannel   *webrtc.DataChannel         // Open file transfer channel, nil outside SendFiles
+	offerChannel  *webrtc.DataChannel         // File transfer channel created with the offer, until SendFiles takes it
+	control       *webrtc.DataChannel         // Open control channel, nil outside SendFiles or without one
+	dataChannelMu sync.Mutex
+}
+
+// resumeTokenSetter is implemented by signalers that can carry a share token with the offer
+type resumeTokenSetter interface {
+	SetResumeToken(token string)
+}
+
+// relayTargetSetter is implemented by signalers that can ask a relay to forward the files
+type relayTargetSetter interface {
+	SetRelayTo(name string)
+}
+
+// pullTokenSetter is implemented by signalers that can answer a pull of the receiver
+type pullTokenSetter interface {
+	SetPullToken(token string)
+}
+
+// guestTokenSetter is implemented by signalers that can give the receiver a guest pass
+type guestTokenSetter interface {
+	SetGuestToken(token string)
+}
+
+// iconSetter is implemented by signalers that can tell the receiver the sender's icon
+type iconSetter interface {
+	SetIcon(icon string)
+}
+
+// fileAckReporter is implemented by signalers that learn from the answer whether the receiver sends file ACKs
+type fileAckReporter interface {
+	FileAcksSupported() bool
 }
 
 // SetSignaler allows setting a custom signaler (mainly for testing)
@@ -68,12 +163,91 @@ func (s *SenderConn) SetSignaler(signaler Signaler) {
 	s.signaler = signaler
 }
 
+// SetResumeToken sets the share token identifying this session to the receiver
+func (s *SenderConn) SetResumeToken(token string) {
+	s.resumeToken = token
+}
+
+// SetRelayTo makes the offer ask the receiver, a relay, to store the files and forward them
+// to the named receiver
+func (s *SenderConn) SetRelayTo(name string) {
+	s.relayTo = name
+}
+
+// SetPullToken makes the offer answer the receiver's pull with token, which it accepts
+// without asking
+func (s *SenderConn) SetPullToken(token string) {
+	s.pullToken = token
+}
+
+// SetGuestToken makes the offer give the receiver a guest pass it issued, which accepts
+// one transfer without asking
+func (s *SenderConn) SetGuestToken(token string) {
+	s.guestToken = token
+}
+
+// SetIcon makes the offer carry the emoji or glyph the receiver shows this device with
+func (s *SenderConn) SetIcon(icon string) {
+	s.icon = icon
+}
+
+// SetResumeState makes SendFiles skip chunks the receiver already has
+func (s *SenderConn) SetResumeState(state *transfer.ResumeState) {
+	s.resumeState = state
+}
+
+// SetSigner makes Establish sign the offer with signer instead of an ephemeral key
+func (s *SenderConn) SetSigner(signer *crypto.FileStructureSigner) {
+	s.signer = signer
+}
+
+// SetRetryPolicy sets how SendFiles retries failed files
+func (s *SenderConn) SetRetryPolicy(policy *transfer.RetryPolicy) {
+	s.retryPolicy = policy
+}
+
+// SetChunkSize sets the size of the chunks SendFiles sends files in. A size outside
+// transfer.MinChunkSize and transfer.MaxChunkSize is ignored.
+func (s *SenderConn) SetChunkSize(size int32) {
+	if size < transfer.MinChunkSize || size > transfer.MaxChunkSize {
+		slog.Warn("Ignoring chunk size outside the supported range", "size", size, "min", transfer.MinChunkSize, "max", transfer.MaxChunkSize)
+		return
+	}
+	s.chunkSize = size
+}
+
+// SetParallelStreams sets how many channels SendFiles stripes large files over, one sending
+// every file on a single channel
+func (s *SenderConn) SetParallelStreams(streams int) {
+	s.parallelStreams = streams
+}
+
+// SetRateLimiter paces the chunk data SendFiles sends with limiter; nil removes the limit
+func (s *SenderConn) SetRateLimiter(limiter *transfer.RateLimiter) {
+	s.limiter = limiter
+}
+
 type ReceiverConn struct {
 	*Connection
 }
 
 type WebrtcAPI struct {
-	api *webrtc.API
+	api     *webrtc.API
+	lanOnly bool
+	offline bool
+}
+
+// APIOptions configures a WebrtcAPI
+type APIOptions struct {
+	// LANOnly keeps connections on the local network: no STUN or TURN servers are used,
+	// only private and link-local addresses are gathered and accepted, and a connection
+	// is closed if it selects a path outside them
+	LANOnly bool
+	// Offline uses no STUN or TURN servers and no multicast DNS, so candidates carry plain
+	// addresses; for networks where neither exists, such as two machines on one cable
+	Offline bool
+	// Interfaces limits the candidates gathered to these network interfaces; empty gathers on all
+	Interfaces []string
 }
 
 // Config holds the configuration for creating a new Connection.
@@ -82,13 +256,34 @@ type Config struct {
 }
 
 func NewWebrtcAPI() *WebrtcAPI {
+	return NewWebrtcAPIWithOptions(APIOptions{})
+}
+
+// NewWebrtcAPIWithOptions creates a WebrtcAPI configured by options
+func NewWebrtcAPIWithOptions(options APIOptions) *WebrtcAPI {
 	settings := webrtc.SettingEngine{}
 	settings.SetICEMulticastDNSMode(ice.MulticastDNSModeQueryAndGather)
+	if options.Offline {
+		settings.SetICEMulticastDNSMode(ice.MulticastDNSModeDisabled)
+	}
 	settings.SetReceiveMTU(MTU)
+	if options.LANOnly {
+		settings.SetIPFilter(func(ip net.IP) bool {
+			return util.IsLANAddress(ip) && !ip.IsLoopback()
+		})
+	}
+
+	if len(options.Interfaces) > 0 {
+		settings.SetInterfaceFilter(func(name string) bool {
+			return slices.Contains(options.Interfaces, name)
+		})
+	}
 
 	api := webrtc.NewAPI(webrtc.WithSettingEngine(settings))
 	return &WebrtcAPI{
-		api: api,
+		api:     api,
+		lanOnly: options.LANOnly,
+		offline: options.Offline,
 	}
 }
 
@@ -96,7 +291,10 @@ func (a *WebrtcAPI) createPeerConnection(config Config) (*webrtc.PeerConnection,
 	peerConnectionConfig := webrtc.Configuration{
 		ICEServers: config.ICEServers,
 	}
-	if len(config.ICEServers) == 0 {
+	if a.lanOnly || a.offline {
+		// No server is asked for addresses, so nothing about the transfer leaves the LAN
+		peerConnectionConfig.ICEServers = nil
+	} else if len(config.ICEServers) == 0 {
 		peerConnectionConfig.ICEServers = []webrtc.ICEServer{
 			{URLs: []string{"stun:stun.l.google.com:19302"}},
 		}
@@ -106,10 +304,17 @@ func (a *WebrtcAPI) createPeerConnection(config Config) (*webrtc.PeerConnection,
 		// Just wrap and return. Let the caller log.
 		return nil, fmt.Errorf("failed to create new peer connection: %w", err)
 	}
+	watchSelectedPath(pc, a.lanOnly, nil)
 	return pc, nil
 }
 
 func (c *Connection) AddICECandidate(candidate webrtc.ICECandidateInit) error {
+	if c.lanOnly {
+		if err := checkLANCandidate(candidate.Candidate); err != nil {
+			slog.Warn("Dropping remote candidate in LAN-only mode", "error", err)
+			return nil
+		}
+	}
 	return c.peerConnection.AddICECandidate(candidate)
 }
 
@@ -125,10 +330,12 @@ func (a *WebrtcAPI) NewSenderConnectionWithProgress(transferCtx context.Context,
 	conn := &SenderConn{
 		Connection: &Connection{
 			peerConnection: pc,
+			lanOnly:        a.lanOnly,
 		},
 		serializer:       transfer.NewJSONSerializer(),
 		progressSignaler: progressSignaler,
 	}
+	watchSelectedPath(pc, a.lanOnly, conn.reportPath)
 
 	signaler := api.NewAPISignaler(apiClient, receiverURL, conn.AddICECandidate)
 	conn.signaler = signaler
@@ -152,11 +359,14 @@ func (a *WebrtcAPI) NewReceiverConnection(config Config) (ReceiverConnection, er
 		return nil, err
 	}
 
-	return &ReceiverConn{
+	conn := &ReceiverConn{
 		Connection: &Connection{
 			peerConnection: pc,
+			lanOnly:        a.lanOnly,
 		},
-	}, nil
+	}
+	watchSelectedPath(pc, a.lanOnly, conn.reportPath)
+	return conn, nil
 }
 
 func (c *SenderConn) Establish(ctx context.Context, fsm *transfer.FileStructureManager) error {
@@ -165,6 +375,16 @@ func (c *SenderConn) Establish(ctx context.Context, fsm *transfer.FileStructureM
 		return err
 	}
 
+	// Without a data channel the offer has no application section and no ICE credentials,
+	// so the first file transfer channel is created with it
+	offerChannel, err := c.CreateDataChannel(fileTransferLabel, transferChannelInit())
+	if err != nil {
+		return fmt.Errorf("failed to create data channel: %w", err)
+	}
+	c.dataChannelMu.Lock()
+	c.offerChannel = offerChannel
+	c.dataChannelMu.Unlock()
+
 	offer, err := c.Peer().CreateOffer(nil)
 	if err != nil {
 		return fmt.Errorf("failed to create offer: %w", err)
@@ -174,15 +394,48 @@ func (c *SenderConn) Establish(ctx context.Context, fsm *transfer.FileStructureM
 		return fmt.Errorf("failed to set local description: %w", err)
 	}
 
-	fileStructureSigner, err := crypto.NewFileStructureSigner()
-	if err != nil {
-		return fmt.Errorf("failed to create file structure signer: %w", err)
+	fileStructureSigner := c.signer
+	if fileStructureSigner == nil {
+		fileStructureSigner, err = crypto.NewFileStructureSignerWithAlgorithm(crypto.DefaultSignatureAlgorithm)
+		if err != nil {
+			return fmt.Errorf("failed to create file structure signer: %w", err)
+		}
 	}
 
-	signed, err := fileStructureSigner.SignFileStructureManager(fsm)
+	signed, err := fileStructureSigner.SignFileStructureManagerContext(ctx, fsm)
 	if err != nil {
 		return fmt.Errorf("failed to sign file structure: %w", err)
 	}
+	c.deltaSigner = crypto.NewStructureDeltaSigner(fileStructureSigner, signed)
+	c.offerSigner = fileStructureSigner
+
+	if setter, ok := c.signaler.(resumeTokenSetter); ok && c.resumeToken != "" {
+		setter.SetResumeToken(c.resumeToken)
+	}
+	if setter, ok := c.signaler.(iconSetter); ok && c.icon != "" {
+		setter.SetIcon(c.icon)
+	}
+	if c.relayTo != "" {
+		setter, ok := c.signaler.(relayTargetSetter)
+		if !ok {
+			return errors.New("signaler cannot ask the receiver to relay the files")
+		}
+		setter.SetRelayTo(c.relayTo)
+	}
+	if c.pullToken != "" {
+		setter, ok := c.signaler.(pullTokenSetter)
+		if !ok {
+			return errors.New("signaler cannot answer a pull of the receiver")
+		}
+		setter.SetPullToken(c.pullToken)
+	}
+	if c.guestToken != "" {
+		setter, ok := c.signaler.(guestTokenSetter)
+		if !ok {
+			return errors.New("signaler cannot give the receiver a guest pass")
+		}
+		setter.SetGuestToken(c.guestToken)
+	}
 
 	if err := c.signaler.SendOffer(ctx, offer, signed); err != nil {
 		return fmt.Errorf("failed to send offer via signaler: %w", err)
@@ -193,15 +446,46 @@ func (c *SenderConn) Establish(ctx context.Context, fsm *transfer.FileStructureM
 		return fmt.Errorf("failed to wait for answer: %w", err)
 	}
 
-	if err := c.Peer().SetRemoteDescription(*answer); err != nil {
+	if err := c.Peer().SetRemoteDescription(lanDescription(*answer, c.lanOnly)); err != nil {
 		return fmt.Errorf("failed to set remote description for answer: %w", err)
 	}
 
+	if reporter, ok := c.signaler.(fileAckReporter); ok {
+		c.fileAcks = reporter.FileAcksSupported()
+	}
+	if !c.fileAcks {
+		slog.Info("Receiver does not acknowledge files, completing them once sent")
+	}
+	if reporter, ok := c.signaler.(speedProbeReporter); ok {
+		c.speedProbe = reporter.SpeedProbeSupported()
+	}
+	if reporter, ok := c.signaler.(hardLinkReporter); ok {
+		c.hardLinks = reporter.HardLinksSupported()
+	}
+	if reporter, ok := c.signaler.(keepaliveReporter); ok {
+		c.keepalive = reporter.KeepaliveSupported()
+	}
+	if reporter, ok := c.signaler.(controlChannelReporter); ok {
+		c.controlChannel = reporter.ControlChannelSupported()
+	}
+	if reporter, ok := c.signaler.(bondReporter); ok {
+		c.bond = reporter.BondSupported()
+	}
+	if reporter, ok := c.signaler.(storedContentReporter); ok {
+		c.stored = make(map[string]bool)
+		for _, checksum := range reporter.StoredContent() {
+			c.stored[checksum] = true
+		}
+		if len(c.stored) > 0 {
+			slog.Info("Receiver already stores some of the files, linking them instead of sending", "files", len(c.stored))
+		}
+	}
+
 	return nil
 }
 
 func (c *ReceiverConn) HandleOfferAndCreateAnswer(offer webrtc.SessionDescription) (*webrtc.SessionDescription, error) {
-	if err := c.Peer().SetRemoteDescription(offer); err != nil {
+	if err := c.Peer().SetRemoteDescription(lanDescription(offer, c.lanOnly)); err != nil {
 		return nil, fmt.Errorf("failed to set remote description: %w", err)
 	}
 
@@ -222,75 +506,245 @@ func (c *SenderConn) CreateDataChannel(label string, options *webrtc.DataChannel
 
 func (c *SenderConn) SendFiles(ctx context.Context, files []fileInfo.FileNode, serviceID string) error {
 	// Create unified transfer manager
-	utm := transfer.NewUnifiedTransferManager(serviceID)
+	transferConfig := transfer.DefaultTransferConfig()
+	if c.retryPolicy != nil {
+		transferConfig.DefaultRetryPolicy = c.retryPolicy
+	}
+	if c.chunkSize > 0 {
+		transferConfig.ChunkSize = c.chunkSize
+	}
+	utm := transfer.NewUnifiedTransferManagerWithConfig(serviceID, transferConfig)
 	defer func() {
 		if err := utm.Close(); err != nil {
 			slog.Error("Failed to close unified transfer manager", "error", err)
 		}
 	}()
 
-	// Add progress listener if progress signaler is available
+	// Hand the transfer manager to the progress signaler, which subscribes to its status
 	if c.progressSignaler != nil {
-		// Set the transfer manager reference in the progress signaler
 		c.progressSignaler.SetTransferManager(utm)
-
-		progressListener := NewProgressListener(c.progressSignaler)
-		utm.AddStatusListener(progressListener)
 	}
 
 	// Add files to the transfer manager
 	for _, file := range files {
-		if err := utm.AddFile(&file); err != nil {
+		if err := utm.AddFileContext(ctx, &file); err != nil {
 			return fmt.Errorf("failed to add file: %w", err)
 		}
 	}
 
-	var channelReadyOnce sync.Once
-	channelReady := make(chan struct{})
-	channelError := make(chan error, 1)
+	// Large files are striped over several channels, each fed by its own reader
+	if c.parallelStreams > 0 {
+		transferConfig.ParallelStreams = c.parallelStreams
+	}
+	streams := max(transferConfig.ParallelStreams, 1)
+	c.parallelThreshold = transferConfig.ParallelThreshold
+
+	// The receiver answers on the channels with a verified ACK for every file
+	c.acks = newFileAckTracker()
+	c.monitor = c.newKeepalive()
+	// Control messages get a channel of their own, so they are not queued behind chunks.
+	// It opens first and closes last, so the receiver has it for the whole session.
+	control := c.openControlChannel(ctx)
+	if control != nil {
+		defer func() {
+			if err := control.Close(); err != nil {
+				slog.Error("Failed to close control channel", "error", err)
+			}
+		}()
+	}
+	channels := make([]*webrtc.DataChannel, 0, streams)
+	defer func() {
+		for _, dataChannel := range channels {
+			if err := dataChannel.Close(); err != nil {
+				slog.Error("Failed to close data channel", "label", dataChannel.Label(), "error", err)
+			}
+		}
+	}()
+	offerChannel := c.takeOfferChannel()
+	for i := 0; i < streams; i++ {
+		label := fileTransferLabel
+		if i > 0 {
+			label = fmt.Sprintf("%s-%d", fileTransferLabel, i)
+		}
+		var dataChannel *webrtc.DataChannel
+		var err error
+		if i == 0 && offerChannel != nil {
+			dataChannel, err = c.awaitDataChannel(ctx, offerChannel)
+		} else {
+			dataChannel, err = c.openDataChannel(ctx, label)
+		}
+		if err != nil {
+			if i == 0 {
+				return err
+			}
+			slog.Warn("Failed to open additional data channel, sending with fewer streams", "streams", i, "error", err)
+			break
+		}
+		channels = append(channels, dataChannel)
+	}
 
-	dataChannel, err := c.CreateDataChannel("file-transfer", &webrtc.DataChannelInit{
+	// Bond paths to the receiver take large files in place of the extra channels
+	paths := c.startBond(channels[0])
+
+	slog.Info("Data channels ready, starting file transfer", "serviceID", serviceID, "streams", len(channels), "paths", len(paths))
+	c.setDataChannel(channels[0])
+	defer c.setDataChannel(nil)
+	if control != nil {
+		c.setControl(control)
+		defer c.setControl(nil)
+		relayCtx, stopRelay := context.WithCancel(ctx)
+		defer stopRelay()
+		go c.relaySessionState(relayCtx, utm)
+	}
+	if c.monitor == nil {
+		return c.performFileTransfer(ctx, channels, paths, utm, serviceID)
+	}
+
+	// A receiver that went to sleep sends nothing, not even keepalives, and ends the session
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	c.monitor.Start()
+	defer c.monitor.Stop()
+	go watchKeepalive(ctx, cancel, c.monitor)
+	err := c.performFileTransfer(ctx, channels, paths, utm, serviceID)
+	if cause := context.Cause(ctx); errors.Is(cause, transfer.ErrSessionIdle) {
+		return cause
+	}
+	return err
+}
+
+// transferChannelInit returns the options of file transfer channels, which are ordered
+func transferChannelInit() *webrtc.DataChannelInit {
+	return &webrtc.DataChannelInit{
 		Ordered: &[]bool{true}[0],
-	})
+	}
+}
+
+// takeOfferChannel returns the channel created with the offer once, nil after that
+func (c *SenderConn) takeOfferChannel() *webrtc.DataChannel {
+	c.dataChannelMu.Lock()
+	defer c.dataChannelMu.Unlock()
+	offerChannel := c.offerChannel
+	c.offerChannel = nil
+	return offerChannel
+}
+
+// openDataChannel creates an ordered channel that delivers file ACKs and waits until it is open
+func (c *SenderConn) openDataChannel(ctx context.Context, label string) (*webrtc.DataChannel, error) {
+	dataChannel, err := c.CreateDataChannel(label, transferChannelInit())
 	if err != nil {
-		return fmt.Errorf("failed to create data channel: %w", err)
+		return nil, fmt.Errorf("failed to create data channel: %w", err)
 	}
+	return c.awaitDataChannel(ctx, dataChannel)
+}
+
+// awaitDataChannel makes dataChannel deliver file ACKs and waits until it is open
+func (c *SenderConn) awaitDataChannel(ctx context.Context, dataChannel *webrtc.DataChannel) (*webrtc.DataChannel, error) {
+	label := dataChannel.Label()
+	var channelReadyOnce sync.Once
+	channelReady := make(chan struct{})
+	channelError := make(chan error, 1)
 
 	dataChannel.OnOpen(func() {
-		slog.Info("Data channel opened for file transfer")
-		close(channelReady)
+		slog.Info("Data channel opened for file transfer", "label", label)
 		channelReadyOnce.Do(func() { close(channelReady) })
 	})
+	// The channel created with the offer may have opened before its handler was set
+	if dataChannel.ReadyState() == webrtc.DataChannelStateOpen {
+		channelReadyOnce.Do(func() { close(channelReady) })
+	}
+
+	c.handleReplies(dataChannel)
 
 	dataChannel.OnError(func(err error) {
 		select {
 		case channelError <- err:
 		default:
 		}
-		channelReadyOnce.Do(func() { close(channelReady) })
 	})
 
-	// Ensure data channel is closed when function exits
-	defer func() {
-		if err := dataChannel.Close(); err != nil {
-			slog.Error("Failed to close data channel", "error", err)
-		}
-		// Clean up channels if they weren't closed
-		channelReadyOnce.Do(func() { close(channelReady) })
-	}()
-
 	select {
 	case <-channelReady:
-		slog.Info("Data channel ready, starting file transfer", "serviceID", serviceID)
-		return c.performFileTransfer(ctx, dataChannel, utm, serviceID)
+		return dataChannel, nil
 	case err := <-channelError:
-		return fmt.Errorf("data channel error: %w", err)
+		dataChannel.Close()
+		return nil, fmt.Errorf("%w: data channel error: %w", transfer.ErrConnectionLost, err)
 	case <-ctx.Done():
-		return fmt.Errorf("context canceled while waiting for data channel: %w", ctx.Err())
+		dataChannel.Close()
+		return nil, fmt.Errorf("context canceled while waiting for data channel: %w", ctx.Err())
 	}
 }
 
-func (c *SenderConn) performFileTransfer(ctx context.Context, dataChannel *webrtc.DataChannel, utm *transfer.UnifiedTransferManager, serviceID string) error {
+// handleReplies handles the messages the receiver sends on dataChannel during the active file transfer
+func (c *SenderConn) handleReplies(dataChannel *webrtc.DataChannel) {
+	acks := c.acks
+	monitor := c.monitor
+	dataChannel.OnMessage(func(msg webrtc.DataChannelMessage) {
+		if monitor != nil {
+			monitor.Touch()
+		}
+		reply, err := c.serializer.Unmarshal(msg.Data)
+		if err != nil {
+			slog.Warn("Failed to unmarshal message from receiver", "error", err)
+			return
+		}
+		transfer.TraceMessage(transfer.TraceIn, dataChannel.Label(), reply, len(msg.Data))
+		switch reply.Type {
+		case transfer.FileAck:
+			acks.deliver(reply)
+		case transfer.WriteAck:
+			if reporter, ok := c.progressSignaler.(WriteProgressReporter); ok {
+				reporter.ReportWriteProgress(reply.Offset, reply.TotalSize, reply.DiskRate)
+			}
+		case transfer.Keepalive:
+			// Only shows the receiver is there, which the monitor was told above
+		default:
+			slog.Warn("Ignoring unexpected message from receiver", "type", reply.Type)
+		}
+	})
+}
+
+func (c *SenderConn) setDataChannel(dataChannel *webrtc.DataChannel) {
+	c.dataChannelMu.Lock()
+	defer c.dataChannelMu.Unlock()
+	c.dataChannel = dataChannel
+}
+
+// SendStructureUpdate signs changes to the offered structure and sends them to the receiver,
+// which verifies them against the signature chain before applying them.
+// It requires an established connection with an active file transfer.
+func (c *SenderConn) SendStructureUpdate(changes []transfer.StructureChange) error {
+	if c.deltaSigner == nil {
+		return errors.New("no signed structure has been offered")
+	}
+	deltaChanges := crypto.DeltaChangesFromStructure(changes)
+	if len(deltaChanges) == 0 {
+		return nil
+	}
+
+	c.dataChannelMu.Lock()
+	defer c.dataChannelMu.Unlock()
+	dataChannel, err := c.controlChannelLocked()
+	if err != nil {
+		return err
+	}
+
+	delta, err := c.deltaSigner.Sign(deltaChanges)
+	if err != nil {
+		return fmt.Errorf("failed to sign structure update: %w", err)
+	}
+	data, err := json.Marshal(delta)
+	if err != nil {
+		return fmt.Errorf("failed to marshal structure update: %w", err)
+	}
+
+	return c.sendMessage(dataChannel, &transfer.ChunkMessage{
+		Type: transfer.StructureUpdate,
+		Data: data,
+	})
+}
+
+func (c *SenderConn) performFileTransfer(ctx context.Context, channels []*webrtc.DataChannel, paths []*pathChannel, utm *transfer.UnifiedTransferManager, serviceID string) error {
 	slog.Info("Starting file transfer process")
 
 	// Helper closure to handle transfer failures gracefully
@@ -311,6 +765,15 @@ func (c *SenderConn) performFileTransfer(ctx context.Context, dataChannel *webrt
 
 	// Process files one by one
 	for {
+		// A cancelled transfer stops here instead of failing every remaining file, and a
+		// paused one waits here until it is resumed
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := awaitSession(ctx, utm); err != nil {
+			return err
+		}
+
 		// Get next pending file
 		fileNode, hasMore := utm.GetNextPendingFile()
 		if !hasMore {
@@ -326,6 +789,18 @@ func (c *SenderConn) performFileTransfer(ctx context.Context, dataChannel *webrt
 			continue
 		}
 
+		// Files the receiver already verified in an interrupted session are not sent again
+		if c.resumeState.IsFileCompleted(fileNode.Path) {
+			slog.Info("Skipping file already received in resumed session", "file", fileNode.Path)
+			if err := utm.UpdateProgress(fileNode.Path, fileNode.Size); err != nil {
+				slog.Warn("Failed to update progress", "file", fileNode.Path, "error", err)
+			}
+			if err := utm.CompleteTransfer(fileNode.Path); err != nil {
+				slog.Error("Failed to mark file as completed", "file", fileNode.Path, "error", err)
+			}
+			continue
+		}
+
 		// Get chunker for this file
 		chunker, exists := utm.GetChunker(fileNode.Path)
 		if !exists {
@@ -334,12 +809,63 @@ func (c *SenderConn) performFileTransfer(ctx context.Context, dataChannel *webrt
 			continue
 		}
 
-		// Transfer file chunks
-		if err := c.transferFileChunks(ctx, dataChannel, utm, fileNode, chunker, serviceID); err != nil {
+		// Wait for the ACK before sending the last chunk, so a fast ACK is not missed
+		var ack <-chan fileAck
+		if c.fileAcks {
+			ack = c.acks.expect(fileNode.Path)
+		}
+
+		// Transfer file chunks, striping large files over all channels. A hard link to a
+		// file the receiver already has, or content it stores, is linked there instead of sent again.
+		var err error
+		switch {
+		case c.hardLinks && linkTargetCompleted(utm, fileNode):
+			slog.Info("Sending hard link instead of content", "file", fileNode.Path, "target", fileNode.LinkTo)
+			err = c.sendFileLink(channels[0], utm, fileNode, fileNode.LinkTo, serviceID)
+		case c.stored[fileNode.Checksum]:
+			slog.Info("Receiver stores the content, linking it instead of sending", "file", fileNode.Path)
+			err = c.sendFileLink(channels[0], utm, fileNode, "", serviceID)
+		case len(paths) > 1 && !chunker.IsStreaming() && fileNode.Size >= c.parallelThreshold:
+			err = c.transferFileChunksBonded(ctx, paths, utm, fileNode, chunker, serviceID)
+		case len(channels) > 1 && !chunker.IsStreaming() && fileNode.Size >= c.parallelThreshold:
+			err = c.transferFileChunksParallel(ctx, channels, utm, fileNode, chunker, serviceID)
+		default:
+			err = c.transferFileChunks(ctx, channels[0], utm, fileNode, chunker, serviceID)
+		}
+		if flushErr := c.flushFaults(); err == nil {
+			err = flushErr
+		}
+		if err != nil {
+			if ack != nil {
+				c.acks.forget(fileNode.Path)
+			}
+			if errors.Is(err, ErrSessionCancelled) {
+				return err
+			}
 			handleTransferFailure(fileNode.Path, err, "transfer chunks")
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return ctxErr
+			}
 			continue
 		}
 
+		// Only a file the receiver has written and verified counts as completed
+		if ack != nil {
+			expectedHash, size := fileNode.Checksum, fileNode.Size
+			if chunker.IsStreaming() {
+				expectedHash, size = chunker.StreamHash(), chunker.BytesRead()
+			}
+			verifyStart := time.Now()
+			writeTime, err := awaitFileAck(ctx, ack, expectedHash, fileAckTimeout(size))
+			recordPhase(utm, fileNode.Path, transfer.PhaseVerifying, time.Since(verifyStart))
+			if err != nil {
+				c.acks.forget(fileNode.Path)
+				handleTransferFailure(fileNode.Path, err, "await receiver ACK")
+				continue
+			}
+			recordPhase(utm, fileNode.Path, transfer.PhaseWriting, writeTime)
+		}
+
 		// Mark file as completed
 		if err := utm.CompleteTransfer(fileNode.Path); err != nil {
 			slog.Error("Failed to mark file as completed", "file", fileNode.Path, "error", err)
@@ -351,20 +877,32 @@ func (c *SenderConn) performFileTransfer(ctx context.Context, dataChannel *webrt
 		slog.Info("File transfer completed successfully", "file", fileNode.Path)
 	}
 
+	c.logFaults()
 	slog.Info("File transfer process completed")
 	return nil
 }
 
 func (c *SenderConn) transferFileChunks(ctx context.Context, dataChannel *webrtc.DataChannel, utm *transfer.UnifiedTransferManager, fileNode *fileInfo.FileNode, chunker *transfer.Chunker, serviceID string) error {
 	var totalBytesSent int64 = 0
+	var hashing, sending time.Duration
+	defer func() {
+		recordPhase(utm, fileNode.Path, transfer.PhaseHashing, hashing)
+		recordPhase(utm, fileNode.Path, transfer.PhaseTransferring, sending)
+	}()
 
 	for {
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
 		default:
+			if err := awaitFile(ctx, utm, fileNode.Path); err != nil {
+				return err
+			}
+
 			// Get next chunk
+			readStart := time.Now()
 			chunk, err := chunker.Next()
+			hashing += time.Since(readStart)
 			if err != nil {
 				if err == io.EOF {
 					// File transfer completed
@@ -373,24 +911,31 @@ func (c *SenderConn) transferFileChunks(ctx context.Context, dataChannel *webrtc
 				return fmt.Errorf("failed to get next chunk: %w", err)
 			}
 
-			// Create chunk message using the correct ChunkMessage structure
-			chunkMsg := &transfer.ChunkMessage{
-				Type:         transfer.ChunkData,                      // Use ChunkData message type
-				Session:      *transfer.NewTransferSession(serviceID), // Create session with serviceID
-				FileID:       fileNode.Path,                           // Use path as file ID
-				FileName:     fileNode.Name,
-				SequenceNo:   chunk.SequenceNo,
-				Offset:       chunk.Offset, // Add offset to support out-of-order writes
-				Data:         chunk.Data,
-				ChunkHash:    chunk.Hash,
-				TotalSize:    fileNode.Size,
-				ExpectedHash: fileNode.Checksum,
+			// Skip chunks the receiver persisted before the interruption; the last chunk
+			// is always sent so the receiver can finish the file
+			if !chunk.IsLast && c.resumeState.HasChunk(fileNode.Path, chunk.SequenceNo) {
+				totalBytesSent += int64(len(chunk.Data))
+				continue
+			}
+
+			sendStart := time.Now()
+			if err := c.limiter.Wait(ctx, len(chunk.Data)); err != nil {
+				return err
+			}
+			chunkMsg := newChunkMessage(serviceID, fileNode, chunk)
+
+			// Streams learn their size and hash only once the source is exhausted
+			if chunker.IsStreaming() && chunk.IsLast {
+				chunkMsg.TotalSize = chunker.BytesRead()
+				chunkMsg.ExpectedHash = chunker.StreamHash()
 			}
 
 			// Send chunk
 			if err := c.sendMessage(dataChannel, chunkMsg); err != nil {
 				return fmt.Errorf("failed to send chunk %d: %w", chunk.SequenceNo, err)
 			}
+			sending += time.Since(sendStart)
+			utm.RecordChunk(fileNode.Path, chunk.SequenceNo)
 
 			// Update progress
 			totalBytesSent += int64(len(chunk.Data))
@@ -406,116 +951,108 @@ func (c *SenderConn) transferFileChunks(ctx context.Context, dataChannel *webrtc
 	}
 }
 
-func (c *SenderConn) sendMessage(dataChannel *webrtc.DataChannel, msg *transfer.ChunkMessage) error {
-	if dataChannel == nil {
-		return errors.New("data channel is nil")
-	}
-
-	data, err := c.serializer.Marshal(msg)
-	if err != nil {
-		return fmt.Errorf("failed to marshal message: %w", err)
+// transferFileChunksParallel sends a file over all channels, each fed by its own reader of
+// a stripe of the file. The receiver writes chunks at their offsets, so order doesn't matter.
+func (c *SenderConn) transferFileChunksParallel(ctx context.Context, channels []*webrtc.DataChannel, utm *transfer.UnifiedTransferManager, fileNode *fileInfo.FileNode, chunker *transfer.Chunker, serviceID string) error {
+	// Reading overlaps sending, so it all counts as transferring
+	defer func(start time.Time) {
+		recordPhase(utm, fileNode.Path, transfer.PhaseTransferring, time.Since(start))
+	}(time.Now())
+	var mu sync.Mutex
+	var totalBytesSent int64
+	addProgress := func(n int64, report bool) {
+		mu.Lock()
+		defer mu.Unlock()
+		totalBytesSent += n
+		if !report {
+			return
+		}
+		if err := utm.UpdateProgress(fileNode.Path, totalBytesSent); err != nil {
+			slog.Warn("Failed to update progress", "file", fileNode.Path, "error", err)
+		}
 	}
 
-	return dataChannel.Send(data)
-}
+	return transfer.ReadChunksParallel(ctx, chunker, len(channels), func(worker int, chunk *transfer.Chunk) error {
+		if err := awaitFile(ctx, utm, fileNode.Path); err != nil {
+			return err
+		}
 
-// ProgressSignaler interface for sending progress updates
-type ProgressSignaler interface {
-	SendProgressUpdate(totalFiles, completedFiles int, totalBytes, transferredBytes int64,
-		currentFile string, transferRate float64, eta string, overallProgress float64)
-	SetTransferManager(utm *transfer.UnifiedTransferManager)
-}
+		// Skip chunks the receiver persisted before the interruption, except the last
+		if !chunk.IsLast && c.resumeState.HasChunk(fileNode.Path, chunk.SequenceNo) {
+			addProgress(int64(len(chunk.Data)), false)
+			return nil
+		}
 
-// ProgressListener implements transfer.StatusListener to send progress updates
-type ProgressListener struct {
-	signaler       ProgressSignaler
-	lastUpdate     time.Time
-	updateInterval time.Duration
+		if err := c.limiter.Wait(ctx, len(chunk.Data)); err != nil {
+			return err
+		}
+		if err := c.sendMessage(channels[worker], newChunkMessage(serviceID, fileNode, chunk)); err != nil {
+			return fmt.Errorf("failed to send chunk %d: %w", chunk.SequenceNo, err)
+		}
+		utm.RecordChunk(fileNode.Path, chunk.SequenceNo)
+		addProgress(int64(len(chunk.Data)), true)
+		return nil
+	})
 }
 
-// NewProgressListener creates a new progress listener
-func NewProgressListener(signaler ProgressSignaler) *ProgressListener {
-	return &ProgressListener{
-		signaler:       signaler,
-		updateInterval: 500 * time.Millisecond, // Update every 500ms
+// recordPhase adds d to the time the file at path spent in phase
+func recordPhase(utm *transfer.UnifiedTransferManager, path string, phase transfer.TransferPhase, d time.Duration) {
+	if err := utm.RecordPhase(path, phase, d); err != nil {
+		slog.Debug("Failed to record phase timing", "file", path, "phase", phase, "error", err)
 	}
 }
 
-// ID returns the listener ID
-func (pl *ProgressListener) ID() string {
-	return "webrtc-progress-listener"
-}
-
-// OnFileStatusChanged handles file status changes
-func (pl *ProgressListener) OnFileStatusChanged(filePath string, oldStatus, newStatus *transfer.TransferStatus) {
-	// File-level changes don't need immediate UI updates
-	// We'll rely on session-level updates for better performance
+// newChunkMessage wraps a chunk of fileNode for sending
+func newChunkMessage(serviceID string, fileNode *fileInfo.FileNode, chunk *transfer.Chunk) *transfer.ChunkMessage {
+	return &transfer.ChunkMessage{
+		Type:         transfer.ChunkData,
+		Session:      *transfer.NewTransferSession(serviceID),
+		FileID:       fileNode.Path, // Use path as file ID
+		FileName:     fileNode.Name,
+		SequenceNo:   chunk.SequenceNo,
+		Offset:       chunk.Offset, // Offsets allow out-of-order writes
+		Data:         chunk.Data,
+		ChunkHash:    chunk.Hash,
+		TotalSize:    fileNode.Size,
+		ExpectedHash: fileNode.Checksum,
+		IsLast:       chunk.IsLast,
+	}
 }
 
-// OnSessionStatusChanged handles session status changes and sends progress updates
-func (pl *ProgressListener) OnSessionStatusChanged(oldStatus, newStatus *transfer.SessionTransferStatus) {
-	// Throttle updates to avoid overwhelming the UI
-	now := time.Now()
-	if now.Sub(pl.lastUpdate) < pl.updateInterval {
-		return
+func (c *SenderConn) sendMessage(dataChannel *webrtc.DataChannel, msg *transfer.ChunkMessage) error {
+	if dataChannel == nil {
+		return errors.New("data channel is nil")
 	}
-	pl.lastUpdate = now
-
-	// Calculate transfer rate and ETA
-	var transferRate float64
-	var eta string
-
-	if newStatus.CurrentFile != nil {
-		transferRate = newStatus.CurrentFile.TransferRate
 
-		// Calculate ETA based on remaining bytes and current rate
-		if transferRate > 0 {
-			remainingBytes := newStatus.TotalBytes - newStatus.BytesCompleted
-			etaSeconds := float64(remainingBytes) / transferRate
-			if etaSeconds > 0 && etaSeconds < 3600 { // Only show ETA if less than 1 hour
-				eta = pl.formatDuration(time.Duration(etaSeconds * float64(time.Second)))
-			}
-		}
+	if c.faults != nil {
+		return c.faults.Send(msg, func(msg *transfer.ChunkMessage) error {
+			return c.sendRaw(dataChannel, msg)
+		})
 	}
+	return c.sendRaw(dataChannel, msg)
+}
 
-	// Get current file name
-	currentFile := ""
-	if newStatus.CurrentFile != nil {
-		currentFile = pl.extractFileName(newStatus.CurrentFile.FilePath)
+// sendRaw marshals msg and sends it on dataChannel
+func (c *SenderConn) sendRaw(dataChannel *webrtc.DataChannel, msg *transfer.ChunkMessage) error {
+	data, err := c.serializer.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: %w", err)
 	}
 
-	// Send progress update
-	pl.signaler.SendProgressUpdate(
-		newStatus.TotalFiles,
-		newStatus.CompletedFiles,
-		newStatus.TotalBytes,
-		newStatus.BytesCompleted,
-		currentFile,
-		transferRate,
-		eta,
-		newStatus.OverallProgress,
-	)
+	if err := dataChannel.Send(data); err != nil {
+		return fmt.Errorf("%w: %w", transfer.ErrConnectionLost, err)
+	}
+	transfer.TraceMessage(transfer.TraceOut, dataChannel.Label(), msg, len(data))
+	return nil
 }
 
-// formatDuration formats a duration into a human-readable string
-func (pl *ProgressListener) formatDuration(d time.Duration) string {
-	if d < time.Minute {
-		return fmt.Sprintf("%ds", int(d.Seconds()))
-	} else if d < time.Hour {
-		minutes := int(d.Minutes())
-		seconds := int(d.Seconds()) % 60
-		return fmt.Sprintf("%dm %ds", minutes, seconds)
-	}
-	return "∞"
+// ProgressSignaler is told about the transfer manager of a transfer, so it can
+// control the transfer and subscribe to its status changes
+type ProgressSignaler interface {
+	SetTransferManager(utm *transfer.UnifiedTransferManager)
 }
 
-// extractFileName extracts the file name from a file path
-func (pl *ProgressListener) extractFileName(filePath string) string {
-	// Simple implementation - in production, use filepath.Base()
-	for i := len(filePath) - 1; i >= 0; i-- {
-		if filePath[i] == '/' || filePath[i] == '\\' {
-			return filePath[i+1:]
-		}
-	}
-	return filePath
+// WriteProgressReporter is implemented by progress signalers that track the receiver's write ACKs
+type WriteProgressReporter interface {
+	ReportWriteProgress(persisted, total int64, diskRate float64)
 }
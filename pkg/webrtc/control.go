@@ -0,0 +1,105 @@
+package webrtc
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/pion/webrtc/v4"
+	"github.com/rescp17/lanFileSharer/pkg/transfer"
+)
+
+// ControlLabel labels the channel that carries the control messages of a file transfer:
+// ACKs, keepalives, structure updates and pause and cancel notices. It carries no chunks,
+// so these messages are not queued behind the data buffered on the file channels.
+const ControlLabel = "control"
+
+// controlChannelReporter is implemented by signalers that learn from the answer whether the receiver takes a control channel
+type controlChannelReporter interface {
+	ControlChannelSupported() bool
+}
+
+// openControlChannel opens the control channel of the session SendFiles is about to start,
+// or returns nil if the receiver does not take one or it failed to open, in which case
+// control messages go out on the first file channel as before.
+func (c *SenderConn) openControlChannel(ctx context.Context) *webrtc.DataChannel {
+	if !c.controlChannel {
+		return nil
+	}
+	control, err := c.openDataChannel(ctx, ControlLabel)
+	if err != nil {
+		slog.Warn("Failed to open control channel, sending control messages with the files", "error", err)
+		return nil
+	}
+	return control
+}
+
+func (c *SenderConn) setControl(control *webrtc.DataChannel) {
+	c.dataChannelMu.Lock()
+	defer c.dataChannelMu.Unlock()
+	c.control = control
+}
+
+// controlChannelLocked returns the channel control messages go out on: the control channel if
+// one is open, the open file transfer channel otherwise; c.dataChannelMu must be held
+func (c *SenderConn) controlChannelLocked() (*webrtc.DataChannel, error) {
+	if c.control != nil {
+		return c.control, nil
+	}
+	if c.dataChannel == nil {
+		return nil, errors.New("no active file transfer")
+	}
+	return c.dataChannel, nil
+}
+
+// sendControl sends msg on the channel control messages go out on
+func (c *SenderConn) sendControl(msg *transfer.ChunkMessage) error {
+	c.dataChannelMu.Lock()
+	defer c.dataChannelMu.Unlock()
+	dataChannel, err := c.controlChannelLocked()
+	if err != nil {
+		return err
+	}
+	return c.sendRaw(dataChannel, msg)
+}
+
+// sessionStateMessage returns the message telling the receiver the session went from one state
+// to another, if the change is one it is told about
+func sessionStateMessage(from, to transfer.StatusSessionState) (transfer.MessageType, bool) {
+	switch {
+	case from == to:
+		return "", false
+	case to == transfer.StatusSessionStatePaused:
+		return transfer.TransferPause, true
+	case to == transfer.StatusSessionStateActive && from == transfer.StatusSessionStatePaused:
+		return transfer.TransferResume, true
+	case to == transfer.StatusSessionStateCancelled:
+		return transfer.TransferCancel, true
+	default:
+		return "", false
+	}
+}
+
+// relaySessionState tells the receiver on the control channel whenever the session of utm is
+// paused, resumed or cancelled, until ctx ends or the session is cancelled
+func (c *SenderConn) relaySessionState(ctx context.Context, utm *transfer.UnifiedTransferManager) {
+	state := utm.GetSessionState()
+	for {
+		changed := utm.SessionStateChanged()
+		next := utm.GetSessionState()
+		if msgType, ok := sessionStateMessage(state, next); ok {
+			if err := c.sendControl(&transfer.ChunkMessage{Type: msgType}); err != nil {
+				slog.Warn("Failed to tell the receiver about the session", "message", msgType, "error", err)
+			}
+		}
+		state = next
+		if state == transfer.StatusSessionStateCancelled {
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-changed:
+		}
+	}
+}
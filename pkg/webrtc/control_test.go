@@ -0,0 +1,31 @@
+package webrtc
+
+import (
+	"testing"
+
+	"github.com/rescp17/lanFileSharer/pkg/transfer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSessionStateMessage(t *testing.T) {
+	tests := []struct {
+		name     string
+		from, to transfer.StatusSessionState
+		want     transfer.MessageType
+		sent     bool
+	}{
+		{"paused", transfer.StatusSessionStateActive, transfer.StatusSessionStatePaused, transfer.TransferPause, true},
+		{"resumed", transfer.StatusSessionStatePaused, transfer.StatusSessionStateActive, transfer.TransferResume, true},
+		{"cancelled while paused", transfer.StatusSessionStatePaused, transfer.StatusSessionStateCancelled, transfer.TransferCancel, true},
+		{"cancelled", transfer.StatusSessionStateActive, transfer.StatusSessionStateCancelled, transfer.TransferCancel, true},
+		{"unchanged", transfer.StatusSessionStatePaused, transfer.StatusSessionStatePaused, "", false},
+		{"completed", transfer.StatusSessionStateActive, transfer.StatusSessionStateCompleted, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, sent := sessionStateMessage(tt.from, tt.to)
+			assert.Equal(t, tt.sent, sent)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
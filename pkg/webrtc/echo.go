@@ -0,0 +1,284 @@
+package webrtc
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/pion/webrtc/v4"
+)
+
+const (
+	// echoLabel labels the data channel of a ping echo
+	echoLabel = "echo"
+	// echoSessionTimeout bounds how long the answering side keeps an echo connection open
+	echoSessionTimeout = 30 * time.Second
+	// echoReplyTimeout is how long Echo waits for a reply before counting the message lost
+	echoReplyTimeout = 2 * time.Second
+	// echoSeqSize is the sequence number at the start of every echo message
+	echoSeqSize = 8
+)
+
+// EchoExchange delivers a complete offer to the remote peer and returns its complete answer
+type EchoExchange func(ctx context.Context, offer webrtc.SessionDescription) (*webrtc.SessionDescription, error)
+
+// EchoOptions configures Echo
+type EchoOptions struct {
+	Count int // Messages to echo; defaults to 5
+	Size  int // Bytes per message; defaults to 1 KiB
+}
+
+// EchoResult reports a data channel echo
+type EchoResult struct {
+	SetupTime time.Duration   // From creating the offer until the channel opened
+	RTTs      []time.Duration // Round trip of every answered message, in order
+	Lost      int             // Messages not answered within echoReplyTimeout
+	Path      string          // Selected candidate pair, empty if unknown
+	PathType  string          // "direct", "nat" or "relay", empty if unknown
+}
+
+// Echo opens a data channel to the peer behind exchange and measures the round trip of
+// options.Count messages.
+func (a *WebrtcAPI) Echo(ctx context.Context, config Config, exchange EchoExchange, options EchoOptions) (*EchoResult, error) {
+	if options.Count <= 0 {
+		options.Count = 5
+	}
+	if options.Size < echoSeqSize {
+		options.Size = 1024
+	}
+
+	conn, err := a.dialEcho(ctx, config, exchange)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.close()
+
+	replies := make(chan []byte, options.Count)
+	conn.dc.OnMessage(func(msg webrtc.DataChannelMessage) {
+		select {
+		case replies <- msg.Data:
+		default:
+		}
+	})
+	result := &EchoResult{SetupTime: conn.setupTime, Path: conn.path, PathType: conn.pathType}
+
+	payload := make([]byte, options.Size)
+	for seq := uint64(0); seq < uint64(options.Count); seq++ {
+		binary.BigEndian.PutUint64(payload, seq)
+		sent := time.Now()
+		if err := conn.dc.Send(payload); err != nil {
+			return nil, fmt.Errorf("failed to send echo message: %w", err)
+		}
+		if !awaitEcho(ctx, replies, seq) {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			result.Lost++
+			continue
+		}
+		result.RTTs = append(result.RTTs, time.Since(sent))
+	}
+	return result, nil
+}
+
+// echoConn is an open echo channel to a peer
+type echoConn struct {
+	pc        *webrtc.PeerConnection
+	dc        *webrtc.DataChannel
+	setupTime time.Duration // From creating the offer until the channel opened
+	path      string        // Selected candidate pair, empty if unknown
+	pathType  string        // "direct", "nat" or "relay", empty if unknown
+}
+
+// dialEcho connects to the peer behind exchange and opens an echo channel to it. The caller
+// closes the connection.
+func (a *WebrtcAPI) dialEcho(ctx context.Context, config Config, exchange EchoExchange) (*echoConn, error) {
+	return a.dialChannel(ctx, config, echoLabel, nil, exchange)
+}
+
+// dialChannel connects to the peer behind exchange and opens a channel labelled label to it.
+// The descriptions are exchanged once gathering completed, so no separate candidate signaling
+// is needed. The caller closes the connection.
+func (a *WebrtcAPI) dialChannel(ctx context.Context, config Config, label string, options *webrtc.DataChannelInit, exchange EchoExchange) (*echoConn, error) {
+	start := time.Now()
+	pc, err := a.createPeerConnection(config)
+	if err != nil {
+		return nil, err
+	}
+	conn := &echoConn{pc: pc}
+	opened := make(chan struct{})
+	fail := func(err error) (*echoConn, error) {
+		conn.close()
+		return nil, err
+	}
+
+	if conn.dc, err = pc.CreateDataChannel(label, options); err != nil {
+		return fail(fmt.Errorf("failed to create %s channel: %w", label, err))
+	}
+	conn.dc.OnOpen(func() { close(opened) })
+
+	offer, err := pc.CreateOffer(nil)
+	if err != nil {
+		return fail(fmt.Errorf("failed to create offer: %w", err))
+	}
+	gathered := webrtc.GatheringCompletePromise(pc)
+	if err := pc.SetLocalDescription(offer); err != nil {
+		return fail(fmt.Errorf("failed to set local description: %w", err))
+	}
+	select {
+	case <-gathered:
+	case <-ctx.Done():
+		return fail(fmt.Errorf("gathering candidates: %w", ctx.Err()))
+	}
+
+	answer, err := exchange(ctx, *pc.LocalDescription())
+	if err != nil {
+		return fail(err)
+	}
+	if err := pc.SetRemoteDescription(lanDescription(*answer, a.lanOnly)); err != nil {
+		return fail(fmt.Errorf("failed to set remote description for answer: %w", err))
+	}
+	select {
+	case <-opened:
+	case <-ctx.Done():
+		return fail(fmt.Errorf("%s channel did not open: %w", label, ctx.Err()))
+	}
+
+	conn.setupTime = time.Since(start)
+	if pair, err := pc.SCTP().Transport().ICETransport().GetSelectedCandidatePair(); err == nil && pair != nil {
+		conn.path = describeCandidatePair(pair)
+		conn.pathType = candidatePairType(pair)
+	}
+	return conn, nil
+}
+
+func (c *echoConn) close() {
+	if err := c.pc.Close(); err != nil {
+		slog.Warn("Failed to close echo connection", "error", err)
+	}
+}
+
+// awaitEcho waits for the reply to seq, skipping late replies to earlier messages
+func awaitEcho(ctx context.Context, replies <-chan []byte, seq uint64) bool {
+	timer := time.NewTimer(echoReplyTimeout)
+	defer timer.Stop()
+	for {
+		select {
+		case reply := <-replies:
+			if len(reply) >= echoSeqSize && binary.BigEndian.Uint64(reply) == seq {
+				return true
+			}
+		case <-timer.C:
+			return false
+		case <-ctx.Done():
+			return false
+		}
+	}
+}
+
+// ServeEcho answers an echo offer with a complete description and sends every message of
+// its echo channel back. The connection is closed once the channel closes, the connection
+// fails or echoSessionTimeout passes.
+func (a *WebrtcAPI) ServeEcho(ctx context.Context, offer webrtc.SessionDescription) (*webrtc.SessionDescription, error) {
+	answer, _, err := a.ServeEchoSession(ctx, offer)
+	return answer, err
+}
+
+// ServeEchoSession is ServeEcho, also returning a channel closed once the echo connection
+// is closed.
+func (a *WebrtcAPI) ServeEchoSession(ctx context.Context, offer webrtc.SessionDescription) (*webrtc.SessionDescription, <-chan struct{}, error) {
+	pc, err := a.createPeerConnection(Config{})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	done := make(chan struct{})
+	var once sync.Once
+	finish := func() { once.Do(func() { close(done) }) }
+	pc.OnDataChannel(func(dc *webrtc.DataChannel) {
+		if dc.Label() != echoLabel {
+			return
+		}
+		dc.OnMessage(func(msg webrtc.DataChannelMessage) {
+			if err := dc.Send(msg.Data); err != nil {
+				slog.Debug("Failed to echo message", "error", err)
+			}
+		})
+		dc.OnClose(finish)
+	})
+	pc.OnConnectionStateChange(func(state webrtc.PeerConnectionState) {
+		switch state {
+		case webrtc.PeerConnectionStateFailed, webrtc.PeerConnectionStateDisconnected, webrtc.PeerConnectionStateClosed:
+			finish()
+		}
+	})
+
+	answer, err := answerEcho(ctx, pc, lanDescription(offer, a.lanOnly))
+	if err != nil {
+		if closeErr := pc.Close(); closeErr != nil {
+			slog.Warn("Failed to close echo connection", "error", closeErr)
+		}
+		return nil, nil, err
+	}
+
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		timer := time.NewTimer(echoSessionTimeout)
+		defer timer.Stop()
+		select {
+		case <-done:
+		case <-timer.C:
+		case <-ctx.Done():
+		}
+		if err := pc.Close(); err != nil {
+			slog.Warn("Failed to close echo connection", "error", err)
+		}
+	}()
+	return answer, closed, nil
+}
+
+// answerEcho applies offer to pc and returns its answer once gathering completed
+func answerEcho(ctx context.Context, pc *webrtc.PeerConnection, offer webrtc.SessionDescription) (*webrtc.SessionDescription, error) {
+	if err := pc.SetRemoteDescription(offer); err != nil {
+		return nil, fmt.Errorf("failed to set remote description: %w", err)
+	}
+	answer, err := pc.CreateAnswer(nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create answer: %w", err)
+	}
+	gathered := webrtc.GatheringCompletePromise(pc)
+	if err := pc.SetLocalDescription(answer); err != nil {
+		return nil, fmt.Errorf("failed to set local description: %w", err)
+	}
+	select {
+	case <-gathered:
+	case <-ctx.Done():
+		return nil, fmt.Errorf("gathering candidates: %w", ctx.Err())
+	}
+	return pc.LocalDescription(), nil
+}
+
+// describeCandidatePair renders pair like "host/udp 192.168.1.2:50000 -> host/udp 192.168.1.3:50001"
+func describeCandidatePair(pair *webrtc.ICECandidatePair) string {
+	describe := func(c *webrtc.ICECandidate) string {
+		return fmt.Sprintf("%s/%s %s:%d", c.Typ, c.Protocol, c.Address, c.Port)
+	}
+	return describe(pair.Local) + " -> " + describe(pair.Remote)
+}
+
+// candidatePairType classifies pair as "relay" through a TURN server, "direct" between
+// host candidates or "nat" when a reflexive address was needed
+func candidatePairType(pair *webrtc.ICECandidatePair) string {
+	switch {
+	case pair.Local.Typ == webrtc.ICECandidateTypeRelay || pair.Remote.Typ == webrtc.ICECandidateTypeRelay:
+		return "relay"
+	case pair.Local.Typ == webrtc.ICECandidateTypeHost && pair.Remote.Typ == webrtc.ICECandidateTypeHost:
+		return "direct"
+	default:
+		return "nat"
+	}
+}
@@ -0,0 +1,37 @@
+package webrtc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pion/webrtc/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEcho_RoundTrips(t *testing.T) {
+	sender := NewWebrtcAPI()
+	receiver := NewWebrtcAPI()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	result, err := sender.Echo(ctx, Config{}, receiver.ServeEcho, EchoOptions{Count: 3, Size: 64})
+	require.NoError(t, err)
+	assert.Len(t, result.RTTs, 3)
+	assert.Zero(t, result.Lost)
+	assert.Positive(t, result.SetupTime)
+	assert.NotEmpty(t, result.Path)
+	assert.Contains(t, []string{"direct", "nat", "relay"}, result.PathType)
+}
+
+func TestCandidatePairType(t *testing.T) {
+	host := &webrtc.ICECandidate{Typ: webrtc.ICECandidateTypeHost}
+	srflx := &webrtc.ICECandidate{Typ: webrtc.ICECandidateTypeSrflx}
+	relay := &webrtc.ICECandidate{Typ: webrtc.ICECandidateTypeRelay}
+
+	assert.Equal(t, "direct", candidatePairType(&webrtc.ICECandidatePair{Local: host, Remote: host}))
+	assert.Equal(t, "nat", candidatePairType(&webrtc.ICECandidatePair{Local: host, Remote: srflx}))
+	assert.Equal(t, "relay", candidatePairType(&webrtc.ICECandidatePair{Local: relay, Remote: host}))
+}
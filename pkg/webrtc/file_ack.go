@@ -0,0 +1,109 @@
+package webrtc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/rescp17/lanFileSharer/pkg/transfer"
+)
+
+var (
+	// ErrFileAckTimeout is returned when the receiver does not acknowledge a sent file in time
+	ErrFileAckTimeout = fmt.Errorf("%w waiting for receiver to acknowledge file", transfer.ErrTimeout)
+	// ErrFileRejected is returned when the receiver reports that it could not store or verify a file.
+	// The receiver's reason is wrapped as well, for example transfer.ErrDiskFull.
+	ErrFileRejected = errors.New("receiver rejected file")
+)
+
+const (
+	// fileAckBaseTimeout is the time allowed for an ACK on top of the receiver's verification time
+	fileAckBaseTimeout = 30 * time.Second
+	// fileAckVerifyRate is a conservative hashing speed used to scale the timeout with file size
+	fileAckVerifyRate = 20 * 1024 * 1024
+)
+
+// fileAckTimeout returns how long to wait for the ACK of a file of size bytes
+func fileAckTimeout(size int64) time.Duration {
+	if size <= 0 {
+		return fileAckBaseTimeout
+	}
+	return fileAckBaseTimeout + time.Duration(size/fileAckVerifyRate)*time.Second
+}
+
+// fileAck is the receiver's verdict on one file
+type fileAck struct {
+	checksum  string
+	writeTime time.Duration // Receiver's time writing the file
+	err       error
+}
+
+// fileAckTracker routes ACKs from the receiver to the file transfers waiting for them
+type fileAckTracker struct {
+	mu      sync.Mutex
+	pending map[string]chan fileAck
+}
+
+func newFileAckTracker() *fileAckTracker {
+	return &fileAckTracker{pending: make(map[string]chan fileAck)}
+}
+
+// expect registers interest in the ACK for fileID. It must be called before the
+// file's last chunk is sent, so a fast ACK cannot arrive before anyone waits for it.
+func (t *fileAckTracker) expect(fileID string) <-chan fileAck {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	ch := make(chan fileAck, 1)
+	t.pending[fileID] = ch
+	return ch
+}
+
+// forget drops the registration for fileID
+func (t *fileAckTracker) forget(fileID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.pending, fileID)
+}
+
+// deliver hands an ACK message to the transfer waiting for it
+func (t *fileAckTracker) deliver(msg *transfer.ChunkMessage) {
+	t.mu.Lock()
+	ch, ok := t.pending[msg.FileID]
+	delete(t.pending, msg.FileID)
+	t.mu.Unlock()
+
+	if !ok {
+		slog.Warn("Received ACK for a file that is not awaiting one", "fileID", msg.FileID)
+		return
+	}
+	ack := fileAck{checksum: msg.ExpectedHash, writeTime: msg.WriteTime}
+	if msg.ErrorMessage != "" {
+		ack.err = fmt.Errorf("%w: %w", ErrFileRejected, transfer.ErrorFromCode(msg.ErrorCode, msg.ErrorMessage))
+	}
+	ch <- ack
+}
+
+// awaitFileAck waits for the ACK on ch and checks that the receiver verified expectedHash.
+// It returns the receiver's time writing the file, zero if the receiver does not report it.
+func awaitFileAck(ctx context.Context, ch <-chan fileAck, expectedHash string, timeout time.Duration) (time.Duration, error) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case ack := <-ch:
+		if ack.err != nil {
+			return 0, ack.err
+		}
+		if ack.checksum != expectedHash {
+			return 0, fmt.Errorf("%w: %w: receiver verified %q, expected %q", ErrFileRejected, transfer.ErrChecksumMismatch, ack.checksum, expectedHash)
+		}
+		return ack.writeTime, nil
+	case <-timer.C:
+		return 0, fmt.Errorf("%w after %s", ErrFileAckTimeout, timeout)
+	case <-ctx.Done():
+		return 0, fmt.Errorf("context canceled while waiting for file ACK: %w", ctx.Err())
+	}
+}
@@ -0,0 +1,57 @@
+package webrtc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rescp17/lanFileSharer/pkg/transfer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileAckTracker(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("verified", func(t *testing.T) {
+		tracker := newFileAckTracker()
+		ack := tracker.expect("/data/a.txt")
+		tracker.deliver(&transfer.ChunkMessage{Type: transfer.FileAck, FileID: "/data/a.txt", ExpectedHash: "abc", WriteTime: time.Second})
+		writeTime, err := awaitFileAck(ctx, ack, "abc", time.Second)
+		assert.NoError(t, err)
+		assert.Equal(t, time.Second, writeTime, "the receiver's write time is passed on")
+	})
+
+	t.Run("rejected", func(t *testing.T) {
+		tracker := newFileAckTracker()
+		ack := tracker.expect("/data/a.txt")
+		tracker.deliver(&transfer.ChunkMessage{Type: transfer.FileAck, FileID: "/data/a.txt", ErrorMessage: "no space left", ErrorCode: "disk_full"})
+		_, err := awaitFileAck(ctx, ack, "abc", time.Second)
+		assert.ErrorIs(t, err, ErrFileRejected)
+		assert.ErrorIs(t, err, transfer.ErrDiskFull, "the receiver's reason is kept")
+		assert.Contains(t, err.Error(), "no space left")
+	})
+
+	t.Run("checksum mismatch", func(t *testing.T) {
+		tracker := newFileAckTracker()
+		ack := tracker.expect("/data/a.txt")
+		tracker.deliver(&transfer.ChunkMessage{Type: transfer.FileAck, FileID: "/data/a.txt", ExpectedHash: "other"})
+		_, err := awaitFileAck(ctx, ack, "abc", time.Second)
+		assert.ErrorIs(t, err, ErrFileRejected)
+		assert.ErrorIs(t, err, transfer.ErrChecksumMismatch)
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		tracker := newFileAckTracker()
+		ack := tracker.expect("/data/a.txt")
+		// ACKs for other files must not complete this one
+		tracker.deliver(&transfer.ChunkMessage{Type: transfer.FileAck, FileID: "/data/b.txt", ExpectedHash: "abc"})
+		_, err := awaitFileAck(ctx, ack, "abc", 10*time.Millisecond)
+		assert.ErrorIs(t, err, ErrFileAckTimeout)
+		assert.ErrorIs(t, err, transfer.ErrTimeout)
+	})
+}
+
+func TestFileAckTimeout_ScalesWithSize(t *testing.T) {
+	assert.Equal(t, fileAckBaseTimeout, fileAckTimeout(-1))
+	assert.Equal(t, fileAckBaseTimeout+50*time.Second, fileAckTimeout(50*fileAckVerifyRate))
+}
@@ -0,0 +1,52 @@
+package webrtc
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/pion/webrtc/v4"
+
+	"github.com/rescp17/lanFileSharer/pkg/fileInfo"
+	"github.com/rescp17/lanFileSharer/pkg/transfer"
+)
+
+// hardLinkReporter is implemented by signalers that learn from the answer whether the receiver accepts FileLink messages
+type hardLinkReporter interface {
+	HardLinksSupported() bool
+}
+
+// storedContentReporter is implemented by signalers that learn from the answer which offered content the receiver stores
+type storedContentReporter interface {
+	StoredContent() []string
+}
+
+// linkTargetCompleted reports whether fileNode is a hard link to a file already completed in this
+// session. Only a completed target is known to be on the receiver's disk, otherwise the content is sent.
+func linkTargetCompleted(utm *transfer.UnifiedTransferManager, fileNode *fileInfo.FileNode) bool {
+	if fileNode.LinkTo == "" {
+		return false
+	}
+	status, err := utm.GetFileStatus(fileNode.LinkTo)
+	return err == nil && status.State == transfer.TransferStateCompleted
+}
+
+// sendFileLink tells the receiver to recreate fileNode from the file linkTo instead of sending its
+// content; an empty linkTo names the content the receiver stores with the checksum of fileNode
+func (c *SenderConn) sendFileLink(dataChannel *webrtc.DataChannel, utm *transfer.UnifiedTransferManager, fileNode *fileInfo.FileNode, linkTo, serviceID string) error {
+	msg := &transfer.ChunkMessage{
+		Type:         transfer.FileLink,
+		Session:      *transfer.NewTransferSession(serviceID),
+		FileID:       fileNode.Path,
+		FileName:     fileNode.Name,
+		TotalSize:    fileNode.Size,
+		ExpectedHash: fileNode.Checksum,
+		LinkTo:       linkTo,
+	}
+	if err := c.sendMessage(dataChannel, msg); err != nil {
+		return fmt.Errorf("failed to send link for %s: %w", fileNode.Name, err)
+	}
+	if err := utm.UpdateProgress(fileNode.Path, fileNode.Size); err != nil {
+		slog.Warn("Failed to update progress", "file", fileNode.Path, "error", err)
+	}
+	return nil
+}
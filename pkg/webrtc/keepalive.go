@@ -0,0 +1,43 @@
+package webrtc
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/rescp17/lanFileSharer/pkg/transfer"
+)
+
+// keepaliveReporter is implemented by signalers that learn from the answer whether the receiver sends keepalives
+type keepaliveReporter interface {
+	KeepaliveSupported() bool
+}
+
+// SetIdleTimeout makes SendFiles end the session with transfer.ErrSessionIdle once a receiver
+// that sends keepalives sent nothing for timeout; zero never ends it
+func (s *SenderConn) SetIdleTimeout(timeout time.Duration) {
+	s.idleTimeout = timeout
+}
+
+// newKeepalive returns the monitor of the session SendFiles is about to start, or nil if the
+// receiver does not send keepalives or no idle timeout is set. The keepalives go out on the
+// control channel, or the open file transfer channel without one.
+func (c *SenderConn) newKeepalive() *transfer.KeepaliveMonitor {
+	if !c.keepalive || c.idleTimeout <= 0 {
+		return nil
+	}
+	return transfer.NewKeepaliveMonitor(c.idleTimeout, func() error {
+		return c.sendControl(&transfer.ChunkMessage{Type: transfer.Keepalive})
+	})
+}
+
+// watchKeepalive cancels ctx with transfer.ErrSessionIdle once monitor finds the receiver idle,
+// until ctx ends
+func watchKeepalive(ctx context.Context, cancel context.CancelCauseFunc, monitor *transfer.KeepaliveMonitor) {
+	select {
+	case <-monitor.Idle():
+		slog.Warn("Receiver stopped responding, ending the session")
+		cancel(transfer.ErrSessionIdle)
+	case <-ctx.Done():
+	}
+}
@@ -0,0 +1,83 @@
+package webrtc
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"strings"
+
+	"github.com/pion/ice/v4"
+	"github.com/pion/webrtc/v4"
+
+	"github.com/rescp17/lanFileSharer/internal/util"
+)
+
+// ErrOutsideLAN is returned for candidates and paths that leave the local network in LAN-only mode
+var ErrOutsideLAN = errors.New("address is outside the local network")
+
+// checkLANCandidate returns an error wrapping ErrOutsideLAN unless candidate is a host or
+// peer-reflexive candidate on a private or link-local address. mDNS names are accepted,
+// they only resolve on the local link. An empty candidate, which ends gathering, passes.
+func checkLANCandidate(candidate string) error {
+	if candidate == "" {
+		return nil
+	}
+	c, err := ice.UnmarshalCandidate(candidate)
+	if err != nil {
+		return fmt.Errorf("failed to parse ICE candidate: %w", err)
+	}
+	switch c.Type() {
+	case ice.CandidateTypeHost, ice.CandidateTypePeerReflexive:
+	default:
+		return fmt.Errorf("%w: %s candidate %s", ErrOutsideLAN, c.Type(), c.Address())
+	}
+	if strings.HasSuffix(c.Address(), ".local") {
+		return nil
+	}
+	if !util.IsLANAddress(net.ParseIP(c.Address())) {
+		return fmt.Errorf("%w: %s", ErrOutsideLAN, c.Address())
+	}
+	return nil
+}
+
+// filterLANCandidates removes the candidates of sdp that checkLANCandidate refuses
+func filterLANCandidates(sdp string) string {
+	lines := strings.SplitAfter(sdp, "\n")
+	kept := lines[:0]
+	for _, line := range lines {
+		if candidate, ok := strings.CutPrefix(strings.TrimRight(line, "\r\n"), "a="); ok && strings.HasPrefix(candidate, "candidate:") {
+			if err := checkLANCandidate(candidate); err != nil {
+				slog.Warn("Dropping remote candidate in LAN-only mode", "error", err)
+				continue
+			}
+		}
+		kept = append(kept, line)
+	}
+	return strings.Join(kept, "")
+}
+
+// lanDescription returns desc without the candidates outside the LAN when lanOnly is set
+func lanDescription(desc webrtc.SessionDescription, lanOnly bool) webrtc.SessionDescription {
+	if lanOnly {
+		desc.SDP = filterLANCandidates(desc.SDP)
+	}
+	return desc
+}
+
+// guardLANPath closes pc if ICE selected pair, a path to an address outside the LAN, such as a
+// peer-reflexive candidate learned from a connectivity check arriving from the internet. It
+// reports whether the path was kept.
+func guardLANPath(pc *webrtc.PeerConnection, pair *webrtc.ICECandidatePair) bool {
+	if strings.HasSuffix(pair.Remote.Address, ".local") || util.IsLANAddress(net.ParseIP(pair.Remote.Address)) {
+		return true
+	}
+	slog.Error("Closing connection, LAN-only mode refuses the selected path", "remote", pair.Remote.Address)
+	// Closing from the ICE callback would wait on the agent that runs it
+	go func() {
+		if err := pc.Close(); err != nil {
+			slog.Warn("Failed to close connection", "error", err)
+		}
+	}()
+	return false
+}
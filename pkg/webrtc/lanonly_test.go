@@ -0,0 +1,28 @@
+package webrtc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckLANCandidate(t *testing.T) {
+	assert.NoError(t, checkLANCandidate(""), "the end of candidates passes")
+	assert.NoError(t, checkLANCandidate("candidate:1 1 udp 2130706431 192.168.1.5 5000 typ host"))
+	assert.NoError(t, checkLANCandidate("candidate:1 1 udp 2130706431 3f1c2a.local 5000 typ host"), "mDNS names stay on the link")
+	assert.ErrorIs(t, checkLANCandidate("candidate:1 1 udp 2130706431 8.8.8.8 5000 typ host"), ErrOutsideLAN)
+	assert.ErrorIs(t, checkLANCandidate("candidate:2 1 udp 1694498815 203.0.113.7 6000 typ srflx raddr 0.0.0.0 rport 0"), ErrOutsideLAN)
+	assert.ErrorIs(t, checkLANCandidate("candidate:3 1 udp 100 10.0.0.1 3478 typ relay raddr 0.0.0.0 rport 0"), ErrOutsideLAN,
+		"relayed traffic goes through a server even when its address is private")
+	assert.Error(t, checkLANCandidate("garbage"))
+}
+
+func TestFilterLANCandidates(t *testing.T) {
+	sdp := "v=0\r\n" +
+		"a=candidate:1 1 udp 2130706431 192.168.1.5 5000 typ host\r\n" +
+		"a=candidate:2 1 udp 1694498815 203.0.113.7 6000 typ srflx raddr 0.0.0.0 rport 0\r\n" +
+		"a=end-of-candidates\r\n"
+	assert.Equal(t, "v=0\r\n"+
+		"a=candidate:1 1 udp 2130706431 192.168.1.5 5000 typ host\r\n"+
+		"a=end-of-candidates\r\n", filterLANCandidates(sdp))
+}
@@ -0,0 +1,46 @@
+package webrtc
+
+import (
+	"github.com/pion/webrtc/v4"
+)
+
+// Path is the pair of candidates ICE selected to carry a connection
+type Path struct {
+	Local  string // Address of the local candidate, an IP or an mDNS name
+	Remote string // Address of the remote candidate, an IP or an mDNS name
+}
+
+// OnSelectedPath calls f with every path ICE selects for the connection, replacing an earlier f.
+// f runs on the ICE agent and must not block.
+func (c *Connection) OnSelectedPath(f func(Path)) {
+	c.pathMu.Lock()
+	defer c.pathMu.Unlock()
+	c.onPath = f
+}
+
+// reportPath calls the function given to OnSelectedPath with path
+func (c *Connection) reportPath(path Path) {
+	c.pathMu.Lock()
+	onPath := c.onPath
+	c.pathMu.Unlock()
+	if onPath != nil {
+		onPath(path)
+	}
+}
+
+// watchSelectedPath handles the paths ICE selects for pc, refusing those outside the LAN when
+// lanOnly is set and passing the others to onPath, which may be nil. It replaces the handler
+// of an earlier call.
+func watchSelectedPath(pc *webrtc.PeerConnection, lanOnly bool, onPath func(Path)) {
+	pc.SCTP().Transport().ICETransport().OnSelectedCandidatePairChange(func(pair *webrtc.ICECandidatePair) {
+		if pair == nil || pair.Local == nil || pair.Remote == nil {
+			return
+		}
+		if lanOnly && !guardLANPath(pc, pair) {
+			return
+		}
+		if onPath != nil {
+			onPath(Path{Local: pair.Local.Address, Remote: pair.Remote.Address})
+		}
+	})
+}
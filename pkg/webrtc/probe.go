@@ -0,0 +1,156 @@
+package webrtc
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/pion/webrtc/v4"
+	"github.com/rescp17/lanFileSharer/pkg/transfer"
+)
+
+const (
+	// SpeedProbeLabel labels the data channel of a speed probe
+	SpeedProbeLabel = "speed-probe"
+	// speedProbeMessageSize is the size of every random message of a probe
+	speedProbeMessageSize = 64 * 1024
+	// speedProbeMaxBuffered pauses sending while this much probe data is still queued
+	speedProbeMaxBuffered = 1 << 20
+	// speedProbeHeaderSize is the size of the total announced first and of the receiver's reply
+	speedProbeHeaderSize = 8
+)
+
+// ErrSpeedProbeUnsupported is returned by ProbeSpeed when the receiver did not announce speed probes
+var ErrSpeedProbeUnsupported = errors.New("receiver does not support speed probes")
+
+// SpeedProbeResult reports how fast a probe reached the receiver
+type SpeedProbeResult struct {
+	Bytes    int64
+	Duration time.Duration // From the first byte sent until the receiver confirmed the last
+}
+
+// Rate returns the measured rate in bytes per second
+func (r SpeedProbeResult) Rate() float64 {
+	if r.Duration <= 0 {
+		return 0
+	}
+	return float64(r.Bytes) / r.Duration.Seconds()
+}
+
+// speedProbeReporter is implemented by signalers that learn from the answer whether the receiver serves speed probes
+type speedProbeReporter interface {
+	SpeedProbeSupported() bool
+}
+
+// ProbeSpeed sends size bytes of random data over a separate channel and measures how long
+// the receiver takes to confirm them. Random data keeps compression from skewing the result.
+func (c *SenderConn) ProbeSpeed(ctx context.Context, size int64) (*SpeedProbeResult, error) {
+	if !c.speedProbe {
+		return nil, ErrSpeedProbeUnsupported
+	}
+	if size <= 0 {
+		return nil, fmt.Errorf("invalid speed probe size %d", size)
+	}
+
+	dataChannel, err := c.CreateDataChannel(SpeedProbeLabel, &webrtc.DataChannelInit{
+		Ordered: &[]bool{true}[0],
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create speed probe channel: %w", err)
+	}
+	defer func() {
+		if err := dataChannel.Close(); err != nil {
+			slog.Warn("Failed to close speed probe channel", "error", err)
+		}
+	}()
+
+	opened := make(chan struct{})
+	dataChannel.OnOpen(func() { close(opened) })
+	confirmed := make(chan int64, 1)
+	dataChannel.OnMessage(func(msg webrtc.DataChannelMessage) {
+		if len(msg.Data) != speedProbeHeaderSize {
+			return
+		}
+		select {
+		case confirmed <- int64(binary.BigEndian.Uint64(msg.Data)):
+		default:
+		}
+	})
+	drained := make(chan struct{}, 1)
+	dataChannel.SetBufferedAmountLowThreshold(speedProbeMaxBuffered / 2)
+	dataChannel.OnBufferedAmountLow(func() {
+		select {
+		case drained <- struct{}{}:
+		default:
+		}
+	})
+
+	select {
+	case <-opened:
+	case <-ctx.Done():
+		return nil, fmt.Errorf("speed probe channel did not open: %w", ctx.Err())
+	}
+
+	header := make([]byte, speedProbeHeaderSize)
+	binary.BigEndian.PutUint64(header, uint64(size))
+	if err := dataChannel.Send(header); err != nil {
+		return nil, fmt.Errorf("%w: %w", transfer.ErrConnectionLost, err)
+	}
+
+	payload := make([]byte, speedProbeMessageSize)
+	if _, err := rand.Read(payload); err != nil {
+		return nil, fmt.Errorf("failed to generate speed probe data: %w", err)
+	}
+
+	start := time.Now()
+	for sent := int64(0); sent < size; {
+		for dataChannel.BufferedAmount() > speedProbeMaxBuffered {
+			select {
+			case <-drained:
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+		n := min(int64(len(payload)), size-sent)
+		if err := dataChannel.Send(payload[:n]); err != nil {
+			return nil, fmt.Errorf("%w: %w", transfer.ErrConnectionLost, err)
+		}
+		sent += n
+	}
+
+	select {
+	case received := <-confirmed:
+		return &SpeedProbeResult{Bytes: received, Duration: time.Since(start)}, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// ServeSpeedProbe counts the data of a speed probe channel and confirms it once the
+// announced total arrived. The data itself is discarded.
+func ServeSpeedProbe(dataChannel *webrtc.DataChannel) {
+	var expected, received int64
+	dataChannel.OnMessage(func(msg webrtc.DataChannelMessage) {
+		if expected == 0 {
+			if len(msg.Data) != speedProbeHeaderSize {
+				slog.Warn("Ignoring speed probe without a size header")
+				return
+			}
+			expected = int64(binary.BigEndian.Uint64(msg.Data))
+			return
+		}
+		received += int64(len(msg.Data))
+		if received < expected || received-int64(len(msg.Data)) >= expected {
+			return
+		}
+		reply := make([]byte, speedProbeHeaderSize)
+		binary.BigEndian.PutUint64(reply, uint64(received))
+		if err := dataChannel.Send(reply); err != nil {
+			slog.Warn("Failed to confirm speed probe", "error", err)
+		}
+	})
+}
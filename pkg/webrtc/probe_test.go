@@ -0,0 +1,69 @@
+package webrtc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pion/webrtc/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// connectProbePeers connects a sender and a receiver peer that serves speed probes
+func connectProbePeers(t *testing.T, ctx context.Context) *SenderConn {
+	t.Helper()
+	api := NewWebrtcAPI()
+	senderPC, err := api.createPeerConnection(Config{})
+	require.NoError(t, err)
+	receiverPC, err := api.createPeerConnection(Config{})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, senderPC.Close())
+		assert.NoError(t, receiverPC.Close())
+	})
+
+	receiverPC.OnDataChannel(func(dc *webrtc.DataChannel) {
+		if dc.Label() == SpeedProbeLabel {
+			ServeSpeedProbe(dc)
+		}
+	})
+
+	// A first channel negotiates the SCTP association the probe channel is opened on
+	_, err = senderPC.CreateDataChannel("setup", nil)
+	require.NoError(t, err)
+	offer, err := senderPC.CreateOffer(nil)
+	require.NoError(t, err)
+	gathered := webrtc.GatheringCompletePromise(senderPC)
+	require.NoError(t, senderPC.SetLocalDescription(offer))
+	<-gathered
+
+	answer, err := answerEcho(ctx, receiverPC, *senderPC.LocalDescription())
+	require.NoError(t, err)
+	require.NoError(t, senderPC.SetRemoteDescription(*answer))
+
+	return &SenderConn{Connection: &Connection{peerConnection: senderPC}, speedProbe: true}
+}
+
+func TestProbeSpeed(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	conn := connectProbePeers(t, ctx)
+
+	size := int64(3*speedProbeMessageSize + 100)
+	result, err := conn.ProbeSpeed(ctx, size)
+	require.NoError(t, err)
+	assert.Equal(t, size, result.Bytes)
+	assert.Positive(t, result.Rate())
+}
+
+func TestProbeSpeed_Unsupported(t *testing.T) {
+	conn := &SenderConn{Connection: &Connection{}}
+	_, err := conn.ProbeSpeed(context.Background(), 1024)
+	assert.ErrorIs(t, err, ErrSpeedProbeUnsupported)
+}
+
+func TestSpeedProbeResult_Rate(t *testing.T) {
+	assert.Equal(t, float64(2048), SpeedProbeResult{Bytes: 4096, Duration: 2 * time.Second}.Rate())
+	assert.Zero(t, SpeedProbeResult{Bytes: 4096}.Rate())
+}
@@ -0,0 +1,44 @@
+package webrtc
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rescp17/lanFileSharer/pkg/transfer"
+)
+
+// ErrSessionCancelled is returned by SendFiles when its session is cancelled through the
+// transfer manager. It wraps context.Canceled, as the user stopped the transfer.
+var ErrSessionCancelled = fmt.Errorf("transfer session cancelled: %w", context.Canceled)
+
+// awaitSession blocks while the session of utm is paused, so no more chunks are sent until it
+// is resumed, and returns ErrSessionCancelled once it is cancelled. Every channel of a parallel
+// transfer waits here, so they all stop and start with the session.
+func awaitSession(ctx context.Context, utm *transfer.UnifiedTransferManager) error {
+	for {
+		changed := utm.SessionStateChanged()
+		if utm.IsSessionCancelled() {
+			return ErrSessionCancelled
+		}
+		if !utm.IsSessionPaused() {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-changed:
+		}
+	}
+}
+
+// awaitFile is awaitSession for sending the file at path, which also stops once the batch
+// item of the file is cancelled, with transfer.ErrBatchItemCancelled
+func awaitFile(ctx context.Context, utm *transfer.UnifiedTransferManager, path string) error {
+	if err := awaitSession(ctx, utm); err != nil {
+		return err
+	}
+	if utm.IsFileCancelled(path) {
+		return transfer.ErrBatchItemCancelled
+	}
+	return nil
+}
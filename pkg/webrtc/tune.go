@@ -0,0 +1,199 @@
+package webrtc
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"github.com/pion/webrtc/v4"
+
+	"github.com/rescp17/lanFileSharer/pkg/transfer"
+)
+
+const (
+	// tuneDefaultBytes is how much chunk data is echoed per chunk size unless configured
+	tuneDefaultBytes = 4 << 20
+	// tuneWindow is how many bytes may await their echo at once
+	tuneWindow = 1 << 20
+	// tuneProbePrecision is how close the largest message size is bisected
+	tuneProbePrecision = 1024
+)
+
+// TuneOptions configures TuneChunkSize
+type TuneOptions struct {
+	ChunkSizes []int32 // Chunk sizes to measure; defaults to transfer.DefaultTuneChunkSizes
+	Bytes      int64   // Chunk data echoed per chunk size; defaults to 4 MiB
+}
+
+// TuneResult reports a chunk size tuning run
+type TuneResult struct {
+	SetupTime time.Duration // From creating the offer until the channel opened
+	Path      string        // Selected candidate pair, empty if unknown
+	PathType  string        // "direct", "nat" or "relay", empty if unknown
+	// MaxMessageSize is the largest message the channel carried to the peer and back, up to
+	// the largest chunk message measured; bigger chunk messages are skipped
+	MaxMessageSize int
+	Samples        []transfer.ChunkSizeSample
+	// Recommended is the chunk size to send files in; without a usable sample it is the
+	// default and Measured is false
+	Recommended int32
+	Measured    bool
+}
+
+// TuneChunkSize opens an echo channel to the peer behind exchange, probes the largest
+// message it carries and measures the throughput of chunk messages of every chunk size in
+// options, encoded as SendFiles encodes them, to recommend a chunk size. Like Echo it needs
+// no acceptance by the peer's user. The throughput counts data that made the round trip, so
+// it compares chunk sizes rather than predicting the speed of a transfer.
+func (a *WebrtcAPI) TuneChunkSize(ctx context.Context, config Config, exchange EchoExchange, options TuneOptions) (*TuneResult, error) {
+	if len(options.ChunkSizes) == 0 {
+		options.ChunkSizes = transfer.DefaultTuneChunkSizes
+	}
+	if options.Bytes <= 0 {
+		options.Bytes = tuneDefaultBytes
+	}
+
+	serializer := transfer.NewJSONSerializer()
+	samples := make([]transfer.ChunkSizeSample, 0, len(options.ChunkSizes))
+	largest := 0
+	for _, chunkSize := range options.ChunkSizes {
+		if chunkSize < transfer.MinChunkSize || chunkSize > transfer.MaxChunkSize {
+			return nil, fmt.Errorf("chunk size %d must be between %d and %d", chunkSize, transfer.MinChunkSize, transfer.MaxChunkSize)
+		}
+		size, err := transfer.EncodedChunkSize(serializer, chunkSize)
+		if err != nil {
+			return nil, err
+		}
+		samples = append(samples, transfer.ChunkSizeSample{ChunkSize: chunkSize, MessageSize: size})
+		largest = max(largest, size)
+	}
+
+	conn, err := a.dialEcho(ctx, config, exchange)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.close()
+
+	var received atomic.Int64
+	replies := make(chan []byte, 16)
+	notify := make(chan struct{}, 1)
+	conn.dc.OnMessage(func(msg webrtc.DataChannelMessage) {
+		received.Add(1)
+		select {
+		case replies <- msg.Data:
+		default:
+		}
+		select {
+		case notify <- struct{}{}:
+		default:
+		}
+	})
+
+	result := &TuneResult{SetupTime: conn.setupTime, Path: conn.path, PathType: conn.pathType}
+	if result.MaxMessageSize, err = probeMessageSize(ctx, conn.dc, replies, largest); err != nil {
+		return nil, err
+	}
+	for i := range samples {
+		if samples[i].MessageSize > result.MaxMessageSize {
+			samples[i].Skipped = true
+			continue
+		}
+		if err := measureChunkSize(ctx, conn.dc, &received, notify, &samples[i], options.Bytes); err != nil {
+			return nil, err
+		}
+	}
+	result.Samples = samples
+	result.Recommended, result.Measured = transfer.RecommendChunkSize(samples)
+	return result, nil
+}
+
+// probeMessageSize returns the largest message size up to limit that dc carries to the peer
+// and back, bisected to tuneProbePrecision. A message the channel refuses to send or the
+// peer does not echo is too large.
+func probeMessageSize(ctx context.Context, dc *webrtc.DataChannel, replies <-chan []byte, limit int) (int, error) {
+	var seq uint64
+	fits := func(size int) (bool, error) {
+		seq++
+		payload := make([]byte, size)
+		binary.BigEndian.PutUint64(payload, seq)
+		if err := dc.Send(payload); err != nil {
+			slog.Debug("Data channel refused probe message", "size", size, "error", err)
+			return false, nil
+		}
+		if awaitEcho(ctx, replies, seq) {
+			return true, nil
+		}
+		return false, ctx.Err()
+	}
+
+	ok, err := fits(limit)
+	if ok || err != nil {
+		return limit, err
+	}
+	low, high := echoSeqSize, limit
+	for high-low > tuneProbePrecision {
+		mid := low + (high-low)/2
+		ok, err := fits(mid)
+		if err != nil {
+			return 0, err
+		}
+		if ok {
+			low = mid
+		} else {
+			high = mid
+		}
+	}
+	return low, nil
+}
+
+// measureChunkSize echoes total bytes of chunk data in messages of sample's size over dc,
+// keeping up to tuneWindow bytes in flight, and records the throughput in sample. received
+// counts the replies of dc, and notify is signaled on every reply.
+func measureChunkSize(ctx context.Context, dc *webrtc.DataChannel, received *atomic.Int64, notify <-chan struct{}, sample *transfer.ChunkSizeSample, total int64) error {
+	count := int((total + int64(sample.ChunkSize) - 1) / int64(sample.ChunkSize))
+	window := max(2, tuneWindow/sample.MessageSize)
+	payload := make([]byte, sample.MessageSize)
+	base := received.Load()
+	replied := func() int { return int(received.Load() - base) }
+
+	start := time.Now()
+	sent := 0
+	for sent < count {
+		if sent-replied() >= window {
+			if !awaitReply(ctx, notify) {
+				break
+			}
+			continue
+		}
+		if err := dc.Send(payload); err != nil {
+			return fmt.Errorf("failed to send %d byte chunk message: %w", sample.MessageSize, err)
+		}
+		sent++
+	}
+	for replied() < sent && awaitReply(ctx, notify) {
+	}
+
+	// Late replies to an earlier measurement may be counted, never more than were sent
+	got := min(replied(), sent)
+	sample.Duration = time.Since(start)
+	sample.Bytes = int64(got) * int64(sample.ChunkSize)
+	sample.Lost = count - got
+	return ctx.Err()
+}
+
+// awaitReply waits for notify until echoReplyTimeout passes without a reply
+func awaitReply(ctx context.Context, notify <-chan struct{}) bool {
+	timer := time.NewTimer(echoReplyTimeout)
+	defer timer.Stop()
+	select {
+	case <-notify:
+		return true
+	case <-timer.C:
+		return false
+	case <-ctx.Done():
+		return false
+	}
+}
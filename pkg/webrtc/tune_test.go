@@ -0,0 +1,39 @@
+package webrtc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rescp17/lanFileSharer/pkg/transfer"
+)
+
+func TestTuneChunkSize(t *testing.T) {
+	sender := NewWebrtcAPI()
+	receiver := NewWebrtcAPI()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	options := TuneOptions{ChunkSizes: []int32{transfer.MinChunkSize, 16 * 1024}, Bytes: 256 * 1024}
+	result, err := sender.TuneChunkSize(ctx, Config{}, receiver.ServeEcho, options)
+	require.NoError(t, err)
+	require.Len(t, result.Samples, 2)
+	assert.GreaterOrEqual(t, result.MaxMessageSize, result.Samples[1].MessageSize)
+	for _, sample := range result.Samples {
+		assert.False(t, sample.Skipped)
+		assert.Zero(t, sample.Lost)
+		assert.Equal(t, options.Bytes, sample.Bytes)
+		assert.Positive(t, sample.Rate())
+	}
+	assert.True(t, result.Measured)
+	assert.Contains(t, options.ChunkSizes, result.Recommended)
+}
+
+func TestTuneChunkSize_RejectsInvalidSizes(t *testing.T) {
+	_, err := NewWebrtcAPI().TuneChunkSize(context.Background(), Config{}, nil, TuneOptions{ChunkSizes: []int32{1024}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,6 @@
+// Package integration holds end-to-end tests that run a sender and a receiver app in one
+// process, connected over real WebRTC on the host's interfaces. They are slow and need
+// working network interfaces, so they only build with the integration tag:
+//
+//	go test -tags=integration ./test/integration/...
+package integration
@@ -0,0 +1,194 @@
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"io/fs"
+	"math/rand"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/stretchr/testify/require"
+
+	appevents "github.com/rescp17/lanFileSharer/internal/app_events"
+	"github.com/rescp17/lanFileSharer/internal/app_events/receiver"
+	"github.com/rescp17/lanFileSharer/pkg/discovery"
+	receiverApp "github.com/rescp17/lanFileSharer/pkg/receiver"
+	senderApp "github.com/rescp17/lanFileSharer/pkg/sender"
+	"github.com/rescp17/lanFileSharer/pkg/transfer"
+)
+
+// transferTimeout bounds every transfer of the suite
+const transferTimeout = 2 * time.Minute
+
+// receiverPeer is a receiver app serving on a local port, answering requests by itself
+type receiverPeer struct {
+	app      *receiverApp.App
+	dir      string
+	port     int
+	requests atomic.Int32
+	// receivedBytes is the latest byte count the receiver reported
+	receivedBytes atomic.Int64
+	finished      chan receiver.TransferFinishedMsg
+}
+
+// startReceiver runs a receiver app until the test ends. It accepts every request if accept
+// is set and declines it otherwise. It is offline: nothing is announced, no STUN is used.
+func startReceiver(t *testing.T, accept bool) *receiverPeer {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	peer := &receiverPeer{
+		dir:      t.TempDir(),
+		port:     freePort(t),
+		finished: make(chan receiver.TransferFinishedMsg, 4),
+	}
+	peer.app = receiverApp.NewAppWithOptions(peer.port, peer.dir, receiverApp.Options{
+		ServiceName: "integration-receiver",
+		Offline:     true,
+	})
+
+	go func() {
+		if err := peer.app.Run(ctx); err != nil {
+			t.Logf("receiver stopped: %v", err)
+		}
+	}()
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg := <-peer.app.UIMessages():
+				peer.handle(ctx, msg, accept)
+			}
+		}
+	}()
+
+	waitForPort(t, peer.port)
+	return peer
+}
+
+// handle answers requests and records finished transfers, as the TUI would
+func (p *receiverPeer) handle(ctx context.Context, msg tea.Msg, accept bool) {
+	switch m := msg.(type) {
+	case receiver.FileNodeUpdateMsg:
+		p.requests.Add(1)
+		var decision appevents.AppEvent = receiver.FileRequestRejected{}
+		if accept {
+			decision = receiver.FileRequestAccepted{}
+		}
+		select {
+		case p.app.AppEvents() <- decision:
+		case <-ctx.Done():
+		}
+	case receiver.ProgressUpdateMsg:
+		p.receivedBytes.Store(m.ReceivedBytes)
+	case receiver.TransferFinishedMsg:
+		select {
+		case p.finished <- m:
+		default:
+		}
+	}
+}
+
+// service is how senders reach the receiver, as discovery would report it
+func (p *receiverPeer) service() discovery.ServiceInfo {
+	return discovery.ServiceInfo{Name: "integration-receiver", Addr: net.IPv4(127, 0, 0, 1), Port: p.port}
+}
+
+// received returns the content of every file the receiver saved, by base name
+func (p *receiverPeer) received(t *testing.T) map[string][]byte {
+	t.Helper()
+	files := make(map[string][]byte)
+	err := filepath.WalkDir(p.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			// Resume state is kept next to the received files
+			if strings.HasPrefix(d.Name(), ".lanfilesharer") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		files[d.Name()] = content
+		return nil
+	})
+	require.NoError(t, err)
+	return files
+}
+
+// startSender runs a sender app until the test ends, so it handles pause, resume and cancel
+func startSender(t *testing.T, options senderApp.Options) *senderApp.App {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	options.Offline = true
+	options.TransferTimeout = transferTimeout
+	app := senderApp.NewAppWithOptions(discovery.OfflineAdapter{}, options)
+	go func() {
+		if err := app.Run(ctx); err != nil && ctx.Err() == nil {
+			t.Logf("sender stopped: %v", err)
+		}
+	}()
+	return app
+}
+
+// send prepares paths like a headless send and sends them to peer, blocking until the
+// transfer ends. Every app message is passed to onMessage, which may be nil.
+func send(t *testing.T, app *senderApp.App, peer *receiverPeer, paths []string, onMessage func(tea.Msg)) error {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), transferTimeout)
+	defer cancel()
+
+	nodes, err := transfer.PrepareNodes(ctx, paths, 2, nil)
+	require.NoError(t, err)
+	return app.SendHeadless(ctx, peer.service(), nodes, onMessage)
+}
+
+// writeFile writes size pseudo-random bytes to path, creating its directory
+func writeFile(t *testing.T, path string, size int, seed int64) []byte {
+	t.Helper()
+	content := make([]byte, size)
+	rand.New(rand.NewSource(seed)).Read(content)
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, os.WriteFile(path, content, 0644))
+	return content
+}
+
+// freePort returns a TCP port that was free a moment ago
+func freePort(t *testing.T) int {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port
+}
+
+// waitForPort waits until the receiver's HTTP server accepts connections
+func waitForPort(t *testing.T, port int) {
+	t.Helper()
+	addr := net.JoinHostPort("127.0.0.1", strconv.Itoa(port))
+	require.Eventually(t, func() bool {
+		conn, err := net.DialTimeout("tcp", addr, 100*time.Millisecond)
+		if err != nil {
+			return false
+		}
+		conn.Close()
+		return true
+	}, 10*time.Second, 50*time.Millisecond, "receiver did not start listening on %s", addr)
+}
@@ -0,0 +1,153 @@
+//go:build integration
+
+package integration
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rescp17/lanFileSharer/api"
+	"github.com/rescp17/lanFileSharer/internal/app_events/sender"
+	senderApp "github.com/rescp17/lanFileSharer/pkg/sender"
+	"github.com/rescp17/lanFileSharer/pkg/transfer"
+)
+
+func TestTransfer_ManySmallFiles(t *testing.T) {
+	peer := startReceiver(t, true)
+	app := startSender(t, senderApp.Options{})
+
+	root := filepath.Join(t.TempDir(), "many")
+	want := make(map[string][]byte)
+	for i := 0; i < 200; i++ {
+		name := fmt.Sprintf("file-%03d.txt", i)
+		want[name] = writeFile(t, filepath.Join(root, fmt.Sprintf("dir-%d", i%10), name), 1+i*37, int64(i))
+	}
+
+	require.NoError(t, send(t, app, peer, []string{root}, nil))
+	assert.Equal(t, want, peer.received(t))
+	assert.Equal(t, int32(1), peer.requests.Load())
+}
+
+func TestTransfer_UnicodePaths(t *testing.T) {
+	peer := startReceiver(t, true)
+	app := startSender(t, senderApp.Options{})
+
+	root := filepath.Join(t.TempDir(), "données")
+	want := make(map[string][]byte)
+	for i, name := range []string{"résumé.txt", "日本語のファイル.txt", "emoji-🚀.bin", "Ünïcödé ñame.dat"} {
+		want[name] = writeFile(t, filepath.Join(root, "子目录", name), 4096+i, int64(i))
+	}
+
+	require.NoError(t, send(t, app, peer, []string{root}, nil))
+	assert.Equal(t, want, peer.received(t))
+}
+
+func TestTransfer_LargeFile(t *testing.T) {
+	if testing.Short() {
+		t.Skip("large transfer skipped in short mode")
+	}
+	peer := startReceiver(t, true)
+	app := startSender(t, senderApp.Options{})
+
+	// Larger than the parallel threshold, so it is striped over several channels
+	size := int(transfer.DefaultTransferConfig().ParallelThreshold) + 8<<20
+	path := filepath.Join(t.TempDir(), "large.bin")
+	content := writeFile(t, path, size, 1)
+
+	require.NoError(t, send(t, app, peer, []string{path}, nil))
+	received := peer.received(t)["large.bin"]
+	require.Len(t, received, size)
+	assert.Equal(t, sha256.Sum256(content), sha256.Sum256(received))
+}
+
+func TestTransfer_Rejected(t *testing.T) {
+	peer := startReceiver(t, false)
+	app := startSender(t, senderApp.Options{})
+
+	path := filepath.Join(t.TempDir(), "declined.txt")
+	writeFile(t, path, 1024, 1)
+
+	err := send(t, app, peer, []string{path}, nil)
+	require.ErrorIs(t, err, api.ErrTransferRejected)
+	assert.Empty(t, peer.received(t))
+}
+
+// slowSend delays every chunk so transfers last long enough to be paused or cancelled
+var slowSend = transfer.ChaosConfig{DelayRate: 1, Delay: 5 * time.Millisecond, Seed: 1}
+
+func TestTransfer_PauseAndResume(t *testing.T) {
+	peer := startReceiver(t, true)
+	app := startSender(t, senderApp.Options{Chaos: slowSend})
+
+	path := filepath.Join(t.TempDir(), "paused.bin")
+	content := writeFile(t, path, 32<<20, 1)
+
+	paused := make(chan struct{}, 1)
+	done := make(chan error, 1)
+	go func() {
+		done <- send(t, app, peer, []string{path}, func(msg tea.Msg) {
+			if _, ok := msg.(sender.TransferPausedMsg); ok {
+				paused <- struct{}{}
+			}
+		})
+	}()
+
+	require.Eventually(t, func() bool { return peer.receivedBytes.Load() > 0 }, 30*time.Second, 10*time.Millisecond)
+	app.AppEvents() <- sender.PauseTransferMsg{}
+	select {
+	case <-paused:
+	case err := <-done:
+		t.Fatalf("transfer ended before it was paused: %v", err)
+	}
+
+	// Chunks already in flight may still arrive; after that nothing is sent until resumed
+	time.Sleep(time.Second)
+	atPause := peer.receivedBytes.Load()
+	time.Sleep(time.Second)
+	assert.Equal(t, atPause, peer.receivedBytes.Load(), "bytes were received while paused")
+	assert.Less(t, atPause, int64(len(content)))
+
+	app.AppEvents() <- sender.ResumeTransferMsg{}
+	require.NoError(t, <-done)
+	assert.True(t, bytes.Equal(content, peer.received(t)["paused.bin"]), "received file differs after resuming")
+}
+
+func TestTransfer_Cancel(t *testing.T) {
+	peer := startReceiver(t, true)
+	app := startSender(t, senderApp.Options{Chaos: slowSend})
+
+	path := filepath.Join(t.TempDir(), "cancelled.bin")
+	content := writeFile(t, path, 32<<20, 1)
+
+	done := make(chan error, 1)
+	go func() { done <- send(t, app, peer, []string{path}, nil) }()
+
+	require.Eventually(t, func() bool { return peer.receivedBytes.Load() > 0 }, 30*time.Second, 10*time.Millisecond)
+	app.AppEvents() <- sender.CancelTransferMsg{}
+
+	select {
+	case err := <-done:
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, context.Canceled), "unexpected error: %v", err)
+	case <-time.After(30 * time.Second):
+		t.Fatal("transfer did not stop after it was cancelled")
+	}
+
+	received, ok := peer.received(t)["cancelled.bin"]
+	if ok {
+		assert.False(t, bytes.Equal(content, received), "a cancelled transfer delivered the whole file")
+	}
+	_, err := os.Stat(path)
+	assert.NoError(t, err, "cancelling leaves the source untouched")
+}